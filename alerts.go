@@ -0,0 +1,164 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// alertStaleFactor is the number of missed scan intervals after which a group is considered stale.
+	alertStaleFactor = 3
+	// alertCoverageDrop is the ratio of targets (compared to the maximum of the last day) below which an alert fires.
+	alertCoverageDrop = 0.8
+)
+
+// alertRuleFile is the structure of a Prometheus rule file.
+type alertRuleFile struct {
+	Groups []alertRuleGroup `yaml:"groups"`
+}
+
+// alertRuleGroup is a single group within a Prometheus rule file.
+type alertRuleGroup struct {
+	Name  string      `yaml:"name"`
+	Rules []alertRule `yaml:"rules"`
+}
+
+// alertRule is a single alerting rule.
+type alertRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// generateAlertRules returns a starter Prometheus rule file monitoring netbox_sd itself. Besides rules covering the
+// Netbox API, each group gets rules for failed and stale updates, missing targets and a drop of its target count.
+// Thresholds are derived from the group's scan interval.
+func generateAlertRules(cfg *config.Config) ([]byte, error) {
+	var (
+		rules   alertRuleFile
+		group   *config.Group
+		stale   time.Duration
+		buf     bytes.Buffer
+		encoder *yaml.Encoder
+		err     error
+	)
+
+	rules.Groups = append(rules.Groups, alertRuleGroup{
+		Name: "netbox_sd",
+		Rules: []alertRule{
+			{
+				Alert:  "NetboxSDDown",
+				Expr:   `up{job="netbox_sd"} == 0`,
+				For:    "5m",
+				Labels: map[string]string{"severity": "critical"},
+				Annotations: map[string]string{
+					"summary": "netbox_sd is down, targets are not updated",
+				},
+			},
+			{
+				Alert:  "NetboxSDAPIErrors",
+				Expr:   fmt.Sprintf(`sum(increase(%s_netbox_api_status{code=~"4..|5.."}[15m])) > 0`, PrometheusNameSpace),
+				For:    "15m",
+				Labels: map[string]string{"severity": "warning"},
+				Annotations: map[string]string{
+					"summary": "Netbox API calls of netbox_sd are failing (check token permissions and Netbox health)",
+				},
+			},
+		},
+	})
+
+	for _, group = range cfg.Groups {
+		stale = alertStaleFactor * group.ScanInterval
+
+		rules.Groups = append(rules.Groups, alertRuleGroup{
+			Name: "netbox_sd_" + group.File,
+			Rules: []alertRule{
+				{
+					Alert: "NetboxSDUpdateFailed",
+					Expr: fmt.Sprintf(`increase(%s_update_error{group=%q}[%s]) > 0`,
+						PrometheusNameSpace, group.File, promDuration(stale)),
+					Labels: map[string]string{"severity": "warning"},
+					Annotations: map[string]string{
+						"summary": fmt.Sprintf("updating targets of group %s failed", group.File),
+					},
+				},
+				{
+					Alert: "NetboxSDGroupStale",
+					Expr: fmt.Sprintf(`time() - %s_update_timestamp{group=%q} > %d`,
+						PrometheusNameSpace, group.File, int64(stale.Seconds())),
+					Labels: map[string]string{"severity": "critical"},
+					Annotations: map[string]string{
+						"summary": fmt.Sprintf("targets of group %s haven't been updated for %s", group.File,
+							promDuration(stale)),
+					},
+				},
+				{
+					Alert:  "NetboxSDTargetsMissing",
+					Expr:   fmt.Sprintf(`%s_target_count{group=%q} == 0`, PrometheusNameSpace, group.File),
+					For:    promDuration(stale),
+					Labels: map[string]string{"severity": "warning"},
+					Annotations: map[string]string{
+						"summary": fmt.Sprintf("group %s doesn't contain any targets", group.File),
+					},
+				},
+				{
+					Alert: "NetboxSDCoverageDrop",
+					Expr: fmt.Sprintf(`%[1]s_target_count{group=%[2]q} < %[3]g * max_over_time(%[1]s_target_count{group=%[2]q}[1d])`,
+						PrometheusNameSpace, group.File, alertCoverageDrop),
+					For:    promDuration(stale),
+					Labels: map[string]string{"severity": "warning"},
+					Annotations: map[string]string{
+						"summary": fmt.Sprintf("number of targets in group %s dropped by more than %d%% within a day",
+							group.File, int((1-alertCoverageDrop)*100)),
+					},
+				},
+			},
+		})
+	}
+
+	encoder = yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// promDuration formats d as Prometheus duration (e.g. 15m) using the largest unit that represents d without loss.
+func promDuration(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+
+	return fmt.Sprintf("%ds", int64(d.Seconds()))
+}
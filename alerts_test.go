@@ -0,0 +1,63 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateAlertRules(t *testing.T) {
+	var (
+		cfg = &config.Config{
+			Groups: []*config.Group{
+				{File: "junos.yml", ScanInterval: 5 * time.Minute},
+				{File: "node.yml", ScanInterval: 30 * time.Second},
+			},
+		}
+		data   []byte
+		result alertRuleFile
+		err    error
+	)
+
+	data, err = generateAlertRules(cfg)
+	require.Nil(t, err)
+	require.Nil(t, yaml.Unmarshal(data, &result))
+
+	require.Len(t, result.Groups, 3)
+	assert.Equal(t, "netbox_sd", result.Groups[0].Name)
+	assert.Equal(t, "netbox_sd_junos.yml", result.Groups[1].Name)
+	assert.Equal(t, "netbox_sd_node.yml", result.Groups[2].Name)
+
+	// thresholds are derived from the scan interval
+	assert.Equal(t, `time() - netbox_sd_update_timestamp{group="junos.yml"} > 900`, result.Groups[1].Rules[1].Expr)
+	assert.Equal(t, `time() - netbox_sd_update_timestamp{group="node.yml"} > 90`, result.Groups[2].Rules[1].Expr)
+	assert.Equal(t, "90s", result.Groups[2].Rules[2].For)
+}
+
+func TestPromDuration(t *testing.T) {
+	assert.Equal(t, "2h", promDuration(2*time.Hour))
+	assert.Equal(t, "15m", promDuration(15*time.Minute))
+	assert.Equal(t, "90s", promDuration(90*time.Second))
+}
@@ -0,0 +1,149 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the target changes of groups between two scans, which are sent to the webhook and appended to the
+// changelog file.
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+
+	"github.com/prometheus/common/model"
+)
+
+// changelog appends target changes to the changelog file. Workers of all groups share the file.
+type changelog struct {
+	mu sync.Mutex
+}
+
+// targetChanges describes the targets added to and removed from a group by a scan.
+type targetChanges struct {
+	Group string    `json:"group"`
+	Time  time.Time `json:"time"`
+	// Targets is the number of active targets after the change.
+	Targets int              `json:"targets"`
+	Added   []*changedTarget `json:"added"`
+	Removed []*changedTarget `json:"removed"`
+}
+
+// changedTarget is a single target address and its labels.
+type changedTarget struct {
+	Address string         `json:"address"`
+	Labels  model.LabelSet `json:"labels"`
+}
+
+// activeTargets returns the labels of all active target addresses by address.
+func activeTargets(targets []*discovery.Target) map[string]model.LabelSet {
+	var (
+		result  map[string]model.LabelSet = make(map[string]model.LabelSet)
+		target  *discovery.Target
+		address model.LabelSet
+	)
+
+	for _, target = range targets {
+		if target.Skipped() || target.Graveyard {
+			continue
+		}
+
+		for _, address = range target.TargetGroup().Targets {
+			result[string(address[model.AddressLabel])] = target.Labels
+		}
+	}
+
+	return result
+}
+
+// diffTargets returns the payload describing the changes from previous to current (as returned by activeTargets) of
+// group. It returns nil when nothing changed.
+func diffTargets(group string, previous, current map[string]model.LabelSet, now time.Time) *targetChanges {
+	var (
+		payload *targetChanges = &targetChanges{
+			Group:   group,
+			Time:    now,
+			Targets: len(current),
+			Added:   make([]*changedTarget, 0),
+			Removed: make([]*changedTarget, 0),
+		}
+		address string
+		ok      bool
+	)
+
+	for address = range current {
+		if _, ok = previous[address]; !ok {
+			payload.Added = append(payload.Added, &changedTarget{Address: address, Labels: current[address]})
+		}
+	}
+
+	for address = range previous {
+		if _, ok = current[address]; !ok {
+			payload.Removed = append(payload.Removed, &changedTarget{Address: address, Labels: previous[address]})
+		}
+	}
+
+	if len(payload.Added) == 0 && len(payload.Removed) == 0 {
+		return nil
+	}
+
+	sortChangedTargets(payload.Added)
+	sortChangedTargets(payload.Removed)
+
+	return payload
+}
+
+// sortChangedTargets sorts targets by address.
+func sortChangedTargets(targets []*changedTarget) {
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Address < targets[j].Address
+	})
+}
+
+// append writes changes as a single JSON line to the end of file, creating it if necessary.
+func (cl *changelog) append(file string, changes *targetChanges) error {
+	var (
+		handle *os.File
+		data   []byte
+		err    error
+	)
+
+	data, err = json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	handle, err = os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = handle.Write(append(data, '\n'))
+	if err != nil {
+		handle.Close()
+		return err
+	}
+
+	return handle.Close()
+}
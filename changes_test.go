@@ -0,0 +1,105 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffTargets(t *testing.T) {
+	var (
+		now      time.Time = time.Now()
+		previous map[string]model.LabelSet
+		current  map[string]model.LabelSet
+		payload  *targetChanges
+		targets  []*discovery.Target = []*discovery.Target{
+			{
+				Addresses:  []*netbox.IP{{Address: "192.0.2.1/24"}},
+				Ports:      []int{9100},
+				Labels:     model.LabelSet{"netbox_name": "device-A"},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				Addresses:  []*netbox.IP{{Address: "192.0.2.2/24"}},
+				Ports:      []int{9100},
+				Labels:     model.LabelSet{"netbox_name": "device-B"},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				Addresses:  []*netbox.IP{{Address: "192.0.2.3/24"}},
+				Labels:     model.LabelSet{"netbox_name": "device-C"},
+				SkipReason: discovery.StateSkippedBadStatus,
+			},
+		}
+	)
+
+	previous = activeTargets(targets[:1])
+	current = activeTargets(targets[1:])
+	assert.Len(t, current, 1)
+
+	payload = diffTargets("node.yml", previous, current, now)
+	assert.Equal(t, &targetChanges{
+		Group:   "node.yml",
+		Time:    now,
+		Targets: 1,
+		Added:   []*changedTarget{{Address: "192.0.2.2:9100", Labels: model.LabelSet{"netbox_name": "device-B"}}},
+		Removed: []*changedTarget{{Address: "192.0.2.1:9100", Labels: model.LabelSet{"netbox_name": "device-A"}}},
+	}, payload)
+
+	// no changes
+	assert.Nil(t, diffTargets("node.yml", current, current, now))
+}
+
+func TestChangelogAppend(t *testing.T) {
+	var (
+		cl      changelog
+		file    string         = filepath.Join(t.TempDir(), "changelog.jsonl")
+		changes *targetChanges = &targetChanges{
+			Group:   "node.yml",
+			Time:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			Targets: 0,
+			Added:   []*changedTarget{},
+			Removed: []*changedTarget{{Address: "192.0.2.1:9100", Labels: model.LabelSet{"netbox_name": "device-A"}}},
+		}
+		data  []byte
+		lines []string
+		err   error
+	)
+
+	require.Nil(t, cl.append(file, changes))
+	require.Nil(t, cl.append(file, changes))
+
+	data, err = os.ReadFile(file)
+	require.Nil(t, err)
+
+	lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, `{"group":"node.yml","time":"2024-05-01T12:00:00Z","targets":0,"added":[],"removed":[{"address":"192.0.2.1:9100","labels":{"netbox_name":"device-A"}}]}`, lines[0])
+	assert.Equal(t, lines[0], lines[1])
+}
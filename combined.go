@@ -0,0 +1,130 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the combined file containing the targets of all groups.
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+
+	"github.com/prometheus/common/model"
+)
+
+// combinedGroupLabel is the label added to each target of the combined file containing the group's file.
+const combinedGroupLabel model.LabelName = "group"
+
+// combinedTargets keeps the targets of the last successful scan of each group for the combined file.
+type combinedTargets struct {
+	mu      sync.Mutex
+	targets map[string][]*discovery.Target
+}
+
+// update stores targets as the current targets of group and returns the content of the combined file in format.
+// Skipped and graveyard targets are not part of the combined file.
+func (combined *combinedTargets) update(group string, targets []*discovery.Target, format string) ([]byte, error) {
+	var (
+		tagged []*discovery.Target
+		target *discovery.Target
+		copied *discovery.Target
+	)
+
+	for _, target = range targets {
+		if target.Skipped() || target.Graveyard {
+			continue
+		}
+
+		copied = new(discovery.Target)
+		*copied = *target
+		copied.Labels = target.Labels.Clone()
+		copied.Labels[combinedGroupLabel] = model.LabelValue(group)
+		tagged = append(tagged, copied)
+	}
+
+	combined.mu.Lock()
+	defer combined.mu.Unlock()
+
+	if combined.targets == nil {
+		combined.targets = make(map[string][]*discovery.Target)
+	}
+
+	combined.targets[group] = tagged
+
+	return renderTargets(combined.list(), format, false)
+}
+
+// remove deletes the targets of group.
+func (combined *combinedTargets) remove(group string) {
+	combined.mu.Lock()
+	defer combined.mu.Unlock()
+
+	delete(combined.targets, group)
+}
+
+// list returns the targets of all groups ordered by group. The caller must hold mu.
+func (combined *combinedTargets) list() []*discovery.Target {
+	var (
+		groups []string = make([]string, 0, len(combined.targets))
+		group  string
+		result []*discovery.Target
+	)
+
+	for group = range combined.targets {
+		groups = append(groups, group)
+	}
+
+	sort.Strings(groups)
+
+	for _, group = range groups {
+		result = append(result, combined.targets[group]...)
+	}
+
+	return result
+}
+
+// writeCombined updates the combined file of cfg with the targets of group. Nothing is done when cfg doesn't define a
+// combined file.
+func (sd *netboxSD) writeCombined(cfg *config.Config, group *config.Group, targets []*discovery.Target) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if cfg.CombinedFile == "" {
+		return nil
+	}
+
+	data, err = sd.combined.update(group.File, targets, cfg.Format)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		slog.Debug("dry-run: not writing combined file", "file", cfg.CombinedFile)
+
+		return nil
+	}
+
+	_, err = writeFile(cfg.CombinedFile, data, config.DefaultFileMode, -1, -1)
+
+	return err
+}
@@ -0,0 +1,84 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCombinedTargets(t *testing.T) {
+	var (
+		combined combinedTargets
+		targetA  *discovery.Target = &discovery.Target{
+			Addresses:  []*netbox.IP{{Address: "10.0.0.1/24"}},
+			Labels:     model.LabelSet{"netbox_name": "device-A"},
+			SkipReason: discovery.StateActive,
+		}
+		targetB *discovery.Target = &discovery.Target{
+			Addresses:  []*netbox.IP{{Address: "10.0.0.2/24"}},
+			Labels:     model.LabelSet{"netbox_name": "device-B"},
+			SkipReason: discovery.StateActive,
+		}
+		skipped *discovery.Target = &discovery.Target{
+			Labels:     model.LabelSet{"netbox_name": "device-C"},
+			SkipReason: discovery.StateSkippedBadStatus,
+		}
+		data []byte
+		err  error
+	)
+
+	data, err = combined.update("b.yml", []*discovery.Target{targetB, skipped}, config.FormatYAML)
+	require.Nil(t, err)
+	assert.Equal(t, `- targets:
+    - 10.0.0.2
+  labels:
+    group: b.yml
+    netbox_name: device-B
+`, string(data))
+
+	// groups are ordered by file
+	data, err = combined.update("a.yml", []*discovery.Target{targetA}, config.FormatYAML)
+	require.Nil(t, err)
+	assert.Equal(t, `- targets:
+    - 10.0.0.1
+  labels:
+    group: a.yml
+    netbox_name: device-A
+- targets:
+    - 10.0.0.2
+  labels:
+    group: b.yml
+    netbox_name: device-B
+`, string(data))
+
+	// labels of the group's targets are not modified
+	assert.Equal(t, model.LabelSet{"netbox_name": "device-A"}, targetA.Labels)
+
+	combined.remove("a.yml")
+	data, err = combined.update("b.yml", nil, config.FormatJSON)
+	require.Nil(t, err)
+	assert.Equal(t, "[]\n", string(data))
+}
@@ -0,0 +1,119 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+)
+
+// getTargetsByConfigContext returns a list of target devices whose rendered config context matches the group's match.
+// VMs are added when the include_vms flag is set.
+func (sd *netboxSD) getTargetsByConfigContext(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
+	var (
+		err      error
+		devList  []*netbox.Device
+		vmList   []*netbox.Device
+		matching []*netbox.Device
+		dev      *netbox.Device
+		path     []string
+		value    string
+		hasValue bool
+	)
+
+	devList, err = sd.apiFor(group).GetDevicesWithConfigContext(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get devices with config context", "group", group.File, "error", err)
+		return nil, err
+	}
+
+	if *group.Flags.IncludeVMs {
+		vmList, err = sd.apiFor(group).GetVMsWithConfigContext(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to get vms with config context", "group", group.File, "error", err)
+			return nil, err
+		}
+
+		devList = append(devList, vmList...)
+	}
+
+	path, value, hasValue = group.ConfigContextMatch()
+
+	for _, dev = range devList {
+		if configContextMatches(dev.ConfigContext, path, value, hasValue) {
+			matching = append(matching, dev)
+		}
+	}
+
+	return sd.getTargetsByDevices(ctx, group, matching, nil), nil
+}
+
+// configContextMatches returns true when the key identified by path exists in ctx and its value equals value. When
+// hasValue is false, the key must exist and must neither be null nor false.
+func configContextMatches(ctx map[string]any, path []string, value string, hasValue bool) bool {
+	var (
+		current any = ctx
+		obj     map[string]any
+		ok      bool
+		key     string
+	)
+
+	for _, key = range path {
+		obj, ok = current.(map[string]any)
+		if !ok {
+			return false
+		}
+
+		current, ok = obj[key]
+		if !ok {
+			return false
+		}
+	}
+
+	if !hasValue {
+		return current != nil && current != false
+	}
+
+	return configContextString(current) == value
+}
+
+// configContextString returns the string representation of a config context value as used for matching.
+func configContextString(value any) string {
+	var data []byte
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return "null"
+	}
+
+	data, _ = json.Marshal(value)
+
+	return string(data)
+}
@@ -0,0 +1,55 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigContextMatches(t *testing.T) {
+	var ctx map[string]any = map[string]any{
+		"monitoring": map[string]any{
+			"enabled": true,
+			"port":    float64(9100),
+			"profile": "default",
+			"paused":  false,
+			"extra":   nil,
+			"modules": []any{"a", "b"},
+		},
+	}
+
+	assert.True(t, configContextMatches(ctx, []string{"monitoring", "enabled"}, "true", true))
+	assert.False(t, configContextMatches(ctx, []string{"monitoring", "enabled"}, "false", true))
+	assert.True(t, configContextMatches(ctx, []string{"monitoring", "port"}, "9100", true))
+	assert.True(t, configContextMatches(ctx, []string{"monitoring", "profile"}, "default", true))
+	assert.True(t, configContextMatches(ctx, []string{"monitoring", "modules"}, `["a","b"]`, true))
+	assert.True(t, configContextMatches(ctx, []string{"monitoring", "extra"}, "null", true))
+
+	// existence only
+	assert.True(t, configContextMatches(ctx, []string{"monitoring"}, "", false))
+	assert.True(t, configContextMatches(ctx, []string{"monitoring", "enabled"}, "", false))
+	assert.False(t, configContextMatches(ctx, []string{"monitoring", "paused"}, "", false))
+	assert.False(t, configContextMatches(ctx, []string{"monitoring", "extra"}, "", false))
+
+	// missing keys
+	assert.False(t, configContextMatches(ctx, []string{"monitoring", "missing"}, "", false))
+	assert.False(t, configContextMatches(ctx, []string{"monitoring", "port", "deeper"}, "", false))
+	assert.False(t, configContextMatches(nil, []string{"monitoring"}, "", false))
+}
@@ -0,0 +1,278 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the Kubernetes sink writing the targets of each group into a ConfigMap.
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+)
+
+const (
+	// serviceAccountDir contains the credentials of the pod's service account.
+	serviceAccountDir string = "/var/run/secrets/kubernetes.io/serviceaccount/"
+)
+
+var (
+	ErrConfigMapNotFound = errors.New("configmap not found")
+	ErrNotInCluster      = errors.New("not running in a Kubernetes cluster (KUBERNETES_SERVICE_HOST not set)")
+
+	// configMapKeyInvalid matches all characters not allowed in keys of ConfigMaps.
+	configMapKeyInvalid *regexp.Regexp = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+)
+
+// configMapClient writes the targets of groups into a Kubernetes ConfigMap.
+type configMapClient struct {
+	cfg *config.ConfigMap
+	// server is the URL of the Kubernetes API server.
+	server string
+	// namespace of the ConfigMap.
+	namespace string
+	// tokenFile contains the bearer token. It is read for each request as tokens are rotated.
+	tokenFile string
+	http      *http.Client
+	// mu protects written, which contains the value last written per key.
+	mu      sync.Mutex
+	written map[string]string
+}
+
+// configMap is the subset of a Kubernetes ConfigMap used by netbox_sd.
+type configMap struct {
+	APIVersion string             `json:"apiVersion,omitempty"`
+	Kind       string             `json:"kind,omitempty"`
+	Metadata   *configMapMetadata `json:"metadata,omitempty"`
+	// Data uses pointers so a merge patch can remove a key by setting it to null.
+	Data map[string]*string `json:"data"`
+}
+
+// configMapMetadata is the metadata of a ConfigMap.
+type configMapMetadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// newConfigMapClient returns a client for the ConfigMap described by cfg using the in-cluster config of the pod.
+func newConfigMapClient(cfg *config.ConfigMap) (*configMapClient, error) {
+	var (
+		host      string = os.Getenv("KUBERNETES_SERVICE_HOST")
+		port      string = os.Getenv("KUBERNETES_SERVICE_PORT")
+		namespace string = cfg.Namespace
+		ca        []byte
+		pool      *x509.CertPool = x509.NewCertPool()
+		data      []byte
+		err       error
+	)
+
+	if host == "" || port == "" {
+		return nil, ErrNotInCluster
+	}
+
+	ca, err = os.ReadFile(serviceAccountDir + "ca.crt")
+	if err != nil {
+		return nil, err
+	}
+
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in %sca.crt", serviceAccountDir)
+	}
+
+	if namespace == "" {
+		data, err = os.ReadFile(serviceAccountDir + "namespace")
+		if err != nil {
+			return nil, err
+		}
+
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	return &configMapClient{
+		cfg:       cfg,
+		server:    "https://" + net.JoinHostPort(host, port),
+		namespace: namespace,
+		tokenFile: serviceAccountDir + "token",
+		http: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		},
+		written: make(map[string]string),
+	}, nil
+}
+
+// configMapKey returns the data key of group, which is its file with all characters not allowed in keys replaced by
+// underscores.
+func configMapKey(group string) string {
+	return configMapKeyInvalid.ReplaceAllString(group, "_")
+}
+
+// put writes all active targets of group as JSON list of target groups into the data key of group. The ConfigMap is
+// created when it doesn't exist. The key isn't written again when its value didn't change.
+func (client *configMapClient) put(group string, targets []*discovery.Target) error {
+	var (
+		key    string = configMapKey(group)
+		active []*discovery.Target
+		target *discovery.Target
+		data   []byte
+		value  string
+		last   string
+		ok     bool
+		err    error
+	)
+
+	for _, target = range targets {
+		if !target.Graveyard {
+			active = append(active, target)
+		}
+	}
+
+	data, err = renderTargets(active, config.FormatJSON, false)
+	if err != nil {
+		return err
+	}
+
+	value = string(data)
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	last, ok = client.written[key]
+	if ok && last == value {
+		return nil
+	}
+
+	err = client.patch(map[string]*string{key: &value})
+	if errors.Is(err, ErrConfigMapNotFound) {
+		err = client.create(map[string]*string{key: &value})
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write key %s of configmap %s/%s: %w", key, client.namespace, client.cfg.Name, err)
+	}
+
+	client.written[key] = value
+
+	return nil
+}
+
+// delete removes the data key of group.
+func (client *configMapClient) delete(group string) error {
+	var (
+		key string = configMapKey(group)
+		err error
+	)
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	err = client.patch(map[string]*string{key: nil})
+	if err != nil && !errors.Is(err, ErrConfigMapNotFound) {
+		return fmt.Errorf("failed to delete key %s of configmap %s/%s: %w", key, client.namespace, client.cfg.Name, err)
+	}
+
+	delete(client.written, key)
+
+	return nil
+}
+
+// patch updates the given keys of the ConfigMap using a JSON merge patch. Keys with a nil value are removed.
+func (client *configMapClient) patch(data map[string]*string) error {
+	return client.do(http.MethodPatch,
+		fmt.Sprintf("/api/v1/namespaces/%s/configmaps/%s", client.namespace, client.cfg.Name),
+		"application/merge-patch+json",
+		&configMap{Data: data})
+}
+
+// create creates the ConfigMap with the given keys.
+func (client *configMapClient) create(data map[string]*string) error {
+	return client.do(http.MethodPost,
+		fmt.Sprintf("/api/v1/namespaces/%s/configmaps", client.namespace),
+		"application/json",
+		&configMap{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+			Metadata:   &configMapMetadata{Name: client.cfg.Name, Namespace: client.namespace},
+			Data:       data,
+		})
+}
+
+// do sends a single request with body encoded as JSON to the API server.
+func (client *configMapClient) do(method, path, contentType string, body any) error {
+	var (
+		req   *http.Request
+		resp  *http.Response
+		token []byte
+		data  []byte
+		err   error
+	)
+
+	data, err = json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err = http.NewRequest(method, client.server+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	token, err = os.ReadFile(client.tokenFile)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+
+	resp, err = client.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return nil
+	case http.StatusNotFound:
+		return ErrConfigMapNotFound
+	default:
+		return fmt.Errorf("unexpected status code %d from Kubernetes API: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+}
@@ -0,0 +1,164 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPIServer is a minimal Kubernetes API server serving a single ConfigMap.
+type fakeAPIServer struct {
+	mu      sync.Mutex
+	data    map[string]string
+	exists  bool
+	patches int
+}
+
+func (f *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		body map[string]any
+		data []byte
+		key  string
+		v    any
+	)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token-1" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	data, _ = io.ReadAll(r.Body)
+	json.Unmarshal(data, &body)
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/namespaces/monitoring/configmaps":
+		f.exists = true
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPatch && r.URL.Path == "/api/v1/namespaces/monitoring/configmaps/netbox-sd":
+		if !f.exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		f.patches++
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	for key, v = range body["data"].(map[string]any) {
+		if v == nil {
+			delete(f.data, key)
+			continue
+		}
+
+		f.data[key] = v.(string)
+	}
+}
+
+func TestConfigMapClient(t *testing.T) {
+	var (
+		fake    *fakeAPIServer = &fakeAPIServer{data: make(map[string]string)}
+		server  *httptest.Server
+		client  *configMapClient
+		groups  []*targetgroup.Group
+		targets []*discovery.Target = []*discovery.Target{
+			{
+				Addresses:  []*netbox.IP{{Address: "192.0.2.1/24"}},
+				Ports:      []int{9100},
+				Labels:     model.LabelSet{"netbox_name": "device-A"},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				// graveyard targets are not written
+				Addresses:  []*netbox.IP{{Address: "192.0.2.2/24"}},
+				Labels:     model.LabelSet{"netbox_name": "device-B"},
+				SkipReason: discovery.StateActive,
+				Graveyard:  true,
+			},
+		}
+		dir string = t.TempDir()
+	)
+
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "token"), []byte("token-1\n"), 0600))
+
+	server = httptest.NewServer(fake)
+	defer server.Close()
+
+	client = &configMapClient{
+		cfg:       &config.ConfigMap{Name: "netbox-sd"},
+		server:    server.URL,
+		namespace: "monitoring",
+		tokenFile: filepath.Join(dir, "token"),
+		http:      server.Client(),
+		written:   make(map[string]string),
+	}
+
+	// the configmap is created by the first put
+	require.Nil(t, client.put("nodes/node.yml", targets))
+	assert.True(t, fake.exists)
+	require.Contains(t, fake.data, "nodes_node.yml")
+	require.Nil(t, json.Unmarshal([]byte(fake.data["nodes_node.yml"]), &groups))
+	require.Len(t, groups, 1)
+	assert.Equal(t, model.LabelValue("192.0.2.1:9100"), groups[0].Targets[0][model.AddressLabel])
+
+	// unchanged values are not written again
+	require.Nil(t, client.put("nodes/node.yml", targets))
+	assert.Equal(t, 0, fake.patches)
+
+	require.Nil(t, client.put("snmp.yml", nil))
+	assert.Equal(t, 1, fake.patches)
+	assert.Len(t, fake.data, 2)
+
+	require.Nil(t, client.delete("nodes/node.yml"))
+	assert.Equal(t, map[string]string{"snmp.yml": "[]\n"}, fake.data)
+
+	// unauthorized requests fail
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "token"), []byte("token-2"), 0600))
+	assert.Error(t, client.put("nodes/node.yml", targets))
+}
+
+func TestConfigMapKey(t *testing.T) {
+	assert.Equal(t, "node.yml", configMapKey("node.yml"))
+	assert.Equal(t, "_etc_prometheus_sd_node-exporter.yml", configMapKey("/etc/prometheus/sd/node-exporter.yml"))
+	assert.Equal(t, "nodes_a_b_.json", configMapKey("nodes/a b$.json"))
+}
@@ -0,0 +1,303 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the Consul sink registering targets as services in the Consul catalog.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+
+	"github.com/prometheus/common/model"
+)
+
+// consulClient registers targets as services of an external node in the Consul catalog.
+type consulClient struct {
+	cfg  *config.Consul
+	http *http.Client
+}
+
+// consulService is a service in the Consul catalog.
+type consulService struct {
+	ID      string            `json:"ID"`
+	Service string            `json:"Service"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port"`
+	Meta    map[string]string `json:"Meta"`
+}
+
+// consulNodeServices is the response of Consul's node-services endpoint.
+type consulNodeServices struct {
+	Services []*consulService `json:"Services"`
+}
+
+// consulRegistration is the body of a catalog registration.
+type consulRegistration struct {
+	Datacenter     string            `json:"Datacenter,omitempty"`
+	Node           string            `json:"Node"`
+	Address        string            `json:"Address"`
+	NodeMeta       map[string]string `json:"NodeMeta"`
+	Service        *consulService    `json:"Service"`
+	SkipNodeUpdate bool              `json:"SkipNodeUpdate"`
+}
+
+// consulDeregistration is the body of a catalog deregistration.
+type consulDeregistration struct {
+	Datacenter string `json:"Datacenter,omitempty"`
+	Node       string `json:"Node"`
+	ServiceID  string `json:"ServiceID"`
+}
+
+// newConsulClient returns a client for the Consul agent described by cfg.
+func newConsulClient(cfg *config.Consul) *consulClient {
+	return &consulClient{
+		cfg:  cfg,
+		http: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// sync registers all active targets as instances of service and deregisters all instances of service registered before
+// that are not part of targets anymore. Instances that didn't change are not registered again.
+func (client *consulClient) sync(service string, targets []*discovery.Target) error {
+	var (
+		current  map[string]*consulService
+		desired  map[string]*consulService = consulServices(service, targets)
+		instance *consulService
+		id       string
+		ok       bool
+		err      error
+	)
+
+	current, err = client.services(service)
+	if err != nil {
+		return err
+	}
+
+	for id, instance = range desired {
+		if _, ok = current[id]; ok && consulServiceEqual(current[id], instance) {
+			continue
+		}
+
+		err = client.do(http.MethodPut, "/v1/catalog/register", &consulRegistration{
+			Datacenter: client.cfg.Datacenter,
+			Node:       client.cfg.Node,
+			Address:    client.cfg.Node,
+			// marks the node as external node, which isn't expected to run a Consul agent
+			NodeMeta:       map[string]string{"external-node": "true"},
+			Service:        instance,
+			SkipNodeUpdate: true,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to register consul service %s: %w", id, err)
+		}
+	}
+
+	for id = range current {
+		if _, ok = desired[id]; ok {
+			continue
+		}
+
+		err = client.do(http.MethodPut, "/v1/catalog/deregister", &consulDeregistration{
+			Datacenter: client.cfg.Datacenter,
+			Node:       client.cfg.Node,
+			ServiceID:  id,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to deregister consul service %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// cleanupConsul deregisters all instances of Consul services used by groups of old that are not used by any group of
+// cfg anymore.
+func (sd *netboxSD) cleanupConsul(old, cfg *config.Config) {
+	var (
+		group *config.Group
+		used  map[string]bool = make(map[string]bool)
+		err   error
+	)
+
+	if sd.consul == nil {
+		return
+	}
+
+	for _, group = range cfg.Groups {
+		used[group.ConsulService] = true
+	}
+
+	for _, group = range old.Groups {
+		if group.ConsulService == "" || used[group.ConsulService] {
+			continue
+		}
+
+		err = sd.consul.sync(group.ConsulService, nil)
+		if err != nil {
+			slog.Error("failed to deregister consul service", "group", group.File, "service", group.ConsulService, "error", err)
+		}
+	}
+}
+
+// services returns all instances of service registered for the node of client by id.
+func (client *consulClient) services(service string) (map[string]*consulService, error) {
+	var (
+		resp     consulNodeServices
+		result   map[string]*consulService = make(map[string]*consulService)
+		instance *consulService
+		err      error
+	)
+
+	err = client.do(http.MethodGet, "/v1/catalog/node-services/"+url.PathEscape(client.cfg.Node), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, instance = range resp.Services {
+		if instance.Service == service {
+			result[instance.ID] = instance
+		}
+	}
+
+	return result, nil
+}
+
+// do sends a request with body encoded as JSON to Consul and decodes the response into out unless out is nil. A 404
+// response is handled like an empty response (e.g. when the node doesn't exist yet).
+func (client *consulClient) do(method, path string, body, out any) error {
+	var (
+		req    *http.Request
+		resp   *http.Response
+		data   []byte
+		target string = client.cfg.Address + path
+		err    error
+	)
+
+	if body != nil {
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	if client.cfg.Datacenter != "" {
+		target += "?dc=" + url.QueryEscape(client.cfg.Datacenter)
+	}
+
+	req, err = http.NewRequest(method, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if client.cfg.Token != "" {
+		req.Header.Set("X-Consul-Token", client.cfg.Token)
+	}
+
+	resp, err = client.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from consul: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// consulServices returns the instances of service for all active targets by id. Each address of a target is a separate
+// instance with the target's labels as meta data.
+func consulServices(service string, targets []*discovery.Target) map[string]*consulService {
+	var (
+		result  map[string]*consulService = make(map[string]*consulService)
+		group   *discovery.Target
+		address model.LabelSet
+		meta    map[string]string
+		name    model.LabelName
+		id      string
+		host    string
+		port    string
+		number  int
+		err     error
+	)
+
+	for _, group = range targets {
+		if group.Skipped() || group.Graveyard {
+			continue
+		}
+
+		meta = make(map[string]string, len(group.Labels))
+		for name = range group.Labels {
+			meta[string(name)] = string(group.Labels[name])
+		}
+
+		for _, address = range group.TargetGroup().Targets {
+			id = service + "-" + string(address[model.AddressLabel])
+
+			// addresses without port are registered with port 0
+			host, port, err = net.SplitHostPort(string(address[model.AddressLabel]))
+			if err != nil {
+				host = string(address[model.AddressLabel])
+				port = "0"
+			}
+
+			number, _ = strconv.Atoi(port)
+
+			result[id] = &consulService{
+				ID:      id,
+				Service: service,
+				Address: host,
+				Port:    number,
+				Meta:    meta,
+			}
+		}
+	}
+
+	return result
+}
+
+// consulServiceEqual returns true when the registrations of a and b are the same.
+func consulServiceEqual(a, b *consulService) bool {
+	return a.Address == b.Address && a.Port == b.Port && maps.Equal(a.Meta, b.Meta)
+}
@@ -0,0 +1,143 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConsul is a minimal Consul catalog for a single node.
+type fakeConsul struct {
+	mu            sync.Mutex
+	services      map[string]*consulService
+	registrations int
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		reg   consulRegistration
+		dereg consulDeregistration
+		resp  consulNodeServices
+	)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("X-Consul-Token") != "secret" || r.URL.Query().Get("dc") != "dc1" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/catalog/node-services/netbox_sd":
+		if len(f.services) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		for _, service := range f.services {
+			resp.Services = append(resp.Services, service)
+		}
+
+		json.NewEncoder(w).Encode(resp)
+	case "/v1/catalog/register":
+		json.NewDecoder(r.Body).Decode(&reg)
+		f.services[reg.Service.ID] = reg.Service
+		f.registrations++
+	case "/v1/catalog/deregister":
+		json.NewDecoder(r.Body).Decode(&dereg)
+		delete(f.services, dereg.ServiceID)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestConsulSync(t *testing.T) {
+	var (
+		fake    *fakeConsul = &fakeConsul{services: map[string]*consulService{}}
+		server  *httptest.Server
+		client  *consulClient
+		targets []*discovery.Target = []*discovery.Target{
+			{
+				Addresses:  []*netbox.IP{{Address: "192.0.2.1/24"}, {Address: "2001:db8::1/64"}},
+				Ports:      []int{9100},
+				Labels:     model.LabelSet{"netbox_name": "device-A"},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				Addresses:  []*netbox.IP{{Address: "192.0.2.2/24"}},
+				Labels:     model.LabelSet{"netbox_name": "device-B"},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				Labels:     model.LabelSet{"netbox_name": "device-C"},
+				SkipReason: discovery.StateSkippedBadStatus,
+			},
+		}
+	)
+
+	server = httptest.NewServer(fake)
+	defer server.Close()
+
+	client = newConsulClient(&config.Consul{Address: server.URL, Token: "secret", Datacenter: "dc1", Node: "netbox_sd"})
+
+	// another service of the node is never touched
+	fake.services["other"] = &consulService{ID: "other", Service: "other"}
+
+	require.Nil(t, client.sync("node", targets))
+	assert.Len(t, fake.services, 4)
+	assert.Equal(t, &consulService{
+		ID:      "node-[2001:db8::1]:9100",
+		Service: "node",
+		Address: "2001:db8::1",
+		Port:    9100,
+		Meta:    map[string]string{"netbox_name": "device-A"},
+	}, fake.services["node-[2001:db8::1]:9100"])
+	assert.Equal(t, 0, fake.services["node-192.0.2.2"].Port)
+	assert.Equal(t, 3, fake.registrations)
+
+	// unchanged instances are not registered again
+	targets[1].Labels = model.LabelSet{"netbox_name": "device-B", "foo": "bar"}
+	require.Nil(t, client.sync("node", targets[1:]))
+	assert.Len(t, fake.services, 2)
+	assert.Equal(t, "bar", fake.services["node-192.0.2.2"].Meta["foo"])
+	assert.Equal(t, 4, fake.registrations)
+
+	require.Nil(t, client.sync("node", targets[1:]))
+	assert.Equal(t, 4, fake.registrations)
+
+	require.Nil(t, client.sync("node", nil))
+	assert.Len(t, fake.services, 1)
+	assert.Contains(t, fake.services, "other")
+
+	// errors are returned
+	client.cfg.Token = "wrong"
+	assert.Error(t, client.sync("node", targets))
+}
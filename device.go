@@ -18,57 +18,243 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 
 	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
 	"github.com/4xoc/netbox_sd/pkg/netbox"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
-	"github.com/prometheus/prometheus/discovery/targetgroup"
 )
 
-// GetTargetsByDeviceTag returns a list of of target devices that match a given device tag.
-func (sd *netboxSD) getTargetsByDeviceTag(group *config.Group) ([]*targetgroup.Group, error) {
+// GetTargetsByDeviceTag returns a list of of target devices that match the group's tag expression. Devices (and VMs
+// when enabled) of all query tags are fetched with a single batch of queries.
+func (sd *netboxSD) getTargetsByDeviceTag(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
 	var (
-		err         error
-		dev         *netbox.Device
-		dynLabels   model.LabelSet
-		data        []*targetgroup.Group = make([]*targetgroup.Group, 0)
-		target      *targetgroup.Group
-		selectedIPs []*netbox.IP
-		devList     []*netbox.Device
-		vmList      []*netbox.Device
-		cfLabels    model.LabelSet
+		err       error
+		filter    *netbox.DeviceFilter = group.DeviceFilter()
+		queryTags []string
+		tag       string
+		queries   []netbox.DeviceQuery
+		lists     [][]*netbox.Device
+		devLists  [][]*netbox.Device
+		vmLists   [][]*netbox.Device
+		devList   []*netbox.Device
+		i         int
+	)
+
+	// validated when reading the config
+	queryTags, _ = group.TagExpr.QueryTags()
+
+	for _, tag = range queryTags {
+		queries = append(queries, netbox.DeviceQuery{Tag: tag, Filter: filter})
+
+		// Adding VMs with that tag here when flags are properly set.
+		if *group.Flags.IncludeVMs {
+			queries = append(queries, netbox.DeviceQuery{Virtual: true, Tag: tag, Filter: filter})
+		}
+	}
+
+	lists, err = sd.apiFor(group).GetDevicesBatch(ctx, queries)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get devices by tag", "group", group.File, "error", err)
+		return nil, err
+	}
+
+	for i = range queries {
+		if queries[i].Virtual {
+			vmLists = append(vmLists, lists[i])
+		} else {
+			devLists = append(devLists, lists[i])
+		}
+	}
+
+	// devices and VMs are matched separately as their IDs overlap
+	devList = matchTagExpr(group.TagExpr, devLists, deviceID, deviceTags)
+	devList = append(devList, matchTagExpr(group.TagExpr, vmLists, deviceID, deviceTags)...)
+
+	return sd.getTargetsByDevices(ctx, group, devList, nil), nil
+}
+
+// getTargetsByCluster returns a list of target VMs that are part of the cluster given by the group's match.
+func (sd *netboxSD) getTargetsByCluster(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
+	var (
+		err    error
+		vmList []*netbox.Device
+	)
+
+	vmList, err = sd.apiFor(group).GetVMsByCluster(ctx, group.Match.Value)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get vms by cluster", "group", group.File, "error", err)
+		return nil, err
+	}
+
+	return sd.getTargetsByDevices(ctx, group, vmList, nil), nil
+}
+
+// getTargetsByClusterGroup returns a list of target VMs that are part of any cluster in the cluster group given by the
+// group's match.
+func (sd *netboxSD) getTargetsByClusterGroup(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
+	var (
+		err    error
+		vmList []*netbox.Device
+	)
+
+	vmList, err = sd.apiFor(group).GetVMsByClusterGroup(ctx, group.Match.Value)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get vms by cluster group", "group", group.File, "error", err)
+		return nil, err
+	}
+
+	return sd.getTargetsByDevices(ctx, group, vmList, nil), nil
+}
+
+// getTargetsByManufacturer returns a list of target devices made by the manufacturer given by the group's match.
+func (sd *netboxSD) getTargetsByManufacturer(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
+	var (
+		err     error
+		devList []*netbox.Device
+	)
+
+	devList, err = sd.apiFor(group).GetDevicesByManufacturer(ctx, group.Match.Value)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get devices by manufacturer", "group", group.File, "error", err)
+		return nil, err
+	}
+
+	return sd.getTargetsByDevices(ctx, group, devList, nil), nil
+}
+
+// getTargetsBySiteGroup returns a list of target devices located at any site within the site group given by the
+// group's match. VMs are added when the include_vms flag is set.
+func (sd *netboxSD) getTargetsBySiteGroup(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
+	var (
+		err     error
+		devList []*netbox.Device
+		vmList  []*netbox.Device
+	)
+
+	devList, err = sd.apiFor(group).GetDevicesBySiteGroup(ctx, group.Match.Value)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get devices by site group", "group", group.File, "error", err)
+		return nil, err
+	}
+
+	if *group.Flags.IncludeVMs {
+		vmList, err = sd.apiFor(group).GetVMsBySiteGroup(ctx, group.Match.Value)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to get vms by site group", "group", group.File, "error", err)
+			return nil, err
+		}
+
+		devList = append(devList, vmList...)
+	}
+
+	return sd.getTargetsByDevices(ctx, group, devList, nil), nil
+}
+
+// getTargetsByAll returns a list of all devices and/or VMs, depending on the group's match. Only active devices with a
+// primary IP result in a target. Devices are iterated over instead of fetched as a list as this group type usually
+// selects a large part of Netbox's inventory.
+func (sd *netboxSD) getTargetsByAll(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
+	var (
+		err     error
+		data    []*discovery.Target = make([]*discovery.Target, 0)
+		collect func(*netbox.Device) error
+	)
+
+	collect = func(dev *netbox.Device) error {
+		data = append(data, sd.getTargetsByDevices(ctx, group, []*netbox.Device{dev}, nil)...)
+		return nil
+	}
+
+	if group.Match.Value != config.AllMatchVMs {
+		err = sd.apiFor(group).ForEachDevice(ctx, netbox.DeviceQuery{}, collect)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to get all devices", "group", group.File, "error", err)
+			return nil, err
+		}
+	}
+
+	if group.Match.Value != config.AllMatchDevices {
+		err = sd.apiFor(group).ForEachDevice(ctx, netbox.DeviceQuery{Virtual: true}, collect)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to get all vms", "group", group.File, "error", err)
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// getTargetsByRESTQuery returns a list of target devices matching the REST API query parameters given by the group's
+// match. VMs are added when the include_vms flag is set.
+func (sd *netboxSD) getTargetsByRESTQuery(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
+	var (
+		err     error
+		devList []*netbox.Device
+		vmList  []*netbox.Device
 	)
 
-	devList, err = sd.api.GetDevicesByTag(group.Match)
+	devList, err = sd.apiFor(group).GetDevicesByQuery(ctx, group.Match.Value)
 	if err != nil {
-		log.Printf("failed to get devices by tag")
+		slog.ErrorContext(ctx, "failed to get devices by rest query", "group", group.File, "error", err)
 		return nil, err
 	}
 
-	// Adding VMs with that tag here when flags are properly set.
 	if *group.Flags.IncludeVMs {
-		vmList, err = sd.api.GetVMsByTag(group.Match)
+		vmList, err = sd.apiFor(group).GetVMsByQuery(ctx, group.Match.Value)
 		if err != nil {
-			log.Printf("failed to get vms by tag")
+			slog.ErrorContext(ctx, "failed to get vms by rest query", "group", group.File, "error", err)
 			return nil, err
 		}
 
 		devList = append(devList, vmList...)
 	}
 
+	return sd.getTargetsByDevices(ctx, group, devList, nil), nil
+}
+
+// getTargetsByDevices returns a target for each device (or VM) in devList using its primary addresses. When extraLabels
+// is not nil, the labels it returns for a device are added before the group's labels.
+func (sd *netboxSD) getTargetsByDevices(ctx context.Context, group *config.Group, devList []*netbox.Device, extraLabels func(*netbox.Device) model.LabelSet) []*discovery.Target {
+	var (
+		err         error
+		dev         *netbox.Device
+		dynLabels   model.LabelSet
+		tmplLabels  model.LabelSet
+		data        []*discovery.Target = make([]*discovery.Target, 0)
+		target      *discovery.Target
+		selectedIPs []*netbox.IP
+		cfLabels    model.LabelSet
+		scheme      string
+	)
+
 	for _, dev = range devList {
 
+		// devices not matching the group's name_match are not part of the group
+		if !group.NameMatches(dev.Name) {
+			continue
+		}
+
 		// reset
-		target = new(targetgroup.Group)
+		dynLabels = nil
+		target = &discovery.Target{
+			Device: dev,
+		}
+		data = append(data, target)
 
-		// check for active device
+		// check for active device; devices in one of the graveyard statuses are processed too
 		if dev.Status != netbox.StatusDeviceActive {
-			log.Printf("device %s is not marked as active...skipping device", dev.Name)
-			SetTargetStatusMetric(group.File, dev, TargetSkippedBadStatus)
-			continue
+			if !group.InGraveyard(dev.Status) {
+				slog.DebugContext(ctx, "device is not marked as active, skipping device", "group", group.File, "device", dev.Name)
+				target.SkipReason = discovery.StateSkippedBadStatus
+				continue
+			}
+
+			target.Graveyard = true
 		}
 
 		target.Labels = model.LabelSet{
@@ -83,10 +269,11 @@ func (sd *netboxSD) getTargetsByDeviceTag(group *config.Group) ([]*targetgroup.G
 		}
 
 		// custom fields
-		cfLabels, err = generateCustomFieldLabels(dev.CustomFields)
+		cfLabels, err = generateCustomFieldLabels(dev.CustomFields, group.CustomFieldAllowed)
 		if err != nil {
-			log.Printf("failed to parse custom fields for device %s...skipping device", dev.Name)
-			SetTargetStatusMetric(group.File, dev, TargetSkippedBadCustomField)
+			slog.WarnContext(ctx, "failed to parse custom fields, skipping device", "group", group.File, "device", dev.Name,
+				"error", err)
+			target.SkipReason = discovery.StateSkippedBadCustomField
 			continue
 		}
 
@@ -99,14 +286,40 @@ func (sd *netboxSD) getTargetsByDeviceTag(group *config.Group) ([]*targetgroup.G
 		}
 
 		target.Labels = target.Labels.Merge(dynLabels)
-		target.Source = "netbox_sd"
+
+		if extraLabels != nil {
+			target.Labels = target.Labels.Merge(extraLabels(dev))
+		}
+
+		tmplLabels, err = templateLabels(group, dev, target.Labels)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to execute label templates, skipping device", "group", group.File, "device", dev.Name,
+				"error", err)
+			target.SkipReason = discovery.StateSkippedOther
+			continue
+		}
 
 		// add additional labels
-		target.Labels = target.Labels.Merge(group.Labels)
+		target.Labels = target.Labels.Merge(group.Labels).Merge(tmplLabels)
+
+		if target.Graveyard {
+			target.Labels = target.Labels.Merge(graveyardLabels(group, dev))
+		}
+
+		scheme = group.SchemeFor(target.Labels)
+		if scheme != "" {
+			target.Labels[model.SchemeLabel] = model.LabelValue(scheme)
+		}
 
-		if !group.FiltersMatch(target) {
-			log.Printf("device %s doesn't match applied filters...skipping device", dev.Name)
-			SetTargetStatusMetric(group.File, dev, TargetSkippedNotMatchingFilters)
+		if group.ExcludedByTags(tagSlugs(dev.Tags)) {
+			slog.DebugContext(ctx, "device carries an excluded tag, skipping device", "group", group.File, "device", dev.Name)
+			target.SkipReason = discovery.StateSkippedNotMatchingFilters
+			continue
+		}
+
+		if !group.FiltersMatch(target.Labels) {
+			slog.DebugContext(ctx, "device doesn't match applied filters, skipping device", "group", group.File, "device", dev.Name)
+			target.SkipReason = discovery.StateSkippedNotMatchingFilters
 			continue
 		}
 
@@ -115,16 +328,22 @@ func (sd *netboxSD) getTargetsByDeviceTag(group *config.Group) ([]*targetgroup.G
 
 		// When there are no selectedIPs this target cannot be used.
 		if len(selectedIPs) == 0 {
-			SetTargetStatusMetric(group.File, dev, TargetSkippedNoValidIP)
+			target.SkipReason = discovery.StateSkippedNoValidIP
 			continue
 		}
 
-		target.Targets = convertToTargets(selectedIPs, group.Port)
+		target.Addresses = filterAddr(selectedIPs, group)
+		target.AddressesFiltered = len(selectedIPs) - len(target.Addresses)
 
-		SetTargetStatusMetric(group.File, dev, TargetActive)
+		if len(target.Addresses) == 0 {
+			slog.InfoContext(ctx, "no address of device matches address filters, skipping device", "group", group.File,
+				"device", dev.Name)
+			target.SkipReason = discovery.StateSkippedNoMatchingAddress
+			continue
+		}
 
-		// add target to list
-		data = append(data, target)
+		target.Ports = portList(group.PortFor(target.Labels))
+		target.SkipReason = discovery.StateActive
 
 		// set prom metric
 		promIPSkipped.
@@ -134,5 +353,5 @@ func (sd *netboxSD) getTargetsByDeviceTag(group *config.Group) ([]*targetgroup.G
 			}).Set(float64(len([]*netbox.IP{dev.PrimaryIP6, dev.PrimaryIP4}) - len(selectedIPs)))
 	}
 
-	return data, nil
+	return data
 }
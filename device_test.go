@@ -0,0 +1,81 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+	"github.com/4xoc/netbox_sd/pkg/netbox/netboxtest"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTargetsByDevices(t *testing.T) {
+	var (
+		sd       netboxSD
+		fixtures *netboxtest.Fixtures
+		server   *netboxtest.Server
+		vms      []*netbox.Device
+		devs     []*netbox.Device
+		targets  []*discovery.Target
+		target   *discovery.Target
+		err      error
+	)
+
+	fixtures, err = netboxtest.LoadFixtures("testdata/fixtures/example/netbox.yml")
+	require.Nil(t, err)
+
+	server = netboxtest.NewServer(fixtures)
+	defer server.Close()
+
+	sd.cfg, err = config.ReadConfigFile("testdata/fixtures/example/config.yml")
+	require.Nil(t, err)
+
+	sd.api, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netbox_sd_test"))
+	require.Nil(t, err)
+
+	vms, err = sd.api.GetVMs(context.Background())
+	require.Nil(t, err)
+	require.NotEmpty(t, vms)
+
+	devs, err = sd.api.GetDevices(context.Background())
+	require.Nil(t, err)
+	require.NotEmpty(t, devs)
+
+	// devices following a vm must not inherit its labels
+	targets = sd.getTargetsByDevices(context.Background(), sd.cfg.Groups[0], append(vms, devs...), nil)
+	require.Len(t, targets, len(vms)+len(devs))
+
+	for _, target = range targets {
+		if target.Labels == nil {
+			continue
+		}
+
+		if target.Device.IsVirtual() {
+			assert.Equal(t, "true", string(target.Labels["is_vm"]), target.Device.Name)
+		} else {
+			assert.NotContains(t, target.Labels, model.LabelName("is_vm"), target.Device.Name)
+		}
+	}
+}
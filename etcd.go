@@ -0,0 +1,216 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the etcd sink writing the targets of each group into a key.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+)
+
+// etcdClient writes the targets of groups into etcd using its JSON gateway (/v3).
+type etcdClient struct {
+	cfg  *config.Etcd
+	http *http.Client
+	// mu protects written, which contains the value last written per key.
+	mu      sync.Mutex
+	written map[string][]byte
+}
+
+// etcdPutRequest is the body of a put request. Keys and values are base64 encoded by encoding/json.
+type etcdPutRequest struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// etcdDeleteRequest is the body of a delete range request.
+type etcdDeleteRequest struct {
+	Key []byte `json:"key"`
+}
+
+// etcdAuthRequest is the body of an authenticate request.
+type etcdAuthRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+// etcdAuthResponse is the response of an authenticate request.
+type etcdAuthResponse struct {
+	Token string `json:"token"`
+}
+
+// newEtcdClient returns a client for the etcd cluster described by cfg.
+func newEtcdClient(cfg *config.Etcd) *etcdClient {
+	return &etcdClient{
+		cfg:     cfg,
+		http:    &http.Client{Timeout: 30 * time.Second},
+		written: make(map[string][]byte),
+	}
+}
+
+// put writes all active targets of group as JSON list of target groups into the key of group. The key isn't written
+// again when its value didn't change.
+func (client *etcdClient) put(group string, targets []*discovery.Target) error {
+	var (
+		key    string = client.cfg.Prefix + group
+		active []*discovery.Target
+		target *discovery.Target
+		data   []byte
+		err    error
+	)
+
+	for _, target = range targets {
+		if !target.Graveyard {
+			active = append(active, target)
+		}
+	}
+
+	data, err = renderTargets(active, config.FormatJSON, false)
+	if err != nil {
+		return err
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if bytes.Equal(client.written[key], data) {
+		return nil
+	}
+
+	err = client.do("/v3/kv/put", &etcdPutRequest{Key: []byte(key), Value: data}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to put key %s: %w", key, err)
+	}
+
+	client.written[key] = data
+
+	return nil
+}
+
+// delete removes the key of group.
+func (client *etcdClient) delete(group string) error {
+	var (
+		key string = client.cfg.Prefix + group
+		err error
+	)
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	err = client.do("/v3/kv/deleterange", &etcdDeleteRequest{Key: []byte(key)}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete key %s: %w", key, err)
+	}
+
+	delete(client.written, key)
+
+	return nil
+}
+
+// do sends a request with body encoded as JSON to the first endpoint answering it and decodes the response into out
+// unless out is nil. Requests are authenticated when a username is configured.
+func (client *etcdClient) do(path string, body, out any) error {
+	var (
+		endpoint string
+		token    string
+		auth     etcdAuthResponse
+		errs     []error
+		err      error
+	)
+
+	for _, endpoint = range client.cfg.Endpoints {
+		if client.cfg.Username != "" {
+			err = client.post(endpoint, "/v3/auth/authenticate", "", &etcdAuthRequest{
+				Name:     client.cfg.Username,
+				Password: client.cfg.Password,
+			}, &auth)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+				continue
+			}
+
+			token = auth.Token
+		}
+
+		err = client.post(endpoint, path, token, body, out)
+		if err == nil {
+			return nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// post sends a single request to endpoint.
+func (client *etcdClient) post(endpoint, path, token string, body, out any) error {
+	var (
+		req  *http.Request
+		resp *http.Response
+		data []byte
+		err  error
+	)
+
+	data, err = json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err = http.NewRequest(http.MethodPost, endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err = client.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from etcd: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,133 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEtcd is a minimal etcd JSON gateway.
+type fakeEtcd struct {
+	mu   sync.Mutex
+	keys map[string][]byte
+	puts int
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		auth  etcdAuthRequest
+		put   etcdPutRequest
+		del   etcdDeleteRequest
+		token string = "token-1"
+	)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v3/auth/authenticate":
+		json.NewDecoder(r.Body).Decode(&auth)
+		if auth.Name != "netbox_sd" || auth.Password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		json.NewEncoder(w).Encode(etcdAuthResponse{Token: token})
+
+		return
+	case "/v3/kv/put":
+		json.NewDecoder(r.Body).Decode(&put)
+		f.keys[string(put.Key)] = put.Value
+		f.puts++
+	case "/v3/kv/deleterange":
+		json.NewDecoder(r.Body).Decode(&del)
+		delete(f.keys, string(del.Key))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if r.Header.Get("Authorization") != token {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+}
+
+func TestEtcdClient(t *testing.T) {
+	var (
+		fake    *fakeEtcd = &fakeEtcd{keys: make(map[string][]byte)}
+		server  *httptest.Server
+		client  *etcdClient
+		groups  []*targetgroup.Group
+		targets []*discovery.Target = []*discovery.Target{
+			{
+				Addresses:  []*netbox.IP{{Address: "192.0.2.1/24"}},
+				Ports:      []int{9100},
+				Labels:     model.LabelSet{"netbox_name": "device-A"},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				// graveyard targets are not written
+				Addresses:  []*netbox.IP{{Address: "192.0.2.2/24"}},
+				Labels:     model.LabelSet{"netbox_name": "device-B"},
+				SkipReason: discovery.StateActive,
+				Graveyard:  true,
+			},
+		}
+	)
+
+	server = httptest.NewServer(fake)
+	defer server.Close()
+
+	// unreachable endpoints are skipped
+	client = newEtcdClient(&config.Etcd{
+		Endpoints: []string{"http://127.0.0.1:1", server.URL},
+		Prefix:    "/netbox_sd/",
+		Username:  "netbox_sd",
+		Password:  "secret",
+	})
+
+	require.Nil(t, client.put("node.yml", targets))
+	require.Contains(t, fake.keys, "/netbox_sd/node.yml")
+	require.Nil(t, json.Unmarshal(fake.keys["/netbox_sd/node.yml"], &groups))
+	require.Len(t, groups, 1)
+	assert.Equal(t, model.LabelValue("192.0.2.1:9100"), groups[0].Targets[0][model.AddressLabel])
+
+	// unchanged values are not written again
+	require.Nil(t, client.put("node.yml", targets))
+	assert.Equal(t, 1, fake.puts)
+
+	require.Nil(t, client.delete("node.yml"))
+	assert.Empty(t, fake.keys)
+
+	// errors of all endpoints are returned
+	client.cfg.Password = "wrong"
+	assert.Error(t, client.put("node.yml", nil))
+}
@@ -0,0 +1,203 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the manifest listing all files written by netbox_sd with their checksums.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+)
+
+// fileManifest keeps the files written by all groups for the manifest file.
+type fileManifest struct {
+	mu sync.Mutex
+	// groups contains the files written by each group.
+	groups map[string][]string
+	// entries contains the last known state of each file.
+	entries map[string]*manifestEntry
+}
+
+// fileManifestContent is the content of the manifest file.
+type fileManifestContent struct {
+	Files []*manifestEntry `json:"files"`
+}
+
+// manifestEntry describes a single file of the manifest.
+type manifestEntry struct {
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	// Updated is the modification time of the file, which isn't changed by netbox_sd while its content is unchanged.
+	Updated time.Time `json:"updated"`
+}
+
+// groupFiles returns all files written by a scan of group: its target files (by name in files) as well as the combined,
+// DNS zone and Icinga files.
+func groupFiles(cfg *config.Config, files map[string][]byte) []string {
+	var (
+		paths []string
+		file  string
+	)
+
+	for file = range files {
+		paths = append(paths, file)
+	}
+
+	if cfg.CombinedFile != "" {
+		paths = append(paths, cfg.CombinedFile)
+	}
+
+	if cfg.DNSZone != nil {
+		paths = append(paths, cfg.DNSZone.File)
+	}
+
+	if cfg.Icinga != nil {
+		paths = append(paths, cfg.Icinga.File)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// update sets the files written by group, computes their checksums and writes the manifest to file.
+func (m *fileManifest) update(file, group string, paths []string) error {
+	var (
+		path  string
+		entry *manifestEntry
+		err   error
+	)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.groups == nil {
+		m.groups = make(map[string][]string)
+		m.entries = make(map[string]*manifestEntry)
+	}
+
+	for _, path = range paths {
+		entry, err = newManifestEntry(path)
+		if err != nil {
+			return err
+		}
+
+		m.entries[path] = entry
+	}
+
+	m.groups[group] = paths
+
+	return m.write(file)
+}
+
+// remove removes the files of group from the manifest and writes it to file. Files also written by other groups (like
+// the combined file) are kept.
+func (m *fileManifest) remove(file, group string) error {
+	var ok bool
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok = m.groups[group]; !ok {
+		return nil
+	}
+
+	delete(m.groups, group)
+
+	return m.write(file)
+}
+
+// write renders all files of all groups into file. m.mu must be held.
+func (m *fileManifest) write(file string) error {
+	var (
+		content fileManifestContent = fileManifestContent{Files: []*manifestEntry{}}
+		seen    map[string]bool     = make(map[string]bool)
+		paths   []string
+		path    string
+		data    []byte
+		err     error
+	)
+
+	for _, paths = range m.groups {
+		for _, path = range paths {
+			if seen[path] {
+				continue
+			}
+
+			seen[path] = true
+			content.Files = append(content.Files, m.entries[path])
+		}
+	}
+
+	// entries of files no longer written by any group are dropped
+	for path = range m.entries {
+		if !seen[path] {
+			delete(m.entries, path)
+		}
+	}
+
+	sort.Slice(content.Files, func(i, j int) bool {
+		return content.Files[i].File < content.Files[j].File
+	})
+
+	data, err = json.MarshalIndent(&content, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = writeFile(file, append(data, '\n'), config.DefaultFileMode, -1, -1)
+
+	return err
+}
+
+// newManifestEntry returns the manifest entry of the file at path.
+func newManifestEntry(path string) (*manifestEntry, error) {
+	var (
+		data []byte
+		info os.FileInfo
+		sum  [sha256.Size]byte
+		err  error
+	)
+
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err = os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sum = sha256.Sum256(data)
+
+	return &manifestEntry{
+		File:    path,
+		SHA256:  hex.EncodeToString(sum[:]),
+		Size:    info.Size(),
+		Updated: info.ModTime().UTC(),
+	}, nil
+}
@@ -0,0 +1,76 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileManifest(t *testing.T) {
+	var (
+		dir      string         = t.TempDir()
+		file     string         = filepath.Join(dir, "manifest.json")
+		node     string         = filepath.Join(dir, "node.yml")
+		snmp     string         = filepath.Join(dir, "snmp.yml")
+		combined string         = filepath.Join(dir, "combined.yml")
+		cfg      *config.Config = &config.Config{CombinedFile: combined}
+		m        fileManifest
+		content  fileManifestContent
+		data     []byte
+	)
+
+	require.Nil(t, os.WriteFile(node, []byte("node"), 0644))
+	require.Nil(t, os.WriteFile(snmp, []byte("snmp"), 0644))
+	require.Nil(t, os.WriteFile(combined, []byte("combined"), 0644))
+
+	assert.Equal(t, []string{combined, node}, groupFiles(cfg, map[string][]byte{node: nil}))
+
+	require.Nil(t, m.update(file, "node.yml", groupFiles(cfg, map[string][]byte{node: nil})))
+	require.Nil(t, m.update(file, "snmp.yml", groupFiles(cfg, map[string][]byte{snmp: nil})))
+
+	data, _ = os.ReadFile(file)
+	require.Nil(t, json.Unmarshal(data, &content))
+	require.Len(t, content.Files, 3)
+	assert.Equal(t, combined, content.Files[0].File)
+	assert.Equal(t, node, content.Files[1].File)
+	// sha256 of "node"
+	assert.Equal(t, "545ea538461003efdc8c81c244531b003f6f26cfccf6c0073b3239fdedf49446", content.Files[1].SHA256)
+	assert.Equal(t, int64(4), content.Files[1].Size)
+	assert.False(t, content.Files[1].Updated.IsZero())
+
+	// the combined file is kept as it's still written by snmp.yml
+	require.Nil(t, m.remove(file, "node.yml"))
+
+	data, _ = os.ReadFile(file)
+	content = fileManifestContent{}
+	require.Nil(t, json.Unmarshal(data, &content))
+	require.Len(t, content.Files, 2)
+	assert.Equal(t, combined, content.Files[0].File)
+	assert.Equal(t, snmp, content.Files[1].File)
+
+	// missing files fail
+	assert.Error(t, m.update(file, "node.yml", []string{filepath.Join(dir, "missing.yml")}))
+}
@@ -0,0 +1,231 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+)
+
+// backupTimeFormat is the format of the timestamp appended to the name of backups of target files.
+const backupTimeFormat = "20060102T150405.000Z"
+
+var (
+	ErrNoBackup = errors.New("no backup found")
+)
+
+// writeTargetFile writes data to file using the file mode and owner configured for group. The mode is set explicitly
+// as os.WriteFile only applies it to new files (and subject to the umask). When file already has the content of data,
+// it isn't written again (keeping its mtime so Prometheus doesn't reload it) and false is returned. When the group
+// keeps backups, the current content of file is backed up before it's overwritten.
+func writeTargetFile(group *config.Group, file string, data []byte) (bool, error) {
+	var err error
+
+	if group.Backups > 0 {
+		err = backupFile(file, data, group.Backups, time.Now())
+		if err != nil {
+			return false, fmt.Errorf("failed to back up file: %w", err)
+		}
+	}
+
+	return writeFile(file, data, group.FileMode, group.UID, group.GID)
+}
+
+// backupFile copies file to <file>.<timestamp of now> unless it doesn't exist or already has the content of data. Only
+// the newest keep backups are kept.
+func backupFile(file string, data []byte, keep int, now time.Time) error {
+	var (
+		current []byte
+		info    os.FileInfo
+		backups []string
+		err     error
+	)
+
+	current, err = os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && bytes.Equal(current, data)) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	info, err = os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(file+"."+now.UTC().Format(backupTimeFormat), current, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	backups, err = listBackups(file)
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > keep {
+		err = os.Remove(backups[0])
+		if err != nil {
+			return err
+		}
+
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// listBackups returns all backups of file ordered from oldest to newest.
+func listBackups(file string) ([]string, error) {
+	var (
+		matches []string
+		backups []string
+		match   string
+		err     error
+	)
+
+	matches, err = filepath.Glob(file + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, match = range matches {
+		if _, err = time.Parse(backupTimeFormat, strings.TrimPrefix(match, file+".")); err == nil {
+			backups = append(backups, match)
+		}
+	}
+
+	// the timestamp format sorts chronologically
+	sort.Strings(backups)
+
+	return backups, nil
+}
+
+// rollbackGroup restores the newest backup of the file (and graveyard file) of the group of cfg with the given file.
+// The restored backup is removed, thus rolling back again restores the backup before it.
+func rollbackGroup(cfg *config.Config, name string) error {
+	var (
+		group    *config.Group
+		current  *config.Group
+		files    []string
+		file     string
+		backups  []string
+		data     []byte
+		restored bool
+		err      error
+	)
+
+	for _, current = range cfg.Groups {
+		if current.File == name {
+			group = current
+		}
+	}
+
+	if group == nil {
+		return fmt.Errorf("unknown group %s", name)
+	}
+
+	files = append(files, group.File)
+	if group.Graveyard != nil {
+		files = append(files, group.Graveyard.File)
+	}
+
+	for _, file = range files {
+		backups, err = listBackups(file)
+		if err != nil {
+			return err
+		}
+
+		if len(backups) == 0 {
+			continue
+		}
+
+		data, err = os.ReadFile(backups[len(backups)-1])
+		if err != nil {
+			return err
+		}
+
+		_, err = writeFile(file, data, group.FileMode, group.UID, group.GID)
+		if err != nil {
+			return err
+		}
+
+		err = os.Remove(backups[len(backups)-1])
+		if err != nil {
+			return err
+		}
+
+		restored = true
+	}
+
+	if !restored {
+		return fmt.Errorf("%w for group %s", ErrNoBackup, name)
+	}
+
+	return nil
+}
+
+// writeFile writes data to file with the given mode and owner (-1 keeps the owner) unless file already has the content
+// of data. It returns true when file has been written.
+func writeFile(file string, data []byte, mode os.FileMode, uid, gid int) (bool, error) {
+	var (
+		current []byte
+		info    os.FileInfo
+		err     error
+	)
+
+	current, err = os.ReadFile(file)
+	if err == nil && bytes.Equal(current, data) {
+		// the file mode might have been changed by a config reload
+		info, err = os.Stat(file)
+		if err == nil && info.Mode().Perm() != mode {
+			err = os.Chmod(file, mode)
+		}
+
+		return false, err
+	}
+
+	err = os.WriteFile(file, data, mode)
+	if err != nil {
+		return false, err
+	}
+
+	err = os.Chmod(file, mode)
+	if err != nil {
+		return false, err
+	}
+
+	if uid != -1 || gid != -1 {
+		err = os.Chown(file, uid, gid)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,143 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTargetFile(t *testing.T) {
+	var (
+		group   *config.Group = &config.Group{FileMode: 0640, UID: -1, GID: -1}
+		file    string        = filepath.Join(t.TempDir(), "targets.yml")
+		info    os.FileInfo
+		data    []byte
+		written bool
+		err     error
+	)
+
+	require.Nil(t, os.WriteFile(file, []byte("old"), 0600))
+
+	// the mode of existing files is changed too
+	written, err = writeTargetFile(group, file, []byte("new"))
+	require.Nil(t, err)
+	assert.True(t, written)
+	info, err = os.Stat(file)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	data, err = os.ReadFile(file)
+	require.Nil(t, err)
+	assert.Equal(t, "new", string(data))
+
+	// unchanged content isn't written again but the mode is still applied
+	group.FileMode = 0600
+	written, err = writeTargetFile(group, file, []byte("new"))
+	require.Nil(t, err)
+	assert.False(t, written)
+	info, err = os.Stat(file)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// changing the owner to the current user works without root
+	group.UID = os.Getuid()
+	group.GID = os.Getgid()
+	written, err = writeTargetFile(group, file, []byte("newer"))
+	assert.Nil(t, err)
+	assert.True(t, written)
+}
+
+func TestBackupFile(t *testing.T) {
+	var (
+		file    string    = filepath.Join(t.TempDir(), "targets.yml")
+		now     time.Time = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		backups []string
+		version string
+		data    []byte
+		err     error
+	)
+
+	// nothing to back up yet
+	require.Nil(t, backupFile(file, []byte("v1"), 2, now))
+	backups, err = listBackups(file)
+	require.Nil(t, err)
+	assert.Empty(t, backups)
+
+	require.Nil(t, os.WriteFile(file, []byte("v1"), 0640))
+
+	// unchanged content isn't backed up
+	require.Nil(t, backupFile(file, []byte("v1"), 2, now))
+	backups, err = listBackups(file)
+	require.Nil(t, err)
+	assert.Empty(t, backups)
+
+	for _, version = range []string{"v2", "v3", "v4"} {
+		now = now.Add(time.Minute)
+		require.Nil(t, backupFile(file, []byte(version), 2, now))
+		require.Nil(t, os.WriteFile(file, []byte(version), 0640))
+	}
+
+	// only the newest two backups are kept
+	backups, err = listBackups(file)
+	require.Nil(t, err)
+	assert.Equal(t, []string{file + ".20240501T120200.000Z", file + ".20240501T120300.000Z"}, backups)
+
+	data, err = os.ReadFile(backups[1])
+	require.Nil(t, err)
+	assert.Equal(t, "v3", string(data))
+}
+
+func TestRollbackGroup(t *testing.T) {
+	var (
+		dir string         = t.TempDir()
+		cfg *config.Config = &config.Config{
+			Groups: []*config.Group{
+				{File: filepath.Join(dir, "targets.yml"), FileMode: 0640, UID: -1, GID: -1},
+			},
+		}
+		file string = cfg.Groups[0].File
+		data []byte
+		err  error
+	)
+
+	require.Nil(t, os.WriteFile(file+".20240501T120000.000Z", []byte("v1"), 0640))
+	require.Nil(t, os.WriteFile(file+".20240501T120100.000Z", []byte("v2"), 0640))
+	require.Nil(t, os.WriteFile(file, []byte("[]\n"), 0640))
+
+	require.Nil(t, rollbackGroup(cfg, file))
+	data, err = os.ReadFile(file)
+	require.Nil(t, err)
+	assert.Equal(t, "v2", string(data))
+
+	// rolling back again restores the backup before
+	require.Nil(t, rollbackGroup(cfg, file))
+	data, err = os.ReadFile(file)
+	require.Nil(t, err)
+	assert.Equal(t, "v1", string(data))
+
+	assert.ErrorIs(t, rollbackGroup(cfg, file), ErrNoBackup)
+	assert.Error(t, rollbackGroup(cfg, "unknown.yml"))
+}
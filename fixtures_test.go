@@ -0,0 +1,103 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+	"github.com/4xoc/netbox_sd/pkg/netbox/netboxtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateFixtures = flag.Bool("update", false, "update expected output of fixture tests")
+
+// TestFixtures runs discovery for every directory in testdata/fixtures against a fake Netbox server. Each directory
+// contains the Netbox content (netbox.yml), a netbox_sd configuration (config.yml) and the expected content of every
+// file written for the groups in the configuration. Run `go test -run TestFixtures -update` to (re)generate the
+// expected output.
+func TestFixtures(t *testing.T) {
+	var (
+		dirs []string
+		dir  string
+		err  error
+	)
+
+	dirs, err = filepath.Glob("testdata/fixtures/*")
+	require.Nil(t, err)
+
+	for _, dir = range dirs {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			testFixture(t, dir)
+		})
+	}
+}
+
+func testFixture(t *testing.T, dir string) {
+	var (
+		sd       netboxSD
+		fixtures *netboxtest.Fixtures
+		server   *netboxtest.Server
+		group    *config.Group
+		results  []*discovery.Target
+		files    map[string][]byte
+		file     string
+		output   []byte
+		expected []byte
+		err      error
+	)
+
+	fixtures, err = netboxtest.LoadFixtures(filepath.Join(dir, "netbox.yml"))
+	require.Nil(t, err)
+
+	server = netboxtest.NewServer(fixtures)
+	defer server.Close()
+
+	sd.cfg, err = config.ReadConfigFile(filepath.Join(dir, "config.yml"))
+	require.Nil(t, err)
+
+	sd.api, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netbox_sd_test"))
+	require.Nil(t, err)
+
+	for _, group = range sd.cfg.Groups {
+		results, err = sd.discover(context.Background(), group)
+		require.Nil(t, err, group.File)
+
+		files, err = renderGroup(group, results)
+		require.Nil(t, err)
+
+		for file, output = range files {
+			if *updateFixtures {
+				require.Nil(t, os.WriteFile(filepath.Join(dir, file), output, 0664))
+				continue
+			}
+
+			expected, err = os.ReadFile(filepath.Join(dir, file))
+			require.Nil(t, err)
+			assert.Equal(t, string(expected), string(output), file)
+		}
+	}
+}
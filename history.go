@@ -0,0 +1,233 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+)
+
+// membershipHistory keeps the target membership changes of the last cycles per group in memory. It answers questions
+// like "when did device X drop out of discovery and why".
+type membershipHistory struct {
+	mu     sync.Mutex
+	size   int
+	groups map[string]*groupHistory
+}
+
+// groupHistory is a ring buffer of the last cycles of a single group.
+type groupHistory struct {
+	// entries contains at most size entries; next is the position the next entry is written to.
+	entries []*historyEntry
+	next    int
+	// members contains the names of all targets that have been active (or in the graveyard) in the last cycle.
+	members map[string]discovery.State
+}
+
+// historyEntry describes the membership changes of a single cycle.
+type historyEntry struct {
+	Time    time.Time       `json:"time"`
+	Group   string          `json:"group"`
+	Targets int             `json:"targets"`
+	Added   []historyChange `json:"added,omitempty"`
+	Removed []historyChange `json:"removed,omitempty"`
+}
+
+// historyChange is a single target that has been added to or removed from a group.
+type historyChange struct {
+	Name string `json:"name"`
+	// State is the target's state after the change; for removed targets this is the reason or "missing" when the
+	// target hasn't been discovered at all.
+	State string `json:"state"`
+}
+
+// newMembershipHistory returns a new membershipHistory keeping the last size cycles per group.
+func newMembershipHistory(size int) *membershipHistory {
+	return &membershipHistory{
+		size:   size,
+		groups: make(map[string]*groupHistory),
+	}
+}
+
+// record adds the result of a discovery cycle of group to the history.
+func (h *membershipHistory) record(group string, targets []*discovery.Target, now time.Time) {
+	var (
+		gh      *groupHistory
+		ok      bool
+		members map[string]discovery.State = make(map[string]discovery.State)
+		states  map[string]discovery.State = make(map[string]discovery.State)
+		entry   *historyEntry
+		target  *discovery.Target
+		name    string
+		state   discovery.State
+	)
+
+	for _, target = range targets {
+		name = target.Device.Name
+
+		switch {
+		case target.Skipped():
+			// keep the first reason unless the device has another active target
+			if _, ok = states[name]; !ok {
+				states[name] = target.SkipReason
+			}
+		case target.Graveyard:
+			members[name] = discovery.StateGraveyard
+		default:
+			members[name] = discovery.StateActive
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	gh, ok = h.groups[group]
+	if !ok {
+		gh = &groupHistory{
+			entries: make([]*historyEntry, 0, h.size),
+			members: make(map[string]discovery.State),
+		}
+		h.groups[group] = gh
+	}
+
+	entry = &historyEntry{
+		Time:    now,
+		Group:   group,
+		Targets: len(members),
+	}
+
+	for name, state = range members {
+		if gh.members[name] != state {
+			entry.Added = append(entry.Added, historyChange{Name: name, State: state.String()})
+		}
+	}
+
+	for name = range gh.members {
+		if _, ok = members[name]; ok {
+			continue
+		}
+
+		state, ok = states[name]
+		if !ok {
+			entry.Removed = append(entry.Removed, historyChange{Name: name, State: "missing"})
+			continue
+		}
+
+		entry.Removed = append(entry.Removed, historyChange{Name: name, State: state.String()})
+	}
+
+	sortChanges(entry.Added)
+	sortChanges(entry.Removed)
+
+	gh.members = members
+
+	if len(gh.entries) < h.size {
+		gh.entries = append(gh.entries, entry)
+	} else {
+		gh.entries[gh.next] = entry
+	}
+
+	gh.next = (gh.next + 1) % h.size
+}
+
+// entries returns all entries of group (or of all groups when empty) ordered by time. When target is set, only entries
+// changing the membership of target are returned.
+func (h *membershipHistory) entries(group, target string) []*historyEntry {
+	var (
+		result []*historyEntry = make([]*historyEntry, 0)
+		name   string
+		gh     *groupHistory
+		i      int
+		entry  *historyEntry
+	)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for name, gh = range h.groups {
+		if group != "" && name != group {
+			continue
+		}
+
+		// the oldest entry is at next (which equals len(entries) until the buffer is full)
+		for i = range gh.entries {
+			entry = gh.entries[(gh.next+i)%len(gh.entries)]
+
+			if target != "" && !entry.changes(target) {
+				continue
+			}
+
+			result = append(result, entry)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+
+	return result
+}
+
+// changes returns true when the entry changes the membership of target.
+func (e *historyEntry) changes(target string) bool {
+	var change historyChange
+
+	for _, change = range e.Added {
+		if change.Name == target {
+			return true
+		}
+	}
+
+	for _, change = range e.Removed {
+		if change.Name == target {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortChanges sorts changes by name.
+func sortChanges(changes []historyChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+}
+
+// historyHandler returns the membership history as JSON. The query parameters group and target limit the result to a
+// single group or to entries changing a single target respectively.
+func (sd *netboxSD) historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if sd.history == nil {
+		http.Error(w, "membership history is disabled (see -history.cycles)", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sd.history.entries(r.URL.Query().Get("group"), r.URL.Query().Get("target")))
+}
@@ -0,0 +1,108 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func historyTarget(name string, state discovery.State) *discovery.Target {
+	return &discovery.Target{
+		Device:     &netbox.Device{Name: name},
+		SkipReason: state,
+	}
+}
+
+func TestMembershipHistory(t *testing.T) {
+	var (
+		history *membershipHistory = newMembershipHistory(2)
+		start   time.Time          = time.Unix(1700000000, 0)
+		entries []*historyEntry
+	)
+
+	history.record("a.yml", []*discovery.Target{
+		historyTarget("dev1", discovery.StateActive),
+		historyTarget("dev2", discovery.StateActive),
+	}, start)
+
+	history.record("a.yml", []*discovery.Target{
+		historyTarget("dev1", discovery.StateActive),
+		historyTarget("dev2", discovery.StateSkippedBadStatus),
+	}, start.Add(time.Minute))
+
+	history.record("b.yml", []*discovery.Target{
+		historyTarget("dev3", discovery.StateActive),
+	}, start.Add(90*time.Second))
+
+	entries = history.entries("", "")
+	require.Len(t, entries, 3)
+	assert.Equal(t, "a.yml", entries[0].Group)
+	assert.Equal(t, []historyChange{{"dev1", "active"}, {"dev2", "active"}}, entries[0].Added)
+	assert.Equal(t, []historyChange{{"dev2", discovery.StateSkippedBadStatus.String()}}, entries[1].Removed)
+	assert.Equal(t, 1, entries[1].Targets)
+	assert.Equal(t, "b.yml", entries[2].Group)
+
+	// dev1 disappears entirely; the oldest cycle of a.yml is dropped
+	history.record("a.yml", nil, start.Add(2*time.Minute))
+
+	entries = history.entries("a.yml", "")
+	require.Len(t, entries, 2)
+	assert.Equal(t, start.Add(time.Minute), entries[0].Time)
+	assert.Equal(t, []historyChange{{"dev1", "missing"}}, entries[1].Removed)
+
+	entries = history.entries("", "dev1")
+	require.Len(t, entries, 1)
+	assert.Equal(t, start.Add(2*time.Minute), entries[0].Time)
+}
+
+func TestHistoryHandler(t *testing.T) {
+	var (
+		sd      netboxSD
+		rec     *httptest.ResponseRecorder
+		entries []*historyEntry
+	)
+
+	rec = httptest.NewRecorder()
+	sd.historyHandler(rec, httptest.NewRequest(http.MethodGet, "/-/history", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	sd.history = newMembershipHistory(10)
+	sd.history.record("a.yml", []*discovery.Target{historyTarget("dev1", discovery.StateActive)}, time.Now())
+	sd.history.record("b.yml", []*discovery.Target{historyTarget("dev2", discovery.StateActive)}, time.Now())
+
+	rec = httptest.NewRecorder()
+	sd.historyHandler(rec, httptest.NewRequest(http.MethodGet, "/-/history?group=b.yml", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.Nil(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "dev2", entries[0].Added[0].Name)
+
+	rec = httptest.NewRecorder()
+	sd.historyHandler(rec, httptest.NewRequest(http.MethodPost, "/-/history", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,199 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the Icinga2 export containing a host object for each device of all groups.
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+
+	"github.com/prometheus/common/model"
+)
+
+// icingaHosts keeps the hosts of the last successful scan of each group for the Icinga file.
+type icingaHosts struct {
+	mu    sync.Mutex
+	hosts map[string][]*icingaHost
+}
+
+// icingaHost is the data the host template is rendered with.
+type icingaHost struct {
+	// Name is the name of the device.
+	Name string
+	// Address and Address6 are the primary IPs of the device; empty when not set.
+	Address  string
+	Address6 string
+	// Import is the host template configured to be imported.
+	Import string
+	// Groups contains the files of all groups the device is part of.
+	Groups []string
+	// Labels are the labels of the device's first target (without internal labels starting with __).
+	Labels map[string]string
+}
+
+// update stores the hosts of the devices of targets as the current hosts of group and returns the content of the
+// Icinga file. Skipped and graveyard targets are not exported.
+func (icinga *icingaHosts) update(cfg *config.Icinga, group string, targets []*discovery.Target) ([]byte, error) {
+	var (
+		hosts  []*icingaHost
+		host   *icingaHost
+		seen   map[string]bool = make(map[string]bool)
+		target *discovery.Target
+		name   model.LabelName
+	)
+
+	for _, target = range targets {
+		if target.Skipped() || target.Graveyard || target.Device == nil || seen[target.Device.Name] {
+			continue
+		}
+
+		seen[target.Device.Name] = true
+
+		host = &icingaHost{
+			Name:   target.Device.Name,
+			Import: cfg.Import,
+			Labels: make(map[string]string, len(target.Labels)),
+		}
+
+		if target.Device.PrimaryIP4 != nil {
+			host.Address = target.Device.PrimaryIP4.ToAddr()
+		}
+
+		if target.Device.PrimaryIP6 != nil {
+			host.Address6 = target.Device.PrimaryIP6.ToAddr()
+		}
+
+		for name = range target.Labels {
+			if !strings.HasPrefix(string(name), model.ReservedLabelPrefix) {
+				host.Labels[string(name)] = string(target.Labels[name])
+			}
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	icinga.mu.Lock()
+	defer icinga.mu.Unlock()
+
+	if icinga.hosts == nil {
+		icinga.hosts = make(map[string][]*icingaHost)
+	}
+
+	icinga.hosts[group] = hosts
+
+	return icinga.render(cfg)
+}
+
+// remove deletes the hosts of group.
+func (icinga *icingaHosts) remove(group string) {
+	icinga.mu.Lock()
+	defer icinga.mu.Unlock()
+
+	delete(icinga.hosts, group)
+}
+
+// render returns the Icinga file containing the hosts of all groups ordered by name. Devices that are part of several
+// groups are only written once using the labels of the first group (ordered by file). The caller must hold mu.
+func (icinga *icingaHosts) render(cfg *config.Icinga) ([]byte, error) {
+	var (
+		groups []string
+		group  string
+		hosts  map[string]*icingaHost = make(map[string]*icingaHost)
+		names  []string
+		name   string
+		host   *icingaHost
+		merged *icingaHost
+		data   []byte
+		buf    bytes.Buffer
+		err    error
+	)
+
+	for group = range icinga.hosts {
+		groups = append(groups, group)
+	}
+
+	sort.Strings(groups)
+
+	for _, group = range groups {
+		for _, host = range icinga.hosts[group] {
+			merged = hosts[host.Name]
+			if merged == nil {
+				// copied so the groups of other scans aren't modified
+				merged = &icingaHost{}
+				*merged = *host
+				merged.Groups = nil
+				hosts[host.Name] = merged
+				names = append(names, host.Name)
+			}
+
+			merged.Groups = append(merged.Groups, group)
+		}
+	}
+
+	sort.Strings(names)
+
+	buf.WriteString("// generated by netbox_sd, do not edit\n")
+
+	for _, name = range names {
+		data, err = cfg.RenderHost(hosts[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to render host %s: %w", name, err)
+		}
+
+		buf.WriteString("\n")
+		buf.Write(data)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeIcinga updates the Icinga file of cfg with the devices of group. Nothing is done when cfg doesn't define an
+// Icinga export.
+func (sd *netboxSD) writeIcinga(cfg *config.Config, group *config.Group, targets []*discovery.Target) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if cfg.Icinga == nil {
+		return nil
+	}
+
+	data, err = sd.icinga.update(cfg.Icinga, group.File, targets)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		slog.Debug("dry-run: not writing icinga file", "file", cfg.Icinga.File)
+
+		return nil
+	}
+
+	_, err = writeFile(cfg.Icinga.File, data, config.DefaultFileMode, -1, -1)
+
+	return err
+}
@@ -0,0 +1,114 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const icingaTestConfig = `base_url: https://netbox.domain.tld
+api_token: 123
+scan_interval: 5m
+
+icinga:
+  file: hosts.conf
+
+groups:
+  - file: node.yml
+    type: device_tag
+    match: node_exporter
+`
+
+func TestIcingaHosts(t *testing.T) {
+	var (
+		icinga  icingaHosts
+		file    string = filepath.Join(t.TempDir(), "config.yml")
+		cfg     *config.Config
+		data    []byte
+		err     error
+		targets []*discovery.Target = []*discovery.Target{
+			{
+				Device: &netbox.Device{
+					Name:       "device-a",
+					PrimaryIP4: &netbox.IP{Address: "192.0.2.1/24"},
+					PrimaryIP6: &netbox.IP{Address: "2001:db8::1/64"},
+				},
+				Labels:     model.LabelSet{"__meta_internal": "x", "netbox_site": `Site "A"`},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				// additional targets of a device are ignored
+				Device:     &netbox.Device{Name: "device-a"},
+				Labels:     model.LabelSet{"netbox_site": "other"},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				Device:     &netbox.Device{Name: "device-b"},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				Device:     &netbox.Device{Name: "device-c"},
+				SkipReason: discovery.StateSkippedBadStatus,
+			},
+		}
+	)
+
+	require.Nil(t, os.WriteFile(file, []byte(icingaTestConfig), 0600))
+
+	cfg, err = config.ReadConfigFile(file)
+	require.Nil(t, err)
+
+	_, err = icinga.update(cfg.Icinga, "node.yml", targets)
+	require.Nil(t, err)
+
+	// devices of several groups are only written once
+	data, err = icinga.update(cfg.Icinga, "other.yml", targets[2:])
+	require.Nil(t, err)
+	assert.Equal(t, `// generated by netbox_sd, do not edit
+
+object Host "device-a" {
+  import "generic-host"
+  address = "192.0.2.1"
+  address6 = "2001:db8::1"
+  vars.netbox_groups = [ "node.yml" ]
+  vars["netbox_site"] = "Site \"A\""
+}
+
+object Host "device-b" {
+  import "generic-host"
+  vars.netbox_groups = [ "node.yml", "other.yml" ]
+}
+`, string(data))
+
+	icinga.remove("node.yml")
+	icinga.remove("other.yml")
+
+	data, err = icinga.update(cfg.Icinga, "empty.yml", nil)
+	require.Nil(t, err)
+	assert.Equal(t, "// generated by netbox_sd, do not edit\n", string(data))
+}
@@ -0,0 +1,219 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// apiFor returns the Netbox API client used for group. This is the group's own client when it overrides the connection
+// of its instance, otherwise the client of the instance group refers to.
+func (sd *netboxSD) apiFor(group *config.Group) netbox.ClientIface {
+	var (
+		worker *groupWorker
+		ok     bool
+	)
+
+	sd.mu.RLock()
+	worker, ok = sd.workers[group.File]
+	sd.mu.RUnlock()
+
+	// the worker might already belong to a newer version of the group
+	if ok && worker.group == group && worker.api != nil {
+		return worker.api
+	}
+
+	if group.Instance == "" {
+		return sd.api
+	}
+
+	return sd.instances[group.Instance]
+}
+
+// clients returns the API clients of all Netbox instances by name.
+func (sd *netboxSD) clients() map[string]netbox.ClientIface {
+	var (
+		list map[string]netbox.ClientIface = make(map[string]netbox.ClientIface)
+		name string
+		api  netbox.ClientIface
+	)
+
+	if sd.api != nil {
+		list[config.DefaultInstance] = sd.api
+	}
+
+	for name, api = range sd.instances {
+		list[name] = api
+	}
+
+	return list
+}
+
+// initClients creates an API client for the default and all additional Netbox instances defined in cfg and verifies
+// connectivity to each of them. The metrics of every client are registered with a netbox_instance label.
+func (sd *netboxSD) initClients(cfg *config.Config) error {
+	var (
+		instance *config.Instance
+		name     string
+		api      netbox.ClientIface
+		err      error
+	)
+
+	if cfg.BaseURL != "" {
+		sd.api, err = newClient(cfg, cfg.InstanceFor(&config.Group{}))
+		if err != nil {
+			return err
+		}
+	}
+
+	sd.instances = make(map[string]netbox.ClientIface)
+
+	for _, instance = range cfg.Instances {
+		sd.instances[instance.Name], err = newClient(cfg, instance)
+		if err != nil {
+			return err
+		}
+	}
+
+	for name, api = range sd.clients() {
+		err = prometheus.WrapRegistererWith(prometheus.Labels{"netbox_instance": name}, prometheus.DefaultRegisterer).
+			Register(api)
+		if err != nil {
+			return fmt.Errorf("failed to register metrics of netbox instance %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// newClient returns a new API client for instance after verifying connectivity.
+func newClient(cfg *config.Config, instance *config.Instance) (netbox.ClientIface, error) {
+	var (
+		api netbox.ClientIface
+		err error
+	)
+
+	api, err = buildClient(cfg, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	err = api.VerifyConnectivity(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify connectivity to netbox instance %s: %w", instance.Name, err)
+	}
+
+	slog.Info("connection to netbox instance successful", "netbox_instance", instance.Name, "netbox_version", api.Version())
+
+	return api, nil
+}
+
+// buildClient returns a new API client for instance without contacting Netbox. The GraphQL options are taken from cfg.
+// Instances using the REST API get a client performing all lookups via REST.
+func buildClient(cfg *config.Config, instance *config.Instance) (netbox.ClientIface, error) {
+	var (
+		api  *netbox.Client
+		opts []netbox.Option = []netbox.Option{
+			netbox.WithPrometheusNamespace(PrometheusNameSpace),
+			netbox.WithClientOptions(instance.Options(&cfg.ClientOptions)),
+			netbox.WithNativeHistograms(cfg.ClientOptions.NativeHistograms),
+			netbox.WithUserAgent(userAgent(cfg.ClientOptions.UserAgentSuffix)),
+			netbox.WithLogger(slog.Default().With("netbox_instance", instance.Name)),
+		}
+		err error
+	)
+
+	// the token file is read again whenever it changes, so rotated tokens are used without a restart
+	if instance.TokenFile != "" {
+		opts = append(opts, netbox.WithTokenSource(netbox.NewFileTokenSource(instance.TokenFile)))
+	}
+
+	api, err = netbox.New(instance.BaseURL, instance.Token, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize api client of netbox instance %s: %w", instance.Name, err)
+	}
+
+	if len(instance.PinnedPublicKeys) > 0 {
+		err = api.SetPinnedPublicKeys(instance.PinnedPublicKeys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set pinned public keys of netbox instance %s: %w", instance.Name, err)
+		}
+	}
+
+	api.UsePersistedQueries(cfg.PersistedQueries)
+	api.SetPageSize(*cfg.PageSize)
+	api.SetCacheTTL(cfg.CacheTTL)
+	api.HTTPTracing(getLogLevel() >= LogLevelTrace)
+
+	if instance.API == config.APIREST {
+		return netbox.NewREST(api), nil
+	}
+
+	return api, nil
+}
+
+// userAgent returns the User-Agent header sent to Netbox: netbox_sd/<version> followed by suffix unless empty.
+func userAgent(suffix string) string {
+	var (
+		agent string = "netbox_sd/" + version
+	)
+
+	if version == "" {
+		agent = "netbox_sd/dev"
+	}
+
+	if suffix != "" {
+		agent += " " + suffix
+	}
+
+	return agent
+}
+
+// groupClient returns a new API client for group when it overrides the connection of its instance and nil otherwise.
+// Its metrics are not registered, see groupClientRegisterer.
+func groupClient(cfg *config.Config, group *config.Group) (netbox.ClientIface, error) {
+	var (
+		api netbox.ClientIface
+		err error
+	)
+
+	if group.BaseURL == "" && group.Token == "" {
+		return nil, nil
+	}
+
+	api, err = buildClient(cfg, cfg.InstanceFor(group))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client of group %s: %w", group.File, err)
+	}
+
+	return api, nil
+}
+
+// groupClientRegisterer returns the registerer for the metrics of the client of group. They are labeled with
+// netbox_instance set to "group:" followed by the group's file.
+func groupClientRegisterer(group *config.Group) prometheus.Registerer {
+	return prometheus.WrapRegistererWith(prometheus.Labels{"netbox_instance": "group:" + group.File},
+		prometheus.DefaultRegisterer)
+}
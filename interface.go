@@ -18,60 +18,162 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"strconv"
 
 	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
 	"github.com/4xoc/netbox_sd/pkg/netbox"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
-	"github.com/prometheus/prometheus/discovery/targetgroup"
 )
 
-// GetTargetsByInterfaceTag returns a list of of target devices that match a given device tag.
-func (sd *netboxSD) getTargetsByInterfaceTag(group *config.Group) ([]*targetgroup.Group, error) {
+// GetTargetsByInterfaceTag returns a list of of target devices with interfaces matching the group's tag expression.
+func (sd *netboxSD) getTargetsByInterfaceTag(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
 	var (
-		err         error
-		iface       *netbox.Interface
-		addrs       []*netbox.IP
-		dynLabels   model.LabelSet
-		data        []*targetgroup.Group = make([]*targetgroup.Group, 0)
-		target      *targetgroup.Group
-		selectedIPs []*netbox.IP
-		ifList      []*netbox.Interface
-		vmList      []*netbox.Interface
-		cfLabels    model.LabelSet
+		err    error
+		ifList []*netbox.Interface
+		vmList []*netbox.Interface
 	)
 
-	ifList, err = sd.api.GetInterfacesByTag(group.Match)
+	ifList, err = getByTagExpr(ctx, group.TagExpr, sd.apiFor(group).GetInterfacesByTag, interfaceID, interfaceTags)
 	if err != nil {
-		log.Printf("failed to get interfaces by tag: %v", err)
+		slog.ErrorContext(ctx, "failed to get interfaces by tag", "group", group.File, "error", err)
 		return nil, err
 	}
 
 	// Adding virtual interfaces with that tag here when flags are properly set.
 	if *group.Flags.IncludeVMs {
-		vmList, err = sd.api.GetVirtualInterfacesByTag(group.Match)
+		vmList, err = getByTagExpr(ctx, group.TagExpr, sd.apiFor(group).GetVirtualInterfacesByTag, interfaceID, interfaceTags)
 		if err != nil {
-			log.Printf("failed to get virtual images by tag: %v", err)
+			slog.ErrorContext(ctx, "failed to get virtual interfaces by tag", "group", group.File, "error", err)
 			return nil, err
 		}
 
 		ifList = append(ifList, vmList...)
 	}
 
+	return sd.getTargetsByInterfaces(ctx, group, ifList), nil
+}
+
+// getTargetsByVLAN returns a list of target devices with interfaces attached (untagged or tagged) to the vlan given by
+// the group's match. The match is either a VLAN ID or a vlan name; all vlans using that VLAN ID or name are considered.
+func (sd *netboxSD) getTargetsByVLAN(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
+	var (
+		err    error
+		vid    uint64
+		vlans  []*netbox.VLAN
+		vlan   *netbox.VLAN
+		list   []*netbox.Interface
+		ifList []*netbox.Interface
+	)
+
+	vid, err = strconv.ParseUint(group.Match.Value, 10, 12)
+	if err == nil {
+		vlans, err = sd.apiFor(group).GetVLANsByVID(ctx, uint16(vid))
+	} else {
+		vlans, err = sd.apiFor(group).GetVLANsByName(ctx, group.Match.Value)
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get vlans", "group", group.File, "error", err)
+		return nil, err
+	}
+
+	for _, vlan = range vlans {
+		list, err = sd.apiFor(group).GetInterfacesByVLAN(ctx, vlan.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to get interfaces by vlan", "group", group.File, "error", err)
+			return nil, err
+		}
+
+		ifList = append(ifList, list...)
+
+		if *group.Flags.IncludeVMs {
+			list, err = sd.apiFor(group).GetVirtualInterfacesByVLAN(ctx, vlan.ID)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to get virtual interfaces by vlan", "group", group.File, "error", err)
+				return nil, err
+			}
+
+			ifList = append(ifList, list...)
+		}
+	}
+
+	return sd.getTargetsByInterfaces(ctx, group, ifList), nil
+}
+
+// getTargetsByWirelessLAN returns a list of target devices (i.e. access points) with interfaces attached to the wireless
+// LAN given by the group's SSID match. All wireless LANs using that SSID are considered.
+func (sd *netboxSD) getTargetsByWirelessLAN(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
+	var (
+		err    error
+		wlans  []*netbox.WirelessLAN
+		wlan   *netbox.WirelessLAN
+		list   []*netbox.Interface
+		ifList []*netbox.Interface
+	)
+
+	wlans, err = sd.apiFor(group).GetWirelessLANsBySSID(ctx, group.Match.Value)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get wireless lans", "group", group.File, "error", err)
+		return nil, err
+	}
+
+	for _, wlan = range wlans {
+		list, err = sd.apiFor(group).GetInterfacesByWirelessLAN(ctx, wlan.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to get interfaces by wireless lan", "group", group.File, "error", err)
+			return nil, err
+		}
+
+		ifList = append(ifList, list...)
+	}
+
+	return sd.getTargetsByInterfaces(ctx, group, ifList), nil
+}
+
+// getTargetsByInterfaces returns a target for each interface in ifList using the interface's addresses.
+func (sd *netboxSD) getTargetsByInterfaces(ctx context.Context, group *config.Group, ifList []*netbox.Interface) []*discovery.Target {
+	var (
+		err         error
+		iface       *netbox.Interface
+		addrs       []*netbox.IP
+		dynLabels   model.LabelSet
+		tmplLabels  model.LabelSet
+		data        []*discovery.Target = make([]*discovery.Target, 0)
+		target      *discovery.Target
+		selectedIPs []*netbox.IP
+		cfLabels    model.LabelSet
+		scheme      string
+	)
+
 	for _, iface = range ifList {
+		// devices not matching the group's name_match are not part of the group
+		if !group.NameMatches(iface.Device.Name) {
+			continue
+		}
+
 		// reset
-		target = new(targetgroup.Group)
+		dynLabels = nil
+		target = &discovery.Target{
+			Device: iface.Device,
+		}
+		data = append(data, target)
 
-		// check for active device & interface
-		if iface.Device.Status != netbox.StatusDeviceActive ||
+		// check for active device & interface; devices in one of the graveyard statuses are processed too
+		if (iface.Device.Status != netbox.StatusDeviceActive && !group.InGraveyard(iface.Device.Status)) ||
 			!iface.Enabled {
-			log.Printf("device %s is not marked as active...skipping device", iface.Device.Name)
-			SetTargetStatusMetric(group.File, iface.Device, TargetSkippedBadStatus)
+			slog.DebugContext(ctx, "device or interface is not marked as active, skipping device", "group", group.File,
+				"device", iface.Device.Name, "interface", iface.Name)
+			target.SkipReason = discovery.StateSkippedBadStatus
 			continue
 		}
 
+		target.Graveyard = iface.Device.Status != netbox.StatusDeviceActive
+
 		target.Labels = model.LabelSet{
 			model.LabelName("netbox_name"):          model.LabelValue(iface.Device.Name),
 			model.LabelName("netbox_rack"):          model.LabelValue(iface.Device.Rack.Name),
@@ -84,19 +186,21 @@ func (sd *netboxSD) getTargetsByInterfaceTag(group *config.Group) ([]*targetgrou
 		}
 
 		// custom fields
-		cfLabels, err = generateCustomFieldLabels(iface.Device.CustomFields)
+		cfLabels, err = generateCustomFieldLabels(iface.Device.CustomFields, group.CustomFieldAllowed)
 		if err != nil {
-			log.Printf("failed to parse custom fields for device %s...skipping device", iface.Device.Name)
-			SetTargetStatusMetric(group.File, iface.Device, TargetSkippedBadCustomField)
+			slog.WarnContext(ctx, "failed to parse custom fields, skipping device", "group", group.File,
+				"device", iface.Device.Name, "error", err)
+			target.SkipReason = discovery.StateSkippedBadCustomField
 			continue
 		}
 
 		target.Labels = target.Labels.Merge(cfLabels)
 
-		cfLabels, err = generateCustomFieldLabels(iface.CustomFields)
+		cfLabels, err = generateCustomFieldLabels(iface.CustomFields, group.CustomFieldAllowed)
 		if err != nil {
-			log.Printf("failed to parse custom fields for interface %s on device %s...skipping device", iface.Name, iface.Device.Name)
-			SetTargetStatusMetric(group.File, iface.Device, TargetSkippedBadCustomField)
+			slog.WarnContext(ctx, "failed to parse custom fields of interface, skipping device", "group", group.File,
+				"device", iface.Device.Name, "interface", iface.Name, "error", err)
+			target.SkipReason = discovery.StateSkippedBadCustomField
 			continue
 		}
 
@@ -109,26 +213,52 @@ func (sd *netboxSD) getTargetsByInterfaceTag(group *config.Group) ([]*targetgrou
 		}
 
 		target.Labels = target.Labels.Merge(dynLabels)
-		target.Source = "netbox_sd"
+
+		tmplLabels, err = templateLabels(group, iface.Device, target.Labels)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to execute label templates, skipping device", "group", group.File,
+				"device", iface.Device.Name, "error", err)
+			target.SkipReason = discovery.StateSkippedOther
+			continue
+		}
 
 		// add additional labels
-		target.Labels = target.Labels.Merge(group.Labels)
+		target.Labels = target.Labels.Merge(group.Labels).Merge(tmplLabels)
+
+		if target.Graveyard {
+			target.Labels = target.Labels.Merge(graveyardLabels(group, iface.Device))
+		}
+
+		scheme = group.SchemeFor(target.Labels)
+		if scheme != "" {
+			target.Labels[model.SchemeLabel] = model.LabelValue(scheme)
+		}
 
-		if !group.FiltersMatch(target) {
-			log.Printf("device %s doesn't match applied filters...skipping device", iface.Device.Name)
+		if group.ExcludedByTags(append(tagSlugs(iface.Device.Tags), tagSlugs(iface.Tags)...)) {
+			slog.DebugContext(ctx, "device carries an excluded tag, skipping device", "group", group.File,
+				"device", iface.Device.Name)
+			target.SkipReason = discovery.StateSkippedNotMatchingFilters
+			continue
+		}
+
+		if !group.FiltersMatch(target.Labels) {
+			slog.DebugContext(ctx, "device doesn't match applied filters, skipping device", "group", group.File,
+				"device", iface.Device.Name)
+			target.SkipReason = discovery.StateSkippedNotMatchingFilters
 			continue
 		}
 
 		// Only possible IPs for a device tag target can be primary v6 or legacy ip.
 		if iface.Device.IsVirtual() {
-			addrs, err = sd.api.GetVirtualInterfaceIPs(iface.ID)
+			addrs, err = sd.apiFor(group).GetVirtualInterfaceIPs(ctx, iface.ID)
 		} else {
-			addrs, err = sd.api.GetInterfaceIPs(iface.ID)
+			addrs, err = sd.apiFor(group).GetInterfaceIPs(ctx, iface.ID)
 		}
 
 		if err != nil {
-			log.Printf("failed to get interface IPs for %s on %s...skipping device", iface.Name, iface.Device.Name)
-			SetTargetStatusMetric(group.File, iface.Device, TargetSkippedNoValidIP)
+			slog.WarnContext(ctx, "failed to get interface IPs, skipping device", "group", group.File,
+				"device", iface.Device.Name, "interface", iface.Name, "error", err)
+			target.SkipReason = discovery.StateSkippedNoValidIP
 			continue
 		}
 
@@ -136,16 +266,22 @@ func (sd *netboxSD) getTargetsByInterfaceTag(group *config.Group) ([]*targetgrou
 
 		// When there are no selectedIPs this target cannot be used.
 		if len(selectedIPs) == 0 {
-			SetTargetStatusMetric(group.File, iface.Device, TargetSkippedNoValidIP)
+			target.SkipReason = discovery.StateSkippedNoValidIP
 			continue
 		}
 
-		target.Targets = convertToTargets(selectedIPs, group.Port)
+		target.Addresses = filterAddr(selectedIPs, group)
+		target.AddressesFiltered = len(selectedIPs) - len(target.Addresses)
 
-		SetTargetStatusMetric(group.File, iface.Device, TargetActive)
+		if len(target.Addresses) == 0 {
+			slog.InfoContext(ctx, "no address of device matches address filters, skipping device", "group", group.File,
+				"device", iface.Device.Name)
+			target.SkipReason = discovery.StateSkippedNoMatchingAddress
+			continue
+		}
 
-		// add target to list
-		data = append(data, target)
+		target.Ports = portList(group.PortFor(target.Labels))
+		target.SkipReason = discovery.StateActive
 
 		// set prom metric
 		promIPSkipped.
@@ -155,5 +291,5 @@ func (sd *netboxSD) getTargetsByInterfaceTag(group *config.Group) ([]*targetgrou
 			}).Set(float64(len(addrs) - len(selectedIPs)))
 	}
 
-	return data, nil
+	return data
 }
@@ -20,41 +20,343 @@
 package config
 
 import (
+	"bytes"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"os/user"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
+	"unicode"
+
+	"github.com/4xoc/netbox_sd/pkg/netbox"
 
 	"github.com/prometheus/common/model"
-	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/model/relabel"
 	"gopkg.in/yaml.v3"
 )
 
 // Config is a generic config struct for netbox_sd
 type Config struct {
-	BaseURL            string        `yaml:"base_url"`
-	Token              string        `yaml:"api_token"`
-	AllowInsecure      bool          `yaml:"allow_insecure"`
+	BaseURL string `yaml:"base_url"`
+	Token   string `yaml:"api_token"`
+	// TokenFile is a file containing the API token, read again whenever it changes. Mutually exclusive with Token.
+	TokenFile string `yaml:"api_token_file"`
+	// AllowInsecure disables certificate verification. Deprecated: use TLS.InsecureSkipVerify.
+	AllowInsecure bool `yaml:"allow_insecure"`
+	// TLS contains the TLS options of the connection to the default instance.
+	TLS *TLS `yaml:"tls"`
+	// PinnedPublicKeys is a list of base64 encoded sha256 hashes of the server certificate's public key. When set, the
+	// certificate chain is not validated but the server's public key must match any of the pins.
+	PinnedPublicKeys []string `yaml:"tls_pinned_public_keys"`
+	// API selects the API used for lookups of the default instance (graphql or rest). Defaults to APIGraphQL.
+	API                string        `yaml:"api"`
 	ScanIntervalString string        `yaml:"scan_interval"`
 	ScanInterval       time.Duration `yaml:"-"`
-	Groups             []*Group      `yaml:"groups"`
+	// StartupStagger is the delay between starting the workers of two groups. Groups are started by priority.
+	StartupStaggerString string        `yaml:"startup_stagger"`
+	StartupStagger       time.Duration `yaml:"-"`
+	// FilterSets are named lists of filters that can be referenced by groups.
+	FilterSets map[string][]*Filter `yaml:"filter_sets"`
+	// LabelSets are named sets of labels that can be referenced by groups.
+	LabelSets map[string]model.LabelSet `yaml:"label_sets"`
+	// Labels are added to all targets of all groups. All labels defined for a group take precedence.
+	Labels model.LabelSet `yaml:"labels"`
+	// Format is the format of all target files unless a group defines its own (yaml or json). Defaults to yaml.
+	Format string `yaml:"format"`
+	// ScrapeConfigFilesDir is the directory the target files are read from by Prometheus, used for relative files in
+	// generated scrape configs. Relative files are used as they are when empty.
+	ScrapeConfigFilesDir string `yaml:"scrape_config_files_dir"`
+	// CombinedFile is an additional file containing the targets of all groups, each with a group label containing the
+	// group's file. It's written in Format.
+	CombinedFile string `yaml:"combined_file"`
+	// TargetStateLabels defines which Netbox labels are exposed with the target_state metric. netbox_name is always
+	// exposed.
+	TargetStateLabels []string `yaml:"target_state_labels"`
+	// PersistedQueries enables sending GraphQL queries as persisted queries (sha256 hash first, document on miss).
+	PersistedQueries bool `yaml:"graphql_persisted_queries"`
+	// PageSize is the number of objects requested per GraphQL request of list queries; 0 disables pagination. Defaults
+	// to DefaultPageSize.
+	PageSize *int `yaml:"graphql_page_size"`
+	// CacheTTL is the time successful API responses are cached for, so that groups sharing the same query within one
+	// scan interval reuse its result. 0 disables caching.
+	CacheTTLString string        `yaml:"cache_ttl"`
+	CacheTTL       time.Duration `yaml:"-"`
+	// ChangeDetection skips scans of groups when nothing changed in Netbox since their last successful scan, based on
+	// the most recent entry of Netbox's change log.
+	ChangeDetection bool `yaml:"change_detection"`
+	// ClientOptions are the HTTP options of the connections to all Netbox instances.
+	ClientOptions ClientOptions `yaml:"client_options"`
+	// Instances are additional Netbox installations groups can refer to by name. base_url and api_token define the
+	// default instance used by all other groups; they are optional when all groups refer to an instance.
+	Instances []*Instance `yaml:"netbox_instances"`
+	// Defaults are inherited by all groups unless they override them.
+	Defaults Defaults `yaml:"defaults"`
+	// StrictParsing makes unknown options (e.g. typos) fail parsing the config. Enabled unless set to false.
+	StrictParsing *bool `yaml:"strict_parsing"`
+	// Consul enables registering the targets of groups with a ConsulService as services in the Consul catalog.
+	Consul *Consul `yaml:"consul"`
+	// Etcd enables writing the targets of all groups into etcd.
+	Etcd *Etcd `yaml:"etcd"`
+	// ConfigMap enables writing the targets of all groups into a Kubernetes ConfigMap.
+	ConfigMap *ConfigMap `yaml:"kubernetes_configmap"`
+	// Webhook receives the targets added to and removed from a group after each scan changing them.
+	Webhook *Webhook `yaml:"webhook"`
+	// DNSZone is an additional file containing DNS records of the devices of all groups.
+	DNSZone *DNSZone `yaml:"dns_zone"`
+	// Icinga is an additional file containing an Icinga2 host object for each device of all groups.
+	Icinga *Icinga `yaml:"icinga"`
+	// StateFile keeps track of the target files written by netbox_sd. Files no longer part of the config are cleaned up
+	// on reload (or at startup with -cleanup). Relative paths are relative to the config file. Empty disables cleanup.
+	StateFile string `yaml:"state_file"`
+	// ChangelogFile is a file a JSON line with the added and removed targets is appended to after each scan changing
+	// the targets of a group. Relative paths are relative to the config file.
+	ChangelogFile string `yaml:"changelog_file"`
+	// ManifestFile lists all files written by netbox_sd with their SHA256 checksum and last update time. Relative paths
+	// are relative to the config file.
+	ManifestFile string `yaml:"manifest_file"`
+	// TargetDBFile is a SQLite database the latest targets of each group, the time of all scans and the changes of the
+	// targets are stored in. Relative paths are relative to the config file.
+	TargetDBFile string `yaml:"target_db_file"`
+	// CleanupMode defines whether orphaned target files are deleted or truncated. Defaults to delete.
+	CleanupMode string `yaml:"cleanup_mode"`
+	// GroupsDir is a directory whose *.yml files define additional groups. Relative paths are relative to the config
+	// file.
+	GroupsDir string   `yaml:"groups_dir"`
+	Groups    []*Group `yaml:"groups"`
 }
 
-// Group contains specific configuration for groups to get targets for
-type Group struct {
-	File               string         `yaml:"file"`
-	Type               string         `yaml:"type"`
-	Match              string         `yaml:"match"`
+// groupsFile is the content of a file in GroupsDir.
+type groupsFile struct {
+	Groups []*Group `yaml:"groups"`
+}
+
+// Instance describes an additional Netbox installation.
+type Instance struct {
+	Name             string   `yaml:"name"`
+	BaseURL          string   `yaml:"base_url"`
+	Token            string   `yaml:"api_token"`
+	TokenFile        string   `yaml:"api_token_file"`
+	AllowInsecure    bool     `yaml:"allow_insecure"`
+	TLS              *TLS     `yaml:"tls"`
+	PinnedPublicKeys []string `yaml:"tls_pinned_public_keys"`
+	API              string   `yaml:"api"`
+}
+
+// ClientOptions contains the HTTP options of the connections to Netbox. A timeout of 0 disables it.
+type ClientOptions struct {
+	// RequestTimeout limits the duration of a single request including reading the response. Defaults to
+	// DefaultRequestTimeout.
+	RequestTimeoutString string        `yaml:"request_timeout"`
+	RequestTimeout       time.Duration `yaml:"-"`
+	// DialTimeout limits the duration of establishing a connection. Defaults to DefaultDialTimeout.
+	DialTimeoutString string        `yaml:"dial_timeout"`
+	DialTimeout       time.Duration `yaml:"-"`
+	// ResponseHeaderTimeout limits the time waiting for the response headers after sending a request. Disabled unless
+	// set.
+	ResponseHeaderTimeoutString string        `yaml:"response_header_timeout"`
+	ResponseHeaderTimeout       time.Duration `yaml:"-"`
+	// MaxAttempts is the number of attempts of a request failing due to network errors or with a 5xx or 429 status
+	// code; 1 disables retries. Defaults to DefaultMaxAttempts.
+	MaxAttempts *int `yaml:"max_attempts"`
+	// RetryBackoff is the delay before the first retry, doubled for every further retry. Defaults to
+	// DefaultRetryBackoff.
+	RetryBackoffString string        `yaml:"retry_backoff"`
+	RetryBackoff       time.Duration `yaml:"-"`
+	// ProxyURL is the HTTP proxy all requests are sent through (http, https or socks5). The proxy given by the
+	// environment (HTTP_PROXY, HTTPS_PROXY and NO_PROXY) is used unless set.
+	ProxyURLString string   `yaml:"proxy_url"`
+	ProxyURL       *url.URL `yaml:"-"`
+	// NativeHistograms exposes the API request duration histogram as native histogram in addition to its buckets.
+	NativeHistograms bool `yaml:"native_histograms"`
+	// UserAgentSuffix is appended to the User-Agent header netbox_sd/<version> sent with all requests.
+	UserAgentSuffix string `yaml:"user_agent_suffix"`
+}
+
+// Consul describes the Consul agent targets are registered with. Services are registered for an external node called
+// Node (no health checks are run by Consul for it).
+type Consul struct {
+	Address    string `yaml:"address"`
+	Token      string `yaml:"token"`
+	Datacenter string `yaml:"datacenter"`
+	Node       string `yaml:"node"`
+}
+
+// DNSZone describes a zone file fragment (RFC 1035) containing an A and AAAA record for the primary IPs of each device
+// of all groups. Names are relative unless Domain is set.
+type DNSZone struct {
+	File   string `yaml:"file"`
+	Domain string `yaml:"domain"`
+	// TTL of all records. Defaults to DefaultDNSZoneTTL.
+	TTLString string        `yaml:"ttl"`
+	TTL       time.Duration `yaml:"-"`
+}
+
+// Etcd describes the etcd cluster the targets of all groups are written to, one key per group below Prefix. Endpoints
+// are tried in order; they are accessed using etcd's JSON gateway.
+type Etcd struct {
+	Endpoints []string `yaml:"endpoints"`
+	// Prefix of all keys. Defaults to DefaultEtcdPrefix.
+	Prefix   string `yaml:"prefix"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Icinga describes a file of Icinga2 host object definitions, one for each device of all groups. Each host is rendered
+// using Template, a Go template.
+type Icinga struct {
+	File string `yaml:"file"`
+	// Template renders a single host object. Defaults to DefaultIcingaTemplate.
+	Template string `yaml:"template"`
+	// Import is the host template imported by the default template. Defaults to DefaultIcingaImport.
+	Import       string             `yaml:"import"`
+	hostTemplate *template.Template `yaml:"-"`
+}
+
+// ConfigMap describes the Kubernetes ConfigMap the targets of all groups are written to, one data key per group. The
+// ConfigMap is accessed using the in-cluster config of the pod's service account.
+type ConfigMap struct {
+	Name string `yaml:"name"`
+	// Namespace of the ConfigMap. Defaults to the namespace of the pod.
+	Namespace string `yaml:"namespace"`
+}
+
+// Webhook describes an HTTP endpoint the target changes of groups are POSTed to as JSON.
+type Webhook struct {
+	URL string `yaml:"url"`
+	// Headers are added to each request, e.g. for authentication.
+	Headers map[string]string `yaml:"headers"`
+	// Retries is the number of retries of a failed request. Defaults to DefaultWebhookRetries.
+	Retries *int `yaml:"retries"`
+	// RetryInterval is the delay between two attempts. Defaults to DefaultWebhookRetryInterval.
+	RetryIntervalString string        `yaml:"retry_interval"`
+	RetryInterval       time.Duration `yaml:"-"`
+	// Timeout is the timeout of a single request. Defaults to DefaultWebhookTimeout.
+	TimeoutString string        `yaml:"timeout"`
+	Timeout       time.Duration `yaml:"-"`
+}
+
+// TLS contains the TLS options of a connection to Netbox. Relative file paths are relative to the config file.
+type TLS struct {
+	// CAFile is a PEM encoded bundle of CA certificates trusted instead of the system wide CAs.
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile are a PEM encoded client certificate and key (mTLS). Either both or none must be set.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// MinVersion is the minimum TLS version accepted (1.0, 1.1, 1.2 or 1.3).
+	MinVersion string `yaml:"min_version"`
+	// ServerName overrides the name used to verify the server's certificate.
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// Defaults contains group options that are used for all groups not setting them. Labels are merged with those of the
+// group (label sets and the group's labels taking precedence) while filters are only used by groups not defining any filters.
+type Defaults struct {
 	ScanIntervalString string         `yaml:"scan_interval"`
-	ScanInterval       time.Duration  `yaml:"-"`
-	Labels             model.LabelSet `yaml:"labels"`
 	Port               *int           `yaml:"port"`
 	Flags              Flags          `yaml:"flags"`
+	Labels             model.LabelSet `yaml:"labels"`
 	Filters            []*Filter      `yaml:"filters"`
 }
 
+// Group contains specific configuration for groups to get targets for
+type Group struct {
+	File string `yaml:"file"`
+	Type string `yaml:"type"`
+	// Match is a single value or, for tag based group types, a list of tags.
+	Match Match `yaml:"match"`
+	// MatchMode defines whether targets must carry all (default) or any of the tags when Match is a list of tags.
+	MatchMode          string        `yaml:"match_mode"`
+	ScanIntervalString string        `yaml:"scan_interval"`
+	ScanInterval       time.Duration `yaml:"-"`
+	// Format is the format of the group's target files (yaml or json). Defaults to the global format.
+	Format string `yaml:"format"`
+	// FileTemplate splits the group's targets into multiple files. It's a Go template rendered with the labels of each
+	// target (e.g. node_exporter_{{.netbox_site}}.yml). Targets the template can't be rendered for are written to File.
+	FileTemplate string             `yaml:"file_template"`
+	fileTemplate *template.Template `yaml:"-"`
+	// ConsulService is the name of the Consul service the group's targets are registered as (requires Consul).
+	ConsulService string `yaml:"consul_service"`
+	// Hashmod shards the group's targets across multiple files by a stable hash of their address.
+	Hashmod *Hashmod `yaml:"hashmod"`
+	// FileModeString is the octal permission mode of the group's target files (e.g. 0640). Defaults to DefaultFileMode.
+	FileModeString string      `yaml:"file_mode"`
+	FileMode       os.FileMode `yaml:"-"`
+	// FileOwner and FileGroup set the owner of the group's target files by name or numeric id (requires root).
+	FileOwner string `yaml:"file_owner"`
+	FileGroup string `yaml:"file_group"`
+	// UID and GID are the ids of FileOwner and FileGroup or -1 when not set.
+	UID int `yaml:"-"`
+	GID int `yaml:"-"`
+	// Priority defines the order in which groups are started. Groups with a higher priority are started first.
+	Priority int            `yaml:"priority"`
+	Labels   model.LabelSet `yaml:"labels"`
+	// LabelSets references label sets by name. Their labels are merged in the given order with Labels taking precedence.
+	LabelSets []string  `yaml:"label_sets"`
+	Port      *int      `yaml:"port"`
+	Flags     Flags     `yaml:"flags"`
+	Filters   []*Filter `yaml:"filters"`
+	// FilterSets references filter sets by name. Their filters are appended to Filters.
+	FilterSets     []string         `yaml:"filter_sets"`
+	AddressFilters []*AddressFilter `yaml:"address_filters"`
+	Graveyard      *Graveyard       `yaml:"graveyard"`
+	Scheme         *Scheme          `yaml:"scheme"`
+	// ProbeMode rewrites the targets for exporters probing them on behalf of Prometheus (e.g. blackbox_exporter).
+	ProbeMode *ProbeMode `yaml:"probe_mode"`
+	// ScrapeConfig contains the options of the Prometheus scrape config generated for the group.
+	ScrapeConfig *ScrapeConfig `yaml:"scrape_config"`
+	// PortFromCustomField is the name of a custom field (of the device, interface or service) containing the port of a
+	// target. When the custom field isn't set or doesn't contain a valid port, Port is used.
+	PortFromCustomField string `yaml:"port_from_custom_field"`
+	// MaxTargets limits the number of targets of a group. When a scan yields more targets, the previous file is kept.
+	// Zero means no limit.
+	MaxTargets int `yaml:"max_targets"`
+	// Backups is the number of timestamped backups kept of each file of the group when it's overwritten. Zero disables
+	// backups.
+	Backups int `yaml:"backups"`
+	// NameMatch is a regular expression the name of a device or VM must match to be part of the group.
+	NameMatch string         `yaml:"name_match"`
+	nameRegex *regexp.Regexp `yaml:"-"`
+	// Prefilter restricts the devices (and VMs) of device_tag groups on the Netbox side.
+	Prefilter *Prefilter `yaml:"prefilter"`
+	// ExcludeTags lists tag slugs that exclude a target when carried by its device (or interface).
+	ExcludeTags []string `yaml:"exclude_tags"`
+	// IPStatuses lists the statuses of IP addresses that can be selected for targets. Defaults to DefaultIPStatuses.
+	IPStatuses []string `yaml:"ip_statuses"`
+	// CustomFieldInclude and CustomFieldExclude limit which custom fields are exposed as labels. When CustomFieldInclude
+	// is set, only the custom fields listed are exposed; custom fields in CustomFieldExclude are never exposed.
+	CustomFieldInclude []string `yaml:"custom_field_include"`
+	CustomFieldExclude []string `yaml:"custom_field_exclude"`
+	// RelabelConfigs are applied to the labels of all targets before they are written using Prometheus' relabeling
+	// semantics. Targets dropped by relabeling are skipped.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs"`
+	// LabelTemplates contains the parsed values of all Labels that are Go templates (i.e. contain "{{").
+	LabelTemplates map[model.LabelName]*template.Template `yaml:"-"`
+	// TagExpr is the parsed match of groups matching by tag (device_tag, interface_tag and vdc_tag).
+	TagExpr *TagExpr `yaml:"-"`
+	// Source is the file in GroupsDir the group has been defined in; empty for groups of the config file.
+	Source string `yaml:"-"`
+	// Instance is the name of the Netbox instance queried for this group. When empty, the default instance is used.
+	Instance string `yaml:"netbox"`
+	// BaseURL and Token override the connection of the instance for this group (e.g. to use a tenant-scoped token).
+	BaseURL string `yaml:"base_url"`
+	Token   string `yaml:"api_token"`
+}
+
 // Flags defines specific behavior that can be toggled on or off
 type Flags struct {
 	// IncludeVMs will cause VMs to be checked for matches too.
@@ -65,39 +367,292 @@ type Flags struct {
 	// AllAddresses causes all addresses of a service, device or interface to be returned when set to true. This still
 	// honors the InetFamily filter.
 	AllAddresses *bool `yaml:"all_addresses"`
+	// ReportSkipped causes a list of skipped targets and the reason why they have been skipped to be written as comments
+	// at the top of the file.
+	ReportSkipped *bool `yaml:"report_skipped"`
 }
 
 // Filter defines a new filter where a the string index of the map is a label name and the value at that index
 // represents a regular expression that must match.
 type Filter struct {
-	Label  string         `yaml:"label"`
-	Match  string         `yaml:"match"`
-	Negate bool           `yaml:"negate"`
-	regex  *regexp.Regexp `yaml:"-"`
+	Label  string `yaml:"label"`
+	Match  string `yaml:"match"`
+	Negate bool   `yaml:"negate"`
+	// Present requires the label to exist (true) or to not exist (false) when set.
+	Present *bool `yaml:"present"`
+	// NotEmpty requires the label to have a non-empty value.
+	NotEmpty bool `yaml:"not_empty"`
+	// MissingLabel defines how a target is treated when it doesn't have Label set. Either the target fails the filter
+	// (default) or the filter is ignored for this target.
+	MissingLabel string         `yaml:"missing_label"`
+	regex        *regexp.Regexp `yaml:"-"`
+}
+
+// AddressFilter defines a filter that is applied to each selected address of a target. An address matches when it is
+// part of any of the given prefixes. Negate inverts the match, excluding addresses within the prefixes.
+type AddressFilter struct {
+	Prefixes []string       `yaml:"prefixes"`
+	Negate   bool           `yaml:"negate"`
+	prefixes []netip.Prefix `yaml:"-"`
+}
+
+// Graveyard defines a separate file for targets whose device is in one of the given lifecycle statuses (e.g.
+// decommissioning). Such targets are written to File with additional Labels instead of being skipped.
+type Graveyard struct {
+	File     string         `yaml:"file"`
+	Statuses []string       `yaml:"statuses"`
+	Labels   model.LabelSet `yaml:"labels"`
+}
+
+// Hashmod splits targets into Shards files by a hash of their (first) address. The shard number is appended to the name
+// of the file (e.g. node_2.yml). Only the shards listed in Write are written; all shards when empty.
+type Hashmod struct {
+	Shards int   `yaml:"shards"`
+	Write  []int `yaml:"write"`
+}
+
+// Prefilter contains constraints that are sent to Netbox as part of the query, reducing the number of devices
+// returned. Sites, roles, tenants and platforms are referenced by slug. Multiple values of an attribute match any of
+// them.
+type Prefilter struct {
+	Sites     []string `yaml:"sites"`
+	Roles     []string `yaml:"roles"`
+	Tenants   []string `yaml:"tenants"`
+	Platforms []string `yaml:"platforms"`
+	Statuses  []string `yaml:"statuses"`
+}
+
+// ProbeMode defines the exporter probing the targets of a group. Each address of a target is written as __param_target
+// label of a separate target with Exporter as address. The module (see ModuleFor) and auth (see AuthFor) of a target are
+// set as __param_module and __param_auth labels when not empty.
+type ProbeMode struct {
+	Exporter string `yaml:"exporter"`
+	Module   string `yaml:"module"`
+	// ModuleCustomField is the name of a custom field containing the module of a target.
+	ModuleCustomField string `yaml:"module_custom_field"`
+	// PlatformModules maps platform names to the module of their targets.
+	PlatformModules map[string]string `yaml:"platform_modules"`
+	// Auth is the auth of all targets (e.g. of snmp_exporter) unless AuthCustomField is set for a target.
+	Auth            string `yaml:"auth"`
+	AuthCustomField string `yaml:"auth_custom_field"`
+}
+
+// ScrapeConfig contains the options of the Prometheus scrape config generated for a group. Empty values are omitted
+// and thus use Prometheus' defaults.
+type ScrapeConfig struct {
+	// JobName defaults to the group's file without extension.
+	JobName        string `yaml:"job_name"`
+	Scheme         string `yaml:"scheme"`
+	MetricsPath    string `yaml:"metrics_path"`
+	ScrapeInterval string `yaml:"scrape_interval"`
+	ScrapeTimeout  string `yaml:"scrape_timeout"`
+}
+
+// Scheme defines how the scrape scheme (__scheme__ label) of a target is determined. The value of CustomField (either
+// of the service or the device) is used when it is a valid scheme, otherwise Default is used.
+type Scheme struct {
+	CustomField string `yaml:"custom_field"`
+	Default     string `yaml:"default"`
 }
 
 const (
-	GroupTypeDeviceTag    = "device_tag"
-	GroupTypeInterfaceTag = "interface_tag"
-	GroupTypeService      = "service"
-	InetFamilyAny         = "any"
-	InetFamilyInet        = "inet"
-	InetFamilyInet6       = "inet6"
+	GroupTypeDeviceTag     = "device_tag"
+	GroupTypeInterfaceTag  = "interface_tag"
+	GroupTypeService       = "service"
+	GroupTypeCluster       = "cluster"
+	GroupTypeManufacturer  = "manufacturer"
+	GroupTypeVLAN          = "vlan"
+	GroupTypeVDCTag        = "vdc_tag"
+	GroupTypeClusterGroup  = "cluster_group"
+	GroupTypeSiteGroup     = "site_group"
+	GroupTypeConfigContext = "config_context"
+	GroupTypeRESTQuery     = "rest_query"
+	GroupTypeAll           = "all"
+	GroupTypeWirelessLAN   = "wireless_lan"
+	AllMatchDevices        = "devices"
+	AllMatchVMs            = "vms"
+	AllMatchAll            = "all"
+	MatchModeAll           = "all"
+	MatchModeAny           = "any"
+	InetFamilyAny          = "any"
+	InetFamilyInet         = "inet"
+	InetFamilyInet6        = "inet6"
+	CleanupModeDelete      = "delete"
+	CleanupModeTruncate    = "truncate"
+	DefaultConsulAddress   = "http://127.0.0.1:8500"
+	DefaultConsulNode      = "netbox_sd"
+	DefaultWebhookRetries  = 3
+	DefaultDNSZoneTTL      = 5 * time.Minute
+	DefaultEtcdPrefix      = "/netbox_sd/"
+	DefaultIcingaImport    = "generic-host"
+	FormatYAML             = "yaml"
+	FormatJSON             = "json"
+	MissingLabelFail       = "fail"
+	MissingLabelIgnore     = "ignore"
+	SchemeHTTP             = "http"
+	SchemeHTTPS            = "https"
+	APIGraphQL             = "graphql"
+	APIREST                = "rest"
+	// DefaultInstance is the name of the Netbox instance defined by base_url and api_token.
+	DefaultInstance = "default"
+	// DefaultIcingaTemplate renders a host with the primary IPs of its device as addresses, the groups it's part of
+	// and its labels as custom variables.
+	DefaultIcingaTemplate = `object Host {{ quote .Name }} {
+  import {{ quote .Import }}
+{{- if .Address }}
+  address = {{ quote .Address }}
+{{- end }}
+{{- if .Address6 }}
+  address6 = {{ quote .Address6 }}
+{{- end }}
+  vars.netbox_groups = [ {{ range $i, $group := .Groups }}{{ if $i }}, {{ end }}{{ quote $group }}{{ end }} ]
+{{- range $name, $value := .Labels }}
+  vars[{{ quote $name }}] = {{ quote $value }}
+{{- end }}
+}
+`
+)
+
+var (
+	// GroupTypes contains all supported group types.
+	GroupTypes []string = []string{
+		GroupTypeDeviceTag,
+		GroupTypeInterfaceTag,
+		GroupTypeService,
+		GroupTypeCluster,
+		GroupTypeManufacturer,
+		GroupTypeVLAN,
+		GroupTypeVDCTag,
+		GroupTypeClusterGroup,
+		GroupTypeSiteGroup,
+		GroupTypeConfigContext,
+		GroupTypeRESTQuery,
+		GroupTypeAll,
+		GroupTypeWirelessLAN,
+	}
+
+	// TargetStateLabels contains all labels that can be exposed with the target_state metric.
+	TargetStateLabels []string = []string{
+		"netbox_name",
+		"netbox_rack",
+		"netbox_site",
+		"netbox_tenant",
+		"netbox_role",
+		"netbox_serial_number",
+		"netbox_asset_tag",
+	}
+	// DefaultTargetStateLabels are the labels exposed with the target_state metric unless configured otherwise.
+	DefaultTargetStateLabels []string = []string{
+		"netbox_name",
+		"netbox_rack",
+		"netbox_site",
+		"netbox_tenant",
+		"netbox_role",
+	}
+	// IPStatuses contains all statuses of IP addresses in Netbox.
+	IPStatuses []string = []string{
+		netbox.StatusIPActive,
+		netbox.StatusIPReserved,
+		netbox.StatusIPDeprecated,
+		netbox.StatusIPDHCP,
+		netbox.StatusIPSLAAC,
+	}
+	// DefaultIPStatuses are the statuses of IP addresses selected for targets unless configured otherwise.
+	DefaultIPStatuses []string = []string{
+		netbox.StatusIPActive,
+		netbox.StatusIPDHCP,
+		netbox.StatusIPSLAAC,
+	}
+	// DefaultWebhookRetryInterval is the delay between two attempts of a webhook request unless configured otherwise.
+	DefaultWebhookRetryInterval time.Duration = 5 * time.Second
+	// DefaultWebhookTimeout is the timeout of a webhook request unless configured otherwise.
+	DefaultWebhookTimeout time.Duration = 10 * time.Second
+	// DefaultRequestTimeout is the timeout of a request towards Netbox unless configured otherwise.
+	DefaultRequestTimeout time.Duration = 5 * time.Minute
+	// DefaultDialTimeout is the timeout of connecting to Netbox unless configured otherwise.
+	DefaultDialTimeout time.Duration = 30 * time.Second
+	// DefaultPageSize is the number of objects requested per GraphQL request of list queries unless configured
+	// otherwise. It matches the default MAX_PAGE_SIZE of Netbox.
+	DefaultPageSize int = 1000
+	// DefaultMaxAttempts is the number of attempts of a failed request towards Netbox unless configured otherwise.
+	DefaultMaxAttempts int = 3
+	// DefaultRetryBackoff is the delay before retrying a failed request towards Netbox unless configured otherwise.
+	DefaultRetryBackoff time.Duration = time.Second
+	// DefaultFileMode is the permission mode of target files unless configured otherwise.
+	DefaultFileMode os.FileMode = 0664
+	// TLSVersions maps the values of TLS.MinVersion to TLS versions.
+	TLSVersions map[string]uint16 = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+	// icingaEscaper escapes all characters that need to be escaped in Icinga2 strings.
+	icingaEscaper *strings.Replacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	// kubernetesName matches a DNS subdomain as used for names of Kubernetes objects.
+	kubernetesName *regexp.Regexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]{0,251}[a-z0-9])?$`)
+	// dnsName matches a DNS domain name (with or without trailing dot).
+	dnsName *regexp.Regexp = regexp.MustCompile(`^([a-zA-Z0-9_]([a-zA-Z0-9_-]{0,61}[a-zA-Z0-9_])?\.)*[a-zA-Z0-9_]([a-zA-Z0-9_-]{0,61}[a-zA-Z0-9_])?\.?$`)
 )
 
 var (
-	ErrorBadFilterLabel    = errors.New("bad label for filter provided (must start with 'netbox_')")
-	ErrorBadFilterMatch    = errors.New("bad filter match provided")
-	ErrorBadGroupType      = errors.New("bad group type value")
-	ErrorBadInetFamily     = errors.New("bad inet_family value provided")
-	ErrorBadPort           = errors.New("bad port value")
-	ErrorBadScanInterval   = errors.New("failed to parse scan_interval")
-	ErrorBaseURLMissingTLS = errors.New("netbox_base_url must start with https and support tls")
-	ErrorDuplicateFile     = errors.New("duplicate file name in configuration")
-	ErrorMissingFile       = errors.New("missing config file path")
-	ErrorMissingRequired   = errors.New("missing one or more required config values")
-	ErrorParsingFile       = errors.New("failed to parse config file")
-	ErrorReadingFile       = errors.New("failed to read config file")
+	ErrorBadAPI                = errors.New("bad api value (must be graphql or rest)")
+	ErrorBadAddressFilter      = errors.New("bad address filter prefix provided")
+	ErrorBadAllMatch           = errors.New("bad all match value (must be devices, vms or all)")
+	ErrorBadBackups            = errors.New("bad backups value (must not be negative)")
+	ErrorBadCacheTTL           = errors.New("failed to parse cache_ttl")
+	ErrorBadCleanupMode        = errors.New("bad cleanup_mode value (must be delete or truncate)")
+	ErrorBadClientOptions      = errors.New("bad client_options config provided")
+	ErrorBadConfigContextMatch = errors.New("bad config_context match (must be path.to.key or path.to.key=value)")
+	ErrorBadConfigMap          = errors.New("bad kubernetes_configmap config provided")
+	ErrorBadConsul             = errors.New("bad consul config provided")
+	ErrorBadDNSZone            = errors.New("bad dns_zone config provided")
+	ErrorBadEtcd               = errors.New("bad etcd config provided")
+	ErrorBadFileMode           = errors.New("bad file_mode value (must be an octal permission mode like 0640)")
+	ErrorBadFileOwner          = errors.New("bad file_owner or file_group provided")
+	ErrorBadFileTemplate       = errors.New("bad file_template provided")
+	ErrorBadFilterCombination  = errors.New("present: false cannot be combined with match or not_empty")
+	ErrorBadFilterLabel        = errors.New("bad label for filter provided (must start with 'netbox_')")
+	ErrorBadFilterMatch        = errors.New("bad filter match provided")
+	ErrorBadFormat             = errors.New("bad format value (must be yaml or json)")
+	ErrorBadGraveyard          = errors.New("bad graveyard config provided")
+	ErrorBadGroupType          = errors.New("bad group type value")
+	ErrorBadHashmod            = errors.New("bad hashmod config provided")
+	ErrorBadIcinga             = errors.New("bad icinga config provided")
+	ErrorBadInetFamily         = errors.New("bad inet_family value provided")
+	ErrorBadIPStatus           = errors.New("bad ip_statuses value provided")
+	ErrorBadLabelTemplate      = errors.New("bad label template provided")
+	ErrorBadMatchList          = errors.New("bad match list provided")
+	ErrorBadMatchMode          = errors.New("bad match_mode value (must be all or any)")
+	ErrorBadMaxTargets         = errors.New("bad max_targets value (must not be negative)")
+	ErrorBadNameMatch          = errors.New("bad name_match regular expression provided")
+	ErrorBadMissingLabel       = errors.New("bad missing_label value provided")
+	ErrorBadPageSize           = errors.New("bad graphql_page_size value (must not be negative)")
+	ErrorBadPort               = errors.New("bad port value")
+	ErrorBadPrefilter          = errors.New("bad prefilter provided")
+	ErrorBadProbeMode          = errors.New("bad probe_mode config provided")
+	ErrorBadRelabelConfig      = errors.New("bad relabel_configs value provided")
+	ErrorBadRESTQuery          = errors.New("bad rest_query match (must be URL query parameters)")
+	ErrorBadScanInterval       = errors.New("failed to parse scan_interval")
+	ErrorBadScheme             = errors.New("bad scheme config provided")
+	ErrorBadScrapeConfig       = errors.New("bad scrape_config provided")
+	ErrorBadStartupStagger     = errors.New("failed to parse startup_stagger")
+	ErrorBadTagExpression      = errors.New("bad tag expression")
+	ErrorBadTargetStateLabel   = errors.New("bad target_state_labels value provided")
+	ErrorBadTLSConfig          = errors.New("bad tls config provided")
+	ErrorBadTLSPin             = errors.New("bad tls_pinned_public_keys value")
+	ErrorBadTokenFile          = errors.New("bad api_token_file config provided")
+	ErrorBadWebhook            = errors.New("bad webhook config provided")
+	ErrorUnknownFilterSet      = errors.New("unknown filter set referenced")
+	ErrorUnknownInstance       = errors.New("unknown netbox instance referenced")
+	ErrorUnknownLabelSet       = errors.New("unknown label set referenced")
+	ErrorBaseURLMissingTLS     = errors.New("netbox_base_url must start with https and support tls")
+	ErrorDuplicateFile         = errors.New("duplicate file name in configuration")
+	ErrorDuplicateInstance     = errors.New("duplicate netbox instance name in configuration")
+	ErrorMissingFile           = errors.New("missing config file path")
+	ErrorMissingRequired       = errors.New("missing one or more required config values")
+	ErrorParsingFile           = errors.New("failed to parse config file")
+	ErrorReadingFile           = errors.New("failed to read config file")
 )
 
 // ReadConfigFile reads and parses a given config file
@@ -110,6 +665,9 @@ func ReadConfigFile(file string) (*Config, error) {
 		knownFiles  map[string]int = make(map[string]int)
 		ok          bool
 		i           int
+		name        string
+		filters     []*Filter
+		pageSize    int = DefaultPageSize
 	)
 
 	if file == "" {
@@ -123,39 +681,233 @@ func ReadConfigFile(file string) (*Config, error) {
 
 	err = yaml.Unmarshal(fileContent, &config)
 	if err != nil {
-		fmt.Printf("%s", err.Error())
-		return nil, fmt.Errorf("%w: %s", ErrorParsingFile, err.Error())
+		return nil, fmt.Errorf("%w: %w", ErrorParsingFile, err)
+	}
+
+	// Whether parsing is strict is only known after parsing, thus the file is parsed again to detect unknown options.
+	if config.strict() {
+		err = unmarshalStrict(fileContent, &Config{})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrorParsingFile, err)
+		}
+	}
+
+	if config.StateFile != "" && !filepath.IsAbs(config.StateFile) {
+		config.StateFile = filepath.Join(filepath.Dir(file), config.StateFile)
+	}
+
+	if config.ChangelogFile != "" && !filepath.IsAbs(config.ChangelogFile) {
+		config.ChangelogFile = filepath.Join(filepath.Dir(file), config.ChangelogFile)
 	}
 
-	// check for required values
-	if config.BaseURL == "" ||
-		config.Token == "" ||
+	if config.ManifestFile != "" && !filepath.IsAbs(config.ManifestFile) {
+		config.ManifestFile = filepath.Join(filepath.Dir(file), config.ManifestFile)
+	}
+
+	if config.TargetDBFile != "" && !filepath.IsAbs(config.TargetDBFile) {
+		config.TargetDBFile = filepath.Join(filepath.Dir(file), config.TargetDBFile)
+	}
+
+	if config.Format == "" {
+		// setting default
+		config.Format = FormatYAML
+	}
+
+	if err = validateConsul(config.Consul); err != nil {
+		return nil, err
+	}
+
+	if err = validateWebhook(config.Webhook); err != nil {
+		return nil, err
+	}
+
+	if err = validateClientOptions(&config.ClientOptions); err != nil {
+		return nil, err
+	}
+
+	if err = validateEtcd(config.Etcd); err != nil {
+		return nil, err
+	}
+
+	if err = validateConfigMap(config.ConfigMap); err != nil {
+		return nil, err
+	}
+
+	if config.Format != FormatYAML && config.Format != FormatJSON {
+		return nil, ErrorBadFormat
+	}
+
+	if config.PageSize == nil {
+		// setting default
+		config.PageSize = &pageSize
+	}
+
+	if *config.PageSize < 0 {
+		return nil, ErrorBadPageSize
+	}
+
+	if config.CombinedFile != "" {
+		knownFiles[config.CombinedFile] = 1
+	}
+
+	if err = validateDNSZone(config.DNSZone); err != nil {
+		return nil, err
+	}
+
+	if config.DNSZone != nil {
+		if _, ok = knownFiles[config.DNSZone.File]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrorDuplicateFile, config.DNSZone.File)
+		}
+
+		knownFiles[config.DNSZone.File] = 1
+	}
+
+	if err = validateIcinga(config.Icinga); err != nil {
+		return nil, err
+	}
+
+	if config.Icinga != nil {
+		if _, ok = knownFiles[config.Icinga.File]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrorDuplicateFile, config.Icinga.File)
+		}
+
+		knownFiles[config.Icinga.File] = 1
+	}
+
+	if config.ManifestFile != "" {
+		if _, ok = knownFiles[config.ManifestFile]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrorDuplicateFile, config.ManifestFile)
+		}
+
+		knownFiles[config.ManifestFile] = 1
+	}
+
+	if config.TargetDBFile != "" {
+		if _, ok = knownFiles[config.TargetDBFile]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrorDuplicateFile, config.TargetDBFile)
+		}
+
+		knownFiles[config.TargetDBFile] = 1
+	}
+
+	if config.CleanupMode == "" {
+		// setting default
+		config.CleanupMode = CleanupModeDelete
+	}
+
+	if config.CleanupMode != CleanupModeDelete && config.CleanupMode != CleanupModeTruncate {
+		return nil, ErrorBadCleanupMode
+	}
+
+	if config.GroupsDir != "" {
+		if !filepath.IsAbs(config.GroupsDir) {
+			config.GroupsDir = filepath.Join(filepath.Dir(file), config.GroupsDir)
+		}
+
+		err = config.readGroupsDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// check for required values; the default instance is optional when other instances are defined
+	if (config.BaseURL == "" && len(config.Instances) == 0) ||
+		(config.BaseURL == "") != (config.Token == "" && config.TokenFile == "") ||
 		config.ScanIntervalString == "" ||
 		len(config.Groups) == 0 {
 		return nil, fmt.Errorf("global configuration: %w", ErrorMissingRequired)
 	}
 
-	if !strings.HasPrefix(config.BaseURL, "https") {
+	if config.BaseURL != "" && !strings.HasPrefix(config.BaseURL, "https") {
 		return nil, ErrorBaseURLMissingTLS
 	}
 
+	if err = validateAPI(&config.API); err != nil {
+		return nil, err
+	}
+
+	if err = validateInstances(config.Instances, filepath.Dir(file)); err != nil {
+		return nil, err
+	}
+
+	if err = config.TLS.validate(filepath.Dir(file)); err != nil {
+		return nil, err
+	}
+
+	if err = validateTokenFile(config.Token, &config.TokenFile, filepath.Dir(file)); err != nil {
+		return nil, err
+	}
+
 	// parse scan_interval
 	config.ScanInterval, err = time.ParseDuration(config.ScanIntervalString)
 	if err != nil {
 		return nil, ErrorBadScanInterval
 	}
 
+	if config.StartupStaggerString != "" {
+		config.StartupStagger, err = time.ParseDuration(config.StartupStaggerString)
+		if err != nil || config.StartupStagger < 0 {
+			return nil, ErrorBadStartupStagger
+		}
+	}
+
+	if config.CacheTTLString != "" {
+		config.CacheTTL, err = time.ParseDuration(config.CacheTTLString)
+		if err != nil || config.CacheTTL < 0 {
+			return nil, ErrorBadCacheTTL
+		}
+	}
+
+	if config.TargetStateLabels == nil {
+		// setting default; serial number and asset tag are not exposed unless explicitly configured
+		config.TargetStateLabels = DefaultTargetStateLabels
+	}
+
+	for i = range config.TargetStateLabels {
+		if !contains(TargetStateLabels, config.TargetStateLabels[i]) {
+			return nil, fmt.Errorf("%w: %s", ErrorBadTargetStateLabel, config.TargetStateLabels[i])
+		}
+	}
+
+	for i = range config.PinnedPublicKeys {
+		if err = netbox.ValidatePin(config.PinnedPublicKeys[i]); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrorBadTLSPin, err.Error())
+		}
+	}
+
+	if err = validateFilters(config.Defaults.Filters); err != nil {
+		return nil, fmt.Errorf("failed to validate defaults: %w", err)
+	}
+
+	for name, filters = range config.FilterSets {
+		if err = validateFilters(filters); err != nil {
+			return nil, fmt.Errorf("failed to validate filter set %s: %w", name, err)
+		}
+	}
+
 	// check all groups for required values & sanity
 	for i, group = range config.Groups {
 		// check for duplicate file name
 		if _, ok = knownFiles[group.File]; ok {
-			return nil, ErrorDuplicateFile
+			return nil, fmt.Errorf("%w: %s", ErrorDuplicateFile, group.File)
 		} else {
 			// add new file to knownFiles
 			knownFiles[group.File] = 1
 		}
 
+		if group.Graveyard != nil {
+			if _, ok = knownFiles[group.Graveyard.File]; ok {
+				return nil, fmt.Errorf("%w: %s", ErrorDuplicateFile, group.Graveyard.File)
+			}
+
+			knownFiles[group.Graveyard.File] = 1
+		}
+
 		if err = validateGroup(group, &config); err != nil {
+			if group.Source != "" {
+				return nil, fmt.Errorf("failed to validate group config %s in %s: %w", group.File, group.Source, err)
+			}
+
 			return nil, fmt.Errorf("failed to validate group config with index %d: %w", i, err)
 		}
 	}
@@ -163,103 +915,1423 @@ func ReadConfigFile(file string) (*Config, error) {
 	return &config, nil
 }
 
-// ValidateGroup checks the contents of group.
-func validateGroup(group *Group, config *Config) error {
+// strict returns true when unknown options must fail parsing the config.
+func (config *Config) strict() bool {
+	return config.StrictParsing == nil || *config.StrictParsing
+}
+
+// unmarshalStrict decodes the yaml document data into out and fails on options not known to out.
+func unmarshalStrict(data []byte, out any) error {
 	var (
-		err error
+		decoder *yaml.Decoder = yaml.NewDecoder(bytes.NewReader(data))
+		err     error
 	)
 
-	if group.File == "" ||
-		group.Type == "" ||
-		group.Match == "" {
-		return ErrorMissingRequired
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(out)
+	// empty documents are handled like yaml.Unmarshal does
+	if errors.Is(err, io.EOF) {
+		return nil
 	}
 
-	if group.Type != GroupTypeService &&
-		group.Type != GroupTypeDeviceTag &&
-		group.Type != GroupTypeInterfaceTag {
-		return ErrorBadGroupType
+	return err
+}
+
+// readGroupsDir appends the groups of all *.yml files in GroupsDir (in lexical order) to the config's groups.
+func (config *Config) readGroupsDir() error {
+	var (
+		files       []string
+		file        string
+		fileContent []byte
+		content     groupsFile
+		group       *Group
+		err         error
+	)
+
+	// Glob only fails for malformed patterns, thus a missing directory has to be checked separately.
+	if _, err = os.Stat(config.GroupsDir); err != nil {
+		return fmt.Errorf("%w: %s", ErrorReadingFile, err.Error())
 	}
 
-	if group.ScanIntervalString != "" {
-		// parse scan_interval
-		group.ScanInterval, err = time.ParseDuration(group.ScanIntervalString)
+	files, err = filepath.Glob(filepath.Join(config.GroupsDir, "*.yml"))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrorReadingFile, err.Error())
+	}
+
+	for _, file = range files {
+		fileContent, err = os.ReadFile(file)
 		if err != nil {
-			return ErrorBadScanInterval
+			return fmt.Errorf("%w: %s", ErrorReadingFile, err.Error())
 		}
-	} else {
-		// use default
-		group.ScanInterval = config.ScanInterval
-	}
 
-	if group.Port != nil {
-		if *group.Port < 0 || *group.Port > 65535 {
-			// port is invalid
-			return ErrorBadPort
+		content = groupsFile{}
+
+		if config.strict() {
+			err = unmarshalStrict(fileContent, &content)
+		} else {
+			err = yaml.Unmarshal(fileContent, &content)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrorParsingFile, file, err)
 		}
+
+		for _, group = range content.Groups {
+			group.Source = file
+		}
+
+		config.Groups = append(config.Groups, content.Groups...)
 	}
 
-	// start checking flags
-	if group.Flags.IncludeVMs == nil {
-		// setting default
-		group.Flags.IncludeVMs = new(bool)
-		*group.Flags.IncludeVMs = true
+	return nil
+}
+
+// validateConsul checks the Consul config and sets defaults. A nil Consul is valid.
+func validateConsul(consul *Consul) error {
+	var err error
+
+	if consul == nil {
+		return nil
 	}
 
-	if group.Flags.InetFamily == nil {
+	if consul.Address == "" {
 		// setting default
-		group.Flags.InetFamily = new(string)
-		*group.Flags.InetFamily = InetFamilyAny
-	} else if *group.Flags.InetFamily != InetFamilyAny &&
-		*group.Flags.InetFamily != InetFamilyInet &&
-		*group.Flags.InetFamily != InetFamilyInet6 {
+		consul.Address = DefaultConsulAddress
+	}
 
-		return ErrorBadInetFamily
+	if _, err = url.ParseRequestURI(consul.Address); err != nil {
+		return fmt.Errorf("%w: %s", ErrorBadConsul, err.Error())
 	}
 
-	if group.Flags.AllAddresses == nil {
+	if consul.Node == "" {
 		// setting default
-		group.Flags.AllAddresses = new(bool)
-		*group.Flags.AllAddresses = false
+		consul.Node = DefaultConsulNode
 	}
 
-	return validateFilters(group.Filters)
+	return nil
 }
 
-// ValidateFilters checks that filters are valid.
-func validateFilters(filters []*Filter) error {
-	var (
-		filter *Filter
-		err    error
-	)
+// validateDNSZone checks the DNS zone config and sets defaults. A nil DNSZone is valid.
+func validateDNSZone(zone *DNSZone) error {
+	var err error
 
-	for _, filter = range filters {
-		// Labels must start with `netbox_` to match in any case.
-		if !strings.HasPrefix(filter.Label, "netbox_") {
-			return ErrorBadFilterLabel
-		}
+	if zone == nil {
+		return nil
+	}
 
-		filter.regex, err = regexp.Compile(filter.Match)
-		if err != nil {
-			return fmt.Errorf("%w: %s", ErrorBadFilterMatch, err.Error())
+	if zone.File == "" {
+		return fmt.Errorf("%w: missing file", ErrorBadDNSZone)
+	}
+
+	if zone.Domain != "" && !dnsName.MatchString(zone.Domain) {
+		return fmt.Errorf("%w: invalid domain %s", ErrorBadDNSZone, zone.Domain)
+	}
+
+	zone.TTL = DefaultDNSZoneTTL
+	if zone.TTLString != "" {
+		zone.TTL, err = time.ParseDuration(zone.TTLString)
+		if err != nil || zone.TTL < time.Second {
+			return fmt.Errorf("%w: bad ttl %s", ErrorBadDNSZone, zone.TTLString)
 		}
 	}
 
 	return nil
 }
 
-// FiltersMatch returns true if all filters match with the target's labels.
-func (group *Group) FiltersMatch(target *targetgroup.Group) bool {
-	var (
-		filter *Filter
-		ok     bool
-		val    model.LabelValue
-	)
+// validateIcinga checks the Icinga config, sets defaults and parses its template. A nil Icinga is valid.
+func validateIcinga(icinga *Icinga) error {
+	var err error
 
-	for _, filter = range group.Filters {
-		if val, ok = target.Labels[model.LabelName(filter.Label)]; !ok {
-			// Filter label doesn't exist for target and therefore cannot match.
-			return false
+	if icinga == nil {
+		return nil
+	}
+
+	if icinga.File == "" {
+		return fmt.Errorf("%w: missing file", ErrorBadIcinga)
+	}
+
+	if icinga.Template == "" {
+		// setting default
+		icinga.Template = DefaultIcingaTemplate
+	}
+
+	if icinga.Import == "" {
+		// setting default
+		icinga.Import = DefaultIcingaImport
+	}
+
+	icinga.hostTemplate, err = template.New("icinga").
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"quote": IcingaQuote}).
+		Parse(icinga.Template)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrorBadIcinga, err.Error())
+	}
+
+	return nil
+}
+
+// validateConfigMap checks the kubernetes_configmap config. A nil ConfigMap is valid.
+func validateConfigMap(configMap *ConfigMap) error {
+	if configMap == nil {
+		return nil
+	}
+
+	if !kubernetesName.MatchString(configMap.Name) {
+		return fmt.Errorf("%w: invalid name '%s'", ErrorBadConfigMap, configMap.Name)
+	}
+
+	if configMap.Namespace != "" && !kubernetesName.MatchString(configMap.Namespace) {
+		return fmt.Errorf("%w: invalid namespace '%s'", ErrorBadConfigMap, configMap.Namespace)
+	}
+
+	return nil
+}
+
+// validateEtcd checks the etcd config and sets defaults. A nil Etcd is valid.
+func validateEtcd(etcd *Etcd) error {
+	var (
+		endpoint string
+		u        *url.URL
+		err      error
+	)
+
+	if etcd == nil {
+		return nil
+	}
+
+	if len(etcd.Endpoints) == 0 {
+		return fmt.Errorf("%w: missing endpoints", ErrorBadEtcd)
+	}
+
+	for _, endpoint = range etcd.Endpoints {
+		if u, err = url.Parse(endpoint); err != nil {
+			return fmt.Errorf("%w: %s", ErrorBadEtcd, err.Error())
+		}
+
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: endpoint %s must be a http(s) URL", ErrorBadEtcd, endpoint)
+		}
+	}
+
+	if etcd.Prefix == "" {
+		// setting default
+		etcd.Prefix = DefaultEtcdPrefix
+	}
+
+	if etcd.Password != "" && etcd.Username == "" {
+		return fmt.Errorf("%w: password requires username", ErrorBadEtcd)
+	}
+
+	return nil
+}
+
+// validateWebhook checks the webhook config and sets defaults. A nil Webhook is valid.
+func validateWebhook(webhook *Webhook) error {
+	var (
+		retries int = DefaultWebhookRetries
+		err     error
+	)
+
+	if webhook == nil {
+		return nil
+	}
+
+	if _, err = url.ParseRequestURI(webhook.URL); err != nil {
+		return fmt.Errorf("%w: %s", ErrorBadWebhook, err.Error())
+	}
+
+	if webhook.Retries == nil {
+		// setting default
+		webhook.Retries = &retries
+	}
+
+	if *webhook.Retries < 0 {
+		return fmt.Errorf("%w: retries must not be negative", ErrorBadWebhook)
+	}
+
+	webhook.RetryInterval = DefaultWebhookRetryInterval
+	if webhook.RetryIntervalString != "" {
+		webhook.RetryInterval, err = time.ParseDuration(webhook.RetryIntervalString)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrorBadWebhook, err.Error())
+		}
+	}
+
+	webhook.Timeout = DefaultWebhookTimeout
+	if webhook.TimeoutString != "" {
+		webhook.Timeout, err = time.ParseDuration(webhook.TimeoutString)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrorBadWebhook, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// validateClientOptions parses all timeouts and retry options of options and sets defaults.
+func validateClientOptions(options *ClientOptions) error {
+	var (
+		attempts int = DefaultMaxAttempts
+		err      error
+	)
+
+	options.RequestTimeout = DefaultRequestTimeout
+	if options.RequestTimeoutString != "" {
+		options.RequestTimeout, err = time.ParseDuration(options.RequestTimeoutString)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrorBadClientOptions, err.Error())
+		}
+	}
+
+	options.DialTimeout = DefaultDialTimeout
+	if options.DialTimeoutString != "" {
+		options.DialTimeout, err = time.ParseDuration(options.DialTimeoutString)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrorBadClientOptions, err.Error())
+		}
+	}
+
+	if options.ResponseHeaderTimeoutString != "" {
+		options.ResponseHeaderTimeout, err = time.ParseDuration(options.ResponseHeaderTimeoutString)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrorBadClientOptions, err.Error())
+		}
+	}
+
+	if options.RequestTimeout < 0 || options.DialTimeout < 0 || options.ResponseHeaderTimeout < 0 {
+		return fmt.Errorf("%w: timeouts must not be negative", ErrorBadClientOptions)
+	}
+
+	if options.MaxAttempts == nil {
+		// setting default
+		options.MaxAttempts = &attempts
+	}
+
+	if *options.MaxAttempts < 1 {
+		return fmt.Errorf("%w: max_attempts must be at least 1", ErrorBadClientOptions)
+	}
+
+	options.RetryBackoff = DefaultRetryBackoff
+	if options.RetryBackoffString != "" {
+		options.RetryBackoff, err = time.ParseDuration(options.RetryBackoffString)
+		if err != nil || options.RetryBackoff <= 0 {
+			return fmt.Errorf("%w: bad retry_backoff %s", ErrorBadClientOptions, options.RetryBackoffString)
+		}
+	}
+
+	if options.ProxyURLString != "" {
+		options.ProxyURL, err = url.Parse(options.ProxyURLString)
+		if err != nil || options.ProxyURL.Host == "" ||
+			!slices.Contains([]string{"http", "https", "socks5"}, options.ProxyURL.Scheme) {
+			return fmt.Errorf("%w: bad proxy_url %s", ErrorBadClientOptions, options.ProxyURLString)
+		}
+	}
+
+	if strings.ContainsFunc(options.UserAgentSuffix, unicode.IsControl) {
+		return fmt.Errorf("%w: user_agent_suffix must not contain control characters", ErrorBadClientOptions)
+	}
+
+	return nil
+}
+
+// validateInstances checks all additional Netbox instances for required values and unique names. Relative TLS file
+// paths are made relative to dir.
+func validateInstances(instances []*Instance, dir string) error {
+	var (
+		instance *Instance
+		known    map[string]bool = make(map[string]bool)
+		i        int
+		err      error
+	)
+
+	for _, instance = range instances {
+		if instance.Name == "" ||
+			instance.BaseURL == "" ||
+			(instance.Token == "" && instance.TokenFile == "") {
+			return fmt.Errorf("netbox instance: %w", ErrorMissingRequired)
+		}
+
+		// the default instance is referred to as "default" in metrics
+		if known[instance.Name] || instance.Name == DefaultInstance {
+			return fmt.Errorf("%w: %s", ErrorDuplicateInstance, instance.Name)
+		}
+
+		known[instance.Name] = true
+
+		if !strings.HasPrefix(instance.BaseURL, "https") {
+			return fmt.Errorf("netbox instance %s: %w", instance.Name, ErrorBaseURLMissingTLS)
+		}
+
+		for i = range instance.PinnedPublicKeys {
+			if err = netbox.ValidatePin(instance.PinnedPublicKeys[i]); err != nil {
+				return fmt.Errorf("%w: %s", ErrorBadTLSPin, err.Error())
+			}
+		}
+
+		if err = instance.TLS.validate(dir); err != nil {
+			return fmt.Errorf("netbox instance %s: %w", instance.Name, err)
+		}
+
+		if err = validateTokenFile(instance.Token, &instance.TokenFile, dir); err != nil {
+			return fmt.Errorf("netbox instance %s: %w", instance.Name, err)
+		}
+
+		if err = validateAPI(&instance.API); err != nil {
+			return fmt.Errorf("netbox instance %s: %w", instance.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateAPI checks the api value and sets it to APIGraphQL when empty.
+func validateAPI(api *string) error {
+	switch *api {
+	case "":
+		*api = APIGraphQL
+	case APIGraphQL, APIREST:
+	default:
+		return fmt.Errorf("%w: %s", ErrorBadAPI, *api)
+	}
+
+	return nil
+}
+
+// validate checks the TLS options and makes relative file paths relative to dir. A nil TLS is valid.
+func (tlsConfig *TLS) validate(dir string) error {
+	var (
+		file *string
+		ok   bool
+	)
+
+	if tlsConfig == nil {
+		return nil
+	}
+
+	if (tlsConfig.CertFile == "") != (tlsConfig.KeyFile == "") {
+		return fmt.Errorf("%w: cert_file and key_file must be set together", ErrorBadTLSConfig)
+	}
+
+	if _, ok = TLSVersions[tlsConfig.MinVersion]; tlsConfig.MinVersion != "" && !ok {
+		return fmt.Errorf("%w: unknown min_version %s", ErrorBadTLSConfig, tlsConfig.MinVersion)
+	}
+
+	for _, file = range []*string{&tlsConfig.CAFile, &tlsConfig.CertFile, &tlsConfig.KeyFile} {
+		if *file != "" && !filepath.IsAbs(*file) {
+			*file = filepath.Join(dir, *file)
+		}
+	}
+
+	return nil
+}
+
+// validateTokenFile checks that api_token and api_token_file aren't set together. A relative tokenFile is made relative
+// to dir.
+func validateTokenFile(token string, tokenFile *string, dir string) error {
+	if *tokenFile == "" {
+		return nil
+	}
+
+	if token != "" {
+		return fmt.Errorf("%w: api_token and api_token_file are mutually exclusive", ErrorBadTokenFile)
+	}
+
+	if !filepath.IsAbs(*tokenFile) {
+		*tokenFile = filepath.Join(dir, *tokenFile)
+	}
+
+	return nil
+}
+
+// TLSConfig returns the TLS options of instance for the Netbox client. AllowInsecure is applied as InsecureSkipVerify.
+func (instance *Instance) TLSConfig() *netbox.TLSConfig {
+	var result *netbox.TLSConfig = &netbox.TLSConfig{InsecureSkipVerify: instance.AllowInsecure}
+
+	if instance.TLS != nil {
+		result.CAFile = instance.TLS.CAFile
+		result.CertFile = instance.TLS.CertFile
+		result.KeyFile = instance.TLS.KeyFile
+		result.MinVersion = TLSVersions[instance.TLS.MinVersion]
+		result.ServerName = instance.TLS.ServerName
+		result.InsecureSkipVerify = result.InsecureSkipVerify || instance.TLS.InsecureSkipVerify
+	}
+
+	return result
+}
+
+// Options returns the options of the Netbox client of instance using the TLS options of instance and the timeouts and
+// retry options of options.
+func (instance *Instance) Options(options *ClientOptions) netbox.ClientOptions {
+	return netbox.ClientOptions{
+		TLS:                   instance.TLSConfig(),
+		Timeout:               options.RequestTimeout,
+		DialTimeout:           options.DialTimeout,
+		ResponseHeaderTimeout: options.ResponseHeaderTimeout,
+		MaxAttempts:           *options.MaxAttempts,
+		RetryBackoff:          options.RetryBackoff,
+		Proxy:                 options.ProxyURL,
+	}
+}
+
+// Instance returns the Netbox instance called name or nil if it doesn't exist.
+func (config *Config) Instance(name string) *Instance {
+	var instance *Instance
+
+	for _, instance = range config.Instances {
+		if instance.Name == name {
+			return instance
+		}
+	}
+
+	return nil
+}
+
+// InstanceFor returns the connection to Netbox used by group. This is the instance referred to by the group (or the
+// default instance) with the group's base_url and api_token applied. The instance's name is kept.
+func (config *Config) InstanceFor(group *Group) *Instance {
+	var instance Instance
+
+	if group.Instance != "" {
+		instance = *config.Instance(group.Instance)
+	} else {
+		instance = Instance{
+			Name:             DefaultInstance,
+			BaseURL:          config.BaseURL,
+			Token:            config.Token,
+			TokenFile:        config.TokenFile,
+			AllowInsecure:    config.AllowInsecure,
+			TLS:              config.TLS,
+			PinnedPublicKeys: config.PinnedPublicKeys,
+			API:              config.API,
+		}
+	}
+
+	if group.BaseURL != "" {
+		instance.BaseURL = group.BaseURL
+	}
+
+	if group.Token != "" {
+		instance.Token = group.Token
+		instance.TokenFile = ""
+	}
+
+	return &instance
+}
+
+// GroupsByPriority returns all groups ordered by priority (highest first). Groups with the same priority keep the order
+// in which they have been defined.
+func (config *Config) GroupsByPriority() []*Group {
+	var groups []*Group = make([]*Group, len(config.Groups))
+
+	copy(groups, config.Groups)
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Priority > groups[j].Priority
+	})
+
+	return groups
+}
+
+// ValidateGroup checks the contents of group.
+func validateGroup(group *Group, config *Config) error {
+	var (
+		err    error
+		name   string
+		ok     bool
+		labels model.LabelSet
+	)
+
+	if group.Match.IsList() {
+		if group.Type != GroupTypeDeviceTag &&
+			group.Type != GroupTypeInterfaceTag &&
+			group.Type != GroupTypeVDCTag {
+			return fmt.Errorf("%w: only supported by tag based group types", ErrorBadMatchList)
+		}
+
+		group.Match.Value = group.Match.expr(group.MatchMode)
+	}
+
+	if group.File == "" ||
+		group.Type == "" ||
+		group.Match.Value == "" {
+		return ErrorMissingRequired
+	}
+
+	if !contains(GroupTypes, group.Type) {
+		return ErrorBadGroupType
+	}
+
+	// a group overriding both base_url and api_token doesn't need a default instance
+	if (group.Instance == "" && config.BaseURL == "" && (group.BaseURL == "" || group.Token == "")) ||
+		(group.Instance != "" && config.Instance(group.Instance) == nil) {
+		return fmt.Errorf("%w: %q", ErrorUnknownInstance, group.Instance)
+	}
+
+	if group.BaseURL != "" && !strings.HasPrefix(group.BaseURL, "https") {
+		return ErrorBaseURLMissingTLS
+	}
+
+	config.Defaults.apply(group)
+
+	if group.Type == GroupTypeDeviceTag ||
+		group.Type == GroupTypeInterfaceTag ||
+		group.Type == GroupTypeVDCTag {
+		group.TagExpr, err = ParseTagExpr(group.Match.Value)
+		if err != nil {
+			return err
+		}
+
+		if _, ok = group.TagExpr.QueryTags(); !ok {
+			return fmt.Errorf("%w: at least one tag must be required", ErrorBadTagExpression)
+		}
+	}
+
+	if group.MatchMode != "" && group.MatchMode != MatchModeAll && group.MatchMode != MatchModeAny {
+		return ErrorBadMatchMode
+	}
+
+	if group.NameMatch != "" {
+		group.nameRegex, err = regexp.Compile(group.NameMatch)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrorBadNameMatch, err.Error())
+		}
+	}
+
+	if group.Prefilter != nil && group.Type != GroupTypeDeviceTag {
+		return fmt.Errorf("%w: only supported by device_tag groups", ErrorBadPrefilter)
+	}
+
+	if group.Type == GroupTypeConfigContext {
+		if err = validateConfigContextMatch(group); err != nil {
+			return err
+		}
+	}
+
+	if group.Type == GroupTypeAll &&
+		group.Match.Value != AllMatchDevices &&
+		group.Match.Value != AllMatchVMs &&
+		group.Match.Value != AllMatchAll {
+		return ErrorBadAllMatch
+	}
+
+	if group.Type == GroupTypeRESTQuery {
+		if _, err = url.ParseQuery(group.Match.Value); err != nil {
+			return fmt.Errorf("%w: %v", ErrorBadRESTQuery, err)
+		}
+	}
+
+	if group.ScanIntervalString != "" {
+		// parse scan_interval
+		group.ScanInterval, err = time.ParseDuration(group.ScanIntervalString)
+		if err != nil {
+			return ErrorBadScanInterval
+		}
+	} else {
+		// use default
+		group.ScanInterval = config.ScanInterval
+	}
+
+	if len(group.LabelSets) > 0 || len(config.Defaults.Labels) > 0 || len(config.Labels) > 0 {
+		// global labels have the lowest precedence, followed by default labels
+		labels = config.Labels.Merge(config.Defaults.Labels)
+
+		for _, name = range group.LabelSets {
+			if _, ok = config.LabelSets[name]; !ok {
+				return fmt.Errorf("%w: %s", ErrorUnknownLabelSet, name)
+			}
+
+			labels = labels.Merge(config.LabelSets[name])
+		}
+
+		group.Labels = labels.Merge(group.Labels)
+	}
+
+	if group.LabelTemplates, err = parseLabelTemplates(group.Labels); err != nil {
+		return err
+	}
+
+	if group.ConsulService != "" && config.Consul == nil {
+		return fmt.Errorf("%w: consul_service requires consul to be configured", ErrorBadConsul)
+	}
+
+	if err = validateHashmod(group.Hashmod); err != nil {
+		return err
+	}
+
+	if err = validateScrapeConfig(group.ScrapeConfig); err != nil {
+		return err
+	}
+
+	if group.FileTemplate != "" {
+		group.fileTemplate, err = template.New("file_template").Option("missingkey=error").Parse(group.FileTemplate)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrorBadFileTemplate, err.Error())
+		}
+	}
+
+	if group.MaxTargets < 0 {
+		return ErrorBadMaxTargets
+	}
+
+	if group.Backups < 0 {
+		return ErrorBadBackups
+	}
+
+	if group.Port != nil {
+		if *group.Port < 0 || *group.Port > 65535 {
+			// port is invalid
+			return ErrorBadPort
+		}
+	}
+
+	// start checking flags
+	if group.Flags.IncludeVMs == nil {
+		// setting default
+		group.Flags.IncludeVMs = new(bool)
+		*group.Flags.IncludeVMs = true
+	}
+
+	if group.Flags.InetFamily == nil {
+		// setting default
+		group.Flags.InetFamily = new(string)
+		*group.Flags.InetFamily = InetFamilyAny
+	} else if *group.Flags.InetFamily != InetFamilyAny &&
+		*group.Flags.InetFamily != InetFamilyInet &&
+		*group.Flags.InetFamily != InetFamilyInet6 {
+
+		return ErrorBadInetFamily
+	}
+
+	if group.Flags.AllAddresses == nil {
+		// setting default
+		group.Flags.AllAddresses = new(bool)
+		*group.Flags.AllAddresses = false
+	}
+
+	if group.Flags.ReportSkipped == nil {
+		// setting default
+		group.Flags.ReportSkipped = new(bool)
+		*group.Flags.ReportSkipped = false
+	}
+
+	if err = validateFileOptions(group); err != nil {
+		return err
+	}
+
+	if group.Format == "" {
+		group.Format = config.Format
+	}
+
+	if group.Format == "" {
+		// setting default
+		group.Format = FormatYAML
+	}
+
+	if group.Format != FormatYAML && group.Format != FormatJSON {
+		return ErrorBadFormat
+	}
+
+	// skipped targets are reported as comments which JSON doesn't support
+	if group.Format == FormatJSON && *group.Flags.ReportSkipped {
+		return fmt.Errorf("%w: report_skipped is not supported with json", ErrorBadFormat)
+	}
+
+	if err = validateFilters(group.Filters); err != nil {
+		return err
+	}
+
+	for _, name = range group.FilterSets {
+		if _, ok = config.FilterSets[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrorUnknownFilterSet, name)
+		}
+
+		group.Filters = append(group.Filters, config.FilterSets[name]...)
+	}
+
+	if err = validateAddressFilters(group.AddressFilters); err != nil {
+		return err
+	}
+
+	if err = validateGraveyard(group.Graveyard); err != nil {
+		return err
+	}
+
+	if err = validateRelabelConfigs(group.RelabelConfigs); err != nil {
+		return err
+	}
+
+	for _, name = range group.IPStatuses {
+		if !contains(IPStatuses, name) {
+			return fmt.Errorf("%w: %s", ErrorBadIPStatus, name)
+		}
+	}
+
+	if err = validateProbeMode(group.ProbeMode); err != nil {
+		return err
+	}
+
+	return validateScheme(group.Scheme)
+}
+
+// apply sets all options of group that aren't set to the defaults. Pointers are not shared with the group so that
+// setting defaults of one group doesn't affect others.
+func (defaults *Defaults) apply(group *Group) {
+	if group.ScanIntervalString == "" {
+		group.ScanIntervalString = defaults.ScanIntervalString
+	}
+
+	if group.Port == nil && defaults.Port != nil {
+		group.Port = new(int)
+		*group.Port = *defaults.Port
+	}
+
+	if group.Flags.IncludeVMs == nil && defaults.Flags.IncludeVMs != nil {
+		group.Flags.IncludeVMs = new(bool)
+		*group.Flags.IncludeVMs = *defaults.Flags.IncludeVMs
+	}
+
+	if group.Flags.InetFamily == nil && defaults.Flags.InetFamily != nil {
+		group.Flags.InetFamily = new(string)
+		*group.Flags.InetFamily = *defaults.Flags.InetFamily
+	}
+
+	if group.Flags.AllAddresses == nil && defaults.Flags.AllAddresses != nil {
+		group.Flags.AllAddresses = new(bool)
+		*group.Flags.AllAddresses = *defaults.Flags.AllAddresses
+	}
+
+	if group.Flags.ReportSkipped == nil && defaults.Flags.ReportSkipped != nil {
+		group.Flags.ReportSkipped = new(bool)
+		*group.Flags.ReportSkipped = *defaults.Flags.ReportSkipped
+	}
+
+	if len(group.Filters) == 0 && len(defaults.Filters) > 0 {
+		group.Filters = append([]*Filter{}, defaults.Filters...)
+	}
+}
+
+// validateConfigContextMatch checks that the match of a config_context group is a valid key path.
+func validateConfigContextMatch(group *Group) error {
+	var (
+		path []string
+		key  string
+	)
+
+	path, _, _ = group.ConfigContextMatch()
+
+	for _, key = range path {
+		if key == "" {
+			return fmt.Errorf("%w: %s", ErrorBadConfigContextMatch, group.Match.Value)
+		}
+	}
+
+	return nil
+}
+
+// parseLabelTemplates returns the parsed templates of all label values that contain a template action.
+func parseLabelTemplates(labels model.LabelSet) (map[model.LabelName]*template.Template, error) {
+	var (
+		templates map[model.LabelName]*template.Template
+		name      model.LabelName
+		value     model.LabelValue
+		tmpl      *template.Template
+		err       error
+	)
+
+	for name, value = range labels {
+		if !strings.Contains(string(value), "{{") {
+			continue
+		}
+
+		// missing map keys (e.g. custom fields) must not silently render as "<no value>"
+		tmpl, err = template.New(string(name)).Option("missingkey=error").Parse(string(value))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrorBadLabelTemplate, err.Error())
+		}
+
+		if templates == nil {
+			templates = make(map[model.LabelName]*template.Template)
+		}
+
+		templates[name] = tmpl
+	}
+
+	return templates, nil
+}
+
+// validateRelabelConfigs checks that all relabel configs are valid.
+func validateRelabelConfigs(configs []*relabel.Config) error {
+	var (
+		i   int
+		err error
+	)
+
+	for i = range configs {
+		if configs[i] == nil {
+			return fmt.Errorf("%w: empty relabel config with index %d", ErrorBadRelabelConfig, i)
+		}
+
+		if err = configs[i].Validate(); err != nil {
+			return fmt.Errorf("%w: %s", ErrorBadRelabelConfig, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// validateProbeMode checks that probe is valid.
+func validateProbeMode(probe *ProbeMode) error {
+	var err error
+
+	if probe == nil {
+		return nil
+	}
+
+	if _, _, err = net.SplitHostPort(probe.Exporter); err != nil {
+		return fmt.Errorf("%w: exporter must be host:port: %s", ErrorBadProbeMode, err.Error())
+	}
+
+	return nil
+}
+
+// validateScrapeConfig checks that the values of scrape are valid Prometheus options.
+func validateScrapeConfig(scrape *ScrapeConfig) error {
+	var (
+		value string
+		err   error
+	)
+
+	if scrape == nil {
+		return nil
+	}
+
+	if scrape.Scheme != "" && scrape.Scheme != SchemeHTTP && scrape.Scheme != SchemeHTTPS {
+		return fmt.Errorf("%w: scheme must be http or https", ErrorBadScrapeConfig)
+	}
+
+	if scrape.MetricsPath != "" && !strings.HasPrefix(scrape.MetricsPath, "/") {
+		return fmt.Errorf("%w: metrics_path must start with /", ErrorBadScrapeConfig)
+	}
+
+	for _, value = range []string{scrape.ScrapeInterval, scrape.ScrapeTimeout} {
+		if value == "" {
+			continue
+		}
+
+		if _, err = model.ParseDuration(value); err != nil {
+			return fmt.Errorf("%w: %s", ErrorBadScrapeConfig, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// validateScheme checks that scheme is valid.
+func validateScheme(scheme *Scheme) error {
+	if scheme == nil {
+		return nil
+	}
+
+	if scheme.CustomField == "" {
+		return fmt.Errorf("%w: missing custom_field", ErrorBadScheme)
+	}
+
+	if scheme.Default != "" && scheme.Default != SchemeHTTP && scheme.Default != SchemeHTTPS {
+		return fmt.Errorf("%w: default must be http or https", ErrorBadScheme)
+	}
+
+	return nil
+}
+
+// validateGraveyard checks that graveyard is valid and sets defaults.
+func validateGraveyard(graveyard *Graveyard) error {
+	var status string
+
+	if graveyard == nil {
+		return nil
+	}
+
+	if graveyard.File == "" {
+		return fmt.Errorf("%w: missing file", ErrorBadGraveyard)
+	}
+
+	if len(graveyard.Statuses) == 0 {
+		// setting default
+		graveyard.Statuses = []string{netbox.StatusDeviceDecommissioning}
+	}
+
+	for _, status = range graveyard.Statuses {
+		if status == netbox.StatusDeviceActive {
+			return fmt.Errorf("%w: active targets cannot be part of the graveyard", ErrorBadGraveyard)
+		}
+	}
+
+	return nil
+}
+
+// validateFileOptions parses the file mode and resolves the file owner and group of group.
+func validateFileOptions(group *Group) error {
+	var (
+		mode uint64
+		err  error
+	)
+
+	group.FileMode = DefaultFileMode
+
+	if group.FileModeString != "" {
+		mode, err = strconv.ParseUint(group.FileModeString, 8, 32)
+		if err != nil || mode > 0777 {
+			return fmt.Errorf("%w: %s", ErrorBadFileMode, group.FileModeString)
+		}
+
+		group.FileMode = os.FileMode(mode)
+	}
+
+	group.UID, err = lookupID(group.FileOwner, func(name string) (string, error) {
+		var (
+			u   *user.User
+			err error
+		)
+
+		if u, err = user.Lookup(name); err != nil {
+			return "", err
+		}
+
+		return u.Uid, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrorBadFileOwner, err.Error())
+	}
+
+	group.GID, err = lookupID(group.FileGroup, func(name string) (string, error) {
+		var (
+			g   *user.Group
+			err error
+		)
+
+		if g, err = user.LookupGroup(name); err != nil {
+			return "", err
+		}
+
+		return g.Gid, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrorBadFileOwner, err.Error())
+	}
+
+	return nil
+}
+
+// lookupID returns the numeric id of name, which is either numeric already or resolved by lookup. An empty name
+// returns -1.
+func lookupID(name string, lookup func(string) (string, error)) (int, error) {
+	var (
+		id  int
+		err error
+	)
+
+	if name == "" {
+		return -1, nil
+	}
+
+	if id, err = strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+
+	if name, err = lookup(name); err != nil {
+		return -1, err
+	}
+
+	return strconv.Atoi(name)
+}
+
+// validateHashmod checks that hashmod has at least one shard and only shards in range are written. A nil hashmod is
+// valid.
+func validateHashmod(hashmod *Hashmod) error {
+	var shard int
+
+	if hashmod == nil {
+		return nil
+	}
+
+	if hashmod.Shards < 1 {
+		return fmt.Errorf("%w: shards must be at least 1", ErrorBadHashmod)
+	}
+
+	for _, shard = range hashmod.Write {
+		if shard < 0 || shard >= hashmod.Shards {
+			return fmt.Errorf("%w: shard %d out of range", ErrorBadHashmod, shard)
+		}
+	}
+
+	return nil
+}
+
+// HashmodFile returns the file a target with address is written to when the group is sharded by hashmod, file being the
+// file the target would be written to otherwise. The shard number is inserted before the extension of file. When the
+// shard isn't written by this instance, false is returned. Without hashmod, file is returned.
+func (group *Group) HashmodFile(file, address string) (string, bool) {
+	var (
+		hasher hash.Hash64 = fnv.New64a()
+		shard  int
+		ext    string = filepath.Ext(file)
+	)
+
+	if group.Hashmod == nil {
+		return file, true
+	}
+
+	hasher.Write([]byte(address))
+	shard = int(hasher.Sum64() % uint64(group.Hashmod.Shards))
+
+	if len(group.Hashmod.Write) > 0 && !slices.Contains(group.Hashmod.Write, shard) {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(file, ext), shard, ext), true
+}
+
+// ShardFile returns the file a target with labels is written to. This is File unless the group defines a file
+// template. Slashes in label values are replaced by underscores to keep files within the directory of the template.
+func (group *Group) ShardFile(labels model.LabelSet) (string, error) {
+	var (
+		data map[string]string = make(map[string]string, len(labels))
+		name model.LabelName
+		buf  strings.Builder
+		err  error
+	)
+
+	if group.fileTemplate == nil {
+		return group.File, nil
+	}
+
+	for name = range labels {
+		data[string(name)] = strings.ReplaceAll(string(labels[name]), "/", "_")
+	}
+
+	err = group.fileTemplate.Execute(&buf, data)
+	if err != nil {
+		return "", err
+	}
+
+	if buf.Len() == 0 {
+		return "", fmt.Errorf("file_template of group %s rendered an empty file name", group.File)
+	}
+
+	return buf.String(), nil
+}
+
+// RenderHost renders the host object of host using the template of icinga.
+func (icinga *Icinga) RenderHost(host any) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		err error
+	)
+
+	err = icinga.hostTemplate.Execute(&buf, host)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// IcingaQuote returns s as double quoted Icinga2 string.
+func IcingaQuote(s string) string {
+	return `"` + icingaEscaper.Replace(s) + `"`
+}
+
+// DeviceFilter returns the group's prefilter as filter for Netbox queries or nil when the group has no prefilter.
+func (group *Group) DeviceFilter() *netbox.DeviceFilter {
+	if group.Prefilter == nil {
+		return nil
+	}
+
+	return &netbox.DeviceFilter{
+		Sites:     group.Prefilter.Sites,
+		Roles:     group.Prefilter.Roles,
+		Tenants:   group.Prefilter.Tenants,
+		Platforms: group.Prefilter.Platforms,
+		Statuses:  group.Prefilter.Statuses,
+	}
+}
+
+// NameMatches returns true when name matches the group's name_match or the group doesn't define one.
+func (group *Group) NameMatches(name string) bool {
+	return group.nameRegex == nil || group.nameRegex.MatchString(name)
+}
+
+// ExcludedByTags returns true when any of the tag slugs is one of the group's exclude tags.
+func (group *Group) ExcludedByTags(tags []string) bool {
+	var tag string
+
+	for _, tag = range tags {
+		if contains(group.ExcludeTags, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IPStatusAllowed returns true when IP addresses with status can be selected for targets of group.
+func (group *Group) IPStatusAllowed(status string) bool {
+	if len(group.IPStatuses) == 0 {
+		return contains(DefaultIPStatuses, status)
+	}
+
+	return contains(group.IPStatuses, status)
+}
+
+// CustomFieldAllowed returns true when the custom field called name is exposed as label for group.
+func (group *Group) CustomFieldAllowed(name string) bool {
+	if len(group.CustomFieldInclude) > 0 && !contains(group.CustomFieldInclude, name) {
+		return false
+	}
+
+	return !contains(group.CustomFieldExclude, name)
+}
+
+// SchemeFor returns the scrape scheme for a target with the given labels or an empty string when the group doesn't
+// define a scheme or neither the custom field nor the default provide a valid one. Custom fields are looked up by their
+// label (i.e. netbox_<custom_field>).
+func (group *Group) SchemeFor(labels model.LabelSet) string {
+	var value string
+
+	if group.Scheme == nil {
+		return ""
+	}
+
+	value = strings.ToLower(string(labels[model.LabelName("netbox_"+group.Scheme.CustomField)]))
+	if value == SchemeHTTP || value == SchemeHTTPS {
+		return value
+	}
+
+	return group.Scheme.Default
+}
+
+// ModuleFor returns the module of a target with the given labels. This is the value of ModuleCustomField when set, the
+// module of the target's platform or Module (in this order). Custom fields are looked up by their label (i.e.
+// netbox_<custom_field>).
+func (probe *ProbeMode) ModuleFor(labels model.LabelSet) string {
+	var (
+		module string
+		ok     bool
+	)
+
+	if probe.ModuleCustomField != "" {
+		module = string(labels[model.LabelName("netbox_"+probe.ModuleCustomField)])
+		if module != "" {
+			return module
+		}
+	}
+
+	if module, ok = probe.PlatformModules[string(labels["netbox_platform"])]; ok {
+		return module
+	}
+
+	return probe.Module
+}
+
+// AuthFor returns the auth of a target with the given labels. This is the value of AuthCustomField when set, otherwise
+// Auth.
+func (probe *ProbeMode) AuthFor(labels model.LabelSet) string {
+	var auth string
+
+	if probe.AuthCustomField != "" {
+		auth = string(labels[model.LabelName("netbox_"+probe.AuthCustomField)])
+		if auth != "" {
+			return auth
+		}
+	}
+
+	return probe.Auth
+}
+
+// PortFor returns the port of a target with the given labels. This is the value of the group's PortFromCustomField when
+// it contains a valid port, otherwise the group's Port (which might be nil). Custom fields are looked up by their label
+// (i.e. netbox_<custom_field>).
+func (group *Group) PortFor(labels model.LabelSet) *int {
+	var (
+		port int
+		err  error
+	)
+
+	if group.PortFromCustomField == "" {
+		return group.Port
+	}
+
+	port, err = strconv.Atoi(string(labels[model.LabelName("netbox_"+group.PortFromCustomField)]))
+	if err != nil || port < 0 || port > 65535 {
+		return group.Port
+	}
+
+	return &port
+}
+
+// ConfigContextMatch returns the key path (split by dots) and the expected value of a config_context group's match
+// (`path.to.key=value`). When the match doesn't contain a value, hasValue is false.
+func (group *Group) ConfigContextMatch() (path []string, value string, hasValue bool) {
+	var key string
+
+	key, value, hasValue = strings.Cut(group.Match.Value, "=")
+
+	return strings.Split(strings.TrimSpace(key), "."), strings.TrimSpace(value), hasValue
+}
+
+// InGraveyard returns true when the group has a graveyard defined and status is one of its statuses.
+func (group *Group) InGraveyard(status string) bool {
+	var s string
+
+	if group.Graveyard == nil {
+		return false
+	}
+
+	for _, s = range group.Graveyard.Statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateFilters checks that filters are valid.
+func validateFilters(filters []*Filter) error {
+	var (
+		filter *Filter
+		err    error
+	)
+
+	for _, filter = range filters {
+		// Labels must start with `netbox_` to match in any case.
+		if !strings.HasPrefix(filter.Label, "netbox_") {
+			return ErrorBadFilterLabel
+		}
+
+		if filter.Present != nil && !*filter.Present &&
+			(filter.Match != "" || filter.NotEmpty) {
+			return ErrorBadFilterCombination
+		}
+
+		filter.regex, err = regexp.Compile(filter.Match)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrorBadFilterMatch, err.Error())
+		}
+
+		switch filter.MissingLabel {
+		case "":
+			// setting default
+			filter.MissingLabel = MissingLabelFail
+		case MissingLabelFail, MissingLabelIgnore:
+		default:
+			return ErrorBadMissingLabel
+		}
+	}
+
+	return nil
+}
+
+// ValidateAddressFilters checks that address filters are valid.
+func validateAddressFilters(filters []*AddressFilter) error {
+	var (
+		filter *AddressFilter
+		prefix netip.Prefix
+		i      int
+		err    error
+	)
+
+	for _, filter = range filters {
+		if len(filter.Prefixes) == 0 {
+			return fmt.Errorf("%w: no prefixes defined", ErrorBadAddressFilter)
+		}
+
+		filter.prefixes = make([]netip.Prefix, len(filter.Prefixes))
+
+		for i = range filter.Prefixes {
+			prefix, err = netip.ParsePrefix(filter.Prefixes[i])
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrorBadAddressFilter, err.Error())
+			}
+
+			filter.prefixes[i] = prefix.Masked()
+		}
+	}
+
+	return nil
+}
+
+// AddressFiltersMatch returns true if all address filters match the given address.
+func (group *Group) AddressFiltersMatch(addr netip.Addr) bool {
+	var (
+		filter  *AddressFilter
+		prefix  netip.Prefix
+		matched bool
+	)
+
+	// Netbox might return IPv4-mapped IPv6 addresses which should be treated as legacy IP.
+	addr = addr.Unmap()
+
+	for _, filter = range group.AddressFilters {
+		matched = false
+
+		for _, prefix = range filter.prefixes {
+			if prefix.Contains(addr) {
+				matched = true
+				break
+			}
+		}
+
+		if matched == filter.Negate {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FiltersMatch returns true if all filters match with the target's labels.
+func (group *Group) FiltersMatch(labels model.LabelSet) bool {
+	var (
+		filter *Filter
+		ok     bool
+		val    model.LabelValue
+	)
+
+	for _, filter = range group.Filters {
+		val, ok = labels[model.LabelName(filter.Label)]
+
+		if filter.Present != nil {
+			if *filter.Present != ok {
+				// Label is expected to be present but isn't or vice versa.
+				return false
+			}
+
+			if !ok {
+				// Label is expected to be absent and there is nothing else to check.
+				continue
+			}
+		}
+
+		if !ok {
+			if filter.MissingLabel == MissingLabelIgnore {
+				// Filter is optional for targets not having the label.
+				continue
+			}
+
+			// Filter label doesn't exist for target and therefore cannot match.
+			return false
+		}
+
+		if filter.NotEmpty && val == "" {
+			return false
+		}
+
+		if filter.Match == "" {
+			// Filter only checks for presence of the label (and its value).
+			continue
 		}
 
 		if filter.regex.Match([]byte(val)) {
@@ -18,14 +18,19 @@
 package config
 
 import (
+	"crypto/tls"
+	"fmt"
+	"net/netip"
+	"os"
 	"regexp"
 	"testing"
+	"text/template"
 	"time"
 
 	"github.com/4xoc/netbox_sd/internal/util"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
 
 	"github.com/prometheus/common/model"
-	"github.com/prometheus/prometheus/discovery/targetgroup"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -35,15 +40,33 @@ func TestReadConfig(t *testing.T) {
 		err      error
 		result   *Config
 		expected *Config = &Config{
-			BaseURL:            "https://netbox.domain.tld",
-			Token:              "680000000000000000000000000000000000s038",
-			ScanIntervalString: "5m",
-			ScanInterval:       time.Duration(5 * time.Minute),
+			BaseURL:              "https://netbox.domain.tld",
+			Token:                "680000000000000000000000000000000000s038",
+			API:                  APIGraphQL,
+			ScanIntervalString:   "5m",
+			ScanInterval:         time.Duration(5 * time.Minute),
+			StartupStaggerString: "2s",
+			StartupStagger:       time.Duration(2 * time.Second),
+			TargetStateLabels:    DefaultTargetStateLabels,
+			CleanupMode:          CleanupModeDelete,
+			Format:               FormatYAML,
+			PageSize:             &DefaultPageSize,
+			ClientOptions: ClientOptions{
+				RequestTimeout: DefaultRequestTimeout,
+				DialTimeout:    DefaultDialTimeout,
+				MaxAttempts:    &DefaultMaxAttempts,
+				RetryBackoff:   DefaultRetryBackoff,
+			},
 			Groups: []*Group{
 				&Group{
 					File:               "junos_exporter.prom",
 					Type:               GroupTypeDeviceTag,
-					Match:              "junos_exporter",
+					Match:              Match{Value: "junos_exporter"},
+					Format:             FormatYAML,
+					FileMode:           DefaultFileMode,
+					UID:                -1,
+					GID:                -1,
+					TagExpr:            &TagExpr{tag: "junos_exporter"},
 					Port:               util.NewPtr[int](1234),
 					ScanIntervalString: "20s",
 					ScanInterval:       time.Duration(20 * time.Second),
@@ -51,15 +74,28 @@ func TestReadConfig(t *testing.T) {
 						"foo": "bar",
 					},
 					Flags: Flags{
-						IncludeVMs:   util.NewPtr[bool](true),
-						InetFamily:   util.NewPtr[string](InetFamilyAny),
-						AllAddresses: util.NewPtr[bool](false),
+						IncludeVMs:    util.NewPtr[bool](true),
+						InetFamily:    util.NewPtr[string](InetFamilyAny),
+						AllAddresses:  util.NewPtr[bool](false),
+						ReportSkipped: util.NewPtr[bool](false),
+					},
+					Graveyard: &Graveyard{
+						File:     "junos_exporter_graveyard.prom",
+						Statuses: []string{"decommissioning"},
+						Labels: model.LabelSet{
+							"alerting": "relaxed",
+						},
 					},
 				},
 				&Group{
 					File:               "ipmi_exporter.prom",
 					Type:               GroupTypeInterfaceTag,
-					Match:              "ipmi_exporter",
+					Match:              Match{Value: "ipmi_exporter"},
+					Format:             FormatYAML,
+					FileMode:           DefaultFileMode,
+					UID:                -1,
+					GID:                -1,
+					TagExpr:            &TagExpr{tag: "ipmi_exporter"},
 					Port:               util.NewPtr[int](1234),
 					ScanIntervalString: "5m",
 					ScanInterval:       time.Duration(5 * time.Minute),
@@ -67,50 +103,64 @@ func TestReadConfig(t *testing.T) {
 						"foo": "bar",
 					},
 					Flags: Flags{
-						IncludeVMs:   util.NewPtr[bool](true),
-						InetFamily:   util.NewPtr[string](InetFamilyAny),
-						AllAddresses: util.NewPtr[bool](false),
+						IncludeVMs:    util.NewPtr[bool](true),
+						InetFamily:    util.NewPtr[string](InetFamilyAny),
+						AllAddresses:  util.NewPtr[bool](false),
+						ReportSkipped: util.NewPtr[bool](false),
 					},
 				},
 				&Group{
 					File:         "junos2.prom",
 					Type:         GroupTypeService,
-					Match:        "junos_exporter",
+					Match:        Match{Value: "junos_exporter"},
+					Format:       FormatYAML,
+					FileMode:     DefaultFileMode,
+					UID:          -1,
+					GID:          -1,
 					ScanInterval: time.Duration(5 * time.Minute),
 					Labels: model.LabelSet{
 						"foo": "bar",
 					},
 					Port: util.NewPtr[int](9100),
 					Flags: Flags{
-						IncludeVMs:   util.NewPtr[bool](false),
-						InetFamily:   util.NewPtr[string](InetFamilyInet),
-						AllAddresses: util.NewPtr[bool](true),
+						IncludeVMs:    util.NewPtr[bool](false),
+						InetFamily:    util.NewPtr[string](InetFamilyInet),
+						AllAddresses:  util.NewPtr[bool](true),
+						ReportSkipped: util.NewPtr[bool](false),
 					},
 				},
 				&Group{
 					File:         "junos3.prom",
 					Type:         GroupTypeService,
-					Match:        "junos_exporter",
+					Priority:     10,
+					Match:        Match{Value: "junos_exporter"},
+					Format:       FormatYAML,
+					FileMode:     DefaultFileMode,
+					UID:          -1,
+					GID:          -1,
 					ScanInterval: time.Duration(5 * time.Minute),
 					Labels: model.LabelSet{
 						"foo": "bar",
 					},
 					Port: nil,
 					Flags: Flags{
-						IncludeVMs:   util.NewPtr[bool](false),
-						InetFamily:   util.NewPtr[string](InetFamilyInet),
-						AllAddresses: util.NewPtr[bool](true),
+						IncludeVMs:    util.NewPtr[bool](false),
+						InetFamily:    util.NewPtr[string](InetFamilyInet),
+						AllAddresses:  util.NewPtr[bool](true),
+						ReportSkipped: util.NewPtr[bool](false),
 					},
 					Filters: []*Filter{
 						&Filter{
-							Label: "netbox_foo",
-							Match: "(bar|blub)",
-							regex: regexp.MustCompile("(bar|blub)"),
+							Label:        "netbox_foo",
+							Match:        "(bar|blub)",
+							MissingLabel: MissingLabelFail,
+							regex:        regexp.MustCompile("(bar|blub)"),
 						},
 						&Filter{
-							Label: "netbox_bar",
-							Match: "something[0-9]+",
-							regex: regexp.MustCompile("something[0-9]+"),
+							Label:        "netbox_bar",
+							Match:        "something[0-9]+",
+							MissingLabel: MissingLabelIgnore,
+							regex:        regexp.MustCompile("something[0-9]+"),
 						},
 					},
 				},
@@ -138,6 +188,14 @@ func TestReadConfig(t *testing.T) {
 	_, err = ReadConfigFile("testdata/config/malformed.yml")
 	assert.ErrorIs(t, err, ErrorParsingFile)
 
+	// unknown option
+	_, err = ReadConfigFile("testdata/config/unknownField.yml")
+	assert.ErrorIs(t, err, ErrorParsingFile)
+
+	// unknown option without strict parsing
+	_, err = ReadConfigFile("testdata/config/unknownField2.yml")
+	assert.Nil(t, err)
+
 	// missing required
 	_, err = ReadConfigFile("testdata/config/missingRequired.yml")
 	assert.ErrorIs(t, err, ErrorMissingRequired)
@@ -150,6 +208,73 @@ func TestReadConfig(t *testing.T) {
 	_, err = ReadConfigFile("testdata/config/badGroupType.yml")
 	assert.ErrorIs(t, err, ErrorBadGroupType)
 
+	// bad config_context match
+	_, err = ReadConfigFile("testdata/config/badConfigContextMatch.yml")
+	assert.ErrorIs(t, err, ErrorBadConfigContextMatch)
+
+	// bad tag expression
+	_, err = ReadConfigFile("testdata/config/badTagExpression.yml")
+	assert.ErrorIs(t, err, ErrorBadTagExpression)
+
+	// bad all match
+	_, err = ReadConfigFile("testdata/config/badAllMatch.yml")
+	assert.ErrorIs(t, err, ErrorBadAllMatch)
+
+	// bad rest_query match
+	_, err = ReadConfigFile("testdata/config/badRESTQuery.yml")
+	assert.ErrorIs(t, err, ErrorBadRESTQuery)
+
+	// bad name_match
+	_, err = ReadConfigFile("testdata/config/badNameMatch.yml")
+	assert.ErrorIs(t, err, ErrorBadNameMatch)
+
+	// hashmod shard out of range
+	_, err = ReadConfigFile("testdata/config/badHashmod.yml")
+	assert.ErrorIs(t, err, ErrorBadHashmod)
+
+	// file_template not parsing
+	_, err = ReadConfigFile("testdata/config/badFileTemplate.yml")
+	assert.ErrorIs(t, err, ErrorBadFileTemplate)
+
+	// unknown cleanup_mode
+	_, err = ReadConfigFile("testdata/config/badCleanupMode.yml")
+	assert.ErrorIs(t, err, ErrorBadCleanupMode)
+
+	// negative max_targets
+	_, err = ReadConfigFile("testdata/config/badMaxTargets.yml")
+	assert.ErrorIs(t, err, ErrorBadMaxTargets)
+
+	// negative backups
+	_, err = ReadConfigFile("testdata/config/badBackups.yml")
+	assert.ErrorIs(t, err, ErrorBadBackups)
+
+	// prefilter on a group type other than device_tag
+	_, err = ReadConfigFile("testdata/config/badPrefilter.yml")
+	assert.ErrorIs(t, err, ErrorBadPrefilter)
+
+	// scrape_config with bad scrape_interval
+	_, err = ReadConfigFile("testdata/config/badScrapeConfig.yml")
+	assert.ErrorIs(t, err, ErrorBadScrapeConfig)
+
+	// probe_mode exporter without port
+	_, err = ReadConfigFile("testdata/config/badProbeMode.yml")
+	assert.ErrorIs(t, err, ErrorBadProbeMode)
+
+	// bad ip status
+	_, err = ReadConfigFile("testdata/config/badIPStatus.yml")
+	assert.ErrorIs(t, err, ErrorBadIPStatus)
+
+	// bad label template
+	_, err = ReadConfigFile("testdata/config/badLabelTemplate.yml")
+	assert.ErrorIs(t, err, ErrorBadLabelTemplate)
+
+	// bad relabel config (rejected by parsing already)
+	_, err = ReadConfigFile("testdata/config/badRelabelConfig.yml")
+	assert.ErrorIs(t, err, ErrorParsingFile)
+
+	_, err = ReadConfigFile("testdata/config/badRelabelConfig2.yml")
+	assert.ErrorIs(t, err, ErrorBadRelabelConfig)
+
 	// bad default scan interval
 	_, err = ReadConfigFile("testdata/config/badScanInterval.yml")
 	assert.ErrorIs(t, err, ErrorBadScanInterval)
@@ -181,6 +306,58 @@ func TestReadConfig(t *testing.T) {
 	// bad filter match
 	_, err = ReadConfigFile("testdata/config/badFilterMatch.yml")
 	assert.ErrorIs(t, err, ErrorBadFilterMatch)
+
+	// groups ordered by priority
+	assert.Equal(t, []*Group{result.Groups[3], result.Groups[0], result.Groups[1], result.Groups[2]},
+		result.GroupsByPriority())
+
+	// bad startup stagger
+	_, err = ReadConfigFile("testdata/config/badStartupStagger.yml")
+	assert.ErrorIs(t, err, ErrorBadStartupStagger)
+
+	// bad tls pin
+	_, err = ReadConfigFile("testdata/config/badTLSPin.yml")
+	assert.ErrorIs(t, err, ErrorBadTLSPin)
+
+	// bad filter missing_label
+	_, err = ReadConfigFile("testdata/config/badMissingLabel.yml")
+	assert.ErrorIs(t, err, ErrorBadMissingLabel)
+
+	// bad target_state_labels
+	_, err = ReadConfigFile("testdata/config/badTargetStateLabel.yml")
+	assert.ErrorIs(t, err, ErrorBadTargetStateLabel)
+
+	// graveyard without file
+	_, err = ReadConfigFile("testdata/config/badGraveyard.yml")
+	assert.ErrorIs(t, err, ErrorBadGraveyard)
+
+	// graveyard with active status
+	_, err = ReadConfigFile("testdata/config/badGraveyard2.yml")
+	assert.ErrorIs(t, err, ErrorBadGraveyard)
+
+	// graveyard file used by another group
+	_, err = ReadConfigFile("testdata/config/duplicateFile2.yml")
+	assert.ErrorIs(t, err, ErrorDuplicateFile)
+
+	// combined file used by a group
+	_, err = ReadConfigFile("testdata/config/duplicateFile4.yml")
+	assert.ErrorIs(t, err, ErrorDuplicateFile)
+
+	// dns zone file used by a group
+	_, err = ReadConfigFile("testdata/config/duplicateFile5.yml")
+	assert.ErrorIs(t, err, ErrorDuplicateFile)
+
+	// manifest file used by a group
+	_, err = ReadConfigFile("testdata/config/duplicateFile6.yml")
+	assert.ErrorIs(t, err, ErrorDuplicateFile)
+
+	// target database used by a group
+	_, err = ReadConfigFile("testdata/config/duplicateFile7.yml")
+	assert.ErrorIs(t, err, ErrorDuplicateFile)
+
+	assert.True(t, result.Groups[0].InGraveyard("decommissioning"))
+	assert.False(t, result.Groups[0].InGraveyard("offline"))
+	assert.False(t, result.Groups[1].InGraveyard("decommissioning"))
 }
 
 func TestFiltersMatch(t *testing.T) {
@@ -204,68 +381,85 @@ func TestFiltersMatch(t *testing.T) {
 					Match:  "bar",
 					Negate: true,
 				},
+				&Filter{
+					Label:        "netbox_foo5",
+					Match:        "bar",
+					MissingLabel: MissingLabelIgnore,
+				},
 			},
 		}
 		data = []struct {
-			target   *targetgroup.Group
+			labels   model.LabelSet
 			expected bool
 		}{
 			{
 				// should work
-				target: &targetgroup.Group{
-					Labels: model.LabelSet{
-						"netbox_foo":  "bar",
-						"netbox_foo2": "foo",
-						"netbox_foo3": "123",
-						"netbox_foo4": "123",
-					},
+				labels: model.LabelSet{
+					"netbox_foo":  "bar",
+					"netbox_foo2": "foo",
+					"netbox_foo3": "123",
+					"netbox_foo4": "123",
 				},
 				expected: true,
 			},
 			{
 				// missing label defined in filters should fail
-				target: &targetgroup.Group{
-					Labels: model.LabelSet{
-						"netbox_foo":  "bar",
-						"netbox_foo2": "foo",
-					},
+				labels: model.LabelSet{
+					"netbox_foo":  "bar",
+					"netbox_foo2": "foo",
 				},
 				expected: false,
 			},
 			{
 				// netbox_foo3 should fail
-				target: &targetgroup.Group{
-					Labels: model.LabelSet{
-						"netbox_foo":  "bar",
-						"netbox_foo2": "foo",
-						"netbox_foo3": "abc",
-					},
+				labels: model.LabelSet{
+					"netbox_foo":  "bar",
+					"netbox_foo2": "foo",
+					"netbox_foo3": "abc",
 				},
 				expected: false,
 			},
 			{
 				// all label values are wrong
-				target: &targetgroup.Group{
-					Labels: model.LabelSet{
-						"netbox_foo":  "this",
-						"netbox_foo2": "should",
-						"netbox_foo3": "fail",
-					},
+				labels: model.LabelSet{
+					"netbox_foo":  "this",
+					"netbox_foo2": "should",
+					"netbox_foo3": "fail",
 				},
 				expected: false,
 			},
 			{
 				// negate match and thus return false
-				target: &targetgroup.Group{
-					Labels: model.LabelSet{
-						"netbox_foo":  "bar",
-						"netbox_foo2": "foo",
-						"netbox_foo3": "123",
-						"netbox_foo4": "bar",
-					},
+				labels: model.LabelSet{
+					"netbox_foo":  "bar",
+					"netbox_foo2": "foo",
+					"netbox_foo3": "123",
+					"netbox_foo4": "bar",
+				},
+				expected: false,
+			},
+			{
+				// present optional label must match
+				labels: model.LabelSet{
+					"netbox_foo":  "bar",
+					"netbox_foo2": "foo",
+					"netbox_foo3": "123",
+					"netbox_foo4": "123",
+					"netbox_foo5": "foo",
 				},
 				expected: false,
 			},
+			{
+				// present optional label matching
+				labels: model.LabelSet{
+					"netbox_foo":  "bar",
+					"netbox_foo2": "foo",
+					"netbox_foo3": "123",
+					"netbox_foo4": "123",
+					"netbox_foo5": "bar",
+				},
+				expected: true,
+			},
 		}
 		i int
 	)
@@ -274,6 +468,904 @@ func TestFiltersMatch(t *testing.T) {
 	require.NoError(t, validateFilters(group.Filters))
 
 	for i = range data {
-		assert.Equal(t, data[i].expected, group.FiltersMatch(data[i].target))
+		assert.Equal(t, data[i].expected, group.FiltersMatch(data[i].labels))
+	}
+}
+
+func TestFiltersMatchPresence(t *testing.T) {
+	var (
+		group = Group{
+			Filters: []*Filter{
+				&Filter{
+					Label:   "netbox_foo",
+					Present: util.NewPtr[bool](true),
+				},
+				&Filter{
+					Label:   "netbox_foo2",
+					Present: util.NewPtr[bool](false),
+				},
+				&Filter{
+					Label:    "netbox_foo3",
+					Match:    "bar",
+					Negate:   true,
+					NotEmpty: true,
+				},
+			},
+		}
+		data = []struct {
+			labels   model.LabelSet
+			expected bool
+		}{
+			{
+				// should work
+				labels: model.LabelSet{
+					"netbox_foo":  "",
+					"netbox_foo3": "foo",
+				},
+				expected: true,
+			},
+			{
+				// netbox_foo must be present
+				labels: model.LabelSet{
+					"netbox_foo3": "foo",
+				},
+				expected: false,
+			},
+			{
+				// netbox_foo2 must not be present
+				labels: model.LabelSet{
+					"netbox_foo":  "",
+					"netbox_foo2": "",
+					"netbox_foo3": "foo",
+				},
+				expected: false,
+			},
+			{
+				// netbox_foo3 must not be empty
+				labels: model.LabelSet{
+					"netbox_foo":  "",
+					"netbox_foo3": "",
+				},
+				expected: false,
+			},
+			{
+				// netbox_foo3 must not be bar
+				labels: model.LabelSet{
+					"netbox_foo":  "",
+					"netbox_foo3": "bar",
+				},
+				expected: false,
+			},
+		}
+		i   int
+		err error
+	)
+
+	// Filters must compile
+	require.NoError(t, validateFilters(group.Filters))
+
+	for i = range data {
+		assert.Equal(t, data[i].expected, group.FiltersMatch(data[i].labels), "index %d", i)
+	}
+
+	// present: false can't be combined with anything else
+	_, err = ReadConfigFile("testdata/config/badFilterCombination.yml")
+	assert.ErrorIs(t, err, ErrorBadFilterCombination)
+}
+
+func TestAddressFiltersMatch(t *testing.T) {
+	var (
+		group = Group{
+			AddressFilters: []*AddressFilter{
+				&AddressFilter{
+					Prefixes: []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"},
+					Negate:   true,
+				},
+				&AddressFilter{
+					Prefixes: []string{"2001:db8::/32", "0.0.0.0/0"},
+				},
+			},
+		}
+		data = []struct {
+			addr     string
+			expected bool
+		}{
+			{"2001:db8::1", true},
+			{"2001:db9::1", false},
+			{"198.51.100.1", true},
+			{"10.1.2.3", false},
+			{"::ffff:192.168.1.1", false},
+		}
+		i   int
+		err error
+	)
+
+	require.NoError(t, validateAddressFilters(group.AddressFilters))
+
+	for i = range data {
+		assert.Equal(t, data[i].expected, group.AddressFiltersMatch(netip.MustParseAddr(data[i].addr)), data[i].addr)
+	}
+
+	_, err = ReadConfigFile("testdata/config/badAddressFilter.yml")
+	assert.ErrorIs(t, err, ErrorBadAddressFilter)
+}
+
+func TestFilterSets(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/filterSets.yml")
+	require.Nil(t, err)
+
+	// group filters come first, followed by the filters of all sets in the order they are referenced
+	require.Len(t, result.Groups[0].Filters, 3)
+	assert.Equal(t, "netbox_role", result.Groups[0].Filters[0].Label)
+	assert.Equal(t, "netbox_tenant", result.Groups[0].Filters[1].Label)
+	assert.Equal(t, "netbox_site", result.Groups[0].Filters[2].Label)
+
+	require.Len(t, result.Groups[1].Filters, 1)
+	assert.Same(t, result.Groups[0].Filters[1], result.Groups[1].Filters[0])
+
+	assert.True(t, result.Groups[0].FiltersMatch(model.LabelSet{
+		"netbox_role":   "router",
+		"netbox_tenant": "prod",
+		"netbox_site":   "dc1",
+	}))
+	assert.False(t, result.Groups[0].FiltersMatch(model.LabelSet{
+		"netbox_role":   "router",
+		"netbox_tenant": "prod",
+		"netbox_site":   "lab1",
+	}))
+
+	_, err = ReadConfigFile("testdata/config/badFilterSet.yml")
+	assert.ErrorIs(t, err, ErrorUnknownFilterSet)
+
+	_, err = ReadConfigFile("testdata/config/badFilterSet2.yml")
+	assert.ErrorIs(t, err, ErrorBadFilterLabel)
+}
+
+func TestLabelSets(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/labelSets.yml")
+	require.Nil(t, err)
+
+	// later sets override earlier ones
+	assert.Equal(t, model.LabelSet{"team": "network", "env": "prod", "foo": "bar"}, result.Groups[0].Labels)
+	// group labels take precedence over label sets
+	assert.Equal(t, model.LabelSet{"env": "staging"}, result.Groups[1].Labels)
+	assert.Nil(t, result.Groups[2].Labels)
+
+	// label sets must not be modified by merging
+	assert.Equal(t, model.LabelSet{"env": "prod"}, result.LabelSets["env_prod"])
+
+	_, err = ReadConfigFile("testdata/config/badLabelSet.yml")
+	assert.ErrorIs(t, err, ErrorUnknownLabelSet)
+}
+
+func TestInstances(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/instances.yml")
+	require.Nil(t, err)
+
+	assert.Equal(t, "", result.BaseURL)
+	assert.Len(t, result.Instances, 2)
+	assert.Equal(t, "dc2", result.Groups[1].Instance)
+	assert.Equal(t, &Instance{
+		Name:          "dc2",
+		BaseURL:       "https://netbox.dc2.domain.tld",
+		Token:         "456",
+		AllowInsecure: true,
+		API:           APIREST,
+	}, result.Instance("dc2"))
+	assert.Nil(t, result.Instance("dc3"))
+
+	// groups can override the token of their instance
+	assert.Equal(t, &Instance{
+		Name:          "dc2",
+		BaseURL:       "https://netbox.dc2.domain.tld",
+		Token:         "789",
+		AllowInsecure: true,
+		API:           APIREST,
+	}, result.InstanceFor(result.Groups[2]))
+	assert.Equal(t, "456", result.InstanceFor(result.Groups[1]).Token)
+	assert.Equal(t, "456", result.Instance("dc2").Token)
+
+	_, err = ReadConfigFile("testdata/config/badGroupBaseURL.yml")
+	assert.ErrorIs(t, err, ErrorBaseURLMissingTLS)
+
+	_, err = ReadConfigFile("testdata/config/unknownInstance.yml")
+	assert.ErrorIs(t, err, ErrorUnknownInstance)
+
+	// groups must refer to an instance when there is no default instance
+	_, err = ReadConfigFile("testdata/config/unknownInstance2.yml")
+	assert.ErrorIs(t, err, ErrorUnknownInstance)
+
+	_, err = ReadConfigFile("testdata/config/duplicateInstance.yml")
+	assert.ErrorIs(t, err, ErrorDuplicateInstance)
+}
+
+func TestTokenFile(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/tokenFile.yml")
+	require.Nil(t, err)
+
+	// relative to the config file
+	assert.Equal(t, "testdata/config/secrets/netbox_token", result.TokenFile)
+	assert.Equal(t, "testdata/config/secrets/netbox_token", result.InstanceFor(result.Groups[0]).TokenFile)
+	assert.Equal(t, "/run/secrets/dc2_token", result.Instance("dc2").TokenFile)
+
+	// a token of the group replaces the token file of its instance
+	assert.Equal(t, "789", result.InstanceFor(result.Groups[1]).Token)
+	assert.Equal(t, "", result.InstanceFor(result.Groups[1]).TokenFile)
+
+	_, err = ReadConfigFile("testdata/config/badTokenFile.yml")
+	assert.ErrorIs(t, err, ErrorBadTokenFile)
+}
+
+func TestAPI(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	// GraphQL is used by default
+	result, err = ReadConfigFile("testdata/config/good.yml")
+	require.Nil(t, err)
+	assert.Equal(t, APIGraphQL, result.API)
+
+	result, err = ReadConfigFile("testdata/config/instances.yml")
+	require.Nil(t, err)
+	assert.Equal(t, APIGraphQL, result.Instance("dc1").API)
+	assert.Equal(t, APIREST, result.Instance("dc2").API)
+
+	_, err = ReadConfigFile("testdata/config/badAPI.yml")
+	assert.ErrorIs(t, err, ErrorBadAPI)
+
+	_, err = ReadConfigFile("testdata/config/badAPI2.yml")
+	assert.ErrorIs(t, err, ErrorBadAPI)
+}
+
+func TestFormat(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/format.yml")
+	require.Nil(t, err)
+
+	assert.Equal(t, FormatJSON, result.Groups[0].Format)
+	assert.Equal(t, FormatYAML, result.Groups[1].Format)
+
+	result, err = ReadConfigFile("testdata/config/good.yml")
+	require.Nil(t, err)
+	assert.Equal(t, FormatYAML, result.Groups[0].Format)
+
+	_, err = ReadConfigFile("testdata/config/badFormat.yml")
+	assert.ErrorIs(t, err, ErrorBadFormat)
+
+	// skipped targets can't be reported in json files
+	_, err = ReadConfigFile("testdata/config/badFormat2.yml")
+	assert.ErrorIs(t, err, ErrorBadFormat)
+
+	_, err = ReadConfigFile("testdata/config/badFormat3.yml")
+	assert.ErrorIs(t, err, ErrorBadFormat)
+}
+
+func TestPageSize(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/pageSize.yml")
+	require.Nil(t, err)
+	assert.Equal(t, 500, *result.PageSize)
+
+	result, err = ReadConfigFile("testdata/config/good.yml")
+	require.Nil(t, err)
+	assert.Equal(t, DefaultPageSize, *result.PageSize)
+
+	// pagination disabled explicitly
+	result, err = ReadConfigFile("testdata/config/pageSize2.yml")
+	require.Nil(t, err)
+	assert.Equal(t, 0, *result.PageSize)
+
+	_, err = ReadConfigFile("testdata/config/badPageSize.yml")
+	assert.ErrorIs(t, err, ErrorBadPageSize)
+}
+
+func TestCacheTTL(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/cacheTTL.yml")
+	require.Nil(t, err)
+	assert.Equal(t, time.Minute, result.CacheTTL)
+
+	// caching disabled by default
+	result, err = ReadConfigFile("testdata/config/good.yml")
+	require.Nil(t, err)
+	assert.Equal(t, time.Duration(0), result.CacheTTL)
+
+	_, err = ReadConfigFile("testdata/config/badCacheTTL.yml")
+	assert.ErrorIs(t, err, ErrorBadCacheTTL)
+
+	_, err = ReadConfigFile("testdata/config/badCacheTTL2.yml")
+	assert.ErrorIs(t, err, ErrorBadCacheTTL)
+}
+
+func TestClientOptions(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/clientOptions.yml")
+	require.Nil(t, err)
+	assert.Equal(t, time.Minute, result.ClientOptions.RequestTimeout)
+	assert.Equal(t, DefaultDialTimeout, result.ClientOptions.DialTimeout)
+	assert.Equal(t, 30*time.Second, result.ClientOptions.ResponseHeaderTimeout)
+	assert.Equal(t, 5, *result.ClientOptions.MaxAttempts)
+	assert.Equal(t, 2*time.Second, result.ClientOptions.RetryBackoff)
+	assert.Equal(t, "http://proxy.domain.tld:3128", result.ClientOptions.ProxyURL.String())
+	assert.True(t, result.ClientOptions.NativeHistograms)
+	assert.Equal(t, "team-monitoring (monitoring@domain.tld)", result.ClientOptions.UserAgentSuffix)
+
+	// defaults
+	result, err = ReadConfigFile("testdata/config/good.yml")
+	require.Nil(t, err)
+	assert.Equal(t, DefaultRequestTimeout, result.ClientOptions.RequestTimeout)
+	assert.Equal(t, DefaultDialTimeout, result.ClientOptions.DialTimeout)
+	assert.Equal(t, time.Duration(0), result.ClientOptions.ResponseHeaderTimeout)
+	assert.Equal(t, DefaultMaxAttempts, *result.ClientOptions.MaxAttempts)
+	assert.Equal(t, DefaultRetryBackoff, result.ClientOptions.RetryBackoff)
+	assert.Nil(t, result.ClientOptions.ProxyURL)
+
+	_, err = ReadConfigFile("testdata/config/badClientOptions.yml")
+	assert.ErrorIs(t, err, ErrorBadClientOptions)
+
+	_, err = ReadConfigFile("testdata/config/badClientOptions2.yml")
+	assert.ErrorIs(t, err, ErrorBadClientOptions)
+
+	_, err = ReadConfigFile("testdata/config/badClientOptions3.yml")
+	assert.ErrorIs(t, err, ErrorBadClientOptions)
+
+	_, err = ReadConfigFile("testdata/config/badClientOptions4.yml")
+	assert.ErrorIs(t, err, ErrorBadClientOptions)
+
+	_, err = ReadConfigFile("testdata/config/badClientOptions5.yml")
+	assert.ErrorIs(t, err, ErrorBadClientOptions)
+}
+
+func TestFileOptions(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/fileMode.yml")
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), result.Groups[0].FileMode)
+	assert.Equal(t, 0, result.Groups[0].UID)
+	assert.Equal(t, 0, result.Groups[0].GID)
+
+	_, err = ReadConfigFile("testdata/config/badFileMode.yml")
+	assert.ErrorIs(t, err, ErrorBadFileMode)
+
+	_, err = ReadConfigFile("testdata/config/badFileOwner.yml")
+	assert.ErrorIs(t, err, ErrorBadFileOwner)
+}
+
+func TestShardFile(t *testing.T) {
+	var (
+		group *Group = &Group{File: "node.yml"}
+		file  string
+		err   error
+	)
+
+	file, err = group.ShardFile(model.LabelSet{"netbox_site": "dc1"})
+	require.Nil(t, err)
+	assert.Equal(t, "node.yml", file)
+
+	group.fileTemplate = template.Must(template.New("").Option("missingkey=error").
+		Parse("node_{{.netbox_site}}.yml"))
+
+	file, err = group.ShardFile(model.LabelSet{"netbox_site": "dc1"})
+	require.Nil(t, err)
+	assert.Equal(t, "node_dc1.yml", file)
+
+	// label values can't change the directory
+	file, err = group.ShardFile(model.LabelSet{"netbox_site": "../etc/dc1"})
+	require.Nil(t, err)
+	assert.Equal(t, "node_.._etc_dc1.yml", file)
+
+	_, err = group.ShardFile(model.LabelSet{"netbox_role": "router"})
+	assert.Error(t, err)
+
+	group.fileTemplate = template.Must(template.New("").Parse("{{if .netbox_site}}x{{end}}"))
+	_, err = group.ShardFile(model.LabelSet{"netbox_site": ""})
+	assert.Error(t, err)
+}
+
+func TestHashmodFile(t *testing.T) {
+	var (
+		group *Group = &Group{File: "node.yml"}
+		file  string
+		again string
+		ok    bool
+		count map[string]int = make(map[string]int)
+		i     int
+	)
+
+	file, ok = group.HashmodFile("node.yml", "192.0.2.1")
+	assert.True(t, ok)
+	assert.Equal(t, "node.yml", file)
+
+	group.Hashmod = &Hashmod{Shards: 3}
+
+	file, ok = group.HashmodFile("node.yml", "192.0.2.1")
+	assert.True(t, ok)
+	assert.Regexp(t, `^node_[0-2]\.yml$`, file)
+
+	// stable
+	again, _ = group.HashmodFile("node.yml", "192.0.2.1")
+	assert.Equal(t, file, again)
+
+	for i = 0; i < 300; i++ {
+		file, _ = group.HashmodFile("node.yml", fmt.Sprintf("192.0.2.%d", i))
+		count[file]++
 	}
+
+	assert.Len(t, count, 3)
+
+	// only shard 1 is written
+	group.Hashmod.Write = []int{1}
+	count = make(map[string]int)
+
+	for i = 0; i < 300; i++ {
+		if file, ok = group.HashmodFile("node.yml", fmt.Sprintf("192.0.2.%d", i)); ok {
+			count[file]++
+		}
+	}
+
+	assert.Len(t, count, 1)
+	assert.Contains(t, count, "node_1.yml")
+}
+
+func TestConsul(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/consul.yml")
+	require.Nil(t, err)
+	assert.Equal(t, &Consul{
+		Address: DefaultConsulAddress,
+		Token:   "secret",
+		Node:    DefaultConsulNode,
+	}, result.Consul)
+	assert.Equal(t, "node", result.Groups[0].ConsulService)
+
+	// consul_service without consul
+	_, err = ReadConfigFile("testdata/config/badConsul.yml")
+	assert.ErrorIs(t, err, ErrorBadConsul)
+
+	// address without scheme
+	_, err = ReadConfigFile("testdata/config/badConsul2.yml")
+	assert.ErrorIs(t, err, ErrorBadConsul)
+}
+
+func TestDNSZone(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/dnsZone.yml")
+	require.Nil(t, err)
+	assert.Equal(t, &DNSZone{
+		File:   "devices.zone",
+		Domain: "devices.example.com.",
+		TTL:    DefaultDNSZoneTTL,
+	}, result.DNSZone)
+
+	// invalid domain
+	_, err = ReadConfigFile("testdata/config/badDNSZone.yml")
+	assert.ErrorIs(t, err, ErrorBadDNSZone)
+
+	// ttl below a second
+	_, err = ReadConfigFile("testdata/config/badDNSZone2.yml")
+	assert.ErrorIs(t, err, ErrorBadDNSZone)
+}
+
+func TestEtcd(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/etcd.yml")
+	require.Nil(t, err)
+	assert.Equal(t, &Etcd{
+		Endpoints: []string{"http://etcd1:2379", "http://etcd2:2379"},
+		Prefix:    DefaultEtcdPrefix,
+	}, result.Etcd)
+
+	// endpoint without scheme
+	_, err = ReadConfigFile("testdata/config/badEtcd.yml")
+	assert.ErrorIs(t, err, ErrorBadEtcd)
+
+	// password without username
+	_, err = ReadConfigFile("testdata/config/badEtcd2.yml")
+	assert.ErrorIs(t, err, ErrorBadEtcd)
+}
+
+func TestIcinga(t *testing.T) {
+	var (
+		result *Config
+		data   []byte
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/icinga.yml")
+	require.Nil(t, err)
+	assert.Equal(t, "hosts.conf", result.Icinga.File)
+	assert.Equal(t, "netbox-host", result.Icinga.Import)
+
+	data, err = result.Icinga.RenderHost(map[string]string{"Name": "device\\a", "Import": "netbox-host"})
+	require.Nil(t, err)
+	assert.Equal(t, "object Host \"device\\\\a\" {\n  import \"netbox-host\"\n}\n", string(data))
+
+	// template not parsing
+	_, err = ReadConfigFile("testdata/config/badIcinga.yml")
+	assert.ErrorIs(t, err, ErrorBadIcinga)
+}
+
+func TestConfigMap(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/configMap.yml")
+	require.Nil(t, err)
+	assert.Equal(t, &ConfigMap{Name: "netbox-sd"}, result.ConfigMap)
+
+	// name not allowed by Kubernetes
+	_, err = ReadConfigFile("testdata/config/badConfigMap.yml")
+	assert.ErrorIs(t, err, ErrorBadConfigMap)
+}
+
+func TestWebhook(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/webhook.yml")
+	require.Nil(t, err)
+	assert.Equal(t, "https://hooks.domain.tld/netbox_sd", result.Webhook.URL)
+	assert.Equal(t, 0, *result.Webhook.Retries)
+	assert.Equal(t, DefaultWebhookRetryInterval, result.Webhook.RetryInterval)
+	assert.Equal(t, 5*time.Second, result.Webhook.Timeout)
+
+	// url without scheme
+	_, err = ReadConfigFile("testdata/config/badWebhook.yml")
+	assert.ErrorIs(t, err, ErrorBadWebhook)
+
+	// negative retries
+	_, err = ReadConfigFile("testdata/config/badWebhook2.yml")
+	assert.ErrorIs(t, err, ErrorBadWebhook)
+
+	// bad timeout
+	_, err = ReadConfigFile("testdata/config/badWebhook3.yml")
+	assert.ErrorIs(t, err, ErrorBadWebhook)
+}
+
+func TestTLS(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/tls.yml")
+	require.Nil(t, err)
+
+	// relative paths are relative to the config file; allow_insecure is still honored
+	assert.Equal(t, &netbox.TLSConfig{
+		CAFile:             "testdata/config/certs/ca.pem",
+		CertFile:           "/etc/netbox_sd/client.pem",
+		KeyFile:            "/etc/netbox_sd/client.key",
+		MinVersion:         tls.VersionTLS13,
+		ServerName:         "netbox.internal",
+		InsecureSkipVerify: true,
+	}, result.InstanceFor(result.Groups[0]).TLSConfig())
+
+	assert.Equal(t, &netbox.TLSConfig{InsecureSkipVerify: true}, result.Instance("dc2").TLSConfig())
+	assert.Equal(t, &netbox.TLSConfig{}, (&Instance{}).TLSConfig())
+
+	_, err = ReadConfigFile("testdata/config/badTLSConfig.yml")
+	assert.ErrorIs(t, err, ErrorBadTLSConfig)
+
+	_, err = ReadConfigFile("testdata/config/badTLSConfig2.yml")
+	assert.ErrorIs(t, err, ErrorBadTLSConfig)
+}
+
+func TestGroupsDir(t *testing.T) {
+	var (
+		result *Config
+		err    error
+		files  []string
+		group  *Group
+	)
+
+	result, err = ReadConfigFile("testdata/config/groupsDir.yml")
+	require.Nil(t, err)
+
+	assert.Equal(t, "testdata/config/groups.d", result.GroupsDir)
+
+	for _, group = range result.Groups {
+		files = append(files, group.File)
+	}
+
+	// groups of the config file come first, followed by the files of groups_dir in lexical order
+	assert.Equal(t, []string{"node.prom", "junos.prom", "ipmi.prom", "snmp.prom"}, files)
+	assert.Equal(t, "", result.Groups[0].Source)
+	assert.Equal(t, "testdata/config/groups.d/20-storage.yml", result.Groups[3].Source)
+	assert.Equal(t, 5*time.Minute, result.Groups[3].ScanInterval)
+
+	// duplicate files are detected across all files
+	_, err = ReadConfigFile("testdata/config/duplicateFile3.yml")
+	assert.ErrorIs(t, err, ErrorDuplicateFile)
+}
+
+func TestDefaults(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/defaults.yml")
+	require.Nil(t, err)
+
+	// all options are inherited
+	assert.Equal(t, time.Minute, result.Groups[0].ScanInterval)
+	assert.Equal(t, 9100, *result.Groups[0].Port)
+	assert.False(t, *result.Groups[0].Flags.IncludeVMs)
+	assert.Equal(t, InetFamilyInet6, *result.Groups[0].Flags.InetFamily)
+	assert.Equal(t, model.LabelSet{"team": "unknown", "env": "prod"}, result.Groups[0].Labels)
+	assert.Len(t, result.Groups[0].Filters, 1)
+	assert.Equal(t, "dc1", result.Groups[0].Filters[0].Match)
+
+	// all options are overridden
+	assert.Equal(t, 10*time.Minute, result.Groups[1].ScanInterval)
+	assert.Equal(t, 9200, *result.Groups[1].Port)
+	assert.True(t, *result.Groups[1].Flags.IncludeVMs)
+	assert.Equal(t, InetFamilyInet6, *result.Groups[1].Flags.InetFamily)
+	assert.Equal(t, model.LabelSet{"team": "network", "env": "staging"}, result.Groups[1].Labels)
+	assert.Len(t, result.Groups[1].Filters, 1)
+	assert.Equal(t, "dc2", result.Groups[1].Filters[0].Match)
+
+	// defaults must not be modified by groups
+	assert.Equal(t, 9100, *result.Defaults.Port)
+	assert.Equal(t, model.LabelSet{"team": "unknown", "env": "prod"}, result.Defaults.Labels)
+
+	_, err = ReadConfigFile("testdata/config/badDefaults.yml")
+	assert.ErrorIs(t, err, ErrorBadFilterLabel)
+}
+
+func TestGlobalLabels(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/globalLabels.yml")
+	require.Nil(t, err)
+
+	// default labels take precedence over global labels
+	assert.Equal(t, model.LabelSet{"datacenter": "dc1", "environment": "staging"}, result.Groups[0].Labels)
+	// group labels take precedence over global labels
+	assert.Equal(t, model.LabelSet{"datacenter": "dc2", "environment": "staging"}, result.Groups[1].Labels)
+	assert.Equal(t, model.LabelSet{"datacenter": "dc1", "environment": "prod"}, result.Labels)
+}
+
+func TestPortFor(t *testing.T) {
+	var (
+		port  int    = 9100
+		group *Group = &Group{}
+	)
+
+	assert.Nil(t, group.PortFor(model.LabelSet{}))
+
+	group.Port = &port
+	assert.Equal(t, 9100, *group.PortFor(model.LabelSet{"netbox_exporter_port": "9101"}))
+
+	group.PortFromCustomField = "exporter_port"
+	assert.Equal(t, 9101, *group.PortFor(model.LabelSet{"netbox_exporter_port": "9101"}))
+
+	// fall back to port when the custom field isn't usable
+	assert.Equal(t, 9100, *group.PortFor(model.LabelSet{}))
+	assert.Equal(t, 9100, *group.PortFor(model.LabelSet{"netbox_exporter_port": "http"}))
+	assert.Equal(t, 9100, *group.PortFor(model.LabelSet{"netbox_exporter_port": "65536"}))
+
+	group.Port = nil
+	assert.Nil(t, group.PortFor(model.LabelSet{"netbox_exporter_port": ""}))
+}
+
+func TestMatchList(t *testing.T) {
+	var (
+		result *Config
+		err    error
+	)
+
+	result, err = ReadConfigFile("testdata/config/matchList.yml")
+	require.Nil(t, err)
+
+	assert.Equal(t, "monitored AND node_exporter", result.Groups[0].Match.Value)
+	assert.True(t, result.Groups[0].TagExpr.Match([]string{"monitored", "node_exporter"}))
+	assert.False(t, result.Groups[0].TagExpr.Match([]string{"node_exporter"}))
+
+	assert.Equal(t, "snmp OR snmp_v3", result.Groups[1].Match.Value)
+	assert.True(t, result.Groups[1].TagExpr.Match([]string{"snmp_v3"}))
+
+	// lists are only supported by tag based group types
+	_, err = ReadConfigFile("testdata/config/badMatchList.yml")
+	assert.ErrorIs(t, err, ErrorBadMatchList)
+
+	// entries must be tags
+	_, err = ReadConfigFile("testdata/config/badMatchList2.yml")
+	assert.ErrorIs(t, err, ErrorBadMatchList)
+
+	_, err = ReadConfigFile("testdata/config/badMatchMode.yml")
+	assert.ErrorIs(t, err, ErrorBadMatchMode)
+
+	// aliases of lists and tags
+	result, err = ReadConfigFile("testdata/config/matchListAlias.yml")
+	require.Nil(t, err)
+
+	assert.Equal(t, "monitored AND snmp", result.Groups[0].Match.Value)
+	assert.Equal(t, "monitored OR snmp", result.Groups[1].Match.Value)
+	assert.Equal(t, "snmp OR snmp_v3", result.Groups[2].Match.Value)
+
+	// errors refer to the lines of the file as it has been written
+	_, err = ReadConfigFile("testdata/config/badMatchListStrict.yml")
+	assert.ErrorIs(t, err, ErrorParsingFile)
+	assert.ErrorContains(t, err, "line 10: field unknown_option not found")
+
+	_, err = ReadConfigFile("testdata/config/badMatchList2.yml")
+	assert.ErrorContains(t, err, "line 10:")
+}
+
+func TestExcludedByTags(t *testing.T) {
+	var group *Group = &Group{}
+
+	assert.False(t, group.ExcludedByTags([]string{"maintenance"}))
+
+	group.ExcludeTags = []string{"maintenance", "no-monitoring"}
+	assert.True(t, group.ExcludedByTags([]string{"node_exporter", "no-monitoring"}))
+	assert.False(t, group.ExcludedByTags([]string{"node_exporter"}))
+	assert.False(t, group.ExcludedByTags(nil))
+}
+
+func TestDeviceFilter(t *testing.T) {
+	var group *Group = &Group{}
+
+	assert.Nil(t, group.DeviceFilter())
+
+	group.Prefilter = &Prefilter{
+		Sites:    []string{"site-a"},
+		Statuses: []string{"active", "staged"},
+	}
+	assert.Equal(t, &netbox.DeviceFilter{
+		Sites:    []string{"site-a"},
+		Statuses: []string{"active", "staged"},
+	}, group.DeviceFilter())
+}
+
+func TestCustomFieldAllowed(t *testing.T) {
+	var group *Group = &Group{}
+
+	assert.True(t, group.CustomFieldAllowed("foo"))
+
+	group.CustomFieldExclude = []string{"foo"}
+	assert.False(t, group.CustomFieldAllowed("foo"))
+	assert.True(t, group.CustomFieldAllowed("bar"))
+
+	group.CustomFieldInclude = []string{"foo", "bar"}
+	assert.False(t, group.CustomFieldAllowed("foo"))
+	assert.True(t, group.CustomFieldAllowed("bar"))
+	assert.False(t, group.CustomFieldAllowed("baz"))
+}
+
+func TestSchemeFor(t *testing.T) {
+	var (
+		group *Group = &Group{
+			Scheme: &Scheme{
+				CustomField: "scrape_scheme",
+				Default:     SchemeHTTP,
+			},
+		}
+		err error
+	)
+
+	assert.Equal(t, SchemeHTTPS, group.SchemeFor(model.LabelSet{"netbox_scrape_scheme": "https"}))
+	assert.Equal(t, SchemeHTTPS, group.SchemeFor(model.LabelSet{"netbox_scrape_scheme": "HTTPS"}))
+	// invalid values and missing custom fields fall back to default
+	assert.Equal(t, SchemeHTTP, group.SchemeFor(model.LabelSet{"netbox_scrape_scheme": "ftp"}))
+	assert.Equal(t, SchemeHTTP, group.SchemeFor(model.LabelSet{}))
+
+	group.Scheme.Default = ""
+	assert.Equal(t, "", group.SchemeFor(model.LabelSet{}))
+
+	group.Scheme = nil
+	assert.Equal(t, "", group.SchemeFor(model.LabelSet{"netbox_scrape_scheme": "https"}))
+
+	_, err = ReadConfigFile("testdata/config/badScheme.yml")
+	assert.ErrorIs(t, err, ErrorBadScheme)
+
+	_, err = ReadConfigFile("testdata/config/badScheme2.yml")
+	assert.ErrorIs(t, err, ErrorBadScheme)
+}
+
+func TestProbeModeFor(t *testing.T) {
+	var probe *ProbeMode = &ProbeMode{
+		Exporter:          "snmp:9116",
+		Module:            "if_mib",
+		ModuleCustomField: "snmp_module",
+		PlatformModules:   map[string]string{"Junos": "juniper"},
+		Auth:              "public_v2",
+		AuthCustomField:   "snmp_auth",
+	}
+
+	// custom field takes precedence over platform and default
+	assert.Equal(t, "arista", probe.ModuleFor(model.LabelSet{"netbox_snmp_module": "arista", "netbox_platform": "Junos"}))
+	assert.Equal(t, "juniper", probe.ModuleFor(model.LabelSet{"netbox_snmp_module": "", "netbox_platform": "Junos"}))
+	assert.Equal(t, "if_mib", probe.ModuleFor(model.LabelSet{"netbox_platform": "EOS"}))
+
+	assert.Equal(t, "private_v3", probe.AuthFor(model.LabelSet{"netbox_snmp_auth": "private_v3"}))
+	assert.Equal(t, "public_v2", probe.AuthFor(model.LabelSet{}))
+
+	probe.Module = ""
+	probe.Auth = ""
+	assert.Equal(t, "", probe.ModuleFor(model.LabelSet{}))
+	assert.Equal(t, "", probe.AuthFor(model.LabelSet{}))
+}
+
+func TestConfigContextMatch(t *testing.T) {
+	var (
+		group    *Group = &Group{Match: Match{Value: "monitoring.enabled = true"}}
+		path     []string
+		value    string
+		hasValue bool
+	)
+
+	path, value, hasValue = group.ConfigContextMatch()
+	assert.Equal(t, []string{"monitoring", "enabled"}, path)
+	assert.Equal(t, "true", value)
+	assert.True(t, hasValue)
+
+	group.Match.Value = "monitoring"
+	path, value, hasValue = group.ConfigContextMatch()
+	assert.Equal(t, []string{"monitoring"}, path)
+	assert.Equal(t, "", value)
+	assert.False(t, hasValue)
 }
@@ -0,0 +1,193 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Diff describes the differences between two configurations. Groups are identified by their file name.
+type Diff struct {
+	// Global contains the names of all global options that have changed (excluding groups).
+	Global []string
+	// GroupsAdded contains the file names of all groups that only exist in the new config.
+	GroupsAdded []string
+	// GroupsRemoved contains the file names of all groups that only exist in the old config.
+	GroupsRemoved []string
+	// GroupsModified maps the file name of groups existing in both configs to the names of all options that changed.
+	GroupsModified map[string][]string
+}
+
+// NewDiff compares old and new and returns the differences. old may be nil, in which case all groups of new are
+// considered to be added.
+func NewDiff(old, new *Config) *Diff {
+	var (
+		diff     *Diff = &Diff{GroupsModified: make(map[string][]string)}
+		oldIndex map[string]*Group
+		newIndex map[string]*Group
+		file     string
+		group    *Group
+		ok       bool
+		changed  []string
+	)
+
+	if old == nil {
+		old = new
+		oldIndex = make(map[string]*Group)
+	} else {
+		oldIndex = groupIndex(old.Groups)
+		diff.Global = changedFields(old, new, "groups")
+	}
+
+	newIndex = groupIndex(new.Groups)
+
+	for file, group = range newIndex {
+		if _, ok = oldIndex[file]; !ok {
+			diff.GroupsAdded = append(diff.GroupsAdded, file)
+			continue
+		}
+
+		changed = changedFields(oldIndex[file], group)
+		if len(changed) > 0 {
+			diff.GroupsModified[file] = changed
+		}
+	}
+
+	for file = range oldIndex {
+		if _, ok = newIndex[file]; !ok {
+			diff.GroupsRemoved = append(diff.GroupsRemoved, file)
+		}
+	}
+
+	sort.Strings(diff.GroupsAdded)
+	sort.Strings(diff.GroupsRemoved)
+
+	return diff
+}
+
+// Empty returns true when there are no differences.
+func (diff *Diff) Empty() bool {
+	return len(diff.Global) == 0 &&
+		len(diff.GroupsAdded) == 0 &&
+		len(diff.GroupsRemoved) == 0 &&
+		len(diff.GroupsModified) == 0
+}
+
+// String returns a human readable representation of diff with one change per line.
+func (diff *Diff) String() string {
+	var (
+		lines []string
+		files []string
+		file  string
+	)
+
+	if len(diff.Global) > 0 {
+		lines = append(lines, fmt.Sprintf("global options changed: %s", strings.Join(diff.Global, ", ")))
+	}
+
+	if len(diff.GroupsAdded) > 0 {
+		lines = append(lines, fmt.Sprintf("groups added: %s", strings.Join(diff.GroupsAdded, ", ")))
+	}
+
+	if len(diff.GroupsRemoved) > 0 {
+		lines = append(lines, fmt.Sprintf("groups removed: %s", strings.Join(diff.GroupsRemoved, ", ")))
+	}
+
+	for file = range diff.GroupsModified {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
+
+	for _, file = range files {
+		lines = append(lines, fmt.Sprintf("group %s changed: %s", file, strings.Join(diff.GroupsModified[file], ", ")))
+	}
+
+	if len(lines) == 0 {
+		return "no changes"
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// groupIndex returns a map of groups indexed by their file name.
+func groupIndex(groups []*Group) map[string]*Group {
+	var (
+		index map[string]*Group = make(map[string]*Group, len(groups))
+		group *Group
+	)
+
+	for _, group = range groups {
+		index[group.File] = group
+	}
+
+	return index
+}
+
+// changedFields compares all yaml options of two structs of the same type and returns the names of those that differ.
+// Values are compared by their yaml representation so that derived (unexported or `yaml:"-"`) fields are ignored.
+// Options listed in skip are not compared.
+func changedFields(old, new any, skip ...string) []string {
+	var (
+		oldValue reflect.Value = reflect.Indirect(reflect.ValueOf(old))
+		newValue reflect.Value = reflect.Indirect(reflect.ValueOf(new))
+		field    reflect.StructField
+		name     string
+		oldData  []byte
+		newData  []byte
+		changed  []string
+		i        int
+	)
+
+	for i = 0; i < oldValue.NumField(); i++ {
+		field = oldValue.Type().Field(i)
+		name = strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+		if !field.IsExported() || name == "-" || name == "" || contains(skip, name) {
+			continue
+		}
+
+		// Marshalling of config values can't fail as they have been unmarshalled from yaml before.
+		oldData, _ = yaml.Marshal(oldValue.Field(i).Interface())
+		newData, _ = yaml.Marshal(newValue.Field(i).Interface())
+
+		if string(oldData) != string(newData) {
+			changed = append(changed, name)
+		}
+	}
+
+	return changed
+}
+
+// contains returns true if needle is part of haystack.
+func contains(haystack []string, needle string) bool {
+	var i int
+
+	for i = range haystack {
+		if haystack[i] == needle {
+			return true
+		}
+	}
+
+	return false
+}
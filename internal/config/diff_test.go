@@ -0,0 +1,67 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDiff(t *testing.T) {
+	var (
+		old  *Config
+		new  *Config
+		diff *Diff
+		err  error
+	)
+
+	old, err = ReadConfigFile("testdata/config/good.yml")
+	require.NoError(t, err)
+
+	// initial load
+	diff = NewDiff(nil, old)
+	assert.Equal(t, []string{"ipmi_exporter.prom", "junos2.prom", "junos3.prom", "junos_exporter.prom"}, diff.GroupsAdded)
+	assert.Empty(t, diff.GroupsRemoved)
+	assert.Empty(t, diff.GroupsModified)
+	assert.Empty(t, diff.Global)
+
+	// no changes
+	new, err = ReadConfigFile("testdata/config/good.yml")
+	require.NoError(t, err)
+	assert.True(t, NewDiff(old, new).Empty())
+	assert.Equal(t, "no changes", NewDiff(old, new).String())
+
+	// various changes
+	new.ScanIntervalString = "1m"
+	new.Groups[0].Match.Value = "something_else"
+	new.Groups[0].Filters = append(new.Groups[0].Filters, &Filter{Label: "netbox_foo", Match: "bar"})
+	new.Groups[2].File = "junos4.prom"
+
+	diff = NewDiff(old, new)
+	assert.False(t, diff.Empty())
+	assert.Equal(t, []string{"scan_interval"}, diff.Global)
+	assert.Equal(t, []string{"junos4.prom"}, diff.GroupsAdded)
+	assert.Equal(t, []string{"junos2.prom"}, diff.GroupsRemoved)
+	assert.Equal(t, map[string][]string{"junos_exporter.prom": {"match", "filters"}}, diff.GroupsModified)
+	assert.Equal(t, `global options changed: scan_interval
+groups added: junos4.prom
+groups removed: junos2.prom
+group junos_exporter.prom changed: match, filters`, diff.String())
+}
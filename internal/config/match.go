@@ -0,0 +1,100 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Match is the match of a group. It's either a single value or, for tag based group types, a list of tags that is
+// equivalent to the tags joined by AND or OR depending on the group's match_mode.
+type Match struct {
+	// Value is the match or, once the group has been validated, the tag expression of the list of tags.
+	Value string
+	// tags contains the list of tags when the match has been given as list.
+	tags []string
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for Match.
+func (match *Match) UnmarshalYAML(node *yaml.Node) error {
+	var (
+		tag    *yaml.Node
+		tokens []string
+	)
+
+	*match = Match{}
+
+	if node.Kind != yaml.SequenceNode {
+		return node.Decode(&match.Value)
+	}
+
+	for _, tag = range node.Content {
+		if tag.Kind == yaml.AliasNode {
+			tag = tag.Alias
+		}
+
+		tokens = tokenizeTagExpr(tag.Value)
+
+		// each entry must be a single tag, not an expression
+		if tag.Kind != yaml.ScalarNode ||
+			len(tokens) != 1 ||
+			tokens[0] == "(" || tokens[0] == ")" ||
+			tokens[0] == "AND" || tokens[0] == "OR" || tokens[0] == "NOT" {
+			return fmt.Errorf("%w: line %d: %q is not a tag", ErrorBadMatchList, tag.Line, tag.Value)
+		}
+
+		match.tags = append(match.tags, tokens[0])
+	}
+
+	if len(match.tags) == 0 {
+		return fmt.Errorf("%w: line %d: empty list", ErrorBadMatchList, node.Line)
+	}
+
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface for Match.
+func (match Match) MarshalYAML() (any, error) {
+	if match.tags != nil {
+		return match.tags, nil
+	}
+
+	return match.Value, nil
+}
+
+// String returns the value of the match.
+func (match Match) String() string {
+	return match.Value
+}
+
+// IsList returns true when the match has been given as a list of tags.
+func (match Match) IsList() bool {
+	return match.tags != nil
+}
+
+// expr returns the tag expression of the list of tags depending on mode.
+func (match Match) expr(mode string) string {
+	if mode == MatchModeAny {
+		return strings.Join(match.tags, " OR ")
+	}
+
+	return strings.Join(match.tags, " AND ")
+}
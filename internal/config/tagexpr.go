@@ -0,0 +1,305 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	tagExprTag tagExprOp = iota
+	tagExprAnd
+	tagExprOr
+	tagExprNot
+)
+
+// tagExprOp is the operation of a TagExpr node.
+type tagExprOp int
+
+// TagExpr is a parsed tag expression like `node_exporter AND production NOT maintenance`. Tags are Netbox tag slugs
+// combined by the operators NOT, AND and OR (in order of precedence) and parentheses. Adjacent operands without an
+// operator are combined by AND, thus a single tag is a valid expression as well.
+type TagExpr struct {
+	op       tagExprOp
+	tag      string
+	operands []*TagExpr
+}
+
+// tagExprParser is a recursive descent parser for tag expressions.
+type tagExprParser struct {
+	tokens []string
+	pos    int
+}
+
+// ParseTagExpr parses s as tag expression.
+func ParseTagExpr(s string) (*TagExpr, error) {
+	var (
+		parser *tagExprParser = &tagExprParser{tokens: tokenizeTagExpr(s)}
+		expr   *TagExpr
+		err    error
+	)
+
+	if len(parser.tokens) == 0 {
+		return nil, fmt.Errorf("%w: empty expression", ErrorBadTagExpression)
+	}
+
+	expr, err = parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrorBadTagExpression, parser.tokens[parser.pos])
+	}
+
+	return expr, nil
+}
+
+// tokenizeTagExpr splits s into tags, operators and parentheses.
+func tokenizeTagExpr(s string) []string {
+	return strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s))
+}
+
+// peek returns the current token or an empty string at the end of the expression.
+func (p *tagExprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+
+	return ""
+}
+
+func (p *tagExprParser) parseOr() (*TagExpr, error) {
+	var (
+		expr    *TagExpr
+		operand *TagExpr
+		err     error
+	)
+
+	expr, err = p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "OR" {
+		p.pos++
+
+		operand, err = p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		expr = combineTagExpr(tagExprOr, expr, operand)
+	}
+
+	return expr, nil
+}
+
+func (p *tagExprParser) parseAnd() (*TagExpr, error) {
+	var (
+		expr    *TagExpr
+		operand *TagExpr
+		err     error
+	)
+
+	expr, err = p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() != "" && p.peek() != "OR" && p.peek() != ")" {
+		if p.peek() == "AND" {
+			p.pos++
+		}
+
+		operand, err = p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		expr = combineTagExpr(tagExprAnd, expr, operand)
+	}
+
+	return expr, nil
+}
+
+func (p *tagExprParser) parseNot() (*TagExpr, error) {
+	var (
+		expr *TagExpr
+		err  error
+	)
+
+	if p.peek() != "NOT" {
+		return p.parsePrimary()
+	}
+
+	p.pos++
+
+	expr, err = p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TagExpr{op: tagExprNot, operands: []*TagExpr{expr}}, nil
+}
+
+func (p *tagExprParser) parsePrimary() (*TagExpr, error) {
+	var (
+		token string = p.peek()
+		expr  *TagExpr
+		err   error
+	)
+
+	switch token {
+	case "":
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrorBadTagExpression)
+
+	case "AND", "OR", ")":
+		return nil, fmt.Errorf("%w: unexpected %q", ErrorBadTagExpression, token)
+
+	case "(":
+		p.pos++
+
+		expr, err = p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrorBadTagExpression)
+		}
+
+		p.pos++
+		return expr, nil
+	}
+
+	p.pos++
+	return &TagExpr{op: tagExprTag, tag: token}, nil
+}
+
+// combineTagExpr returns an expression combining left and right by op, flattening nested operations of the same kind.
+func combineTagExpr(op tagExprOp, left, right *TagExpr) *TagExpr {
+	if left.op == op {
+		left.operands = append(left.operands, right)
+		return left
+	}
+
+	return &TagExpr{op: op, operands: []*TagExpr{left, right}}
+}
+
+// Match returns true when an object carrying tags matches the expression.
+func (expr *TagExpr) Match(tags []string) bool {
+	var operand *TagExpr
+
+	switch expr.op {
+	case tagExprNot:
+		return !expr.operands[0].Match(tags)
+
+	case tagExprAnd:
+		for _, operand = range expr.operands {
+			if !operand.Match(tags) {
+				return false
+			}
+		}
+
+		return true
+
+	case tagExprOr:
+		for _, operand = range expr.operands {
+			if operand.Match(tags) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return contains(tags, expr.tag)
+}
+
+// QueryTags returns a list of tags such that every object matching the expression carries at least one of them. Thus
+// objects can be queried by these tags and filtered using Match afterwards. When no such list exists (e.g. for `NOT
+// maintenance`), ok is false.
+func (expr *TagExpr) QueryTags() (tags []string, ok bool) {
+	var (
+		operand *TagExpr
+		list    []string
+		tag     string
+	)
+
+	switch expr.op {
+	case tagExprNot:
+		return nil, false
+
+	case tagExprAnd:
+		// every operand must match, thus the shortest list of any operand is sufficient
+		for _, operand = range expr.operands {
+			list, ok = operand.QueryTags()
+			if ok && (tags == nil || len(list) < len(tags)) {
+				tags = list
+			}
+		}
+
+		return tags, tags != nil
+
+	case tagExprOr:
+		for _, operand = range expr.operands {
+			list, ok = operand.QueryTags()
+			if !ok {
+				return nil, false
+			}
+
+			for _, tag = range list {
+				if !contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+		}
+
+		return tags, true
+	}
+
+	return []string{expr.tag}, true
+}
+
+// String returns the expression in its normalized form.
+func (expr *TagExpr) String() string {
+	var (
+		operand *TagExpr
+		parts   []string
+		sep     string = " AND "
+	)
+
+	switch expr.op {
+	case tagExprTag:
+		return expr.tag
+
+	case tagExprNot:
+		return "NOT " + expr.operands[0].String()
+
+	case tagExprOr:
+		sep = " OR "
+	}
+
+	for _, operand = range expr.operands {
+		parts = append(parts, operand.String())
+	}
+
+	return "(" + strings.Join(parts, sep) + ")"
+}
@@ -0,0 +1,72 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagExpr(t *testing.T) {
+	var (
+		tests = []struct {
+			expr      string
+			tags      []string
+			match     bool
+			queryTags []string
+		}{
+			{"node_exporter", []string{"node_exporter"}, true, []string{"node_exporter"}},
+			{"node_exporter", []string{"production"}, false, []string{"node_exporter"}},
+			{"node_exporter AND production NOT maintenance", []string{"node_exporter", "production"}, true, []string{"node_exporter"}},
+			{"node_exporter AND production NOT maintenance", []string{"node_exporter", "production", "maintenance"}, false, []string{"node_exporter"}},
+			{"node_exporter production", []string{"node_exporter"}, false, []string{"node_exporter"}},
+			{"node_exporter OR snmp NOT maintenance", []string{"node_exporter", "maintenance"}, true, nil},
+			{"(node_exporter OR snmp) NOT maintenance", []string{"snmp"}, true, []string{"node_exporter", "snmp"}},
+			{"(node_exporter OR snmp) NOT maintenance", []string{"snmp", "maintenance"}, false, []string{"node_exporter", "snmp"}},
+			{"NOT NOT a", []string{"a"}, true, nil},
+		}
+		expr *TagExpr
+		tags []string
+		ok   bool
+		err  error
+	)
+
+	for _, test := range tests {
+		expr, err = ParseTagExpr(test.expr)
+		require.Nil(t, err, test.expr)
+		assert.Equal(t, test.match, expr.Match(test.tags), test.expr)
+
+		tags, ok = expr.QueryTags()
+		if test.queryTags != nil {
+			assert.True(t, ok, test.expr)
+			assert.ElementsMatch(t, test.queryTags, tags, test.expr)
+		}
+	}
+
+	expr, err = ParseTagExpr("NOT maintenance")
+	require.Nil(t, err)
+	_, ok = expr.QueryTags()
+	assert.False(t, ok)
+
+	for _, bad := range []string{"", "AND a", "a OR", "(a", "a )", "NOT"} {
+		_, err = ParseTagExpr(bad)
+		assert.ErrorIs(t, err, ErrorBadTagExpression, bad)
+	}
+}
@@ -0,0 +1,85 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+)
+
+// labelTemplateData is the data label templates are executed with.
+type labelTemplateData struct {
+	// Device is the device or VM of the target.
+	Device *netbox.Device
+	// CustomFields contains the custom fields of the device by name, formatted like their labels.
+	CustomFields map[string]string
+	// Labels contains all labels generated for the target before the group's labels have been added.
+	Labels map[string]string
+}
+
+// templateLabels returns the labels of group whose values are templates, executed for a target of dev with the given
+// labels.
+func templateLabels(group *config.Group, dev *netbox.Device, labels model.LabelSet) (model.LabelSet, error) {
+	var (
+		data     labelTemplateData
+		cfLabels model.LabelSet
+		result   model.LabelSet
+		name     model.LabelName
+		value    model.LabelValue
+		builder  strings.Builder
+		err      error
+	)
+
+	if len(group.LabelTemplates) == 0 {
+		return nil, nil
+	}
+
+	cfLabels, err = generateCustomFieldLabels(dev.CustomFields, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data = labelTemplateData{
+		Device:       dev,
+		CustomFields: make(map[string]string, len(cfLabels)),
+		Labels:       toMap(labels),
+	}
+
+	for name, value = range cfLabels {
+		data.CustomFields[strings.TrimPrefix(string(name), "netbox_")] = string(value)
+	}
+
+	result = make(model.LabelSet, len(group.LabelTemplates))
+
+	for name = range group.LabelTemplates {
+		builder.Reset()
+
+		err = group.LabelTemplates[name].Execute(&builder, data)
+		if err != nil {
+			return nil, err
+		}
+
+		result[name] = model.LabelValue(builder.String())
+	}
+
+	return result, nil
+}
@@ -0,0 +1,150 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"text/template"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+	"github.com/4xoc/netbox_sd/pkg/netbox/netboxtest"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLabelTemplates returns the parsed label templates the same way the config package does.
+func newLabelTemplates(t *testing.T, labels map[model.LabelName]string) map[model.LabelName]*template.Template {
+	var (
+		templates = make(map[model.LabelName]*template.Template, len(labels))
+		name      model.LabelName
+		value     string
+		err       error
+	)
+
+	t.Helper()
+
+	for name, value = range labels {
+		templates[name], err = template.New(string(name)).Option("missingkey=error").Parse(value)
+		require.Nil(t, err)
+	}
+
+	return templates
+}
+
+func TestTemplateLabels(t *testing.T) {
+	var (
+		dev    netbox.Device
+		group  config.Group
+		result model.LabelSet
+		err    error
+	)
+
+	require.Nil(t, json.Unmarshal([]byte(`{"name":"device-A","custom_fields":{"owner":"team-a","rack_unit":7}}`), &dev))
+
+	// no templates
+	result, err = templateLabels(&group, &dev, model.LabelSet{"netbox_name": "device-A"})
+	assert.Nil(t, err)
+	assert.Nil(t, result)
+
+	// successful render
+	group.LabelTemplates = newLabelTemplates(t, map[model.LabelName]string{
+		"instance": "{{ .Device.Name }}.example.com",
+		"owner":    "{{ .CustomFields.owner }}-{{ .CustomFields.rack_unit }}",
+		"name":     "{{ .Labels.netbox_name }}",
+		"optional": `{{ index .CustomFields "missing" }}`,
+	})
+
+	result, err = templateLabels(&group, &dev, model.LabelSet{"netbox_name": "device-A"})
+	require.Nil(t, err)
+	assert.Equal(t, model.LabelSet{
+		"instance": "device-A.example.com",
+		"owner":    "team-a-7",
+		"name":     "device-A",
+		"optional": "",
+	}, result)
+
+	// missing key
+	group.LabelTemplates = newLabelTemplates(t, map[model.LabelName]string{
+		"team": "{{ .CustomFields.team }}",
+	})
+
+	result, err = templateLabels(&group, &dev, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, result)
+
+	// execution error
+	group.LabelTemplates = newLabelTemplates(t, map[model.LabelName]string{
+		"broken": "{{ index .Labels 1 }}",
+	})
+
+	result, err = templateLabels(&group, &dev, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, result)
+}
+
+func TestGetTargetsByDevicesLabelTemplateError(t *testing.T) {
+	var (
+		sd       netboxSD
+		fixtures *netboxtest.Fixtures
+		server   *netboxtest.Server
+		devs     []*netbox.Device
+		targets  []*discovery.Target
+		target   *discovery.Target
+		skipped  int
+		err      error
+	)
+
+	fixtures, err = netboxtest.LoadFixtures("testdata/fixtures/example/netbox.yml")
+	require.Nil(t, err)
+
+	server = netboxtest.NewServer(fixtures)
+	defer server.Close()
+
+	sd.cfg, err = config.ReadConfigFile("testdata/fixtures/example/config.yml")
+	require.Nil(t, err)
+
+	sd.api, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netbox_sd_test"))
+	require.Nil(t, err)
+
+	devs, err = sd.api.GetDevices(context.Background())
+	require.Nil(t, err)
+	require.NotEmpty(t, devs)
+
+	sd.cfg.Groups[0].LabelTemplates = newLabelTemplates(t, map[model.LabelName]string{
+		"broken": "{{ index .Labels 1 }}",
+	})
+
+	targets = sd.getTargetsByDevices(context.Background(), sd.cfg.Groups[0], devs, nil)
+	require.Len(t, targets, len(devs))
+
+	for _, target = range targets {
+		assert.NotEqual(t, discovery.StateActive, target.SkipReason, target.Device.Name)
+
+		if target.SkipReason == discovery.StateSkippedOther {
+			skipped++
+		}
+	}
+
+	assert.NotZero(t, skipped)
+}
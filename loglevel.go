@@ -0,0 +1,219 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+)
+
+// LogLevel defines the verbosity of log output.
+type LogLevel int32
+
+const (
+	// LogLevelInfo only logs regular messages.
+	LogLevelInfo LogLevel = iota
+	// LogLevelDebug additionally logs debug messages.
+	LogLevelDebug
+	// LogLevelTrace additionally logs all HTTP requests and responses towards Netbox. This exposes secrets in the log.
+	LogLevelTrace
+)
+
+// Supported log formats.
+const (
+	LogFormatText string = "text"
+	LogFormatJSON string = "json"
+)
+
+var (
+	ErrBadLogLevel  = errors.New("bad log level (must be info, debug or trace)")
+	ErrBadLogFormat = errors.New("bad log format (must be text or json)")
+
+	// currentLogLevel holds the active LogLevel. It is changed at runtime thus must only be accessed atomically.
+	currentLogLevel atomic.Int32
+
+	// logLevelVar is the level of the handler installed by newLogger, kept in sync with currentLogLevel.
+	logLevelVar slog.LevelVar
+)
+
+// String returns the name of level.
+func (level LogLevel) String() string {
+	switch level {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelTrace:
+		return "trace"
+	}
+
+	return "info"
+}
+
+// ParseLogLevel returns the LogLevel for name.
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "info":
+		return LogLevelInfo, nil
+	case "debug":
+		return LogLevelDebug, nil
+	case "trace":
+		return LogLevelTrace, nil
+	}
+
+	return LogLevelInfo, fmt.Errorf("%w: %s", ErrBadLogLevel, name)
+}
+
+// slogLevel returns the slog.Level of level.
+func (level LogLevel) slogLevel() slog.Level {
+	switch level {
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelTrace:
+		return netbox.LevelTrace
+	}
+
+	return slog.LevelInfo
+}
+
+// getLogLevel returns the active LogLevel.
+func getLogLevel() LogLevel {
+	return LogLevel(currentLogLevel.Load())
+}
+
+// newLogger returns a slog.Logger writing records in format to w, filtered by the active LogLevel. Installed with
+// slog.SetDefault, it is shared by netbox_sd, the Netbox clients and the standard library's log package (logging with
+// level info).
+func newLogger(format string, w io.Writer) (*slog.Logger, error) {
+	var (
+		opts *slog.HandlerOptions = &slog.HandlerOptions{
+			AddSource:   true,
+			Level:       &logLevelVar,
+			ReplaceAttr: replaceLogAttr,
+		}
+	)
+
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case LogFormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrBadLogFormat, format)
+}
+
+// replaceLogAttr names netbox.LevelTrace "TRACE" and shortens the source of records to file and line.
+func replaceLogAttr(groups []string, attr slog.Attr) slog.Attr {
+	var (
+		level  slog.Level
+		source *slog.Source
+		ok     bool
+	)
+
+	if len(groups) > 0 {
+		return attr
+	}
+
+	switch attr.Key {
+	case slog.LevelKey:
+		if level, ok = attr.Value.Any().(slog.Level); ok && level <= netbox.LevelTrace {
+			attr.Value = slog.StringValue("TRACE")
+		}
+
+	case slog.SourceKey:
+		if source, ok = attr.Value.Any().(*slog.Source); ok {
+			attr.Value = slog.StringValue(fmt.Sprintf("%s:%d", filepath.Base(source.File), source.Line))
+		}
+	}
+
+	return attr
+}
+
+// setLogLevel changes the active LogLevel and enables HTTP tracing of all Netbox clients for LogLevelTrace.
+func (sd *netboxSD) setLogLevel(level LogLevel) {
+	var (
+		api    netbox.ClientIface
+		worker *groupWorker
+	)
+
+	currentLogLevel.Store(int32(level))
+	logLevelVar.Set(level.slogLevel())
+
+	for _, api = range sd.clients() {
+		api.HTTPTracing(level >= LogLevelTrace)
+	}
+
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+
+	for _, worker = range sd.workers {
+		if worker.api != nil {
+			worker.api.HTTPTracing(level >= LogLevelTrace)
+		}
+	}
+}
+
+// logLevelHandler returns the current log level on GET requests. When allowChange is true, the log level can be changed
+// with a PUT request containing the new level as body.
+func (sd *netboxSD) logLevelHandler(allowChange bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			body  []byte
+			level LogLevel
+			err   error
+		)
+
+		switch r.Method {
+		case http.MethodGet:
+			io.WriteString(w, getLogLevel().String()+"\n")
+
+		case http.MethodPut:
+			if !allowChange {
+				http.Error(w, "changing the log level is disabled (see -web.enable-loglevel)", http.StatusForbidden)
+				return
+			}
+
+			body, err = io.ReadAll(io.LimitReader(r.Body, 64))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			level, err = ParseLogLevel(string(body))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			slog.Info("changing log level", "from", getLogLevel(), "to", level)
+			sd.setLogLevel(level)
+			io.WriteString(w, level.String()+"\n")
+
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
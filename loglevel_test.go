@@ -0,0 +1,123 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	var (
+		level LogLevel
+		err   error
+	)
+
+	level, err = ParseLogLevel("debug")
+	assert.Nil(t, err)
+	assert.Equal(t, LogLevelDebug, level)
+
+	level, err = ParseLogLevel(" TRACE\n")
+	assert.Nil(t, err)
+	assert.Equal(t, LogLevelTrace, level)
+
+	_, err = ParseLogLevel("verbose")
+	assert.ErrorIs(t, err, ErrBadLogLevel)
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	var (
+		sd  netboxSD
+		rec *httptest.ResponseRecorder
+	)
+
+	defer sd.setLogLevel(LogLevelInfo)
+	sd.setLogLevel(LogLevelInfo)
+
+	rec = httptest.NewRecorder()
+	sd.logLevelHandler(true)(rec, httptest.NewRequest(http.MethodGet, "/-/loglevel", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "info\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	sd.logLevelHandler(true)(rec, httptest.NewRequest(http.MethodPut, "/-/loglevel", strings.NewReader("debug")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, LogLevelDebug, getLogLevel())
+	assert.Equal(t, slog.LevelDebug, logLevelVar.Level())
+
+	rec = httptest.NewRecorder()
+	sd.logLevelHandler(true)(rec, httptest.NewRequest(http.MethodPut, "/-/loglevel", strings.NewReader("verbose")))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, LogLevelDebug, getLogLevel())
+
+	// changing is not allowed unless enabled
+	rec = httptest.NewRecorder()
+	sd.logLevelHandler(false)(rec, httptest.NewRequest(http.MethodPut, "/-/loglevel", strings.NewReader("trace")))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, LogLevelDebug, getLogLevel())
+
+	rec = httptest.NewRecorder()
+	sd.logLevelHandler(true)(rec, httptest.NewRequest(http.MethodPost, "/-/loglevel", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestNewLogger(t *testing.T) {
+	var (
+		sd     netboxSD
+		buf    bytes.Buffer
+		logger *slog.Logger
+		err    error
+	)
+
+	_, err = newLogger("logfmt", &buf)
+	assert.ErrorIs(t, err, ErrBadLogFormat)
+
+	logger, err = newLogger("json", &buf)
+	require.NoError(t, err)
+
+	defer sd.setLogLevel(LogLevelInfo)
+	sd.setLogLevel(LogLevelInfo)
+
+	logger.Debug("hidden")
+	logger.Log(context.Background(), netbox.LevelTrace, "hidden")
+	assert.Empty(t, buf.String())
+
+	sd.setLogLevel(LogLevelTrace)
+
+	logger.Log(context.Background(), netbox.LevelTrace, "traced", "group", "test.yml")
+	assert.Contains(t, buf.String(), `"level":"TRACE"`)
+	assert.Contains(t, buf.String(), `"source":"loglevel_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"traced","group":"test.yml"`)
+
+	buf.Reset()
+	logger, err = newLogger("text", &buf)
+	require.NoError(t, err)
+
+	logger.Debug("shown")
+	assert.Contains(t, buf.String(), "level=DEBUG")
+	assert.Contains(t, buf.String(), "msg=shown")
+}
@@ -19,23 +19,15 @@ package main
 
 import (
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-type TargetState float64
-
 const (
-	PrometheusNameSpace             string      = "netbox_sd"
-	TargetActive                    TargetState = 1
-	TargetSkippedOther              TargetState = 0
-	TargetSkippedBadStatus          TargetState = -1
-	TargetSkippedBadCustomField     TargetState = -2
-	TargetSkippedNoValidIP          TargetState = -3
-	TargetSkippedNotMatchingFilters TargetState = -4
+	PrometheusNameSpace string = "netbox_sd"
 )
 
 var (
@@ -76,6 +68,7 @@ var (
 			"netbox_role",
 			"netbox_serial_number",
 			"netbox_asset_tag",
+			"netbox_service_id",
 		},
 	)
 
@@ -101,6 +94,50 @@ var (
 		[]string{"group"},
 	)
 
+	promMaxTargetsExceeded *prometheus.CounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   PrometheusNameSpace,
+			Subsystem:   "",
+			Name:        "max_targets_exceeded_total",
+			Help:        "Number of scans discarded because the group yielded more targets than max_targets",
+			ConstLabels: nil,
+		},
+		[]string{"group"},
+	)
+
+	promScanSkipped *prometheus.CounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   PrometheusNameSpace,
+			Subsystem:   "",
+			Name:        "scan_skipped_total",
+			Help:        "Number of scans skipped because nothing changed in Netbox since the last scan",
+			ConstLabels: nil,
+		},
+		[]string{"group"},
+	)
+
+	promFileUnchanged *prometheus.CounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   PrometheusNameSpace,
+			Subsystem:   "",
+			Name:        "file_unchanged_total",
+			Help:        "Number of target file writes skipped because the content didn't change",
+			ConstLabels: nil,
+		},
+		[]string{"group"},
+	)
+
+	promWebhookError *prometheus.CounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   PrometheusNameSpace,
+			Subsystem:   "",
+			Name:        "webhook_error_total",
+			Help:        "Number of target changes that couldn't be sent to the webhook (after all retries)",
+			ConstLabels: nil,
+		},
+		[]string{"group"},
+	)
+
 	promUpdateDuration *prometheus.GaugeVec = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace:   PrometheusNameSpace,
@@ -133,6 +170,75 @@ var (
 		},
 		[]string{"group", "netbox_name"},
 	)
+
+	promUpdateAvailable prometheus.Gauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace:   PrometheusNameSpace,
+			Subsystem:   "",
+			Name:        "update_available",
+			Help:        "1 when a newer release of netbox_sd is available (requires -update.check)",
+			ConstLabels: nil,
+		})
+
+	promDryRun prometheus.Gauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace:   PrometheusNameSpace,
+			Subsystem:   "",
+			Name:        "dry_run",
+			Help:        "1 when running with -dry-run (no target files are written)",
+			ConstLabels: nil,
+		})
+
+	promLatestVersion *prometheus.GaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   PrometheusNameSpace,
+			Subsystem:   "",
+			Name:        "latest_version",
+			Help:        "latest release of netbox_sd found during update check",
+			ConstLabels: nil,
+		},
+		[]string{"version"},
+	)
+
+	promConfigChanges *prometheus.GaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   PrometheusNameSpace,
+			Subsystem:   "",
+			Name:        "config_changes",
+			Help:        "Number of changes applied by the last config (re)load by type of change",
+			ConstLabels: nil,
+		},
+		[]string{"change"},
+	)
+
+	promConfigReloadSuccess prometheus.Gauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace:   PrometheusNameSpace,
+			Subsystem:   "",
+			Name:        "config_last_reload_successful",
+			Help:        "1 when the last config reload (SIGHUP) was successful, 0 otherwise",
+			ConstLabels: nil,
+		})
+
+	promConfigReloadTime prometheus.Gauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace:   PrometheusNameSpace,
+			Subsystem:   "",
+			Name:        "config_last_reload_success_timestamp_seconds",
+			Help:        "Time in seconds since epoch of the last successful config (re)load",
+			ConstLabels: nil,
+		})
+
+	promAddressesFiltered *prometheus.GaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   PrometheusNameSpace,
+			Subsystem:   "",
+			Name:        "addresses_filtered",
+			Help:        "Number of selected ip addresses removed by address filters in last update",
+			ConstLabels: nil,
+		},
+		[]string{"group"},
+	)
 )
 
 // Describe implements the prometheus.Describe interface.
@@ -141,15 +247,21 @@ func (sd *netboxSD) Describe(ch chan<- *prometheus.Desc) {
 	promInfo.Describe(ch)
 	promUpdateTime.Describe(ch)
 	promUpdateError.Describe(ch)
+	promMaxTargetsExceeded.Describe(ch)
+	promScanSkipped.Describe(ch)
+	promFileUnchanged.Describe(ch)
+	promWebhookError.Describe(ch)
 	promUpdateDuration.Describe(ch)
 	promTargetCount.Describe(ch)
 	promIPSkipped.Describe(ch)
+	promAddressesFiltered.Describe(ch)
+	ch <- promUpdateAvailable.Desc()
+	ch <- promDryRun.Desc()
+	promConfigChanges.Describe(ch)
+	ch <- promConfigReloadSuccess.Desc()
+	ch <- promConfigReloadTime.Desc()
+	promLatestVersion.Describe(ch)
 	promTargetState.Describe(ch)
-
-	if sd.api != nil {
-		// Get metrics from netbox-go, when already initialized.
-		sd.api.Describe(ch)
-	}
 }
 
 // Collect implements the prometheus.Collect interface.
@@ -158,18 +270,24 @@ func (sd *netboxSD) Collect(ch chan<- prometheus.Metric) {
 	promInfo.Collect(ch)
 	promUpdateTime.Collect(ch)
 	promUpdateError.Collect(ch)
+	promMaxTargetsExceeded.Collect(ch)
+	promScanSkipped.Collect(ch)
+	promFileUnchanged.Collect(ch)
+	promWebhookError.Collect(ch)
 	promUpdateDuration.Collect(ch)
 	promTargetCount.Collect(ch)
 	promIPSkipped.Collect(ch)
+	promAddressesFiltered.Collect(ch)
+	ch <- promUpdateAvailable
+	ch <- promDryRun
+	promConfigChanges.Collect(ch)
+	ch <- promConfigReloadSuccess
+	ch <- promConfigReloadTime
+	promLatestVersion.Collect(ch)
 	promTargetState.Collect(ch)
-
-	if sd.api != nil {
-		// Get metrics from netbox-go, when already initialized.
-		sd.api.Collect(ch)
-	}
 }
 
-// serveMetrics starts an http server 
+// serveMetrics starts an http server
 func (sd *netboxSD) serveMetrics(addr *string) {
 
 	prometheus.MustRegister(sd)
@@ -199,13 +317,15 @@ func (sd *netboxSD) serveMetrics(addr *string) {
 		})
 
 		mux.Handle("/metrics", promhttp.Handler())
+		mux.HandleFunc("/-/loglevel", sd.logLevelHandler(*enableLogLevel))
+		mux.HandleFunc("/-/history", sd.historyHandler)
 
-		log.Printf("starting metrics http endpont on %s", sd.httpServer.Addr)
+		slog.Info("starting metrics http endpoint", "address", sd.httpServer.Addr)
 
 		err = sd.httpServer.ListenAndServe()
 
 		if err != nil {
-			log.Printf("failed to start metrics server: %v", err)
+			slog.Error("failed to start metrics server", "error", err)
 		}
 	}()
 }
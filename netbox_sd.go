@@ -18,19 +18,25 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
 	"github.com/4xoc/netbox_sd/pkg/netbox"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/prometheus/discovery/targetgroup"
-	"gopkg.in/yaml.v3"
+	"github.com/prometheus/common/model"
 )
 
 const (
@@ -38,17 +44,59 @@ const (
 )
 
 type netboxSD struct {
-	cfg        *config.Config
+	// mu protects cfg and workers, which are replaced on config reload.
+	mu      sync.RWMutex
+	cfg     *config.Config
+	workers map[string]*groupWorker
+	// api is the client of the default Netbox instance, instances those of additional instances by name.
 	api        netbox.ClientIface
+	instances  map[string]netbox.ClientIface
 	httpServer *http.Server
+	// history keeps the target membership changes of the last cycles; nil when disabled.
+	history *membershipHistory
+	// combined keeps the targets of all groups for the combined file.
+	combined combinedTargets
+	// zone keeps the DNS records of all groups for the DNS zone file.
+	zone zoneRecords
+	// icinga keeps the hosts of all groups for the Icinga file.
+	icinga icingaHosts
+	// changelog serializes appending to the changelog file.
+	changelog changelog
+	// manifest keeps the files written by all groups for the manifest file.
+	manifest fileManifest
+	// consul registers targets in the Consul catalog; nil when not configured.
+	consul *consulClient
+	// etcd writes the targets of all groups into etcd; nil when not configured.
+	etcd *etcdClient
+	// configMap writes the targets of all groups into a Kubernetes ConfigMap; nil when not configured.
+	configMap *configMapClient
+	// stream sends target changes to gRPC subscribers; nil when disabled.
+	stream *targetStream
+	// targetDB stores the targets of all groups in a SQLite database; nil when not configured.
+	targetDB *targetDB
 }
 
 var (
 	// All cmd flags come here.
-	cfgFile     = flag.String("config.file", "config.yml", "config file path")
-	showVersion = flag.Bool("version", false, "show version information")
-	debug       = flag.Bool("debug", false, "enable debug output")
-	promListen  = flag.String("web.listen", "[::]:9099", "prometheus metrics listen address")
+	cfgFile             = flag.String("config.file", "config.yml", "config file path")
+	showVersion         = flag.Bool("version", false, "show version information")
+	debug               = flag.Bool("debug", false, "enable debug output including HTTP tracing (same as -log.level=trace)")
+	logLevel            = flag.String("log.level", "info", "log level (info, debug or trace); trace logs all HTTP requests towards Netbox")
+	logFormat           = flag.String("log.format", LogFormatText, "log format (text or json)")
+	enableLogLevel      = flag.Bool("web.enable-loglevel", false, "allow changing the log level at runtime via PUT /-/loglevel")
+	historyCycles       = flag.Int("history.cycles", 0, "number of cycles per group to keep target membership changes of (served at /-/history, 0 disables)")
+	dryRun              = flag.Bool("dry-run", false, "perform discovery and expose metrics but never write any target files")
+	rollback            = flag.String("rollback", "", "restore the newest backup of the files of the group with this file and exit (see backups)")
+	cleanup             = flag.Bool("cleanup", false, "remove target files of groups no longer configured at startup (requires state_file)")
+	stdout              = flag.Bool("stdout", false, "scan all groups once, print their targets to stdout instead of writing target files and exit")
+	stdoutFormat        = flag.String("stdout.format", "", "format of the targets printed with -stdout (yaml or json, default: global format)")
+	generateAlerts      = flag.Bool("generate.alert-rules", false, "print Prometheus alert rules for all configured groups and exit")
+	generateScrape      = flag.Bool("generate.scrape-configs", false, "print Prometheus scrape configs for all configured groups and exit")
+	generateManifest    = flag.Bool("generate.query-manifest", false, "print all GraphQL query documents sent to Netbox as JSON and exit")
+	grpcListen          = flag.String("grpc.listen", "", "listen address of the gRPC service streaming target changes (disabled when empty)")
+	promListen          = flag.String("web.listen", "[::]:9099", "prometheus metrics listen address")
+	updateCheck         = flag.Bool("update.check", false, "periodically check GitHub for new releases (exposed as metric only)")
+	updateCheckInterval = flag.Duration("update.check-interval", 24*time.Hour, "interval between update checks")
 
 	// SD is the single global instance of netboxSD to manage all groups.
 	sd *netboxSD = new(netboxSD)
@@ -60,10 +108,6 @@ var (
 )
 
 func init() {
-	// Who doesn't like more details in their logs?
-	log.SetFlags(log.Lshortfile | log.Ldate | log.Ltime | log.Lmicroseconds)
-	log.Printf("Version: %s (compiled on %s with commit %s)\n", version, date, commit)
-
 	flag.Usage = func() {
 		fmt.Println("Usage: netbox_sd [parameters]\n\nParameters:")
 		flag.PrintDefaults()
@@ -73,8 +117,14 @@ func init() {
 
 func main() {
 	var (
-		err error
-		i   int
+		err    error
+		i      int
+		group  *config.Group
+		api    netbox.ClientIface
+		level  LogLevel
+		logger *slog.Logger
+		data   []byte
+		reload chan os.Signal = make(chan os.Signal, 1)
 	)
 
 	flag.Parse()
@@ -84,116 +134,446 @@ func main() {
 		os.Exit(0)
 	}
 
-	sd.serveMetrics(promListen)
+	level, err = ParseLogLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-	log.Printf("loading config")
+	if *debug {
+		level = LogLevelTrace
+	}
 
-	sd.cfg, err = config.ReadConfigFile(*cfgFile)
+	logger, err = newLogger(*logFormat, os.Stderr)
 	if err != nil {
-		log.Printf("failed to load config file: %v", err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	sd.api, err = netbox.New(sd.cfg.BaseURL, sd.cfg.Token, PrometheusNameSpace, true, sd.cfg.AllowInsecure)
+	// log messages of the standard library's log package are sent to logger as well
+	slog.SetDefault(logger)
+	slog.Info("starting netbox_sd", "version", version, "date", date, "commit", commit)
+
+	if *generateManifest {
+		data, err = json.MarshalIndent(netbox.QueryManifest(), "", "  ")
+		if err != nil {
+			slog.Error("failed to generate query manifest", "error", err)
+			os.Exit(1)
+		}
+
+		os.Stdout.Write(append(data, '\n'))
+		os.Exit(0)
+	}
+
+	sd.setLogLevel(level)
+
+	if *historyCycles > 0 {
+		sd.history = newMembershipHistory(*historyCycles)
+	}
+
+	// generating alert rules and scrape configs as well as rolling back only require the config, printing targets to
+	// stdout is a one-shot job
+	if !*generateAlerts && !*generateScrape && !*stdout && *rollback == "" {
+		sd.serveMetrics(promListen)
+
+		if *updateCheck {
+			go checkForUpdates(*updateCheckInterval)
+		}
+	}
+
+	slog.Info("loading config", "file", *cfgFile)
+
+	sd.cfg, err = config.ReadConfigFile(*cfgFile)
 	if err != nil {
-		log.Printf("failed to initialize new api client")
+		slog.Error("failed to load config file", "file", *cfgFile, "error", err)
 		os.Exit(1)
 	}
 
-	if *debug {
-		sd.api.HTTPTracing(true)
+	if *generateAlerts {
+		data, err = generateAlertRules(sd.cfg)
+		if err != nil {
+			slog.Error("failed to generate alert rules", "error", err)
+			os.Exit(1)
+		}
+
+		os.Stdout.Write(data)
+		os.Exit(0)
+	}
+
+	if *rollback != "" {
+		err = rollbackGroup(sd.cfg, *rollback)
+		if err != nil {
+			slog.Error("failed to roll back group", "group", *rollback, "error", err)
+			os.Exit(1)
+		}
+
+		slog.Info("restored newest backup of group", "group", *rollback)
+		os.Exit(0)
 	}
 
-	err = sd.api.VerifyConnectivity()
+	if *generateScrape {
+		data, err = generateScrapeConfigs(sd.cfg)
+		if err != nil {
+			slog.Error("failed to generate scrape configs", "error", err)
+			os.Exit(1)
+		}
+
+		os.Stdout.Write(data)
+		os.Exit(0)
+	}
+
+	err = sd.initClients(sd.cfg)
 	if err != nil {
-		log.Printf("failed to verify connectivity to Netbox: %v", err)
+		slog.Error("failed to initialize netbox clients", "error", err)
 		os.Exit(1)
-	} else {
-		log.Printf("connection to Netbox successful")
 	}
 
-	// At this point the config has been read and been through a basic validation. The Netbox API client is initialized
-	// and the provided baseURL and token seem fine. Now we can start with the actual data gathering.
+	sd.setLogLevel(level)
+
+	if *stdout {
+		if *stdoutFormat == "" {
+			*stdoutFormat = sd.cfg.Format
+		}
+
+		if *stdoutFormat != config.FormatYAML && *stdoutFormat != config.FormatJSON {
+			slog.Error(config.ErrorBadFormat.Error(), "format", *stdoutFormat)
+			os.Exit(1)
+		}
+
+		data, err = sd.dumpTargets(context.Background(), sd.cfg, *stdoutFormat)
+		if err != nil {
+			slog.Error("failed to dump targets", "error", err)
+			os.Exit(1)
+		}
+
+		os.Stdout.Write(data)
+		os.Exit(0)
+	}
+
+	if sd.cfg.Consul != nil {
+		sd.consul = newConsulClient(sd.cfg.Consul)
+	}
+
+	if sd.cfg.Etcd != nil {
+		sd.etcd = newEtcdClient(sd.cfg.Etcd)
+	}
+
+	if sd.cfg.ConfigMap != nil {
+		sd.configMap, err = newConfigMapClient(sd.cfg.ConfigMap)
+		if err != nil {
+			slog.Error("failed to create Kubernetes client", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if sd.cfg.TargetDBFile != "" && !*dryRun {
+		sd.targetDB, err = openTargetDB(sd.cfg.TargetDBFile)
+		if err != nil {
+			slog.Error("failed to open target database", "file", sd.cfg.TargetDBFile, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if *grpcListen != "" {
+		sd.stream = newTargetStream()
+
+		err = serveStream(*grpcListen, sd.stream)
+		if err != nil {
+			slog.Error("failed to start grpc server", "address", *grpcListen, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if !*dryRun {
+		err = cleanupFiles(sd.cfg, *cleanup)
+		if err != nil {
+			slog.Error("failed to update state file", "error", err)
+		}
+	}
+
+	// At this point the config has been read and been through a basic validation. The Netbox API clients are initialized
+	// and the provided baseURLs and tokens seem fine. Now we can start with the actual data gathering.
 
 	promGroups.Set(float64(len(sd.cfg.Groups)))
 
+	if *dryRun {
+		slog.Info("running in dry-run mode, target files are not written")
+		promDryRun.Set(1)
+	}
+	reportConfigDiff(config.NewDiff(nil, sd.cfg))
+	promConfigReloadSuccess.Set(1)
+	promConfigReloadTime.Set(float64(time.Now().Unix()))
+
 	// Start an independent worker thread per group. This makes tracking the individual scanInterval much easier and who
-	// doesn't like goroutines?
-	for i = range sd.cfg.Groups {
-		log.Printf("starting worker for group %s", sd.cfg.Groups[i].File)
-		go sd.worker(sd.cfg.Groups[i])
+	// doesn't like goroutines? Workers are started by priority and delayed by the configured stagger.
+	sd.mu.Lock()
+	for i, group = range sd.cfg.GroupsByPriority() {
+		api, err = groupClient(sd.cfg, group)
+		if err != nil {
+			slog.Error("failed to start worker", "group", group.File, "error", err)
+			os.Exit(1)
+		}
+
+		sd.startWorker(sd.cfg, group, api, time.Duration(i)*sd.cfg.StartupStagger)
 	}
+	sd.mu.Unlock()
+
+	// wait until the end of times, reloading the config on SIGHUP
+	signal.Notify(reload, syscall.SIGHUP)
 
-	// wait until the end of times
-	select {}
+	for range reload {
+		slog.Info("reloading config", "file", *cfgFile)
+
+		err = sd.reload(*cfgFile)
+		if err != nil {
+			slog.Error("failed to reload config, keeping current config", "file", *cfgFile, "error", err)
+		}
+	}
+}
+
+// reportConfigDiff logs all changes of a config (re)load and exposes the number of changes as metrics.
+func reportConfigDiff(diff *config.Diff) {
+	var line string
+
+	for _, line = range strings.Split(diff.String(), "\n") {
+		slog.Info("config loaded", "change", line)
+	}
+
+	promConfigChanges.With(prometheus.Labels{"change": "global"}).Set(float64(len(diff.Global)))
+	promConfigChanges.With(prometheus.Labels{"change": "added"}).Set(float64(len(diff.GroupsAdded)))
+	promConfigChanges.With(prometheus.Labels{"change": "removed"}).Set(float64(len(diff.GroupsRemoved)))
+	promConfigChanges.With(prometheus.Labels{"change": "modified"}).Set(float64(len(diff.GroupsModified)))
 }
 
 // Worker performs all necessary steps to fetch targets based on the group's configuration markers and writes those
-// targets into a file that can be picked up by Prometheus' file_sd.
-func (sd *netboxSD) worker(group *config.Group) {
+// targets into a file that can be picked up by Prometheus' file_sd. Global options are taken from cfg. The first scan is
+// delayed by startDelay. The worker returns once ctx is done, cancelling a scan in progress.
+func (sd *netboxSD) worker(ctx context.Context, cfg *config.Config, group *config.Group, startDelay time.Duration) {
 	var (
-		// init last run with a time that is sure to trigger a scan on first iteration
-		lastRun  time.Time = time.Now().Add(-group.ScanInterval)
+		// init last run with a time that is sure to trigger a scan on first iteration (after startDelay)
+		lastRun  time.Time = time.Now().Add(startDelay - group.ScanInterval)
 		runStart time.Time
 		failed   bool
 		err      error
-		targets  []*targetgroup.Group
+		results  []*discovery.Target
+		files    map[string][]byte
+		file     string
 		data     []byte
+		written  bool
+		// previous contains the files written by the last scan
+		previous map[string]bool
+		webhook  *webhookClient
+		changes  *targetChanges
+		// known and logged contain the targets last reported to the webhook and the changelog; nil until the first
+		// successful scan
+		known   map[string]model.LabelSet
+		logged  map[string]model.LabelSet
+		current map[string]model.LabelSet
+		// lastChange is the ID of Netbox's most recent change log entry at the last successful scan; 0 when unknown
+		lastChange uint64
+		changeID   uint64
+		changed    bool
 	)
 
+	if cfg.Webhook != nil {
+		webhook = newWebhookClient(cfg.Webhook)
+	}
+
 	for {
 		if time.Since(lastRun) >= group.ScanInterval {
-			if *debug {
-				log.Printf("new scan for group %s\n", group.File)
-			}
+			slog.DebugContext(ctx, "new scan", "group", group.File)
 
 			// reset vars
 			runStart = time.Now()
 			failed = false
 
-			switch group.Type {
-			case config.GroupTypeService:
-				targets, err = sd.getTargetsByService(group)
-				if err != nil {
-					log.Printf("getting targets for group %s failed: %s", group.File, err.Error())
-					failed = true
-				}
+			changeID, changed = sd.detectChange(ctx, cfg, group, lastChange)
+			if !changed {
+				slog.DebugContext(ctx, "nothing changed in netbox since the last scan, skipping scan", "group", group.File)
 
-			case config.GroupTypeDeviceTag:
-				targets, err = sd.getTargetsByDeviceTag(group)
-				if err != nil {
-					log.Printf("getting targets for group %s failed: %s", group.File, err.Error())
-					failed = true
-				}
+				promScanSkipped.With(prometheus.Labels{"group": group.File}).Inc()
+				lastRun = time.Now()
 
-			case config.GroupTypeInterfaceTag:
-				targets, err = sd.getTargetsByInterfaceTag(group)
-				if err != nil {
-					log.Printf("getting targets for group %s failed: %s", group.File, err.Error())
-					failed = true
-				}
+				// the targets are still current
+				promUpdateTime.
+					With(prometheus.Labels{
+						"group": group.File,
+					}).Set(float64(time.Now().Unix()))
+
+				continue
+			}
+
+			results, err = sd.discover(ctx, group)
+			if err != nil && ctx.Err() != nil {
+				// the worker was stopped while scanning, this isn't a failure of the scan
+				return
+			}
+
+			if err != nil {
+				slog.ErrorContext(ctx, "getting targets failed", "group", group.File, "error", err)
+				failed = true
+			}
+
+			if !failed && exceedsMaxTargets(group, results) {
+				slog.ErrorContext(ctx, "targets exceed max_targets, keeping previous file", "group", group.File,
+					"targets", countActive(results), "max_targets", group.MaxTargets)
+				promMaxTargetsExceeded.With(prometheus.Labels{"group": group.File}).Inc()
+				failed = true
 			}
 
 			if !failed {
-				// NOTE: Unfortunately only YAML is a valid option here since there is no proper way to marshal JSON. See this
-				// issue: https://github.com/prometheus/prometheus/pull/6691.
-				data, err = yaml.Marshal(targets)
+				setTargetStateMetrics(group.File, results, cfg.TargetStateLabels)
+
+				if sd.history != nil {
+					sd.history.record(group.File, results, time.Now())
+				}
+
+				files, err = renderGroup(group, results)
+				if err == nil {
+					previous, err = addVanishedFiles(group, files, previous)
+				}
+
 				if err != nil {
 					// This should never happen unless there is as bug in Prometheus. This panicing here so this get's picked up.
-					log.Panicf("parsing targets to yaml failed: %v", err)
+					slog.ErrorContext(ctx, "parsing targets to yaml failed", "group", group.File, "error", err)
+					panic(err)
 				}
 
-				err = os.WriteFile(group.File, data, 0664)
-				if err != nil {
-					log.Printf("failed to write file %s: %v", group.File, err)
-					failed = true
-				} else {
+				for file, data = range files {
+					if *dryRun {
+						slog.InfoContext(ctx, "dry-run: not writing targets to file", "group", group.File, "file", file,
+							"targets", countActive(results), "bytes", len(data))
+						continue
+					}
+
+					written, err = writeTargetFile(group, file, data)
+					if err != nil {
+						slog.ErrorContext(ctx, "failed to write file", "group", group.File, "file", file, "error", err)
+						failed = true
+					} else if !written {
+						slog.DebugContext(ctx, "targets are unchanged, not writing file", "group", group.File, "file", file)
+
+						promFileUnchanged.With(prometheus.Labels{"group": group.File}).Inc()
+					}
+				}
+
+				if !failed {
+					err = sd.writeCombined(cfg, group, results)
+					if err != nil {
+						slog.ErrorContext(ctx, "failed to write combined file", "group", group.File, "file", cfg.CombinedFile,
+							"error", err)
+						failed = true
+					}
+				}
+
+				if !failed {
+					err = sd.writeZone(cfg, group, results)
+					if err != nil {
+						slog.ErrorContext(ctx, "failed to write dns zone file", "group", group.File, "file", cfg.DNSZone.File,
+							"error", err)
+						failed = true
+					}
+				}
+
+				if !failed {
+					err = sd.writeIcinga(cfg, group, results)
+					if err != nil {
+						slog.ErrorContext(ctx, "failed to write icinga file", "group", group.File, "file", cfg.Icinga.File,
+							"error", err)
+						failed = true
+					}
+				}
+
+				if !failed && cfg.ManifestFile != "" && !*dryRun {
+					err = sd.manifest.update(cfg.ManifestFile, group.File, groupFiles(cfg, files))
+					if err != nil {
+						slog.ErrorContext(ctx, "failed to write manifest file", "group", group.File, "file", cfg.ManifestFile,
+							"error", err)
+						failed = true
+					}
+				}
+
+				if !failed && group.ConsulService != "" && !*dryRun {
+					err = sd.consul.sync(group.ConsulService, results)
+					if err != nil {
+						slog.ErrorContext(ctx, "failed to sync consul service", "group", group.File, "service", group.ConsulService,
+							"error", err)
+						failed = true
+					}
+				}
+
+				if !failed && sd.etcd != nil && !*dryRun {
+					err = sd.etcd.put(group.File, results)
+					if err != nil {
+						slog.ErrorContext(ctx, "failed to write targets to etcd", "group", group.File, "error", err)
+						failed = true
+					}
+				}
+
+				if !failed && sd.configMap != nil && !*dryRun {
+					err = sd.configMap.put(group.File, results)
+					if err != nil {
+						slog.ErrorContext(ctx, "failed to write targets to configmap", "group", group.File, "error", err)
+						failed = true
+					}
+				}
+
+				if !failed && sd.stream != nil && !*dryRun {
+					sd.stream.update(group.File, results)
+				}
+
+				if !failed && cfg.ChangelogFile != "" && !*dryRun {
+					current = activeTargets(results)
+					changes = diffTargets(group.File, logged, current, time.Now())
+
+					// The first scan is the baseline, thus it isn't logged. Changes that failed to be appended are logged
+					// with the next scan.
+					if logged == nil || changes == nil {
+						logged = current
+					} else if err = sd.changelog.append(cfg.ChangelogFile, changes); err != nil {
+						slog.ErrorContext(ctx, "failed to append to changelog file", "group", group.File, "file", cfg.ChangelogFile,
+							"error", err)
+						failed = true
+					} else {
+						logged = current
+					}
+				}
+
+				if !failed && sd.targetDB != nil && !*dryRun {
+					changes, err = sd.targetDB.store(group.File, results, time.Now())
+					if err != nil {
+						slog.ErrorContext(ctx, "failed to store targets in target database", "group", group.File,
+							"file", cfg.TargetDBFile, "error", err)
+						failed = true
+					} else if changes != nil {
+						slog.DebugContext(ctx, "stored target changes in target database", "group", group.File,
+							"added", len(changes.Added), "removed", len(changes.Removed))
+					}
+				}
+
+				if !failed && webhook != nil && !*dryRun {
+					current = activeTargets(results)
+					changes = diffTargets(group.File, known, current, time.Now())
+
+					// The first scan is the baseline, thus it isn't reported. Changes that failed to be sent are reported
+					// again with the next scan.
+					if known == nil || changes == nil {
+						known = current
+					} else if err = webhook.send(changes); err != nil {
+						slog.ErrorContext(ctx, "failed to send target changes to webhook", "group", group.File, "error", err)
+						promWebhookError.With(prometheus.Labels{"group": group.File}).Inc()
+					} else {
+						known = current
+					}
+				}
+
+				if !failed {
 					// Update target count; otherwise we report the old value as nothing has changed.
 					promTargetCount.
 						With(prometheus.Labels{
 							"group": group.File,
 						}).
-						Set(float64(len(targets)))
+						Set(float64(countActive(results)))
 				}
 			} else {
 				promUpdateError.
@@ -203,6 +583,13 @@ func (sd *netboxSD) worker(group *config.Group) {
 					Inc()
 			}
 
+			// A failed scan is repeated regardless of changes in Netbox.
+			if failed {
+				lastChange = 0
+			} else {
+				lastChange = changeID
+			}
+
 			// Update lastRun time to track next iteration.
 			lastRun = time.Now()
 
@@ -218,6 +605,99 @@ func (sd *netboxSD) worker(group *config.Group) {
 				}).Set(float64(time.Now().Unix()))
 		}
 
-		time.Sleep(WorkerSleepTimeMS * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(WorkerSleepTimeMS * time.Millisecond):
+		}
 	}
 }
+
+// detectChange returns the ID of Netbox's most recent change log entry and whether it differs from last, thus a scan of
+// group is needed. Without change_detection or when the change log can't be queried, 0 and true are returned.
+func (sd *netboxSD) detectChange(ctx context.Context, cfg *config.Config, group *config.Group,
+	last uint64) (uint64, bool) {
+	var (
+		id  uint64
+		err error
+	)
+
+	if !cfg.ChangeDetection {
+		return 0, true
+	}
+
+	id, err = sd.apiFor(group).GetLastChangeID(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.WarnContext(ctx, "failed to query netbox change log, scanning anyway", "group", group.File, "error", err)
+		}
+
+		return 0, true
+	}
+
+	return id, id == 0 || id != last
+}
+
+// discover returns all targets for group with the group's relabel configs and probe mode applied.
+func (sd *netboxSD) discover(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
+	var (
+		targets []*discovery.Target
+		err     error
+	)
+
+	targets, err = sd.discoverByType(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	relabelTargets(group, targets)
+	applyProbeMode(group, targets)
+
+	return targets, nil
+}
+
+// discoverByType returns all targets for group based on the group's type.
+func (sd *netboxSD) discoverByType(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
+	switch group.Type {
+	case config.GroupTypeService:
+		return sd.getTargetsByService(ctx, group)
+
+	case config.GroupTypeDeviceTag:
+		return sd.getTargetsByDeviceTag(ctx, group)
+
+	case config.GroupTypeInterfaceTag:
+		return sd.getTargetsByInterfaceTag(ctx, group)
+
+	case config.GroupTypeCluster:
+		return sd.getTargetsByCluster(ctx, group)
+
+	case config.GroupTypeClusterGroup:
+		return sd.getTargetsByClusterGroup(ctx, group)
+
+	case config.GroupTypeManufacturer:
+		return sd.getTargetsByManufacturer(ctx, group)
+
+	case config.GroupTypeSiteGroup:
+		return sd.getTargetsBySiteGroup(ctx, group)
+
+	case config.GroupTypeConfigContext:
+		return sd.getTargetsByConfigContext(ctx, group)
+
+	case config.GroupTypeRESTQuery:
+		return sd.getTargetsByRESTQuery(ctx, group)
+
+	case config.GroupTypeAll:
+		return sd.getTargetsByAll(ctx, group)
+
+	case config.GroupTypeWirelessLAN:
+		return sd.getTargetsByWirelessLAN(ctx, group)
+
+	case config.GroupTypeVLAN:
+		return sd.getTargetsByVLAN(ctx, group)
+
+	case config.GroupTypeVDCTag:
+		return sd.getTargetsByVDCTag(ctx, group)
+	}
+
+	return nil, fmt.Errorf("unsupported group type %s", group.Type)
+}
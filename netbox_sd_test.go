@@ -0,0 +1,220 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+	"github.com/4xoc/netbox_sd/pkg/netbox/netboxtest"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dryRunTestConfig string = `base_url: https://netbox.domain.tld
+api_token: 123
+scan_interval: 5m
+combined_file: %[1]s/combined.yml
+changelog_file: %[1]s/changelog.jsonl
+manifest_file: %[1]s/manifest.json
+dns_zone:
+  file: %[1]s/zone.db
+icinga:
+  file: %[1]s/hosts.conf
+consul:
+  address: %[2]s
+  token: secret
+  datacenter: dc1
+  node: netbox_sd
+etcd:
+  endpoints: [%[3]s]
+  username: netbox_sd
+  password: secret
+webhook:
+  url: %[4]s
+  retries: 0
+
+groups:
+  - file: %[1]s/dry_run.yml
+    type: device_tag
+    match: junos_exporter
+    port: 9100
+`
+
+func TestDetectChange(t *testing.T) {
+	var (
+		sd      netboxSD
+		server  *netboxtest.Server
+		cfg     *config.Config = &config.Config{ChangeDetection: true}
+		group   *config.Group  = &config.Group{File: "a.yml"}
+		id      uint64
+		last    uint64
+		changed bool
+		err     error
+	)
+
+	server = netboxtest.NewServer(&netboxtest.Fixtures{})
+	defer server.Close()
+
+	sd.api, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netbox_sd_test"))
+	require.Nil(t, err)
+
+	// nothing known yet
+	last, changed = sd.detectChange(context.Background(), cfg, group, 0)
+	assert.True(t, changed)
+	assert.NotZero(t, last)
+
+	id, changed = sd.detectChange(context.Background(), cfg, group, last)
+	assert.False(t, changed)
+	assert.Equal(t, last, id)
+
+	server.SetFixtures(&netboxtest.Fixtures{})
+	id, changed = sd.detectChange(context.Background(), cfg, group, last)
+	assert.True(t, changed)
+	assert.NotEqual(t, last, id)
+
+	// disabled
+	cfg.ChangeDetection = false
+	id, changed = sd.detectChange(context.Background(), cfg, group, id)
+	assert.True(t, changed)
+	assert.Zero(t, id)
+
+	// scanning anyway when the change log can't be queried
+	cfg.ChangeDetection = true
+	sd.api, err = netbox.New(server.URL, "other", netbox.WithPrometheusNamespace("netbox_sd_test"))
+	require.Nil(t, err)
+
+	id, changed = sd.detectChange(context.Background(), cfg, group, last)
+	assert.True(t, changed)
+	assert.Zero(t, id)
+}
+
+func TestUserAgent(t *testing.T) {
+	defer func(previous string) { version = previous }(version)
+
+	version = ""
+	assert.Equal(t, "netbox_sd/dev", userAgent(""))
+
+	version = "1.2.3"
+	assert.Equal(t, "netbox_sd/1.2.3", userAgent(""))
+	assert.Equal(t, "netbox_sd/1.2.3 team-monitoring", userAgent("team-monitoring"))
+}
+
+func TestDryRun(t *testing.T) {
+	var (
+		sd       netboxSD
+		fixtures *netboxtest.Fixtures
+		server   *netboxtest.Server
+		consul   *fakeConsul = &fakeConsul{services: map[string]*consulService{}}
+		etcd     *fakeEtcd   = &fakeEtcd{keys: make(map[string][]byte)}
+		webhook  atomic.Int32
+		servers  []*httptest.Server
+		dir      string = t.TempDir()
+		file     string = filepath.Join(t.TempDir(), "config.yml")
+		group    *config.Group
+		ctx      context.Context
+		cancel   context.CancelFunc
+		done     chan struct{} = make(chan struct{})
+		entries  []os.DirEntry
+		err      error
+	)
+
+	*dryRun = true
+	defer func() { *dryRun = false }()
+
+	fixtures, err = netboxtest.LoadFixtures("testdata/fixtures/example/netbox.yml")
+	require.Nil(t, err)
+
+	server = netboxtest.NewServer(fixtures)
+	defer server.Close()
+
+	servers = []*httptest.Server{
+		httptest.NewServer(consul),
+		httptest.NewServer(etcd),
+		httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { webhook.Add(1) })),
+	}
+
+	for _, s := range servers {
+		defer s.Close()
+	}
+
+	require.Nil(t, os.WriteFile(file, []byte(fmt.Sprintf(dryRunTestConfig, dir, servers[0].URL, servers[1].URL,
+		servers[2].URL)), 0600))
+
+	sd.cfg, err = config.ReadConfigFile(file)
+	require.Nil(t, err)
+
+	sd.api, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netbox_sd_test"))
+	require.Nil(t, err)
+
+	sd.consul = newConsulClient(sd.cfg.Consul)
+	sd.etcd = newEtcdClient(sd.cfg.Etcd)
+	sd.stream = newTargetStream()
+
+	group = sd.cfg.Groups[0]
+	group.ScanInterval = time.Millisecond
+
+	ctx, cancel = context.WithCancel(context.Background())
+
+	go func() {
+		sd.worker(ctx, sd.cfg, group, 0)
+		close(done)
+	}()
+
+	// the first scan finds targets, the second one removes them which would be reported as changes
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(promTargetCount.WithLabelValues(group.File)) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	server.SetFixtures(&netboxtest.Fixtures{})
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(promTargetCount.WithLabelValues(group.File)) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+
+	// no files are written
+	entries, err = os.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Empty(t, entries)
+
+	// no sinks are called
+	consul.mu.Lock()
+	assert.Zero(t, consul.registrations)
+	consul.mu.Unlock()
+
+	etcd.mu.Lock()
+	assert.Zero(t, etcd.puts)
+	etcd.mu.Unlock()
+
+	assert.Zero(t, webhook.Load())
+	assert.Empty(t, sd.stream.groups)
+}
@@ -0,0 +1,201 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package discovery contains the typed result model of netbox_sd's discovery. Every Netbox object processed for a group
+// results in a Target, including those that have been skipped, which allows library users and tests to assert on the
+// outcome of a discovery run before it is converted into Prometheus' file_sd format.
+package discovery
+
+import (
+	"fmt"
+
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+)
+
+// State describes the state of a Target. Values are exposed as netbox_sd_target_state metric; negative values are
+// reasons why a target has been skipped.
+type State float64
+
+const (
+	StateGraveyard                 State = 2
+	StateActive                    State = 1
+	StateSkippedOther              State = 0
+	StateSkippedBadStatus          State = -1
+	StateSkippedBadCustomField     State = -2
+	StateSkippedNoValidIP          State = -3
+	StateSkippedNotMatchingFilters State = -4
+	StateSkippedNoMatchingAddress  State = -5
+	StateSkippedDroppedByRelabel   State = -6
+)
+
+// String returns a short description of state.
+func (state State) String() string {
+	switch state {
+	case StateActive:
+		return "active"
+	case StateGraveyard:
+		return "graveyard"
+	case StateSkippedBadStatus:
+		return "bad status"
+	case StateSkippedBadCustomField:
+		return "bad custom field"
+	case StateSkippedNoValidIP:
+		return "no valid ip"
+	case StateSkippedNotMatchingFilters:
+		return "not matching filters"
+	case StateSkippedNoMatchingAddress:
+		return "no matching address"
+	case StateSkippedDroppedByRelabel:
+		return "dropped by relabeling"
+	}
+
+	return "other"
+}
+
+// Target describes the result of processing a single Netbox object (device, VM, interface or service) for a
+// group. Every object that matched a group is returned as Target, including those that have been skipped.
+// Conversion into targetgroup.Group is only done as last step before writing the file.
+type Target struct {
+	// Device is the device or VM the target belongs to.
+	Device *netbox.Device
+	// Addresses contains all addresses selected for this target.
+	Addresses []*netbox.IP
+	// AddressesFiltered is the number of selected addresses that have been removed by the group's address filters.
+	AddressesFiltered int
+	// Ports is a list of ports appended to each address. When empty, addresses are used without any port.
+	Ports []int
+	// Labels contains all labels generated for this target.
+	Labels model.LabelSet
+	// SkipReason is StateActive unless the target has been skipped, in which case it contains the reason why.
+	SkipReason State
+	// Source identifies the Netbox object the target has been generated from. Defaults to "netbox_sd" when empty.
+	Source string
+	// ServiceID is the id of the Netbox service the target has been generated from; 0 for all other targets.
+	ServiceID uint64
+	// Graveyard is true when the target's device is in one of the group's graveyard statuses. Such targets are written
+	// to the graveyard file instead of the group's file.
+	Graveyard bool
+	// Exporter is the address of an exporter probing the target's addresses on behalf of Prometheus (e.g.
+	// blackbox_exporter). When set, every address is converted into a separate targetgroup.Group with the address as
+	// __param_target label and Exporter as its only target.
+	Exporter string
+}
+
+// Skipped returns true when the target has been skipped and must not be part of the resulting target group.
+func (t *Target) Skipped() bool {
+	return t.SkipReason != StateActive
+}
+
+// TargetGroup converts the target into a targetgroup.Group usable by Prometheus' file_sd.
+func (t *Target) TargetGroup() *targetgroup.Group {
+	var source string = t.Source
+
+	if source == "" {
+		source = "netbox_sd"
+	}
+
+	return &targetgroup.Group{
+		Targets: convertToTargets(t.Addresses, t.Ports),
+		Labels:  t.Labels,
+		Source:  source,
+	}
+}
+
+// ProbeGroups converts the target into one targetgroup.Group per address for an exporter probing the addresses. Each
+// group has Exporter as only target and the address as __param_target label.
+func (t *Target) ProbeGroups() []*targetgroup.Group {
+	var (
+		group   *targetgroup.Group = t.TargetGroup()
+		result  []*targetgroup.Group
+		address model.LabelSet
+		labels  model.LabelSet
+	)
+
+	for _, address = range group.Targets {
+		labels = t.Labels.Clone()
+		labels[model.ParamLabelPrefix+"target"] = address[model.AddressLabel]
+
+		result = append(result, &targetgroup.Group{
+			Targets: []model.LabelSet{{model.AddressLabel: model.LabelValue(t.Exporter)}},
+			Labels:  labels,
+			Source:  group.Source,
+		})
+	}
+
+	return result
+}
+
+// TargetGroups converts all non-skipped targets into a list of targetgroup.Group.
+func TargetGroups(targets []*Target) []*targetgroup.Group {
+	var (
+		data   []*targetgroup.Group = make([]*targetgroup.Group, 0, len(targets))
+		target *Target
+	)
+
+	for _, target = range targets {
+		if target.Skipped() {
+			continue
+		}
+
+		if target.Exporter != "" {
+			data = append(data, target.ProbeGroups()...)
+			continue
+		}
+
+		data = append(data, target.TargetGroup())
+	}
+
+	return data
+}
+
+// convertToTargets takes a list of IPs and optional ports and normalizes it into a slice of LabelSets. Each address is
+// combined with each port.
+func convertToTargets(ips []*netbox.IP, ports []int) []model.LabelSet {
+	var (
+		// Init targets with appropriate capacity.
+		targets = make([]model.LabelSet, 0, len(ips)*max(len(ports), 1))
+		i, j    int
+	)
+
+	for i = range ips {
+		// Ports are optional, thus only appending them when defined.
+		if len(ports) == 0 {
+			targets = append(targets, model.LabelSet{
+				model.AddressLabel: model.LabelValue(ips[i].ToAddr()),
+			})
+			continue
+		}
+
+		for j = range ports {
+			if ips[i].Family() == 4 {
+				targets = append(targets, model.LabelSet{
+					model.AddressLabel: model.LabelValue(fmt.Sprintf("%s:%d", ips[i].ToAddr(), ports[j])),
+				})
+			} else {
+				// IPv6 requires wrapping in brackets.
+				targets = append(targets, model.LabelSet{
+					model.AddressLabel: model.LabelValue(fmt.Sprintf("[%s]:%d", ips[i].ToAddr(), ports[j])),
+				})
+			}
+		}
+	}
+
+	return targets
+}
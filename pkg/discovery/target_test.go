@@ -0,0 +1,118 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package discovery
+
+import (
+	"testing"
+
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetGroups(t *testing.T) {
+	var (
+		input = []*Target{
+			{
+				Device: &netbox.Device{Name: "device-A"},
+				Addresses: []*netbox.IP{
+					{Address: "2001:db8::1/64"},
+					{Address: "10.0.0.1/24"},
+				},
+				Ports:      []int{9100, 9101},
+				Labels:     model.LabelSet{"netbox_name": "device-A"},
+				SkipReason: StateActive,
+			},
+			{
+				Device: &netbox.Device{Name: "device-B"},
+				Addresses: []*netbox.IP{
+					{Address: "2001:db8::2/64"},
+				},
+				Labels:     model.LabelSet{"netbox_name": "device-B"},
+				SkipReason: StateActive,
+			},
+			{
+				// skipped targets must not be part of the result
+				Device:     &netbox.Device{Name: "device-C"},
+				SkipReason: StateSkippedBadStatus,
+			},
+			{
+				// unspecified state is considered skipped
+				Device: &netbox.Device{Name: "device-D"},
+			},
+		}
+		expected = []*targetgroup.Group{
+			{
+				Targets: []model.LabelSet{
+					{model.AddressLabel: "[2001:db8::1]:9100"},
+					{model.AddressLabel: "[2001:db8::1]:9101"},
+					{model.AddressLabel: "10.0.0.1:9100"},
+					{model.AddressLabel: "10.0.0.1:9101"},
+				},
+				Labels: model.LabelSet{"netbox_name": "device-A"},
+				Source: "netbox_sd",
+			},
+			{
+				Targets: []model.LabelSet{
+					{model.AddressLabel: "2001:db8::2"},
+				},
+				Labels: model.LabelSet{"netbox_name": "device-B"},
+				Source: "netbox_sd",
+			},
+		}
+	)
+
+	assert.Equal(t, expected, TargetGroups(input))
+	assert.False(t, input[0].Skipped())
+	assert.True(t, input[2].Skipped())
+	assert.True(t, input[3].Skipped())
+}
+
+func TestProbeGroups(t *testing.T) {
+	var (
+		input = []*Target{
+			{
+				Addresses: []*netbox.IP{
+					{Address: "2001:db8::1/64"},
+					{Address: "10.0.0.1/24"},
+				},
+				Labels:     model.LabelSet{"netbox_name": "device-A"},
+				SkipReason: StateActive,
+				Exporter:   "blackbox:9115",
+			},
+		}
+		expected = []*targetgroup.Group{
+			{
+				Targets: []model.LabelSet{{model.AddressLabel: "blackbox:9115"}},
+				Labels:  model.LabelSet{"netbox_name": "device-A", "__param_target": "2001:db8::1"},
+				Source:  "netbox_sd",
+			},
+			{
+				Targets: []model.LabelSet{{model.AddressLabel: "blackbox:9115"}},
+				Labels:  model.LabelSet{"netbox_name": "device-A", "__param_target": "10.0.0.1"},
+				Source:  "netbox_sd",
+			},
+		}
+	)
+
+	assert.Equal(t, expected, TargetGroups(input))
+	// the labels of the target are not modified
+	assert.Equal(t, model.LabelSet{"netbox_name": "device-A"}, input[0].Labels)
+}
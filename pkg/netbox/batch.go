@@ -0,0 +1,176 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains batching of several list queries into a single GraphQL document.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DeviceQuery selects a list of devices or VMs fetched as part of a batch. See GetDevicesBatch.
+type DeviceQuery struct {
+	// Virtual selects VMs instead of devices.
+	Virtual bool
+	// Tag limits the list to objects having the tag. All objects are selected when empty.
+	Tag string
+	// Filter limits the list further and may be nil.
+	Filter *DeviceFilter
+}
+
+// graphQLBatchWrapper is a structure for extracting data from the response to a batch document. Each sub-query's list
+// is found by its alias.
+type graphQLBatchWrapper struct {
+	Data map[string][]*Device `json:"data"`
+}
+
+// filters returns the value of the $filters variable of query or nil when nothing is filtered.
+func (query *DeviceQuery) filters() map[string]any {
+	var filters map[string]any = make(map[string]any)
+
+	if query.Tag != "" {
+		filters["tag"] = query.Tag
+	}
+
+	filters = query.Filter.apply(filters)
+
+	if len(filters) == 0 {
+		return nil
+	}
+
+	return filters
+}
+
+// batchAlias returns the alias of the sub-query at index i of a batch.
+func batchAlias(i int) string {
+	return "q" + strconv.Itoa(i)
+}
+
+// batchDocument returns a GraphQL document combining the sub-queries of queries selected by pending. Each sub-query is
+// aliased by batchAlias and uses its own $filters<i> and $pagination<i> variables. Only indexes are written into the
+// document, thus it only depends on the kind and number of sub-queries, never on their values.
+func batchDocument(queries []DeviceQuery, pending []int) string {
+	var (
+		definitions []string
+		fields      []string
+		field       string
+		filterType  string
+		attributes  string
+		i           int
+	)
+
+	for _, i = range pending {
+		if queries[i].Virtual {
+			field, filterType, attributes = "virtual_machine_list", "VirtualMachineFilter", queryVMAttributes
+		} else {
+			field, filterType, attributes = "device_list", "DeviceFilter", queryDeviceAttributes
+		}
+
+		definitions = append(definitions,
+			fmt.Sprintf("$filters%d: %s, $pagination%d: OffsetPaginationInput", i, filterType, i))
+		fields = append(fields,
+			fmt.Sprintf("%s: %s(filters: $filters%d, pagination: $pagination%d){%s}", batchAlias(i), field, i, i, attributes))
+	}
+
+	return "query(" + strings.Join(definitions, ", ") + "){" + strings.Join(fields, " ") + "}"
+}
+
+// GetDevicesBatch returns the lists of devices and VMs selected by queries, fetched with a single GraphQL request. The
+// list at index i of the result belongs to queries[i]. With a page size set, each request fetches the next page of all
+// lists that haven't been completed yet.
+func (client *Client) GetDevicesBatch(ctx context.Context, queries []DeviceQuery) ([][]*Device, error) {
+	var (
+		size      int         = client.listPageSize()
+		results   [][]*Device = make([][]*Device, len(queries))
+		pending   []int       = make([]int, 0, len(queries))
+		next      []int
+		offset    int
+		filters   map[string]any
+		variables map[string]any
+		resp      response
+		wrapper   graphQLBatchWrapper
+		list      []*Device
+		i, j      int
+		err       error
+	)
+
+	ctx = withQueryType(ctx, "devices_batch")
+
+	for i = range queries {
+		pending = append(pending, i)
+	}
+
+	for len(pending) > 0 {
+		variables = make(map[string]any)
+
+		for _, i = range pending {
+			if filters = queries[i].filters(); filters != nil {
+				variables["filters"+strconv.Itoa(i)] = filters
+			}
+
+			if size > 0 {
+				variables["pagination"+strconv.Itoa(i)] = map[string]any{"offset": offset, "limit": size}
+			}
+		}
+
+		resp, err = client.graphQL(ctx, client.document(batchDocument(queries, pending)), variables)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query api: %w", err)
+		}
+
+		if resp.StatusCode() != 200 {
+			return nil, ErrUnexpectedStatusCode
+		}
+
+		wrapper = graphQLBatchWrapper{}
+
+		err = json.Unmarshal(resp.RawBody().Bytes(), &wrapper)
+		if err != nil {
+			client.promFailure.Inc()
+			return nil, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
+		}
+
+		next = nil
+
+		for _, i = range pending {
+			list = wrapper.Data[batchAlias(i)]
+
+			for j = range list {
+				list[j].isVirtual = queries[i].Virtual
+
+				// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
+				list[j].parseIDs()
+			}
+
+			results[i] = append(results[i], list...)
+
+			if size > 0 && len(list) >= size {
+				next = append(next, i)
+			}
+		}
+
+		pending = next
+		offset += size
+	}
+
+	return results, nil
+}
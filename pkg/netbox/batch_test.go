@@ -0,0 +1,146 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchDocument(t *testing.T) {
+	var queries []DeviceQuery = []DeviceQuery{{Tag: "a"}, {Virtual: true, Tag: "a"}}
+
+	assert.Equal(t, "query($filters0: DeviceFilter, $pagination0: OffsetPaginationInput, "+
+		"$filters1: VirtualMachineFilter, $pagination1: OffsetPaginationInput){"+
+		"q0: device_list(filters: $filters0, pagination: $pagination0){"+queryDeviceAttributes+"} "+
+		"q1: virtual_machine_list(filters: $filters1, pagination: $pagination1){"+queryVMAttributes+"}}",
+		batchDocument(queries, []int{0, 1}))
+
+	// the document doesn't depend on the values
+	assert.Equal(t, batchDocument(queries, []int{1}), batchDocument([]DeviceQuery{{}, {Virtual: true, Tag: "b"}}, []int{1}))
+}
+
+func TestGetDevicesBatch(t *testing.T) {
+	var (
+		server   *httptest.Server
+		mu       sync.Mutex
+		requests []graphQLRequest
+		client   *Client
+		lists    [][]*Device
+		err      error
+	)
+
+	// serves 3 devices and 1 VM for each alias, paginated when requested
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			request graphQLRequest
+			data    map[string]any = make(map[string]any)
+		)
+
+		json.NewDecoder(r.Body).Decode(&request)
+
+		mu.Lock()
+		requests = append(requests, request)
+		mu.Unlock()
+
+		for _, alias := range []string{"q0", "q1", "q2"} {
+			var (
+				list   []map[string]any = []map[string]any{}
+				total  int              = 3
+				offset int              = 0
+				limit  int              = 3
+			)
+
+			if !strings.Contains(request.Query, alias+": ") {
+				continue
+			}
+
+			if strings.Contains(request.Query, alias+": virtual_machine_list") {
+				total = 1
+			}
+
+			if pagination, ok := request.Variables["pagination"+alias[1:]].(map[string]any); ok {
+				offset = int(pagination["offset"].(float64))
+				limit = int(pagination["limit"].(float64))
+			}
+
+			for i := offset; i < min(offset+limit, total); i++ {
+				list = append(list, map[string]any{"id": "1", "name": alias})
+			}
+
+			data[alias] = list
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+
+	lists, err = client.GetDevicesBatch(context.Background(), []DeviceQuery{
+		{Tag: "a"},
+		{Virtual: true, Tag: "a"},
+		{Tag: "b", Filter: &DeviceFilter{Sites: []string{"site"}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	require.Len(t, lists, 3)
+	assert.Len(t, lists[0], 3)
+	assert.Len(t, lists[1], 1)
+	assert.Len(t, lists[2], 3)
+	assert.False(t, lists[0][0].IsVirtual())
+	assert.True(t, lists[1][0].IsVirtual())
+	assert.Equal(t, uint64(1), lists[1][0].ID)
+	assert.Equal(t, "q2", lists[2][0].Name)
+	assert.Equal(t, map[string]any{
+		"filters0": map[string]any{"tag": "a"},
+		"filters1": map[string]any{"tag": "a"},
+		"filters2": map[string]any{"tag": "b", "site": []any{"site"}},
+	}, requests[0].Variables)
+
+	// only lists not completed yet are requested again
+	requests = nil
+	client.SetPageSize(2)
+
+	lists, err = client.GetDevicesBatch(context.Background(), []DeviceQuery{{}, {Virtual: true}})
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Len(t, lists[0], 3)
+	assert.Len(t, lists[1], 1)
+	assert.Contains(t, requests[0].Query, "q1: ")
+	assert.NotContains(t, requests[1].Query, "q1: ")
+	assert.Equal(t, map[string]any{"pagination0": map[string]any{"offset": float64(2), "limit": float64(2)}},
+		requests[1].Variables)
+
+	// nothing to query
+	requests = nil
+
+	lists, err = client.GetDevicesBatch(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, lists)
+	assert.Empty(t, requests)
+}
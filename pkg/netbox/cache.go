@@ -0,0 +1,118 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains caching of responses.
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// responseCache keeps successful responses for a limited time, identified by a key (e.g. their query).
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry is a single cached response, valid until expires.
+type cacheEntry struct {
+	resp    response
+	expires time.Time
+}
+
+// SetCacheTTL sets the time successful responses are cached for. Identical queries (including their variables) issued
+// within ttl are served from the cache instead of being sent to Netbox again, e.g. when several groups share the same
+// tag or service name. A ttl of 0 disables caching. Changing the ttl drops all cached responses.
+func (client *Client) SetCacheTTL(ttl time.Duration) {
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	client.cacheTTL.Store(int64(ttl))
+	client.dropCache()
+}
+
+// dropCache removes all cached responses.
+func (client *Client) dropCache() {
+	client.cache.mu.Lock()
+	client.cache.entries = nil
+	client.cache.mu.Unlock()
+}
+
+// cached returns a copy of the cached response for key if it hasn't expired yet. Otherwise fn is called for key (see
+// coalesce) and its response is cached when it has been successful. Without cache ttl, fn is always called.
+func (client *Client) cached(
+	ctx context.Context,
+	key string,
+	fn func(context.Context) (response, error),
+) (response, error) {
+	var (
+		ttl   time.Duration = time.Duration(client.cacheTTL.Load())
+		entry *cacheEntry
+		resp  response
+		now   time.Time
+		other string
+		ok    bool
+		err   error
+	)
+
+	if ttl == 0 {
+		return client.coalesce(ctx, key, fn)
+	}
+
+	client.cache.mu.Lock()
+	entry, ok = client.cache.entries[key]
+	if ok && time.Now().Before(entry.expires) {
+		client.cache.mu.Unlock()
+		client.promCacheHit.Inc()
+
+		return copyResponse(entry.resp), nil
+	}
+	client.cache.mu.Unlock()
+	client.promCacheMiss.Inc()
+
+	resp, err = client.coalesce(ctx, key, fn)
+	if err != nil || resp == nil || resp.StatusCode() != 200 {
+		return resp, err
+	}
+
+	now = time.Now()
+
+	client.cache.mu.Lock()
+	defer client.cache.mu.Unlock()
+
+	if client.cache.entries == nil {
+		client.cache.entries = make(map[string]*cacheEntry)
+	}
+
+	// drop expired entries so that queries no longer issued don't pile up
+	for other, entry = range client.cache.entries {
+		if !now.Before(entry.expires) {
+			delete(client.cache.entries, other)
+		}
+	}
+
+	client.cache.entries[key] = &cacheEntry{
+		resp:    copyResponse(resp),
+		expires: now.Add(ttl),
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,130 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCached(t *testing.T) {
+	var (
+		client *Client
+		calls  int
+		status int = 200
+		resp   response
+		metric dto.Metric
+		err    error
+	)
+
+	client, err = New("http://localhost", "token", WithPrometheusNamespace("test"))
+	require.Nil(t, err)
+
+	fn := func(context.Context) (response, error) {
+		var resp *graphQLResponse = &graphQLResponse{statusCode: status}
+
+		calls++
+		resp.body.WriteString("query")
+		return resp, nil
+	}
+
+	// caching disabled by default
+	_, err = client.cached(context.Background(), "query", fn)
+	require.Nil(t, err)
+	_, err = client.cached(context.Background(), "query", fn)
+	require.Nil(t, err)
+	assert.Equal(t, 2, calls)
+
+	client.SetCacheTTL(time.Minute)
+	calls = 0
+
+	for range 3 {
+		resp, err = client.cached(context.Background(), "query", fn)
+		require.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode())
+		assert.Equal(t, "query", resp.RawBody().String())
+	}
+	assert.Equal(t, 1, calls)
+
+	// every caller consumes a copy of the cached response
+	resp.RawBody().Reset()
+	resp, err = client.cached(context.Background(), "query", fn)
+	require.Nil(t, err)
+	assert.Equal(t, "query", resp.RawBody().String())
+
+	client.promCacheHit.Write(&metric)
+	assert.Equal(t, float64(3), metric.GetCounter().GetValue())
+	client.promCacheMiss.Write(&metric)
+	assert.Equal(t, float64(1), metric.GetCounter().GetValue())
+
+	// other keys are not served from the cache
+	_, err = client.cached(context.Background(), "other", fn)
+	require.Nil(t, err)
+	assert.Equal(t, 2, calls)
+
+	// unsuccessful responses are not cached
+	status = 500
+	_, err = client.cached(context.Background(), "failed", fn)
+	require.Nil(t, err)
+	_, err = client.cached(context.Background(), "failed", fn)
+	require.Nil(t, err)
+	assert.Equal(t, 4, calls)
+
+	// changing the ttl drops all cached responses
+	status = 200
+	client.SetCacheTTL(time.Minute)
+	_, err = client.cached(context.Background(), "query", fn)
+	require.Nil(t, err)
+	assert.Equal(t, 5, calls)
+}
+
+func TestCachedExpiry(t *testing.T) {
+	var (
+		client *Client
+		calls  int
+		err    error
+	)
+
+	client, err = New("http://localhost", "token", WithPrometheusNamespace("test"))
+	require.Nil(t, err)
+
+	fn := func(context.Context) (response, error) {
+		calls++
+		return &graphQLResponse{statusCode: 200}, nil
+	}
+
+	client.SetCacheTTL(10 * time.Millisecond)
+
+	_, err = client.cached(context.Background(), "query", fn)
+	require.Nil(t, err)
+	time.Sleep(20 * time.Millisecond)
+	_, err = client.cached(context.Background(), "query", fn)
+	require.Nil(t, err)
+	assert.Equal(t, 2, calls)
+
+	// expired entries of other keys are dropped when storing a response
+	time.Sleep(20 * time.Millisecond)
+	_, err = client.cached(context.Background(), "other", fn)
+	require.Nil(t, err)
+	assert.Len(t, client.cache.entries, 1)
+}
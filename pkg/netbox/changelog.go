@@ -0,0 +1,98 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains change detection based on Netbox's change log.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// restObjectChangesPath is the change log endpoint since Netbox 4.1, restLegacyObjectChangesPath the one before.
+	restObjectChangesPath       string = "/api/core/object-changes/"
+	restLegacyObjectChangesPath string = "/api/extras/object-changes/"
+)
+
+// restObjectChange is an entry of the change log as returned by the REST API.
+type restObjectChange struct {
+	ID uint64 `json:"id"`
+}
+
+// GetLastChangeID returns the ID of the most recent entry of Netbox's change log or 0 when the change log is empty.
+// Every object created, updated or deleted in Netbox adds an entry, thus an unchanged ID means nothing has changed in
+// between. Unlike filtering by last_updated, this also covers deleted objects. The change log is always queried via the
+// REST API and responses are never cached. When the ID differs from the one returned before, all cached responses are
+// dropped, thus lookups following a detected change never return results older than the change.
+func (client *Client) GetLastChangeID(ctx context.Context) (uint64, error) {
+	var (
+		resp    response
+		page    restPage
+		changes []restObjectChange
+		id      uint64
+		err     error
+	)
+
+	ctx = withQueryType(ctx, "last_change_id")
+
+	if !client.supports().coreObjectChanges {
+		resp, err = client.get(ctx, restLegacyObjectChangesPath+"?limit=1")
+	} else {
+		resp, err = client.get(ctx, restObjectChangesPath+"?limit=1")
+
+		// without a detected version, the legacy endpoint is tried when the current one doesn't exist
+		if err == nil && resp.StatusCode() == 404 && client.version.Load() == nil {
+			resp, err = client.get(ctx, restLegacyObjectChangesPath+"?limit=1")
+		}
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to query api: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return 0, ErrUnexpectedStatusCode
+	}
+
+	err = json.Unmarshal(resp.RawBody().Bytes(), &page)
+	if err != nil {
+		client.promFailure.Inc()
+		return 0, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
+	}
+
+	if len(page.Results) > 0 {
+		err = json.Unmarshal(page.Results, &changes)
+		if err != nil {
+			client.promFailure.Inc()
+			return 0, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
+		}
+	}
+
+	// the change log is ordered by time, newest first
+	if len(changes) > 0 {
+		id = changes[0].ID
+	}
+
+	if client.lastChange.Swap(id) != id {
+		client.dropCache()
+	}
+
+	return id, nil
+}
@@ -0,0 +1,148 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLastChangeID(t *testing.T) {
+	var (
+		server *httptest.Server
+		legacy bool
+		body   string = `{"count":2,"next":"x","results":[{"id":42,"action":{"value":"update"}}]}`
+		paths  []string
+		client *Client
+		id     uint64
+		err    error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+
+		if legacy && r.URL.Path == restObjectChangesPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		assert.Equal(t, "1", r.URL.Query().Get("limit"))
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+
+	id, err = client.GetLastChangeID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), id)
+	assert.Equal(t, []string{restObjectChangesPath}, paths)
+
+	// Netbox before 4.1
+	legacy = true
+	paths = nil
+	id, err = client.GetLastChangeID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), id)
+	assert.Equal(t, []string{restObjectChangesPath, restLegacyObjectChangesPath}, paths)
+
+	// empty change log
+	body = `{"count":0,"next":null,"results":[]}`
+	id, err = client.GetLastChangeID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), id)
+
+	// the change log is never cached
+	client.SetCacheTTL(time.Minute)
+	body = `{"count":1,"next":null,"results":[{"id":43}]}`
+	_, err = client.GetLastChangeID(context.Background())
+	require.NoError(t, err)
+	body = `{"count":1,"next":null,"results":[{"id":44}]}`
+	id, err = client.GetLastChangeID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(44), id)
+}
+
+func TestGetLastChangeIDDropsCache(t *testing.T) {
+	var (
+		client *Client
+		server *httptest.Server
+		last   string = "1"
+		calls  int
+		err    error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"count":1,"next":null,"results":[{"id":` + last + `}]}`))
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+	client.SetCacheTTL(time.Minute)
+
+	fn := func(context.Context) (response, error) {
+		calls++
+		return &graphQLResponse{statusCode: 200}, nil
+	}
+
+	_, err = client.GetLastChangeID(context.Background())
+	require.NoError(t, err)
+	_, err = client.cached(context.Background(), "query", fn)
+	require.NoError(t, err)
+
+	// unchanged
+	_, err = client.GetLastChangeID(context.Background())
+	require.NoError(t, err)
+	_, err = client.cached(context.Background(), "query", fn)
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	last = "2"
+	_, err = client.GetLastChangeID(context.Background())
+	require.NoError(t, err)
+	_, err = client.cached(context.Background(), "query", fn)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestGetLastChangeIDUnexpectedStatus(t *testing.T) {
+	var (
+		server *httptest.Server
+		client *Client
+		err    error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+
+	_, err = client.GetLastChangeID(context.Background())
+	assert.ErrorIs(t, err, ErrUnexpectedStatusCode)
+}
@@ -0,0 +1,112 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// inflightRequests keeps track of requests currently in flight, identified by a key (e.g. their query).
+type inflightRequests struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+// inflightCall is a single request in flight. Its result is available once done is closed.
+type inflightCall struct {
+	done chan struct{}
+	resp response
+	err  error
+}
+
+// coalesce calls fn for key unless a call for the same key is already in flight. In that case the caller waits for
+// the call in flight to complete and receives a copy of its response instead. This avoids sending the same query
+// multiple times to Netbox, e.g. when several groups start at the same time.
+//
+// A waiting caller stops waiting as soon as its own ctx is done. When the call in flight failed only because the
+// context of the caller issuing it was cancelled, the waiting caller calls fn itself.
+func (client *Client) coalesce(
+	ctx context.Context,
+	key string,
+	fn func(context.Context) (response, error),
+) (response, error) {
+	var (
+		call *inflightCall
+		ok   bool
+	)
+
+	client.inflight.mu.Lock()
+
+	if client.inflight.calls == nil {
+		client.inflight.calls = make(map[string]*inflightCall)
+	}
+
+	if call, ok = client.inflight.calls[key]; ok {
+		client.inflight.mu.Unlock()
+		client.promCoalesced.Inc()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if isContextError(call.err) && ctx.Err() == nil {
+			return client.coalesce(ctx, key, fn)
+		}
+
+		return copyResponse(call.resp), call.err
+	}
+
+	call = &inflightCall{
+		done: make(chan struct{}),
+	}
+	client.inflight.calls[key] = call
+	client.inflight.mu.Unlock()
+
+	call.resp, call.err = fn(ctx)
+
+	client.inflight.mu.Lock()
+	delete(client.inflight.calls, key)
+	client.inflight.mu.Unlock()
+
+	close(call.done)
+
+	return copyResponse(call.resp), call.err
+}
+
+// isContextError returns true when err was caused by a cancelled context or an exceeded deadline.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// copyResponse returns a copy of resp so that every caller can consume the body independently.
+func copyResponse(resp response) response {
+	var copied *graphQLResponse
+
+	if resp == nil {
+		return nil
+	}
+
+	copied = &graphQLResponse{statusCode: resp.StatusCode()}
+	copied.body.Write(resp.RawBody().Bytes())
+
+	return copied
+}
@@ -0,0 +1,171 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalesce(t *testing.T) {
+	var (
+		client  *Client
+		calls   atomic.Int32
+		started chan struct{} = make(chan struct{})
+		release chan struct{} = make(chan struct{})
+		wg      sync.WaitGroup
+		results [5]response
+		err     error
+	)
+
+	client, err = New("http://localhost", "token", WithPrometheusNamespace("test"))
+	require.Nil(t, err)
+
+	fn := func(context.Context) (response, error) {
+		var resp *graphQLResponse = &graphQLResponse{statusCode: 200}
+
+		calls.Add(1)
+		close(started)
+		<-release
+
+		resp.body.WriteString("query")
+		return resp, nil
+	}
+
+	// first call blocks until released
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0], _ = client.coalesce(context.Background(), "query", fn)
+	}()
+	<-started
+
+	for i := 1; i < len(results); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = client.coalesce(context.Background(), "query", fn)
+		}(i)
+	}
+
+	// wait for all callers to join the call in flight
+	assert.Eventually(t, func() bool {
+		var metric dto.Metric
+
+		client.promCoalesced.Write(&metric)
+		return metric.GetCounter().GetValue() == float64(len(results)-1)
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for i := range results {
+		require.NotNil(t, results[i])
+		assert.Equal(t, 200, results[i].StatusCode())
+		assert.Equal(t, "query", results[i].RawBody().String())
+	}
+
+	// responses must not share the same buffer
+	results[0].RawBody().Reset()
+	assert.Equal(t, "query", results[1].RawBody().String())
+
+	// once completed, the next call is performed again
+	_, err = client.coalesce(context.Background(), "query", func(context.Context) (response, error) {
+		calls.Add(1)
+		return nil, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestCoalesceCancel(t *testing.T) {
+	var (
+		client       *Client
+		calls        atomic.Int32
+		started      chan struct{} = make(chan struct{}, 2)
+		ctx, waitCtx context.Context
+		cancel       context.CancelFunc
+		waitCancel   context.CancelFunc
+		wg           sync.WaitGroup
+		resp         response
+		err, err2    error
+	)
+
+	client, err = New("http://localhost", "token", WithPrometheusNamespace("test"))
+	require.Nil(t, err)
+
+	// the first call blocks until its context is cancelled, any later call returns immediately
+	fn := func(ctx context.Context) (response, error) {
+		var resp *graphQLResponse = &graphQLResponse{statusCode: 200}
+
+		if calls.Add(1) == 1 {
+			started <- struct{}{}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+
+		resp.body.WriteString("query")
+		return resp, nil
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	waitCtx, waitCancel = context.WithCancel(context.Background())
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err2 = client.coalesce(ctx, "query", fn)
+	}()
+	<-started
+
+	// a waiting caller whose own context is cancelled stops waiting
+	waitCancel()
+	_, err = client.coalesce(waitCtx, "query", fn)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// a waiting caller with a live context issues the query itself once the call in flight was cancelled
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		resp, err = client.coalesce(context.Background(), "query", fn)
+	}()
+
+	assert.Eventually(t, func() bool {
+		var metric dto.Metric
+
+		client.promCoalesced.Write(&metric)
+		return metric.GetCounter().GetValue() == 2
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	wg.Wait()
+
+	assert.ErrorIs(t, err2, context.Canceled)
+	require.Nil(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, "query", resp.RawBody().String())
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -27,6 +27,10 @@ const (
 	StatusDeviceFailed          string = "failed"
 	StatusDeviceDecommissioning string = "decommissioning"
 
+	StatusVDCActive  string = "active"
+	StatusVDCPlanned string = "planned"
+	StatusVDCOffline string = "offline"
+
 	StatusIPActive     string = "active"
 	StatusIPReserved   string = "reserved"
 	StatusIPDeprecated string = "deprecated"
@@ -36,8 +40,4 @@ const (
 	ServiceProtocolTCP  string = "tcp"
 	ServiceProtocolUDP  string = "udp"
 	ServiceProtocolSCTP string = "sctp"
-
-	// because:
-	// >=4.0.10 - https://github.com/netbox-community/netbox/issues/16946
-	compatibleNetboxVersion string = ">=4.0.10"
 )
@@ -18,15 +18,18 @@
 package netbox
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 )
 
 const (
-	queryDeviceAttributes string = "id name primary_ip4{" + queryIPAddressAttributes + "} primary_ip6{" + queryIPAddressAttributes + "} custom_fields rack{name} site{name} role{name} tenant{name} platform{name} serial asset_tag status tags{name}"
-	queryDevice           string = "{device(id:%d){" + queryDeviceAttributes + "}}"
-	queryDevices          string = "{device_list{" + queryDeviceAttributes + "}}"
-	queryDevicesByTag     string = "{device_list(filters: {tag: \"%s\"}){" + queryDeviceAttributes + "}}"
+	queryDeviceAttributes        string = "id name primary_ip4{" + queryIPAddressAttributes + "} primary_ip6{" + queryIPAddressAttributes + "} custom_fields rack{name} site{name} role{name} tenant{name} platform{name} serial asset_tag status tags{name slug}"
+	queryDevice                  string = "query($id: ID!){device(id: $id){" + queryDeviceAttributes + "}}"
+	queryDeviceList              string = "query($filters: DeviceFilter, $pagination: OffsetPaginationInput){device_list(filters: $filters, pagination: $pagination){" + queryDeviceAttributes + "}}"
+	queryDeviceListConfigContext string = "query($filters: DeviceFilter, $pagination: OffsetPaginationInput){device_list(filters: $filters, pagination: $pagination){" + queryDeviceAttributes + " config_context" + "}}"
+	queryDeviceConfigContext     string = "query($id: ID!){device(id: $id){config_context}}"
 )
 
 // Device describes a subset of details of a Netbox device.
@@ -45,22 +48,26 @@ type Device struct {
 	SerialNumber string `json:"serial"`
 	AssetTag     string `json:"asset_tag"`
 	Status       string `json:"status"`
-	Tags         []Name `json:"tags"`
-	isVirtual    bool   `json:"-"`
+	Tags         []Tag  `json:"tags"`
+	// ConfigContext is the rendered config context. It is only populated by GetDevicesWithConfigContext and
+	// GetVMsWithConfigContext. Use GetDeviceConfigContext or GetVMConfigContext to fetch it for a single device/vm.
+	ConfigContext map[string]any `json:"config_context"`
+	isVirtual     bool           `json:"-"`
 }
 
 // GetDevice returns information about a device gathered from Netbox. When error is not nil, the request failed and
 // error gives further details what went wrong. Dev might point to an invalid address at that point and must not be used
 // whenever an error has been returned. When no device with the given ID has been found, Device as well as error are nil.
-func (client *Client) GetDevice(id uint64) (*Device, error) {
+func (client *Client) GetDevice(ctx context.Context, id uint64) (*Device, error) {
 	var (
-		query   string = fmt.Sprintf(queryDevice, id)
 		resp    response
 		wrapper graphQLResponseWrapper
 		err     error
 	)
 
-	resp, err = client.graphQL(query)
+	ctx = withQueryType(ctx, "device")
+
+	resp, err = client.graphQL(ctx, queryDevice, map[string]any{"id": strconv.FormatUint(id, 10)})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query api: %w", err)
 	}
@@ -82,14 +89,64 @@ func (client *Client) GetDevice(id uint64) (*Device, error) {
 }
 
 // GetDevices returns a list of all devices.
-func (client *Client) GetDevices() ([]*Device, error) {
+func (client *Client) GetDevices(ctx context.Context) ([]*Device, error) {
+	return client.getDeviceList(withQueryType(ctx, "devices"), queryDeviceList, nil)
+}
+
+// GetDevicesByTag returns a list of all devices with a given tag.
+func (client *Client) GetDevicesByTag(ctx context.Context, tag string) ([]*Device, error) {
+	return client.GetDevicesByTagFiltered(withQueryType(ctx, "devices_by_tag"), tag, nil)
+}
+
+// GetDevicesByTagFiltered returns a list of all devices with a given tag that match filter. Filtering is done by
+// Netbox.
+func (client *Client) GetDevicesByTagFiltered(ctx context.Context, tag string, filter *DeviceFilter) ([]*Device, error) {
+	return client.getDeviceList(withQueryType(ctx, "devices_by_tag_filtered"), queryDeviceList, filter.apply(map[string]any{"tag": tag}))
+}
+
+// GetDevicesByManufacturer returns a list of all devices whose device type is made by the manufacturer with the given
+// slug.
+func (client *Client) GetDevicesByManufacturer(ctx context.Context, manufacturer string) ([]*Device, error) {
+	return client.getDeviceList(withQueryType(ctx, "devices_by_manufacturer"), queryDeviceList, map[string]any{"manufacturer": manufacturer})
+}
+
+// GetDevicesBySiteGroup returns a list of all devices located at any site within the site group with the given slug
+// (including nested site groups).
+func (client *Client) GetDevicesBySiteGroup(ctx context.Context, group string) ([]*Device, error) {
+	return client.getDeviceList(withQueryType(ctx, "devices_by_site_group"), queryDeviceList, map[string]any{"site_group": group})
+}
+
+// GetDevicesWithConfigContext returns a list of all devices including their rendered config context. Rendering config
+// contexts is expensive in Netbox, thus this should only be used when the config context is needed.
+func (client *Client) GetDevicesWithConfigContext(ctx context.Context) ([]*Device, error) {
+	return client.getDeviceList(withQueryType(ctx, "devices_with_config_context"), queryDeviceListConfigContext, nil)
+}
+
+// GetDeviceConfigContext returns the rendered config context (local and inherited data merged by Netbox) of the device
+// identified by id. Map and error are nil when no device with the given ID exists.
+func (client *Client) GetDeviceConfigContext(ctx context.Context, id uint64) (map[string]any, error) {
 	var (
+		wrapper *graphQLResponseWrapper
 		err     error
+	)
+
+	wrapper, err = client.getConfigContext(withQueryType(ctx, "device_config_context"), queryDeviceConfigContext, id)
+	if err != nil || wrapper.Data.Device == nil {
+		return nil, err
+	}
+
+	return wrapper.Data.Device.ConfigContext, nil
+}
+
+// getConfigContext runs query, selecting the config context of a single device or vm identified by id.
+func (client *Client) getConfigContext(ctx context.Context, query string, id uint64) (*graphQLResponseWrapper, error) {
+	var (
 		resp    response
 		wrapper graphQLResponseWrapper
+		err     error
 	)
 
-	resp, err = client.graphQL(queryDevices)
+	resp, err = client.graphQL(ctx, query, map[string]any{"id": strconv.FormatUint(id, 10)})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query api: %w", err)
 	}
@@ -104,38 +161,23 @@ func (client *Client) GetDevices() ([]*Device, error) {
 		return nil, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
 	}
 
-	// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
-	wrapper.parseIDs()
-
-	return wrapper.Data.DeviceList, nil
+	return &wrapper, nil
 }
 
-// GetDevicesByTag returns a list of all devices with a given tag.
-func (client *Client) GetDevicesByTag(tag string) ([]*Device, error) {
+// getDeviceList returns the list of devices returned by query using filters.
+func (client *Client) getDeviceList(ctx context.Context, query string, filters map[string]any) ([]*Device, error) {
 	var (
-		query   string = fmt.Sprintf(queryDevicesByTag, tag)
+		devices []*Device
 		err     error
-		resp    response
-		wrapper graphQLResponseWrapper
 	)
 
-	resp, err = client.graphQL(query)
+	err = client.graphQLList(ctx, query, filters, func(wrapper *graphQLResponseWrapper) int {
+		devices = append(devices, wrapper.Data.DeviceList...)
+		return len(wrapper.Data.DeviceList)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query api: %w", err)
+		return nil, err
 	}
 
-	if resp.StatusCode() != 200 {
-		return nil, ErrUnexpectedStatusCode
-	}
-
-	err = json.Unmarshal(resp.RawBody().Bytes(), &wrapper)
-	if err != nil {
-		client.promFailure.Inc()
-		return nil, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
-	}
-
-	// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
-	wrapper.parseIDs()
-
-	return wrapper.Data.DeviceList, nil
+	return devices, nil
 }
@@ -18,6 +18,10 @@
 package netbox
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -65,9 +69,10 @@ var (
 		SerialNumber: "abcd",
 		AssetTag:     "a1234",
 		Status:       StatusDeviceActive,
-		Tags: []Name{
+		Tags: []Tag{
 			{
 				Name: "node_exporter",
+				Slug: "node_exporter",
 			},
 		},
 		isVirtual: false,
@@ -99,9 +104,10 @@ var (
 		SerialNumber: "abcde",
 		AssetTag:     "a12345",
 		Status:       StatusDeviceActive,
-		Tags: []Name{
+		Tags: []Tag{
 			{
 				Name: "node_exporter",
+				Slug: "node_exporter",
 			},
 		},
 		isVirtual: false,
@@ -116,13 +122,13 @@ func TestGetDevice(t *testing.T) {
 	client := newTestClient(t)
 
 	// device exists
-	dev, err := client.GetDevice(1)
+	dev, err := client.GetDevice(context.Background(), 1)
 	assert.NoError(t, err)
 	require.NotEmpty(t, dev)
 	assert.Equal(t, devA, dev)
 
 	// device is missing
-	dev, err = client.GetDevice(99999)
+	dev, err = client.GetDevice(context.Background(), 99999)
 	assert.NoError(t, err)
 	assert.Empty(t, dev)
 }
@@ -134,7 +140,7 @@ func TestGetDevices(t *testing.T) {
 
 	client := newTestClient(t)
 
-	devs, err := client.GetDevices()
+	devs, err := client.GetDevices(context.Background())
 	assert.NoError(t, err)
 	require.Len(t, devs, 2)
 
@@ -149,7 +155,7 @@ func TestGetDevicesByTag(t *testing.T) {
 
 	client := newTestClient(t)
 
-	devs, err := client.GetDevicesByTag("node_exporter")
+	devs, err := client.GetDevicesByTag(context.Background(), "node_exporter")
 	assert.NoError(t, err)
 	require.Len(t, devs, 2)
 
@@ -157,7 +163,52 @@ func TestGetDevicesByTag(t *testing.T) {
 	assert.Equal(t, []*Device{devA, devB}, devs)
 
 	// tag doesn't exist
-	devs, err = client.GetDevicesByTag("doesn_t-exist")
+	devs, err = client.GetDevicesByTag(context.Background(), "doesn_t-exist")
 	assert.NoError(t, err)
 	assert.Empty(t, devs)
 }
+
+func TestGetDeviceConfigContext(t *testing.T) {
+	var (
+		server   *httptest.Server
+		requests []graphQLRequest
+		queries  []string
+		client   *Client
+		api      ClientIface
+		cc       map[string]any
+		err      error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request graphQLRequest
+
+		if r.URL.Path == "/api/dcim/devices/" {
+			queries = append(queries, r.URL.Query().Encode())
+			w.Write([]byte(`{"next":null,"results":[{"id":1,"name":"device-A","status":{"value":"active"},` +
+				`"tags":[],"custom_fields":{},"config_context":{"monitoring":{"port":9100}}}]}`))
+			return
+		}
+
+		json.NewDecoder(r.Body).Decode(&request)
+		requests = append(requests, request)
+
+		w.Write([]byte(`{"data":{"device":{"config_context":{"monitoring":{"port":9100}}}}}`))
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+
+	for _, api = range []ClientIface{client, NewREST(client)} {
+		cc, err = api.GetDeviceConfigContext(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"monitoring": map[string]any{"port": float64(9100)}}, cc)
+	}
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, queryDeviceConfigContext, requests[0].Query)
+	assert.Equal(t, map[string]any{"id": "1"}, requests[0].Variables)
+
+	// primary IPs are not resolved and the config context is not excluded
+	assert.Equal(t, []string{"id=1&limit=1000&offset=0"}, queries)
+}
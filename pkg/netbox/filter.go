@@ -0,0 +1,127 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import "net/url"
+
+// DeviceFilter restricts lists of devices and virtual machines on the server side. Sites, roles, tenants and platforms
+// are given by slug; multiple values of a field match any of them. Empty fields don't restrict the list.
+type DeviceFilter struct {
+	Sites     []string
+	Roles     []string
+	Tenants   []string
+	Platforms []string
+	Statuses  []string
+}
+
+// apply adds the filter's fields to filters (the value of a GraphQL filters variable) and returns filters. Filters is
+// returned as is when filter is nil or empty.
+func (filter *DeviceFilter) apply(filters map[string]any) map[string]any {
+	if filter == nil {
+		return filters
+	}
+
+	setListFilter(filters, "site", filter.Sites)
+	setListFilter(filters, "role", filter.Roles)
+	setListFilter(filters, "tenant", filter.Tenants)
+	setListFilter(filters, "platform", filter.Platforms)
+	setListFilter(filters, "status", filter.Statuses)
+
+	return filters
+}
+
+// setListFilter sets filters[name] to values unless values is empty.
+func setListFilter(filters map[string]any, name string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+
+	filters[name] = values
+}
+
+// values adds the filter's fields to values (REST API query parameters) and returns values.
+func (filter *DeviceFilter) values(values url.Values) url.Values {
+	if filter == nil {
+		return values
+	}
+
+	addListValues(values, "site", filter.Sites)
+	addListValues(values, "role", filter.Roles)
+	addListValues(values, "tenant", filter.Tenants)
+	addListValues(values, "platform", filter.Platforms)
+	addListValues(values, "status", filter.Statuses)
+
+	return values
+}
+
+// addListValues adds each of list as value of the query parameter name.
+func addListValues(values url.Values, name string, list []string) {
+	var value string
+
+	for _, value = range list {
+		values.Add(name, value)
+	}
+}
+
+// PrefixFilter restricts lists of prefixes on the server side. Roles and tenants are given by slug; multiple values of
+// these fields match any of them while prefixes must carry all Tags (by slug). Within restricts the list to prefixes
+// contained in the given prefix (e.g. 10.0.0.0/8). Empty fields don't restrict the list.
+type PrefixFilter struct {
+	Within   string
+	Roles    []string
+	Tenants  []string
+	Statuses []string
+	Tags     []string
+}
+
+// apply adds the filter's fields to filters (the value of a GraphQL filters variable) and returns filters. Filters is
+// returned as is when filter is nil or empty.
+func (filter *PrefixFilter) apply(filters map[string]any) map[string]any {
+	if filter == nil {
+		return filters
+	}
+
+	if filter.Within != "" {
+		filters["within"] = filter.Within
+	}
+
+	setListFilter(filters, "role", filter.Roles)
+	setListFilter(filters, "tenant", filter.Tenants)
+	setListFilter(filters, "status", filter.Statuses)
+	setListFilter(filters, "tag", filter.Tags)
+
+	return filters
+}
+
+// values adds the filter's fields to values (REST API query parameters) and returns values.
+func (filter *PrefixFilter) values(values url.Values) url.Values {
+	if filter == nil {
+		return values
+	}
+
+	if filter.Within != "" {
+		values.Set("within", filter.Within)
+	}
+
+	addListValues(values, "role", filter.Roles)
+	addListValues(values, "tenant", filter.Tenants)
+	addListValues(values, "status", filter.Statuses)
+	addListValues(values, "tag", filter.Tags)
+
+	return values
+}
@@ -0,0 +1,67 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceFilterApply(t *testing.T) {
+	var filter *DeviceFilter
+
+	assert.Equal(t, map[string]any{"tag": "foo"}, filter.apply(map[string]any{"tag": "foo"}))
+
+	filter = &DeviceFilter{}
+	assert.Equal(t, map[string]any{"tag": "foo"}, filter.apply(map[string]any{"tag": "foo"}))
+
+	filter = &DeviceFilter{
+		Sites:    []string{"site-a", "site-b"},
+		Statuses: []string{"active"},
+	}
+	assert.Equal(t, map[string]any{
+		"tag":    "foo",
+		"site":   []string{"site-a", "site-b"},
+		"status": []string{"active"},
+	}, filter.apply(map[string]any{"tag": "foo"}))
+}
+
+func TestPrefixFilter(t *testing.T) {
+	var filter *PrefixFilter
+
+	assert.Equal(t, map[string]any{}, filter.apply(map[string]any{}))
+	assert.Equal(t, url.Values{}, filter.values(url.Values{}))
+
+	filter = &PrefixFilter{
+		Within:   "10.0.0.0/8",
+		Statuses: []string{"active", "reserved"},
+		Tags:     []string{"monitoring"},
+	}
+	assert.Equal(t, map[string]any{
+		"within": "10.0.0.0/8",
+		"status": []string{"active", "reserved"},
+		"tag":    []string{"monitoring"},
+	}, filter.apply(map[string]any{}))
+	assert.Equal(t, url.Values{
+		"within": {"10.0.0.0/8"},
+		"status": {"active", "reserved"},
+		"tag":    {"monitoring"},
+	}, filter.values(url.Values{}))
+}
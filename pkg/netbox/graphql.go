@@ -21,6 +21,8 @@ package netbox
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -47,27 +49,237 @@ func (r *graphQLResponse) RawBody() *bytes.Buffer {
 	return &r.body
 }
 
+// GraphQLError is a single error reported by Netbox in the errors of a GraphQL response.
+type GraphQLError struct {
+	Message string `json:"message"`
+	// Path is the path of the field the error occurred at, e.g. [device_list 0 primary_ip4].
+	Path       []any          `json:"path"`
+	Extensions map[string]any `json:"extensions"`
+}
+
+// Error implements the error interface.
+func (err *GraphQLError) Error() string {
+	var (
+		path []string
+		elem any
+	)
+
+	if len(err.Path) == 0 {
+		return err.Message
+	}
+
+	for _, elem = range err.Path {
+		path = append(path, fmt.Sprint(elem))
+	}
+
+	return err.Message + " (at " + strings.Join(path, ".") + ")"
+}
+
+// GraphQLErrors is returned when Netbox answered a GraphQL query with errors, e.g. because of missing permissions.
+// Partial is true when the response contained data besides the errors, which is incomplete and thus discarded. It
+// matches ErrGraphQL with errors.Is while every single error can be retrieved with errors.As.
+type GraphQLErrors struct {
+	Errors  []*GraphQLError
+	Partial bool
+}
+
+// Error implements the error interface.
+func (errs *GraphQLErrors) Error() string {
+	var (
+		msgs   []string = make([]string, len(errs.Errors))
+		prefix string   = "graphql query failed: "
+		i      int
+	)
+
+	if errs.Partial {
+		prefix = "graphql query returned partial data: "
+	}
+
+	for i = range errs.Errors {
+		msgs[i] = errs.Errors[i].Error()
+	}
+
+	return prefix + strings.Join(msgs, "; ")
+}
+
+// Is returns true for ErrGraphQL.
+func (errs *GraphQLErrors) Is(target error) bool {
+	return target == ErrGraphQL
+}
+
+// Unwrap returns all single errors.
+func (errs *GraphQLErrors) Unwrap() []error {
+	var (
+		result []error = make([]error, len(errs.Errors))
+		i      int
+	)
+
+	for i = range errs.Errors {
+		result[i] = errs.Errors[i]
+	}
+
+	return result
+}
+
+// graphQLErrors is used to read errors of a GraphQL response.
+type graphQLErrors struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []*GraphQLError            `json:"errors"`
+}
+
+// parseGraphQLErrors returns a *GraphQLErrors when body is a GraphQL response containing errors and nil otherwise.
+func parseGraphQLErrors(body []byte) error {
+	var (
+		errs   graphQLErrors
+		result *GraphQLErrors
+		field  json.RawMessage
+	)
+
+	// avoid decoding the whole response a second time when there can't be any errors
+	if !bytes.Contains(body, []byte(`"errors"`)) {
+		return nil
+	}
+
+	if json.Unmarshal(body, &errs) != nil || len(errs.Errors) == 0 {
+		return nil
+	}
+
+	result = &GraphQLErrors{Errors: errs.Errors}
+
+	for _, field = range errs.Data {
+		if !bytes.Equal(field, []byte("null")) {
+			result.Partial = true
+			break
+		}
+	}
+
+	return result
+}
+
 // GraphQLResponseWrapper is a structure for extracting data from a GraphQL response body. A downstream function can use
 // it to extract the parts of any GraphQL query it's interested in.
 type graphQLResponseWrapper struct {
 	Data struct {
-		Device        *Device      `json:"device"`
-		DeviceList    []*Device    `json:"device_list"`
-		VM            *Device      `json:"virtual_machine"`
-		VMList        []*Device    `json:"virtual_machine_list"`
-		Interface     *Interface   `json:"interface"`
-		InterfaceList []*Interface `json:"interface_list"`
-		IP            *IP          `json:"ip_address"`
-		IPList        []*IP        `json:"ip_address_list"`
-		ServiceList   []*Service   `json:"service_list"`
+		Device          *Device        `json:"device"`
+		DeviceList      []*Device      `json:"device_list"`
+		VM              *Device        `json:"virtual_machine"`
+		VMList          []*Device      `json:"virtual_machine_list"`
+		Interface       *Interface     `json:"interface"`
+		InterfaceList   []*Interface   `json:"interface_list"`
+		IP              *IP            `json:"ip_address"`
+		IPList          []*IP          `json:"ip_address_list"`
+		ServiceList     []*Service     `json:"service_list"`
+		VLANList        []*VLAN        `json:"vlan_list"`
+		VDCList         []*VDC         `json:"virtual_device_context_list"`
+		WirelessLANList []*WirelessLAN `json:"wireless_lan_list"`
+		SiteList        []*Site        `json:"site_list"`
+		TenantList      []*Tenant      `json:"tenant_list"`
+		RackList        []*Rack        `json:"rack_list"`
+		ClusterList     []*Cluster     `json:"cluster_list"`
+		RegionList      []*Region      `json:"region_list"`
+		LocationList    []*Location    `json:"location_list"`
+		PrefixList      []*Prefix      `json:"prefix_list"`
 	} `json:"data"`
 }
 
-// GraphQL performs a new GraphQL request towards Netbox, using query as GraphQL compliant query string. No validation
-// of query is performed. No pagenation is used. On success a ptr to a Response struct is returned while error is not.
-// The contents of the request is not further validated. Success therefore means some 2xx response code has been
-// returned by Netbox. Otherwise error contains details about the failure and a nil ptr for Response is returned.
-func (client *Client) graphQL(query string) (response, error) {
+// GraphQL performs a new GraphQL request towards Netbox, using query as GraphQL compliant query string and variables as
+// values of the variables defined by query. Values must never be interpolated into query, instead query is a fixed
+// document. No validation of query is performed. No pagenation is used. On success a ptr to a Response struct is
+// returned while error is not. The contents of the request is not further validated. Success therefore means some 2xx
+// response code has been returned by Netbox. Otherwise error contains details about the failure and a nil ptr for
+// Response is returned.
+//
+// Identical queries (including their variables) issued concurrently are coalesced into a single request towards Netbox
+// and served from the response cache when a cache ttl is set (see SetCacheTTL). Responses containing GraphQL errors
+// result in a *GraphQLErrors error, even if they contain (partial) data.
+func (client *Client) graphQL(ctx context.Context, query string, variables map[string]any) (response, error) {
+	var (
+		key []byte
+		err error
+	)
+
+	// map keys are sorted, thus identical variables result in the same key
+	key, err = json.Marshal(variables)
+	if err != nil {
+		client.promFailure.Inc()
+		return nil, fmt.Errorf("failed to marshal graphql variables: %w", err)
+	}
+
+	return client.cached(ctx, query+"\n"+string(key), func(ctx context.Context) (response, error) {
+		return client.doGraphQL(ctx, query, variables)
+	})
+}
+
+// Query performs the GraphQL query with the given variables and decodes the data of the response as T. It allows fetching
+// objects (or fields) not covered by the client's methods while benefiting from its caching, coalescing, retries and
+// metrics. As for the client's own queries, values must be passed as variables instead of being interpolated into query.
+// The query is sent as is, thus pagination is up to the caller. Responses containing GraphQL errors result in a
+// *GraphQLErrors error.
+//
+// T is usually a struct with fields matching the top level fields of query, e.g.
+//
+//	type siteList struct {
+//		Sites []struct {
+//			Name string `json:"name"`
+//		} `json:"site_list"`
+//	}
+//
+//	sites, err := netbox.Query[siteList](ctx, client, "query{site_list{name}}", nil)
+func Query[T any](ctx context.Context, client *Client, query string, variables map[string]any) (T, error) {
+	var (
+		wrapper struct {
+			Data T `json:"data"`
+		}
+		resp response
+		err  error
+	)
+
+	resp, err = client.graphQL(withQueryType(ctx, "query"), query, variables)
+	if err != nil {
+		return wrapper.Data, fmt.Errorf("failed to query api: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return wrapper.Data, ErrUnexpectedStatusCode
+	}
+
+	err = json.Unmarshal(resp.RawBody().Bytes(), &wrapper)
+	if err != nil {
+		client.promFailure.Inc()
+		return wrapper.Data, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
+	}
+
+	return wrapper.Data, nil
+}
+
+// doGraphQL performs the actual GraphQL request. See graphQL.
+func (client *Client) doGraphQL(ctx context.Context, query string, variables map[string]any) (response, error) {
+	var (
+		resp response
+		err  error
+	)
+
+	if client.persistedQueries.Load() {
+		resp, err = client.persistedGraphQL(ctx, query, variables)
+	} else {
+		resp, err = client.postGraphQL(ctx, &graphQLRequest{Query: query, Variables: variables})
+	}
+
+	if err != nil || resp.StatusCode() != 200 {
+		return resp, err
+	}
+
+	err = parseGraphQLErrors(resp.RawBody().Bytes())
+	if err != nil {
+		client.promFailure.Inc()
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// postGraphQL sends request to Netbox's GraphQL endpoint.
+func (client *Client) postGraphQL(ctx context.Context, request *graphQLRequest) (response, error) {
 	var (
 		resp        *http.Response
 		gResp       graphQLResponse
@@ -75,22 +287,31 @@ func (client *Client) graphQL(query string) (response, error) {
 		err         error
 		dump, dump2 []byte
 		body        string
+		data        []byte
 
 		// used for request timing
 		timer time.Time
 		dur   time.Duration
 	)
 
-	body = "{\"query\":\"" + strings.ReplaceAll(query, "\"", "\\\"") + "\"}"
+	data, err = json.Marshal(request)
+	if err != nil {
+		client.promFailure.Inc()
+		return nil, fmt.Errorf("failed to marshal graphql request: %w", err)
+	}
+
+	body = string(data)
 
 	req = http.Request{
 		Method: http.MethodPost,
 		Header: map[string][]string{
-			"Accept":        {"application/json"},
-			"Content-Type":  {"application/json"},
-			"Authorization": {fmt.Sprintf("Token %s", client.token)},
+			"Accept":       {"application/json"},
+			"Content-Type": {"application/json"},
 		},
 		Body: io.NopCloser(bytes.NewBufferString(body)),
+		GetBody: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewBufferString(body)), nil
+		},
 		// sad panda - netbox-docker doesn't support chunked encoding
 		ContentLength:    int64(len(body)),
 		TransferEncoding: []string{"identity"},
@@ -99,11 +320,12 @@ func (client *Client) graphQL(query string) (response, error) {
 	req.URL, _ = url.ParseRequestURI(client.url + "/graphql/")
 
 	timer = time.Now()
-	resp, err = client.http.Do(&req)
+	resp, err = client.do(ctx, &req, "/graphql/")
 	if err != nil {
 		client.promError.
 			With(prometheus.Labels{
-				"url": "/graphql/",
+				"url":   "/graphql/",
+				"query": queryType(ctx),
 			}).
 			Inc()
 		return nil, fmt.Errorf("http graphql call failed: %w", err)
@@ -116,15 +338,25 @@ func (client *Client) graphQL(query string) (response, error) {
 
 	client.promDuration.
 		With(prometheus.Labels{
-			"url":  "/graphql/",
-			"code": strconv.Itoa(resp.StatusCode),
+			"url":   "/graphql/",
+			"code":  strconv.Itoa(resp.StatusCode),
+			"query": queryType(ctx),
 		}).
 		Set(float64(dur * time.Nanosecond))
 
+	client.promLatency.
+		With(prometheus.Labels{
+			"url":   "/graphql/",
+			"code":  strconv.Itoa(resp.StatusCode),
+			"query": queryType(ctx),
+		}).
+		Observe(dur.Seconds())
+
 	client.promStatus.
 		With(prometheus.Labels{
-			"url":  "/graphql/",
-			"code": strconv.Itoa(resp.StatusCode),
+			"url":   "/graphql/",
+			"code":  strconv.Itoa(resp.StatusCode),
+			"query": queryType(ctx),
 		}).
 		Inc()
 
@@ -136,7 +368,7 @@ func (client *Client) graphQL(query string) (response, error) {
 		return nil, fmt.Errorf("failed to read response body into buffer: %w", err)
 	}
 
-	if client.httpTracing {
+	if client.httpTracing.Load() {
 		// It is more efficient to check the level instead of dumping the entire requests and response every time and just
 		// throwing away the result.
 
@@ -144,20 +376,21 @@ func (client *Client) graphQL(query string) (response, error) {
 		dump, err = httputil.DumpRequest(&req, false)
 		if err != nil {
 			client.promFailure.Inc()
-			client.log.Errorf("failed to dump http request: %v", err)
+			client.logger().ErrorContext(ctx, "failed to dump http request", "error", err)
 		} else {
 			dump2, err = httputil.DumpResponse(resp, false)
 			if err != nil {
 				client.promFailure.Inc()
-				client.log.Errorf("failed to dump http response: %v", err)
+				client.logger().ErrorContext(ctx, "failed to dump http response", "error", err)
 			} else {
-				client.log.Tracef("===> HTTP Request <===\n%s%s\n\n", string(dump), body)
-				client.log.Tracef("===> HTTP Response <===\n%s%s\n\n", string(dump2), gResp.body.String())
+				client.logger().Log(ctx, LevelTrace, "http request", "dump", string(dump)+body)
+				client.logger().Log(ctx, LevelTrace, "http response", "dump", string(dump2)+gResp.body.String())
 			}
 		}
 	}
 
-	client.log.Tracef("http call took %dms", dur.Milliseconds())
+	client.logger().Log(ctx, LevelTrace, "http call finished", "url", "/graphql/", "query", queryType(ctx), "status",
+		resp.StatusCode, "duration", dur)
 
 	return &gResp, nil
 }
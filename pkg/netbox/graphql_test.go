@@ -0,0 +1,142 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGraphQLErrors(t *testing.T) {
+	var (
+		err   error
+		errs  *GraphQLErrors
+		field *GraphQLError
+	)
+
+	assert.Nil(t, parseGraphQLErrors([]byte(`{"data":{"device_list":[]}}`)))
+	assert.Nil(t, parseGraphQLErrors([]byte(`{"data":{"device_list":[]},"errors":[]}`)))
+
+	// a device named "errors" isn't an error
+	assert.Nil(t, parseGraphQLErrors([]byte(`{"data":{"device_list":[{"name":"errors"}]}}`)))
+
+	err = parseGraphQLErrors([]byte(`{"data":null,"errors":[{"message":"permission denied"}]}`))
+	require.ErrorAs(t, err, &errs)
+	assert.False(t, errs.Partial)
+	assert.ErrorIs(t, err, ErrGraphQL)
+	assert.Equal(t, "graphql query failed: permission denied", err.Error())
+
+	// nullable root fields are null on errors
+	err = parseGraphQLErrors([]byte(`{"data":{"device":null},"errors":[{"message":"permission denied"}]}`))
+	require.ErrorAs(t, err, &errs)
+	assert.False(t, errs.Partial)
+
+	err = parseGraphQLErrors([]byte(`{"data":{"device_list":[{"id":"1","primary_ip4":null}]},"errors":[` +
+		`{"message":"permission denied","path":["device_list",0,"primary_ip4"]},{"message":"other"}]}`))
+	require.ErrorAs(t, err, &errs)
+	assert.True(t, errs.Partial)
+	assert.Len(t, errs.Errors, 2)
+	assert.Equal(t, "graphql query returned partial data: permission denied (at device_list.0.primary_ip4); other",
+		err.Error())
+
+	require.ErrorAs(t, err, &field)
+	assert.Equal(t, "permission denied", field.Message)
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	var (
+		server *httptest.Server
+		calls  int
+		client *Client
+		err    error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"data":{"device_list":[{"id":"1"}]},"errors":[{"message":"permission denied"}]}`))
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token")
+	require.NoError(t, err)
+	client.SetCacheTTL(time.Minute)
+
+	_, err = client.GetDevicesByTag(context.Background(), "foo")
+	assert.ErrorIs(t, err, ErrGraphQL)
+
+	// responses with errors aren't cached
+	_, err = client.GetDevicesByTag(context.Background(), "foo")
+	assert.ErrorIs(t, err, ErrGraphQL)
+	assert.Equal(t, 2, calls)
+}
+
+func TestQuery(t *testing.T) {
+	type siteList struct {
+		Sites []struct {
+			Name string `json:"name"`
+		} `json:"site_list"`
+	}
+
+	var (
+		server  *httptest.Server
+		request graphQLRequest
+		client  *Client
+		sites   siteList
+		err     error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&request)
+
+		switch request.Variables["name"] {
+		case "broken":
+			w.Write([]byte(`{"data":{"site_list":{}}}`))
+		case "denied":
+			w.Write([]byte(`{"data":{"site_list":null},"errors":[{"message":"permission denied"}]}`))
+		default:
+			w.Write([]byte(`{"data":{"site_list":[{"name":"site-A"},{"name":"site-B"}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+
+	sites, err = Query[siteList](context.Background(), client, "query($name: [String!]){site_list(filters: {name: $name}){name}}",
+		map[string]any{"name": "site"})
+	require.NoError(t, err)
+	require.Len(t, sites.Sites, 2)
+	assert.Equal(t, "site-A", sites.Sites[0].Name)
+	assert.Equal(t, "site-B", sites.Sites[1].Name)
+	assert.Equal(t, map[string]any{"name": "site"}, request.Variables)
+
+	_, err = Query[siteList](context.Background(), client, "query($name: [String!]){site_list{name}}",
+		map[string]any{"name": "broken"})
+	assert.Error(t, err)
+
+	_, err = Query[siteList](context.Background(), client, "query($name: [String!]){site_list{name}}",
+		map[string]any{"name": "denied"})
+	assert.ErrorIs(t, err, ErrGraphQL)
+}
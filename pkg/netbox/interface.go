@@ -18,17 +18,19 @@
 package netbox
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 )
 
 const (
-	queryInterfaceAttributes        string = "id name description enabled mark_connected mgmt_only type mtu parent{id} lag{id} mode custom_fields device {" + queryDeviceAttributes + "} tags{name}"
-	queryVirtualInterfaceAttributes string = "id name description enabled mtu parent{id} mode custom_fields device: virtual_machine{" + queryVMAttributes + "} tags{name}"
-	queryInterface                  string = "{interface(id:%d){" + queryInterfaceAttributes + "}}"
-	queryVirtualInterface           string = "{interface: vm_interface(id:%d){" + queryVirtualInterfaceAttributes + "}}"
-	queryInterfacesByTag            string = "{interface_list(filters: {tag:\"%s\"}){" + queryInterfaceAttributes + "}}"
-	queryVirtualInterfacesByTag     string = "{interface_list: vm_interface_list(filters: {tag:\"%s\"}){" + queryVirtualInterfaceAttributes + "}}"
+	queryInterfaceAttributes        string = "id name description enabled mark_connected mgmt_only type mtu parent{id} lag{id} mode untagged_vlan{" + queryVLANAttributes + "} tagged_vlans{" + queryVLANAttributes + "} custom_fields device {" + queryDeviceAttributes + "} tags{name slug}"
+	queryVirtualInterfaceAttributes string = "id name description enabled mtu parent{id} mode untagged_vlan{" + queryVLANAttributes + "} tagged_vlans{" + queryVLANAttributes + "} custom_fields device: virtual_machine{" + queryVMAttributes + "} tags{name slug}"
+	queryInterface                  string = "query($id: ID!){interface(id: $id){" + queryInterfaceAttributes + "}}"
+	queryVirtualInterface           string = "query($id: ID!){interface: vm_interface(id: $id){" + queryVirtualInterfaceAttributes + "}}"
+	queryInterfaceList              string = "query($filters: InterfaceFilter, $pagination: OffsetPaginationInput){interface_list(filters: $filters, pagination: $pagination){" + queryInterfaceAttributes + "}}"
+	queryVirtualInterfaceList       string = "query($filters: VMInterfaceFilter, $pagination: OffsetPaginationInput){interface_list: vm_interface_list(filters: $filters, pagination: $pagination){" + queryVirtualInterfaceAttributes + "}}"
 )
 
 // Interface describes a subset of details about a Netbox interface.
@@ -39,20 +41,26 @@ type Interface struct {
 	Enabled      bool    `json:"enabled"`
 	CustomFields CFMap   `json:"custom_fields"`
 	Device       *Device `json:"device"`
-	Tags         []Name  `json:"tags"`
+	// Mode is the 802.1Q mode of the interface (access, tagged or tagged-all) and empty when not set.
+	Mode string `json:"mode"`
+	// UntaggedVLAN and TaggedVLANs are the vlans the interface is attached to.
+	UntaggedVLAN *VLAN   `json:"untagged_vlan"`
+	TaggedVLANs  []*VLAN `json:"tagged_vlans"`
+	Tags         []Tag   `json:"tags"`
 	isVirtual    bool    `json:"-"`
 }
 
 // GetInterface returns the device interface identified by id.
-func (client *Client) GetInterface(id uint64) (*Interface, error) {
+func (client *Client) GetInterface(ctx context.Context, id uint64) (*Interface, error) {
 	var (
-		query   string = fmt.Sprintf(queryInterface, id)
 		resp    response
 		wrapper graphQLResponseWrapper
 		err     error
 	)
 
-	resp, err = client.graphQL(query)
+	ctx = withQueryType(ctx, "interface")
+
+	resp, err = client.graphQL(ctx, queryInterface, map[string]any{"id": strconv.FormatUint(id, 10)})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query api: %w", err)
 	}
@@ -74,15 +82,16 @@ func (client *Client) GetInterface(id uint64) (*Interface, error) {
 }
 
 // GetVirtualInterface returns the virtual interface identified by id.
-func (client *Client) GetVirtualInterface(id uint64) (*Interface, error) {
+func (client *Client) GetVirtualInterface(ctx context.Context, id uint64) (*Interface, error) {
 	var (
-		query   string = fmt.Sprintf(queryVirtualInterface, id)
 		resp    response
 		wrapper graphQLResponseWrapper
 		err     error
 	)
 
-	resp, err = client.graphQL(query)
+	ctx = withQueryType(ctx, "virtual_interface")
+
+	resp, err = client.graphQL(ctx, queryVirtualInterface, map[string]any{"id": strconv.FormatUint(id, 10)})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query api: %w", err)
 	}
@@ -114,69 +123,62 @@ func (client *Client) GetVirtualInterface(id uint64) (*Interface, error) {
 }
 
 // GetInterfacesByTag returns a list of all device interfaces having a specific tag set in Netbox.
-func (client *Client) GetInterfacesByTag(tag string) ([]*Interface, error) {
-	var (
-		query   string = fmt.Sprintf(queryInterfacesByTag, tag)
-		err     error
-		resp    response
-		wrapper graphQLResponseWrapper
-	)
-
-	resp, err = client.graphQL(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query api: %w", err)
-	}
+func (client *Client) GetInterfacesByTag(ctx context.Context, tag string) ([]*Interface, error) {
+	return client.getInterfaceList(withQueryType(ctx, "interfaces_by_tag"), queryInterfaceList, map[string]any{"tag": tag}, false)
+}
 
-	if resp.StatusCode() != 200 {
-		return nil, ErrUnexpectedStatusCode
-	}
+// GetVirtualInterfacesByTag returns a list of all virtual interfaces having a specific tag set in Netbox.
+func (client *Client) GetVirtualInterfacesByTag(ctx context.Context, tag string) ([]*Interface, error) {
+	return client.getInterfaceList(withQueryType(ctx, "virtual_interfaces_by_tag"), queryVirtualInterfaceList, map[string]any{"tag": tag}, true)
+}
 
-	err = json.Unmarshal(resp.RawBody().Bytes(), &wrapper)
-	if err != nil {
-		client.promFailure.Inc()
-		return nil, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
-	}
+// GetInterfacesByVLAN returns a list of all device interfaces attached (untagged or tagged) to the vlan identified by
+// id.
+func (client *Client) GetInterfacesByVLAN(ctx context.Context, id uint64) ([]*Interface, error) {
+	return client.getInterfaceList(withQueryType(ctx, "interfaces_by_vlan"), queryInterfaceList, map[string]any{"vlan_id": strconv.FormatUint(id, 10)}, false)
+}
 
-	// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
-	wrapper.parseIDs()
+// GetVirtualInterfacesByVLAN returns a list of all virtual interfaces attached (untagged or tagged) to the vlan
+// identified by id.
+func (client *Client) GetVirtualInterfacesByVLAN(ctx context.Context, id uint64) ([]*Interface, error) {
+	return client.getInterfaceList(withQueryType(ctx, "virtual_interfaces_by_vlan"), queryVirtualInterfaceList, map[string]any{"vlan_id": strconv.FormatUint(id, 10)},
+		true)
+}
 
-	return wrapper.Data.InterfaceList, nil
+// GetInterfacesByWirelessLAN returns a list of all device interfaces (i.e. of access points) attached to the wireless
+// LAN identified by id. Wireless LANs can only be attached to device interfaces.
+func (client *Client) GetInterfacesByWirelessLAN(ctx context.Context, id uint64) ([]*Interface, error) {
+	return client.getInterfaceList(withQueryType(ctx, "interfaces_by_wireless_lan"), queryInterfaceList, map[string]any{"wireless_lan_id": strconv.FormatUint(id, 10)},
+		false)
 }
 
-// GetVirtualInterfacesByTag returns a list of all virtual interfaces having a specific tag set in Netbox.
-func (client *Client) GetVirtualInterfacesByTag(tag string) ([]*Interface, error) {
+// getInterfaceList returns the list of interfaces returned by query using filters. When virtual is true, the interfaces are marked as
+// virtual interfaces of VMs.
+func (client *Client) getInterfaceList(ctx context.Context, query string, filters map[string]any,
+	virtual bool) ([]*Interface, error) {
 	var (
-		query   string = fmt.Sprintf(queryVirtualInterfacesByTag, tag)
-		err     error
-		resp    response
-		wrapper graphQLResponseWrapper
+		interfaces []*Interface
+		err        error
 	)
 
-	resp, err = client.graphQL(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query api: %w", err)
-	}
-
-	if resp.StatusCode() != 200 {
-		return nil, ErrUnexpectedStatusCode
-	}
+	err = client.graphQLList(ctx, query, filters, func(wrapper *graphQLResponseWrapper) int {
+		var i int
 
-	err = json.Unmarshal(resp.RawBody().Bytes(), &wrapper)
-	if err != nil {
-		client.promFailure.Inc()
-		return nil, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
-	}
-
-	for i := range wrapper.Data.InterfaceList {
-		wrapper.Data.InterfaceList[i].isVirtual = true
+		for i = range wrapper.Data.InterfaceList {
+			wrapper.Data.InterfaceList[i].isVirtual = virtual
 
-		if wrapper.Data.InterfaceList[i].Device != nil {
-			wrapper.Data.InterfaceList[i].Device.isVirtual = true
+			if virtual && wrapper.Data.InterfaceList[i].Device != nil {
+				wrapper.Data.InterfaceList[i].Device.isVirtual = true
+			}
 		}
 
-		// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
-		wrapper.Data.InterfaceList[i].parseIDs()
+		interfaces = append(interfaces, wrapper.Data.InterfaceList...)
+
+		return len(wrapper.Data.InterfaceList)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return wrapper.Data.InterfaceList, nil
+	return interfaces, nil
 }
@@ -18,6 +18,7 @@
 package netbox
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -47,12 +48,14 @@ var (
 				},
 			},
 		},
-		Tags: []Name{
+		Tags: []Tag{
 			{
 				Name: "ipmi_exporter",
+				Slug: "ipmi_exporter",
 			},
 		},
-		Device: devA,
+		Device:      devA,
+		TaggedVLANs: []*VLAN{},
 	}
 	iface2 = &Interface{
 		ID:        2,
@@ -63,12 +66,14 @@ var (
 		CustomFields: CFMap{
 			entries: map[string]*CustomField{},
 		},
-		Tags: []Name{
+		Tags: []Tag{
 			{
 				Name: "ipmi_exporter",
+				Slug: "ipmi_exporter",
 			},
 		},
-		Device: devB,
+		Device:      devB,
+		TaggedVLANs: []*VLAN{},
 	}
 
 	vIface1 = &Interface{
@@ -93,12 +98,14 @@ var (
 				},
 			},
 		},
-		Tags: []Name{
+		Tags: []Tag{
 			{
 				Name: "node_exporter",
+				Slug: "node_exporter",
 			},
 		},
-		Device: vmA,
+		Device:      vmA,
+		TaggedVLANs: []*VLAN{},
 	}
 	vIface2 = &Interface{
 		ID:        2,
@@ -109,12 +116,14 @@ var (
 		CustomFields: CFMap{
 			entries: map[string]*CustomField{},
 		},
-		Tags: []Name{
+		Tags: []Tag{
 			{
 				Name: "node_exporter",
+				Slug: "node_exporter",
 			},
 		},
-		Device: vmB,
+		Device:      vmB,
+		TaggedVLANs: []*VLAN{},
 	}
 )
 
@@ -126,14 +135,14 @@ func TestGetInterface(t *testing.T) {
 	client := newTestClient(t)
 
 	// interface exists
-	iface, err := client.GetInterface(1)
+	iface, err := client.GetInterface(context.Background(), 1)
 	assert.NoError(t, err)
 	require.NotEmpty(t, iface)
 
 	assert.Equal(t, iface1, iface)
 
 	// interface is missing
-	iface, err = client.GetInterface(99999)
+	iface, err = client.GetInterface(context.Background(), 99999)
 	assert.NoError(t, err)
 	assert.Empty(t, iface)
 }
@@ -145,7 +154,7 @@ func TestGetInterfacesByTag(t *testing.T) {
 	client := newTestClient(t)
 
 	// interface exists
-	iface, err := client.GetInterfacesByTag("ipmi_exporter")
+	iface, err := client.GetInterfacesByTag(context.Background(), "ipmi_exporter")
 	assert.NoError(t, err)
 	require.NotEmpty(t, iface)
 	require.Equal(t, 2, len(iface))
@@ -153,7 +162,7 @@ func TestGetInterfacesByTag(t *testing.T) {
 	assert.Equal(t, []*Interface{iface1, iface2}, iface)
 
 	// interface is missing
-	iface, err = client.GetInterfacesByTag("this-does_not-exist")
+	iface, err = client.GetInterfacesByTag(context.Background(), "this-does_not-exist")
 	assert.NoError(t, err)
 	require.Empty(t, iface)
 }
@@ -166,13 +175,13 @@ func TestGetVirtualInterface(t *testing.T) {
 	client := newTestClient(t)
 
 	// interface exists
-	iface, err := client.GetVirtualInterface(1)
+	iface, err := client.GetVirtualInterface(context.Background(), 1)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, iface)
 	assert.Equal(t, vIface1, iface)
 
 	// interface is missing
-	iface, err = client.GetVirtualInterface(99999)
+	iface, err = client.GetVirtualInterface(context.Background(), 99999)
 	assert.NoError(t, err)
 	assert.Empty(t, iface)
 }
@@ -185,7 +194,7 @@ func TestGetVirtualInterfacesByTag(t *testing.T) {
 	client := newTestClient(t)
 
 	// interface exists
-	iface, err := client.GetVirtualInterfacesByTag("node_exporter")
+	iface, err := client.GetVirtualInterfacesByTag(context.Background(), "node_exporter")
 	assert.NoError(t, err)
 	require.NotEmpty(t, iface)
 	require.Equal(t, 2, len(iface))
@@ -193,7 +202,7 @@ func TestGetVirtualInterfacesByTag(t *testing.T) {
 	assert.Equal(t, []*Interface{vIface1, vIface2}, iface)
 
 	// interface is missing
-	iface, err = client.GetVirtualInterfacesByTag("this-does_not-exist")
+	iface, err = client.GetVirtualInterfacesByTag(context.Background(), "this-does_not-exist")
 	assert.NoError(t, err)
 	require.Empty(t, iface)
 }
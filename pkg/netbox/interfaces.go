@@ -19,53 +19,81 @@ package netbox
 
 import (
 	"bytes"
+	"context"
+	"log/slog"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-// ClientIface defines function for interacting with the Netbox API.
+// ClientIface defines function for interacting with the Netbox API. All functions performing API calls take a context
+// which is used to cancel requests still in flight, e.g. on shutdown or when a deadline is exceeded. It only consists of
+// exported methods, thus it can be implemented outside of this package (see netboxtest.Mock).
 type ClientIface interface {
 	prometheus.Collector
 
-	// Get performs a simple GET request against Netbox's API. It takes a URL (either absolute, including protocol,
-	// hostname, etc *OR* a relative to the API path like `/dcim/devices..`. When  the request was successful, the
-	// response is returned and error is nil. If the request could not be performed for whatever reason, error is not nil
-	// and response *must* not be used further.
-	get(string) (response, error)
-
-	// GraphQL performs a new GraphQL request towards Netbox, using a GraphQL compliant query string. No validation of
-	// query is performed. No pagenation is used. On success a ptr to a Response struct is returned while error is not.
-	// The contents of the request is not further validated. Success therefore means some 2xx response code has been
-	// returned by Netbox. Otherwise error contains details about the failure and a nil ptr for Response is returned.
-	graphQL(string) (response, error)
-
 	/*
 	 * devices
 	 */
 
 	// GetDevice queries Netbox for a specific device, identified by its nummeric ID. An error is returned when the API
 	// call failed. *Device and error may be nil when a device of the ID doesn't exist.
-	GetDevice(uint64) (*Device, error)
+	GetDevice(context.Context, uint64) (*Device, error)
 
 	// GetDevices returns a list of all devices.
-	GetDevices() ([]*Device, error)
+	GetDevices(context.Context) ([]*Device, error)
 
 	// GetDevicesByTag returns a list of all devices with a given tag.
-	GetDevicesByTag(string) ([]*Device, error)
+	GetDevicesByTag(context.Context, string) ([]*Device, error)
+
+	// GetDevicesByTagFiltered returns a list of all devices with a given tag that match the filter.
+	GetDevicesByTagFiltered(context.Context, string, *DeviceFilter) ([]*Device, error)
+
+	// GetDevicesByManufacturer returns a list of all devices made by a specific manufacturer (by slug).
+	GetDevicesByManufacturer(context.Context, string) ([]*Device, error)
+
+	// GetDevicesBySiteGroup returns a list of all devices located in a specific site group (by slug).
+	GetDevicesBySiteGroup(context.Context, string) ([]*Device, error)
+
+	// GetDevicesWithConfigContext returns a list of all devices including their rendered config context.
+	GetDevicesWithConfigContext(context.Context) ([]*Device, error)
+
+	// GetDeviceConfigContext returns the rendered config context of a device identified by id. The map and error are
+	// nil when a device of the ID doesn't exist.
+	GetDeviceConfigContext(context.Context, uint64) (map[string]any, error)
+
+	// GetDevicesByQuery returns a list of all devices matching REST API query parameters.
+	GetDevicesByQuery(context.Context, string) ([]*Device, error)
+
+	// GetDevicesBatch returns the lists of devices and VMs selected by each query, fetched with a single request.
+	GetDevicesBatch(context.Context, []DeviceQuery) ([][]*Device, error)
+
+	// ForEachDevice calls the given function for each device or VM selected by the query while decoding the list
+	// incrementally. Iteration stops at the first error returned by the function, which is returned as is.
+	ForEachDevice(context.Context, DeviceQuery, func(*Device) error) error
 
 	/*
 	 * interfaces
 	 */
 
 	// GetInterface returns a single interface identified by id.
-	GetInterface(uint64) (*Interface, error)
+	GetInterface(context.Context, uint64) (*Interface, error)
 	// GetInterfacesByTag returns a list of all interfaces having a specific tag set in Netbox.
-	GetInterfacesByTag(string) ([]*Interface, error)
+	GetInterfacesByTag(context.Context, string) ([]*Interface, error)
 
 	// GetVirtualInterface returns a single VM interface identified by id.
-	GetVirtualInterface(uint64) (*Interface, error)
+	GetVirtualInterface(context.Context, uint64) (*Interface, error)
 	// GetVirtualInterfacesByTag returns a list of all VM interfaces having a specific tag set in Netbox.
-	GetVirtualInterfacesByTag(string) ([]*Interface, error)
+	GetVirtualInterfacesByTag(context.Context, string) ([]*Interface, error)
+
+	// GetInterfacesByVLAN returns a list of all device interfaces attached to a specific vlan (by id).
+	GetInterfacesByVLAN(context.Context, uint64) ([]*Interface, error)
+
+	// GetVirtualInterfacesByVLAN returns a list of all VM interfaces attached to a specific vlan (by id).
+	GetVirtualInterfacesByVLAN(context.Context, uint64) ([]*Interface, error)
+
+	// GetInterfacesByWirelessLAN returns a list of all device interfaces attached to a specific wireless LAN (by id).
+	GetInterfacesByWirelessLAN(context.Context, uint64) ([]*Interface, error)
 
 	/*
 	 * IP addresses
@@ -73,50 +101,154 @@ type ClientIface interface {
 
 	// GetIPsByAddress searches Netbox for an IP object based on an address string given. Address MUST NOT be a cidr. An
 	// error is returned when the API call failed. *IP and error may be nil when no ip matches the given address.
-	GetIPsByAddress(string) ([]*IP, error)
+	GetIPsByAddress(context.Context, string) ([]*IP, error)
 
 	// GetInterfaceIPs returns a list of all IPs associated with a given interface id.
-	GetInterfaceIPs(uint64) ([]*IP, error)
+	GetInterfaceIPs(context.Context, uint64) ([]*IP, error)
 	// GetVirtualInterfaceIPs returns a list of all IPs associated with a given virtual interface id.
-	GetVirtualInterfaceIPs(uint64) ([]*IP, error)
+	GetVirtualInterfaceIPs(context.Context, uint64) ([]*IP, error)
+
+	// GetIPsInPrefix returns a list of all IPs within a prefix (cidr) in any VRF.
+	GetIPsInPrefix(context.Context, string) ([]*IP, error)
+
+	/*
+	 * prefixes
+	 */
+
+	// GetPrefixes returns a list of all prefixes matching the filter (nil for all prefixes).
+	GetPrefixes(context.Context, *PrefixFilter) ([]*Prefix, error)
 
 	/*
 	 * services
 	 */
 
 	// GetServices returns a list of all services that exists in Netbox.
-	GetServices() ([]*Service, error)
+	GetServices(context.Context) ([]*Service, error)
 
 	// GetServicesByName returns a list of all services that exists in Netbox based on the service's name.
-	GetServicesByName(string) ([]*Service, error)
+	GetServicesByName(context.Context, string) ([]*Service, error)
+
+	/*
+	 * VLANs
+	 */
+
+	// GetVLANs returns a list of all vlans.
+	GetVLANs(context.Context) ([]*VLAN, error)
+
+	// GetVLANsByVID returns a list of all vlans using a specific VLAN ID.
+	GetVLANsByVID(context.Context, uint16) ([]*VLAN, error)
+
+	// GetVLANsByName returns a list of all vlans with a specific name.
+	GetVLANsByName(context.Context, string) ([]*VLAN, error)
+
+	/*
+	 * Wireless LANs
+	 */
+
+	// GetWirelessLANsBySSID returns a list of all wireless LANs with a specific SSID.
+	GetWirelessLANsBySSID(context.Context, string) ([]*WirelessLAN, error)
+
+	/*
+	 * VDCs
+	 */
+
+	// GetVDCsByTag returns a list of all virtual device contexts with a given tag.
+	GetVDCsByTag(context.Context, string) ([]*VDC, error)
 
 	/*
 	 * VMs
 	 */
 
 	// GetVM returns a device/vm identified by id.
-	GetVM(uint64) (*Device, error)
+	GetVM(context.Context, uint64) (*Device, error)
 
 	// GetVMs returns a list of all VMs.
-	GetVMs() ([]*Device, error)
+	GetVMs(context.Context) ([]*Device, error)
 
 	// GetVMsByTag returns a list of all vms with a given tag.
-	GetVMsByTag(string) ([]*Device, error)
+	GetVMsByTag(context.Context, string) ([]*Device, error)
+
+	// GetVMsByTagFiltered returns a list of all vms with a given tag that match the filter.
+	GetVMsByTagFiltered(context.Context, string, *DeviceFilter) ([]*Device, error)
+
+	// GetVMsByCluster returns a list of all vms that are part of a specific cluster (by name).
+	GetVMsByCluster(context.Context, string) ([]*Device, error)
+
+	// GetVMsByClusterGroup returns a list of all vms that are part of a specific cluster group (by slug).
+	GetVMsByClusterGroup(context.Context, string) ([]*Device, error)
+
+	// GetVMsBySiteGroup returns a list of all vms located in a specific site group (by slug).
+	GetVMsBySiteGroup(context.Context, string) ([]*Device, error)
+
+	// GetVMsWithConfigContext returns a list of all vms including their rendered config context.
+	GetVMsWithConfigContext(context.Context) ([]*Device, error)
+
+	// GetVMConfigContext returns the rendered config context of a vm identified by id.
+	GetVMConfigContext(context.Context, uint64) (map[string]any, error)
+
+	// GetVMsByQuery returns a list of all vms matching REST API query parameters.
+	GetVMsByQuery(context.Context, string) ([]*Device, error)
+
+	/*
+	 * organizational objects
+	 */
+
+	// GetSites returns a list of all sites.
+	GetSites(context.Context) ([]*Site, error)
+	// GetSitesByTag returns a list of all sites with a given tag.
+	GetSitesByTag(context.Context, string) ([]*Site, error)
+
+	// GetTenants returns a list of all tenants.
+	GetTenants(context.Context) ([]*Tenant, error)
+	// GetTenantsByTag returns a list of all tenants with a given tag.
+	GetTenantsByTag(context.Context, string) ([]*Tenant, error)
+
+	// GetRacks returns a list of all racks.
+	GetRacks(context.Context) ([]*Rack, error)
+	// GetRacksByTag returns a list of all racks with a given tag.
+	GetRacksByTag(context.Context, string) ([]*Rack, error)
+
+	// GetClusters returns a list of all virtualization clusters.
+	GetClusters(context.Context) ([]*Cluster, error)
+	// GetClustersByTag returns a list of all virtualization clusters with a given tag.
+	GetClustersByTag(context.Context, string) ([]*Cluster, error)
+
+	// GetRegions returns a list of all regions.
+	GetRegions(context.Context) ([]*Region, error)
+	// GetRegionsByTag returns a list of all regions with a given tag.
+	GetRegionsByTag(context.Context, string) ([]*Region, error)
+
+	// GetLocations returns a list of all locations.
+	GetLocations(context.Context) ([]*Location, error)
+	// GetLocationsByTag returns a list of all locations with a given tag.
+	GetLocationsByTag(context.Context, string) ([]*Location, error)
 
 	/*
 	 * utilities
 	 */
 
-	// SetLogger updates the instance of ClientIface with a new Logger implementation.
-	SetLogger(Logger)
+	// SetLogger updates the instance of ClientIface with a new slog.Logger.
+	SetLogger(*slog.Logger)
 	// HTTPTracing allows for enabling/disabling http request tracing.
 	HTTPTracing(bool)
+	// SetPinnedPublicKeys restricts accepted server certificates to those matching any of the given public key pins.
+	SetPinnedPublicKeys([]string) error
+	// UsePersistedQueries allows for enabling/disabling GraphQL persisted queries.
+	UsePersistedQueries(bool)
+	// SetPageSize sets the number of objects requested per page of GraphQL list queries (0 disables pagination).
+	SetPageSize(int)
+	// SetCacheTTL sets the time successful responses are cached for (0 disables caching).
+	SetCacheTTL(time.Duration)
+	// GetLastChangeID returns the ID of the most recent entry of Netbox's change log (0 when empty).
+	GetLastChangeID(context.Context) (uint64, error)
 	// Copy creates an identical copy of the Netbox client.
 	Copy() ClientIface
 	// VerifyConnectivity tries to connect to the Netbox API, read data from it and checks if this was successful. It
 	// tries to differentiate errors and return ErrInvalidToken when connectivity was okay but Netbox refused to comply
 	// because the token is not valid (no such token, missing permissions, etc).
-	VerifyConnectivity() error
+	VerifyConnectivity(context.Context) error
+	// Version returns the version of Netbox detected by VerifyConnectivity.
+	Version() string
 }
 
 // CustomFieldMap contains custom fields defined in Netbox associated with an entity (like device, interface, etc). It
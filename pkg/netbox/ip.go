@@ -18,18 +18,17 @@
 package netbox
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/netip"
 	"regexp"
+	"strconv"
 )
 
 // Values of IP status as in IP.Status.Value
 const (
-	queryIPAddressAttributes string = "id address status vrf {id, name}"
-	queryIPByAddress         string = "{ip_address_list(filters: {address: {starts_with: \"%s\"}}){" + queryIPAddressAttributes + "}}"
-	queryInterfaceIPs        string = "{ip_address_list(filters: {interface_id:\"%d\"}){" + queryIPAddressAttributes + "}}"
-	queryVirtualInterfaceIPs string = "{ip_address_list(filters: {vminterface_id:\"%d\"}){" + queryIPAddressAttributes + "}}"
+	queryIPAddressAttributes string = "id address status dns_name vrf {id, name}"
+	queryIPAddressList       string = "query($filters: IPAddressFilter, $pagination: OffsetPaginationInput){ip_address_list(filters: $filters, pagination: $pagination){" + queryIPAddressAttributes + "}}"
 )
 
 var (
@@ -42,6 +41,7 @@ type IP struct {
 	IDString string `json:"id"`
 	Address  string `json:"address"`
 	Status   string `json:"status"`
+	DNSName  string `json:"dns_name"`
 	VRF      *VRF   `json:"vrf"`
 }
 
@@ -57,98 +57,63 @@ func (ip *IP) Family() int {
 // GetIPsByAddress returns a list of netbox IP object based on a given address string (legacy IP or IPv6). This is the
 // default option to get any IP object since an address can exist multiple times in various VRFs.  The caller is
 // responslible to filter through the result to find the IP it's looking for.
-func (client *Client) GetIPsByAddress(ip string) ([]*IP, error) {
+func (client *Client) GetIPsByAddress(ctx context.Context, ip string) ([]*IP, error) {
 	var (
-		query   string = fmt.Sprintf(queryIPByAddress, ip)
-		resp    response
-		wrapper graphQLResponseWrapper
-		err     error
+		ips []*IP
+		err error
 	)
 
-	resp, err = client.graphQL(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query api: %w", err)
-	}
-
-	if resp.StatusCode() != 200 {
-		return nil, ErrUnexpectedStatusCode
-	}
+	ctx = withQueryType(ctx, "ips_by_address")
 
-	err = json.Unmarshal(resp.RawBody().Bytes(), &wrapper)
+	ips, err = client.getIPList(ctx, map[string]any{"address": map[string]any{"starts_with": ip}})
 	if err != nil {
-		client.promFailure.Inc()
-		return nil, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
+		return nil, err
 	}
 
-	if len(wrapper.Data.IPList) == 0 {
+	if len(ips) == 0 {
 		// No matching IP was found.
 		return nil, nil
 	}
 
-	// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
-	wrapper.parseIDs()
-
-	return wrapper.Data.IPList, nil
+	return ips, nil
 }
 
 // GetInterfaceIPs returns a list of all IPs associated with a given dcim interface id.
-func (client *Client) GetInterfaceIPs(id uint64) ([]*IP, error) {
-	var (
-		query   string = fmt.Sprintf(queryInterfaceIPs, id)
-		resp    response
-		wrapper graphQLResponseWrapper
-		err     error
-	)
-
-	resp, err = client.graphQL(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query api: %w", err)
-	}
+func (client *Client) GetInterfaceIPs(ctx context.Context, id uint64) ([]*IP, error) {
+	return client.getIPList(withQueryType(ctx, "interface_ips"), map[string]any{"interface_id": strconv.FormatUint(id, 10)})
+}
 
-	if resp.StatusCode() != 200 {
-		return nil, ErrUnexpectedStatusCode
-	}
+// GetVirtualInterfaceIPs returns a list of all IPs associated with a given virtual interface id.
+func (client *Client) GetVirtualInterfaceIPs(ctx context.Context, id uint64) ([]*IP, error) {
+	return client.getIPList(withQueryType(ctx, "virtual_interface_ips"), map[string]any{"vminterface_id": strconv.FormatUint(id, 10)})
+}
 
-	err = json.Unmarshal(resp.RawBody().Bytes(), &wrapper)
-	if err != nil {
-		client.promFailure.Inc()
-		return nil, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
+// GetIPsInPrefix returns a list of all IPs within the given prefix (e.g. 192.0.2.0/24) in any VRF. ErrInvalidPrefix is
+// returned when prefix is not a valid cidr.
+func (client *Client) GetIPsInPrefix(ctx context.Context, prefix string) ([]*IP, error) {
+	if _, err := netip.ParsePrefix(prefix); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPrefix, prefix)
 	}
 
-	// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
-	wrapper.parseIDs()
-
-	return wrapper.Data.IPList, nil
+	return client.getIPList(withQueryType(ctx, "ips_in_prefix"), map[string]any{"parent": []string{prefix}})
 }
 
-// GetVirtualInterfaceIPs returns a list of all IPs associated with a given virtual interface id.
-func (client *Client) GetVirtualInterfaceIPs(id uint64) ([]*IP, error) {
+// getIPList returns the list of IPs matching filters.
+func (client *Client) getIPList(ctx context.Context, filters map[string]any) ([]*IP, error) {
 	var (
-		query   string = fmt.Sprintf(queryVirtualInterfaceIPs, id)
-		resp    response
-		wrapper graphQLResponseWrapper
-		err     error
+		ips []*IP
+		err error
 	)
 
-	resp, err = client.graphQL(query)
+	err = client.graphQLList(ctx, queryIPAddressList, filters, func(wrapper *graphQLResponseWrapper) int {
+		ips = append(ips, wrapper.Data.IPList...)
+		return len(wrapper.Data.IPList)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query api: %w", err)
+		return nil, err
 	}
 
-	if resp.StatusCode() != 200 {
-		return nil, ErrUnexpectedStatusCode
-	}
-
-	err = json.Unmarshal(resp.RawBody().Bytes(), &wrapper)
-	if err != nil {
-		client.promFailure.Inc()
-		return nil, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
-	}
-
-	// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
-	wrapper.parseIDs()
-
-	return wrapper.Data.IPList, nil
+	return ips, nil
 }
 
 // ToAddr converts a given IP struct to a single IP (i.e. converting cidr to address).
@@ -18,6 +18,7 @@
 package netbox
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -132,28 +133,28 @@ func TestGetIsPByAddress(t *testing.T) {
 	client := newTestClient(t)
 
 	// simple IP without any shenanigans
-	ip, err := client.GetIPsByAddress("2001:db8::1")
+	ip, err := client.GetIPsByAddress(context.Background(), "2001:db8::1")
 	require.NoError(t, err)
 	require.NotEmpty(t, ip)
 	assert.Equal(t, []*IP{ip1}, ip)
 
 	// simple legacy IP
-	ip, err = client.GetIPsByAddress("10.0.0.2")
+	ip, err = client.GetIPsByAddress(context.Background(), "10.0.0.2")
 	require.NoError(t, err)
 	require.NotEmpty(t, ip)
 	assert.Equal(t, []*IP{ip5}, ip)
 
 	// checking for IP that doesn't exist
-	ip, err = client.GetIPsByAddress("::1")
+	ip, err = client.GetIPsByAddress(context.Background(), "::1")
 	require.NoError(t, err)
 	require.Empty(t, ip)
 
-	ip, err = client.GetIPsByAddress("127.0.0.1")
+	ip, err = client.GetIPsByAddress(context.Background(), "127.0.0.1")
 	require.NoError(t, err)
 	require.Empty(t, ip)
 
 	// check multiple VRFs
-	ip, err = client.GetIPsByAddress("2001:db8::4")
+	ip, err = client.GetIPsByAddress(context.Background(), "2001:db8::4")
 	require.NoError(t, err)
 	require.NotEmpty(t, ip)
 	assert.Equal(t, []*IP{ip7, ip8}, ip)
@@ -166,13 +167,13 @@ func TestGetInterfaceIPs(t *testing.T) {
 
 	client := newTestClient(t)
 
-	ips, err := client.GetInterfaceIPs(iface1.ID)
+	ips, err := client.GetInterfaceIPs(context.Background(), iface1.ID)
 	require.NoError(t, err)
 	require.NotEmpty(t, ips)
 	assert.Equal(t, []*IP{ip2, ip1}, ips)
 
 	// checking for interface that doesn't exist
-	ips, err = client.GetInterfaceIPs(9999)
+	ips, err = client.GetInterfaceIPs(context.Background(), 9999)
 	require.NoError(t, err)
 	require.Empty(t, ips)
 }
@@ -184,13 +185,13 @@ func TestGetVirtualInterfaceIPs(t *testing.T) {
 
 	client := newTestClient(t)
 
-	ips, err := client.GetVirtualInterfaceIPs(vIface1.ID)
+	ips, err := client.GetVirtualInterfaceIPs(context.Background(), vIface1.ID)
 	require.NoError(t, err)
 	require.NotEmpty(t, ips)
 	assert.Equal(t, []*IP{ip3, ip4}, ips)
 
 	// checking for interface that doesn't exist
-	ips, err = client.GetVirtualInterfaceIPs(9999)
+	ips, err = client.GetVirtualInterfaceIPs(context.Background(), 9999)
 	require.NoError(t, err)
 	require.Empty(t, ips)
 }
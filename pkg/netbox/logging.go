@@ -17,52 +17,35 @@
 
 package netbox
 
-import "log"
+import "log/slog"
 
-// Logger implements log related methods to forward messages to the appropriate recipient.
-type Logger interface {
-	Infof(format string, val ...interface{})
-	Errorf(format string, val ...interface{})
-	Debugf(format string, val ...interface{})
-	Tracef(format string, val ...interface{})
-}
+// LevelTrace is the level of HTTP requests and responses logged with HTTPTracing enabled. It is below slog.LevelDebug,
+// thus handlers only emit these records when configured with LevelTrace (or lower) explicitly.
+const LevelTrace slog.Level = slog.LevelDebug - 4
 
-// HTTPTracing enables or disables HTTP tracing. When enabled, the Logger's Tracef function is called and contains all
-// HTTP request and response headers and payload as well as timing information. Use with care, this will expose secrets
-// in plain text and affects performance.
+// HTTPTracing enables or disables HTTP tracing. When enabled, all HTTP request and response headers and payload as well
+// as timing information are logged with LevelTrace. Use with care, this will expose secrets in plain text and affects
+// performance. It is safe to change tracing while requests are in flight.
 func (client *Client) HTTPTracing(val bool) {
-	client.httpTracing = val
+	client.httpTracing.Store(val)
 }
 
-// SetLogger updates the Logger interface used by this Client for sending log messages. NOTE: there is no check in place
-// that validates the correctness of the interface. Logging messages might cause panics when a specific interface method
-// is not defined. Make sure all methods exist for an instance of Logger.
-func (client *Client) SetLogger(logger Logger) {
-	client.log = logger
+// SetLogger updates the slog.Logger used by this Client for sending log messages. Setting nil restores the default,
+// which is slog.Default at the time of logging, thus a logger installed with slog.SetDefault is picked up as well.
+func (client *Client) SetLogger(logger *slog.Logger) {
+	client.log.Store(logger)
 }
 
-// defaultLogger is the default implementation of the Logger interface used by this package. It only logs Info and Error
-// messages.
-//
-// NOTE: Be aware that this package updates the log.SetFlags globally.
-type defaultLogger int
-
-// Infof is the default implementation of the Logger interface.
-func (logger defaultLogger) Infof(format string, val ...interface{}) {
-	log.Printf("[netbox-go] "+format, val...)
-}
+// logger returns the slog.Logger used by this Client.
+func (client *Client) logger() *slog.Logger {
+	var (
+		logger *slog.Logger
+	)
 
-// Errorf is the default implementation of the Logger interface.
-func (logger defaultLogger) Errorf(format string, val ...interface{}) {
-	log.Printf("[netbox-go] "+format, val...)
-}
-
-// Debugf is the default implementation of the Logger interface.
-func (logger defaultLogger) Debugf(format string, val ...interface{}) {
-	log.Printf("[netbox-go] "+format, val...)
-}
+	logger = client.log.Load()
+	if logger == nil {
+		return slog.Default()
+	}
 
-// Tracef is the default implementation of the Logger interface.
-func (logger defaultLogger) Tracef(format string, val ...interface{}) {
-	log.Printf("[netbox-go] "+format, val...)
+	return logger
 }
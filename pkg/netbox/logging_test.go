@@ -0,0 +1,82 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPTracing(t *testing.T) {
+	var (
+		server *httptest.Server
+		client *Client
+		buf    bytes.Buffer
+		err    error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"netbox-version": "4.1.0"}`))
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token",
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: LevelTrace}))))
+	require.NoError(t, err)
+
+	require.NoError(t, client.VerifyConnectivity(context.Background()))
+	assert.Contains(t, buf.String(), `"msg":"http call finished","url":"/api/status/","query":"verify_connectivity"`)
+	assert.NotContains(t, buf.String(), `"msg":"http request"`)
+
+	buf.Reset()
+	client.HTTPTracing(true)
+
+	require.NoError(t, client.VerifyConnectivity(context.Background()))
+	assert.Contains(t, buf.String(), `"msg":"http request"`)
+	assert.Contains(t, buf.String(), `"msg":"http response"`)
+	assert.Contains(t, buf.String(), `netbox-version`)
+}
+
+func TestSetLogger(t *testing.T) {
+	var (
+		client *Client
+		logger *slog.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		err    error
+	)
+
+	client, err = New("http://localhost", "token")
+	require.NoError(t, err)
+
+	// without a logger, the default logger at the time of logging is used
+	assert.Same(t, slog.Default(), client.logger())
+
+	client.SetLogger(logger)
+	assert.Same(t, logger, client.logger())
+	assert.Same(t, logger, client.Copy().(*Client).logger())
+
+	client.SetLogger(nil)
+	assert.Same(t, slog.Default(), client.logger())
+}
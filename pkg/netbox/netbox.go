@@ -20,21 +20,26 @@
 //
 // This package implements the Prometheus collector interface to provide information about inner workings. For
 // normalization of metrics all new instances of Client can be created with a given namespace (see Prometheus Go library
-// for details) to attach to the existing namespace of your application. The subsystem name is always `netbox` and
-// cannot be changed.
+// for details and WithPrometheusNamespace) to attach to the existing namespace of your application. The subsystem name
+// is always `netbox_api` (see SubsystemName) and cannot be changed.
 //
 // Exported metrics:
-//   - <namespace>_netbox_status{code,url} # number of API calls by response code and relative url
-//   - <namespace>_netbox_error{url} # number of failed HTTP requests (due to network or whatever)
-//   - <namespace>_netbox_failure # number of function invocations that resulted in an error being returned
-//   - <namespace>_netbox_duration{code,url} # (last) duration it took to perform an HTTP request to Netbox by response code and url
+//   - <namespace>_netbox_api_status{code,url,query} # number of API calls by response code and relative url
+//   - <namespace>_netbox_api_error{url,query} # number of failed HTTP requests (due to network or whatever)
+//   - <namespace>_netbox_api_failure # number of function invocations that resulted in an error being returned
+//   - <namespace>_netbox_api_duration_nanoseconds{code,url,query} # (last) duration it took to perform an HTTP request
+//     to Netbox by response code and url
+//   - <namespace>_netbox_api_request_duration_seconds{code,url,query} # histogram of the duration of HTTP requests to
+//     Netbox
+//   - <namespace>_netbox_api_coalesced # number of API calls served by an identical call already in flight
+//   - <namespace>_netbox_api_retry{url,query} # number of retried HTTP requests
+//   - <namespace>_netbox_api_cache_hit # number of API calls served from the response cache
+//   - <namespace>_netbox_api_cache_miss # number of API calls not found in the response cache
 //
-// TODO: the logging stuff is probably wrong now
-// By default this package logs through the Golang standard library log package. This is obviously annoying when adding
-// this package to another project that uses it's own logging library. To easily change the log facility, this package
-// provides special Interface called `Logger` that can be implemented by other packages and registered with
-// `SetLogger()`. All log messages are then sent through the interface to the target functions that can then decide on
-// how to process the message further.
+// This package logs structured records through log/slog. By default slog.Default is used, so applications calling
+// slog.SetDefault share their logger (and its handler, level and format) with this package. A dedicated logger, e.g.
+// one carrying attributes identifying the Netbox instance, can be set with WithLogger or SetLogger. HTTP requests and
+// responses are logged with LevelTrace when enabled with HTTPTracing.
 //
 // WARNING: Most Netbox objects in this library contain a public struct attribute `IDString`. This is only used for a
 // workaround when handling GraphQL requests. DO NOT use this attribute anywhere as it will be removed once the bug has
@@ -42,14 +47,19 @@
 package netbox
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
+	"sync/atomic"
+	"time"
 
+	"github.com/Masterminds/semver"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -60,27 +70,35 @@ const SubsystemName string = "netbox_api"
 var (
 	ErrMissingURL           = errors.New("netbox url has not been provided")
 	ErrMissingToken         = errors.New("netbox token has not been provided")
+	ErrIncompatibleVersion  = errors.New("detected incompatible Netbox version")
 	ErrInvalidToken         = errors.New("provided token invalid or missing permissions")
 	ErrInvalidURL           = errors.New("provided url invalid")
+	ErrInvalidPrefix        = errors.New("provided prefix invalid")
 	ErrUnexpectedStatusCode = errors.New("received unexpected status code from netbox")
 	ErrAmbiguous            = errors.New("provided search returned more than one possible result in netbox")
+	ErrGraphQL              = errors.New("netbox returned errors for graphql query")
 )
 
-// defaultLog is an instance of defaultLogger used by this package.
-var defaultLog defaultLogger
+// latencyBuckets are the buckets of the request duration histogram in seconds. Rendering large lists (or config
+// contexts) can take Netbox considerably longer than the default buckets cover.
+var latencyBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
 
-// Client describes a Netbox API client to perform REST calls with.
+// Client describes a Netbox API client to perform REST calls with. A Client is safe for concurrent use by multiple
+// goroutines, including changing its settings while requests are in flight. The only exception is
+// SetPinnedPublicKeys, which reconfigures the shared transport and must be called before any requests are performed.
 type Client struct {
 	// URL contains the complete path to the base of Netbox's API (i.e. https://[..])
 	url string
-	// Token used for Netbox API queries.
-	token string
+	// Source of the token used for Netbox API queries.
+	tokens TokenSource
+	// User-Agent header sent with all requests, see ClientOptions.
+	userAgent string
 	// HTTP client used across this instance
 	http *http.Client
 
 	// Logging options.
-	log         Logger
-	httpTracing bool // log http requests and resposes
+	log         atomic.Pointer[slog.Logger] // nil logs through slog.Default
+	httpTracing atomic.Bool                 // log http requests and resposes
 
 	// Prometheus metrics for this instance.
 	promNamespace string
@@ -88,6 +106,36 @@ type Client struct {
 	promError     *prometheus.CounterVec
 	promFailure   prometheus.Counter
 	promDuration  *prometheus.GaugeVec
+	promLatency   *prometheus.HistogramVec
+	promCoalesced prometheus.Counter
+	promRetry     *prometheus.CounterVec
+	promCacheHit  prometheus.Counter
+	promCacheMiss prometheus.Counter
+
+	// Retry options of failed requests, see ClientOptions.
+	maxAttempts  int
+	retryBackoff time.Duration
+
+	// Requests currently in flight, used to coalesce identical requests.
+	inflight inflightRequests
+
+	// Successful responses cached for cacheTTL (nanoseconds); 0 disables caching.
+	cache    responseCache
+	cacheTTL atomic.Int64
+	// ID of the most recent change log entry seen, see GetLastChangeID.
+	lastChange atomic.Uint64
+
+	// Send GraphQL queries as persisted queries (hash only).
+	persistedQueries atomic.Bool
+
+	// Number of objects requested per page of list queries; 0 disables pagination.
+	pageSize atomic.Int64
+
+	// Version of Netbox detected by VerifyConnectivity; nil before.
+	version atomic.Pointer[semver.Version]
+
+	// Public key pinning state, shared by all copies like the transport it belongs to.
+	pinning *pinningState
 }
 
 // Value is a generic structure that is often used to define a label and value of some kind (think interface type, etc)
@@ -100,26 +148,65 @@ type Name struct {
 	Name string `json:"name"`
 }
 
+// Tag describes a Netbox tag. Filters refer to tags by their slug.
+type Tag struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
 // NetboxStatus contains details about a Netbox installation.
 type netboxStatus struct {
 	Version string `json:"netbox-version"`
 }
 
+// ClientOptions contains options of the HTTP connection of a Client (see WithClientOptions). A zero timeout disables the
+// respective timeout.
+type ClientOptions struct {
+	// TLS enables TLS for the HTTP transport when not nil.
+	TLS *TLSConfig
+	// Timeout limits the duration of a single request, including connecting and reading the response body.
+	Timeout time.Duration
+	// DialTimeout limits the duration of establishing a TCP connection.
+	DialTimeout time.Duration
+	// ResponseHeaderTimeout limits the time waiting for the response headers after the request has been written.
+	ResponseHeaderTimeout time.Duration
+	// MaxAttempts is the number of attempts of a request failing due to network errors or with a 5xx or 429 status
+	// code. Values below 2 disable retries.
+	MaxAttempts int
+	// RetryBackoff is the delay before the first retry of a request. It's doubled for every further retry unless Netbox
+	// asks for a specific delay using Retry-After.
+	RetryBackoff time.Duration
+	// Proxy is the HTTP proxy all requests are sent through. When nil, the proxy is taken from the environment
+	// (HTTP_PROXY, HTTPS_PROXY and NO_PROXY) like the standard library's default transport does.
+	Proxy *url.URL
+	// UserAgent is sent as User-Agent header with all requests, allowing Netbox operators to identify the client in their
+	// access logs. The standard library's default is sent unless set.
+	UserAgent string
+}
+
 // New creates a new Client to interact with a netbox API. baseURL must point to a valid Netbox installation (without
-// /api or /graphql at the end) while token must be a valid Netbox API key. WithTLS enabled TLS for HTTP transport while
-// tlsInsecure can be set to allow any certificate to be accepted.
+// /api or /graphql at the end) while token must be a valid Netbox API key unless a TokenSource is given with
+// WithTokenSource. Further settings like TLS, timeouts or the namespace of metrics are given as Options (see With*
+// functions). Each Client uses its own HTTP transport.
 //
-// In standard operation TLS should be used. System wide CAs are trusted.
-func New(baseURL, token, promNamespace string, withTLS bool, tlsInsecure bool) (*Client, error) {
+// In standard operation TLS should be used (see WithTLS). System wide CAs are trusted unless configured otherwise.
+func New(baseURL, token string, opts ...Option) (*Client, error) {
 	var (
-		client Client
-		err    error
+		client    Client
+		clientTLS *tls.Config
+		transport *http.Transport = http.DefaultTransport.(*http.Transport).Clone()
+		settings  options
+		option    Option
+		err       error
 	)
 
-	client.log = defaultLog
-	log.SetFlags(log.Lshortfile | log.Ldate | log.Ltime | log.Lmicroseconds)
+	for _, option = range opts {
+		option(&settings)
+	}
+
+	client.log.Store(settings.logger)
 
-	if token == "" {
+	if token == "" && settings.tokens == nil {
 		return nil, ErrMissingToken
 	}
 
@@ -129,50 +216,76 @@ func New(baseURL, token, promNamespace string, withTLS bool, tlsInsecure bool) (
 
 	_, err = url.Parse(baseURL)
 	if err != nil {
-		client.log.Errorf("given url could not be parsed: %v", err)
+		client.logger().Error("given url could not be parsed", "url", baseURL, "error", err)
 		return nil, ErrInvalidURL
 	}
 
 	client.url = baseURL
-	client.token = token
-	if withTLS {
-		client.http = &http.Client{
-			Transport: &http.Transport{
-				// Set tls certificate validation.
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: tlsInsecure},
-			},
+	client.tokens = StaticToken(token)
+	if settings.tokens != nil {
+		client.tokens = settings.tokens
+	}
+	client.userAgent = settings.client.UserAgent
+	client.maxAttempts = settings.client.MaxAttempts
+	client.retryBackoff = settings.client.RetryBackoff
+	if settings.client.TLS != nil {
+		clientTLS, err = settings.client.TLS.build()
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		client.http = http.DefaultClient
+
 	}
 
+	// the default transport might already carry a TLS config of its own
+	transport.TLSClientConfig = clientTLS
+
+	if settings.client.DialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   settings.client.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+
+	transport.ResponseHeaderTimeout = settings.client.ResponseHeaderTimeout
+
+	// the default transport already uses the proxy of the environment
+	if settings.client.Proxy != nil {
+		transport.Proxy = http.ProxyURL(settings.client.Proxy)
+	}
+
+	client.http = &http.Client{
+		Transport: transport,
+		Timeout:   settings.client.Timeout,
+	}
+	client.pinning = new(pinningState)
+
 	// Init Prometheus metrics
-	client.promNamespace = promNamespace
+	client.promNamespace = settings.promNamespace
 	client.promStatus = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Namespace:   promNamespace,
+			Namespace:   client.promNamespace,
 			Subsystem:   SubsystemName,
 			Name:        "status",
 			Help:        "number of API calls",
 			ConstLabels: nil,
 		},
-		[]string{"code", "url"},
+		[]string{"code", "url", "query"},
 	)
 
 	client.promError = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Namespace:   promNamespace,
+			Namespace:   client.promNamespace,
 			Subsystem:   SubsystemName,
 			Name:        "error",
 			Help:        "number of http calls not completed due to errors",
 			ConstLabels: nil,
 		},
-		[]string{"url"},
+		[]string{"url", "query"},
 	)
 
 	client.promFailure = prometheus.NewCounter(
 		prometheus.CounterOpts{
-			Namespace:   promNamespace,
+			Namespace:   client.promNamespace,
 			Subsystem:   SubsystemName,
 			Name:        "failure",
 			Help:        "number of unexpected errors",
@@ -181,28 +294,87 @@ func New(baseURL, token, promNamespace string, withTLS bool, tlsInsecure bool) (
 
 	client.promDuration = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Namespace:   promNamespace,
+			Namespace:   client.promNamespace,
 			Subsystem:   SubsystemName,
 			Name:        "duration_nanoseconds",
 			Help:        "duration of api call",
 			ConstLabels: nil,
 		},
-		[]string{"code", "url"},
+		[]string{"code", "url", "query"},
+	)
+
+	client.promLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   client.promNamespace,
+			Subsystem:   SubsystemName,
+			Name:        "request_duration_seconds",
+			Help:        "duration of api calls",
+			ConstLabels: nil,
+			Buckets:     latencyBuckets,
+			// native histograms are only used when enabled, classic buckets are always exposed
+			NativeHistogramBucketFactor:     settings.nativeHistogramFactor,
+			NativeHistogramMaxBucketNumber:  100,
+			NativeHistogramMinResetDuration: time.Hour,
+		},
+		[]string{"code", "url", "query"},
+	)
+
+	client.promCoalesced = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace:   client.promNamespace,
+			Subsystem:   SubsystemName,
+			Name:        "coalesced",
+			Help:        "number of api calls served by an identical call already in flight",
+			ConstLabels: nil,
+		})
+
+	client.promRetry = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   client.promNamespace,
+			Subsystem:   SubsystemName,
+			Name:        "retry",
+			Help:        "number of retried http calls",
+			ConstLabels: nil,
+		},
+		[]string{"url", "query"},
 	)
 
+	client.promCacheHit = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace:   client.promNamespace,
+			Subsystem:   SubsystemName,
+			Name:        "cache_hit",
+			Help:        "number of api calls served from the response cache",
+			ConstLabels: nil,
+		})
+
+	client.promCacheMiss = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace:   client.promNamespace,
+			Subsystem:   SubsystemName,
+			Name:        "cache_miss",
+			Help:        "number of api calls not found in the response cache",
+			ConstLabels: nil,
+		})
+
 	return &client, nil
 }
 
 // VerifyConnectivity checks connectivity towards the netbox target machine. It also checks for validity of the API
-// token. If connection and token are okay, nil is returned.
-func (client *Client) VerifyConnectivity() error {
+// token. If connection and token are okay, nil is returned. The version of Netbox is recorded (see Version) and requests
+// following are adapted to the features of this version; versions older than the minimum supported one are rejected
+// with ErrIncompatibleVersion.
+func (client *Client) VerifyConnectivity(ctx context.Context) error {
 	var (
-		resp   response
-		err    error
-		status netboxStatus
+		resp    response
+		err     error
+		status  netboxStatus
+		version *semver.Version
 	)
 
-	resp, err = client.get("/api/status/")
+	ctx = withQueryType(ctx, "verify_connectivity")
+
+	resp, err = client.get(ctx, "/api/status/")
 	if err != nil {
 		return fmt.Errorf("failed to query api: %w", err)
 	}
@@ -218,25 +390,54 @@ func (client *Client) VerifyConnectivity() error {
 		return fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
 	}
 
-	if !netboxIsCompatible(status.Version) {
-		return fmt.Errorf("detected incompatible Netbox version: v%s", status.Version)
+	version, err = parseNetboxVersion(status.Version)
+	if err != nil {
+		return err
 	}
 
+	client.version.Store(version)
+
 	return nil
 }
 
 // Copy creates and returns an identical copy of client. The http.Client is not duplicated but instead points to the
 // same http.Client used for other copies. "[..] Clients should be reused instead of created as needed [..]" as per
 // net/http docs.
+//
+// The copy shares the Prometheus metrics of client, thus requests of all copies are accounted to the same metrics and
+// only one of them must be registered. Settings (logger, page size, cache ttl, etc.) are taken over but can be changed
+// independently afterwards. Neither the response cache nor requests in flight are shared.
 func (client *Client) Copy() ClientIface {
-	// TODO: needs prometheus stuff
-	return &Client{
-		url:         client.url,
-		token:       client.token,
-		http:        client.http,
-		log:         client.log,
-		httpTracing: client.httpTracing,
+	var copied *Client
+
+	copied = &Client{
+		url:       client.url,
+		tokens:    client.tokens,
+		userAgent: client.userAgent,
+		http:      client.http,
+		// the transport is shared, thus the pinning state is as well
+		pinning:       client.pinning,
+		maxAttempts:   client.maxAttempts,
+		retryBackoff:  client.retryBackoff,
+		promNamespace: client.promNamespace,
+		promStatus:    client.promStatus,
+		promError:     client.promError,
+		promFailure:   client.promFailure,
+		promDuration:  client.promDuration,
+		promLatency:   client.promLatency,
+		promCoalesced: client.promCoalesced,
+		promRetry:     client.promRetry,
+		promCacheHit:  client.promCacheHit,
+		promCacheMiss: client.promCacheMiss,
 	}
+	copied.log.Store(client.log.Load())
+	copied.httpTracing.Store(client.httpTracing.Load())
+	copied.persistedQueries.Store(client.persistedQueries.Load())
+	copied.pageSize.Store(client.pageSize.Load())
+	copied.version.Store(client.version.Load())
+	copied.cacheTTL.Store(client.cacheTTL.Load())
+
+	return copied
 }
 
 // Describe implements the prometheus.Describe interface.
@@ -244,7 +445,12 @@ func (client *Client) Describe(ch chan<- *prometheus.Desc) {
 	client.promStatus.Describe(ch)
 	client.promError.Describe(ch)
 	client.promDuration.Describe(ch)
+	client.promLatency.Describe(ch)
 	ch <- client.promFailure.Desc()
+	ch <- client.promCoalesced.Desc()
+	client.promRetry.Describe(ch)
+	ch <- client.promCacheHit.Desc()
+	ch <- client.promCacheMiss.Desc()
 }
 
 // Collect implements the prometheus.Collect interface.
@@ -252,5 +458,10 @@ func (client *Client) Collect(ch chan<- prometheus.Metric) {
 	client.promStatus.Collect(ch)
 	client.promError.Collect(ch)
 	client.promDuration.Collect(ch)
+	client.promLatency.Collect(ch)
 	ch <- client.promFailure
+	ch <- client.promCoalesced
+	client.promRetry.Collect(ch)
+	ch <- client.promCacheHit
+	ch <- client.promCacheMiss
 }
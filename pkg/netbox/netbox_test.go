@@ -18,10 +18,20 @@
 package netbox
 
 import (
+	"context"
 	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -31,7 +41,8 @@ var (
 )
 
 func newTestClient(t *testing.T) *Client {
-	client, err := New("http://localhost:8000", "0123456789abcdef0123456789abcdef01234567", "netbox_go", false, false)
+	client, err := New("http://localhost:8000", "0123456789abcdef0123456789abcdef01234567",
+		WithPrometheusNamespace("netbox_go"))
 	require.NoError(t, err)
 	require.NotEmpty(t, client)
 
@@ -46,4 +57,199 @@ func newTestClient(t *testing.T) *Client {
 func TestNetboxIface(t *testing.T) {
 	assert.Implements(t, (*ClientIface)(nil), &Client{})
 	assert.Implements(t, (*prometheus.Collector)(nil), &Client{})
+	assert.Implements(t, (*ClientIface)(nil), &RESTClient{})
+}
+
+func TestNewOptions(t *testing.T) {
+	var (
+		server  *httptest.Server
+		release chan struct{} = make(chan struct{})
+		client  *Client
+		err     error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err = New(server.URL, "token", WithTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	assert.ErrorIs(t, client.VerifyConnectivity(context.Background()), context.DeadlineExceeded)
+
+	client, err = New(server.URL, "token", WithResponseHeaderTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	assert.ErrorContains(t, client.VerifyConnectivity(context.Background()), "timeout awaiting response headers")
+
+	// each client has its own transport
+	assert.NotSame(t, client.http.Transport, http.DefaultTransport)
+}
+
+func TestNewSettings(t *testing.T) {
+	var (
+		client *Client
+		logger *slog.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		err    error
+	)
+
+	client, err = New("http://localhost", "token",
+		WithPrometheusNamespace("netbox_go"),
+		WithLogger(logger),
+		WithClientOptions(ClientOptions{MaxAttempts: 5, RetryBackoff: time.Second, Timeout: time.Minute}),
+		WithRetries(2, time.Millisecond))
+	require.NoError(t, err)
+
+	assert.Equal(t, "netbox_go", client.promNamespace)
+	assert.Same(t, logger, client.logger())
+	assert.Equal(t, 2, client.maxAttempts)
+	assert.Equal(t, time.Millisecond, client.retryBackoff)
+	assert.Equal(t, time.Minute, client.http.Timeout)
+
+	_, err = New("", "token")
+	assert.ErrorIs(t, err, ErrMissingURL)
+
+	_, err = New("http://localhost", "")
+	assert.ErrorIs(t, err, ErrMissingToken)
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var (
+		server   *httptest.Server
+		client   *Client
+		registry *prometheus.Registry
+		families []*dto.MetricFamily
+		family   *dto.MetricFamily
+		found    *dto.MetricFamily
+		native   bool
+		err      error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"netbox-version": "4.1.0"}`))
+	}))
+	defer server.Close()
+
+	for _, native = range []bool{false, true} {
+		client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"), WithNativeHistograms(native))
+		require.NoError(t, err)
+		require.NoError(t, client.VerifyConnectivity(context.Background()))
+		require.NoError(t, client.VerifyConnectivity(context.Background()))
+
+		registry = prometheus.NewPedanticRegistry()
+		require.NoError(t, registry.Register(client))
+
+		families, err = registry.Gather()
+		require.NoError(t, err)
+
+		found = nil
+		for _, family = range families {
+			if family.GetName() == "netbox_go_netbox_api_request_duration_seconds" {
+				found = family
+			}
+		}
+
+		require.NotNil(t, found)
+		require.Len(t, found.GetMetric(), 1)
+		assert.Equal(t, uint64(2), found.GetMetric()[0].GetHistogram().GetSampleCount())
+		assert.Len(t, found.GetMetric()[0].GetHistogram().GetBucket(), len(latencyBuckets))
+		assert.Equal(t, native, found.GetMetric()[0].GetHistogram().Schema != nil)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	var (
+		server *httptest.Server
+		client *Client
+		copied ClientIface
+		wg     sync.WaitGroup
+		err    error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"netbox-version": "4.1.0"}`))
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+	client.SetPageSize(100)
+
+	copied = client.Copy()
+	assert.Equal(t, 100, copied.(*Client).listPageSize())
+
+	// settings are independent after copying
+	copied.SetPageSize(10)
+	assert.Equal(t, 100, client.listPageSize())
+
+	// copies are used concurrently and account to the same metrics
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(api ClientIface) {
+			defer wg.Done()
+			assert.NoError(t, api.VerifyConnectivity(context.Background()))
+		}(copied.Copy())
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, float64(10), testutil.ToFloat64(client.promStatus))
+	assert.Equal(t, 1, testutil.CollectAndCount(copied, "netbox_go_netbox_api_status"))
+}
+
+func TestUserAgent(t *testing.T) {
+	var (
+		server *httptest.Server
+		client *Client
+		agents []string
+		err    error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.UserAgent())
+		w.Write([]byte(`{"netbox-version": "4.1.0", "data": {}}`))
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithUserAgent("netbox_sd/1.2.3 team-monitoring"))
+	require.NoError(t, err)
+	require.NoError(t, client.VerifyConnectivity(context.Background()))
+	_, err = client.graphQL(context.Background(), "query{status}", nil)
+	require.NoError(t, err)
+
+	// the standard library's default without one
+	client, err = New(server.URL, "token")
+	require.NoError(t, err)
+	require.NoError(t, client.VerifyConnectivity(context.Background()))
+
+	require.Len(t, agents, 3)
+	assert.Equal(t, "netbox_sd/1.2.3 team-monitoring", agents[0])
+	assert.Equal(t, "netbox_sd/1.2.3 team-monitoring", agents[1])
+	assert.Equal(t, "Go-http-client/1.1", agents[2])
+}
+
+func TestNewProxy(t *testing.T) {
+	var (
+		proxy   *httptest.Server
+		proxied []string
+		client  *Client
+		err     error
+	)
+
+	// the proxy answers all requests itself
+	proxy = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Write([]byte(`{"netbox-version": "4.1.0"}`))
+	}))
+	defer proxy.Close()
+
+	client, err = New("http://netbox.invalid", "token",
+		WithProxy(&url.URL{Scheme: "http", Host: proxy.Listener.Addr().String()}))
+	require.NoError(t, err)
+	require.NoError(t, client.VerifyConnectivity(context.Background()))
+	assert.Equal(t, []string{"http://netbox.invalid/api/status/"}, proxied)
 }
@@ -0,0 +1,479 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package netboxtest provides a fake Netbox server for testing code that uses the netbox package without a running
+// Netbox installation. The content of the fake server is described by Fixtures which can be loaded from YAML files,
+// making it easy to reproduce a specific topology (e.g. as part of a bug report).
+//
+// Only the subset of Netbox's API used by netbox_sd is implemented.
+//
+// Tests not concerned with HTTP at all can use Mock, an in-memory implementation of netbox.ClientIface returning
+// whatever the test's functions return.
+package netboxtest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Errors returned when loading fixtures.
+var (
+	ErrReadingFixtures = errors.New("failed to read fixtures file")
+	ErrParsingFixtures = errors.New("failed to parse fixtures")
+	ErrBadReference    = errors.New("fixture references unknown object")
+	ErrDuplicateID     = errors.New("fixture id used more than once")
+)
+
+// Fixtures describes all objects known to the fake Netbox server.
+//
+// Example:
+//
+//	version: 4.1.0
+//	devices:
+//	  - id: 1
+//	    name: device-A
+//	    status: active
+//	    site: site-A
+//	    tags: [node_exporter]
+//	    custom_fields:
+//	      foo: bar
+//	    primary_ip6: 2001:db8::1/64
+//	ip_addresses:
+//	  - id: 1
+//	    address: 2001:db8::1/64
+//	    status: active
+type Fixtures struct {
+	// Version is the Netbox version reported by the fake server. Defaults to DefaultVersion.
+	Version      string         `yaml:"version"`
+	Devices      []*Device      `yaml:"devices"`
+	VMs          []*Device      `yaml:"virtual_machines"`
+	Interfaces   []*Interface   `yaml:"interfaces"`
+	IPAddresses  []*IP          `yaml:"ip_addresses"`
+	Services     []*Service     `yaml:"services"`
+	VLANs        []*VLAN        `yaml:"vlans"`
+	WirelessLANs []*WirelessLAN `yaml:"wireless_lans"`
+	VDCs         []*VDC         `yaml:"virtual_device_contexts"`
+	Clusters     []*Cluster     `yaml:"clusters"`
+	Sites        []*Site        `yaml:"sites"`
+}
+
+// Device describes a device or virtual machine. Rack, SerialNumber, AssetTag and Manufacturer are ignored for virtual
+// machines while Cluster is ignored for devices.
+type Device struct {
+	ID           uint64 `yaml:"id"`
+	Name         string `yaml:"name"`
+	Status       string `yaml:"status"`
+	Rack         string `yaml:"rack"`
+	Site         string `yaml:"site"`
+	Role         string `yaml:"role"`
+	Tenant       string `yaml:"tenant"`
+	Platform     string `yaml:"platform"`
+	SerialNumber string `yaml:"serial"`
+	AssetTag     string `yaml:"asset_tag"`
+	// Manufacturer is the slug of the device type's manufacturer; only used for devices.
+	Manufacturer string `yaml:"manufacturer"`
+	// ConfigContext is the rendered config context.
+	ConfigContext map[string]any `yaml:"config_context"`
+	// Cluster is the name of the virtualization cluster; only used for virtual machines.
+	Cluster      string         `yaml:"cluster"`
+	Tags         []string       `yaml:"tags"`
+	CustomFields map[string]any `yaml:"custom_fields"`
+	// PrimaryIP4 and PrimaryIP6 reference an entry of Fixtures.IPAddresses by address.
+	PrimaryIP4 string `yaml:"primary_ip4"`
+	PrimaryIP6 string `yaml:"primary_ip6"`
+}
+
+// Interface describes a device or virtual machine interface. Exactly one of Device or VM must reference an existing
+// device or virtual machine by name.
+type Interface struct {
+	ID           uint64         `yaml:"id"`
+	Name         string         `yaml:"name"`
+	Enabled      *bool          `yaml:"enabled"`
+	Device       string         `yaml:"device"`
+	VM           string         `yaml:"virtual_machine"`
+	Tags         []string       `yaml:"tags"`
+	CustomFields map[string]any `yaml:"custom_fields"`
+	// Mode is the 802.1Q mode (access, tagged or tagged-all).
+	Mode string `yaml:"mode"`
+	// UntaggedVLAN and TaggedVLANs reference entries of Fixtures.VLANs by id.
+	UntaggedVLAN uint64   `yaml:"untagged_vlan"`
+	TaggedVLANs  []uint64 `yaml:"tagged_vlans"`
+	// WirelessLANs references entries of Fixtures.WirelessLANs by id. Only device interfaces can be attached to
+	// wireless LANs.
+	WirelessLANs []uint64 `yaml:"wireless_lans"`
+}
+
+// VDC describes a virtual device context. Device must reference an existing device by name.
+type VDC struct {
+	ID           uint64         `yaml:"id"`
+	Name         string         `yaml:"name"`
+	Device       string         `yaml:"device"`
+	Identifier   *int           `yaml:"identifier"`
+	Status       string         `yaml:"status"`
+	Tenant       string         `yaml:"tenant"`
+	Tags         []string       `yaml:"tags"`
+	CustomFields map[string]any `yaml:"custom_fields"`
+	// PrimaryIP4 and PrimaryIP6 reference an entry of Fixtures.IPAddresses by address.
+	PrimaryIP4 string `yaml:"primary_ip4"`
+	PrimaryIP6 string `yaml:"primary_ip6"`
+}
+
+// Cluster describes a virtualization cluster. Virtual machines reference clusters by name; clusters are only needed
+// when filtering by cluster group.
+type Cluster struct {
+	Name string `yaml:"name"`
+	// Group is the slug of the cluster's cluster group.
+	Group string `yaml:"group"`
+}
+
+// Site describes a site. Devices and virtual machines reference sites by name; sites are only needed when filtering by
+// site group.
+type Site struct {
+	Name string `yaml:"name"`
+	// Groups contains the slugs of the site's site group and all its parent site groups.
+	Groups []string `yaml:"groups"`
+}
+
+// VLAN describes a vlan. Group and Site are names.
+type VLAN struct {
+	ID     uint64   `yaml:"id"`
+	VID    uint16   `yaml:"vid"`
+	Name   string   `yaml:"name"`
+	Status string   `yaml:"status"`
+	Group  string   `yaml:"group"`
+	Site   string   `yaml:"site"`
+	Tenant string   `yaml:"tenant"`
+	Tags   []string `yaml:"tags"`
+}
+
+// WirelessLAN describes a wireless LAN.
+type WirelessLAN struct {
+	ID   uint64 `yaml:"id"`
+	SSID string `yaml:"ssid"`
+}
+
+// IP describes an IP address. Interface optionally references an entry of Fixtures.Interfaces by id.
+type IP struct {
+	ID        uint64 `yaml:"id"`
+	Address   string `yaml:"address"`
+	Status    string `yaml:"status"`
+	DNSName   string `yaml:"dns_name"`
+	VRF       string `yaml:"vrf"`
+	Interface uint64 `yaml:"interface"`
+}
+
+// Service describes a service. Exactly one of Device or VM must reference an existing device or virtual machine by
+// name while IPAddresses reference entries of Fixtures.IPAddresses by address.
+type Service struct {
+	ID           uint64         `yaml:"id"`
+	Name         string         `yaml:"name"`
+	Device       string         `yaml:"device"`
+	VM           string         `yaml:"virtual_machine"`
+	Ports        []int          `yaml:"ports"`
+	Protocol     string         `yaml:"protocol"`
+	IPAddresses  []string       `yaml:"ip_addresses"`
+	CustomFields map[string]any `yaml:"custom_fields"`
+}
+
+// DefaultVersion is the Netbox version reported when fixtures don't define one.
+const DefaultVersion string = "4.1.0"
+
+// LoadFixtures reads fixtures from a YAML file.
+func LoadFixtures(file string) (*Fixtures, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	data, err = os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrReadingFixtures, err.Error())
+	}
+
+	return ParseFixtures(data)
+}
+
+// ParseFixtures parses YAML formatted fixtures and validates all references between objects.
+func ParseFixtures(data []byte) (*Fixtures, error) {
+	var (
+		fixtures Fixtures
+		err      error
+	)
+
+	err = yaml.Unmarshal(data, &fixtures)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrParsingFixtures, err.Error())
+	}
+
+	err = fixtures.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fixtures, nil
+}
+
+// Validate sets defaults and checks that all references between objects can be resolved.
+func (f *Fixtures) Validate() error {
+	var (
+		dev   *Device
+		iface *Interface
+		ip    *IP
+		serv  *Service
+		vlan  *VLAN
+		wlan  *WirelessLAN
+		vdc   *VDC
+		addr  string
+		id    uint64
+		ids   map[string]map[uint64]bool = map[string]map[uint64]bool{
+			"device": {}, "vm": {}, "interface": {}, "ip": {}, "service": {}, "vlan": {}, "vdc": {}, "wlan": {},
+		}
+	)
+
+	if f.Version == "" {
+		f.Version = DefaultVersion
+	}
+
+	unique := func(kind string, id uint64) error {
+		if ids[kind][id] {
+			return fmt.Errorf("%w: %s %d", ErrDuplicateID, kind, id)
+		}
+
+		ids[kind][id] = true
+		return nil
+	}
+
+	for _, ip = range f.IPAddresses {
+		if err := unique("ip", ip.ID); err != nil {
+			return err
+		}
+
+		if ip.Status == "" {
+			ip.Status = "active"
+		}
+	}
+
+	for _, dev = range append(append([]*Device{}, f.Devices...), f.VMs...) {
+		if dev.Status == "" {
+			dev.Status = "active"
+		}
+
+		for _, addr = range []string{dev.PrimaryIP4, dev.PrimaryIP6} {
+			if addr != "" && f.ipByAddress(addr) == nil {
+				return fmt.Errorf("%w: ip %s of %s", ErrBadReference, addr, dev.Name)
+			}
+		}
+	}
+
+	for _, dev = range f.Devices {
+		if err := unique("device", dev.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, dev = range f.VMs {
+		if err := unique("vm", dev.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, vdc = range f.VDCs {
+		if err := unique("vdc", vdc.ID); err != nil {
+			return err
+		}
+
+		if vdc.Status == "" {
+			vdc.Status = "active"
+		}
+
+		if f.device(vdc.Device) == nil {
+			return fmt.Errorf("%w: device of vdc %d", ErrBadReference, vdc.ID)
+		}
+
+		for _, addr = range []string{vdc.PrimaryIP4, vdc.PrimaryIP6} {
+			if addr != "" && f.ipByAddress(addr) == nil {
+				return fmt.Errorf("%w: ip %s of %s", ErrBadReference, addr, vdc.Name)
+			}
+		}
+	}
+
+	for _, vlan = range f.VLANs {
+		if err := unique("vlan", vlan.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, wlan = range f.WirelessLANs {
+		if err := unique("wlan", wlan.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, iface = range f.Interfaces {
+		if err := unique("interface", iface.ID); err != nil {
+			return err
+		}
+
+		for _, id = range iface.WirelessLANs {
+			if f.wirelessLAN(id) == nil || iface.Device == "" {
+				return fmt.Errorf("%w: wireless lan %d of interface %d", ErrBadReference, id, iface.ID)
+			}
+		}
+
+		for _, id = range append([]uint64{iface.UntaggedVLAN}, iface.TaggedVLANs...) {
+			if id != 0 && f.vlan(id) == nil {
+				return fmt.Errorf("%w: vlan %d of interface %d", ErrBadReference, id, iface.ID)
+			}
+		}
+
+		if iface.Enabled == nil {
+			iface.Enabled = new(bool)
+			*iface.Enabled = true
+		}
+
+		if (iface.Device == "") == (iface.VM == "") ||
+			(iface.Device != "" && f.device(iface.Device) == nil) ||
+			(iface.VM != "" && f.vm(iface.VM) == nil) {
+			return fmt.Errorf("%w: parent of interface %d", ErrBadReference, iface.ID)
+		}
+	}
+
+	for _, ip = range f.IPAddresses {
+		if ip.Interface != 0 && f.iface(ip.Interface) == nil {
+			return fmt.Errorf("%w: interface of ip %s", ErrBadReference, ip.Address)
+		}
+	}
+
+	for _, serv = range f.Services {
+		if err := unique("service", serv.ID); err != nil {
+			return err
+		}
+
+		if serv.Protocol == "" {
+			serv.Protocol = "tcp"
+		}
+
+		if (serv.Device == "") == (serv.VM == "") ||
+			(serv.Device != "" && f.device(serv.Device) == nil) ||
+			(serv.VM != "" && f.vm(serv.VM) == nil) {
+			return fmt.Errorf("%w: parent of service %d", ErrBadReference, serv.ID)
+		}
+
+		for _, addr = range serv.IPAddresses {
+			if f.ipByAddress(addr) == nil {
+				return fmt.Errorf("%w: ip %s of service %d", ErrBadReference, addr, serv.ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// device returns the device identified by name or nil.
+func (f *Fixtures) device(name string) *Device {
+	for i := range f.Devices {
+		if f.Devices[i].Name == name {
+			return f.Devices[i]
+		}
+	}
+
+	return nil
+}
+
+// vm returns the virtual machine identified by name or nil.
+func (f *Fixtures) vm(name string) *Device {
+	for i := range f.VMs {
+		if f.VMs[i].Name == name {
+			return f.VMs[i]
+		}
+	}
+
+	return nil
+}
+
+// iface returns the interface identified by id or nil.
+func (f *Fixtures) iface(id uint64) *Interface {
+	for i := range f.Interfaces {
+		if f.Interfaces[i].ID == id {
+			return f.Interfaces[i]
+		}
+	}
+
+	return nil
+}
+
+// clusterGroup returns the cluster group slug of the cluster identified by name or an empty string.
+func (f *Fixtures) clusterGroup(name string) string {
+	for i := range f.Clusters {
+		if f.Clusters[i].Name == name {
+			return f.Clusters[i].Group
+		}
+	}
+
+	return ""
+}
+
+// inSiteGroup returns true when the site identified by name is part of the site group identified by slug.
+func (f *Fixtures) inSiteGroup(name, slug string) bool {
+	for i := range f.Sites {
+		if f.Sites[i].Name == name {
+			for _, group := range f.Sites[i].Groups {
+				if group == slug {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// vlan returns the vlan identified by id or nil.
+func (f *Fixtures) vlan(id uint64) *VLAN {
+	for i := range f.VLANs {
+		if f.VLANs[i].ID == id {
+			return f.VLANs[i]
+		}
+	}
+
+	return nil
+}
+
+// wirelessLAN returns the wireless LAN identified by id or nil.
+func (f *Fixtures) wirelessLAN(id uint64) *WirelessLAN {
+	for i := range f.WirelessLANs {
+		if f.WirelessLANs[i].ID == id {
+			return f.WirelessLANs[i]
+		}
+	}
+
+	return nil
+}
+
+// ipByAddress returns the ip identified by address or nil.
+func (f *Fixtures) ipByAddress(address string) *IP {
+	for i := range f.IPAddresses {
+		if f.IPAddresses[i].Address == address {
+			return f.IPAddresses[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netboxtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	var (
+		fixtures *Fixtures
+		err      error
+	)
+
+	fixtures, err = LoadFixtures("testdata/fixtures.yml")
+	require.Nil(t, err)
+	assert.Equal(t, "4.1.0", fixtures.Version)
+	assert.Len(t, fixtures.Devices, 2)
+	assert.Len(t, fixtures.VMs, 1)
+	assert.Len(t, fixtures.Interfaces, 2)
+	assert.Len(t, fixtures.IPAddresses, 5)
+	assert.Len(t, fixtures.Services, 1)
+
+	// defaults
+	assert.Equal(t, "active", fixtures.Devices[0].Status)
+	assert.Equal(t, "offline", fixtures.Devices[1].Status)
+	assert.True(t, *fixtures.Interfaces[0].Enabled)
+	assert.False(t, *fixtures.Interfaces[1].Enabled)
+	assert.Equal(t, "tcp", fixtures.Services[0].Protocol)
+
+	_, err = LoadFixtures("testdata/doesNotExist.yml")
+	assert.ErrorIs(t, err, ErrReadingFixtures)
+
+	_, err = LoadFixtures("testdata/badReference.yml")
+	assert.ErrorIs(t, err, ErrBadReference)
+
+	_, err = LoadFixtures("testdata/duplicateID.yml")
+	assert.ErrorIs(t, err, ErrDuplicateID)
+
+	_, err = ParseFixtures([]byte("devices: foo"))
+	assert.ErrorIs(t, err, ErrParsingFixtures)
+}
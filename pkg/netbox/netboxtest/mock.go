@@ -0,0 +1,819 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netboxtest
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Mock is an in-memory implementation of netbox.ClientIface for unit tests that don't need a (fake) Netbox server at
+// all. Each method calls the function stored in the field named after it with a Func suffix, e.g. GetDevicesByTag
+// calls GetDevicesByTagFunc. Methods whose function is nil return zero values, i.e. empty results without an error.
+// All calls are recorded and can be inspected with Calls. Copy returns the mock itself, thus calls on copies are
+// recorded as well. A Mock is safe for concurrent use as long as its functions are not changed while in use.
+//
+// Example:
+//
+//	mock := &netboxtest.Mock{
+//		GetDevicesByTagFunc: func(ctx context.Context, tag string) ([]*netbox.Device, error) {
+//			return []*netbox.Device{{Name: "device-A", Status: netbox.StatusDeviceActive}}, nil
+//		},
+//	}
+type Mock struct {
+	GetDeviceFunc                   func(context.Context, uint64) (*netbox.Device, error)
+	GetDevicesFunc                  func(context.Context) ([]*netbox.Device, error)
+	GetDevicesByTagFunc             func(context.Context, string) ([]*netbox.Device, error)
+	GetDevicesByTagFilteredFunc     func(context.Context, string, *netbox.DeviceFilter) ([]*netbox.Device, error)
+	GetDevicesByManufacturerFunc    func(context.Context, string) ([]*netbox.Device, error)
+	GetDevicesBySiteGroupFunc       func(context.Context, string) ([]*netbox.Device, error)
+	GetDevicesWithConfigContextFunc func(context.Context) ([]*netbox.Device, error)
+	GetDeviceConfigContextFunc      func(context.Context, uint64) (map[string]any, error)
+	GetDevicesByQueryFunc           func(context.Context, string) ([]*netbox.Device, error)
+	GetDevicesBatchFunc             func(context.Context, []netbox.DeviceQuery) ([][]*netbox.Device, error)
+	ForEachDeviceFunc               func(context.Context, netbox.DeviceQuery, func(*netbox.Device) error) error
+	GetInterfaceFunc                func(context.Context, uint64) (*netbox.Interface, error)
+	GetInterfacesByTagFunc          func(context.Context, string) ([]*netbox.Interface, error)
+	GetVirtualInterfaceFunc         func(context.Context, uint64) (*netbox.Interface, error)
+	GetVirtualInterfacesByTagFunc   func(context.Context, string) ([]*netbox.Interface, error)
+	GetInterfacesByVLANFunc         func(context.Context, uint64) ([]*netbox.Interface, error)
+	GetVirtualInterfacesByVLANFunc  func(context.Context, uint64) ([]*netbox.Interface, error)
+	GetInterfacesByWirelessLANFunc  func(context.Context, uint64) ([]*netbox.Interface, error)
+	GetIPsByAddressFunc             func(context.Context, string) ([]*netbox.IP, error)
+	GetInterfaceIPsFunc             func(context.Context, uint64) ([]*netbox.IP, error)
+	GetVirtualInterfaceIPsFunc      func(context.Context, uint64) ([]*netbox.IP, error)
+	GetIPsInPrefixFunc              func(context.Context, string) ([]*netbox.IP, error)
+	GetPrefixesFunc                 func(context.Context, *netbox.PrefixFilter) ([]*netbox.Prefix, error)
+	GetServicesFunc                 func(context.Context) ([]*netbox.Service, error)
+	GetServicesByNameFunc           func(context.Context, string) ([]*netbox.Service, error)
+	GetVLANsFunc                    func(context.Context) ([]*netbox.VLAN, error)
+	GetVLANsByVIDFunc               func(context.Context, uint16) ([]*netbox.VLAN, error)
+	GetVLANsByNameFunc              func(context.Context, string) ([]*netbox.VLAN, error)
+	GetWirelessLANsBySSIDFunc       func(context.Context, string) ([]*netbox.WirelessLAN, error)
+	GetVDCsByTagFunc                func(context.Context, string) ([]*netbox.VDC, error)
+	GetVMFunc                       func(context.Context, uint64) (*netbox.Device, error)
+	GetVMsFunc                      func(context.Context) ([]*netbox.Device, error)
+	GetVMsByTagFunc                 func(context.Context, string) ([]*netbox.Device, error)
+	GetVMsByTagFilteredFunc         func(context.Context, string, *netbox.DeviceFilter) ([]*netbox.Device, error)
+	GetVMsByClusterFunc             func(context.Context, string) ([]*netbox.Device, error)
+	GetVMsByClusterGroupFunc        func(context.Context, string) ([]*netbox.Device, error)
+	GetVMsBySiteGroupFunc           func(context.Context, string) ([]*netbox.Device, error)
+	GetVMsWithConfigContextFunc     func(context.Context) ([]*netbox.Device, error)
+	GetVMConfigContextFunc          func(context.Context, uint64) (map[string]any, error)
+	GetVMsByQueryFunc               func(context.Context, string) ([]*netbox.Device, error)
+	GetSitesFunc                    func(context.Context) ([]*netbox.Site, error)
+	GetSitesByTagFunc               func(context.Context, string) ([]*netbox.Site, error)
+	GetTenantsFunc                  func(context.Context) ([]*netbox.Tenant, error)
+	GetTenantsByTagFunc             func(context.Context, string) ([]*netbox.Tenant, error)
+	GetRacksFunc                    func(context.Context) ([]*netbox.Rack, error)
+	GetRacksByTagFunc               func(context.Context, string) ([]*netbox.Rack, error)
+	GetClustersFunc                 func(context.Context) ([]*netbox.Cluster, error)
+	GetClustersByTagFunc            func(context.Context, string) ([]*netbox.Cluster, error)
+	GetRegionsFunc                  func(context.Context) ([]*netbox.Region, error)
+	GetRegionsByTagFunc             func(context.Context, string) ([]*netbox.Region, error)
+	GetLocationsFunc                func(context.Context) ([]*netbox.Location, error)
+	GetLocationsByTagFunc           func(context.Context, string) ([]*netbox.Location, error)
+	SetLoggerFunc                   func(*slog.Logger)
+	HTTPTracingFunc                 func(bool)
+	SetPinnedPublicKeysFunc         func([]string) error
+	UsePersistedQueriesFunc         func(bool)
+	SetPageSizeFunc                 func(int)
+	SetCacheTTLFunc                 func(time.Duration)
+	GetLastChangeIDFunc             func(context.Context) (uint64, error)
+	VerifyConnectivityFunc          func(context.Context) error
+	VersionFunc                     func() string
+
+	mu    sync.Mutex
+	calls []string
+}
+
+// Calls returns the names of all methods called so far, in order.
+func (m *Mock) Calls() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.calls...)
+}
+
+// record adds a call of method to the list of calls.
+func (m *Mock) record(method string) {
+	m.mu.Lock()
+	m.calls = append(m.calls, method)
+	m.mu.Unlock()
+}
+
+// Describe implements prometheus.Collector. The mock doesn't provide any metrics.
+func (m *Mock) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector. The mock doesn't provide any metrics.
+func (m *Mock) Collect(chan<- prometheus.Metric) {}
+
+// Copy implements netbox.ClientIface by returning the mock itself.
+func (m *Mock) Copy() netbox.ClientIface {
+	m.record("Copy")
+
+	return m
+}
+
+// GetDevice implements netbox.ClientIface.
+func (m *Mock) GetDevice(ctx context.Context, id uint64) (*netbox.Device, error) {
+	m.record("GetDevice")
+
+	if m.GetDeviceFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetDeviceFunc(ctx, id)
+}
+
+// GetDevices implements netbox.ClientIface.
+func (m *Mock) GetDevices(ctx context.Context) ([]*netbox.Device, error) {
+	m.record("GetDevices")
+
+	if m.GetDevicesFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetDevicesFunc(ctx)
+}
+
+// GetDevicesByTag implements netbox.ClientIface.
+func (m *Mock) GetDevicesByTag(ctx context.Context, tag string) ([]*netbox.Device, error) {
+	m.record("GetDevicesByTag")
+
+	if m.GetDevicesByTagFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetDevicesByTagFunc(ctx, tag)
+}
+
+// GetDevicesByTagFiltered implements netbox.ClientIface.
+func (m *Mock) GetDevicesByTagFiltered(ctx context.Context, tag string, filter *netbox.DeviceFilter) (
+	[]*netbox.Device, error) {
+	m.record("GetDevicesByTagFiltered")
+
+	if m.GetDevicesByTagFilteredFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetDevicesByTagFilteredFunc(ctx, tag, filter)
+}
+
+// GetDevicesByManufacturer implements netbox.ClientIface.
+func (m *Mock) GetDevicesByManufacturer(ctx context.Context, manufacturer string) ([]*netbox.Device, error) {
+	m.record("GetDevicesByManufacturer")
+
+	if m.GetDevicesByManufacturerFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetDevicesByManufacturerFunc(ctx, manufacturer)
+}
+
+// GetDevicesBySiteGroup implements netbox.ClientIface.
+func (m *Mock) GetDevicesBySiteGroup(ctx context.Context, group string) ([]*netbox.Device, error) {
+	m.record("GetDevicesBySiteGroup")
+
+	if m.GetDevicesBySiteGroupFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetDevicesBySiteGroupFunc(ctx, group)
+}
+
+// GetDevicesWithConfigContext implements netbox.ClientIface.
+func (m *Mock) GetDevicesWithConfigContext(ctx context.Context) ([]*netbox.Device, error) {
+	m.record("GetDevicesWithConfigContext")
+
+	if m.GetDevicesWithConfigContextFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetDevicesWithConfigContextFunc(ctx)
+}
+
+// GetDeviceConfigContext implements netbox.ClientIface.
+func (m *Mock) GetDeviceConfigContext(ctx context.Context, id uint64) (map[string]any, error) {
+	m.record("GetDeviceConfigContext")
+
+	if m.GetDeviceConfigContextFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetDeviceConfigContextFunc(ctx, id)
+}
+
+// GetDevicesByQuery implements netbox.ClientIface.
+func (m *Mock) GetDevicesByQuery(ctx context.Context, query string) ([]*netbox.Device, error) {
+	m.record("GetDevicesByQuery")
+
+	if m.GetDevicesByQueryFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetDevicesByQueryFunc(ctx, query)
+}
+
+// GetDevicesBatch implements netbox.ClientIface.
+func (m *Mock) GetDevicesBatch(ctx context.Context, queries []netbox.DeviceQuery) ([][]*netbox.Device, error) {
+	m.record("GetDevicesBatch")
+
+	if m.GetDevicesBatchFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetDevicesBatchFunc(ctx, queries)
+}
+
+// ForEachDevice implements netbox.ClientIface. Without ForEachDeviceFunc, fn is called for each device returned by
+// GetDevicesBatch for query.
+func (m *Mock) ForEachDevice(ctx context.Context, query netbox.DeviceQuery, fn func(*netbox.Device) error) error {
+	var (
+		lists  [][]*netbox.Device
+		device *netbox.Device
+		err    error
+	)
+
+	m.record("ForEachDevice")
+
+	if m.ForEachDeviceFunc != nil {
+		return m.ForEachDeviceFunc(ctx, query, fn)
+	}
+
+	lists, err = m.GetDevicesBatch(ctx, []netbox.DeviceQuery{query})
+	if err != nil || len(lists) == 0 {
+		return err
+	}
+
+	for _, device = range lists[0] {
+		err = fn(device)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetInterface implements netbox.ClientIface.
+func (m *Mock) GetInterface(ctx context.Context, id uint64) (*netbox.Interface, error) {
+	m.record("GetInterface")
+
+	if m.GetInterfaceFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetInterfaceFunc(ctx, id)
+}
+
+// GetInterfacesByTag implements netbox.ClientIface.
+func (m *Mock) GetInterfacesByTag(ctx context.Context, tag string) ([]*netbox.Interface, error) {
+	m.record("GetInterfacesByTag")
+
+	if m.GetInterfacesByTagFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetInterfacesByTagFunc(ctx, tag)
+}
+
+// GetVirtualInterface implements netbox.ClientIface.
+func (m *Mock) GetVirtualInterface(ctx context.Context, id uint64) (*netbox.Interface, error) {
+	m.record("GetVirtualInterface")
+
+	if m.GetVirtualInterfaceFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVirtualInterfaceFunc(ctx, id)
+}
+
+// GetVirtualInterfacesByTag implements netbox.ClientIface.
+func (m *Mock) GetVirtualInterfacesByTag(ctx context.Context, tag string) ([]*netbox.Interface, error) {
+	m.record("GetVirtualInterfacesByTag")
+
+	if m.GetVirtualInterfacesByTagFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVirtualInterfacesByTagFunc(ctx, tag)
+}
+
+// GetInterfacesByVLAN implements netbox.ClientIface.
+func (m *Mock) GetInterfacesByVLAN(ctx context.Context, vlan uint64) ([]*netbox.Interface, error) {
+	m.record("GetInterfacesByVLAN")
+
+	if m.GetInterfacesByVLANFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetInterfacesByVLANFunc(ctx, vlan)
+}
+
+// GetVirtualInterfacesByVLAN implements netbox.ClientIface.
+func (m *Mock) GetVirtualInterfacesByVLAN(ctx context.Context, vlan uint64) ([]*netbox.Interface, error) {
+	m.record("GetVirtualInterfacesByVLAN")
+
+	if m.GetVirtualInterfacesByVLANFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVirtualInterfacesByVLANFunc(ctx, vlan)
+}
+
+// GetInterfacesByWirelessLAN implements netbox.ClientIface.
+func (m *Mock) GetInterfacesByWirelessLAN(ctx context.Context, wlan uint64) ([]*netbox.Interface, error) {
+	m.record("GetInterfacesByWirelessLAN")
+
+	if m.GetInterfacesByWirelessLANFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetInterfacesByWirelessLANFunc(ctx, wlan)
+}
+
+// GetIPsByAddress implements netbox.ClientIface.
+func (m *Mock) GetIPsByAddress(ctx context.Context, address string) ([]*netbox.IP, error) {
+	m.record("GetIPsByAddress")
+
+	if m.GetIPsByAddressFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetIPsByAddressFunc(ctx, address)
+}
+
+// GetInterfaceIPs implements netbox.ClientIface.
+func (m *Mock) GetInterfaceIPs(ctx context.Context, id uint64) ([]*netbox.IP, error) {
+	m.record("GetInterfaceIPs")
+
+	if m.GetInterfaceIPsFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetInterfaceIPsFunc(ctx, id)
+}
+
+// GetVirtualInterfaceIPs implements netbox.ClientIface.
+func (m *Mock) GetVirtualInterfaceIPs(ctx context.Context, id uint64) ([]*netbox.IP, error) {
+	m.record("GetVirtualInterfaceIPs")
+
+	if m.GetVirtualInterfaceIPsFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVirtualInterfaceIPsFunc(ctx, id)
+}
+
+// GetIPsInPrefix implements netbox.ClientIface.
+func (m *Mock) GetIPsInPrefix(ctx context.Context, prefix string) ([]*netbox.IP, error) {
+	m.record("GetIPsInPrefix")
+
+	if m.GetIPsInPrefixFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetIPsInPrefixFunc(ctx, prefix)
+}
+
+// GetPrefixes implements netbox.ClientIface.
+func (m *Mock) GetPrefixes(ctx context.Context, filter *netbox.PrefixFilter) ([]*netbox.Prefix, error) {
+	m.record("GetPrefixes")
+
+	if m.GetPrefixesFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetPrefixesFunc(ctx, filter)
+}
+
+// GetServices implements netbox.ClientIface.
+func (m *Mock) GetServices(ctx context.Context) ([]*netbox.Service, error) {
+	m.record("GetServices")
+
+	if m.GetServicesFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetServicesFunc(ctx)
+}
+
+// GetServicesByName implements netbox.ClientIface.
+func (m *Mock) GetServicesByName(ctx context.Context, name string) ([]*netbox.Service, error) {
+	m.record("GetServicesByName")
+
+	if m.GetServicesByNameFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetServicesByNameFunc(ctx, name)
+}
+
+// GetVLANs implements netbox.ClientIface.
+func (m *Mock) GetVLANs(ctx context.Context) ([]*netbox.VLAN, error) {
+	m.record("GetVLANs")
+
+	if m.GetVLANsFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVLANsFunc(ctx)
+}
+
+// GetVLANsByVID implements netbox.ClientIface.
+func (m *Mock) GetVLANsByVID(ctx context.Context, vid uint16) ([]*netbox.VLAN, error) {
+	m.record("GetVLANsByVID")
+
+	if m.GetVLANsByVIDFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVLANsByVIDFunc(ctx, vid)
+}
+
+// GetVLANsByName implements netbox.ClientIface.
+func (m *Mock) GetVLANsByName(ctx context.Context, name string) ([]*netbox.VLAN, error) {
+	m.record("GetVLANsByName")
+
+	if m.GetVLANsByNameFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVLANsByNameFunc(ctx, name)
+}
+
+// GetWirelessLANsBySSID implements netbox.ClientIface.
+func (m *Mock) GetWirelessLANsBySSID(ctx context.Context, ssid string) ([]*netbox.WirelessLAN, error) {
+	m.record("GetWirelessLANsBySSID")
+
+	if m.GetWirelessLANsBySSIDFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetWirelessLANsBySSIDFunc(ctx, ssid)
+}
+
+// GetVDCsByTag implements netbox.ClientIface.
+func (m *Mock) GetVDCsByTag(ctx context.Context, tag string) ([]*netbox.VDC, error) {
+	m.record("GetVDCsByTag")
+
+	if m.GetVDCsByTagFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVDCsByTagFunc(ctx, tag)
+}
+
+// GetVM implements netbox.ClientIface.
+func (m *Mock) GetVM(ctx context.Context, id uint64) (*netbox.Device, error) {
+	m.record("GetVM")
+
+	if m.GetVMFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVMFunc(ctx, id)
+}
+
+// GetVMs implements netbox.ClientIface.
+func (m *Mock) GetVMs(ctx context.Context) ([]*netbox.Device, error) {
+	m.record("GetVMs")
+
+	if m.GetVMsFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVMsFunc(ctx)
+}
+
+// GetVMsByTag implements netbox.ClientIface.
+func (m *Mock) GetVMsByTag(ctx context.Context, tag string) ([]*netbox.Device, error) {
+	m.record("GetVMsByTag")
+
+	if m.GetVMsByTagFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVMsByTagFunc(ctx, tag)
+}
+
+// GetVMsByTagFiltered implements netbox.ClientIface.
+func (m *Mock) GetVMsByTagFiltered(ctx context.Context, tag string, filter *netbox.DeviceFilter) (
+	[]*netbox.Device, error) {
+	m.record("GetVMsByTagFiltered")
+
+	if m.GetVMsByTagFilteredFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVMsByTagFilteredFunc(ctx, tag, filter)
+}
+
+// GetVMsByCluster implements netbox.ClientIface.
+func (m *Mock) GetVMsByCluster(ctx context.Context, cluster string) ([]*netbox.Device, error) {
+	m.record("GetVMsByCluster")
+
+	if m.GetVMsByClusterFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVMsByClusterFunc(ctx, cluster)
+}
+
+// GetVMsByClusterGroup implements netbox.ClientIface.
+func (m *Mock) GetVMsByClusterGroup(ctx context.Context, group string) ([]*netbox.Device, error) {
+	m.record("GetVMsByClusterGroup")
+
+	if m.GetVMsByClusterGroupFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVMsByClusterGroupFunc(ctx, group)
+}
+
+// GetVMsBySiteGroup implements netbox.ClientIface.
+func (m *Mock) GetVMsBySiteGroup(ctx context.Context, group string) ([]*netbox.Device, error) {
+	m.record("GetVMsBySiteGroup")
+
+	if m.GetVMsBySiteGroupFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVMsBySiteGroupFunc(ctx, group)
+}
+
+// GetVMsWithConfigContext implements netbox.ClientIface.
+func (m *Mock) GetVMsWithConfigContext(ctx context.Context) ([]*netbox.Device, error) {
+	m.record("GetVMsWithConfigContext")
+
+	if m.GetVMsWithConfigContextFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVMsWithConfigContextFunc(ctx)
+}
+
+// GetVMConfigContext implements netbox.ClientIface.
+func (m *Mock) GetVMConfigContext(ctx context.Context, id uint64) (map[string]any, error) {
+	m.record("GetVMConfigContext")
+
+	if m.GetVMConfigContextFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVMConfigContextFunc(ctx, id)
+}
+
+// GetVMsByQuery implements netbox.ClientIface.
+func (m *Mock) GetVMsByQuery(ctx context.Context, query string) ([]*netbox.Device, error) {
+	m.record("GetVMsByQuery")
+
+	if m.GetVMsByQueryFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetVMsByQueryFunc(ctx, query)
+}
+
+// GetSites implements netbox.ClientIface.
+func (m *Mock) GetSites(ctx context.Context) ([]*netbox.Site, error) {
+	m.record("GetSites")
+
+	if m.GetSitesFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetSitesFunc(ctx)
+}
+
+// GetSitesByTag implements netbox.ClientIface.
+func (m *Mock) GetSitesByTag(ctx context.Context, tag string) ([]*netbox.Site, error) {
+	m.record("GetSitesByTag")
+
+	if m.GetSitesByTagFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetSitesByTagFunc(ctx, tag)
+}
+
+// GetTenants implements netbox.ClientIface.
+func (m *Mock) GetTenants(ctx context.Context) ([]*netbox.Tenant, error) {
+	m.record("GetTenants")
+
+	if m.GetTenantsFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetTenantsFunc(ctx)
+}
+
+// GetTenantsByTag implements netbox.ClientIface.
+func (m *Mock) GetTenantsByTag(ctx context.Context, tag string) ([]*netbox.Tenant, error) {
+	m.record("GetTenantsByTag")
+
+	if m.GetTenantsByTagFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetTenantsByTagFunc(ctx, tag)
+}
+
+// GetRacks implements netbox.ClientIface.
+func (m *Mock) GetRacks(ctx context.Context) ([]*netbox.Rack, error) {
+	m.record("GetRacks")
+
+	if m.GetRacksFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetRacksFunc(ctx)
+}
+
+// GetRacksByTag implements netbox.ClientIface.
+func (m *Mock) GetRacksByTag(ctx context.Context, tag string) ([]*netbox.Rack, error) {
+	m.record("GetRacksByTag")
+
+	if m.GetRacksByTagFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetRacksByTagFunc(ctx, tag)
+}
+
+// GetClusters implements netbox.ClientIface.
+func (m *Mock) GetClusters(ctx context.Context) ([]*netbox.Cluster, error) {
+	m.record("GetClusters")
+
+	if m.GetClustersFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetClustersFunc(ctx)
+}
+
+// GetClustersByTag implements netbox.ClientIface.
+func (m *Mock) GetClustersByTag(ctx context.Context, tag string) ([]*netbox.Cluster, error) {
+	m.record("GetClustersByTag")
+
+	if m.GetClustersByTagFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetClustersByTagFunc(ctx, tag)
+}
+
+// GetRegions implements netbox.ClientIface.
+func (m *Mock) GetRegions(ctx context.Context) ([]*netbox.Region, error) {
+	m.record("GetRegions")
+
+	if m.GetRegionsFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetRegionsFunc(ctx)
+}
+
+// GetRegionsByTag implements netbox.ClientIface.
+func (m *Mock) GetRegionsByTag(ctx context.Context, tag string) ([]*netbox.Region, error) {
+	m.record("GetRegionsByTag")
+
+	if m.GetRegionsByTagFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetRegionsByTagFunc(ctx, tag)
+}
+
+// GetLocations implements netbox.ClientIface.
+func (m *Mock) GetLocations(ctx context.Context) ([]*netbox.Location, error) {
+	m.record("GetLocations")
+
+	if m.GetLocationsFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetLocationsFunc(ctx)
+}
+
+// GetLocationsByTag implements netbox.ClientIface.
+func (m *Mock) GetLocationsByTag(ctx context.Context, tag string) ([]*netbox.Location, error) {
+	m.record("GetLocationsByTag")
+
+	if m.GetLocationsByTagFunc == nil {
+		return nil, nil
+	}
+
+	return m.GetLocationsByTagFunc(ctx, tag)
+}
+
+// SetLogger implements netbox.ClientIface.
+func (m *Mock) SetLogger(logger *slog.Logger) {
+	m.record("SetLogger")
+
+	if m.SetLoggerFunc != nil {
+		m.SetLoggerFunc(logger)
+	}
+}
+
+// HTTPTracing implements netbox.ClientIface.
+func (m *Mock) HTTPTracing(enabled bool) {
+	m.record("HTTPTracing")
+
+	if m.HTTPTracingFunc != nil {
+		m.HTTPTracingFunc(enabled)
+	}
+}
+
+// SetPinnedPublicKeys implements netbox.ClientIface.
+func (m *Mock) SetPinnedPublicKeys(pins []string) error {
+	m.record("SetPinnedPublicKeys")
+
+	if m.SetPinnedPublicKeysFunc == nil {
+		return nil
+	}
+
+	return m.SetPinnedPublicKeysFunc(pins)
+}
+
+// UsePersistedQueries implements netbox.ClientIface.
+func (m *Mock) UsePersistedQueries(enabled bool) {
+	m.record("UsePersistedQueries")
+
+	if m.UsePersistedQueriesFunc != nil {
+		m.UsePersistedQueriesFunc(enabled)
+	}
+}
+
+// SetPageSize implements netbox.ClientIface.
+func (m *Mock) SetPageSize(size int) {
+	m.record("SetPageSize")
+
+	if m.SetPageSizeFunc != nil {
+		m.SetPageSizeFunc(size)
+	}
+}
+
+// SetCacheTTL implements netbox.ClientIface.
+func (m *Mock) SetCacheTTL(ttl time.Duration) {
+	m.record("SetCacheTTL")
+
+	if m.SetCacheTTLFunc != nil {
+		m.SetCacheTTLFunc(ttl)
+	}
+}
+
+// GetLastChangeID implements netbox.ClientIface.
+func (m *Mock) GetLastChangeID(ctx context.Context) (uint64, error) {
+	m.record("GetLastChangeID")
+
+	if m.GetLastChangeIDFunc == nil {
+		return 0, nil
+	}
+
+	return m.GetLastChangeIDFunc(ctx)
+}
+
+// VerifyConnectivity implements netbox.ClientIface.
+func (m *Mock) VerifyConnectivity(ctx context.Context) error {
+	m.record("VerifyConnectivity")
+
+	if m.VerifyConnectivityFunc == nil {
+		return nil
+	}
+
+	return m.VerifyConnectivityFunc(ctx)
+}
+
+// Version implements netbox.ClientIface.
+func (m *Mock) Version() string {
+	m.record("Version")
+
+	if m.VersionFunc == nil {
+		return ""
+	}
+
+	return m.VersionFunc()
+}
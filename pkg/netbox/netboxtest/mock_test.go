@@ -0,0 +1,82 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netboxtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+	"github.com/4xoc/netbox_sd/pkg/netbox/netboxtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMock(t *testing.T) {
+	var (
+		mock    *netboxtest.Mock
+		api     netbox.ClientIface
+		devices []*netbox.Device
+		names   []string
+		stop    error           = errors.New("stop")
+		ctx     context.Context = context.Background()
+		err     error
+	)
+
+	mock = &netboxtest.Mock{
+		GetDevicesByTagFunc: func(ctx context.Context, tag string) ([]*netbox.Device, error) {
+			return []*netbox.Device{{Name: tag}}, nil
+		},
+		GetDevicesBatchFunc: func(ctx context.Context, queries []netbox.DeviceQuery) ([][]*netbox.Device, error) {
+			return [][]*netbox.Device{{{Name: "device-A"}, {Name: "device-B"}}}, nil
+		},
+	}
+	api = mock
+
+	devices, err = api.GetDevicesByTag(ctx, "node_exporter")
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "node_exporter", devices[0].Name)
+
+	// methods without a function return empty results
+	devices, err = api.GetVMs(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, devices)
+	assert.NoError(t, api.VerifyConnectivity(ctx))
+	api.SetPageSize(10)
+
+	// ForEachDevice falls back to GetDevicesBatch
+	err = api.Copy().ForEachDevice(ctx, netbox.DeviceQuery{}, func(device *netbox.Device) error {
+		names = append(names, device.Name)
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, []string{"device-A"}, names)
+
+	assert.Equal(t, []string{
+		"GetDevicesByTag",
+		"GetVMs",
+		"VerifyConnectivity",
+		"SetPageSize",
+		"Copy",
+		"ForEachDevice",
+		"GetDevicesBatch",
+	}, mock.Calls())
+}
@@ -0,0 +1,446 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netboxtest
+
+// This file contains the REST API endpoints of the fake server. Only list endpoints with the filters used by the netbox
+// package are supported.
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// restDefaultLimit is the page size used when the request doesn't contain a limit.
+const restDefaultLimit int = 50
+
+// restList serves a REST list endpoint for objs. Only objects matching all filters in the request's query are
+// returned using limit/offset based pagination like Netbox does.
+func restList[T any](w http.ResponseWriter, r *http.Request, objs []T, match func(T, string, string) bool, render func(T) map[string]any) {
+	var (
+		query    url.Values = r.URL.Query()
+		filtered []T
+		obj      T
+		key      string
+		values   []string
+		limit    int = restDefaultLimit
+		offset   int
+		err      error
+		next     any
+		results  []map[string]any = make([]map[string]any, 0)
+		i        int
+		matches  bool
+	)
+
+	if query.Has("limit") {
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]any{"limit": []string{"invalid limit"}})
+			return
+		}
+	}
+
+	if query.Has("offset") {
+		offset, err = strconv.Atoi(query.Get("offset"))
+		if err != nil || offset < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]any{"offset": []string{"invalid offset"}})
+			return
+		}
+	}
+
+	query.Del("limit")
+	query.Del("offset")
+
+	for _, obj = range objs {
+		matches = true
+
+		// values of the same filter are ORed while different filters are ANDed
+		for key, values = range query {
+			matches = false
+
+			for i = range values {
+				if match(obj, key, values[i]) {
+					matches = true
+					break
+				}
+			}
+
+			if !matches {
+				break
+			}
+		}
+
+		if matches {
+			filtered = append(filtered, obj)
+		}
+	}
+
+	for i = offset; i < len(filtered) && i < offset+limit; i++ {
+		results = append(results, render(filtered[i]))
+	}
+
+	if offset+limit < len(filtered) {
+		query.Set("limit", strconv.Itoa(limit))
+		query.Set("offset", strconv.Itoa(offset+limit))
+		next = fmt.Sprintf("http://%s%s?%s", r.Host, r.URL.Path, query.Encode())
+	}
+
+	writeJSON(w, map[string]any{
+		"count":    len(filtered),
+		"next":     next,
+		"previous": nil,
+		"results":  results,
+	})
+}
+
+// restMatchDevice returns true when d matches the REST filter key=value. Unknown filters are ignored.
+func (f *Fixtures) restMatchDevice(d *Device, key, value string) bool {
+	switch key {
+	case "id":
+		return strconv.FormatUint(d.ID, 10) == value
+	case "name":
+		return d.Name == value
+	case "status":
+		return d.Status == value
+	case "role":
+		return d.Role == value
+	case "site":
+		return d.Site == value
+	case "tenant":
+		return d.Tenant == value
+	case "platform":
+		return d.Platform == value
+	case "manufacturer":
+		return d.Manufacturer == value
+	case "cluster":
+		return d.Cluster == value
+	case "cluster_group":
+		return d.Cluster != "" && f.clusterGroup(d.Cluster) == value
+	case "site_group":
+		return f.inSiteGroup(d.Site, value)
+	case "tag":
+		return restMatchTag(d.Tags, value)
+	}
+
+	if strings.HasPrefix(key, "cf_") {
+		return fmt.Sprint(d.CustomFields[strings.TrimPrefix(key, "cf_")]) == value
+	}
+
+	return true
+}
+
+// restMatchIP returns true when ip matches the REST filter key=value. Unknown filters are ignored.
+func (f *Fixtures) restMatchIP(ip *IP, key, value string) bool {
+	var iface *Interface = f.iface(ip.Interface)
+
+	switch key {
+	case "id":
+		return strconv.FormatUint(ip.ID, 10) == value
+	case "address":
+		// like Netbox, the mask is optional
+		return ip.Address == value || strings.HasPrefix(ip.Address, value+"/")
+	case "status":
+		return ip.Status == value
+	case "interface_id":
+		return iface != nil && iface.Device != "" && strconv.FormatUint(iface.ID, 10) == value
+	case "vminterface_id":
+		return iface != nil && iface.VM != "" && strconv.FormatUint(iface.ID, 10) == value
+	case "parent":
+		return inPrefix(ip.Address, value)
+	}
+
+	return true
+}
+
+// restMatchInterface returns true when i matches the REST filter key=value. Unknown filters are ignored.
+func restMatchInterface(i *Interface, key, value string) bool {
+	var id uint64
+
+	switch key {
+	case "id":
+		return strconv.FormatUint(i.ID, 10) == value
+	case "tag":
+		return restMatchTag(i.Tags, value)
+	case "vlan_id":
+		for _, id = range append([]uint64{i.UntaggedVLAN}, i.TaggedVLANs...) {
+			if id != 0 && strconv.FormatUint(id, 10) == value {
+				return true
+			}
+		}
+
+		return false
+	case "wireless_lan_id":
+		for _, id = range i.WirelessLANs {
+			if strconv.FormatUint(id, 10) == value {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// restMatchService returns true when s matches the REST filter key=value. Unknown filters are ignored.
+func restMatchService(s *Service, key, value string) bool {
+	switch key {
+	case "id":
+		return strconv.FormatUint(s.ID, 10) == value
+	case "name":
+		return s.Name == value
+	}
+
+	return true
+}
+
+// restMatchVLAN returns true when v matches the REST filter key=value. Unknown filters are ignored.
+func restMatchVLAN(v *VLAN, key, value string) bool {
+	switch key {
+	case "id":
+		return strconv.FormatUint(v.ID, 10) == value
+	case "vid":
+		return strconv.FormatUint(uint64(v.VID), 10) == value
+	case "name":
+		return v.Name == value
+	}
+
+	return true
+}
+
+// restMatchWirelessLAN returns true when w matches the REST filter key=value. Unknown filters are ignored.
+func restMatchWirelessLAN(w *WirelessLAN, key, value string) bool {
+	switch key {
+	case "id":
+		return strconv.FormatUint(w.ID, 10) == value
+	case "ssid":
+		return w.SSID == value
+	}
+
+	return true
+}
+
+// restMatchVDC returns true when v matches the REST filter key=value. Unknown filters are ignored.
+func restMatchVDC(v *VDC, key, value string) bool {
+	switch key {
+	case "id":
+		return strconv.FormatUint(v.ID, 10) == value
+	case "tag":
+		return restMatchTag(v.Tags, value)
+	}
+
+	return true
+}
+
+// restMatchTag returns true when tag is one of tags.
+func restMatchTag(tags []string, tag string) bool {
+	var t string
+
+	for _, t = range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// restName renders a nested object referenced by name or nil when name is empty.
+func restName(name string) any {
+	if name == "" {
+		return nil
+	}
+
+	return map[string]any{"name": name}
+}
+
+// restRef renders a reference to the IP identified by address or nil when address is empty.
+func (f *Fixtures) restRef(address string) any {
+	if address == "" {
+		return nil
+	}
+
+	return map[string]any{"id": f.ipByAddress(address).ID, "address": address}
+}
+
+// restDevices returns the renderer of devices for r. Config contexts are rendered unless excluded by r.
+func (f *Fixtures) restDevices(r *http.Request) func(*Device) map[string]any {
+	if r.URL.Query().Get("exclude") == "config_context" {
+		return f.restDevice
+	}
+
+	return func(d *Device) map[string]any {
+		var result map[string]any = f.restDevice(d)
+
+		result["config_context"] = renderConfigContext(d.ConfigContext)
+
+		return result
+	}
+}
+
+func (f *Fixtures) restDevice(d *Device) map[string]any {
+	return map[string]any{
+		"id":            d.ID,
+		"name":          d.Name,
+		"primary_ip4":   f.restRef(d.PrimaryIP4),
+		"primary_ip6":   f.restRef(d.PrimaryIP6),
+		"custom_fields": renderCustomFields(d.CustomFields),
+		"rack":          restName(d.Rack),
+		"site":          restName(d.Site),
+		"role":          restName(d.Role),
+		"tenant":        restName(d.Tenant),
+		"platform":      restName(d.Platform),
+		"serial":        d.SerialNumber,
+		"asset_tag":     d.AssetTag,
+		"status":        map[string]any{"value": d.Status},
+		"tags":          renderTags(d.Tags),
+	}
+}
+
+func (f *Fixtures) restIP(ip *IP) map[string]any {
+	var vrf any
+
+	if ip.VRF != "" {
+		vrf = map[string]any{"id": f.vrfID(ip.VRF), "name": ip.VRF}
+	}
+
+	return map[string]any{
+		"id":       ip.ID,
+		"address":  ip.Address,
+		"status":   map[string]any{"value": ip.Status},
+		"dns_name": ip.DNSName,
+		"vrf":      vrf,
+	}
+}
+
+// restDeviceRef renders a reference to the device identified by name or nil when name is empty.
+func (f *Fixtures) restDeviceRef(name string) any {
+	if name == "" {
+		return nil
+	}
+
+	return map[string]any{"id": f.device(name).ID, "name": name}
+}
+
+// restVMRef renders a reference to the virtual machine identified by name or nil when name is empty.
+func (f *Fixtures) restVMRef(name string) any {
+	if name == "" {
+		return nil
+	}
+
+	return map[string]any{"id": f.vm(name).ID, "name": name}
+}
+
+func (f *Fixtures) restInterface(i *Interface) map[string]any {
+	var (
+		untagged any
+		tagged   []map[string]any = make([]map[string]any, 0, len(i.TaggedVLANs))
+		id       uint64
+	)
+
+	if i.UntaggedVLAN != 0 {
+		untagged = restVLAN(f.vlan(i.UntaggedVLAN))
+	}
+
+	for _, id = range i.TaggedVLANs {
+		tagged = append(tagged, restVLAN(f.vlan(id)))
+	}
+
+	return map[string]any{
+		"id":              i.ID,
+		"name":            i.Name,
+		"enabled":         *i.Enabled,
+		"custom_fields":   renderCustomFields(i.CustomFields),
+		"device":          f.restDeviceRef(i.Device),
+		"virtual_machine": f.restVMRef(i.VM),
+		"mode":            restChoice(i.Mode),
+		"untagged_vlan":   untagged,
+		"tagged_vlans":    tagged,
+		"tags":            renderTags(i.Tags),
+	}
+}
+
+func (f *Fixtures) restService(s *Service) map[string]any {
+	var (
+		ips  []any = make([]any, 0, len(s.IPAddresses))
+		addr string
+	)
+
+	for _, addr = range s.IPAddresses {
+		ips = append(ips, f.restRef(addr))
+	}
+
+	return map[string]any{
+		"id":              s.ID,
+		"name":            s.Name,
+		"device":          f.restDeviceRef(s.Device),
+		"virtual_machine": f.restVMRef(s.VM),
+		"ports":           s.Ports,
+		"ipaddresses":     ips,
+		"protocol":        map[string]any{"value": s.Protocol},
+		"custom_fields":   renderCustomFields(s.CustomFields),
+	}
+}
+
+func restVLAN(v *VLAN) map[string]any {
+	return map[string]any{
+		"id":     v.ID,
+		"vid":    v.VID,
+		"name":   v.Name,
+		"status": restChoice(v.Status),
+		"group":  restName(v.Group),
+		"site":   restName(v.Site),
+		"tenant": restName(v.Tenant),
+		"tags":   renderTags(v.Tags),
+	}
+}
+
+// restChoice renders a choice field or nil when value is empty.
+func restChoice(value string) any {
+	if value == "" {
+		return nil
+	}
+
+	return map[string]any{"value": value}
+}
+
+func restWirelessLAN(w *WirelessLAN) map[string]any {
+	return map[string]any{
+		"id":   w.ID,
+		"ssid": w.SSID,
+	}
+}
+
+func (f *Fixtures) restVDC(v *VDC) map[string]any {
+	return map[string]any{
+		"id":            v.ID,
+		"name":          v.Name,
+		"identifier":    v.Identifier,
+		"status":        map[string]any{"value": v.Status},
+		"primary_ip4":   f.restRef(v.PrimaryIP4),
+		"primary_ip6":   f.restRef(v.PrimaryIP6),
+		"custom_fields": renderCustomFields(v.CustomFields),
+		"tenant":        restName(v.Tenant),
+		"tags":          renderTags(v.Tags),
+		"device":        f.restDeviceRef(v.Device),
+	}
+}
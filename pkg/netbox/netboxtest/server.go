@@ -0,0 +1,1084 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netboxtest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultToken is the API token accepted by servers created by this package.
+const DefaultToken string = "0123456789abcdef0123456789abcdef01234567"
+
+var (
+	argID            *regexp.Regexp = regexp.MustCompile(`\bid\s*:\s*"?(\d+)"?`)
+	argTag           *regexp.Regexp = regexp.MustCompile(`\btag\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	argInterfaceID   *regexp.Regexp = regexp.MustCompile(`\binterface_id\s*:\s*"?(\d+)"?`)
+	argVMInterfaceID *regexp.Regexp = regexp.MustCompile(`\bvminterface_id\s*:\s*"?(\d+)"?`)
+	argAddress       *regexp.Regexp = regexp.MustCompile(`\baddress\s*:\s*\{\s*starts_with\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	argParentList    *regexp.Regexp = regexp.MustCompile(`\bparent\s*:\s*(\[[^\]]*\])`)
+	argCluster       *regexp.Regexp = regexp.MustCompile(`\bcluster\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	argSiteGroup     *regexp.Regexp = regexp.MustCompile(`\bsite_group\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	argClusterGroup  *regexp.Regexp = regexp.MustCompile(`\bcluster_group\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	argManufacturer  *regexp.Regexp = regexp.MustCompile(`\bmanufacturer\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	argVLANID        *regexp.Regexp = regexp.MustCompile(`\bvlan_id\s*:\s*"?(\d+)"?`)
+	argWirelessLANID *regexp.Regexp = regexp.MustCompile(`\bwireless_lan_id\s*:\s*"?(\d+)"?`)
+	argSSID          *regexp.Regexp = regexp.MustCompile(`\bssid\s*:\s*\{\s*exact\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	argVID           *regexp.Regexp = regexp.MustCompile(`\bvid\s*:\s*\{\s*exact\s*:\s*(\d+)`)
+	argNameExact     *regexp.Regexp = regexp.MustCompile(`\bname\s*:\s*\{\s*exact\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	argName          *regexp.Regexp = regexp.MustCompile(`\bname\s*:\s*\{\s*starts_with\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	argSiteList      *regexp.Regexp = regexp.MustCompile(`\bsite\s*:\s*(\[[^\]]*\])`)
+	argRoleList      *regexp.Regexp = regexp.MustCompile(`\brole\s*:\s*(\[[^\]]*\])`)
+	argTenantList    *regexp.Regexp = regexp.MustCompile(`\btenant\s*:\s*(\[[^\]]*\])`)
+	argPlatformList  *regexp.Regexp = regexp.MustCompile(`\bplatform\s*:\s*(\[[^\]]*\])`)
+	argStatusList    *regexp.Regexp = regexp.MustCompile(`\bstatus\s*:\s*(\[[^\]]*\])`)
+	argPagination    *regexp.Regexp = regexp.MustCompile(`\bpagination\s*:\s*\{([^}]*)\}`)
+	argOffset        *regexp.Regexp = regexp.MustCompile(`\boffset\s*:\s*(\d+)`)
+	argLimit         *regexp.Regexp = regexp.MustCompile(`\blimit\s*:\s*(\d+)`)
+)
+
+// Server is a fake Netbox server serving the content of Fixtures via GraphQL. It embeds httptest.Server, thus URL
+// can be used as base URL for netbox.New.
+type Server struct {
+	*httptest.Server
+	// Token is the only API token accepted by the server.
+	Token string
+	// PersistedQueries enables support for automatic persisted queries like a GraphQL gateway in front of Netbox would
+	// provide. Queries sent as hash only are answered with a PersistedQueryNotFound error until they have been
+	// registered by sending them including the query document.
+	PersistedQueries bool
+	// MaxListSize limits the number of objects returned for list fields like a Netbox instance limiting the size of
+	// GraphQL responses would. 0 means no limit. Lists are paginated using the pagination argument regardless.
+	MaxListSize int
+
+	mu       sync.RWMutex
+	fixtures *Fixtures
+	// lastChange is the ID of the only entry of the change log, increased by every call of SetFixtures.
+	lastChange uint64
+
+	persistedMu sync.Mutex
+	persisted   map[string]string
+}
+
+// resolver returns the data for a single GraphQL root field based on its arguments.
+type resolver func(f *Fixtures, args string) any
+
+// resolvers contains all supported GraphQL root fields.
+var resolvers map[string]resolver = map[string]resolver{
+	"device": func(f *Fixtures, args string) any {
+		return renderOne(f.Devices, args, func(d *Device) uint64 { return d.ID }, f.renderDevice)
+	},
+	"device_list": func(f *Fixtures, args string) any {
+		return renderList(f.Devices, func(d *Device) bool {
+			return matchTag(d.Tags, args) &&
+				matchExact(argManufacturer, d.Manufacturer, args) &&
+				f.matchSiteGroup(d, args) &&
+				matchDeviceFilter(d, args)
+		}, f.renderDevice)
+	},
+	"virtual_machine": func(f *Fixtures, args string) any {
+		return renderOne(f.VMs, args, func(d *Device) uint64 { return d.ID }, f.renderVM)
+	},
+	"virtual_machine_list": func(f *Fixtures, args string) any {
+		return renderList(f.VMs, func(d *Device) bool {
+			return matchTag(d.Tags, args) &&
+				matchExact(argCluster, d.Cluster, args) &&
+				matchExact(argClusterGroup, f.clusterGroup(d.Cluster), args) &&
+				f.matchSiteGroup(d, args) &&
+				matchDeviceFilter(d, args)
+		}, f.renderVM)
+	},
+	"interface": func(f *Fixtures, args string) any {
+		return renderOne(f.deviceInterfaces(), args, func(i *Interface) uint64 { return i.ID }, f.renderInterface)
+	},
+	"interface_list": func(f *Fixtures, args string) any {
+		return renderList(f.deviceInterfaces(), func(i *Interface) bool {
+			return matchTag(i.Tags, args) && matchVLAN(i, args) && matchWirelessLAN(i, args)
+		}, f.renderInterface)
+	},
+	"vm_interface": func(f *Fixtures, args string) any {
+		return renderOne(f.vmInterfaces(), args, func(i *Interface) uint64 { return i.ID }, f.renderInterface)
+	},
+	"vm_interface_list": func(f *Fixtures, args string) any {
+		return renderList(f.vmInterfaces(), func(i *Interface) bool {
+			return matchTag(i.Tags, args) && matchVLAN(i, args)
+		}, f.renderInterface)
+	},
+	"ip_address_list": func(f *Fixtures, args string) any {
+		return renderList(f.IPAddresses, func(ip *IP) bool { return f.matchIP(ip, args) }, f.renderIP)
+	},
+	"service_list": func(f *Fixtures, args string) any {
+		return renderList(f.Services, func(s *Service) bool { return matchPrefix(argName, s.Name, args) }, f.renderService)
+	},
+	"virtual_device_context_list": func(f *Fixtures, args string) any {
+		return renderList(f.VDCs, func(v *VDC) bool { return matchTag(v.Tags, args) }, f.renderVDC)
+	},
+	"vlan_list": func(f *Fixtures, args string) any {
+		return renderList(f.VLANs, func(v *VLAN) bool {
+			return matchExact(argVID, strconv.FormatUint(uint64(v.VID), 10), args) && matchExact(argNameExact, v.Name, args)
+		}, renderVLAN)
+	},
+	"wireless_lan_list": func(f *Fixtures, args string) any {
+		return renderList(f.WirelessLANs, func(w *WirelessLAN) bool { return matchExact(argSSID, w.SSID, args) }, renderWirelessLAN)
+	},
+}
+
+// NewServer starts and returns a new fake Netbox server using plain HTTP. The caller must call Close when finished. It
+// panics if fixtures contain invalid references.
+func NewServer(fixtures *Fixtures) *Server {
+	var server *Server = newServer(fixtures)
+
+	server.Server = httptest.NewServer(server)
+
+	return server
+}
+
+// NewTLSServer starts and returns a new fake Netbox server using TLS with a self-signed certificate. Use
+// Server.Client() or the certificate returned by Server.Certificate() to connect to it.
+func NewTLSServer(fixtures *Fixtures) *Server {
+	var server *Server = newServer(fixtures)
+
+	server.Server = httptest.NewTLSServer(server)
+
+	return server
+}
+
+func newServer(fixtures *Fixtures) *Server {
+	if fixtures == nil {
+		fixtures = new(Fixtures)
+	}
+
+	if err := fixtures.Validate(); err != nil {
+		panic("netboxtest: " + err.Error())
+	}
+
+	return &Server{
+		Token:      DefaultToken,
+		fixtures:   fixtures,
+		lastChange: 1,
+		persisted:  make(map[string]string),
+	}
+}
+
+// SetFixtures replaces the data served by the server and adds an entry to its change log. It panics if fixtures contain
+// invalid references.
+func (s *Server) SetFixtures(fixtures *Fixtures) {
+	if err := fixtures.Validate(); err != nil {
+		panic("netboxtest: " + err.Error())
+	}
+
+	s.mu.Lock()
+	s.fixtures = fixtures
+	s.lastChange++
+	s.mu.Unlock()
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		body struct {
+			Query      string         `json:"query"`
+			Variables  map[string]any `json:"variables"`
+			Extensions struct {
+				PersistedQuery *struct {
+					SHA256Hash string `json:"sha256Hash"`
+				} `json:"persistedQuery"`
+			} `json:"extensions"`
+		}
+		data map[string]any
+		err  error
+	)
+
+	if r.Header.Get("Authorization") != "Token "+s.Token {
+		w.WriteHeader(http.StatusForbidden)
+		writeJSON(w, map[string]any{"detail": "Invalid token"})
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/status/":
+		writeJSON(w, map[string]any{"netbox-version": s.fixtures.Version})
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/core/object-changes/":
+		writeJSON(w, map[string]any{
+			"count":   1,
+			"next":    nil,
+			"results": []any{map[string]any{"id": s.lastChange}},
+		})
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/dcim/devices/":
+		restList(w, r, s.fixtures.Devices, s.fixtures.restMatchDevice, s.fixtures.restDevices(r))
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/virtualization/virtual-machines/":
+		restList(w, r, s.fixtures.VMs, s.fixtures.restMatchDevice, s.fixtures.restDevices(r))
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/ipam/ip-addresses/":
+		restList(w, r, s.fixtures.IPAddresses, s.fixtures.restMatchIP, s.fixtures.restIP)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/dcim/interfaces/":
+		restList(w, r, s.fixtures.deviceInterfaces(), restMatchInterface, s.fixtures.restInterface)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/virtualization/interfaces/":
+		restList(w, r, s.fixtures.vmInterfaces(), restMatchInterface, s.fixtures.restInterface)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/ipam/services/":
+		restList(w, r, s.fixtures.Services, restMatchService, s.fixtures.restService)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/ipam/vlans/":
+		restList(w, r, s.fixtures.VLANs, restMatchVLAN, restVLAN)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/wireless/wireless-lans/":
+		restList(w, r, s.fixtures.WirelessLANs, restMatchWirelessLAN, restWirelessLAN)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/dcim/virtual-device-contexts/":
+		restList(w, r, s.fixtures.VDCs, restMatchVDC, s.fixtures.restVDC)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/graphql/":
+		err = json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]any{"errors": []any{map[string]any{"message": err.Error()}}})
+			return
+		}
+
+		if s.PersistedQueries && body.Extensions.PersistedQuery != nil {
+			body.Query, err = s.persistedQuery(body.Extensions.PersistedQuery.SHA256Hash, body.Query)
+			if err != nil {
+				writeJSON(w, map[string]any{"errors": []any{map[string]any{
+					"message":    err.Error(),
+					"extensions": map[string]any{"code": "PERSISTED_QUERY_NOT_FOUND"},
+				}}})
+				return
+			}
+		}
+
+		body.Query, err = inlineVariables(body.Query, body.Variables)
+		if err == nil {
+			data, err = s.fixtures.resolve(body.Query, s.MaxListSize)
+		}
+
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]any{"errors": []any{map[string]any{"message": err.Error()}}})
+			return
+		}
+
+		writeJSON(w, map[string]any{"data": data})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]any{"detail": "Not found."})
+	}
+}
+
+// persistedQuery returns the query registered for hash. When query is set, it is registered for hash first.
+func (s *Server) persistedQuery(hash, query string) (string, error) {
+	var (
+		sum [32]byte = sha256.Sum256([]byte(query))
+		ok  bool
+	)
+
+	s.persistedMu.Lock()
+	defer s.persistedMu.Unlock()
+
+	if query != "" {
+		if hex.EncodeToString(sum[:]) != hash {
+			return "", errors.New("provided sha does not match query")
+		}
+
+		s.persisted[hash] = query
+		return query, nil
+	}
+
+	query, ok = s.persisted[hash]
+	if !ok {
+		return "", errors.New("PersistedQueryNotFound")
+	}
+
+	return query, nil
+}
+
+// RegisteredQueries returns the number of persisted queries registered with the server.
+func (s *Server) RegisteredQueries() int {
+	s.persistedMu.Lock()
+	defer s.persistedMu.Unlock()
+
+	return len(s.persisted)
+}
+
+// writeJSON writes v as JSON to w.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// resolve parses the root fields of a GraphQL query and returns the data for each of them. Only the root fields and
+// their arguments are evaluated; selection sets are ignored and objects are always returned with all attributes. Lists
+// are paginated and truncated to maxList objects unless maxList is 0.
+func (f *Fixtures) resolve(query string, maxList int) (map[string]any, error) {
+	var (
+		data  map[string]any = make(map[string]any)
+		pos   int
+		alias string
+		field string
+		args  string
+		res   resolver
+		ok    bool
+		err   error
+	)
+
+	query = strings.TrimSpace(query)
+	query = strings.TrimPrefix(query, "query")
+	query = strings.TrimSpace(query)
+
+	if !strings.HasPrefix(query, "{") {
+		return nil, fmt.Errorf("query must start with '{'")
+	}
+
+	pos = 1
+
+	for {
+		pos = skipSpace(query, pos)
+		if pos >= len(query) {
+			return nil, fmt.Errorf("unexpected end of query")
+		}
+
+		if query[pos] == '}' {
+			return data, nil
+		}
+
+		field, pos = readIdent(query, pos)
+		alias = field
+		pos = skipSpace(query, pos)
+
+		if pos < len(query) && query[pos] == ':' {
+			field, pos = readIdent(query, skipSpace(query, pos+1))
+			pos = skipSpace(query, pos)
+		}
+
+		if field == "" {
+			return nil, fmt.Errorf("expected field name at position %d", pos)
+		}
+
+		args = ""
+		if pos < len(query) && query[pos] == '(' {
+			args, pos, err = readBalanced(query, pos, '(', ')')
+			if err != nil {
+				return nil, err
+			}
+			pos = skipSpace(query, pos)
+		}
+
+		if pos < len(query) && query[pos] == '{' {
+			_, pos, err = readBalanced(query, pos, '{', '}')
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if res, ok = resolvers[field]; !ok {
+			return nil, fmt.Errorf("unsupported field %s", field)
+		}
+
+		data[alias] = paginate(res(f, args), args, maxList)
+	}
+}
+
+// inlineVariables returns the selection set of query with all variables replaced by their values from variables as
+// GraphQL literals. Variables without a value are replaced by null. The operation definition is removed, thus
+// variable types are not checked.
+func inlineVariables(query string, variables map[string]any) (string, error) {
+	var (
+		builder  strings.Builder
+		pos      int
+		name     string
+		inString bool
+		err      error
+	)
+
+	query = strings.TrimSpace(query)
+
+	if strings.HasPrefix(query, "query") {
+		pos = skipSpace(query, len("query"))
+		_, pos = readIdent(query, pos)
+		pos = skipSpace(query, pos)
+
+		if pos < len(query) && query[pos] == '(' {
+			_, pos, err = readBalanced(query, pos, '(', ')')
+			if err != nil {
+				return "", err
+			}
+		}
+
+		query = query[pos:]
+	}
+
+	for pos = 0; pos < len(query); pos++ {
+		switch {
+		case inString && query[pos] == '\\' && pos+1 < len(query):
+			builder.WriteByte(query[pos])
+			pos++
+		case query[pos] == '"':
+			inString = !inString
+		case !inString && query[pos] == '$':
+			name, pos = readIdent(query, pos+1)
+			if name == "" {
+				return "", fmt.Errorf("expected variable name at position %d", pos)
+			}
+
+			builder.WriteString(literal(variables[name]))
+			pos--
+			continue
+		}
+
+		builder.WriteByte(query[pos])
+	}
+
+	return builder.String(), nil
+}
+
+// literal returns value decoded from JSON as GraphQL literal.
+func literal(value any) string {
+	var (
+		parts []string
+		keys  []string
+		key   string
+		item  any
+	)
+
+	switch v := value.(type) {
+	case nil:
+		return "null"
+
+	case string:
+		return `"` + strings.ReplaceAll(strings.ReplaceAll(v, `\`, `\\`), `"`, `\"`) + `"`
+
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+
+	case []any:
+		for _, item = range v {
+			parts = append(parts, literal(item))
+		}
+
+		return "[" + strings.Join(parts, ", ") + "]"
+
+	case map[string]any:
+		for key = range v {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key = range keys {
+			parts = append(parts, key+": "+literal(v[key]))
+		}
+
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+
+	// booleans
+	return fmt.Sprint(value)
+}
+
+func skipSpace(s string, pos int) int {
+	for pos < len(s) && strings.ContainsRune(" \t\r\n,", rune(s[pos])) {
+		pos++
+	}
+
+	return pos
+}
+
+func readIdent(s string, pos int) (string, int) {
+	var start int = pos
+
+	for pos < len(s) && (s[pos] == '_' ||
+		(s[pos] >= 'a' && s[pos] <= 'z') ||
+		(s[pos] >= 'A' && s[pos] <= 'Z') ||
+		(s[pos] >= '0' && s[pos] <= '9')) {
+		pos++
+	}
+
+	return s[start:pos], pos
+}
+
+// readBalanced returns the content between open at pos and its matching close as well as the position after close.
+// Strings are skipped so that brackets within strings are ignored.
+func readBalanced(s string, pos int, open, close byte) (string, int, error) {
+	var (
+		start    int = pos + 1
+		depth    int
+		inString bool
+	)
+
+	for ; pos < len(s); pos++ {
+		switch {
+		case inString && s[pos] == '\\':
+			pos++
+		case s[pos] == '"':
+			inString = !inString
+		case inString:
+		case s[pos] == open:
+			depth++
+		case s[pos] == close:
+			depth--
+			if depth == 0 {
+				return s[start:pos], pos + 1, nil
+			}
+		}
+	}
+
+	return "", pos, fmt.Errorf("unbalanced '%c' in query", open)
+}
+
+// paginate returns the page of result selected by the pagination argument in args limited to max objects when result
+// is a list. All other results are returned as is.
+func paginate(result any, args string, max int) any {
+	var (
+		list   []map[string]any
+		match  []string = argPagination.FindStringSubmatch(args)
+		field  []string
+		offset int
+		limit  int = -1
+		ok     bool
+	)
+
+	if list, ok = result.([]map[string]any); !ok {
+		return result
+	}
+
+	if match != nil {
+		if field = argOffset.FindStringSubmatch(match[1]); field != nil {
+			offset, _ = strconv.Atoi(field[1])
+		}
+
+		if field = argLimit.FindStringSubmatch(match[1]); field != nil {
+			limit, _ = strconv.Atoi(field[1])
+		}
+
+		list = list[min(offset, len(list)):]
+
+		if limit >= 0 {
+			list = list[:min(limit, len(list))]
+		}
+	}
+
+	if max > 0 {
+		list = list[:min(max, len(list))]
+	}
+
+	return list
+}
+
+// renderOne returns the rendered object matching the id argument or nil.
+func renderOne[T any](objs []T, args string, id func(T) uint64, render func(T) map[string]any) any {
+	var (
+		match []string = argID.FindStringSubmatch(args)
+		want  uint64
+		i     int
+	)
+
+	if match == nil {
+		return nil
+	}
+
+	want, _ = strconv.ParseUint(match[1], 10, 64)
+
+	for i = range objs {
+		if id(objs[i]) == want {
+			return render(objs[i])
+		}
+	}
+
+	return nil
+}
+
+// renderList returns all rendered objects for which filter returns true.
+func renderList[T any](objs []T, filter func(T) bool, render func(T) map[string]any) []map[string]any {
+	var (
+		result []map[string]any = make([]map[string]any, 0, len(objs))
+		i      int
+	)
+
+	for i = range objs {
+		if filter(objs[i]) {
+			result = append(result, render(objs[i]))
+		}
+	}
+
+	return result
+}
+
+// matchTag returns true when args don't contain a tag filter or the tag is part of tags.
+func matchTag(tags []string, args string) bool {
+	var (
+		match []string = argTag.FindStringSubmatch(args)
+		tag   string
+	)
+
+	if match == nil {
+		return true
+	}
+
+	for _, tag = range tags {
+		if tag == unquote(match[1]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchPrefix returns true when args don't contain a filter for arg or value starts with the filter value.
+func matchPrefix(arg *regexp.Regexp, value, args string) bool {
+	var match []string = arg.FindStringSubmatch(args)
+
+	return match == nil || strings.HasPrefix(value, unquote(match[1]))
+}
+
+// matchExact returns true when args don't contain a filter for arg or value equals the filter value.
+func matchExact(arg *regexp.Regexp, value, args string) bool {
+	var match []string = arg.FindStringSubmatch(args)
+
+	return match == nil || value == unquote(match[1])
+}
+
+// matchList returns true when args don't contain the list argument or value is one of the list's values.
+func matchList(arg *regexp.Regexp, value, args string) bool {
+	var (
+		match  []string = arg.FindStringSubmatch(args)
+		values []string
+		v      string
+	)
+
+	if match == nil {
+		return true
+	}
+
+	if json.Unmarshal([]byte(match[1]), &values) != nil {
+		return false
+	}
+
+	for _, v = range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchDeviceFilter returns true when the device or vm matches all site, role, tenant, platform and status list
+// arguments in args.
+func matchDeviceFilter(d *Device, args string) bool {
+	return matchList(argSiteList, d.Site, args) &&
+		matchList(argRoleList, d.Role, args) &&
+		matchList(argTenantList, d.Tenant, args) &&
+		matchList(argPlatformList, d.Platform, args) &&
+		matchList(argStatusList, d.Status, args)
+}
+
+// matchSiteGroup returns true when args don't contain a site group filter or the device's site is part of the site
+// group.
+func (f *Fixtures) matchSiteGroup(d *Device, args string) bool {
+	var match []string = argSiteGroup.FindStringSubmatch(args)
+
+	return match == nil || f.inSiteGroup(d.Site, unquote(match[1]))
+}
+
+// matchVLAN returns true when args don't contain a vlan filter or the interface is attached to the vlan.
+func matchVLAN(i *Interface, args string) bool {
+	var (
+		match []string = argVLANID.FindStringSubmatch(args)
+		id    uint64
+		vlan  uint64
+	)
+
+	if match == nil {
+		return true
+	}
+
+	id, _ = strconv.ParseUint(match[1], 10, 64)
+
+	for _, vlan = range append([]uint64{i.UntaggedVLAN}, i.TaggedVLANs...) {
+		if vlan == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchWirelessLAN returns true when args don't contain a wireless LAN filter or the interface is attached to the
+// wireless LAN.
+func matchWirelessLAN(i *Interface, args string) bool {
+	var (
+		match []string = argWirelessLANID.FindStringSubmatch(args)
+		id    uint64
+		wlan  uint64
+	)
+
+	if match == nil {
+		return true
+	}
+
+	id, _ = strconv.ParseUint(match[1], 10, 64)
+
+	for _, wlan = range i.WirelessLANs {
+		if wlan == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchIP returns true when ip matches all filters in args.
+func (f *Fixtures) matchIP(ip *IP, args string) bool {
+	var (
+		match   []string
+		id      uint64
+		iface   *Interface
+		parents []string
+	)
+
+	if !matchPrefix(argAddress, ip.Address, args) {
+		return false
+	}
+
+	if match = argParentList.FindStringSubmatch(args); match != nil {
+		if json.Unmarshal([]byte(match[1]), &parents) != nil {
+			return false
+		}
+
+		if !slices.ContainsFunc(parents, func(parent string) bool { return inPrefix(ip.Address, parent) }) {
+			return false
+		}
+	}
+
+	if match = argInterfaceID.FindStringSubmatch(args); match != nil {
+		id, _ = strconv.ParseUint(match[1], 10, 64)
+		iface = f.iface(ip.Interface)
+
+		return ip.Interface == id && iface != nil && iface.Device != ""
+	}
+
+	if match = argVMInterfaceID.FindStringSubmatch(args); match != nil {
+		id, _ = strconv.ParseUint(match[1], 10, 64)
+		iface = f.iface(ip.Interface)
+
+		return ip.Interface == id && iface != nil && iface.VM != ""
+	}
+
+	return true
+}
+
+// inPrefix returns true when address (in cidr notation like Netbox returns addresses) lies within prefix.
+func inPrefix(address, prefix string) bool {
+	var (
+		addr   netip.Prefix
+		parent netip.Prefix
+		err    error
+	)
+
+	addr, err = netip.ParsePrefix(address)
+	if err != nil {
+		return false
+	}
+
+	parent, err = netip.ParsePrefix(prefix)
+	if err != nil {
+		return false
+	}
+
+	return parent.Contains(addr.Addr())
+}
+
+func unquote(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, `\"`, `"`), `\\`, `\`)
+}
+
+func (f *Fixtures) deviceInterfaces() []*Interface {
+	var result []*Interface
+
+	for _, iface := range f.Interfaces {
+		if iface.Device != "" {
+			result = append(result, iface)
+		}
+	}
+
+	return result
+}
+
+func (f *Fixtures) vmInterfaces() []*Interface {
+	var result []*Interface
+
+	for _, iface := range f.Interfaces {
+		if iface.VM != "" {
+			result = append(result, iface)
+		}
+	}
+
+	return result
+}
+
+// The following functions render objects in the same JSON structure as Netbox' GraphQL API does.
+
+func renderName(name string) any {
+	if name == "" {
+		return nil
+	}
+
+	return map[string]any{"name": name}
+}
+
+func renderTags(tags []string) []map[string]any {
+	var result []map[string]any = make([]map[string]any, 0, len(tags))
+
+	for _, tag := range tags {
+		result = append(result, map[string]any{"name": tag, "slug": tag})
+	}
+
+	return result
+}
+
+func renderCustomFields(cf map[string]any) map[string]any {
+	if cf == nil {
+		return map[string]any{}
+	}
+
+	return cf
+}
+
+func (f *Fixtures) renderIP(ip *IP) map[string]any {
+	var vrf any
+
+	if ip.VRF != "" {
+		vrf = map[string]any{"id": strconv.Itoa(f.vrfID(ip.VRF)), "name": ip.VRF}
+	}
+
+	return map[string]any{
+		"id":       strconv.FormatUint(ip.ID, 10),
+		"address":  ip.Address,
+		"status":   ip.Status,
+		"dns_name": ip.DNSName,
+		"vrf":      vrf,
+	}
+}
+
+// vrfID returns a stable id for a VRF name based on the order of appearance.
+func (f *Fixtures) vrfID(name string) int {
+	var (
+		seen map[string]int = make(map[string]int)
+		ip   *IP
+	)
+
+	for _, ip = range f.IPAddresses {
+		if _, ok := seen[ip.VRF]; ip.VRF != "" && !ok {
+			seen[ip.VRF] = len(seen) + 1
+		}
+	}
+
+	return seen[name]
+}
+
+func (f *Fixtures) renderPrimaryIP(address string) any {
+	if address == "" {
+		return nil
+	}
+
+	return f.renderIP(f.ipByAddress(address))
+}
+
+func (f *Fixtures) renderDevice(d *Device) map[string]any {
+	return map[string]any{
+		"id":             strconv.FormatUint(d.ID, 10),
+		"name":           d.Name,
+		"primary_ip4":    f.renderPrimaryIP(d.PrimaryIP4),
+		"primary_ip6":    f.renderPrimaryIP(d.PrimaryIP6),
+		"custom_fields":  renderCustomFields(d.CustomFields),
+		"rack":           renderName(d.Rack),
+		"site":           renderName(d.Site),
+		"role":           renderName(d.Role),
+		"tenant":         renderName(d.Tenant),
+		"platform":       renderName(d.Platform),
+		"serial":         d.SerialNumber,
+		"asset_tag":      d.AssetTag,
+		"status":         d.Status,
+		"tags":           renderTags(d.Tags),
+		"config_context": renderConfigContext(d.ConfigContext),
+	}
+}
+
+func (f *Fixtures) renderVM(d *Device) map[string]any {
+	return map[string]any{
+		"id":             strconv.FormatUint(d.ID, 10),
+		"name":           d.Name,
+		"primary_ip4":    f.renderPrimaryIP(d.PrimaryIP4),
+		"primary_ip6":    f.renderPrimaryIP(d.PrimaryIP6),
+		"custom_fields":  renderCustomFields(d.CustomFields),
+		"site":           renderName(d.Site),
+		"role":           renderName(d.Role),
+		"tenant":         renderName(d.Tenant),
+		"platform":       renderName(d.Platform),
+		"status":         d.Status,
+		"tags":           renderTags(d.Tags),
+		"config_context": renderConfigContext(d.ConfigContext),
+	}
+}
+
+func (f *Fixtures) renderInterface(i *Interface) map[string]any {
+	var device, mode any
+
+	if i.Mode != "" {
+		mode = i.Mode
+	}
+
+	if i.Device != "" {
+		device = f.renderDevice(f.device(i.Device))
+	} else {
+		device = f.renderVM(f.vm(i.VM))
+	}
+
+	return map[string]any{
+		"id":            strconv.FormatUint(i.ID, 10),
+		"name":          i.Name,
+		"enabled":       *i.Enabled,
+		"custom_fields": renderCustomFields(i.CustomFields),
+		"device":        device,
+		"tags":          renderTags(i.Tags),
+		"mode":          mode,
+		"untagged_vlan": f.renderVLANRef(i.UntaggedVLAN),
+		"tagged_vlans":  f.renderVLANRefs(i.TaggedVLANs),
+	}
+}
+
+func (f *Fixtures) renderVDC(v *VDC) map[string]any {
+	return map[string]any{
+		"id":            strconv.FormatUint(v.ID, 10),
+		"name":          v.Name,
+		"identifier":    v.Identifier,
+		"status":        v.Status,
+		"primary_ip4":   f.renderPrimaryIP(v.PrimaryIP4),
+		"primary_ip6":   f.renderPrimaryIP(v.PrimaryIP6),
+		"custom_fields": renderCustomFields(v.CustomFields),
+		"tenant":        renderName(v.Tenant),
+		"tags":          renderTags(v.Tags),
+		"device":        f.renderDevice(f.device(v.Device)),
+	}
+}
+
+func renderConfigContext(ctx map[string]any) map[string]any {
+	if ctx == nil {
+		return map[string]any{}
+	}
+
+	return ctx
+}
+
+func renderVLAN(v *VLAN) map[string]any {
+	var status any
+
+	if v.Status != "" {
+		status = v.Status
+	}
+
+	return map[string]any{
+		"id":     strconv.FormatUint(v.ID, 10),
+		"vid":    v.VID,
+		"name":   v.Name,
+		"status": status,
+		"group":  renderName(v.Group),
+		"site":   renderName(v.Site),
+		"tenant": renderName(v.Tenant),
+		"tags":   renderTags(v.Tags),
+	}
+}
+
+func renderWirelessLAN(w *WirelessLAN) map[string]any {
+	return map[string]any{
+		"id":   strconv.FormatUint(w.ID, 10),
+		"ssid": w.SSID,
+	}
+}
+
+func (f *Fixtures) renderVLANRef(id uint64) any {
+	if id == 0 {
+		return nil
+	}
+
+	return renderVLAN(f.vlan(id))
+}
+
+func (f *Fixtures) renderVLANRefs(ids []uint64) []map[string]any {
+	var (
+		result []map[string]any = make([]map[string]any, 0, len(ids))
+		id     uint64
+	)
+
+	for _, id = range ids {
+		result = append(result, renderVLAN(f.vlan(id)))
+	}
+
+	return result
+}
+
+func (f *Fixtures) renderService(s *Service) map[string]any {
+	var (
+		device any
+		vm     any
+		ips    []map[string]any = make([]map[string]any, 0, len(s.IPAddresses))
+		addr   string
+	)
+
+	if s.Device != "" {
+		device = f.renderDevice(f.device(s.Device))
+	} else {
+		vm = f.renderVM(f.vm(s.VM))
+	}
+
+	for _, addr = range s.IPAddresses {
+		ips = append(ips, f.renderIP(f.ipByAddress(addr)))
+	}
+
+	return map[string]any{
+		"id":              strconv.FormatUint(s.ID, 10),
+		"name":            s.Name,
+		"device":          device,
+		"virtual_machine": vm,
+		"ports":           s.Ports,
+		"protocol":        s.Protocol,
+		"ipaddresses":     ips,
+		"custom_fields":   renderCustomFields(s.CustomFields),
+	}
+}
@@ -0,0 +1,753 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netboxtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+	"github.com/4xoc/netbox_sd/pkg/netbox/netboxtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClient(t *testing.T) netbox.ClientIface {
+	var (
+		fixtures *netboxtest.Fixtures
+		server   *netboxtest.Server
+		client   netbox.ClientIface
+		err      error
+	)
+
+	fixtures, err = netboxtest.LoadFixtures("testdata/fixtures.yml")
+	require.Nil(t, err)
+
+	server = netboxtest.NewServer(fixtures)
+	t.Cleanup(server.Close)
+
+	client, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netboxtest"))
+	require.Nil(t, err)
+
+	return client
+}
+
+func TestServerAuth(t *testing.T) {
+	var (
+		server *netboxtest.Server = netboxtest.NewServer(nil)
+		client netbox.ClientIface
+		resp   *http.Response
+		err    error
+	)
+
+	defer server.Close()
+
+	resp, err = http.Get(server.URL + "/api/status/")
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	client, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netboxtest"))
+	require.Nil(t, err)
+	assert.Nil(t, client.VerifyConnectivity(context.Background()))
+}
+
+func TestServerDevices(t *testing.T) {
+	var (
+		client  netbox.ClientIface = newClient(t)
+		devices []*netbox.Device
+		device  *netbox.Device
+		err     error
+	)
+
+	devices, err = client.GetDevicesByTag(context.Background(), "junos_exporter")
+	require.Nil(t, err)
+	require.Len(t, devices, 2)
+	assert.Equal(t, "device-A", devices[0].Name)
+	assert.Equal(t, "site-A", devices[0].Site.Name)
+	assert.Equal(t, "router", devices[0].Role.Name)
+	assert.Equal(t, "192.0.2.1/24", devices[0].PrimaryIP4.Address)
+	assert.Equal(t, "mgmt", devices[0].PrimaryIP4.VRF.Name)
+	assert.Equal(t, "2001:db8::1/64", devices[0].PrimaryIP6.Address)
+	assert.Equal(t, "offline", devices[1].Status)
+	assert.Nil(t, devices[1].PrimaryIP4)
+
+	devices, err = client.GetDevicesByTag(context.Background(), "unknown")
+	require.Nil(t, err)
+	assert.Len(t, devices, 0)
+
+	devices, err = client.GetDevicesByTagFiltered(context.Background(), "junos_exporter", &netbox.DeviceFilter{Statuses: []string{"offline"}})
+	require.Nil(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "device-B", devices[0].Name)
+
+	devices, err = client.GetDevicesByTagFiltered(context.Background(), "junos_exporter", &netbox.DeviceFilter{
+		Sites: []string{"site-A", "site-B"},
+		Roles: []string{"router"},
+	})
+	require.Nil(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "device-A", devices[0].Name)
+
+	devices, err = client.GetDevicesByTagFiltered(context.Background(), "junos_exporter", &netbox.DeviceFilter{Roles: []string{"switch"}})
+	require.Nil(t, err)
+	assert.Len(t, devices, 0)
+
+	devices, err = client.GetDevicesBySiteGroup(context.Background(), "europe")
+	require.Nil(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "device-A", devices[0].Name)
+
+	devices, err = client.GetDevicesBySiteGroup(context.Background(), "unknown")
+	require.Nil(t, err)
+	assert.Len(t, devices, 0)
+
+	devices, err = client.GetDevicesByManufacturer(context.Background(), "juniper")
+	require.Nil(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "device-A", devices[0].Name)
+
+	device, err = client.GetDevice(context.Background(), 2)
+	require.Nil(t, err)
+	require.NotNil(t, device)
+	assert.Equal(t, "device-B", device.Name)
+
+	device, err = client.GetDevice(context.Background(), 42)
+	assert.Nil(t, err)
+	assert.Nil(t, device)
+}
+
+func TestServerVMs(t *testing.T) {
+	var (
+		client netbox.ClientIface = newClient(t)
+		vms    []*netbox.Device
+		err    error
+	)
+
+	vms, err = client.GetVMsByTag(context.Background(), "node_exporter")
+	require.Nil(t, err)
+	require.Len(t, vms, 1)
+	assert.Equal(t, "vm-A", vms[0].Name)
+	assert.True(t, vms[0].IsVirtual())
+
+	vms, err = client.GetVMsByCluster(context.Background(), "cluster-A")
+	require.Nil(t, err)
+	require.Len(t, vms, 1)
+	assert.Equal(t, "vm-A", vms[0].Name)
+
+	vms, err = client.GetVMsByCluster(context.Background(), "unknown")
+	require.Nil(t, err)
+	assert.Len(t, vms, 0)
+
+	vms, err = client.GetVMsByClusterGroup(context.Background(), "platform-A")
+	require.Nil(t, err)
+	require.Len(t, vms, 1)
+	assert.Equal(t, "vm-A", vms[0].Name)
+
+	vms, err = client.GetVMsByClusterGroup(context.Background(), "unknown")
+	require.Nil(t, err)
+	assert.Len(t, vms, 0)
+}
+
+func TestServerRESTQuery(t *testing.T) {
+	var (
+		client  netbox.ClientIface = newClient(t)
+		devices []*netbox.Device
+		err     error
+	)
+
+	devices, err = client.GetDevicesByQuery(context.Background(), "role=router&status=active")
+	require.Nil(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "device-A", devices[0].Name)
+	assert.Equal(t, "router", devices[0].Role.Name)
+	require.NotNil(t, devices[0].PrimaryIP4)
+	assert.Equal(t, "192.0.2.1/24", devices[0].PrimaryIP4.Address)
+	assert.Equal(t, "active", devices[0].PrimaryIP4.Status)
+	assert.Equal(t, "mgmt", devices[0].PrimaryIP4.VRF.Name)
+
+	devices, err = client.GetDevicesByQuery(context.Background(), "status=active&status=offline")
+	require.Nil(t, err)
+	assert.Len(t, devices, 2)
+
+	devices, err = client.GetVMsByQuery(context.Background(), "cluster=cluster-A")
+	require.Nil(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "vm-A", devices[0].Name)
+	assert.True(t, devices[0].IsVirtual())
+
+	_, err = client.GetDevicesByQuery(context.Background(), "status=%zz")
+	assert.ErrorIs(t, err, netbox.ErrBadRESTQuery)
+}
+
+func TestServerRESTPagination(t *testing.T) {
+	var (
+		fixtures *netboxtest.Fixtures
+		server   *netboxtest.Server
+		req      *http.Request
+		resp     *http.Response
+		page     struct {
+			Count   int               `json:"count"`
+			Next    *string           `json:"next"`
+			Results []json.RawMessage `json:"results"`
+		}
+		err error
+	)
+
+	fixtures, err = netboxtest.LoadFixtures("testdata/fixtures.yml")
+	require.Nil(t, err)
+
+	server = netboxtest.NewServer(fixtures)
+	defer server.Close()
+
+	req, err = http.NewRequest(http.MethodGet, server.URL+"/api/dcim/devices/?limit=1", nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "Token "+server.Token)
+
+	resp, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&page))
+	assert.Equal(t, 2, page.Count)
+	assert.Len(t, page.Results, 1)
+	require.NotNil(t, page.Next)
+	assert.Contains(t, *page.Next, "offset=1")
+}
+
+func TestServerGraphQLPagination(t *testing.T) {
+	var (
+		fixtures *netboxtest.Fixtures
+		server   *netboxtest.Server
+		client   netbox.ClientIface
+		devices  []*netbox.Device
+		err      error
+	)
+
+	fixtures, err = netboxtest.LoadFixtures("testdata/fixtures.yml")
+	require.Nil(t, err)
+
+	server = netboxtest.NewServer(fixtures)
+	server.MaxListSize = 1
+	defer server.Close()
+
+	client, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netboxtest"))
+	require.Nil(t, err)
+
+	// truncated by the server
+	devices, err = client.GetDevicesByTag(context.Background(), "junos_exporter")
+	require.Nil(t, err)
+	assert.Len(t, devices, 1)
+
+	client.SetPageSize(1)
+
+	devices, err = client.GetDevicesByTag(context.Background(), "junos_exporter")
+	require.Nil(t, err)
+	require.Len(t, devices, 2)
+	assert.Equal(t, "device-A", devices[0].Name)
+	assert.Equal(t, "device-B", devices[1].Name)
+
+	// a full last page requires an additional request
+	server.MaxListSize = 2
+	client.SetPageSize(2)
+
+	devices, err = client.GetDevices(context.Background())
+	require.Nil(t, err)
+	assert.Len(t, devices, 2)
+}
+
+func TestServerCancel(t *testing.T) {
+	var (
+		client netbox.ClientIface = newClient(t)
+		ctx    context.Context
+		cancel context.CancelFunc
+		err    error
+	)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	_, err = client.GetDevices(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = client.GetDevicesByQuery(ctx, "status=active")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// the client remains usable with a live context
+	_, err = client.GetDevices(context.Background())
+	assert.Nil(t, err)
+}
+
+func TestServerVDCs(t *testing.T) {
+	var (
+		client netbox.ClientIface = newClient(t)
+		vdcs   []*netbox.VDC
+		err    error
+	)
+
+	vdcs, err = client.GetVDCsByTag(context.Background(), "vdc_exporter")
+	require.Nil(t, err)
+	require.Len(t, vdcs, 1)
+	assert.Equal(t, "device-A-vdc1", vdcs[0].Name)
+	assert.Equal(t, uint64(1), vdcs[0].ID)
+	require.NotNil(t, vdcs[0].Identifier)
+	assert.Equal(t, 2, *vdcs[0].Identifier)
+	require.NotNil(t, vdcs[0].Parent)
+	assert.Equal(t, "device-A", vdcs[0].Parent.Name)
+	assert.Equal(t, "192.0.2.1/24", vdcs[0].PrimaryIP4.Address)
+
+	vdcs, err = client.GetVDCsByTag(context.Background(), "unknown")
+	require.Nil(t, err)
+	assert.Len(t, vdcs, 0)
+}
+
+func TestServerVLANs(t *testing.T) {
+	var (
+		client netbox.ClientIface = newClient(t)
+		vlans  []*netbox.VLAN
+		err    error
+	)
+
+	vlans, err = client.GetVLANsByVID(context.Background(), 200)
+	require.Nil(t, err)
+	require.Len(t, vlans, 1)
+	assert.Equal(t, "storage", vlans[0].Name)
+	assert.Equal(t, uint64(2), vlans[0].ID)
+
+	vlans, err = client.GetVLANsByName(context.Background(), "mgmt")
+	require.Nil(t, err)
+	require.Len(t, vlans, 1)
+	assert.Equal(t, uint16(100), vlans[0].VID)
+
+	vlans, err = client.GetVLANsByName(context.Background(), "unknown")
+	require.Nil(t, err)
+	assert.Len(t, vlans, 0)
+
+	vlans, err = client.GetVLANs(context.Background())
+	require.Nil(t, err)
+	require.Len(t, vlans, 2)
+	assert.Equal(t, "active", vlans[1].Status)
+	assert.Equal(t, "datacenter-A", vlans[1].Group.Name)
+	assert.Equal(t, "site-A", vlans[1].Site.Name)
+	assert.Equal(t, []netbox.Tag{{Name: "storage", Slug: "storage"}}, vlans[1].Tags)
+}
+
+func TestServerWirelessLANs(t *testing.T) {
+	var (
+		client netbox.ClientIface = newClient(t)
+		wlans  []*netbox.WirelessLAN
+		ifaces []*netbox.Interface
+		err    error
+	)
+
+	wlans, err = client.GetWirelessLANsBySSID(context.Background(), "corp")
+	require.Nil(t, err)
+	require.Len(t, wlans, 1)
+	assert.Equal(t, uint64(1), wlans[0].ID)
+	assert.Equal(t, "corp", wlans[0].SSID)
+
+	wlans, err = client.GetWirelessLANsBySSID(context.Background(), "unknown")
+	require.Nil(t, err)
+	assert.Len(t, wlans, 0)
+
+	ifaces, err = client.GetInterfacesByWirelessLAN(context.Background(), 1)
+	require.Nil(t, err)
+	require.Len(t, ifaces, 1)
+	assert.Equal(t, "ipmi", ifaces[0].Name)
+	assert.Equal(t, "device-A", ifaces[0].Device.Name)
+
+	ifaces, err = client.GetInterfacesByWirelessLAN(context.Background(), 42)
+	require.Nil(t, err)
+	assert.Len(t, ifaces, 0)
+}
+
+func TestServerInterfaces(t *testing.T) {
+	var (
+		client netbox.ClientIface = newClient(t)
+		ifaces []*netbox.Interface
+		ips    []*netbox.IP
+		err    error
+	)
+
+	ifaces, err = client.GetInterfacesByTag(context.Background(), "ipmi_exporter")
+	require.Nil(t, err)
+	require.Len(t, ifaces, 1)
+	assert.Equal(t, "ipmi", ifaces[0].Name)
+	assert.True(t, ifaces[0].Enabled)
+	assert.Equal(t, "device-A", ifaces[0].Device.Name)
+
+	ifaces, err = client.GetVirtualInterfacesByTag(context.Background(), "ipmi_exporter")
+	require.Nil(t, err)
+	require.Len(t, ifaces, 1)
+	assert.Equal(t, "eth0", ifaces[0].Name)
+	assert.False(t, ifaces[0].Enabled)
+	assert.Equal(t, "vm-A", ifaces[0].Device.Name)
+
+	ifaces, err = client.GetInterfacesByVLAN(context.Background(), 2)
+	require.Nil(t, err)
+	require.Len(t, ifaces, 1)
+	assert.Equal(t, "ipmi", ifaces[0].Name)
+	assert.Equal(t, "tagged", ifaces[0].Mode)
+	require.NotNil(t, ifaces[0].UntaggedVLAN)
+	assert.Equal(t, uint16(100), ifaces[0].UntaggedVLAN.VID)
+	require.Len(t, ifaces[0].TaggedVLANs, 1)
+	assert.Equal(t, uint64(2), ifaces[0].TaggedVLANs[0].ID)
+
+	ifaces, err = client.GetVirtualInterfacesByVLAN(context.Background(), 2)
+	require.Nil(t, err)
+	assert.Len(t, ifaces, 0)
+
+	ips, err = client.GetInterfaceIPs(context.Background(), 1)
+	require.Nil(t, err)
+	require.Len(t, ips, 1)
+	assert.Equal(t, "198.51.100.1/24", ips[0].Address)
+
+	ips, err = client.GetVirtualInterfaceIPs(context.Background(), 2)
+	require.Nil(t, err)
+	require.Len(t, ips, 1)
+	assert.Equal(t, "2001:db8:1::1/64", ips[0].Address)
+	assert.Equal(t, "deprecated", ips[0].Status)
+
+	// interface 1 belongs to a device, not a VM
+	ips, err = client.GetVirtualInterfaceIPs(context.Background(), 1)
+	require.Nil(t, err)
+	assert.Len(t, ips, 0)
+}
+
+func TestServerServices(t *testing.T) {
+	var (
+		client   netbox.ClientIface = newClient(t)
+		services []*netbox.Service
+		ips      []*netbox.IP
+		err      error
+	)
+
+	services, err = client.GetServicesByName(context.Background(), "node_exporter")
+	require.Nil(t, err)
+	require.Len(t, services, 1)
+	assert.Equal(t, "vm-A", services[0].VM.Name)
+	assert.Nil(t, services[0].Device)
+	assert.Equal(t, []int{9100}, services[0].Ports)
+	require.Len(t, services[0].IPAddresses, 1)
+	assert.Equal(t, "2001:db8::10/64", services[0].IPAddresses[0].Address)
+
+	ips, err = client.GetIPsByAddress(context.Background(), "192.0.2.1")
+	require.Nil(t, err)
+	require.Len(t, ips, 1)
+	assert.Equal(t, "mgmt", ips[0].VRF.Name)
+}
+
+func TestServerPersistedQueries(t *testing.T) {
+	var (
+		fixtures *netboxtest.Fixtures
+		server   *netboxtest.Server
+		client   netbox.ClientIface
+		device   *netbox.Device
+		err      error
+	)
+
+	fixtures, err = netboxtest.LoadFixtures("testdata/fixtures.yml")
+	require.Nil(t, err)
+
+	server = netboxtest.NewServer(fixtures)
+	server.PersistedQueries = true
+	t.Cleanup(server.Close)
+
+	client, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netboxtest"))
+	require.Nil(t, err)
+	client.UsePersistedQueries(true)
+
+	// first request registers the query
+	device, err = client.GetDevice(context.Background(), 2)
+	require.Nil(t, err)
+	require.NotNil(t, device)
+	assert.Equal(t, "device-B", device.Name)
+	assert.Equal(t, 1, server.RegisteredQueries())
+
+	// second request is served by hash only
+	device, err = client.GetDevice(context.Background(), 2)
+	require.Nil(t, err)
+	require.NotNil(t, device)
+	assert.Equal(t, "device-B", device.Name)
+	assert.Equal(t, 1, server.RegisteredQueries())
+}
+
+// withoutConfigContext removes the config context from devices. The fake server ignores selection sets, thus GraphQL
+// responses always contain config contexts.
+func withoutConfigContext(devices ...*netbox.Device) {
+	for _, device := range devices {
+		if device != nil {
+			device.ConfigContext = nil
+		}
+	}
+}
+
+func TestServerREST(t *testing.T) {
+	var (
+		fixtures *netboxtest.Fixtures
+		server   *netboxtest.Server
+		client   *netbox.Client
+		graphQL  netbox.ClientIface
+		rest     netbox.ClientIface
+		ctx      context.Context = context.Background()
+		err      error
+	)
+
+	fixtures, err = netboxtest.LoadFixtures("testdata/fixtures.yml")
+	require.Nil(t, err)
+
+	server = netboxtest.NewServer(fixtures)
+	t.Cleanup(server.Close)
+
+	graphQL, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netboxtest"))
+	require.Nil(t, err)
+
+	client, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netboxtest_rest"))
+	require.Nil(t, err)
+	rest = netbox.NewREST(client)
+
+	// devices and VMs
+	for _, lookup := range []func(netbox.ClientIface) ([]*netbox.Device, error){
+		func(api netbox.ClientIface) ([]*netbox.Device, error) { return api.GetDevices(ctx) },
+		func(api netbox.ClientIface) ([]*netbox.Device, error) {
+			return api.GetDevicesByTag(ctx, "junos_exporter")
+		},
+		func(api netbox.ClientIface) ([]*netbox.Device, error) {
+			return api.GetDevicesByTagFiltered(ctx, "junos_exporter", &netbox.DeviceFilter{Statuses: []string{"offline"}})
+		},
+		func(api netbox.ClientIface) ([]*netbox.Device, error) {
+			return api.GetDevicesByManufacturer(ctx, "juniper")
+		},
+		func(api netbox.ClientIface) ([]*netbox.Device, error) {
+			return api.GetDevicesBySiteGroup(ctx, "europe")
+		},
+		func(api netbox.ClientIface) ([]*netbox.Device, error) { return api.GetVMs(ctx) },
+		func(api netbox.ClientIface) ([]*netbox.Device, error) { return api.GetVMsByTag(ctx, "node_exporter") },
+		func(api netbox.ClientIface) ([]*netbox.Device, error) { return api.GetVMsByCluster(ctx, "cluster-A") },
+		func(api netbox.ClientIface) ([]*netbox.Device, error) {
+			return api.GetVMsByClusterGroup(ctx, "platform-A")
+		},
+		func(api netbox.ClientIface) ([]*netbox.Device, error) {
+			device, err := api.GetDevice(ctx, 1)
+			return []*netbox.Device{device}, err
+		},
+		func(api netbox.ClientIface) ([]*netbox.Device, error) {
+			vm, err := api.GetVM(ctx, 1)
+			return []*netbox.Device{vm}, err
+		},
+	} {
+		expected, err := lookup(graphQL)
+		require.Nil(t, err)
+		require.NotEmpty(t, expected)
+		withoutConfigContext(expected...)
+
+		actual, err := lookup(rest)
+		require.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	// config contexts are only returned when requested
+	for _, lookup := range []func(netbox.ClientIface) ([]*netbox.Device, error){
+		func(api netbox.ClientIface) ([]*netbox.Device, error) { return api.GetDevicesWithConfigContext(ctx) },
+		func(api netbox.ClientIface) ([]*netbox.Device, error) { return api.GetVMsWithConfigContext(ctx) },
+	} {
+		expected, err := lookup(graphQL)
+		require.Nil(t, err)
+
+		actual, err := lookup(rest)
+		require.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	// config contexts of single devices and vms
+	for i, lookup := range []func(netbox.ClientIface, uint64) (map[string]any, error){
+		func(api netbox.ClientIface, id uint64) (map[string]any, error) {
+			return api.GetDeviceConfigContext(ctx, id)
+		},
+		func(api netbox.ClientIface, id uint64) (map[string]any, error) {
+			return api.GetVMConfigContext(ctx, id)
+		},
+	} {
+		expected, err := lookup(graphQL, 1)
+		require.Nil(t, err)
+		assert.Equal(t, map[string]any{"monitoring": map[string]any{"port": []float64{9326, 9100}[i]}}, expected)
+
+		actual, err := lookup(rest, 1)
+		require.Nil(t, err)
+		assert.Equal(t, expected, actual)
+
+		for _, api := range []netbox.ClientIface{graphQL, rest} {
+			actual, err = lookup(api, 99999)
+			require.Nil(t, err)
+			assert.Nil(t, actual)
+		}
+	}
+
+	// batches
+	batch := []netbox.DeviceQuery{{Tag: "junos_exporter"}, {Virtual: true, Tag: "node_exporter"}}
+	expectedLists, err := graphQL.GetDevicesBatch(ctx, batch)
+	require.Nil(t, err)
+	withoutConfigContext(append(expectedLists[0], expectedLists[1]...)...)
+	actualLists, err := rest.GetDevicesBatch(ctx, batch)
+	require.Nil(t, err)
+	assert.Equal(t, expectedLists, actualLists)
+
+	// iterators yield the same devices as lists
+	for _, query := range batch {
+		var (
+			expected [][]*netbox.Device
+			actual   []*netbox.Device
+		)
+
+		expected, err = graphQL.GetDevicesBatch(ctx, []netbox.DeviceQuery{query})
+		require.Nil(t, err)
+
+		err = graphQL.ForEachDevice(ctx, query, func(device *netbox.Device) error {
+			actual = append(actual, device)
+			return nil
+		})
+		require.Nil(t, err)
+		assert.Equal(t, expected[0], actual)
+
+		withoutConfigContext(expected[0]...)
+		actual = nil
+
+		err = rest.ForEachDevice(ctx, query, func(device *netbox.Device) error {
+			actual = append(actual, device)
+			return nil
+		})
+		require.Nil(t, err)
+		assert.Equal(t, expected[0], actual)
+	}
+
+	// interfaces
+	for _, lookup := range []func(netbox.ClientIface) ([]*netbox.Interface, error){
+		func(api netbox.ClientIface) ([]*netbox.Interface, error) {
+			return api.GetInterfacesByTag(ctx, "ipmi_exporter")
+		},
+		func(api netbox.ClientIface) ([]*netbox.Interface, error) {
+			return api.GetVirtualInterfacesByTag(ctx, "ipmi_exporter")
+		},
+		func(api netbox.ClientIface) ([]*netbox.Interface, error) { return api.GetInterfacesByVLAN(ctx, 2) },
+		func(api netbox.ClientIface) ([]*netbox.Interface, error) {
+			return api.GetInterfacesByWirelessLAN(ctx, 1)
+		},
+		func(api netbox.ClientIface) ([]*netbox.Interface, error) {
+			iface, err := api.GetInterface(ctx, 1)
+			return []*netbox.Interface{iface}, err
+		},
+		func(api netbox.ClientIface) ([]*netbox.Interface, error) {
+			iface, err := api.GetVirtualInterface(ctx, 2)
+			return []*netbox.Interface{iface}, err
+		},
+	} {
+		expected, err := lookup(graphQL)
+		require.Nil(t, err)
+		require.NotEmpty(t, expected)
+
+		for _, iface := range expected {
+			withoutConfigContext(iface.Device)
+		}
+
+		actual, err := lookup(rest)
+		require.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	// IP addresses
+	for _, lookup := range []func(netbox.ClientIface) ([]*netbox.IP, error){
+		func(api netbox.ClientIface) ([]*netbox.IP, error) { return api.GetIPsByAddress(ctx, "192.0.2.1") },
+		func(api netbox.ClientIface) ([]*netbox.IP, error) { return api.GetInterfaceIPs(ctx, 1) },
+		func(api netbox.ClientIface) ([]*netbox.IP, error) { return api.GetVirtualInterfaceIPs(ctx, 2) },
+		func(api netbox.ClientIface) ([]*netbox.IP, error) { return api.GetIPsInPrefix(ctx, "2001:db8::/48") },
+	} {
+		expected, err := lookup(graphQL)
+		require.Nil(t, err)
+		require.NotEmpty(t, expected)
+
+		actual, err := lookup(rest)
+		require.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	ips, err := rest.GetIPsInPrefix(ctx, "2001:db8::/48")
+	require.Nil(t, err)
+	require.Len(t, ips, 2)
+	assert.Equal(t, "2001:db8::1/64", ips[0].Address)
+	assert.Equal(t, "2001:db8::10/64", ips[1].Address)
+
+	ips, err = rest.GetIPsByAddress(ctx, "192.0.2.1")
+	require.Nil(t, err)
+	require.Len(t, ips, 1)
+	assert.Equal(t, "device-a.example.com", ips[0].DNSName)
+
+	_, err = graphQL.GetIPsInPrefix(ctx, "2001:db8::1")
+	assert.ErrorIs(t, err, netbox.ErrInvalidPrefix)
+
+	// services
+	expectedServices, err := graphQL.GetServicesByName(ctx, "node_exporter")
+	require.Nil(t, err)
+	require.Len(t, expectedServices, 1)
+	withoutConfigContext(expectedServices[0].VM)
+	actualServices, err := rest.GetServicesByName(ctx, "node_exporter")
+	require.Nil(t, err)
+	assert.Equal(t, expectedServices, actualServices)
+
+	// VLANs
+	expectedVLANs, err := graphQL.GetVLANs(ctx)
+	require.Nil(t, err)
+	require.Len(t, expectedVLANs, 2)
+	actualVLANs, err := rest.GetVLANs(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, expectedVLANs, actualVLANs)
+
+	expectedVLANs, err = graphQL.GetVLANsByVID(ctx, 200)
+	require.Nil(t, err)
+	actualVLANs, err = rest.GetVLANsByVID(ctx, 200)
+	require.Nil(t, err)
+	assert.Equal(t, expectedVLANs, actualVLANs)
+
+	expectedVLANs, err = graphQL.GetVLANsByName(ctx, "mgmt")
+	require.Nil(t, err)
+	actualVLANs, err = rest.GetVLANsByName(ctx, "mgmt")
+	require.Nil(t, err)
+	assert.Equal(t, expectedVLANs, actualVLANs)
+
+	// wireless LANs
+	expectedWLANs, err := graphQL.GetWirelessLANsBySSID(ctx, "corp")
+	require.Nil(t, err)
+	actualWLANs, err := rest.GetWirelessLANsBySSID(ctx, "corp")
+	require.Nil(t, err)
+	assert.Equal(t, expectedWLANs, actualWLANs)
+
+	// VDCs
+	expectedVDCs, err := graphQL.GetVDCsByTag(ctx, "vdc_exporter")
+	require.Nil(t, err)
+	require.Len(t, expectedVDCs, 1)
+	withoutConfigContext(expectedVDCs[0].Parent)
+	actualVDCs, err := rest.GetVDCsByTag(ctx, "vdc_exporter")
+	require.Nil(t, err)
+	assert.Equal(t, expectedVDCs, actualVDCs)
+
+	// copies keep using the REST API
+	assert.IsType(t, &netbox.RESTClient{}, rest.Copy())
+}
@@ -0,0 +1,132 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains the options of New.
+
+import (
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+// Option configures a Client created by New.
+type Option func(*options)
+
+// options contains all settings applied by the Options passed to New.
+type options struct {
+	client        ClientOptions
+	promNamespace string
+	logger        *slog.Logger
+	tokens        TokenSource
+	// nativeHistogramFactor is the bucket factor of native histograms, 0 disables them.
+	nativeHistogramFactor float64
+}
+
+// WithPrometheusNamespace sets the namespace of all metrics of the Client. Without it, metric names have no namespace.
+func WithPrometheusNamespace(namespace string) Option {
+	return func(opts *options) {
+		opts.promNamespace = namespace
+	}
+}
+
+// WithNativeHistograms exposes the request duration histogram as native histogram in addition to its classic buckets.
+// Native histograms have a much higher resolution but must be enabled in Prometheus (native-histograms feature flag).
+func WithNativeHistograms(enabled bool) Option {
+	return func(opts *options) {
+		opts.nativeHistogramFactor = 0
+		if enabled {
+			opts.nativeHistogramFactor = 1.1
+		}
+	}
+}
+
+// WithLogger sets the slog.Logger used by the Client (see SetLogger). Without it, messages are logged through
+// slog.Default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(opts *options) {
+		opts.logger = logger
+	}
+}
+
+// WithTokenSource fetches the API token sent with each request from source, allowing to rotate tokens at runtime. The
+// token given to New is ignored and may be empty then.
+func WithTokenSource(source TokenSource) Option {
+	return func(opts *options) {
+		opts.tokens = source
+	}
+}
+
+// WithClientOptions sets all options of the HTTP connection at once. Options given after it override single settings.
+func WithClientOptions(clientOptions ClientOptions) Option {
+	return func(opts *options) {
+		opts.client = clientOptions
+	}
+}
+
+// WithTLS enables TLS for the HTTP transport using tlsConfig. Without it, the standard library's defaults are used for
+// https URLs, but public key pinning (see SetPinnedPublicKeys) is not available.
+func WithTLS(tlsConfig *TLSConfig) Option {
+	return func(opts *options) {
+		opts.client.TLS = tlsConfig
+	}
+}
+
+// WithTimeout limits the duration of a single request, including connecting and reading the response body.
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.client.Timeout = timeout
+	}
+}
+
+// WithDialTimeout limits the duration of establishing a TCP connection.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.client.DialTimeout = timeout
+	}
+}
+
+// WithResponseHeaderTimeout limits the time waiting for the response headers after the request has been written.
+func WithResponseHeaderTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.client.ResponseHeaderTimeout = timeout
+	}
+}
+
+// WithRetries retries requests failing due to network errors or with a 5xx or 429 status code up to maxAttempts
+// attempts in total, waiting backoff before the first retry (see ClientOptions).
+func WithRetries(maxAttempts int, backoff time.Duration) Option {
+	return func(opts *options) {
+		opts.client.MaxAttempts = maxAttempts
+		opts.client.RetryBackoff = backoff
+	}
+}
+
+// WithProxy sends all requests through the HTTP proxy at proxyURL instead of the proxy given by the environment.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(opts *options) {
+		opts.client.Proxy = proxyURL
+	}
+}
+
+// WithUserAgent sends userAgent as User-Agent header with all requests.
+func WithUserAgent(userAgent string) Option {
+	return func(opts *options) {
+		opts.client.UserAgent = userAgent
+	}
+}
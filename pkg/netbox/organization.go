@@ -0,0 +1,276 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains organizational objects (sites, tenants, racks, etc.).
+
+import "context"
+
+const (
+	queryOrganizationAttributes string = "id name tags{name slug} custom_fields"
+	querySiteList               string = "query($filters: SiteFilter, $pagination: OffsetPaginationInput){site_list(filters: $filters, pagination: $pagination){" + queryOrganizationAttributes + " slug status region{name} group{name} tenant{name}}}"
+	queryTenantList             string = "query($filters: TenantFilter, $pagination: OffsetPaginationInput){tenant_list(filters: $filters, pagination: $pagination){" + queryOrganizationAttributes + " slug group{name}}}"
+	queryRackList               string = "query($filters: RackFilter, $pagination: OffsetPaginationInput){rack_list(filters: $filters, pagination: $pagination){" + queryOrganizationAttributes + " status site{name} location{name} tenant{name}}}"
+	queryClusterList            string = "query($filters: ClusterFilter, $pagination: OffsetPaginationInput){cluster_list(filters: $filters, pagination: $pagination){" + queryOrganizationAttributes + " status type{name} group{name} tenant{name}}}"
+	queryRegionList             string = "query($filters: RegionFilter, $pagination: OffsetPaginationInput){region_list(filters: $filters, pagination: $pagination){" + queryOrganizationAttributes + " slug parent{name}}}"
+	queryLocationList           string = "query($filters: LocationFilter, $pagination: OffsetPaginationInput){location_list(filters: $filters, pagination: $pagination){" + queryOrganizationAttributes + " slug status site{name} parent{name} tenant{name}}}"
+)
+
+// Site describes a subset of details of a Netbox site.
+type Site struct {
+	ID           uint64 `json:"-"`
+	IDString     string `json:"id"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	Status       string `json:"status"`
+	Region       Name   `json:"region"`
+	Group        Name   `json:"group"`
+	Tenant       Name   `json:"tenant"`
+	Tags         []Tag  `json:"tags"`
+	CustomFields CFMap  `json:"custom_fields"`
+}
+
+// Tenant describes a subset of details of a Netbox tenant.
+type Tenant struct {
+	ID           uint64 `json:"-"`
+	IDString     string `json:"id"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	Group        Name   `json:"group"`
+	Tags         []Tag  `json:"tags"`
+	CustomFields CFMap  `json:"custom_fields"`
+}
+
+// Rack describes a subset of details of a Netbox rack.
+type Rack struct {
+	ID           uint64 `json:"-"`
+	IDString     string `json:"id"`
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	Site         Name   `json:"site"`
+	Location     Name   `json:"location"`
+	Tenant       Name   `json:"tenant"`
+	Tags         []Tag  `json:"tags"`
+	CustomFields CFMap  `json:"custom_fields"`
+}
+
+// Cluster describes a subset of details of a Netbox virtualization cluster.
+type Cluster struct {
+	ID           uint64 `json:"-"`
+	IDString     string `json:"id"`
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	Type         Name   `json:"type"`
+	Group        Name   `json:"group"`
+	Tenant       Name   `json:"tenant"`
+	Tags         []Tag  `json:"tags"`
+	CustomFields CFMap  `json:"custom_fields"`
+}
+
+// Region describes a subset of details of a Netbox region. Parent is empty for top level regions.
+type Region struct {
+	ID           uint64 `json:"-"`
+	IDString     string `json:"id"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	Parent       Name   `json:"parent"`
+	Tags         []Tag  `json:"tags"`
+	CustomFields CFMap  `json:"custom_fields"`
+}
+
+// Location describes a subset of details of a Netbox location. Parent is empty for top level locations of a site.
+type Location struct {
+	ID           uint64 `json:"-"`
+	IDString     string `json:"id"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	Status       string `json:"status"`
+	Site         Name   `json:"site"`
+	Parent       Name   `json:"parent"`
+	Tenant       Name   `json:"tenant"`
+	Tags         []Tag  `json:"tags"`
+	CustomFields CFMap  `json:"custom_fields"`
+}
+
+// GetSites returns a list of all sites.
+func (client *Client) GetSites(ctx context.Context) ([]*Site, error) {
+	return client.getSiteList(withQueryType(ctx, "sites"), nil)
+}
+
+// GetSitesByTag returns a list of all sites with a given tag.
+func (client *Client) GetSitesByTag(ctx context.Context, tag string) ([]*Site, error) {
+	return client.getSiteList(withQueryType(ctx, "sites_by_tag"), map[string]any{"tag": tag})
+}
+
+// getSiteList returns the list of sites matching filters.
+func (client *Client) getSiteList(ctx context.Context, filters map[string]any) ([]*Site, error) {
+	var (
+		sites []*Site
+		err   error
+	)
+
+	err = client.graphQLList(ctx, querySiteList, filters, func(wrapper *graphQLResponseWrapper) int {
+		sites = append(sites, wrapper.Data.SiteList...)
+		return len(wrapper.Data.SiteList)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sites, nil
+}
+
+// GetTenants returns a list of all tenants.
+func (client *Client) GetTenants(ctx context.Context) ([]*Tenant, error) {
+	return client.getTenantList(withQueryType(ctx, "tenants"), nil)
+}
+
+// GetTenantsByTag returns a list of all tenants with a given tag.
+func (client *Client) GetTenantsByTag(ctx context.Context, tag string) ([]*Tenant, error) {
+	return client.getTenantList(withQueryType(ctx, "tenants_by_tag"), map[string]any{"tag": tag})
+}
+
+// getTenantList returns the list of tenants matching filters.
+func (client *Client) getTenantList(ctx context.Context, filters map[string]any) ([]*Tenant, error) {
+	var (
+		tenants []*Tenant
+		err     error
+	)
+
+	err = client.graphQLList(ctx, queryTenantList, filters, func(wrapper *graphQLResponseWrapper) int {
+		tenants = append(tenants, wrapper.Data.TenantList...)
+		return len(wrapper.Data.TenantList)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tenants, nil
+}
+
+// GetRacks returns a list of all racks.
+func (client *Client) GetRacks(ctx context.Context) ([]*Rack, error) {
+	return client.getRackList(withQueryType(ctx, "racks"), nil)
+}
+
+// GetRacksByTag returns a list of all racks with a given tag.
+func (client *Client) GetRacksByTag(ctx context.Context, tag string) ([]*Rack, error) {
+	return client.getRackList(withQueryType(ctx, "racks_by_tag"), map[string]any{"tag": tag})
+}
+
+// getRackList returns the list of racks matching filters.
+func (client *Client) getRackList(ctx context.Context, filters map[string]any) ([]*Rack, error) {
+	var (
+		racks []*Rack
+		err   error
+	)
+
+	err = client.graphQLList(ctx, queryRackList, filters, func(wrapper *graphQLResponseWrapper) int {
+		racks = append(racks, wrapper.Data.RackList...)
+		return len(wrapper.Data.RackList)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return racks, nil
+}
+
+// GetClusters returns a list of all virtualization clusters.
+func (client *Client) GetClusters(ctx context.Context) ([]*Cluster, error) {
+	return client.getClusterList(withQueryType(ctx, "clusters"), nil)
+}
+
+// GetClustersByTag returns a list of all virtualization clusters with a given tag.
+func (client *Client) GetClustersByTag(ctx context.Context, tag string) ([]*Cluster, error) {
+	return client.getClusterList(withQueryType(ctx, "clusters_by_tag"), map[string]any{"tag": tag})
+}
+
+// getClusterList returns the list of virtualization clusters matching filters.
+func (client *Client) getClusterList(ctx context.Context, filters map[string]any) ([]*Cluster, error) {
+	var (
+		clusters []*Cluster
+		err      error
+	)
+
+	err = client.graphQLList(ctx, queryClusterList, filters, func(wrapper *graphQLResponseWrapper) int {
+		clusters = append(clusters, wrapper.Data.ClusterList...)
+		return len(wrapper.Data.ClusterList)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clusters, nil
+}
+
+// GetRegions returns a list of all regions.
+func (client *Client) GetRegions(ctx context.Context) ([]*Region, error) {
+	return client.getRegionList(withQueryType(ctx, "regions"), nil)
+}
+
+// GetRegionsByTag returns a list of all regions with a given tag.
+func (client *Client) GetRegionsByTag(ctx context.Context, tag string) ([]*Region, error) {
+	return client.getRegionList(withQueryType(ctx, "regions_by_tag"), map[string]any{"tag": tag})
+}
+
+// getRegionList returns the list of regions matching filters.
+func (client *Client) getRegionList(ctx context.Context, filters map[string]any) ([]*Region, error) {
+	var (
+		regions []*Region
+		err     error
+	)
+
+	err = client.graphQLList(ctx, queryRegionList, filters, func(wrapper *graphQLResponseWrapper) int {
+		regions = append(regions, wrapper.Data.RegionList...)
+		return len(wrapper.Data.RegionList)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return regions, nil
+}
+
+// GetLocations returns a list of all locations.
+func (client *Client) GetLocations(ctx context.Context) ([]*Location, error) {
+	return client.getLocationList(withQueryType(ctx, "locations"), nil)
+}
+
+// GetLocationsByTag returns a list of all locations with a given tag.
+func (client *Client) GetLocationsByTag(ctx context.Context, tag string) ([]*Location, error) {
+	return client.getLocationList(withQueryType(ctx, "locations_by_tag"), map[string]any{"tag": tag})
+}
+
+// getLocationList returns the list of locations matching filters.
+func (client *Client) getLocationList(ctx context.Context, filters map[string]any) ([]*Location, error) {
+	var (
+		locations []*Location
+		err       error
+	)
+
+	err = client.graphQLList(ctx, queryLocationList, filters, func(wrapper *graphQLResponseWrapper) int {
+		locations = append(locations, wrapper.Data.LocationList...)
+		return len(wrapper.Data.LocationList)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
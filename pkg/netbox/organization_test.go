@@ -0,0 +1,85 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSites(t *testing.T) {
+	var (
+		server   *httptest.Server
+		requests []graphQLRequest
+		paths    []string
+		client   *Client
+		api      ClientIface
+		sites    []*Site
+		err      error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request graphQLRequest
+
+		paths = append(paths, r.URL.Path+"?"+r.URL.Query().Get("tag"))
+
+		if r.URL.Path == "/api/dcim/sites/" {
+			w.Write([]byte(`{"next":null,"results":[{"id":1,"name":"site-A","slug":"site-a",` +
+				`"status":{"value":"active","label":"Active"},"region":{"id":2,"name":"region-A"},"group":null,` +
+				`"tenant":null,"tags":[{"name":"Foo","slug":"foo"}],"custom_fields":{"cf":"bar"}}]}`))
+			return
+		}
+
+		json.NewDecoder(r.Body).Decode(&request)
+		requests = append(requests, request)
+
+		w.Write([]byte(`{"data":{"site_list":[{"id":"1","name":"site-A","slug":"site-a","status":"active",` +
+			`"region":{"name":"region-A"},"group":null,"tenant":null,"tags":[{"name":"Foo","slug":"foo"}],` +
+			`"custom_fields":{"cf":"bar"}}]}}`))
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+
+	for _, api = range []ClientIface{client, NewREST(client)} {
+		sites, err = api.GetSitesByTag(context.Background(), "foo")
+		require.NoError(t, err)
+		require.Len(t, sites, 1)
+		assert.Equal(t, uint64(1), sites[0].ID)
+		assert.Equal(t, "1", sites[0].IDString)
+		assert.Equal(t, "site-A", sites[0].Name)
+		assert.Equal(t, "site-a", sites[0].Slug)
+		assert.Equal(t, "active", sites[0].Status)
+		assert.Equal(t, "region-A", sites[0].Region.Name)
+		assert.Empty(t, sites[0].Group.Name)
+		assert.Equal(t, []Tag{{Name: "Foo", Slug: "foo"}}, sites[0].Tags)
+		assert.Equal(t, "bar", sites[0].CustomFields.GetEntry("cf").Value)
+	}
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, querySiteList, requests[0].Query)
+	assert.Equal(t, map[string]any{"tag": "foo"}, requests[0].Variables["filters"])
+	assert.Equal(t, []string{"/graphql/?", "/api/dcim/sites/?foo"}, paths)
+}
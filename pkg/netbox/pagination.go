@@ -0,0 +1,95 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains pagination of GraphQL list queries.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SetPageSize sets the number of objects requested per GraphQL request for list queries. Lists are then fetched page by
+// page using offset pagination until a page contains less than size objects. A size of 0 disables pagination, thus
+// each list is fetched with a single request (and might be truncated by Netbox).
+func (client *Client) SetPageSize(size int) {
+	if size < 0 {
+		size = 0
+	}
+
+	client.pageSize.Store(int64(size))
+}
+
+// graphQLList performs the list query and calls page with the response of each page. The query must define the
+// variables $filters and $pagination, which are set to filters and the offset and limit of the page. Page must return
+// the number of objects of the list contained in the page. Pages are requested until a page contains less objects than
+// the page size. Without page size, $pagination is not set and page is called once.
+func (client *Client) graphQLList(ctx context.Context, query string, filters map[string]any,
+	page func(*graphQLResponseWrapper) int) error {
+	var (
+		size      int = client.listPageSize()
+		offset    int
+		count     int
+		variables map[string]any
+		resp      response
+		wrapper   graphQLResponseWrapper
+		err       error
+	)
+
+	for {
+		variables = make(map[string]any)
+
+		if filters != nil {
+			variables["filters"] = filters
+		}
+
+		if size > 0 {
+			variables["pagination"] = map[string]any{"offset": offset, "limit": size}
+		}
+
+		resp, err = client.graphQL(ctx, client.document(query), variables)
+
+		if err != nil {
+			return fmt.Errorf("failed to query api: %w", err)
+		}
+
+		if resp.StatusCode() != 200 {
+			return ErrUnexpectedStatusCode
+		}
+
+		wrapper = graphQLResponseWrapper{}
+
+		err = json.Unmarshal(resp.RawBody().Bytes(), &wrapper)
+		if err != nil {
+			client.promFailure.Inc()
+			return fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
+		}
+
+		// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
+		wrapper.parseIDs()
+
+		count = page(&wrapper)
+
+		if size == 0 || count < size {
+			return nil
+		}
+
+		offset += size
+	}
+}
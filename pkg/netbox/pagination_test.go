@@ -0,0 +1,95 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQLList(t *testing.T) {
+	var (
+		server   *httptest.Server
+		mu       sync.Mutex
+		requests []graphQLRequest
+		client   *Client
+		devices  []*Device
+		err      error
+	)
+
+	// serves 5 devices, paginated when requested
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			request graphQLRequest
+			list    []map[string]any
+			offset  int = 0
+			limit   int = 5
+		)
+
+		json.NewDecoder(r.Body).Decode(&request)
+
+		mu.Lock()
+		requests = append(requests, request)
+		mu.Unlock()
+
+		if pagination, ok := request.Variables["pagination"].(map[string]any); ok {
+			offset = int(pagination["offset"].(float64))
+			limit = int(pagination["limit"].(float64))
+		}
+
+		for i := offset; i < min(offset+limit, 5); i++ {
+			list = append(list, map[string]any{"id": "1", "name": "device"})
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"device_list": list}})
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+
+	devices, err = client.GetDevicesByTag(context.Background(), `foo" } evil`)
+	require.NoError(t, err)
+	assert.Len(t, devices, 5)
+	require.Len(t, requests, 1)
+	assert.Equal(t, queryDeviceList, requests[0].Query)
+	assert.Equal(t, map[string]any{"filters": map[string]any{"tag": `foo" } evil`}}, requests[0].Variables)
+
+	requests = nil
+	client.SetPageSize(2)
+
+	devices, err = client.GetDevices(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, devices, 5)
+	require.Len(t, requests, 3)
+
+	// the document is the same for all pages
+	for i := range requests {
+		assert.Equal(t, queryDeviceList, requests[i].Query)
+		assert.Equal(t, map[string]any{
+			"pagination": map[string]any{"offset": float64(i * 2), "limit": float64(2)},
+		}, requests[i].Variables)
+	}
+}
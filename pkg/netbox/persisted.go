@@ -0,0 +1,169 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains support for GraphQL persisted queries.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	// persistedQueryNotFound is the error message returned by gateways when a persisted query hash is unknown.
+	persistedQueryNotFound string = "PersistedQueryNotFound"
+	// persistedQueryNotFoundCode is the error code returned by gateways when a persisted query hash is unknown.
+	persistedQueryNotFoundCode string = "PERSISTED_QUERY_NOT_FOUND"
+)
+
+// graphQLRequest is the body of a GraphQL request.
+type graphQLRequest struct {
+	Query      string             `json:"query,omitempty"`
+	Variables  map[string]any     `json:"variables,omitempty"`
+	Extensions *graphQLExtensions `json:"extensions,omitempty"`
+}
+
+// graphQLExtensions contains protocol extensions of a GraphQL request.
+type graphQLExtensions struct {
+	PersistedQuery *persistedQuery `json:"persistedQuery,omitempty"`
+}
+
+// persistedQuery identifies a query by the sha256 hash of its document.
+type persistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// UsePersistedQueries enables or disables persisted queries. When enabled, GraphQL queries are first sent as sha256
+// hash only (see QueryHash). When the GraphQL gateway in front of Netbox doesn't know the hash yet, the query is sent
+// again including the document, which registers it with the gateway (automatic persisted queries).
+func (client *Client) UsePersistedQueries(val bool) {
+	client.persistedQueries.Store(val)
+}
+
+// QueryHash returns the hex encoded sha256 hash of a GraphQL document as used for persisted queries.
+func QueryHash(query string) string {
+	var sum [32]byte = sha256.Sum256([]byte(query))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// ManifestQuery is a single GraphQL document of the query manifest along with its persisted query hash.
+type ManifestQuery struct {
+	Hash     string `json:"hash"`
+	Document string `json:"document"`
+}
+
+// QueryManifest returns all GraphQL documents used by this package and their persisted query hashes indexed by name.
+// Documents are fixed, all values like IDs, tags or the page of a list are passed as variables. Thus, the hashes are
+// exactly those sent with persisted queries. The manifest is meant for reviewing and allowlisting queries in GraphQL
+// gateways.
+func QueryManifest() map[string]ManifestQuery {
+	var (
+		documents map[string]string = map[string]string{
+			"device":                              queryDevice,
+			"device_list":                         queryDeviceList,
+			"device_list_config_context":          queryDeviceListConfigContext,
+			"device_config_context":               queryDeviceConfigContext,
+			"interface":                           queryInterface,
+			"interface_list":                      queryInterfaceList,
+			"virtual_interface":                   queryVirtualInterface,
+			"virtual_interface_list":              queryVirtualInterfaceList,
+			"ip_address_list":                     queryIPAddressList,
+			"service_list":                        queryServiceList,
+			"virtual_device_context_list":         queryVDCList,
+			"virtual_machine":                     queryVM,
+			"virtual_machine_list":                queryVMList,
+			"virtual_machine_list_config_context": queryVMListConfigContext,
+			"virtual_machine_config_context":      queryVMConfigContext,
+			"vlan_list":                           queryVLANList,
+			"wireless_lan_list":                   queryWirelessLANList,
+			"site_list":                           querySiteList,
+			"tenant_list":                         queryTenantList,
+			"rack_list":                           queryRackList,
+			"cluster_list":                        queryClusterList,
+			"region_list":                         queryRegionList,
+			"location_list":                       queryLocationList,
+			"prefix_list":                         queryPrefixList,
+		}
+		manifest map[string]ManifestQuery = make(map[string]ManifestQuery, len(documents))
+		name     string
+	)
+
+	for name = range documents {
+		manifest[name] = ManifestQuery{
+			Hash:     QueryHash(documents[name]),
+			Document: documents[name],
+		}
+	}
+
+	return manifest
+}
+
+// persistedGraphQL sends query as persisted query and registers the query when the gateway doesn't know it yet.
+// Variables are sent with every request.
+func (client *Client) persistedGraphQL(ctx context.Context, query string, variables map[string]any) (response, error) {
+	var (
+		request *graphQLRequest = &graphQLRequest{
+			Variables: variables,
+			Extensions: &graphQLExtensions{
+				PersistedQuery: &persistedQuery{
+					Version:    1,
+					SHA256Hash: QueryHash(query),
+				},
+			},
+		}
+		resp response
+		err  error
+	)
+
+	resp, err = client.postGraphQL(ctx, request)
+	if err != nil || !isPersistedQueryNotFound(resp) {
+		return resp, err
+	}
+
+	client.logger().DebugContext(ctx, "registering persisted query", "hash", request.Extensions.PersistedQuery.SHA256Hash)
+
+	request.Query = query
+
+	return client.postGraphQL(ctx, request)
+}
+
+// isPersistedQueryNotFound returns true when resp indicates that the hash of a persisted query is unknown.
+func isPersistedQueryNotFound(resp response) bool {
+	var (
+		errs graphQLErrors
+		i    int
+	)
+
+	if json.Unmarshal(resp.RawBody().Bytes(), &errs) != nil {
+		return false
+	}
+
+	for i = range errs.Errors {
+		if errs.Errors[i].Extensions["code"] == persistedQueryNotFoundCode ||
+			strings.Contains(errs.Errors[i].Message, persistedQueryNotFound) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,118 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryHash(t *testing.T) {
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", QueryHash(""))
+}
+
+func TestIsPersistedQueryNotFound(t *testing.T) {
+	var resp *graphQLResponse
+
+	resp = &graphQLResponse{statusCode: 200}
+	resp.body.WriteString(`{"errors":[{"message":"PersistedQueryNotFound"}]}`)
+	assert.True(t, isPersistedQueryNotFound(resp))
+
+	resp = &graphQLResponse{statusCode: 200}
+	resp.body.WriteString(`{"errors":[{"message":"not found","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`)
+	assert.True(t, isPersistedQueryNotFound(resp))
+
+	resp = &graphQLResponse{statusCode: 200}
+	resp.body.WriteString(`{"data":{"device":null}}`)
+	assert.False(t, isPersistedQueryNotFound(resp))
+
+	resp = &graphQLResponse{statusCode: 200}
+	resp.body.WriteString(`not json`)
+	assert.False(t, isPersistedQueryNotFound(resp))
+}
+
+func TestQueryManifest(t *testing.T) {
+	var (
+		manifest map[string]ManifestQuery = QueryManifest()
+		name     string
+	)
+
+	for name = range manifest {
+		assert.NotEmpty(t, manifest[name].Document, name)
+		assert.Equal(t, QueryHash(manifest[name].Document), manifest[name].Hash, name)
+	}
+
+	assert.Equal(t, queryDevice, manifest["device"].Document)
+}
+
+func TestPersistedQueryHashes(t *testing.T) {
+	var (
+		server *httptest.Server
+		mu     sync.Mutex
+		hashes []string
+		known  map[string]bool = make(map[string]bool)
+		client *Client
+		query  ManifestQuery
+		hash   string
+		err    error
+	)
+
+	// records the hash of each persisted query and answers with empty results
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request graphQLRequest
+
+		json.NewDecoder(r.Body).Decode(&request)
+
+		mu.Lock()
+		hashes = append(hashes, request.Extensions.PersistedQuery.SHA256Hash)
+		mu.Unlock()
+
+		w.Write([]byte(`{"data":{"device_list":[],"virtual_machine_list":[]}}`))
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+	client.UsePersistedQueries(true)
+
+	_, err = client.GetDevicesByTag(context.Background(), "foo")
+	require.NoError(t, err)
+	_, err = client.GetDevicesByTag(context.Background(), "bar")
+	require.NoError(t, err)
+	_, err = client.GetVMs(context.Background())
+	require.NoError(t, err)
+
+	for _, query = range QueryManifest() {
+		known[query.Hash] = true
+	}
+
+	// the same document is sent regardless of the tag
+	require.Len(t, hashes, 3)
+	assert.Equal(t, hashes[0], hashes[1])
+
+	for _, hash = range hashes {
+		assert.True(t, known[hash], hash)
+	}
+}
@@ -0,0 +1,60 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import "context"
+
+const (
+	queryPrefixAttributes string = "id prefix status vrf {id, name} tenant{name} role{name} is_pool tags{name slug} custom_fields"
+	queryPrefixList       string = "query($filters: PrefixFilter, $pagination: OffsetPaginationInput){prefix_list(filters: $filters, pagination: $pagination){" + queryPrefixAttributes + "}}"
+)
+
+// Prefix describes a subset of details of a Netbox prefix.
+type Prefix struct {
+	ID           uint64 `json:"-"`
+	IDString     string `json:"id"`
+	Prefix       string `json:"prefix"`
+	Status       string `json:"status"`
+	VRF          *VRF   `json:"vrf"`
+	Tenant       Name   `json:"tenant"`
+	Role         Name   `json:"role"`
+	IsPool       bool   `json:"is_pool"`
+	Tags         []Tag  `json:"tags"`
+	CustomFields CFMap  `json:"custom_fields"`
+}
+
+// GetPrefixes returns a list of all prefixes matching filter. A nil filter returns all prefixes. Use GetIPsInPrefix to
+// get the IPs of a prefix.
+func (client *Client) GetPrefixes(ctx context.Context, filter *PrefixFilter) ([]*Prefix, error) {
+	var (
+		prefixes []*Prefix
+		err      error
+	)
+
+	ctx = withQueryType(ctx, "prefixes")
+
+	err = client.graphQLList(ctx, queryPrefixList, filter.apply(map[string]any{}), func(wrapper *graphQLResponseWrapper) int {
+		prefixes = append(prefixes, wrapper.Data.PrefixList...)
+		return len(wrapper.Data.PrefixList)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return prefixes, nil
+}
@@ -0,0 +1,84 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPrefixes(t *testing.T) {
+	var (
+		server   *httptest.Server
+		requests []graphQLRequest
+		queries  []string
+		client   *Client
+		api      ClientIface
+		prefixes []*Prefix
+		err      error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request graphQLRequest
+
+		if r.URL.Path == "/api/ipam/prefixes/" {
+			queries = append(queries, r.URL.Query().Get("within"))
+			w.Write([]byte(`{"next":null,"results":[{"id":1,"prefix":"10.0.0.0/24",` +
+				`"status":{"value":"active","label":"Active"},"vrf":{"id":2,"name":"mgmt"},"tenant":null,` +
+				`"role":{"id":3,"name":"servers"},"is_pool":true,"tags":[],"custom_fields":{}}]}`))
+			return
+		}
+
+		json.NewDecoder(r.Body).Decode(&request)
+		requests = append(requests, request)
+
+		w.Write([]byte(`{"data":{"prefix_list":[{"id":"1","prefix":"10.0.0.0/24","status":"active",` +
+			`"vrf":{"id":"2","name":"mgmt"},"tenant":null,"role":{"name":"servers"},"is_pool":true,"tags":[],` +
+			`"custom_fields":{}}]}}`))
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+
+	for _, api = range []ClientIface{client, NewREST(client)} {
+		prefixes, err = api.GetPrefixes(context.Background(), &PrefixFilter{Within: "10.0.0.0/8"})
+		require.NoError(t, err)
+		require.Len(t, prefixes, 1)
+		assert.Equal(t, uint64(1), prefixes[0].ID)
+		assert.Equal(t, "10.0.0.0/24", prefixes[0].Prefix)
+		assert.Equal(t, "active", prefixes[0].Status)
+		require.NotNil(t, prefixes[0].VRF)
+		assert.Equal(t, uint64(2), prefixes[0].VRF.ID)
+		assert.Equal(t, "mgmt", prefixes[0].VRF.Name)
+		assert.Equal(t, "servers", prefixes[0].Role.Name)
+		assert.Empty(t, prefixes[0].Tenant.Name)
+		assert.True(t, prefixes[0].IsPool)
+	}
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, queryPrefixList, requests[0].Query)
+	assert.Equal(t, map[string]any{"within": "10.0.0.0/8"}, requests[0].Variables["filters"])
+	assert.Equal(t, []string{"10.0.0.0/8"}, queries)
+}
@@ -0,0 +1,54 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains the logical query type used to label metrics.
+
+import "context"
+
+// defaultQueryType is the query type of requests not issued by a lookup setting one.
+const defaultQueryType string = "other"
+
+// queryTypeKey is the context key of the query type.
+type queryTypeKey struct{}
+
+// withQueryType returns a copy of ctx carrying the logical query type (e.g. devices_by_tag) of a lookup. All requests
+// issued with the returned context are labeled with it in metrics, thus slow or failing lookups can be told apart
+// although all GraphQL queries share the same url. An already set query type is kept, so requests of a lookup resolving
+// objects with further lookups are accounted to the outermost one.
+func withQueryType(ctx context.Context, name string) context.Context {
+	if _, ok := ctx.Value(queryTypeKey{}).(string); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, queryTypeKey{}, name)
+}
+
+// queryType returns the query type carried by ctx or defaultQueryType.
+func queryType(ctx context.Context) string {
+	var (
+		name string
+		ok   bool
+	)
+
+	if name, ok = ctx.Value(queryTypeKey{}).(string); ok {
+		return name
+	}
+
+	return defaultQueryType
+}
@@ -0,0 +1,91 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryType(t *testing.T) {
+	var (
+		ctx context.Context = context.Background()
+	)
+
+	assert.Equal(t, defaultQueryType, queryType(ctx))
+
+	ctx = withQueryType(ctx, "devices_by_tag")
+	assert.Equal(t, "devices_by_tag", queryType(ctx))
+
+	// nested lookups are accounted to the outermost one
+	ctx = withQueryType(ctx, "devices_by_tag_filtered")
+	assert.Equal(t, "devices_by_tag", queryType(ctx))
+}
+
+func TestQueryTypeLabel(t *testing.T) {
+	var (
+		server   *httptest.Server
+		client   *Client
+		registry *prometheus.Registry
+		families []*dto.MetricFamily
+		family   *dto.MetricFamily
+		pair     *dto.LabelPair
+		labels   map[string]string
+		err      error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"netbox-version": "4.1.0"}`))
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+	require.NoError(t, client.VerifyConnectivity(context.Background()))
+
+	registry = prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(client))
+
+	families, err = registry.Gather()
+	require.NoError(t, err)
+
+	for _, family = range families {
+		if family.GetName() != "netbox_go_netbox_api_status" {
+			continue
+		}
+
+		require.Len(t, family.GetMetric(), 1)
+
+		labels = make(map[string]string)
+		for _, pair = range family.GetMetric()[0].GetLabel() {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+
+		assert.Equal(t, map[string]string{"code": "200", "url": "/api/status/", "query": "verify_connectivity"}, labels)
+		return
+	}
+
+	t.Fatal("status metric not found")
+}
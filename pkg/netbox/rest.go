@@ -21,11 +21,13 @@ package netbox
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -53,13 +55,15 @@ func (r *restResponse) RawBody() *bytes.Buffer {
 //
 // This implementation doesn't support paging by itself but a calling function can supply the limit and offset parameter
 // itself within query to do it itself.
-func (client *Client) get(query string) (response, error) {
+func (client *Client) get(ctx context.Context, query string) (response, error) {
 	var (
 		resp        *http.Response
 		rResp       restResponse
 		req         http.Request
 		err         error
 		dump, dump2 []byte
+		// query parameters are not part of metric labels to limit cardinality
+		path string = strings.SplitN(query, "?", 2)[0]
 
 		// used for request timing
 		timer time.Time
@@ -69,19 +73,19 @@ func (client *Client) get(query string) (response, error) {
 	req = http.Request{
 		Method: http.MethodGet,
 		Header: map[string][]string{
-			"Accept":        {"application/json"},
-			"Authorization": {fmt.Sprintf("Token %s", client.token)},
+			"Accept": {"application/json"},
 		},
 	}
 
 	req.URL, _ = url.ParseRequestURI(client.url + query)
 
 	timer = time.Now()
-	resp, err = client.http.Do(&req)
+	resp, err = client.do(ctx, &req, path)
 	if err != nil {
 		client.promError.
 			With(prometheus.Labels{
-				"url": query,
+				"url":   path,
+				"query": queryType(ctx),
 			}).
 			Inc()
 		return nil, fmt.Errorf("http api call failed: %w", err)
@@ -94,19 +98,37 @@ func (client *Client) get(query string) (response, error) {
 
 	client.promDuration.
 		With(prometheus.Labels{
-			"url":  query,
-			"code": strconv.Itoa(resp.StatusCode),
+			"url":   path,
+			"code":  strconv.Itoa(resp.StatusCode),
+			"query": queryType(ctx),
 		}).
 		Set(float64(dur * time.Nanosecond))
 
+	client.promLatency.
+		With(prometheus.Labels{
+			"url":   path,
+			"code":  strconv.Itoa(resp.StatusCode),
+			"query": queryType(ctx),
+		}).
+		Observe(dur.Seconds())
+
 	client.promStatus.
 		With(prometheus.Labels{
-			"url":  query,
-			"code": strconv.Itoa(resp.StatusCode),
+			"url":   path,
+			"code":  strconv.Itoa(resp.StatusCode),
+			"query": queryType(ctx),
 		}).
 		Inc()
 
-	if client.httpTracing {
+	// putting data into response
+	rResp.statusCode = resp.StatusCode
+	_, err = rResp.body.ReadFrom(resp.Body)
+	if err != nil {
+		client.promFailure.Inc()
+		return nil, fmt.Errorf("failed to read response body into buffer: %w", err)
+	}
+
+	if client.httpTracing.Load() {
 		// It is more efficient to check the level instead of dumping the entire requests and response every time and just
 		// throwing away the result.
 
@@ -114,28 +136,21 @@ func (client *Client) get(query string) (response, error) {
 		dump, err = httputil.DumpRequest(&req, false)
 		if err != nil {
 			client.promFailure.Inc()
-			client.log.Errorf("failed to dump http request: %v", err)
+			client.logger().ErrorContext(ctx, "failed to dump http request", "error", err)
 		} else {
 			dump2, err = httputil.DumpResponse(resp, false)
 			if err != nil {
 				client.promFailure.Inc()
-				client.log.Errorf("failed to dump http response: %v", err)
+				client.logger().ErrorContext(ctx, "failed to dump http response", "error", err)
 			} else {
-				client.log.Tracef("===> HTTP Request <===\n%s\n", string(dump))
-				client.log.Tracef("===> HTTP Response <===\n%s%s\n\n", string(dump2), rResp.body.String())
+				client.logger().Log(ctx, LevelTrace, "http request", "dump", string(dump))
+				client.logger().Log(ctx, LevelTrace, "http response", "dump", string(dump2)+rResp.body.String())
 			}
 		}
 	}
 
-	client.log.Tracef("http call took %dms", dur.Milliseconds())
-
-	// putting data into response
-	rResp.statusCode = resp.StatusCode
-	_, err = rResp.body.ReadFrom(resp.Body)
-	if err != nil {
-		client.promFailure.Inc()
-		return nil, fmt.Errorf("failed to read response body into buffer: %w", err)
-	}
+	client.logger().Log(ctx, LevelTrace, "http call finished", "url", path, "query", queryType(ctx), "status",
+		resp.StatusCode, "duration", dur)
 
 	return &rResp, nil
 }
@@ -0,0 +1,1091 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains the REST API implementation of all lookups Client performs via GraphQL.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+)
+
+const (
+	restInterfacesPath        string = "/api/dcim/interfaces/"
+	restVirtualInterfacesPath string = "/api/virtualization/interfaces/"
+	restServicesPath          string = "/api/ipam/services/"
+	restVLANsPath             string = "/api/ipam/vlans/"
+	restWirelessLANsPath      string = "/api/wireless/wireless-lans/"
+	restVDCsPath              string = "/api/dcim/virtual-device-contexts/"
+	restSitesPath             string = "/api/dcim/sites/"
+	restTenantsPath           string = "/api/tenancy/tenants/"
+	restRacksPath             string = "/api/dcim/racks/"
+	restClustersPath          string = "/api/virtualization/clusters/"
+	restRegionsPath           string = "/api/dcim/regions/"
+	restLocationsPath         string = "/api/dcim/locations/"
+	restPrefixesPath          string = "/api/ipam/prefixes/"
+)
+
+// RESTClient is a Client performing all lookups via Netbox's REST API instead of GraphQL. It is meant for installations
+// where the GraphQL API is disabled or restricted. Objects referenced by other objects (like the device of an interface)
+// are resolved with additional requests, thus considerably more requests are needed than with GraphQL. The GraphQL
+// specific options (persisted queries and page size) have no effect.
+type RESTClient struct {
+	*Client
+}
+
+// restInterface is a device or VM interface as returned by the REST API.
+type restInterface struct {
+	ID           uint64     `json:"id"`
+	Name         string     `json:"name"`
+	Enabled      bool       `json:"enabled"`
+	CustomFields CFMap      `json:"custom_fields"`
+	Device       *restRef   `json:"device"`
+	VM           *restRef   `json:"virtual_machine"`
+	Mode         restChoice `json:"mode"`
+	UntaggedVLAN *restVLAN  `json:"untagged_vlan"`
+	TaggedVLANs  []restVLAN `json:"tagged_vlans"`
+	Tags         []Tag      `json:"tags"`
+}
+
+// restVLAN is a vlan as returned by the REST API. Vlans referenced by interfaces only contain ID, VID and Name.
+type restVLAN struct {
+	ID     uint64     `json:"id"`
+	VID    uint16     `json:"vid"`
+	Name   string     `json:"name"`
+	Status restChoice `json:"status"`
+	Group  *Name      `json:"group"`
+	Site   *Name      `json:"site"`
+	Tenant *Name      `json:"tenant"`
+	Tags   []Tag      `json:"tags"`
+}
+
+// restWirelessLAN is a wireless LAN as returned by the REST API.
+type restWirelessLAN struct {
+	ID   uint64 `json:"id"`
+	SSID string `json:"ssid"`
+}
+
+// restService is a service as returned by the REST API.
+type restService struct {
+	ID           uint64     `json:"id"`
+	Name         string     `json:"name"`
+	Device       *restRef   `json:"device"`
+	VM           *restRef   `json:"virtual_machine"`
+	Ports        []int      `json:"ports"`
+	IPAddresses  []restRef  `json:"ipaddresses"`
+	Protocol     restChoice `json:"protocol"`
+	CustomFields CFMap      `json:"custom_fields"`
+}
+
+// restVDC is a virtual device context as returned by the REST API.
+type restVDC struct {
+	ID           uint64     `json:"id"`
+	Name         string     `json:"name"`
+	Identifier   *int       `json:"identifier"`
+	Status       restChoice `json:"status"`
+	PrimaryIP4   *restRef   `json:"primary_ip4"`
+	PrimaryIP6   *restRef   `json:"primary_ip6"`
+	CustomFields CFMap      `json:"custom_fields"`
+	Tenant       *Name      `json:"tenant"`
+	Tags         []Tag      `json:"tags"`
+	Device       *restRef   `json:"device"`
+}
+
+// NewREST returns a RESTClient performing its requests with client.
+func NewREST(client *Client) *RESTClient {
+	return &RESTClient{Client: client}
+}
+
+// Copy creates an identical copy of client. See Client.Copy.
+func (client *RESTClient) Copy() ClientIface {
+	return NewREST(client.Client.Copy().(*Client))
+}
+
+/*
+ * devices
+ */
+
+// GetDevice returns the device identified by id or nil when it doesn't exist.
+func (client *RESTClient) GetDevice(ctx context.Context, id uint64) (*Device, error) {
+	return client.getDevice(withQueryType(ctx, "device"), restDevicesPath, id, false)
+}
+
+// GetDevices returns a list of all devices.
+func (client *RESTClient) GetDevices(ctx context.Context) ([]*Device, error) {
+	return client.getDevicesByValues(withQueryType(ctx, "devices"), restDevicesPath, url.Values{}, false, false)
+}
+
+// GetDevicesByTag returns a list of all devices with a given tag.
+func (client *RESTClient) GetDevicesByTag(ctx context.Context, tag string) ([]*Device, error) {
+	return client.GetDevicesByTagFiltered(withQueryType(ctx, "devices_by_tag"), tag, nil)
+}
+
+// GetDevicesByTagFiltered returns a list of all devices with a given tag that match filter.
+func (client *RESTClient) GetDevicesByTagFiltered(ctx context.Context, tag string, filter *DeviceFilter) ([]*Device,
+	error) {
+	return client.getDevicesByValues(withQueryType(ctx, "devices_by_tag_filtered"), restDevicesPath, filter.values(url.Values{"tag": {tag}}), false, false)
+}
+
+// GetDevicesByManufacturer returns a list of all devices made by the manufacturer with the given slug.
+func (client *RESTClient) GetDevicesByManufacturer(ctx context.Context, manufacturer string) ([]*Device, error) {
+	return client.getDevicesByValues(withQueryType(ctx, "devices_by_manufacturer"), restDevicesPath, url.Values{"manufacturer": {manufacturer}}, false, false)
+}
+
+// GetDevicesBySiteGroup returns a list of all devices located within the site group with the given slug.
+func (client *RESTClient) GetDevicesBySiteGroup(ctx context.Context, group string) ([]*Device, error) {
+	return client.getDevicesByValues(withQueryType(ctx, "devices_by_site_group"), restDevicesPath, url.Values{"site_group": {group}}, false, false)
+}
+
+// GetDevicesWithConfigContext returns a list of all devices including their rendered config context.
+func (client *RESTClient) GetDevicesWithConfigContext(ctx context.Context) ([]*Device, error) {
+	return client.getDevicesByValues(withQueryType(ctx, "devices_with_config_context"), restDevicesPath, url.Values{}, false, true)
+}
+
+// GetDevicesBatch returns the lists of devices and VMs selected by queries. The REST API doesn't support batching, thus
+// each query is a request of its own.
+func (client *RESTClient) GetDevicesBatch(ctx context.Context, queries []DeviceQuery) ([][]*Device, error) {
+	var (
+		results [][]*Device = make([][]*Device, len(queries))
+		values  url.Values
+		path    string
+		err     error
+		i       int
+	)
+
+	ctx = withQueryType(ctx, "devices_batch")
+
+	for i = range queries {
+		path, values = queries[i].restRequest()
+
+		results[i], err = client.getDevicesByValues(ctx, path, values, queries[i].Virtual, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// ForEachDevice calls fn for each device (or VM when query.Virtual is set) selected by query. Devices are fetched and
+// converted page by page, thus memory is only needed for the devices of a single page instead of the whole list.
+// Iteration stops at the first error returned by fn, which is returned as is.
+func (client *RESTClient) ForEachDevice(ctx context.Context, query DeviceQuery, fn func(*Device) error) error {
+	var (
+		path    string
+		values  url.Values
+		stopped error
+		err     error
+	)
+
+	ctx = withQueryType(ctx, "each_device")
+	if query.Virtual {
+		ctx = withQueryType(ctx, "each_vm")
+	}
+
+	path, values = query.restRequest()
+	values.Set("exclude", "config_context")
+
+	err = client.forEachPage(ctx, path, values, func(items []json.RawMessage) error {
+		var (
+			devs    []restDevice = make([]restDevice, len(items))
+			devices []*Device
+			device  *Device
+			err     error
+			i       int
+		)
+
+		for i = range items {
+			err = json.Unmarshal(items[i], &devs[i])
+			if err != nil {
+				client.promFailure.Inc()
+				return fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
+			}
+		}
+
+		devices, err = client.restDevices(ctx, devs, query.Virtual)
+		if err != nil {
+			return err
+		}
+
+		for _, device = range devices {
+			stopped = fn(device)
+			if stopped != nil {
+				return stopped
+			}
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// restRequest returns the REST list endpoint and filter values selecting the devices (or VMs) of query.
+func (query *DeviceQuery) restRequest() (string, url.Values) {
+	var (
+		values url.Values = query.Filter.values(url.Values{})
+		path   string     = restDevicesPath
+	)
+
+	if query.Tag != "" {
+		values.Set("tag", query.Tag)
+	}
+
+	if query.Virtual {
+		path = restVMsPath
+	}
+
+	return path, values
+}
+
+// GetDeviceConfigContext returns the rendered config context of the device identified by id or nil when it doesn't
+// exist.
+func (client *RESTClient) GetDeviceConfigContext(ctx context.Context, id uint64) (map[string]any, error) {
+	return client.getConfigContext(withQueryType(ctx, "device_config_context"), restDevicesPath, id)
+}
+
+// getConfigContext returns the rendered config context of the device (or VM) identified by id at path. Primary IPs
+// aren't resolved as only the config context is returned.
+func (client *RESTClient) getConfigContext(ctx context.Context, path string, id uint64) (map[string]any, error) {
+	var (
+		devs []restDevice
+		err  error
+	)
+
+	devs, err = getAllAs[restDevice](ctx, client.Client, path, idValues([]uint64{id}))
+	if err != nil || len(devs) == 0 {
+		return nil, err
+	}
+
+	return devs[0].ConfigContext, nil
+}
+
+// getDevice returns the device (or VM when virtual is true) identified by id or nil when it doesn't exist.
+func (client *RESTClient) getDevice(ctx context.Context, path string, id uint64, virtual bool) (*Device, error) {
+	var (
+		devices []*Device
+		err     error
+	)
+
+	devices, err = client.getDevicesByValues(ctx, path, idValues([]uint64{id}), virtual, false)
+	if err != nil || len(devices) == 0 {
+		return nil, err
+	}
+
+	return devices[0], nil
+}
+
+// getDevicesByID returns all devices (or VMs when virtual is true) identified by ids indexed by their ID.
+func (client *RESTClient) getDevicesByID(ctx context.Context, ids []uint64, virtual bool) (map[uint64]*Device,
+	error) {
+	var (
+		result  map[uint64]*Device = make(map[uint64]*Device)
+		path    string             = restDevicesPath
+		devices []*Device
+		err     error
+		i, j    int
+	)
+
+	if virtual {
+		path = restVMsPath
+	}
+
+	for i = 0; i < len(ids); i += restIDBatchSize {
+		devices, err = client.getDevicesByValues(ctx, path, idValues(ids[i:min(i+restIDBatchSize, len(ids))]), virtual,
+			false)
+		if err != nil {
+			return nil, err
+		}
+
+		for j = range devices {
+			result[devices[j].ID] = devices[j]
+		}
+	}
+
+	return result, nil
+}
+
+/*
+ * VMs
+ */
+
+// GetVM returns the VM identified by id or nil when it doesn't exist.
+func (client *RESTClient) GetVM(ctx context.Context, id uint64) (*Device, error) {
+	return client.getDevice(withQueryType(ctx, "vm"), restVMsPath, id, true)
+}
+
+// GetVMConfigContext returns the rendered config context of the VM identified by id or nil when it doesn't exist.
+func (client *RESTClient) GetVMConfigContext(ctx context.Context, id uint64) (map[string]any, error) {
+	return client.getConfigContext(withQueryType(ctx, "vm_config_context"), restVMsPath, id)
+}
+
+// GetVMs returns a list of all VMs.
+func (client *RESTClient) GetVMs(ctx context.Context) ([]*Device, error) {
+	return client.getDevicesByValues(withQueryType(ctx, "vms"), restVMsPath, url.Values{}, true, false)
+}
+
+// GetVMsByTag returns a list of all vms with a given tag.
+func (client *RESTClient) GetVMsByTag(ctx context.Context, tag string) ([]*Device, error) {
+	return client.GetVMsByTagFiltered(withQueryType(ctx, "vms_by_tag"), tag, nil)
+}
+
+// GetVMsByTagFiltered returns a list of all vms with a given tag that match filter.
+func (client *RESTClient) GetVMsByTagFiltered(ctx context.Context, tag string, filter *DeviceFilter) ([]*Device, error) {
+	return client.getDevicesByValues(withQueryType(ctx, "vms_by_tag_filtered"), restVMsPath, filter.values(url.Values{"tag": {tag}}), true, false)
+}
+
+// GetVMsByCluster returns a list of all vms that are part of the cluster with the given name.
+func (client *RESTClient) GetVMsByCluster(ctx context.Context, cluster string) ([]*Device, error) {
+	return client.getDevicesByValues(withQueryType(ctx, "vms_by_cluster"), restVMsPath, url.Values{"cluster": {cluster}}, true, false)
+}
+
+// GetVMsByClusterGroup returns a list of all vms that are part of any cluster in the cluster group with the given slug.
+func (client *RESTClient) GetVMsByClusterGroup(ctx context.Context, group string) ([]*Device, error) {
+	return client.getDevicesByValues(withQueryType(ctx, "vms_by_cluster_group"), restVMsPath, url.Values{"cluster_group": {group}}, true, false)
+}
+
+// GetVMsBySiteGroup returns a list of all vms located within the site group with the given slug.
+func (client *RESTClient) GetVMsBySiteGroup(ctx context.Context, group string) ([]*Device, error) {
+	return client.getDevicesByValues(withQueryType(ctx, "vms_by_site_group"), restVMsPath, url.Values{"site_group": {group}}, true, false)
+}
+
+// GetVMsWithConfigContext returns a list of all vms including their rendered config context.
+func (client *RESTClient) GetVMsWithConfigContext(ctx context.Context) ([]*Device, error) {
+	return client.getDevicesByValues(withQueryType(ctx, "vms_with_config_context"), restVMsPath, url.Values{}, true, true)
+}
+
+/*
+ * interfaces
+ */
+
+// GetInterface returns the device interface identified by id or nil when it doesn't exist.
+func (client *RESTClient) GetInterface(ctx context.Context, id uint64) (*Interface, error) {
+	return client.getInterface(withQueryType(ctx, "interface"), id, false)
+}
+
+// GetVirtualInterface returns the VM interface identified by id or nil when it doesn't exist.
+func (client *RESTClient) GetVirtualInterface(ctx context.Context, id uint64) (*Interface, error) {
+	return client.getInterface(withQueryType(ctx, "virtual_interface"), id, true)
+}
+
+// GetInterfacesByTag returns a list of all device interfaces having a specific tag set in Netbox.
+func (client *RESTClient) GetInterfacesByTag(ctx context.Context, tag string) ([]*Interface, error) {
+	return client.getInterfacesByValues(withQueryType(ctx, "interfaces_by_tag"), url.Values{"tag": {tag}}, false)
+}
+
+// GetVirtualInterfacesByTag returns a list of all VM interfaces having a specific tag set in Netbox.
+func (client *RESTClient) GetVirtualInterfacesByTag(ctx context.Context, tag string) ([]*Interface, error) {
+	return client.getInterfacesByValues(withQueryType(ctx, "virtual_interfaces_by_tag"), url.Values{"tag": {tag}}, true)
+}
+
+// GetInterfacesByVLAN returns a list of all device interfaces attached (untagged or tagged) to the vlan identified by
+// id.
+func (client *RESTClient) GetInterfacesByVLAN(ctx context.Context, id uint64) ([]*Interface, error) {
+	return client.getInterfacesByValues(withQueryType(ctx, "interfaces_by_vlan"), url.Values{"vlan_id": {strconv.FormatUint(id, 10)}}, false)
+}
+
+// GetVirtualInterfacesByVLAN returns a list of all VM interfaces attached (untagged or tagged) to the vlan identified by
+// id.
+func (client *RESTClient) GetVirtualInterfacesByVLAN(ctx context.Context, id uint64) ([]*Interface, error) {
+	return client.getInterfacesByValues(withQueryType(ctx, "virtual_interfaces_by_vlan"), url.Values{"vlan_id": {strconv.FormatUint(id, 10)}}, true)
+}
+
+// GetInterfacesByWirelessLAN returns a list of all device interfaces attached to the wireless LAN identified by id.
+func (client *RESTClient) GetInterfacesByWirelessLAN(ctx context.Context, id uint64) ([]*Interface, error) {
+	return client.getInterfacesByValues(withQueryType(ctx, "interfaces_by_wireless_lan"), url.Values{"wireless_lan_id": {strconv.FormatUint(id, 10)}}, false)
+}
+
+// getInterface returns the interface identified by id or nil when it doesn't exist.
+func (client *RESTClient) getInterface(ctx context.Context, id uint64, virtual bool) (*Interface, error) {
+	var (
+		interfaces []*Interface
+		err        error
+	)
+
+	interfaces, err = client.getInterfacesByValues(ctx, idValues([]uint64{id}), virtual)
+	if err != nil || len(interfaces) == 0 {
+		return nil, err
+	}
+
+	return interfaces[0], nil
+}
+
+// getInterfacesByValues returns all device interfaces (or VM interfaces when virtual is true) matching values. The
+// devices of the interfaces are resolved with additional requests.
+func (client *RESTClient) getInterfacesByValues(ctx context.Context, values url.Values, virtual bool) ([]*Interface,
+	error) {
+	var (
+		path       string = restInterfacesPath
+		interfaces []restInterface
+		iface      restInterface
+		parent     *restRef
+		ids        []uint64
+		devices    map[uint64]*Device
+		result     []*Interface = make([]*Interface, 0)
+		converted  *Interface
+		err        error
+		i          int
+	)
+
+	if virtual {
+		path = restVirtualInterfacesPath
+	}
+
+	interfaces, err = getAllAs[restInterface](ctx, client.Client, path, values)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, iface = range interfaces {
+		if parent = iface.parent(); parent != nil {
+			ids = append(ids, parent.ID)
+		}
+	}
+
+	devices, err = client.getDevicesByID(ctx, ids, virtual)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, iface = range interfaces {
+		converted = &Interface{
+			ID:           iface.ID,
+			IDString:     strconv.FormatUint(iface.ID, 10),
+			Name:         iface.Name,
+			Enabled:      iface.Enabled,
+			CustomFields: iface.CustomFields,
+			Mode:         iface.Mode.Value,
+			Tags:         iface.Tags,
+			TaggedVLANs:  make([]*VLAN, 0, len(iface.TaggedVLANs)),
+			isVirtual:    virtual,
+		}
+
+		if parent = iface.parent(); parent != nil {
+			converted.Device = devices[parent.ID]
+		}
+
+		if iface.UntaggedVLAN != nil {
+			converted.UntaggedVLAN = iface.UntaggedVLAN.vlan()
+		}
+
+		for i = range iface.TaggedVLANs {
+			converted.TaggedVLANs = append(converted.TaggedVLANs, iface.TaggedVLANs[i].vlan())
+		}
+
+		result = append(result, converted)
+	}
+
+	return result, nil
+}
+
+// parent returns the reference to the device or VM of iface.
+func (iface *restInterface) parent() *restRef {
+	if iface.VM != nil {
+		return iface.VM
+	}
+
+	return iface.Device
+}
+
+// vlan converts vlan to a VLAN.
+func (vlan *restVLAN) vlan() *VLAN {
+	return &VLAN{
+		ID:       vlan.ID,
+		IDString: strconv.FormatUint(vlan.ID, 10),
+		VID:      vlan.VID,
+		Name:     vlan.Name,
+		Status:   vlan.Status.Value,
+		Group:    restName(vlan.Group),
+		Site:     restName(vlan.Site),
+		Tenant:   restName(vlan.Tenant),
+		Tags:     vlan.Tags,
+	}
+}
+
+/*
+ * IP addresses
+ */
+
+// GetIPsByAddress returns a list of all IPs with the given address (regardless of VRF and mask) or nil if there is
+// none.
+func (client *RESTClient) GetIPsByAddress(ctx context.Context, ip string) ([]*IP, error) {
+	var (
+		ips []*IP
+		err error
+	)
+
+	ctx = withQueryType(ctx, "ips_by_address")
+
+	ips, err = client.getIPsByValues(ctx, url.Values{"address": {ip}})
+	if err != nil || len(ips) == 0 {
+		return nil, err
+	}
+
+	return ips, nil
+}
+
+// GetInterfaceIPs returns a list of all IPs associated with the device interface identified by id.
+func (client *RESTClient) GetInterfaceIPs(ctx context.Context, id uint64) ([]*IP, error) {
+	return client.getIPsByValues(withQueryType(ctx, "interface_ips"), url.Values{"interface_id": {strconv.FormatUint(id, 10)}})
+}
+
+// GetVirtualInterfaceIPs returns a list of all IPs associated with the VM interface identified by id.
+func (client *RESTClient) GetVirtualInterfaceIPs(ctx context.Context, id uint64) ([]*IP, error) {
+	return client.getIPsByValues(withQueryType(ctx, "virtual_interface_ips"), url.Values{"vminterface_id": {strconv.FormatUint(id, 10)}})
+}
+
+// GetIPsInPrefix returns a list of all IPs within the given prefix in any VRF.
+func (client *RESTClient) GetIPsInPrefix(ctx context.Context, prefix string) ([]*IP, error) {
+	if _, err := netip.ParsePrefix(prefix); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPrefix, prefix)
+	}
+
+	return client.getIPsByValues(withQueryType(ctx, "ips_in_prefix"), url.Values{"parent": {prefix}})
+}
+
+/*
+ * prefixes
+ */
+
+// restPrefix is a prefix as returned by the REST API.
+type restPrefix struct {
+	ID     uint64     `json:"id"`
+	Prefix string     `json:"prefix"`
+	Status restChoice `json:"status"`
+	VRF    *struct {
+		ID   uint64 `json:"id"`
+		Name string `json:"name"`
+	} `json:"vrf"`
+	Tenant       *Name `json:"tenant"`
+	Role         *Name `json:"role"`
+	IsPool       bool  `json:"is_pool"`
+	Tags         []Tag `json:"tags"`
+	CustomFields CFMap `json:"custom_fields"`
+}
+
+// GetPrefixes returns a list of all prefixes matching filter.
+func (client *RESTClient) GetPrefixes(ctx context.Context, filter *PrefixFilter) ([]*Prefix, error) {
+	return getAllConverted(withQueryType(ctx, "prefixes"), client.Client, restPrefixesPath, filter.values(url.Values{}),
+		(*restPrefix).prefix)
+}
+
+// prefix converts prefix to a Prefix.
+func (prefix *restPrefix) prefix() *Prefix {
+	var result *Prefix = &Prefix{
+		ID:           prefix.ID,
+		IDString:     strconv.FormatUint(prefix.ID, 10),
+		Prefix:       prefix.Prefix,
+		Status:       prefix.Status.Value,
+		Tenant:       restName(prefix.Tenant),
+		Role:         restName(prefix.Role),
+		IsPool:       prefix.IsPool,
+		Tags:         prefix.Tags,
+		CustomFields: prefix.CustomFields,
+	}
+
+	if prefix.VRF != nil {
+		result.VRF = &VRF{
+			ID:       prefix.VRF.ID,
+			IDString: strconv.FormatUint(prefix.VRF.ID, 10),
+			Name:     prefix.VRF.Name,
+		}
+	}
+
+	return result
+}
+
+/*
+ * services
+ */
+
+// GetServices returns a list of all services.
+func (client *RESTClient) GetServices(ctx context.Context) ([]*Service, error) {
+	return client.getServicesByValues(withQueryType(ctx, "services"), url.Values{})
+}
+
+// GetServicesByName returns a list of all services with the given name.
+func (client *RESTClient) GetServicesByName(ctx context.Context, name string) ([]*Service, error) {
+	return client.getServicesByValues(withQueryType(ctx, "services_by_name"), url.Values{"name": {name}})
+}
+
+// getServicesByValues returns all services matching values. Their devices, VMs and IPs are resolved with additional
+// requests.
+func (client *RESTClient) getServicesByValues(ctx context.Context, values url.Values) ([]*Service, error) {
+	var (
+		services  []restService
+		service   restService
+		deviceIDs []uint64
+		vmIDs     []uint64
+		ipIDs     []uint64
+		devices   map[uint64]*Device
+		vms       map[uint64]*Device
+		ips       map[uint64]*IP
+		result    []*Service = make([]*Service, 0)
+		converted *Service
+		err       error
+		i         int
+	)
+
+	services, err = getAllAs[restService](ctx, client.Client, restServicesPath, values)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, service = range services {
+		if service.Device != nil {
+			deviceIDs = append(deviceIDs, service.Device.ID)
+		}
+
+		if service.VM != nil {
+			vmIDs = append(vmIDs, service.VM.ID)
+		}
+
+		for i = range service.IPAddresses {
+			ipIDs = append(ipIDs, service.IPAddresses[i].ID)
+		}
+	}
+
+	devices, err = client.getDevicesByID(ctx, deviceIDs, false)
+	if err != nil {
+		return nil, err
+	}
+
+	vms, err = client.getDevicesByID(ctx, vmIDs, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err = client.getIPsByID(ctx, ipIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, service = range services {
+		converted = &Service{
+			ID:           service.ID,
+			IDString:     strconv.FormatUint(service.ID, 10),
+			Name:         service.Name,
+			Ports:        service.Ports,
+			IPAddresses:  make([]*IP, 0, len(service.IPAddresses)),
+			Protocol:     service.Protocol.Value,
+			CustomFields: service.CustomFields,
+		}
+
+		if service.Device != nil {
+			converted.Device = devices[service.Device.ID]
+		}
+
+		if service.VM != nil {
+			converted.VM = vms[service.VM.ID]
+		}
+
+		for i = range service.IPAddresses {
+			if ips[service.IPAddresses[i].ID] != nil {
+				converted.IPAddresses = append(converted.IPAddresses, ips[service.IPAddresses[i].ID])
+			}
+		}
+
+		result = append(result, converted)
+	}
+
+	return result, nil
+}
+
+/*
+ * VLANs
+ */
+
+// GetVLANs returns a list of all vlans.
+func (client *RESTClient) GetVLANs(ctx context.Context) ([]*VLAN, error) {
+	return client.getVLANsByValues(withQueryType(ctx, "vlans"), url.Values{})
+}
+
+// GetVLANsByVID returns a list of all vlans using the given VLAN ID.
+func (client *RESTClient) GetVLANsByVID(ctx context.Context, vid uint16) ([]*VLAN, error) {
+	return client.getVLANsByValues(withQueryType(ctx, "vlans_by_vid"), url.Values{"vid": {strconv.FormatUint(uint64(vid), 10)}})
+}
+
+// GetVLANsByName returns a list of all vlans with the given name.
+func (client *RESTClient) GetVLANsByName(ctx context.Context, name string) ([]*VLAN, error) {
+	return client.getVLANsByValues(withQueryType(ctx, "vlans_by_name"), url.Values{"name": {name}})
+}
+
+// getVLANsByValues returns all vlans matching values.
+func (client *RESTClient) getVLANsByValues(ctx context.Context, values url.Values) ([]*VLAN, error) {
+	var (
+		vlans  []restVLAN
+		result []*VLAN = make([]*VLAN, 0)
+		err    error
+		i      int
+	)
+
+	vlans, err = getAllAs[restVLAN](ctx, client.Client, restVLANsPath, values)
+	if err != nil {
+		return nil, err
+	}
+
+	for i = range vlans {
+		result = append(result, vlans[i].vlan())
+	}
+
+	return result, nil
+}
+
+/*
+ * Wireless LANs
+ */
+
+// GetWirelessLANsBySSID returns a list of all wireless LANs with the given SSID.
+func (client *RESTClient) GetWirelessLANsBySSID(ctx context.Context, ssid string) ([]*WirelessLAN, error) {
+	var (
+		wlans  []restWirelessLAN
+		result []*WirelessLAN = make([]*WirelessLAN, 0)
+		err    error
+		i      int
+	)
+
+	ctx = withQueryType(ctx, "wireless_lans_by_ssid")
+
+	wlans, err = getAllAs[restWirelessLAN](ctx, client.Client, restWirelessLANsPath, url.Values{"ssid": {ssid}})
+	if err != nil {
+		return nil, err
+	}
+
+	for i = range wlans {
+		result = append(result, &WirelessLAN{
+			ID:       wlans[i].ID,
+			IDString: strconv.FormatUint(wlans[i].ID, 10),
+			SSID:     wlans[i].SSID,
+		})
+	}
+
+	return result, nil
+}
+
+/*
+ * VDCs
+ */
+
+// GetVDCsByTag returns a list of all virtual device contexts with a given tag. Their devices and primary IPs are
+// resolved with additional requests.
+func (client *RESTClient) GetVDCsByTag(ctx context.Context, tag string) ([]*VDC, error) {
+	var (
+		vdcs      []restVDC
+		vdc       restVDC
+		deviceIDs []uint64
+		ipIDs     []uint64
+		devices   map[uint64]*Device
+		ips       map[uint64]*IP
+		result    []*VDC = make([]*VDC, 0)
+		converted *VDC
+		err       error
+	)
+
+	ctx = withQueryType(ctx, "vdcs_by_tag")
+
+	vdcs, err = getAllAs[restVDC](ctx, client.Client, restVDCsPath, url.Values{"tag": {tag}})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vdc = range vdcs {
+		if vdc.Device != nil {
+			deviceIDs = append(deviceIDs, vdc.Device.ID)
+		}
+
+		if vdc.PrimaryIP4 != nil {
+			ipIDs = append(ipIDs, vdc.PrimaryIP4.ID)
+		}
+
+		if vdc.PrimaryIP6 != nil {
+			ipIDs = append(ipIDs, vdc.PrimaryIP6.ID)
+		}
+	}
+
+	devices, err = client.getDevicesByID(ctx, deviceIDs, false)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err = client.getIPsByID(ctx, ipIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vdc = range vdcs {
+		converted = &VDC{
+			ID:           vdc.ID,
+			IDString:     strconv.FormatUint(vdc.ID, 10),
+			Name:         vdc.Name,
+			Identifier:   vdc.Identifier,
+			Status:       vdc.Status.Value,
+			CustomFields: vdc.CustomFields,
+			Tenant:       restName(vdc.Tenant),
+			Tags:         vdc.Tags,
+		}
+
+		if vdc.Device != nil {
+			converted.Parent = devices[vdc.Device.ID]
+		}
+
+		if vdc.PrimaryIP4 != nil {
+			converted.PrimaryIP4 = ips[vdc.PrimaryIP4.ID]
+		}
+
+		if vdc.PrimaryIP6 != nil {
+			converted.PrimaryIP6 = ips[vdc.PrimaryIP6.ID]
+		}
+
+		result = append(result, converted)
+	}
+
+	return result, nil
+}
+
+/*
+ * organizational objects
+ */
+
+// restSite is a site as returned by the REST API.
+type restSite struct {
+	ID           uint64     `json:"id"`
+	Name         string     `json:"name"`
+	Slug         string     `json:"slug"`
+	Status       restChoice `json:"status"`
+	Region       *Name      `json:"region"`
+	Group        *Name      `json:"group"`
+	Tenant       *Name      `json:"tenant"`
+	Tags         []Tag      `json:"tags"`
+	CustomFields CFMap      `json:"custom_fields"`
+}
+
+// restTenant is a tenant as returned by the REST API.
+type restTenant struct {
+	ID           uint64 `json:"id"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	Group        *Name  `json:"group"`
+	Tags         []Tag  `json:"tags"`
+	CustomFields CFMap  `json:"custom_fields"`
+}
+
+// restRack is a rack as returned by the REST API.
+type restRack struct {
+	ID           uint64     `json:"id"`
+	Name         string     `json:"name"`
+	Status       restChoice `json:"status"`
+	Site         *Name      `json:"site"`
+	Location     *Name      `json:"location"`
+	Tenant       *Name      `json:"tenant"`
+	Tags         []Tag      `json:"tags"`
+	CustomFields CFMap      `json:"custom_fields"`
+}
+
+// restCluster is a virtualization cluster as returned by the REST API.
+type restCluster struct {
+	ID           uint64     `json:"id"`
+	Name         string     `json:"name"`
+	Status       restChoice `json:"status"`
+	Type         *Name      `json:"type"`
+	Group        *Name      `json:"group"`
+	Tenant       *Name      `json:"tenant"`
+	Tags         []Tag      `json:"tags"`
+	CustomFields CFMap      `json:"custom_fields"`
+}
+
+// restRegion is a region as returned by the REST API.
+type restRegion struct {
+	ID           uint64 `json:"id"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	Parent       *Name  `json:"parent"`
+	Tags         []Tag  `json:"tags"`
+	CustomFields CFMap  `json:"custom_fields"`
+}
+
+// restLocation is a location as returned by the REST API.
+type restLocation struct {
+	ID           uint64     `json:"id"`
+	Name         string     `json:"name"`
+	Slug         string     `json:"slug"`
+	Status       restChoice `json:"status"`
+	Site         *Name      `json:"site"`
+	Parent       *Name      `json:"parent"`
+	Tenant       *Name      `json:"tenant"`
+	Tags         []Tag      `json:"tags"`
+	CustomFields CFMap      `json:"custom_fields"`
+}
+
+// GetSites returns a list of all sites.
+func (client *RESTClient) GetSites(ctx context.Context) ([]*Site, error) {
+	return getAllConverted(withQueryType(ctx, "sites"), client.Client, restSitesPath, url.Values{}, (*restSite).site)
+}
+
+// GetSitesByTag returns a list of all sites with a given tag.
+func (client *RESTClient) GetSitesByTag(ctx context.Context, tag string) ([]*Site, error) {
+	return getAllConverted(withQueryType(ctx, "sites_by_tag"), client.Client, restSitesPath, url.Values{"tag": {tag}},
+		(*restSite).site)
+}
+
+// GetTenants returns a list of all tenants.
+func (client *RESTClient) GetTenants(ctx context.Context) ([]*Tenant, error) {
+	return getAllConverted(withQueryType(ctx, "tenants"), client.Client, restTenantsPath, url.Values{}, (*restTenant).tenant)
+}
+
+// GetTenantsByTag returns a list of all tenants with a given tag.
+func (client *RESTClient) GetTenantsByTag(ctx context.Context, tag string) ([]*Tenant, error) {
+	return getAllConverted(withQueryType(ctx, "tenants_by_tag"), client.Client, restTenantsPath,
+		url.Values{"tag": {tag}}, (*restTenant).tenant)
+}
+
+// GetRacks returns a list of all racks.
+func (client *RESTClient) GetRacks(ctx context.Context) ([]*Rack, error) {
+	return getAllConverted(withQueryType(ctx, "racks"), client.Client, restRacksPath, url.Values{}, (*restRack).rack)
+}
+
+// GetRacksByTag returns a list of all racks with a given tag.
+func (client *RESTClient) GetRacksByTag(ctx context.Context, tag string) ([]*Rack, error) {
+	return getAllConverted(withQueryType(ctx, "racks_by_tag"), client.Client, restRacksPath, url.Values{"tag": {tag}},
+		(*restRack).rack)
+}
+
+// GetClusters returns a list of all virtualization clusters.
+func (client *RESTClient) GetClusters(ctx context.Context) ([]*Cluster, error) {
+	return getAllConverted(withQueryType(ctx, "clusters"), client.Client, restClustersPath, url.Values{}, (*restCluster).cluster)
+}
+
+// GetClustersByTag returns a list of all virtualization clusters with a given tag.
+func (client *RESTClient) GetClustersByTag(ctx context.Context, tag string) ([]*Cluster, error) {
+	return getAllConverted(withQueryType(ctx, "clusters_by_tag"), client.Client, restClustersPath,
+		url.Values{"tag": {tag}}, (*restCluster).cluster)
+}
+
+// GetRegions returns a list of all regions.
+func (client *RESTClient) GetRegions(ctx context.Context) ([]*Region, error) {
+	return getAllConverted(withQueryType(ctx, "regions"), client.Client, restRegionsPath, url.Values{}, (*restRegion).region)
+}
+
+// GetRegionsByTag returns a list of all regions with a given tag.
+func (client *RESTClient) GetRegionsByTag(ctx context.Context, tag string) ([]*Region, error) {
+	return getAllConverted(withQueryType(ctx, "regions_by_tag"), client.Client, restRegionsPath,
+		url.Values{"tag": {tag}}, (*restRegion).region)
+}
+
+// GetLocations returns a list of all locations.
+func (client *RESTClient) GetLocations(ctx context.Context) ([]*Location, error) {
+	return getAllConverted(withQueryType(ctx, "locations"), client.Client, restLocationsPath, url.Values{},
+		(*restLocation).location)
+}
+
+// GetLocationsByTag returns a list of all locations with a given tag.
+func (client *RESTClient) GetLocationsByTag(ctx context.Context, tag string) ([]*Location, error) {
+	return getAllConverted(withQueryType(ctx, "locations_by_tag"), client.Client, restLocationsPath,
+		url.Values{"tag": {tag}}, (*restLocation).location)
+}
+
+// site converts site to a Site.
+func (site *restSite) site() *Site {
+	return &Site{
+		ID:           site.ID,
+		IDString:     strconv.FormatUint(site.ID, 10),
+		Name:         site.Name,
+		Slug:         site.Slug,
+		Status:       site.Status.Value,
+		Region:       restName(site.Region),
+		Group:        restName(site.Group),
+		Tenant:       restName(site.Tenant),
+		Tags:         site.Tags,
+		CustomFields: site.CustomFields,
+	}
+}
+
+// tenant converts tenant to a Tenant.
+func (tenant *restTenant) tenant() *Tenant {
+	return &Tenant{
+		ID:           tenant.ID,
+		IDString:     strconv.FormatUint(tenant.ID, 10),
+		Name:         tenant.Name,
+		Slug:         tenant.Slug,
+		Group:        restName(tenant.Group),
+		Tags:         tenant.Tags,
+		CustomFields: tenant.CustomFields,
+	}
+}
+
+// rack converts rack to a Rack.
+func (rack *restRack) rack() *Rack {
+	return &Rack{
+		ID:           rack.ID,
+		IDString:     strconv.FormatUint(rack.ID, 10),
+		Name:         rack.Name,
+		Status:       rack.Status.Value,
+		Site:         restName(rack.Site),
+		Location:     restName(rack.Location),
+		Tenant:       restName(rack.Tenant),
+		Tags:         rack.Tags,
+		CustomFields: rack.CustomFields,
+	}
+}
+
+// cluster converts cluster to a Cluster.
+func (cluster *restCluster) cluster() *Cluster {
+	return &Cluster{
+		ID:           cluster.ID,
+		IDString:     strconv.FormatUint(cluster.ID, 10),
+		Name:         cluster.Name,
+		Status:       cluster.Status.Value,
+		Type:         restName(cluster.Type),
+		Group:        restName(cluster.Group),
+		Tenant:       restName(cluster.Tenant),
+		Tags:         cluster.Tags,
+		CustomFields: cluster.CustomFields,
+	}
+}
+
+// region converts region to a Region.
+func (region *restRegion) region() *Region {
+	return &Region{
+		ID:           region.ID,
+		IDString:     strconv.FormatUint(region.ID, 10),
+		Name:         region.Name,
+		Slug:         region.Slug,
+		Parent:       restName(region.Parent),
+		Tags:         region.Tags,
+		CustomFields: region.CustomFields,
+	}
+}
+
+// location converts location to a Location.
+func (location *restLocation) location() *Location {
+	return &Location{
+		ID:           location.ID,
+		IDString:     strconv.FormatUint(location.ID, 10),
+		Name:         location.Name,
+		Slug:         location.Slug,
+		Status:       location.Status.Value,
+		Site:         restName(location.Site),
+		Parent:       restName(location.Parent),
+		Tenant:       restName(location.Tenant),
+		Tags:         location.Tags,
+		CustomFields: location.CustomFields,
+	}
+}
@@ -0,0 +1,426 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains functions to query devices and VMs with arbitrary REST API filters.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	restDevicesPath string = "/api/dcim/devices/"
+	restVMsPath     string = "/api/virtualization/virtual-machines/"
+	restIPsPath     string = "/api/ipam/ip-addresses/"
+	// restPageSize is the number of objects requested per page.
+	restPageSize int = 1000
+	// restIDBatchSize is the number of IDs requested at once when resolving primary IPs.
+	restIDBatchSize int = 100
+)
+
+// ErrBadRESTQuery is returned when REST query parameters cannot be parsed.
+var ErrBadRESTQuery = errors.New("bad REST query parameters")
+
+// restPage is a single page of a REST list response.
+type restPage struct {
+	Next    *string         `json:"next"`
+	Results json.RawMessage `json:"results"`
+}
+
+// restChoice is a choice field (like status) of a REST response.
+type restChoice struct {
+	Value string `json:"value"`
+}
+
+// restRef is a reference to another object in a REST response.
+type restRef struct {
+	ID uint64 `json:"id"`
+}
+
+// restDevice is a device or VM as returned by the REST API.
+type restDevice struct {
+	ID           uint64     `json:"id"`
+	Name         *string    `json:"name"`
+	PrimaryIP4   *restRef   `json:"primary_ip4"`
+	PrimaryIP6   *restRef   `json:"primary_ip6"`
+	CustomFields CFMap      `json:"custom_fields"`
+	Rack         *Name      `json:"rack"`
+	Site         *Name      `json:"site"`
+	Role         *Name      `json:"role"`
+	Tenant       *Name      `json:"tenant"`
+	Platform     *Name      `json:"platform"`
+	Serial       string     `json:"serial"`
+	AssetTag     *string    `json:"asset_tag"`
+	Status       restChoice `json:"status"`
+	Tags         []Tag      `json:"tags"`
+	// ConfigContext is only returned when not excluded.
+	ConfigContext map[string]any `json:"config_context"`
+}
+
+// restIP is an IP address as returned by the REST API.
+type restIP struct {
+	ID      uint64     `json:"id"`
+	Address string     `json:"address"`
+	Status  restChoice `json:"status"`
+	DNSName string     `json:"dns_name"`
+	VRF     *struct {
+		ID   uint64 `json:"id"`
+		Name string `json:"name"`
+	} `json:"vrf"`
+}
+
+// GetDevicesByQuery returns a list of all devices matching the given REST API query parameters (e.g.
+// `role=router&status=active`). This allows using filters not supported by GraphQL.
+func (client *Client) GetDevicesByQuery(ctx context.Context, params string) ([]*Device, error) {
+	return client.getDevicesByQuery(withQueryType(ctx, "devices_by_query"), restDevicesPath, params, false)
+}
+
+// GetVMsByQuery returns a list of all vms matching the given REST API query parameters. See GetDevicesByQuery.
+func (client *Client) GetVMsByQuery(ctx context.Context, params string) ([]*Device, error) {
+	return client.getDevicesByQuery(withQueryType(ctx, "vms_by_query"), restVMsPath, params, true)
+}
+
+// getDevicesByQuery returns the devices (or VMs when virtual is true) returned by path using params as filter.
+func (client *Client) getDevicesByQuery(ctx context.Context, path, params string, virtual bool) ([]*Device, error) {
+	var (
+		values url.Values
+		err    error
+	)
+
+	values, err = url.ParseQuery(strings.TrimPrefix(params, "?"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadRESTQuery, err)
+	}
+
+	return client.getDevicesByValues(ctx, path, values, virtual, false)
+}
+
+// getDevicesByValues returns the devices (or VMs when virtual is true) returned by path using values as filter. Primary
+// IPs are resolved with additional requests as the REST API only returns their address but no status or vrf. Rendering
+// config contexts is expensive, thus they are excluded unless configContext is true.
+func (client *Client) getDevicesByValues(ctx context.Context, path string, values url.Values, virtual,
+	configContext bool) ([]*Device, error) {
+	var (
+		devs []restDevice
+		err  error
+	)
+
+	if !configContext {
+		values.Set("exclude", "config_context")
+	}
+
+	devs, err = getAllAs[restDevice](ctx, client, path, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.restDevices(ctx, devs, virtual)
+}
+
+// restDevices converts devs to Devices (VMs when virtual is true), resolving their primary IPs with additional
+// requests.
+func (client *Client) restDevices(ctx context.Context, devs []restDevice, virtual bool) ([]*Device, error) {
+	var (
+		dev    restDevice
+		ids    []uint64
+		ips    map[uint64]*IP
+		result []*Device = make([]*Device, 0, len(devs))
+		device *Device
+		err    error
+		i      int
+	)
+
+	for i = range devs {
+		if devs[i].PrimaryIP4 != nil {
+			ids = append(ids, devs[i].PrimaryIP4.ID)
+		}
+
+		if devs[i].PrimaryIP6 != nil {
+			ids = append(ids, devs[i].PrimaryIP6.ID)
+		}
+	}
+
+	ips, err = client.getIPsByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dev = range devs {
+		device = &Device{
+			ID:            dev.ID,
+			IDString:      strconv.FormatUint(dev.ID, 10),
+			CustomFields:  dev.CustomFields,
+			Rack:          restName(dev.Rack),
+			Site:          restName(dev.Site),
+			Role:          restName(dev.Role),
+			Tenant:        restName(dev.Tenant),
+			Platform:      restName(dev.Platform),
+			SerialNumber:  dev.Serial,
+			Status:        dev.Status.Value,
+			Tags:          dev.Tags,
+			ConfigContext: dev.ConfigContext,
+			isVirtual:     virtual,
+		}
+
+		if dev.Name != nil {
+			device.Name = *dev.Name
+		}
+
+		if dev.AssetTag != nil {
+			device.AssetTag = *dev.AssetTag
+		}
+
+		if dev.PrimaryIP4 != nil {
+			device.PrimaryIP4 = ips[dev.PrimaryIP4.ID]
+		}
+
+		if dev.PrimaryIP6 != nil {
+			device.PrimaryIP6 = ips[dev.PrimaryIP6.ID]
+		}
+
+		result = append(result, device)
+	}
+
+	return result, nil
+}
+
+// restName returns the referenced Name or an empty Name when the reference is null.
+func restName(name *Name) Name {
+	if name == nil {
+		return Name{}
+	}
+
+	return *name
+}
+
+// getIPsByID returns all IPs identified by ids indexed by their ID.
+func (client *Client) getIPsByID(ctx context.Context, ids []uint64) (map[uint64]*IP, error) {
+	var (
+		result map[uint64]*IP = make(map[uint64]*IP)
+		ips    []*IP
+		err    error
+		i, j   int
+	)
+
+	for i = 0; i < len(ids); i += restIDBatchSize {
+		ips, err = client.getIPsByValues(ctx, idValues(ids[i:min(i+restIDBatchSize, len(ids))]))
+		if err != nil {
+			return nil, err
+		}
+
+		for j = range ips {
+			result[ips[j].ID] = ips[j]
+		}
+	}
+
+	return result, nil
+}
+
+// getIPsByValues returns all IPs matching values.
+func (client *Client) getIPsByValues(ctx context.Context, values url.Values) ([]*IP, error) {
+	var (
+		ips    []restIP
+		result []*IP = make([]*IP, 0)
+		err    error
+		i      int
+	)
+
+	ips, err = getAllAs[restIP](ctx, client, restIPsPath, values)
+	if err != nil {
+		return nil, err
+	}
+
+	for i = range ips {
+		result = append(result, ips[i].ip())
+	}
+
+	return result, nil
+}
+
+// ip converts ip to an IP.
+func (ip *restIP) ip() *IP {
+	var result *IP = &IP{
+		ID:       ip.ID,
+		IDString: strconv.FormatUint(ip.ID, 10),
+		Address:  ip.Address,
+		Status:   ip.Status.Value,
+		DNSName:  ip.DNSName,
+	}
+
+	if ip.VRF != nil {
+		result.VRF = &VRF{
+			ID:       ip.VRF.ID,
+			IDString: strconv.FormatUint(ip.VRF.ID, 10),
+			Name:     ip.VRF.Name,
+		}
+	}
+
+	return result
+}
+
+// idValues returns REST API query parameters selecting the objects identified by ids.
+func idValues(ids []uint64) url.Values {
+	var (
+		values url.Values = make(url.Values)
+		id     uint64
+	)
+
+	for _, id = range ids {
+		values.Add("id", strconv.FormatUint(id, 10))
+	}
+
+	return values
+}
+
+// getAllConverted returns the results of all pages of a REST list endpoint using values as filter decoded as R and
+// converted by convert.
+func getAllConverted[R any, T any](ctx context.Context, client *Client, path string, values url.Values,
+	convert func(*R) *T) ([]*T, error) {
+	var (
+		objs   []R
+		result []*T
+		err    error
+		i      int
+	)
+
+	objs, err = getAllAs[R](ctx, client, path, values)
+	if err != nil {
+		return nil, err
+	}
+
+	result = make([]*T, 0, len(objs))
+
+	for i = range objs {
+		result = append(result, convert(&objs[i]))
+	}
+
+	return result, nil
+}
+
+// getAllAs returns the results of all pages of a REST list endpoint using values as filter decoded as T.
+func getAllAs[T any](ctx context.Context, client *Client, path string, values url.Values) ([]T, error) {
+	var (
+		results []json.RawMessage
+		objs    []T
+		err     error
+		i       int
+	)
+
+	results, err = client.getAll(ctx, path, values)
+	if err != nil {
+		return nil, err
+	}
+
+	objs = make([]T, len(results))
+
+	for i = range results {
+		err = json.Unmarshal(results[i], &objs[i])
+		if err != nil {
+			client.promFailure.Inc()
+			return nil, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
+		}
+	}
+
+	return objs, nil
+}
+
+// getAll returns the results of all pages of a REST list endpoint using values as filter.
+func (client *Client) getAll(ctx context.Context, path string, values url.Values) ([]json.RawMessage, error) {
+	var (
+		results []json.RawMessage
+		err     error
+	)
+
+	err = client.forEachPage(ctx, path, values, func(items []json.RawMessage) error {
+		results = append(results, items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// forEachPage calls fn with the results of each page of a REST list endpoint using values as filter. Errors returned
+// by fn stop fetching further pages and are returned as is.
+func (client *Client) forEachPage(ctx context.Context, path string, values url.Values,
+	fn func([]json.RawMessage) error) error {
+	var (
+		items  []json.RawMessage
+		page   restPage
+		resp   response
+		query  string
+		offset int
+		err    error
+	)
+
+	values.Set("limit", strconv.Itoa(restPageSize))
+
+	for {
+		values.Set("offset", strconv.Itoa(offset))
+
+		query = path + "?" + values.Encode()
+
+		resp, err = client.cached(ctx, query, func(ctx context.Context) (response, error) {
+			return client.get(ctx, query)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to query api: %w", err)
+		}
+
+		if resp.StatusCode() != 200 {
+			return ErrUnexpectedStatusCode
+		}
+
+		page = restPage{}
+
+		err = json.Unmarshal(resp.RawBody().Bytes(), &page)
+		if err != nil {
+			client.promFailure.Inc()
+			return fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
+		}
+
+		items = nil
+
+		if len(page.Results) > 0 {
+			err = json.Unmarshal(page.Results, &items)
+			if err != nil {
+				client.promFailure.Inc()
+				return fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
+			}
+		}
+
+		err = fn(items)
+		if err != nil {
+			return err
+		}
+
+		if page.Next == nil || len(items) == 0 {
+			return nil
+		}
+
+		offset += len(items)
+	}
+}
@@ -0,0 +1,145 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains functions for retrying failed HTTP requests.
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxRetryDelay limits the delay before retrying a request, including a delay requested by Netbox using Retry-After.
+const maxRetryDelay time.Duration = time.Minute
+
+// do sends req to Netbox. Requests failing due to network errors or with a 5xx or 429 status code are attempted up to
+// maxAttempts times in total. Before the first retry the client waits for retryBackoff, doubling the delay for every
+// further retry unless Netbox asks for a specific delay using Retry-After. The result of the last attempt is returned.
+// path is used as url label of metrics. The body of req is restored using req.GetBody for every retry. The User-Agent
+// header is set when the Client has one configured, the Authorization header is set to the token of the Client's
+// TokenSource.
+func (client *Client) do(ctx context.Context, req *http.Request, path string) (*http.Response, error) {
+	var (
+		resp    *http.Response
+		err     error
+		attempt int
+		delay   time.Duration
+		token   string
+	)
+
+	token, err = client.tokens.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api token: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Token "+token)
+
+	if client.userAgent != "" {
+		req.Header.Set("User-Agent", client.userAgent)
+	}
+
+	for attempt = 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err = client.http.Do(req.WithContext(ctx))
+		if attempt >= client.maxAttempts || !retryable(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay = retryDelay(resp, client.retryBackoff, attempt)
+
+		if err != nil {
+			client.logger().WarnContext(ctx, "request failed, retrying", "url", path, "delay", delay, "error", err)
+		} else {
+			client.logger().WarnContext(ctx, "request failed, retrying", "url", path, "delay", delay, "status",
+				resp.StatusCode)
+
+			// drain the body so the connection can be reused
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		client.promRetry.
+			With(prometheus.Labels{
+				"url":   path,
+				"query": queryType(ctx),
+			}).
+			Inc()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryable returns true when a request resulting in resp and err is worth retrying.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !isContextError(err)
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// retryDelay returns the delay before the next attempt after attempt failed. A delay requested by Netbox using
+// Retry-After (either in seconds or as a date) takes precedence over backoff, which is doubled for every attempt.
+func retryDelay(resp *http.Response, backoff time.Duration, attempt int) time.Duration {
+	var (
+		delay   time.Duration = backoff
+		i       int
+		value   string
+		seconds int
+		date    time.Time
+		err     error
+	)
+
+	for i = 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if resp != nil {
+		value = resp.Header.Get("Retry-After")
+	}
+
+	if value != "" {
+		if seconds, err = strconv.Atoi(value); err == nil && seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+		} else if date, err = http.ParseTime(value); err == nil {
+			delay = max(time.Until(date), 0)
+		}
+	}
+
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	return delay
+}
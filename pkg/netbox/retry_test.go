@@ -0,0 +1,123 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry(t *testing.T) {
+	var (
+		server *httptest.Server
+		calls  atomic.Int32
+		fails  atomic.Int32
+		status atomic.Int32
+		client *Client
+		resp   response
+		err    error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+
+		calls.Add(1)
+
+		// the body must be sent again with every attempt
+		body, _ = io.ReadAll(r.Body)
+		if r.Method == http.MethodPost && string(body) != `{"query":"query"}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if fails.Add(-1) >= 0 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(int(status.Load()))
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithRetries(3, time.Hour))
+	require.NoError(t, err)
+
+	// succeeds with the last attempt
+	fails.Store(2)
+	status.Store(http.StatusServiceUnavailable)
+	resp, err = client.get(context.Background(), "/api/status/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, int32(3), calls.Load())
+
+	calls.Store(0)
+	fails.Store(1)
+	status.Store(http.StatusTooManyRequests)
+	resp, err = client.postGraphQL(context.Background(), &graphQLRequest{Query: "query"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, int32(2), calls.Load())
+
+	// attempts exhausted
+	calls.Store(0)
+	fails.Store(5)
+	status.Store(http.StatusBadGateway)
+	resp, err = client.get(context.Background(), "/api/status/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode())
+	assert.Equal(t, int32(3), calls.Load())
+
+	// client errors are not retried
+	calls.Store(0)
+	fails.Store(5)
+	status.Store(http.StatusForbidden)
+	resp, err = client.get(context.Background(), "/api/status/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode())
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRetryDelay(t *testing.T) {
+	var resp *http.Response = &http.Response{Header: http.Header{}}
+
+	assert.Equal(t, time.Second, retryDelay(nil, time.Second, 1))
+	assert.Equal(t, 4*time.Second, retryDelay(nil, time.Second, 3))
+	assert.Equal(t, maxRetryDelay, retryDelay(nil, time.Second, 100))
+
+	resp.Header.Set("Retry-After", "7")
+	assert.Equal(t, 7*time.Second, retryDelay(resp, time.Second, 1))
+
+	resp.Header.Set("Retry-After", "3600")
+	assert.Equal(t, maxRetryDelay, retryDelay(resp, time.Second, 1))
+
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	assert.Equal(t, time.Duration(0), retryDelay(resp, time.Second, 1))
+
+	// invalid values are ignored
+	resp.Header.Set("Retry-After", "soon")
+	assert.Equal(t, 2*time.Second, retryDelay(resp, time.Second, 2))
+}
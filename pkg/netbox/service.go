@@ -17,15 +17,11 @@
 
 package netbox
 
-import (
-	"encoding/json"
-	"fmt"
-)
+import "context"
 
 const (
 	queryServiceAttributes string = "id name device {" + queryDeviceAttributes + "} virtual_machine {" + queryVMAttributes + "} ports ipaddresses {" + queryIPAddressAttributes + "} protocol custom_fields"
-	queryServicesByName    string = "{service_list(filters: {name: {starts_with: \"%s\"}}){" + queryServiceAttributes + "}}"
-	queryServices          string = "{service_list{" + queryServiceAttributes + "}}"
+	queryServiceList       string = "query($filters: ServiceFilter, $pagination: OffsetPaginationInput){service_list(filters: $filters, pagination: $pagination){" + queryServiceAttributes + "}}"
 )
 
 // Service describes a subset of details of a netbox service
@@ -42,53 +38,47 @@ type Service struct {
 }
 
 // GetServices returns a list of all services that exists in Netbox.
-func (client *Client) GetServices() ([]*Service, error) {
+func (client *Client) GetServices(ctx context.Context) ([]*Service, error) {
 	var (
-		resp    response
-		wrapper graphQLResponseWrapper
-		err     error
+		services []*Service
+		err      error
 	)
 
-	resp, err = client.graphQL(queryServices)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query api: %w", err)
-	}
+	ctx = withQueryType(ctx, "services")
 
-	if resp.StatusCode() != 200 {
-		return nil, ErrUnexpectedStatusCode
-	}
+	err = client.graphQLList(ctx, queryServiceList, nil, func(wrapper *graphQLResponseWrapper) int {
+		for i := range wrapper.Data.ServiceList {
+			if wrapper.Data.ServiceList[i].VM != nil {
+				wrapper.Data.ServiceList[i].VM.isVirtual = true
+			}
+		}
 
-	err = json.Unmarshal(resp.RawBody().Bytes(), &wrapper)
-	if err != nil {
-		client.promFailure.Inc()
-		return nil, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
-	}
+		services = append(services, wrapper.Data.ServiceList...)
 
-	for i := range wrapper.Data.ServiceList {
-		if wrapper.Data.ServiceList[i].VM != nil {
-			wrapper.Data.ServiceList[i].VM.isVirtual = true
-		}
+		return len(wrapper.Data.ServiceList)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
-	wrapper.parseIDs()
-
-	return wrapper.Data.ServiceList, nil
+	return services, nil
 }
 
 // GetServicesByName returns a list of all services that exists in Netbox based on the service's name.
-func (client *Client) GetServicesByName(name string) ([]*Service, error) {
+func (client *Client) GetServicesByName(ctx context.Context, name string) ([]*Service, error) {
+	ctx = withQueryType(ctx, "services_by_name")
+
 	//var (
-	//	query   string = fmt.Sprintf(queryServicesByName, name)
+	//	filters map[string]any = map[string]any{"name": map[string]any{"starts_with": name}}
 	//	resp    response
 	//	wrapper graphQLResponseWrapper
 	//	err     error
 	//)
 
 	// TODO: remove this once https://github.com/netbox-community/netbox/issues/17457 has been released
-	return client.getServiceByNameIssue17457(name)
+	return client.getServiceByNameIssue17457(ctx, name)
 
-	// resp, err = client.graphQL(query)
+	// resp, err = client.graphQL(ctx, queryServiceList, map[string]any{"filters": filters})
 	//
 	//	if err != nil {
 	//		return nil, fmt.Errorf("failed to query api: %w", err)
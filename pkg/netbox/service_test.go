@@ -18,6 +18,7 @@
 package netbox
 
 import (
+	"context"
 	"sort"
 	"testing"
 
@@ -109,7 +110,7 @@ func TestGetServices(t *testing.T) {
 	}
 	client := newTestClient(t)
 
-	srv, err := client.GetServices()
+	srv, err := client.GetServices(context.Background())
 	require.NoError(t, err)
 	require.NotEmpty(t, srv)
 	sort.Slice(srv, func(i, j int) bool { return srv[i].ID < srv[j].ID })
@@ -123,13 +124,13 @@ func TestGetServicesByName(t *testing.T) {
 
 	client := newTestClient(t)
 
-	srv, err := client.GetServicesByName("SSH")
+	srv, err := client.GetServicesByName(context.Background(), "SSH")
 	require.NoError(t, err)
 	require.NotEmpty(t, srv)
 	assert.Equal(t, []*Service{service1, service4, service5}, srv)
 
 	// checking for services that don't exist
-	srv, err = client.GetServicesByName("does_not-exist")
+	srv, err = client.GetServicesByName(context.Background(), "does_not-exist")
 	require.NoError(t, err)
 	require.Empty(t, srv)
 }
@@ -0,0 +1,230 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains iterators over lists decoding one object at a time instead of materializing the whole list.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// errUnexpectedJSON is returned when a list response doesn't have the expected structure.
+var errUnexpectedJSON = errors.New("unexpected json structure")
+
+// ForEachDevice calls fn for each device (or VM when query.Virtual is set) selected by query. Unlike the methods
+// returning lists, objects are decoded one at a time while reading the response and passed to fn, thus memory is only
+// needed for a single encoded page (see SetPageSize) instead of all decoded objects of the list. Iteration stops at the
+// first error returned by fn, which is returned as is.
+func (client *Client) ForEachDevice(ctx context.Context, query DeviceQuery, fn func(*Device) error) error {
+	var (
+		document string = queryDeviceList
+		field    string = "device_list"
+		name     string = "each_device"
+	)
+
+	if query.Virtual {
+		document, field, name = queryVMList, "virtual_machine_list", "each_vm"
+	}
+
+	return graphQLEach(withQueryType(ctx, name), client, document, query.filters(), field, func(device *Device) error {
+		// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
+		device.parseIDs()
+		device.isVirtual = query.Virtual
+
+		return fn(device)
+	})
+}
+
+// graphQLEach performs the list query like graphQLList but decodes the objects of the list named field one at a time as
+// T and calls fn for each of them. Errors returned by fn stop the iteration and are returned as is.
+func graphQLEach[T any](ctx context.Context, client *Client, query string, filters map[string]any, field string,
+	fn func(*T) error) error {
+	var (
+		size      int = client.listPageSize()
+		offset    int
+		count     int
+		variables map[string]any
+		resp      response
+		stopped   error
+		err       error
+	)
+
+	for {
+		variables = make(map[string]any)
+
+		if filters != nil {
+			variables["filters"] = filters
+		}
+
+		if size > 0 {
+			variables["pagination"] = map[string]any{"offset": offset, "limit": size}
+		}
+
+		resp, err = client.graphQL(ctx, client.document(query), variables)
+		if err != nil {
+			return fmt.Errorf("failed to query api: %w", err)
+		}
+
+		if resp.StatusCode() != 200 {
+			return ErrUnexpectedStatusCode
+		}
+
+		// the body is read through a reader of its own as it might be shared with cached or coalesced responses
+		count, err = decodeGraphQLList(bytes.NewReader(resp.RawBody().Bytes()), field, func(dec *json.Decoder) error {
+			var (
+				obj T
+				err error
+			)
+
+			err = dec.Decode(&obj)
+			if err != nil {
+				return err
+			}
+
+			stopped = fn(&obj)
+
+			return stopped
+		})
+		if stopped != nil {
+			return stopped
+		}
+
+		if err != nil {
+			client.promFailure.Inc()
+			return fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
+		}
+
+		if size == 0 || count < size {
+			return nil
+		}
+
+		offset += size
+	}
+}
+
+// decodeGraphQLList reads the GraphQL response from body and calls fn for each element of the list data.<field> with
+// dec positioned at the element, which fn must decode. All other fields are skipped. The number of elements is returned.
+func decodeGraphQLList(body io.Reader, field string, fn func(dec *json.Decoder) error) (int, error) {
+	var (
+		dec   *json.Decoder = json.NewDecoder(body)
+		count int
+		err   error
+	)
+
+	err = decodeObject(dec, func(key string) error {
+		if key != "data" {
+			return skipValue(dec)
+		}
+
+		return decodeObject(dec, func(key string) error {
+			if key != field {
+				return skipValue(dec)
+			}
+
+			return decodeArray(dec, func() error {
+				count++
+				return fn(dec)
+			})
+		})
+	})
+
+	return count, err
+}
+
+// decodeObject reads a JSON object from dec and calls fn with the key of each member, which must consume its value. A
+// null value is accepted as empty object.
+func decodeObject(dec *json.Decoder, fn func(key string) error) error {
+	var (
+		token json.Token
+		key   string
+		ok    bool
+		err   error
+	)
+
+	token, err = dec.Token()
+	if err != nil || token == nil {
+		return err
+	}
+
+	if token != json.Delim('{') {
+		return fmt.Errorf("%w: expected object, got %v", errUnexpectedJSON, token)
+	}
+
+	for dec.More() {
+		token, err = dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if key, ok = token.(string); !ok {
+			return fmt.Errorf("%w: expected object key, got %v", errUnexpectedJSON, token)
+		}
+
+		err = fn(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	// closing brace
+	_, err = dec.Token()
+
+	return err
+}
+
+// decodeArray reads a JSON array from dec and calls fn for each element, which must consume it. A null value is
+// accepted as empty array.
+func decodeArray(dec *json.Decoder, fn func() error) error {
+	var (
+		token json.Token
+		err   error
+	)
+
+	token, err = dec.Token()
+	if err != nil || token == nil {
+		return err
+	}
+
+	if token != json.Delim('[') {
+		return fmt.Errorf("%w: expected array, got %v", errUnexpectedJSON, token)
+	}
+
+	for dec.More() {
+		err = fn()
+		if err != nil {
+			return err
+		}
+	}
+
+	// closing bracket
+	_, err = dec.Token()
+
+	return err
+}
+
+// skipValue reads and discards the next JSON value from dec.
+func skipValue(dec *json.Decoder) error {
+	var value json.RawMessage
+
+	return dec.Decode(&value)
+}
@@ -0,0 +1,151 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeGraphQLList(t *testing.T) {
+	var (
+		names []string
+		count int
+		err   error
+	)
+
+	decodeName := func(dec *json.Decoder) error {
+		var obj struct {
+			Name string `json:"name"`
+		}
+
+		err := dec.Decode(&obj)
+		names = append(names, obj.Name)
+
+		return err
+	}
+
+	for body, expected := range map[string][]string{
+		`{"data":{"device_list":[{"name":"a"},{"name":"b"}]}}`:                                    {"a", "b"},
+		`{"extensions":{"x":[1,2]},"data":{"other":[{"name":"x"}],"device_list":[{"name":"a"}]}}`: {"a"},
+		`{"data":{"device_list":[]}}`:                                                             nil,
+		`{"data":{"device_list":null}}`:                                                           nil,
+		`{"data":null}`:                                                                           nil,
+		`{}`:                                                                                      nil,
+	} {
+		names = nil
+
+		count, err = decodeGraphQLList(strings.NewReader(body), "device_list", decodeName)
+		require.NoError(t, err, body)
+		assert.Equal(t, expected, names, body)
+		assert.Equal(t, len(expected), count, body)
+	}
+
+	for _, body := range []string{
+		`[]`,
+		`{"data":{"device_list":{}}}`,
+		`{"data":{"device_list":[{"name":"a"}`,
+		`{"data":{"device_list":[{"name":1}]}}`,
+	} {
+		_, err = decodeGraphQLList(strings.NewReader(body), "device_list", decodeName)
+		assert.Error(t, err, body)
+	}
+}
+
+func TestForEachDevice(t *testing.T) {
+	var (
+		server   *httptest.Server
+		mu       sync.Mutex
+		requests []graphQLRequest
+		client   *Client
+		names    []string
+		stop     error = errors.New("stop")
+		err      error
+	)
+
+	// serves 5 devices, paginated when requested
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			request graphQLRequest
+			list    []map[string]any
+			offset  int = 0
+			limit   int = 5
+		)
+
+		json.NewDecoder(r.Body).Decode(&request)
+
+		mu.Lock()
+		requests = append(requests, request)
+		mu.Unlock()
+
+		if pagination, ok := request.Variables["pagination"].(map[string]any); ok {
+			offset = int(pagination["offset"].(float64))
+			limit = int(pagination["limit"].(float64))
+		}
+
+		for i := offset; i < min(offset+limit, 5); i++ {
+			list = append(list, map[string]any{"id": "1", "name": string(rune('a' + i))})
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"virtual_machine_list": list}})
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token", WithPrometheusNamespace("netbox_go"))
+	require.NoError(t, err)
+	client.SetPageSize(2)
+
+	err = client.ForEachDevice(context.Background(), DeviceQuery{Virtual: true, Tag: "foo"}, func(device *Device) error {
+		assert.True(t, device.IsVirtual())
+		assert.Equal(t, uint64(1), device.ID)
+		names = append(names, device.Name)
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, names)
+	require.Len(t, requests, 3)
+	assert.Equal(t, queryVMList, requests[0].Query)
+	assert.Equal(t, map[string]any{"tag": "foo"}, requests[0].Variables["filters"])
+
+	// errors returned by fn stop the iteration
+	requests = nil
+	names = nil
+
+	err = client.ForEachDevice(context.Background(), DeviceQuery{Virtual: true}, func(device *Device) error {
+		names = append(names, device.Name)
+
+		if device.Name == "c" {
+			return stop
+		}
+
+		return nil
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+	assert.Len(t, requests, 2)
+}
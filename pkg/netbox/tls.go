@@ -0,0 +1,203 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains TLS specific functions.
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Errors related to TLS settings.
+var (
+	ErrTLSNotEnabled   = errors.New("tls transport has not been enabled for client")
+	ErrInvalidPin      = errors.New("invalid public key pin (must be base64 encoded sha256 hash)")
+	ErrPinMismatch     = errors.New("server certificate doesn't match any pinned public key")
+	ErrMissingPeerCert = errors.New("server didn't present any certificate")
+	ErrBadCAFile       = errors.New("failed to read CA certificates")
+	ErrBadClientCert   = errors.New("failed to load client certificate")
+)
+
+// TLSConfig contains TLS options of a Client. CA certificates and client certificates can be given as files or, when
+// embedding this package, in memory. Both are combined when given.
+type TLSConfig struct {
+	// CAFile is a PEM encoded bundle of CA certificates trusted instead of the system wide CAs.
+	CAFile string
+	// RootCAs are CA certificates trusted instead of the system wide CAs. Certificates of CAFile are added to a copy.
+	RootCAs *x509.CertPool
+	// CertFile and KeyFile are a PEM encoded client certificate and key presented to the server (mTLS).
+	CertFile string
+	KeyFile  string
+	// Certificates are client certificates presented to the server (mTLS) in addition to CertFile.
+	Certificates []tls.Certificate
+	// MinVersion is the minimum TLS version accepted (e.g. tls.VersionTLS13). Zero uses the standard library's default.
+	MinVersion uint16
+	// ServerName overrides the name used to verify the server's certificate (and sent via SNI).
+	ServerName string
+	// InsecureSkipVerify disables certificate verification.
+	InsecureSkipVerify bool
+}
+
+// build returns the tls.Config for cfg, reading all referenced files.
+func (cfg *TLSConfig) build() (*tls.Config, error) {
+	var (
+		result *tls.Config = &tls.Config{
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+			MinVersion:         cfg.MinVersion,
+			ServerName:         cfg.ServerName,
+			RootCAs:            cfg.RootCAs,
+			Certificates:       slices.Clone(cfg.Certificates),
+		}
+		data []byte
+		cert tls.Certificate
+		err  error
+	)
+
+	if cfg.CAFile != "" {
+		data, err = os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadCAFile, err)
+		}
+
+		if result.RootCAs == nil {
+			result.RootCAs = x509.NewCertPool()
+		} else {
+			// the pool might be shared with the caller
+			result.RootCAs = result.RootCAs.Clone()
+		}
+
+		if !result.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%w: no certificates found in %s", ErrBadCAFile, cfg.CAFile)
+		}
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err = tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadClientCert, err)
+		}
+
+		result.Certificates = append(result.Certificates, cert)
+	}
+
+	return result, nil
+}
+
+// SPKIHash returns the base64 encoded sha256 hash of the certificate's SubjectPublicKeyInfo. This is the same format as
+// used by HPKP and `openssl x509 -pubkey | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64`.
+func SPKIHash(cert *x509.Certificate) string {
+	var sum [sha256.Size]byte = sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ValidatePin checks that pin is a valid base64 encoded sha256 hash.
+func ValidatePin(pin string) error {
+	var (
+		raw []byte
+		err error
+	)
+
+	raw, err = base64.StdEncoding.DecodeString(pin)
+	if err != nil || len(raw) != sha256.Size {
+		return fmt.Errorf("%w: %s", ErrInvalidPin, pin)
+	}
+
+	return nil
+}
+
+// pinningState is the public key pinning state of a transport.
+type pinningState struct {
+	mu      sync.Mutex
+	enabled bool
+	// InsecureSkipVerify as it was before pinning has been enabled.
+	skipVerify bool
+}
+
+// SetPinnedPublicKeys configures the client to only accept server certificates whose public key hash (see SPKIHash)
+// matches any of the given pins. The certificate chain itself is not validated any further, making this a safer
+// alternative to skipping certificate verification altogether for instances using self-signed certificates. Passing
+// an empty list removes pinning and restores certificate chain validation as it was before pinning. As the transport
+// is shared by all copies of the client (see Copy), pinning applies to all of them. It must not be called while
+// requests are in flight.
+func (client *Client) SetPinnedPublicKeys(pins []string) error {
+	var (
+		transport *http.Transport
+		ok        bool
+		pin       string
+		known     map[string]struct{} = make(map[string]struct{}, len(pins))
+		err       error
+	)
+
+	if transport, ok = client.http.Transport.(*http.Transport); !ok || transport.TLSClientConfig == nil {
+		return ErrTLSNotEnabled
+	}
+
+	for _, pin = range pins {
+		if err = ValidatePin(pin); err != nil {
+			return err
+		}
+
+		known[pin] = struct{}{}
+	}
+
+	client.pinning.mu.Lock()
+	defer client.pinning.mu.Unlock()
+
+	if len(known) == 0 {
+		if client.pinning.enabled {
+			transport.TLSClientConfig.InsecureSkipVerify = client.pinning.skipVerify
+			transport.TLSClientConfig.VerifyConnection = nil
+			client.pinning.enabled = false
+		}
+
+		return nil
+	}
+
+	if !client.pinning.enabled {
+		client.pinning.skipVerify = transport.TLSClientConfig.InsecureSkipVerify
+		client.pinning.enabled = true
+	}
+
+	// The chain is not verified by the standard library but instead the leaf certificate's public key is compared.
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	transport.TLSClientConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return ErrMissingPeerCert
+		}
+
+		if _, ok := known[SPKIHash(state.PeerCertificates[0])]; !ok {
+			client.logger().Error("server certificate public key is not pinned", "spki",
+				SPKIHash(state.PeerCertificates[0]))
+			return ErrPinMismatch
+		}
+
+		return nil
+	}
+
+	return nil
+}
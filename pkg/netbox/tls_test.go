@@ -0,0 +1,190 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinnedPublicKeys(t *testing.T) {
+	var (
+		server *httptest.Server
+		client *Client
+		pin    string
+		err    error
+	)
+
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, `{"netbox-version": "4.1.0"}`)
+	}))
+	defer server.Close()
+
+	pin = SPKIHash(server.Certificate())
+
+	client, err = New(server.URL, "0123456789abcdef0123456789abcdef01234567", WithTLS(&TLSConfig{}))
+	require.NoError(t, err)
+
+	// self-signed certificate is rejected by default
+	assert.Error(t, client.VerifyConnectivity(context.Background()))
+
+	// invalid pins
+	assert.ErrorIs(t, client.SetPinnedPublicKeys([]string{"foo"}), ErrInvalidPin)
+	assert.ErrorIs(t, client.SetPinnedPublicKeys([]string{"Zm9v"}), ErrInvalidPin)
+
+	// matching pin
+	require.NoError(t, client.SetPinnedPublicKeys([]string{pin}))
+	assert.NoError(t, client.VerifyConnectivity(context.Background()))
+
+	// new connections must fail for a different pin
+	server.CloseClientConnections()
+	require.NoError(t, client.SetPinnedPublicKeys([]string{"47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="}))
+	assert.ErrorIs(t, client.VerifyConnectivity(context.Background()), ErrPinMismatch)
+
+	// removing pinning restores certificate chain validation, thus the self-signed certificate is rejected again
+	require.NoError(t, client.SetPinnedPublicKeys([]string{pin}))
+	require.NoError(t, client.VerifyConnectivity(context.Background()))
+	server.CloseClientConnections()
+	require.NoError(t, client.SetPinnedPublicKeys(nil))
+	assert.Error(t, client.VerifyConnectivity(context.Background()))
+
+	// unless verification has been disabled before pinning
+	client, err = New(server.URL, "0123456789abcdef0123456789abcdef01234567",
+		WithTLS(&TLSConfig{InsecureSkipVerify: true}))
+	require.NoError(t, err)
+	require.NoError(t, client.SetPinnedPublicKeys([]string{pin}))
+	require.NoError(t, client.SetPinnedPublicKeys([]string{}))
+	assert.NoError(t, client.VerifyConnectivity(context.Background()))
+
+	// http only client doesn't support pinning
+	client, err = New(server.URL, "0123456789abcdef0123456789abcdef01234567")
+	require.NoError(t, err)
+	assert.ErrorIs(t, client.SetPinnedPublicKeys([]string{pin}), ErrTLSNotEnabled)
+}
+
+func TestNewTLS(t *testing.T) {
+	var (
+		server *httptest.Server
+		client *Client
+		dir    string = t.TempDir()
+		caFile string = filepath.Join(dir, "ca.pem")
+		err    error
+	)
+
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, `{"netbox-version": "4.1.0"}`)
+	}))
+	defer server.Close()
+
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	}), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.pem"), []byte("foo"), 0600))
+
+	// server certificate is trusted through the CA file
+	client, err = New(server.URL, "0123456789abcdef0123456789abcdef01234567", WithTLS(&TLSConfig{
+		CAFile:     caFile,
+		MinVersion: tls.VersionTLS13,
+	}))
+	require.NoError(t, err)
+	assert.NoError(t, client.VerifyConnectivity(context.Background()))
+
+	// the test certificate is valid for example.com but not for other names
+	client, err = New(server.URL, "0123456789abcdef0123456789abcdef01234567", WithTLS(&TLSConfig{
+		CAFile:     caFile,
+		ServerName: "example.com",
+	}))
+	require.NoError(t, err)
+	assert.NoError(t, client.VerifyConnectivity(context.Background()))
+
+	client, err = New(server.URL, "0123456789abcdef0123456789abcdef01234567", WithTLS(&TLSConfig{
+		CAFile:     caFile,
+		ServerName: "netbox.example.org",
+	}))
+	require.NoError(t, err)
+	assert.Error(t, client.VerifyConnectivity(context.Background()))
+
+	// bad files
+	_, err = New(server.URL, "0123456789abcdef0123456789abcdef01234567", WithTLS(&TLSConfig{
+		CAFile: filepath.Join(dir, "missing.pem"),
+	}))
+	assert.ErrorIs(t, err, ErrBadCAFile)
+
+	_, err = New(server.URL, "0123456789abcdef0123456789abcdef01234567", WithTLS(&TLSConfig{
+		CAFile: filepath.Join(dir, "empty.pem"),
+	}))
+	assert.ErrorIs(t, err, ErrBadCAFile)
+
+	_, err = New(server.URL, "0123456789abcdef0123456789abcdef01234567", WithTLS(&TLSConfig{
+		CertFile: caFile,
+		KeyFile:  filepath.Join(dir, "missing.key"),
+	}))
+	assert.ErrorIs(t, err, ErrBadClientCert)
+}
+
+func TestNewTLSInMemory(t *testing.T) {
+	var (
+		server *httptest.Server
+		client *Client
+		pool   *x509.CertPool = x509.NewCertPool()
+		err    error
+	)
+
+	// the server requires a client certificate
+	server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, `{"netbox-version": "4.1.0"}`)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	pool.AddCert(server.Certificate())
+
+	client, err = New(server.URL, "0123456789abcdef0123456789abcdef01234567", WithTLS(&TLSConfig{
+		RootCAs:      pool,
+		Certificates: server.TLS.Certificates,
+	}))
+	require.NoError(t, err)
+	assert.NoError(t, client.VerifyConnectivity(context.Background()))
+
+	// no client certificate
+	client, err = New(server.URL, "0123456789abcdef0123456789abcdef01234567", WithTLS(&TLSConfig{
+		RootCAs: pool,
+	}))
+	require.NoError(t, err)
+	assert.Error(t, client.VerifyConnectivity(context.Background()))
+
+	// system wide CAs don't trust the server
+	client, err = New(server.URL, "0123456789abcdef0123456789abcdef01234567", WithTLS(&TLSConfig{
+		Certificates: server.TLS.Certificates,
+	}))
+	require.NoError(t, err)
+	assert.Error(t, client.VerifyConnectivity(context.Background()))
+}
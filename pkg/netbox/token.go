@@ -0,0 +1,98 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains the sources of the API token sent with each request.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// TokenSource provides the API token sent with each request. Token is called for every request, thus implementations
+// must be safe for concurrent use and should cache the token instead of fetching it every time. A rotated token is
+// used with the next request without recreating the Client.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource always returning the same token. It is used for the token given to New.
+type StaticToken string
+
+// Token returns token.
+func (token StaticToken) Token(context.Context) (string, error) {
+	return string(token), nil
+}
+
+// FileTokenSource is a TokenSource reading the token from a file, e.g. one rendered by Vault Agent. The file is read
+// again whenever its modification time or size changes, so rotating the token only requires replacing the file.
+type FileTokenSource struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// NewFileTokenSource returns a FileTokenSource reading the token from the file at path. Leading and trailing whitespace
+// of the file's content is ignored.
+func NewFileTokenSource(path string) *FileTokenSource {
+	return &FileTokenSource{path: path}
+}
+
+// Token returns the token read from the file, reading it again when the file has changed since the last call.
+func (source *FileTokenSource) Token(context.Context) (string, error) {
+	var (
+		info os.FileInfo
+		data []byte
+		err  error
+	)
+
+	info, err = os.Stat(source.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	source.mu.Lock()
+	defer source.mu.Unlock()
+
+	if source.token != "" && info.ModTime().Equal(source.modTime) && info.Size() == source.size {
+		return source.token, nil
+	}
+
+	data, err = os.ReadFile(source.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return "", fmt.Errorf("%w: token file %s is empty", ErrMissingToken, source.path)
+	}
+
+	source.token = string(data)
+	source.modTime = info.ModTime()
+	source.size = info.Size()
+
+	return source.token, nil
+}
@@ -0,0 +1,100 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTokenSource(t *testing.T) {
+	var (
+		path   string = filepath.Join(t.TempDir(), "token")
+		source *FileTokenSource
+		token  string
+		err    error
+	)
+
+	source = NewFileTokenSource(path)
+
+	_, err = source.Token(context.Background())
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(path, []byte("\n"), 0600))
+	_, err = source.Token(context.Background())
+	assert.ErrorIs(t, err, ErrMissingToken)
+
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0600))
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "first", token)
+
+	// rotated tokens are read again
+	require.NoError(t, os.WriteFile(path, []byte("second\n"), 0600))
+	require.NoError(t, os.Chtimes(path, time.Time{}, time.Now().Add(time.Minute)))
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "second", token)
+
+	// the last token is kept while the file is unchanged
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "second", token)
+}
+
+func TestWithTokenSource(t *testing.T) {
+	var (
+		server *httptest.Server
+		client *Client
+		path   string = filepath.Join(t.TempDir(), "token")
+		tokens []string
+		err    error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		w.Write([]byte(`{"netbox-version": "4.1.0"}`))
+	}))
+	defer server.Close()
+
+	_, err = New(server.URL, "")
+	assert.ErrorIs(t, err, ErrMissingToken)
+
+	client, err = New(server.URL, "", WithTokenSource(NewFileTokenSource(path)))
+	require.NoError(t, err)
+
+	// requests fail without a token
+	assert.ErrorIs(t, client.VerifyConnectivity(context.Background()), os.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(path, []byte("first"), 0600))
+	require.NoError(t, client.VerifyConnectivity(context.Background()))
+
+	require.NoError(t, os.WriteFile(path, []byte("second"), 0600))
+	require.NoError(t, os.Chtimes(path, time.Time{}, time.Now().Add(time.Minute)))
+	require.NoError(t, client.VerifyConnectivity(context.Background()))
+
+	assert.Equal(t, []string{"Token first", "Token second"}, tokens)
+}
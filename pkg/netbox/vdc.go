@@ -0,0 +1,100 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import "context"
+
+const (
+	queryVDCAttributes string = "id name identifier status primary_ip4{" + queryIPAddressAttributes + "} primary_ip6{" + queryIPAddressAttributes + "} custom_fields tenant{name} tags{name slug} device{" + queryDeviceAttributes + "}"
+	queryVDCList       string = "query($filters: VirtualDeviceContextFilter, $pagination: OffsetPaginationInput){virtual_device_context_list(filters: $filters, pagination: $pagination){" + queryVDCAttributes + "}}"
+)
+
+// VDC describes a subset of details of a Netbox virtual device context.
+type VDC struct {
+	ID           uint64  `json:"-"`
+	IDString     string  `json:"id"`
+	Name         string  `json:"name"`
+	Identifier   *int    `json:"identifier"`
+	Status       string  `json:"status"`
+	PrimaryIP4   *IP     `json:"primary_ip4"`
+	PrimaryIP6   *IP     `json:"primary_ip6"`
+	CustomFields CFMap   `json:"custom_fields"`
+	Tenant       Name    `json:"tenant"`
+	Tags         []Tag   `json:"tags"`
+	Parent       *Device `json:"device"`
+}
+
+// AsDevice returns the VDC as Device. Name, status and primary IPs are taken from the VDC while all other attributes are
+// inherited from the parent device. The tenant and custom fields of the VDC take precedence over the parent's.
+func (vdc *VDC) AsDevice() *Device {
+	var (
+		dev  Device
+		name string
+		cf   *CustomField
+	)
+
+	if vdc.Parent != nil {
+		dev = *vdc.Parent
+	}
+
+	dev.ID = vdc.ID
+	dev.IDString = vdc.IDString
+	dev.Name = vdc.Name
+	dev.Status = vdc.Status
+	dev.PrimaryIP4 = vdc.PrimaryIP4
+	dev.PrimaryIP6 = vdc.PrimaryIP6
+	dev.Tags = vdc.Tags
+
+	if vdc.Tenant.Name != "" {
+		dev.Tenant = vdc.Tenant
+	}
+
+	dev.CustomFields = CFMap{entries: make(map[string]*CustomField)}
+
+	if vdc.Parent != nil {
+		for name, cf = range vdc.Parent.CustomFields.entries {
+			dev.CustomFields.entries[name] = cf
+		}
+	}
+
+	for name, cf = range vdc.CustomFields.entries {
+		dev.CustomFields.entries[name] = cf
+	}
+
+	return &dev
+}
+
+// GetVDCsByTag returns a list of all virtual device contexts with a given tag.
+func (client *Client) GetVDCsByTag(ctx context.Context, tag string) ([]*VDC, error) {
+	var (
+		vdcs []*VDC
+		err  error
+	)
+
+	ctx = withQueryType(ctx, "vdcs_by_tag")
+
+	err = client.graphQLList(ctx, queryVDCList, map[string]any{"tag": tag}, func(wrapper *graphQLResponseWrapper) int {
+		vdcs = append(vdcs, wrapper.Data.VDCList...)
+		return len(wrapper.Data.VDCList)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return vdcs, nil
+}
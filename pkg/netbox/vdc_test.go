@@ -0,0 +1,70 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVDCAsDevice(t *testing.T) {
+	var (
+		vdc *VDC = &VDC{
+			ID:         3,
+			IDString:   "3",
+			Name:       "vdc-A",
+			Status:     StatusVDCActive,
+			PrimaryIP4: ip3,
+			CustomFields: CFMap{
+				entries: map[string]*CustomField{
+					"custom_field_A": {Datatype: CustomFieldText, Value: "vdc"},
+				},
+			},
+			Parent: &Device{
+				ID:     1,
+				Name:   "dev-A",
+				Status: StatusDeviceOffline,
+				Site:   Name{Name: "site-A"},
+				Tenant: Name{Name: "tenant-A"},
+				CustomFields: CFMap{
+					entries: map[string]*CustomField{
+						"custom_field_A": {Datatype: CustomFieldText, Value: "device"},
+						"custom_field_B": {Datatype: CustomFieldText, Value: "device"},
+					},
+				},
+			},
+		}
+		dev *Device
+	)
+
+	dev = vdc.AsDevice()
+	assert.Equal(t, uint64(3), dev.ID)
+	assert.Equal(t, "vdc-A", dev.Name)
+	assert.Equal(t, StatusVDCActive, dev.Status)
+	assert.Equal(t, ip3, dev.PrimaryIP4)
+	assert.Nil(t, dev.PrimaryIP6)
+	assert.Equal(t, "site-A", dev.Site.Name)
+	assert.Equal(t, "tenant-A", dev.Tenant.Name)
+	assert.Equal(t, "vdc", dev.CustomFields.entries["custom_field_A"].Value)
+	assert.Equal(t, "device", dev.CustomFields.entries["custom_field_B"].Value)
+
+	// parent must not be modified
+	assert.Equal(t, "dev-A", vdc.Parent.Name)
+	assert.Equal(t, "device", vdc.Parent.CustomFields.entries["custom_field_A"].Value)
+}
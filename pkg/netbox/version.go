@@ -0,0 +1,139 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+// This file contains the detection of Netbox's version and the API features depending on it.
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/Masterminds/semver"
+)
+
+// minimumNetboxVersion is the oldest supported version of Netbox.
+//
+// because:
+// >=4.0.10 - https://github.com/netbox-community/netbox/issues/16946
+var minimumNetboxVersion *semver.Version = semver.MustParse("4.0.10")
+
+// paginationDefinition matches the $pagination variables of GraphQL documents along with the arguments using them.
+var paginationDefinition *regexp.Regexp = regexp.MustCompile(`, (\$pagination\d*: OffsetPaginationInput|pagination: \$pagination\d*)`)
+
+// features describes the parts of Netbox's API differing between the supported versions. Each feature is enabled for
+// all versions since the one introducing it, as are all features when the version is unknown.
+type features struct {
+	// coreObjectChanges is true when the change log is served by the core app (Netbox 4.1+) instead of extras.
+	coreObjectChanges bool
+	// pagination is true when GraphQL lists take a pagination argument (Netbox 4.1+). Lists of older versions are
+	// always fetched with a single request.
+	pagination bool
+}
+
+// allFeatures are the features of the most recent version of Netbox, assumed when the version hasn't been detected.
+var allFeatures features = features{
+	coreObjectChanges: true,
+	pagination:        true,
+}
+
+// featuresOf returns the features of Netbox at version.
+func featuresOf(version *semver.Version) features {
+	return features{
+		coreObjectChanges: versionAtLeast(version, 4, 1, 0),
+		pagination:        versionAtLeast(version, 4, 1, 0),
+	}
+}
+
+// versionAtLeast returns true when version is major.minor.patch or newer. Pre-release and build suffixes (e.g.
+// 4.1.0-Docker-3.0.2) are ignored as Netbox images add them to released versions.
+func versionAtLeast(version *semver.Version, major, minor, patch int64) bool {
+	if version.Major() != major {
+		return version.Major() > major
+	}
+
+	if version.Minor() != minor {
+		return version.Minor() > minor
+	}
+
+	return version.Patch() >= patch
+}
+
+// parseNetboxVersion parses version as reported by Netbox's status endpoint and checks it's supported.
+func parseNetboxVersion(version string) (*semver.Version, error) {
+	var (
+		parsed *semver.Version
+		err    error
+	)
+
+	parsed, err = semver.NewVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not parse version '%s'", ErrIncompatibleVersion, version)
+	}
+
+	if !versionAtLeast(parsed, minimumNetboxVersion.Major(), minimumNetboxVersion.Minor(),
+		minimumNetboxVersion.Patch()) {
+		return nil, fmt.Errorf("%w: v%s (requires v%s or newer)", ErrIncompatibleVersion, version, minimumNetboxVersion)
+	}
+
+	return parsed, nil
+}
+
+// Version returns the version of Netbox detected by VerifyConnectivity or an empty string before.
+func (client *Client) Version() string {
+	var (
+		version *semver.Version = client.version.Load()
+	)
+
+	if version == nil {
+		return ""
+	}
+
+	return version.Original()
+}
+
+// supports returns the features of the Netbox version detected by VerifyConnectivity or allFeatures before.
+func (client *Client) supports() features {
+	var (
+		version *semver.Version = client.version.Load()
+	)
+
+	if version == nil {
+		return allFeatures
+	}
+
+	return featuresOf(version)
+}
+
+// listPageSize returns the page size of GraphQL lists, which is 0 when Netbox doesn't support pagination.
+func (client *Client) listPageSize() int {
+	if !client.supports().pagination {
+		return 0
+	}
+
+	return int(client.pageSize.Load())
+}
+
+// document returns the GraphQL document query adapted to the features of Netbox. Without pagination support, all
+// $pagination variables and the arguments using them are removed.
+func (client *Client) document(query string) string {
+	if !client.supports().pagination {
+		return paginationDefinition.ReplaceAllString(query, "")
+	}
+
+	return query
+}
@@ -0,0 +1,132 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNetboxVersion(t *testing.T) {
+	var (
+		version *semver.Version
+		err     error
+	)
+
+	version, err = parseNetboxVersion("4.1.3")
+	require.NoError(t, err)
+	assert.Equal(t, features{coreObjectChanges: true, pagination: true}, featuresOf(version))
+
+	// suffixes of Docker images don't downgrade the version
+	version, err = parseNetboxVersion("4.1.0-Docker-3.0.2")
+	require.NoError(t, err)
+	assert.Equal(t, allFeatures, featuresOf(version))
+
+	version, err = parseNetboxVersion("4.0.10")
+	require.NoError(t, err)
+	assert.Equal(t, features{}, featuresOf(version))
+
+	_, err = parseNetboxVersion("4.0.9")
+	assert.ErrorIs(t, err, ErrIncompatibleVersion)
+
+	_, err = parseNetboxVersion("3.7.8")
+	assert.ErrorIs(t, err, ErrIncompatibleVersion)
+
+	_, err = parseNetboxVersion("unknown")
+	assert.ErrorIs(t, err, ErrIncompatibleVersion)
+}
+
+func TestVersionFeatures(t *testing.T) {
+	var (
+		server    *httptest.Server
+		client    *Client
+		version   string
+		paths     []string
+		documents []string
+		variables []map[string]any
+		err       error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request graphQLRequest
+
+		paths = append(paths, r.URL.Path)
+
+		switch r.URL.Path {
+		case "/api/status/":
+			w.Write([]byte(`{"netbox-version": "` + version + `"}`))
+
+		case "/graphql/":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			documents = append(documents, request.Query)
+			variables = append(variables, request.Variables)
+			w.Write([]byte(`{"data": {"device_list": []}}`))
+
+		default:
+			w.Write([]byte(`{"count": 1, "next": null, "results": [{"id": 7}]}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err = New(server.URL, "token")
+	require.NoError(t, err)
+	client.SetPageSize(100)
+
+	// all features are assumed until the version is known
+	assert.Equal(t, "", client.Version())
+	_, err = client.GetDevices(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, documents[0], "$pagination: OffsetPaginationInput")
+	assert.Contains(t, variables[0], "pagination")
+
+	version = "4.0.12"
+	require.NoError(t, client.VerifyConnectivity(context.Background()))
+	assert.Equal(t, "4.0.12", client.Version())
+
+	_, err = client.GetDevices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "query($filters: DeviceFilter){device_list(filters: $filters){"+queryDeviceAttributes+"}}", documents[1])
+	assert.NotContains(t, variables[1], "pagination")
+
+	_, err = client.GetDevicesBatch(context.Background(), []DeviceQuery{{Tag: "a"}, {Tag: "b", Virtual: true}})
+	require.NoError(t, err)
+	assert.NotContains(t, documents[2], "pagination")
+
+	paths = nil
+	_, err = client.GetLastChangeID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{restLegacyObjectChangesPath}, paths)
+
+	version = "4.2.1"
+	require.NoError(t, client.VerifyConnectivity(context.Background()))
+
+	paths = nil
+	_, err = client.GetLastChangeID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{restObjectChangesPath}, paths)
+
+	version = "3.7.8"
+	assert.ErrorIs(t, client.VerifyConnectivity(context.Background()), ErrIncompatibleVersion)
+}
@@ -0,0 +1,76 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+)
+
+const (
+	queryVLANAttributes     string = "id vid name"
+	queryVLANListAttributes string = queryVLANAttributes + " status group{name} site{name} tenant{name} tags{name slug}"
+	queryVLANList           string = "query($filters: VLANFilter, $pagination: OffsetPaginationInput){vlan_list(filters: $filters, pagination: $pagination){" + queryVLANListAttributes + "}}"
+)
+
+// VLAN describes a subset of details of a Netbox vlan. Status, Group, Site, Tenant and Tags are only set for vlans
+// returned by the GetVLANs* methods but not for the vlans of interfaces.
+type VLAN struct {
+	ID       uint64 `json:"-"`
+	IDString string `json:"id"`
+	VID      uint16 `json:"vid"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Group    Name   `json:"group"`
+	Site     Name   `json:"site"`
+	Tenant   Name   `json:"tenant"`
+	Tags     []Tag  `json:"tags"`
+}
+
+// GetVLANs returns a list of all vlans.
+func (client *Client) GetVLANs(ctx context.Context) ([]*VLAN, error) {
+	return client.getVLANList(withQueryType(ctx, "vlans"), nil)
+}
+
+// GetVLANsByVID returns a list of all vlans using the given VLAN ID. As the same VLAN ID can be used in different VLAN
+// groups or sites, more than one vlan might be returned.
+func (client *Client) GetVLANsByVID(ctx context.Context, vid uint16) ([]*VLAN, error) {
+	return client.getVLANList(withQueryType(ctx, "vlans_by_vid"), map[string]any{"vid": map[string]any{"exact": vid}})
+}
+
+// GetVLANsByName returns a list of all vlans with the given name.
+func (client *Client) GetVLANsByName(ctx context.Context, name string) ([]*VLAN, error) {
+	return client.getVLANList(withQueryType(ctx, "vlans_by_name"), map[string]any{"name": map[string]any{"exact": name}})
+}
+
+// getVLANList returns the list of vlans matching filters.
+func (client *Client) getVLANList(ctx context.Context, filters map[string]any) ([]*VLAN, error) {
+	var (
+		vlans []*VLAN
+		err   error
+	)
+
+	err = client.graphQLList(ctx, queryVLANList, filters, func(wrapper *graphQLResponseWrapper) int {
+		vlans = append(vlans, wrapper.Data.VLANList...)
+		return len(wrapper.Data.VLANList)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return vlans, nil
+}
@@ -18,15 +18,18 @@
 package netbox
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 )
 
 const (
-	queryVMAttributes string = "id name primary_ip4{" + queryIPAddressAttributes + "} primary_ip6{" + queryIPAddressAttributes + "} custom_fields site{name} tenant{name} platform{name} role{name} status tags{name}"
-	queryVM           string = "{virtual_machine(id:%d){" + queryVMAttributes + "}}"
-	queryVMs          string = "{virtual_machine_list{" + queryVMAttributes + "}}"
-	queryVMsByTag     string = "{virtual_machine_list(filters: {tag:\"%s\"}){" + queryVMAttributes + "}}"
+	queryVMAttributes        string = "id name primary_ip4{" + queryIPAddressAttributes + "} primary_ip6{" + queryIPAddressAttributes + "} custom_fields site{name} tenant{name} platform{name} role{name} status tags{name slug}"
+	queryVM                  string = "query($id: ID!){virtual_machine(id: $id){" + queryVMAttributes + "}}"
+	queryVMList              string = "query($filters: VirtualMachineFilter, $pagination: OffsetPaginationInput){virtual_machine_list(filters: $filters, pagination: $pagination){" + queryVMAttributes + "}}"
+	queryVMListConfigContext string = "query($filters: VirtualMachineFilter, $pagination: OffsetPaginationInput){virtual_machine_list(filters: $filters, pagination: $pagination){" + queryVMAttributes + " config_context" + "}}"
+	queryVMConfigContext     string = "query($id: ID!){virtual_machine(id: $id){config_context}}"
 )
 
 // IsVirtual returns true if the device represents a virtual machine.
@@ -37,15 +40,16 @@ func (d *Device) IsVirtual() bool {
 // GetVM returns information about a VM gathered from Netbox. When error is not nil, the request failed and error gives
 // further details what went wrong. VM might point to an invalid address at that point and must not be used whenever an
 // error has been returned. When no vm with the given ID has been found, Device as well as error are nil.
-func (client *Client) GetVM(id uint64) (*Device, error) {
+func (client *Client) GetVM(ctx context.Context, id uint64) (*Device, error) {
 	var (
-		query   string = fmt.Sprintf(queryVM, id)
 		resp    response
 		wrapper graphQLResponseWrapper
 		err     error
 	)
 
-	resp, err = client.graphQL(query)
+	ctx = withQueryType(ctx, "vm")
+
+	resp, err = client.graphQL(ctx, queryVM, map[string]any{"id": strconv.FormatUint(id, 10)})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query api: %w", err)
 	}
@@ -73,69 +77,78 @@ func (client *Client) GetVM(id uint64) (*Device, error) {
 }
 
 // GetVMs returns a list of all VMs.
-func (client *Client) GetVMs() ([]*Device, error) {
-	var (
-		err     error
-		resp    response
-		wrapper graphQLResponseWrapper
-	)
+func (client *Client) GetVMs(ctx context.Context) ([]*Device, error) {
+	return client.getVMList(withQueryType(ctx, "vms"), queryVMList, nil)
+}
 
-	resp, err = client.graphQL(queryVMs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query api: %w", err)
-	}
+// GetVMsByTag returns a list of all vms with a given tag.
+func (client *Client) GetVMsByTag(ctx context.Context, tag string) ([]*Device, error) {
+	return client.GetVMsByTagFiltered(withQueryType(ctx, "vms_by_tag"), tag, nil)
+}
 
-	if resp.StatusCode() != 200 {
-		return nil, ErrUnexpectedStatusCode
-	}
+// GetVMsByTagFiltered returns a list of all vms with a given tag that match filter. Filtering is done by Netbox.
+func (client *Client) GetVMsByTagFiltered(ctx context.Context, tag string, filter *DeviceFilter) ([]*Device, error) {
+	return client.getVMList(withQueryType(ctx, "vms_by_tag_filtered"), queryVMList, filter.apply(map[string]any{"tag": tag}))
+}
 
-	err = json.Unmarshal(resp.RawBody().Bytes(), &wrapper)
-	if err != nil {
-		client.promFailure.Inc()
-		return nil, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
-	}
+// GetVMsByCluster returns a list of all vms that are part of the cluster with the given name.
+func (client *Client) GetVMsByCluster(ctx context.Context, cluster string) ([]*Device, error) {
+	return client.getVMList(withQueryType(ctx, "vms_by_cluster"), queryVMList, map[string]any{"cluster": cluster})
+}
 
-	for i := range wrapper.Data.VMList {
-		wrapper.Data.VMList[i].isVirtual = true
+// GetVMsByClusterGroup returns a list of all vms that are part of any cluster in the cluster group with the given slug.
+func (client *Client) GetVMsByClusterGroup(ctx context.Context, group string) ([]*Device, error) {
+	return client.getVMList(withQueryType(ctx, "vms_by_cluster_group"), queryVMList, map[string]any{"cluster_group": group})
+}
 
-		// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
-		wrapper.Data.VMList[i].parseIDs()
-	}
+// GetVMsBySiteGroup returns a list of all vms located at any site within the site group with the given slug (including
+// nested site groups).
+func (client *Client) GetVMsBySiteGroup(ctx context.Context, group string) ([]*Device, error) {
+	return client.getVMList(withQueryType(ctx, "vms_by_site_group"), queryVMList, map[string]any{"site_group": group})
+}
 
-	return wrapper.Data.VMList, nil
+// GetVMsWithConfigContext returns a list of all vms including their rendered config context. See
+// GetDevicesWithConfigContext.
+func (client *Client) GetVMsWithConfigContext(ctx context.Context) ([]*Device, error) {
+	return client.getVMList(withQueryType(ctx, "vms_with_config_context"), queryVMListConfigContext, nil)
 }
 
-// GetVMsByTag returns a list of all vms with a given tag.
-func (client *Client) GetVMsByTag(tag string) ([]*Device, error) {
+// GetVMConfigContext returns the rendered config context of the vm identified by id. See GetDeviceConfigContext.
+func (client *Client) GetVMConfigContext(ctx context.Context, id uint64) (map[string]any, error) {
 	var (
-		query   string = fmt.Sprintf(queryVMsByTag, tag)
+		wrapper *graphQLResponseWrapper
 		err     error
-		resp    response
-		wrapper graphQLResponseWrapper
-		i       int
 	)
 
-	resp, err = client.graphQL(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query api: %w", err)
+	wrapper, err = client.getConfigContext(withQueryType(ctx, "vm_config_context"), queryVMConfigContext, id)
+	if err != nil || wrapper.Data.VM == nil {
+		return nil, err
 	}
 
-	if resp.StatusCode() != 200 {
-		return nil, ErrUnexpectedStatusCode
-	}
+	return wrapper.Data.VM.ConfigContext, nil
+}
 
-	err = json.Unmarshal(resp.RawBody().Bytes(), &wrapper)
-	if err != nil {
-		client.promFailure.Inc()
-		return nil, fmt.Errorf("failed to unmarshal json from response body buffer: %w", err)
-	}
+// getVMList returns the list of vms returned by query using filters.
+func (client *Client) getVMList(ctx context.Context, query string, filters map[string]any) ([]*Device, error) {
+	var (
+		vms []*Device
+		err error
+	)
+
+	err = client.graphQLList(ctx, query, filters, func(wrapper *graphQLResponseWrapper) int {
+		var i int
 
-	for i = range wrapper.Data.VMList {
-		wrapper.Data.VMList[i].isVirtual = true
+		for i = range wrapper.Data.VMList {
+			wrapper.Data.VMList[i].isVirtual = true
+		}
 
-		// TODO: remove once fixed in Netbox (https://github.com/netbox-community/netbox/issues/11472)
-		wrapper.Data.VMList[i].parseIDs()
+		vms = append(vms, wrapper.Data.VMList...)
+
+		return len(wrapper.Data.VMList)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return wrapper.Data.VMList, nil
+	return vms, nil
 }
@@ -18,6 +18,7 @@
 package netbox
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -60,9 +61,10 @@ var (
 			Name: "platform-A",
 		},
 		Status: StatusDeviceActive,
-		Tags: []Name{
+		Tags: []Tag{
 			{
 				Name: "node_exporter",
+				Slug: "node_exporter",
 			},
 		},
 		isVirtual: true,
@@ -99,9 +101,10 @@ var (
 			Name: "platform-B",
 		},
 		Status: StatusDeviceActive,
-		Tags: []Name{
+		Tags: []Tag{
 			{
 				Name: "node_exporter",
+				Slug: "node_exporter",
 			},
 		},
 		isVirtual: true,
@@ -122,7 +125,7 @@ var (
 			Name: "role-C",
 		},
 		Status:    StatusDeviceActive,
-		Tags:      []Name{},
+		Tags:      []Tag{},
 		isVirtual: true,
 	}
 )
@@ -135,13 +138,13 @@ func TestGetVM(t *testing.T) {
 	client := newTestClient(t)
 
 	// vm exists
-	vm, err := client.GetVM(1)
+	vm, err := client.GetVM(context.Background(), 1)
 	assert.NoError(t, err)
 	require.NotEmpty(t, vm)
 	assert.Equal(t, vmA, vm)
 
 	// vm is missing
-	vm, err = client.GetVM(99999)
+	vm, err = client.GetVM(context.Background(), 99999)
 	assert.NoError(t, err)
 	assert.Empty(t, vm)
 }
@@ -153,7 +156,7 @@ func TestGetVMs(t *testing.T) {
 
 	client := newTestClient(t)
 
-	vms, err := client.GetVMs()
+	vms, err := client.GetVMs(context.Background())
 	assert.NoError(t, err)
 	require.NotEmpty(t, vms)
 	assert.Equal(t, []*Device{vmA, vmB, vmC}, vms)
@@ -166,7 +169,7 @@ func TestGetVMsByTag(t *testing.T) {
 
 	client := newTestClient(t)
 
-	vms, err := client.GetVMsByTag("node_exporter")
+	vms, err := client.GetVMsByTag(context.Background(), "node_exporter")
 	assert.NoError(t, err)
 	require.Len(t, vms, 2)
 
@@ -174,7 +177,7 @@ func TestGetVMsByTag(t *testing.T) {
 	assert.Equal(t, []*Device{vmA, vmB}, vms)
 
 	// tag doesn't exist
-	vms, err = client.GetVMsByTag("doesn_t-exist")
+	vms, err = client.GetVMsByTag(context.Background(), "doesn_t-exist")
 	assert.NoError(t, err)
 	assert.Empty(t, vms)
 }
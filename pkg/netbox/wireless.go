@@ -0,0 +1,55 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package netbox
+
+import (
+	"context"
+)
+
+const (
+	queryWirelessLANAttributes string = "id ssid"
+	queryWirelessLANList       string = "query($filters: WirelessLANFilter, $pagination: OffsetPaginationInput){wireless_lan_list(filters: $filters, pagination: $pagination){" + queryWirelessLANAttributes + "}}"
+)
+
+// WirelessLAN describes a subset of details of a Netbox wireless LAN.
+type WirelessLAN struct {
+	ID       uint64 `json:"-"`
+	IDString string `json:"id"`
+	SSID     string `json:"ssid"`
+}
+
+// GetWirelessLANsBySSID returns a list of all wireless LANs with the given SSID. As the same SSID can be used by
+// different wireless LAN groups, more than one wireless LAN might be returned.
+func (client *Client) GetWirelessLANsBySSID(ctx context.Context, ssid string) ([]*WirelessLAN, error) {
+	var (
+		wlans []*WirelessLAN
+		err   error
+	)
+
+	ctx = withQueryType(ctx, "wireless_lans_by_ssid")
+
+	err = client.graphQLList(ctx, queryWirelessLANList, map[string]any{"ssid": map[string]any{"exact": ssid}}, func(wrapper *graphQLResponseWrapper) int {
+		wlans = append(wlans, wrapper.Data.WirelessLANList...)
+		return len(wrapper.Data.WirelessLANList)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return wlans, nil
+}
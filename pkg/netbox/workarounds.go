@@ -18,6 +18,7 @@
 package netbox
 
 import (
+	"context"
 	"strconv"
 )
 
@@ -71,17 +72,57 @@ func (w *graphQLResponseWrapper) parseIDs() {
 	for i := range w.Data.ServiceList {
 		w.Data.ServiceList[i].parseIDs()
 	}
+
+	for i := range w.Data.VLANList {
+		w.Data.VLANList[i].parseIDs()
+	}
+
+	for i := range w.Data.VDCList {
+		w.Data.VDCList[i].parseIDs()
+	}
+
+	for i := range w.Data.WirelessLANList {
+		w.Data.WirelessLANList[i].parseIDs()
+	}
+
+	for i := range w.Data.SiteList {
+		w.Data.SiteList[i].ID = parseNetboxID(w.Data.SiteList[i].IDString)
+	}
+
+	for i := range w.Data.TenantList {
+		w.Data.TenantList[i].ID = parseNetboxID(w.Data.TenantList[i].IDString)
+	}
+
+	for i := range w.Data.RackList {
+		w.Data.RackList[i].ID = parseNetboxID(w.Data.RackList[i].IDString)
+	}
+
+	for i := range w.Data.ClusterList {
+		w.Data.ClusterList[i].ID = parseNetboxID(w.Data.ClusterList[i].IDString)
+	}
+
+	for i := range w.Data.RegionList {
+		w.Data.RegionList[i].ID = parseNetboxID(w.Data.RegionList[i].IDString)
+	}
+
+	for i := range w.Data.LocationList {
+		w.Data.LocationList[i].ID = parseNetboxID(w.Data.LocationList[i].IDString)
+	}
+
+	for i := range w.Data.PrefixList {
+		w.Data.PrefixList[i].parseIDs()
+	}
 }
 
 func (d *Device) parseIDs() {
 	d.ID = parseNetboxID(d.IDString)
 
 	if d.PrimaryIP6 != nil {
-		d.PrimaryIP6.ID = parseNetboxID(d.PrimaryIP6.IDString)
+		d.PrimaryIP6.parseIDs()
 	}
 
 	if d.PrimaryIP4 != nil {
-		d.PrimaryIP4.ID = parseNetboxID(d.PrimaryIP4.IDString)
+		d.PrimaryIP4.parseIDs()
 	}
 }
 
@@ -91,6 +132,38 @@ func (i *Interface) parseIDs() {
 	if i.Device != nil {
 		i.Device.parseIDs()
 	}
+
+	if i.UntaggedVLAN != nil {
+		i.UntaggedVLAN.parseIDs()
+	}
+
+	for j := range i.TaggedVLANs {
+		i.TaggedVLANs[j].parseIDs()
+	}
+}
+
+func (vdc *VDC) parseIDs() {
+	vdc.ID = parseNetboxID(vdc.IDString)
+
+	if vdc.PrimaryIP6 != nil {
+		vdc.PrimaryIP6.parseIDs()
+	}
+
+	if vdc.PrimaryIP4 != nil {
+		vdc.PrimaryIP4.parseIDs()
+	}
+
+	if vdc.Parent != nil {
+		vdc.Parent.parseIDs()
+	}
+}
+
+func (v *VLAN) parseIDs() {
+	v.ID = parseNetboxID(v.IDString)
+}
+
+func (wlan *WirelessLAN) parseIDs() {
+	wlan.ID = parseNetboxID(wlan.IDString)
 }
 
 func (ip *IP) parseIDs() {
@@ -101,6 +174,14 @@ func (ip *IP) parseIDs() {
 	}
 }
 
+func (p *Prefix) parseIDs() {
+	p.ID = parseNetboxID(p.IDString)
+	if p.VRF != nil {
+		// vrf can be nil when the prefix is in `global`
+		p.VRF.ID = parseNetboxID(p.VRF.IDString)
+	}
+}
+
 func (s *Service) parseIDs() {
 	s.ID = parseNetboxID(s.IDString)
 
@@ -119,7 +200,7 @@ func (s *Service) parseIDs() {
 
 // getServiceByNameIssue17457 is a workaround until https://github.com/netbox-community/netbox/issues/17457 has been
 // released in a new version of Netbox. It adds additional filtering on top of getting all services.
-func (client *Client) getServiceByNameIssue17457(name string) ([]*Service, error) {
+func (client *Client) getServiceByNameIssue17457(ctx context.Context, name string) ([]*Service, error) {
 	var (
 		allServices      []*Service
 		err              error
@@ -127,7 +208,7 @@ func (client *Client) getServiceByNameIssue17457(name string) ([]*Service, error
 		matchingServices []*Service = make([]*Service, 0)
 	)
 
-	allServices, err = client.GetServices()
+	allServices, err = client.GetServices(ctx)
 	if err != nil {
 		return nil, err
 	}
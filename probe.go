@@ -0,0 +1,66 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// paramModuleLabel and paramAuthLabel are the labels passed to exporters as module and auth parameter.
+	paramModuleLabel model.LabelName = model.ParamLabelPrefix + "module"
+	paramAuthLabel   model.LabelName = model.ParamLabelPrefix + "auth"
+)
+
+// applyProbeMode rewrites all targets that haven't been skipped to be probed by the exporter of group's probe mode.
+func applyProbeMode(group *config.Group, targets []*discovery.Target) {
+	var (
+		target *discovery.Target
+		module string
+		auth   string
+	)
+
+	if group.ProbeMode == nil {
+		return
+	}
+
+	for _, target = range targets {
+		if target.Skipped() {
+			continue
+		}
+
+		target.Exporter = group.ProbeMode.Exporter
+		module = group.ProbeMode.ModuleFor(target.Labels)
+		auth = group.ProbeMode.AuthFor(target.Labels)
+
+		if target.Labels == nil && (module != "" || auth != "") {
+			target.Labels = make(model.LabelSet)
+		}
+
+		if module != "" {
+			target.Labels[paramModuleLabel] = model.LabelValue(module)
+		}
+
+		if auth != "" {
+			target.Labels[paramAuthLabel] = model.LabelValue(auth)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyProbeMode(t *testing.T) {
+	var (
+		group *config.Group = &config.Group{
+			ProbeMode: &config.ProbeMode{
+				Exporter:          "snmp:9116",
+				Module:            "if_mib",
+				ModuleCustomField: "snmp_module",
+				PlatformModules:   map[string]string{"Junos": "juniper"},
+				Auth:              "public_v2",
+				AuthCustomField:   "snmp_auth",
+			},
+		}
+		data = []struct {
+			name   string
+			labels model.LabelSet
+			module model.LabelValue
+			auth   model.LabelValue
+		}{
+			{
+				name:   "custom fields",
+				labels: model.LabelSet{"netbox_snmp_module": "arista", "netbox_snmp_auth": "private_v3", "netbox_platform": "Junos"},
+				module: "arista",
+				auth:   "private_v3",
+			},
+			{
+				name:   "platform",
+				labels: model.LabelSet{"netbox_snmp_module": "", "netbox_platform": "Junos"},
+				module: "juniper",
+				auth:   "public_v2",
+			},
+			{
+				name:   "fallback",
+				labels: model.LabelSet{"netbox_snmp_auth": "", "netbox_platform": "EOS"},
+				module: "if_mib",
+				auth:   "public_v2",
+			},
+			{
+				name:   "no labels",
+				module: "if_mib",
+				auth:   "public_v2",
+			},
+		}
+		targets []*discovery.Target
+		i       int
+	)
+
+	for i = range data {
+		targets = []*discovery.Target{
+			{Labels: data[i].labels.Clone(), SkipReason: discovery.StateActive},
+			// skipped targets are left alone
+			{Labels: data[i].labels.Clone(), SkipReason: discovery.StateSkippedBadStatus},
+		}
+
+		applyProbeMode(group, targets)
+
+		assert.Equal(t, "snmp:9116", targets[0].Exporter, data[i].name)
+		assert.Equal(t, data[i].module, targets[0].Labels[paramModuleLabel], data[i].name)
+		assert.Equal(t, data[i].auth, targets[0].Labels[paramAuthLabel], data[i].name)
+
+		assert.Equal(t, "", targets[1].Exporter, data[i].name)
+		assert.Equal(t, data[i].labels.Clone(), targets[1].Labels, data[i].name)
+	}
+
+	// without module and auth no params are set
+	group.ProbeMode.Module = ""
+	group.ProbeMode.Auth = ""
+	targets = []*discovery.Target{{SkipReason: discovery.StateActive}}
+
+	applyProbeMode(group, targets)
+	assert.Equal(t, "snmp:9116", targets[0].Exporter)
+	assert.Nil(t, targets[0].Labels)
+
+	// groups without probe mode are left alone
+	targets = []*discovery.Target{{SkipReason: discovery.StateActive}}
+
+	applyProbeMode(&config.Group{}, targets)
+	assert.Equal(t, "", targets[0].Exporter)
+}
@@ -0,0 +1,74 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+)
+
+// relabelTargets applies the relabel configs of group to the labels of all targets that haven't been skipped. Targets
+// dropped by relabeling are skipped.
+func relabelTargets(group *config.Group, targets []*discovery.Target) {
+	var (
+		target *discovery.Target
+		lbls   labels.Labels
+		keep   bool
+	)
+
+	if len(group.RelabelConfigs) == 0 {
+		return
+	}
+
+	for _, target = range targets {
+		if target.Skipped() {
+			continue
+		}
+
+		lbls, keep = relabel.Process(labels.FromMap(toMap(target.Labels)), group.RelabelConfigs...)
+		if !keep {
+			target.SkipReason = discovery.StateSkippedDroppedByRelabel
+			continue
+		}
+
+		target.Labels = make(model.LabelSet, lbls.Len())
+
+		lbls.Range(func(label labels.Label) {
+			target.Labels[model.LabelName(label.Name)] = model.LabelValue(label.Value)
+		})
+	}
+}
+
+// toMap converts labels into a plain map.
+func toMap(labels model.LabelSet) map[string]string {
+	var (
+		result map[string]string = make(map[string]string, len(labels))
+		name   model.LabelName
+		value  model.LabelValue
+	)
+
+	for name, value = range labels {
+		result[string(name)] = string(value)
+	}
+
+	return result
+}
@@ -0,0 +1,155 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRelabelTargets(t *testing.T) {
+	var (
+		input = model.LabelSet{
+			"netbox_name":          "device-A",
+			"netbox_site":          "site-A",
+			"netbox_serial_number": "abcd",
+		}
+		data = []struct {
+			name   string
+			config string
+			// expected is nil when the target is dropped
+			expected model.LabelSet
+		}{
+			{
+				name:   "replace",
+				config: "{source_labels: [netbox_site], regex: 'site-(.*)', target_label: dc, replacement: dc-$1}",
+				expected: model.LabelSet{
+					"netbox_name":          "device-A",
+					"netbox_site":          "site-A",
+					"netbox_serial_number": "abcd",
+					"dc":                   "dc-A",
+				},
+			},
+			{
+				name:     "replace regex mismatch",
+				config:   "{source_labels: [netbox_site], regex: 'dc-(.*)', target_label: dc, replacement: $1}",
+				expected: input,
+			},
+			{
+				name:     "replace empty source label",
+				config:   "{source_labels: [netbox_rack], target_label: rack}",
+				expected: input,
+			},
+			{
+				name:     "keep",
+				config:   "{action: keep, source_labels: [netbox_site], regex: site-A}",
+				expected: input,
+			},
+			{
+				name:   "keep regex mismatch",
+				config: "{action: keep, source_labels: [netbox_site], regex: site-B}",
+			},
+			{
+				name:   "keep empty source label",
+				config: "{action: keep, source_labels: [netbox_rack], regex: '.+'}",
+			},
+			{
+				name:   "drop",
+				config: "{action: drop, source_labels: [netbox_site], regex: site-A}",
+			},
+			{
+				name:     "drop regex mismatch",
+				config:   "{action: drop, source_labels: [netbox_site], regex: site-B}",
+				expected: input,
+			},
+			{
+				name:   "drop empty source label",
+				config: "{action: drop, source_labels: [netbox_rack], regex: ''}",
+			},
+			{
+				name:   "labelmap",
+				config: "{action: labelmap, regex: 'netbox_(name|site)', replacement: nb_$1}",
+				expected: model.LabelSet{
+					"netbox_name":          "device-A",
+					"netbox_site":          "site-A",
+					"netbox_serial_number": "abcd",
+					"nb_name":              "device-A",
+					"nb_site":              "site-A",
+				},
+			},
+			{
+				name:     "labelmap regex mismatch",
+				config:   "{action: labelmap, regex: 'custom_(.*)', replacement: $1}",
+				expected: input,
+			},
+			{
+				name:   "labeldrop",
+				config: "{action: labeldrop, regex: 'netbox_serial_.*'}",
+				expected: model.LabelSet{
+					"netbox_name": "device-A",
+					"netbox_site": "site-A",
+				},
+			},
+			{
+				name:     "labeldrop regex mismatch",
+				config:   "{action: labeldrop, regex: 'custom_.*'}",
+				expected: input,
+			},
+		}
+		group   *config.Group
+		targets []*discovery.Target
+		i       int
+	)
+
+	for i = range data {
+		group = &config.Group{RelabelConfigs: []*relabel.Config{{}}}
+		require.Nil(t, yaml.Unmarshal([]byte(data[i].config), group.RelabelConfigs[0]), data[i].name)
+		require.Nil(t, group.RelabelConfigs[0].Validate(), data[i].name)
+
+		targets = []*discovery.Target{
+			{Labels: input.Clone(), SkipReason: discovery.StateActive},
+			// skipped targets are left alone
+			{Labels: input.Clone(), SkipReason: discovery.StateSkippedBadStatus},
+		}
+
+		relabelTargets(group, targets)
+
+		if data[i].expected == nil {
+			assert.Equal(t, discovery.StateSkippedDroppedByRelabel, targets[0].SkipReason, data[i].name)
+		} else {
+			assert.Equal(t, discovery.StateActive, targets[0].SkipReason, data[i].name)
+			assert.Equal(t, data[i].expected, targets[0].Labels, data[i].name)
+		}
+
+		assert.Equal(t, discovery.StateSkippedBadStatus, targets[1].SkipReason, data[i].name)
+		assert.Equal(t, input, targets[1].Labels, data[i].name)
+	}
+
+	// without relabel configs, targets are left alone
+	targets = []*discovery.Target{{Labels: input.Clone(), SkipReason: discovery.StateActive}}
+	relabelTargets(&config.Group{}, targets)
+	assert.Equal(t, input, targets[0].Labels)
+}
@@ -0,0 +1,278 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	ErrReloadRequiresRestart = errors.New("changed options require a restart")
+
+	// restartOptions are global options that can't be applied at runtime as they are used to create the Netbox API
+	// client or the sinks opened at startup (e.g. etcd or the target database).
+	restartOptions []string = []string{"base_url", "api_token", "api_token_file", "api", "allow_insecure", "tls",
+		"tls_pinned_public_keys", "netbox_instances", "client_options", "consul", "etcd", "kubernetes_configmap",
+		"target_db_file"}
+)
+
+// groupWorker tracks a running worker of a group.
+type groupWorker struct {
+	group *config.Group
+	// api is the client of a group overriding the connection of its instance, nil otherwise.
+	api netbox.ClientIface
+	// cancel asks the worker to exit, done is closed by the worker once it exited.
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startWorker starts a new worker for group delaying its first scan by startDelay. Global options are taken from cfg,
+// thus all workers must be restarted when global options change. api is the client returned by groupClient for group,
+// its metrics are registered. The caller must hold sd.mu.
+func (sd *netboxSD) startWorker(cfg *config.Config, group *config.Group, api netbox.ClientIface,
+	startDelay time.Duration) {
+	var (
+		worker *groupWorker = &groupWorker{
+			group: group,
+			api:   api,
+			done:  make(chan struct{}),
+		}
+		ctx context.Context
+		err error
+	)
+
+	if api != nil {
+		// the client of a previous worker of the group has been unregistered when it was stopped
+		err = groupClientRegisterer(group).Register(api)
+		if err != nil {
+			slog.Error("failed to register metrics of group client", "group", group.File, "error", err)
+		}
+	}
+
+	if sd.workers == nil {
+		sd.workers = make(map[string]*groupWorker)
+	}
+
+	slog.Info("starting worker", "group", group.File, "priority", group.Priority)
+
+	ctx, worker.cancel = context.WithCancel(context.Background())
+	sd.workers[group.File] = worker
+
+	go func() {
+		defer close(worker.done)
+		sd.worker(ctx, cfg, group, startDelay)
+	}()
+}
+
+// stopWorkers stops all workers and waits until they exited. A scan in progress is cancelled, including any request
+// towards Netbox still in flight. The caller must not hold sd.mu.
+func stopWorkers(workers []*groupWorker) {
+	var worker *groupWorker
+
+	for _, worker = range workers {
+		slog.Info("stopping worker", "group", worker.group.File)
+		worker.cancel()
+	}
+
+	for _, worker = range workers {
+		<-worker.done
+
+		if worker.api != nil {
+			groupClientRegisterer(worker.group).Unregister(worker.api)
+		}
+	}
+}
+
+// reload reads the config file again and applies all changes by stopping workers of removed or modified groups and
+// starting workers for added or modified groups. Unchanged groups continue without interruption. When the new config
+// is invalid, changes options that require a restart or the client of a group can't be created, the running config is
+// kept and an error is returned. reload must not be called concurrently.
+func (sd *netboxSD) reload(file string) error {
+	var (
+		err      error
+		cfg      *config.Config
+		old      *config.Config
+		diff     *config.Diff
+		option   string
+		name     string
+		group    *config.Group
+		worker   *groupWorker
+		stopped  []*groupWorker
+		api      netbox.ClientIface
+		apis     map[string]netbox.ClientIface = make(map[string]netbox.ClientIface)
+		ok       bool
+		modified bool
+		i        int
+	)
+
+	cfg, err = config.ReadConfigFile(file)
+	if err != nil {
+		promConfigReloadSuccess.Set(0)
+		return err
+	}
+
+	sd.mu.Lock()
+
+	diff = config.NewDiff(sd.cfg, cfg)
+
+	for _, option = range diff.Global {
+		if slices.Contains(restartOptions, option) {
+			sd.mu.Unlock()
+			promConfigReloadSuccess.Set(0)
+			return fmt.Errorf("%w: %s", ErrReloadRequiresRestart, option)
+		}
+	}
+
+	// Clients of groups to be (re)started are created before any worker is stopped, thus a group whose client can't be
+	// created keeps being discovered using the running config.
+	for _, group = range cfg.Groups {
+		_, ok = sd.workers[group.File]
+		_, modified = diff.GroupsModified[group.File]
+
+		if ok && !modified && len(diff.Global) == 0 {
+			continue
+		}
+
+		apis[group.File], err = groupClient(cfg, group)
+		if err != nil {
+			sd.mu.Unlock()
+			promConfigReloadSuccess.Set(0)
+			return err
+		}
+	}
+
+	reportConfigDiff(diff)
+	old = sd.cfg
+
+	// Global options (e.g. the default scan_interval) might affect all groups, thus all workers are restarted.
+	for name, worker = range sd.workers {
+		_, ok = diff.GroupsModified[name]
+
+		if ok || len(diff.Global) > 0 || slices.Contains(diff.GroupsRemoved, name) {
+			stopped = append(stopped, worker)
+			delete(sd.workers, name)
+		}
+	}
+
+	sd.mu.Unlock()
+
+	// Workers can't be waited for while holding the lock as they might need it to finish their current scan.
+	stopWorkers(stopped)
+
+	for _, name = range diff.GroupsRemoved {
+		deleteGroupMetrics(name)
+		sd.combined.remove(name)
+		sd.zone.remove(name)
+		sd.icinga.remove(name)
+
+		if sd.stream != nil {
+			sd.stream.remove(name)
+		}
+
+		if sd.etcd != nil && !*dryRun {
+			err = sd.etcd.delete(name)
+			if err != nil {
+				slog.Error("failed to delete targets from etcd", "group", name, "error", err)
+			}
+		}
+
+		if sd.configMap != nil && !*dryRun {
+			err = sd.configMap.delete(name)
+			if err != nil {
+				slog.Error("failed to delete targets from configmap", "group", name, "error", err)
+			}
+		}
+
+		if sd.targetDB != nil && !*dryRun {
+			err = sd.targetDB.remove(name)
+			if err != nil {
+				slog.Error("failed to delete targets from target database", "group", name, "error", err)
+			}
+		}
+	}
+
+	if !*dryRun {
+		err = cleanupFiles(cfg, true)
+		if err != nil {
+			slog.Error("failed to clean up target files", "error", err)
+		}
+
+		sd.cleanupConsul(old, cfg)
+
+		if cfg.ManifestFile != "" {
+			for _, name = range diff.GroupsRemoved {
+				err = sd.manifest.remove(cfg.ManifestFile, name)
+				if err != nil {
+					slog.Error("failed to write manifest file", "group", name, "file", cfg.ManifestFile, "error", err)
+				}
+			}
+		}
+	}
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	sd.cfg = cfg
+	for _, api = range sd.clients() {
+		api.UsePersistedQueries(cfg.PersistedQueries)
+		api.SetPageSize(*cfg.PageSize)
+		api.SetCacheTTL(cfg.CacheTTL)
+	}
+
+	for _, group = range cfg.GroupsByPriority() {
+		if _, ok = sd.workers[group.File]; ok {
+			continue
+		}
+
+		sd.startWorker(cfg, group, apis[group.File], time.Duration(i)*cfg.StartupStagger)
+		i++
+	}
+
+	promGroups.Set(float64(len(cfg.Groups)))
+	promConfigReloadSuccess.Set(1)
+	promConfigReloadTime.Set(float64(time.Now().Unix()))
+
+	return nil
+}
+
+// deleteGroupMetrics removes all metrics of the group writing file.
+func deleteGroupMetrics(file string) {
+	var labels prometheus.Labels = prometheus.Labels{"group": file}
+
+	promTargetCount.Delete(labels)
+	promUpdateTime.Delete(labels)
+	promUpdateError.Delete(labels)
+	promMaxTargetsExceeded.Delete(labels)
+	promFileUnchanged.Delete(labels)
+	promScanSkipped.Delete(labels)
+	promWebhookError.Delete(labels)
+	promUpdateDuration.Delete(labels)
+	promTargetState.DeletePartialMatch(labels)
+	promIPSkipped.DeletePartialMatch(labels)
+	promAddressesFiltered.Delete(labels)
+}
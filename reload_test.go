@@ -0,0 +1,149 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+	"github.com/4xoc/netbox_sd/pkg/netbox/netboxtest"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadTestConfig string = `base_url: %s
+api_token: 123
+scan_interval: 5m
+
+groups:
+`
+
+func TestReload(t *testing.T) {
+	var (
+		sd       netboxSD
+		fixtures *netboxtest.Fixtures
+		server   *netboxtest.Server
+		file     string = filepath.Join(t.TempDir(), "config.yml")
+		workerA  *groupWorker
+		data     []byte
+		err      error
+	)
+
+	*dryRun = true
+	defer func() { *dryRun = false }()
+
+	fixtures, err = netboxtest.LoadFixtures("testdata/fixtures/example/netbox.yml")
+	require.Nil(t, err)
+
+	server = netboxtest.NewServer(fixtures)
+	defer server.Close()
+
+	sd.api, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netbox_sd_test"))
+	require.Nil(t, err)
+
+	writeConfig := func(baseURL string, groups ...string) {
+		var (
+			data  string = strings.Replace(reloadTestConfig, "%s", baseURL, 1)
+			group string
+		)
+
+		for _, group = range groups {
+			data += "  - file: " + group + "\n    type: device_tag\n    match: junos_exporter\n"
+		}
+
+		require.Nil(t, os.WriteFile(file, []byte(data), 0600))
+	}
+
+	defer func() {
+		var workers []*groupWorker
+
+		sd.mu.Lock()
+		for _, worker := range sd.workers {
+			workers = append(workers, worker)
+		}
+		sd.mu.Unlock()
+
+		stopWorkers(workers)
+	}()
+
+	writeConfig("https://netbox.domain.tld", "a.yml", "b.yml")
+
+	sd.cfg, err = config.ReadConfigFile(file)
+	require.Nil(t, err)
+
+	sd.mu.Lock()
+	for _, group := range sd.cfg.Groups {
+		sd.startWorker(sd.cfg, group, nil, 0)
+	}
+	workerA = sd.workers["a.yml"]
+	sd.mu.Unlock()
+
+	// b.yml removed, c.yml added, a.yml continues
+	writeConfig("https://netbox.domain.tld", "a.yml", "c.yml")
+	require.Nil(t, sd.reload(file))
+
+	sd.mu.RLock()
+	assert.Len(t, sd.workers, 2)
+	assert.Same(t, workerA, sd.workers["a.yml"])
+	assert.Contains(t, sd.workers, "c.yml")
+	assert.Len(t, sd.cfg.Groups, 2)
+	sd.mu.RUnlock()
+
+	// changing the Netbox connection requires a restart
+	writeConfig("https://other.domain.tld", "a.yml")
+	assert.ErrorIs(t, sd.reload(file), ErrReloadRequiresRestart)
+
+	// invalid config
+	require.Nil(t, os.WriteFile(file, []byte("groups: ["), 0600))
+	assert.NotNil(t, sd.reload(file))
+
+	// running config is kept on failure
+	sd.mu.RLock()
+	assert.Len(t, sd.workers, 2)
+	assert.Equal(t, "https://netbox.domain.tld", sd.cfg.BaseURL)
+	sd.mu.RUnlock()
+
+	// the client of a group overriding its connection can't be created as the CA file of the running config vanished
+	sd.mu.Lock()
+	sd.cfg.TLS = &config.TLS{CAFile: filepath.Join(t.TempDir(), "missing.pem")}
+	sd.mu.Unlock()
+
+	writeConfig("https://netbox.domain.tld", "c.yml")
+	data, err = os.ReadFile(file)
+	require.Nil(t, err)
+	data = []byte(strings.Replace(string(data), "scan_interval: 5m\n", "scan_interval: 5m\ntls:\n  ca_file: "+
+		sd.cfg.TLS.CAFile+"\n", 1) + "  - file: a.yml\n    type: device_tag\n    match: junos_exporter\n" +
+		"    base_url: https://tenant.domain.tld\n    api_token: 456\n")
+	require.Nil(t, os.WriteFile(file, data, 0600))
+
+	assert.ErrorIs(t, sd.reload(file), netbox.ErrBadCAFile)
+	assert.Zero(t, testutil.ToFloat64(promConfigReloadSuccess))
+
+	// no worker has been stopped
+	sd.mu.RLock()
+	assert.Len(t, sd.workers, 2)
+	assert.Same(t, workerA, sd.workers["a.yml"])
+	assert.Len(t, sd.cfg.Groups, 2)
+	sd.mu.RUnlock()
+}
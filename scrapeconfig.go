@@ -0,0 +1,136 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateAction matches the actions of a file template, which are replaced by a glob in generated scrape configs.
+var templateAction *regexp.Regexp = regexp.MustCompile(`\{\{.*?\}\}`)
+
+// scrapeConfigFile is the part of a Prometheus config containing scrape configs.
+type scrapeConfigFile struct {
+	ScrapeConfigs []scrapeConfig `yaml:"scrape_configs"`
+}
+
+// scrapeConfig is a single Prometheus scrape config reading targets with file_sd.
+type scrapeConfig struct {
+	JobName        string         `yaml:"job_name"`
+	Scheme         string         `yaml:"scheme,omitempty"`
+	MetricsPath    string         `yaml:"metrics_path,omitempty"`
+	ScrapeInterval string         `yaml:"scrape_interval,omitempty"`
+	ScrapeTimeout  string         `yaml:"scrape_timeout,omitempty"`
+	FileSDConfigs  []fileSDConfig `yaml:"file_sd_configs"`
+}
+
+// fileSDConfig is a single file_sd config.
+type fileSDConfig struct {
+	Files []string `yaml:"files"`
+}
+
+// generateScrapeConfigs returns a Prometheus scrape config per group reading the group's target files with file_sd.
+// Graveyard files are not part of it.
+func generateScrapeConfigs(cfg *config.Config) ([]byte, error) {
+	var (
+		result  scrapeConfigFile
+		group   *config.Group
+		options config.ScrapeConfig
+		jobs    map[string]string = make(map[string]string)
+		buf     bytes.Buffer
+		encoder *yaml.Encoder
+		ok      bool
+		err     error
+	)
+
+	for _, group = range cfg.Groups {
+		options = config.ScrapeConfig{}
+		if group.ScrapeConfig != nil {
+			options = *group.ScrapeConfig
+		}
+
+		if options.JobName == "" {
+			options.JobName = strings.TrimSuffix(filepath.Base(group.File), filepath.Ext(group.File))
+		}
+
+		if options.MetricsPath == "" && group.ProbeMode != nil {
+			options.MetricsPath = "/probe"
+		}
+
+		if _, ok = jobs[options.JobName]; ok {
+			return nil, fmt.Errorf("job_name %s of group %s is already used by group %s", options.JobName, group.File,
+				jobs[options.JobName])
+		}
+
+		jobs[options.JobName] = group.File
+
+		result.ScrapeConfigs = append(result.ScrapeConfigs, scrapeConfig{
+			JobName:        options.JobName,
+			Scheme:         options.Scheme,
+			MetricsPath:    options.MetricsPath,
+			ScrapeInterval: options.ScrapeInterval,
+			ScrapeTimeout:  options.ScrapeTimeout,
+			FileSDConfigs:  []fileSDConfig{{Files: scrapeFiles(cfg, group)}},
+		})
+	}
+
+	encoder = yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// scrapeFiles returns the files (or globs) containing the targets of group as seen by Prometheus. Actions of the file
+// template and the shard number of hashmod are replaced by globs.
+func scrapeFiles(cfg *config.Config, group *config.Group) []string {
+	var (
+		files []string = []string{group.File}
+		ext   string
+		i     int
+	)
+
+	if group.FileTemplate != "" {
+		files = append(files, templateAction.ReplaceAllString(group.FileTemplate, "*"))
+	}
+
+	for i = range files {
+		if group.Hashmod != nil {
+			ext = filepath.Ext(files[i])
+			files[i] = strings.TrimSuffix(files[i], ext) + "_*" + ext
+		}
+
+		if cfg.ScrapeConfigFilesDir != "" && !filepath.IsAbs(files[i]) {
+			files[i] = filepath.Join(cfg.ScrapeConfigFilesDir, files[i])
+		}
+	}
+
+	return files
+}
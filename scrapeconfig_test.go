@@ -0,0 +1,86 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateScrapeConfigs(t *testing.T) {
+	var (
+		cfg *config.Config = &config.Config{
+			ScrapeConfigFilesDir: "/etc/prometheus/netbox_sd",
+			Groups: []*config.Group{
+				{File: "junos.yml"},
+				{
+					File: "node.yml",
+					ScrapeConfig: &config.ScrapeConfig{
+						JobName:        "node_exporter",
+						Scheme:         "https",
+						ScrapeInterval: "1m",
+					},
+				},
+				{File: "/srv/sd/icmp.yml", ProbeMode: &config.ProbeMode{Exporter: "blackbox:9115"}},
+				{File: "site.yml", FileTemplate: "site_{{.netbox_site}}.yml", Hashmod: &config.Hashmod{Shards: 2}},
+			},
+		}
+		data   []byte
+		result scrapeConfigFile
+		err    error
+	)
+
+	data, err = generateScrapeConfigs(cfg)
+	require.Nil(t, err)
+	require.Nil(t, yaml.Unmarshal(data, &result))
+
+	assert.Equal(t, []scrapeConfig{
+		{
+			JobName:       "junos",
+			FileSDConfigs: []fileSDConfig{{Files: []string{"/etc/prometheus/netbox_sd/junos.yml"}}},
+		},
+		{
+			JobName:        "node_exporter",
+			Scheme:         "https",
+			ScrapeInterval: "1m",
+			FileSDConfigs:  []fileSDConfig{{Files: []string{"/etc/prometheus/netbox_sd/node.yml"}}},
+		},
+		{
+			JobName:       "icmp",
+			MetricsPath:   "/probe",
+			FileSDConfigs: []fileSDConfig{{Files: []string{"/srv/sd/icmp.yml"}}},
+		},
+		{
+			JobName: "site",
+			FileSDConfigs: []fileSDConfig{{Files: []string{
+				"/etc/prometheus/netbox_sd/site_*.yml",
+				"/etc/prometheus/netbox_sd/site_*_*.yml",
+			}}},
+		},
+	}, result.ScrapeConfigs)
+
+	// job names must be unique
+	cfg.Groups[1].ScrapeConfig.JobName = "junos"
+	_, err = generateScrapeConfigs(cfg)
+	assert.Error(t, err)
+}
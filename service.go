@@ -18,41 +18,43 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
 
 	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
 	"github.com/4xoc/netbox_sd/pkg/netbox"
 
 	"github.com/prometheus/common/model"
-	"github.com/prometheus/prometheus/discovery/targetgroup"
 )
 
 // GetTargetsByService returns a list of of target devices that match a given service name
-func (sd *netboxSD) getTargetsByService(group *config.Group) ([]*targetgroup.Group, error) {
+func (sd *netboxSD) getTargetsByService(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
 	var (
 		err         error
-		i, j        int
+		j           int
+		port        *int
 		dev         *netbox.Device
 		dynLabels   model.LabelSet
-		data        []*targetgroup.Group = make([]*targetgroup.Group, 0)
-		target      *targetgroup.Group
+		tmplLabels  model.LabelSet
+		data        []*discovery.Target = make([]*discovery.Target, 0)
+		target      *discovery.Target
 		selectedIPs []*netbox.IP
 		serv        *netbox.Service
 		servList    []*netbox.Service
 		cfLabels    model.LabelSet
+		scheme      string
 	)
 
-	servList, err = sd.api.GetServicesByName(group.Match)
+	servList, err = sd.apiFor(group).GetServicesByName(ctx, group.Match.Value)
 	if err != nil {
-		log.Printf("failed to get services")
+		slog.ErrorContext(ctx, "failed to get services", "group", group.File, "error", err)
 		return nil, err
 	}
 
 	for _, serv = range servList {
-		// reset
-		target = new(targetgroup.Group)
-
 		// check if VM should be included
 		if serv.VM != nil && !*group.Flags.IncludeVMs {
 			continue
@@ -65,39 +67,62 @@ func (sd *netboxSD) getTargetsByService(group *config.Group) ([]*targetgroup.Gro
 			dev = serv.Device
 		}
 
-		// check for active device
-		if dev.Status != netbox.StatusDeviceActive {
-			log.Printf("device %s is not marked as active...skipping device", dev.Name)
-			SetTargetStatusMetric(group.File, dev, TargetSkippedBadStatus)
+		// devices not matching the group's name_match are not part of the group
+		if !group.NameMatches(dev.Name) {
 			continue
 		}
 
+		// reset
+		dynLabels = nil
+		target = &discovery.Target{
+			Device: dev,
+			// services are identified by id as name and device are not unique
+			Source:    fmt.Sprintf("netbox_sd/service/%d", serv.ID),
+			ServiceID: serv.ID,
+		}
+		data = append(data, target)
+
+		// check for active device; devices in one of the graveyard statuses are processed too
+		if dev.Status != netbox.StatusDeviceActive {
+			if !group.InGraveyard(dev.Status) {
+				slog.DebugContext(ctx, "device is not marked as active, skipping device", "group", group.File, "device", dev.Name)
+				target.SkipReason = discovery.StateSkippedBadStatus
+				continue
+			}
+
+			target.Graveyard = true
+		}
+
 		target.Labels = model.LabelSet{
-			model.LabelName("netbox_service"):       model.LabelValue(serv.Name),
-			model.LabelName("netbox_name"):          model.LabelValue(dev.Name),
-			model.LabelName("netbox_rack"):          model.LabelValue(dev.Rack.Name),
-			model.LabelName("netbox_site"):          model.LabelValue(dev.Site.Name),
-			model.LabelName("netbox_tenant"):        model.LabelValue(dev.Tenant.Name),
-			model.LabelName("netbox_role"):          model.LabelValue(dev.Role.Name),
-			model.LabelName("netbox_platform"):      model.LabelValue(dev.Platform.Name),
-			model.LabelName("netbox_serial_number"): model.LabelValue(dev.SerialNumber),
-			model.LabelName("netbox_asset_tag"):     model.LabelValue(dev.AssetTag),
+			model.LabelName("netbox_service"):          model.LabelValue(serv.Name),
+			model.LabelName("netbox_service_id"):       model.LabelValue(strconv.FormatUint(serv.ID, 10)),
+			model.LabelName("netbox_service_protocol"): model.LabelValue(serv.Protocol),
+			model.LabelName("netbox_name"):             model.LabelValue(dev.Name),
+			model.LabelName("netbox_rack"):             model.LabelValue(dev.Rack.Name),
+			model.LabelName("netbox_site"):             model.LabelValue(dev.Site.Name),
+			model.LabelName("netbox_tenant"):           model.LabelValue(dev.Tenant.Name),
+			model.LabelName("netbox_role"):             model.LabelValue(dev.Role.Name),
+			model.LabelName("netbox_platform"):         model.LabelValue(dev.Platform.Name),
+			model.LabelName("netbox_serial_number"):    model.LabelValue(dev.SerialNumber),
+			model.LabelName("netbox_asset_tag"):        model.LabelValue(dev.AssetTag),
 		}
 
 		// custom fields
-		cfLabels, err = generateCustomFieldLabels(dev.CustomFields)
+		cfLabels, err = generateCustomFieldLabels(dev.CustomFields, group.CustomFieldAllowed)
 		if err != nil {
-			log.Printf("failed to parse custom fields for device %s...skipping device", dev.Name)
-			SetTargetStatusMetric(group.File, dev, TargetSkippedBadCustomField)
+			slog.WarnContext(ctx, "failed to parse custom fields, skipping device", "group", group.File, "device", dev.Name,
+				"error", err)
+			target.SkipReason = discovery.StateSkippedBadCustomField
 			continue
 		}
 
 		target.Labels = target.Labels.Merge(cfLabels)
 
-		cfLabels, err = generateCustomFieldLabels(serv.CustomFields)
+		cfLabels, err = generateCustomFieldLabels(serv.CustomFields, group.CustomFieldAllowed)
 		if err != nil {
-			log.Printf("failed to parse custom fields for service %s on device %s...skipping device", serv.Name, dev.Name)
-			SetTargetStatusMetric(group.File, dev, TargetSkippedBadCustomField)
+			slog.WarnContext(ctx, "failed to parse custom fields of service, skipping device", "group", group.File,
+				"device", dev.Name, "service", serv.Name, "error", err)
+			target.SkipReason = discovery.StateSkippedBadCustomField
 			continue
 		}
 
@@ -110,14 +135,36 @@ func (sd *netboxSD) getTargetsByService(group *config.Group) ([]*targetgroup.Gro
 		}
 
 		target.Labels = target.Labels.Merge(dynLabels)
-		target.Source = "netbox_sd"
+
+		tmplLabels, err = templateLabels(group, dev, target.Labels)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to execute label templates, skipping device", "group", group.File, "device", dev.Name,
+				"error", err)
+			target.SkipReason = discovery.StateSkippedOther
+			continue
+		}
 
 		// add additional labels
-		target.Labels = target.Labels.Merge(group.Labels)
+		target.Labels = target.Labels.Merge(group.Labels).Merge(tmplLabels)
 
-		if !group.FiltersMatch(target) {
-			log.Printf("device %s doesn't match applied filters...skipping device", dev.Name)
-			SetTargetStatusMetric(group.File, dev, TargetSkippedNotMatchingFilters)
+		if target.Graveyard {
+			target.Labels = target.Labels.Merge(graveyardLabels(group, dev))
+		}
+
+		scheme = group.SchemeFor(target.Labels)
+		if scheme != "" {
+			target.Labels[model.SchemeLabel] = model.LabelValue(scheme)
+		}
+
+		if group.ExcludedByTags(tagSlugs(dev.Tags)) {
+			slog.DebugContext(ctx, "device carries an excluded tag, skipping device", "group", group.File, "device", dev.Name)
+			target.SkipReason = discovery.StateSkippedNotMatchingFilters
+			continue
+		}
+
+		if !group.FiltersMatch(target.Labels) {
+			slog.DebugContext(ctx, "device doesn't match applied filters, skipping device", "group", group.File, "device", dev.Name)
+			target.SkipReason = discovery.StateSkippedNotMatchingFilters
 			continue
 		}
 
@@ -125,14 +172,24 @@ func (sd *netboxSD) getTargetsByService(group *config.Group) ([]*targetgroup.Gro
 
 		// When there are no selectedIPs this target cannot be used.
 		if len(selectedIPs) == 0 {
-			SetTargetStatusMetric(group.File, dev, TargetSkippedNoValidIP)
+			target.SkipReason = discovery.StateSkippedNoValidIP
+			continue
+		}
+
+		target.Addresses = filterAddr(selectedIPs, group)
+		target.AddressesFiltered = len(selectedIPs) - len(target.Addresses)
+
+		if len(target.Addresses) == 0 {
+			slog.InfoContext(ctx, "no address of device matches address filters, skipping device", "group", group.File,
+				"device", dev.Name)
+			target.SkipReason = discovery.StateSkippedNoMatchingAddress
 			continue
 		}
 
 		// overwrite port if given in group config
-		if group.Port != nil {
+		if port = group.PortFor(target.Labels); port != nil {
 			serv.Ports = make([]int, 1)
-			serv.Ports[0] = *group.Port
+			serv.Ports[0] = *port
 		}
 
 		// Unless AllAddresses is set to true, only the first port is used
@@ -143,25 +200,8 @@ func (sd *netboxSD) getTargetsByService(group *config.Group) ([]*targetgroup.Gro
 			serv.Ports[0] = j
 		}
 
-		SetTargetStatusMetric(group.File, dev, TargetActive)
-
-		for i = range selectedIPs {
-			for j = range serv.Ports {
-				// adding ports
-				if selectedIPs[i].Family() == 4 {
-					target.Targets = append(target.Targets, model.LabelSet{
-						model.AddressLabel: model.LabelValue(fmt.Sprintf("%s:%d", selectedIPs[i].ToAddr(), serv.Ports[j])),
-					})
-				} else {
-					target.Targets = append(target.Targets, model.LabelSet{
-						model.AddressLabel: model.LabelValue(fmt.Sprintf("[%s]:%d", selectedIPs[i].ToAddr(), serv.Ports[j])),
-					})
-				}
-			}
-		}
-
-		// add target to list
-		data = append(data, target)
+		target.Ports = serv.Ports
+		target.SkipReason = discovery.StateActive
 	}
 
 	return data, nil
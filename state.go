@@ -0,0 +1,177 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the state manifest used to clean up target files of groups no longer part of the config.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+)
+
+// fileState is the content of the state file.
+type fileState struct {
+	// Files are all target files managed by netbox_sd.
+	Files []string `json:"files"`
+}
+
+// managedFiles returns all target files written for cfg (including graveyard files).
+func managedFiles(cfg *config.Config) []string {
+	var (
+		files []string
+		group *config.Group
+	)
+
+	for _, group = range cfg.Groups {
+		files = append(files, group.File)
+
+		if group.Graveyard != nil {
+			files = append(files, group.Graveyard.File)
+		}
+	}
+
+	if cfg.CombinedFile != "" {
+		files = append(files, cfg.CombinedFile)
+	}
+
+	if cfg.DNSZone != nil {
+		files = append(files, cfg.DNSZone.File)
+	}
+
+	if cfg.Icinga != nil {
+		files = append(files, cfg.Icinga.File)
+	}
+
+	sort.Strings(files)
+
+	return files
+}
+
+// readState returns the content of the state file. A missing state file is returned as empty state.
+func readState(file string) (*fileState, error) {
+	var (
+		state fileState
+		data  []byte
+		err   error
+	)
+
+	data, err = os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &state, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", file, err)
+	}
+
+	return &state, nil
+}
+
+// cleanupFiles removes (or truncates) all target files recorded in the state file of cfg that are no longer part of
+// cfg when remove is true and records the files of cfg afterwards. Orphaned files that have not been removed are kept
+// in the state file. Nothing is done when cfg doesn't define a state file.
+func cleanupFiles(cfg *config.Config, remove bool) error {
+	var (
+		state   *fileState
+		managed []string = managedFiles(cfg)
+		files   []string
+		file    string
+		data    []byte
+		err     error
+	)
+
+	if cfg.StateFile == "" {
+		return nil
+	}
+
+	state, err = readState(cfg.StateFile)
+	if err != nil {
+		return err
+	}
+
+	files = slices.Clone(managed)
+
+	for _, file = range state.Files {
+		if slices.Contains(managed, file) {
+			continue
+		}
+
+		if !remove {
+			files = append(files, file)
+			continue
+		}
+
+		err = removeTargetFile(file, cfg.CleanupMode)
+		if err != nil {
+			slog.Error("failed to clean up orphaned target file", "file", file, "error", err)
+			files = append(files, file)
+			continue
+		}
+
+		slog.Info("cleaned up orphaned target file", "file", file, "cleanup_mode", cfg.CleanupMode)
+	}
+
+	sort.Strings(files)
+
+	data, err = json.MarshalIndent(fileState{Files: files}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(cfg.StateFile, append(data, '\n'), 0664)
+}
+
+// removeTargetFile deletes file or truncates it to an empty target list, depending on mode. Files that don't exist
+// anymore are ignored.
+func removeTargetFile(file, mode string) error {
+	var err error
+
+	if mode == config.CleanupModeTruncate {
+		if _, err = os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		// Prometheus determines the format by the file's extension; an empty file isn't valid JSON
+		if filepath.Ext(file) == ".json" {
+			return os.WriteFile(file, []byte("[]\n"), 0664)
+		}
+
+		return os.WriteFile(file, nil, 0664)
+	}
+
+	err = os.Remove(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,102 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanupFiles(t *testing.T) {
+	var (
+		dir   string = t.TempDir()
+		state *fileState
+		data  []byte
+		err   error
+		cfg   *config.Config = &config.Config{
+			StateFile:   filepath.Join(dir, "state.json"),
+			CleanupMode: config.CleanupModeDelete,
+			Groups: []*config.Group{
+				{File: filepath.Join(dir, "a.yml")},
+				{File: filepath.Join(dir, "b.json")},
+				{File: filepath.Join(dir, "c.yml"), Graveyard: &config.Graveyard{File: filepath.Join(dir, "c_graveyard.yml")}},
+			},
+		}
+	)
+
+	// a missing state file is created
+	require.Nil(t, cleanupFiles(cfg, true))
+	state, err = readState(cfg.StateFile)
+	require.Nil(t, err)
+	assert.Equal(t, managedFiles(cfg), state.Files)
+	assert.Len(t, state.Files, 4)
+
+	for _, file := range state.Files {
+		require.Nil(t, os.WriteFile(file, []byte("- targets: []\n"), 0600))
+	}
+
+	// orphaned files are kept in the state file unless removing them
+	cfg.Groups = cfg.Groups[:1]
+	require.Nil(t, cleanupFiles(cfg, false))
+	state, err = readState(cfg.StateFile)
+	require.Nil(t, err)
+	assert.Len(t, state.Files, 4)
+	assert.FileExists(t, filepath.Join(dir, "c.yml"))
+
+	// truncating
+	cfg.CleanupMode = config.CleanupModeTruncate
+	cfg.Groups = append(cfg.Groups, &config.Group{File: filepath.Join(dir, "c.yml")})
+	require.Nil(t, cleanupFiles(cfg, true))
+	state, err = readState(cfg.StateFile)
+	require.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.yml"), filepath.Join(dir, "c.yml")}, state.Files)
+
+	data, err = os.ReadFile(filepath.Join(dir, "b.json"))
+	require.Nil(t, err)
+	assert.Equal(t, "[]\n", string(data))
+
+	data, err = os.ReadFile(filepath.Join(dir, "c_graveyard.yml"))
+	require.Nil(t, err)
+	assert.Empty(t, data)
+
+	// the file of the group still configured is untouched
+	data, err = os.ReadFile(filepath.Join(dir, "c.yml"))
+	require.Nil(t, err)
+	assert.NotEmpty(t, data)
+
+	// deleting; missing files are ignored
+	cfg.CleanupMode = config.CleanupModeDelete
+	cfg.Groups = cfg.Groups[:0]
+	require.Nil(t, os.Remove(filepath.Join(dir, "a.yml")))
+	require.Nil(t, cleanupFiles(cfg, true))
+	assert.NoFileExists(t, filepath.Join(dir, "c.yml"))
+
+	state, err = readState(cfg.StateFile)
+	require.Nil(t, err)
+	assert.Empty(t, state.Files)
+
+	// broken state file
+	require.Nil(t, os.WriteFile(cfg.StateFile, []byte("{"), 0600))
+	assert.Error(t, cleanupFiles(cfg, true))
+}
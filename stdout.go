@@ -0,0 +1,77 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the stdout mode printing the targets of all groups once instead of writing target files.
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+)
+
+// dumpTargets scans all groups of cfg once (ordered by priority) and returns their targets in format. Like the combined
+// file, each target has a group label containing the file of its group and skipped or graveyard targets are omitted.
+// It fails when any group fails or ctx is done.
+func (sd *netboxSD) dumpTargets(ctx context.Context, cfg *config.Config, format string) ([]byte, error) {
+	var (
+		dump    combinedTargets
+		group   *config.Group
+		results []*discovery.Target
+		err     error
+	)
+
+	sd.mu.Lock()
+	sd.workers = make(map[string]*groupWorker)
+
+	// no workers are started, they only provide the clients of groups overriding their instance's connection
+	for _, group = range cfg.Groups {
+		sd.workers[group.File] = &groupWorker{group: group}
+
+		sd.workers[group.File].api, err = groupClient(cfg, group)
+		if err != nil {
+			sd.mu.Unlock()
+			return nil, err
+		}
+	}
+	sd.mu.Unlock()
+
+	for _, group = range cfg.GroupsByPriority() {
+		results, err = sd.discover(ctx, group)
+		if err != nil {
+			return nil, fmt.Errorf("getting targets for group %s failed: %w", group.File, err)
+		}
+
+		if exceedsMaxTargets(group, results) {
+			return nil, fmt.Errorf("group %s yielded %d targets exceeding max_targets of %d", group.File,
+				countActive(results), group.MaxTargets)
+		}
+
+		_, err = dump.update(group.File, results, format)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dump.mu.Lock()
+	defer dump.mu.Unlock()
+
+	return renderTargets(dump.list(), format, false)
+}
@@ -0,0 +1,78 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+	"github.com/4xoc/netbox_sd/pkg/netbox/netboxtest"
+
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpTargets(t *testing.T) {
+	var (
+		sd       netboxSD
+		fixtures *netboxtest.Fixtures
+		server   *netboxtest.Server
+		data     []byte
+		groups   []*targetgroup.Group
+		group    *targetgroup.Group
+		cfgGroup *config.Group
+		files    map[string]bool = make(map[string]bool)
+		err      error
+	)
+
+	fixtures, err = netboxtest.LoadFixtures("testdata/fixtures/example/netbox.yml")
+	require.Nil(t, err)
+
+	server = netboxtest.NewServer(fixtures)
+	defer server.Close()
+
+	sd.cfg, err = config.ReadConfigFile("testdata/fixtures/example/config.yml")
+	require.Nil(t, err)
+
+	sd.api, err = netbox.New(server.URL, server.Token, netbox.WithPrometheusNamespace("netbox_sd_test"))
+	require.Nil(t, err)
+
+	data, err = sd.dumpTargets(context.Background(), sd.cfg, config.FormatJSON)
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(data, &groups))
+	assert.NotEmpty(t, groups)
+
+	for _, group = range groups {
+		files[string(group.Labels[combinedGroupLabel])] = true
+	}
+
+	assert.True(t, files["node.yml"])
+	assert.True(t, files["json.json"])
+
+	// a failing group fails the dump
+	for _, cfgGroup = range sd.cfg.Groups {
+		cfgGroup.MaxTargets = 1
+	}
+
+	_, err = sd.dumpTargets(context.Background(), sd.cfg, config.FormatYAML)
+	assert.ErrorContains(t, err, "max_targets")
+}
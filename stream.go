@@ -0,0 +1,308 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the gRPC service streaming target changes to subscribers (see api/targets.proto).
+
+import (
+	"log/slog"
+	"maps"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+
+	"github.com/prometheus/common/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	streamEventAdd    = "add"
+	streamEventRemove = "remove"
+	streamEventUpdate = "update"
+	// streamBufferSize is the number of events buffered per subscriber. Subscribers falling behind are disconnected.
+	streamBufferSize = 1024
+)
+
+// targetStream keeps the current targets of all groups and sends changes to its subscribers.
+type targetStream struct {
+	mu sync.Mutex
+	// groups contains the labels of all active target addresses by address per group.
+	groups      map[string]map[string]model.LabelSet
+	subscribers map[*streamSubscriber]bool
+}
+
+// streamSubscriber is a single client of the Watch method.
+type streamSubscriber struct {
+	// group limits the events to a single group; all groups when empty.
+	group  string
+	events chan *structpb.Struct
+	// done is closed when the subscriber has been disconnected for falling behind.
+	done chan struct{}
+}
+
+// targetsServiceDesc describes the gRPC service netbox_sd.v1.Targets. Messages are well-known protobuf types, thus no
+// generated code is required.
+var targetsServiceDesc grpc.ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "netbox_sd.v1.Targets",
+	HandlerType: (*any)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       watchHandler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/targets.proto",
+}
+
+// newTargetStream returns an empty targetStream.
+func newTargetStream() *targetStream {
+	return &targetStream{
+		groups:      make(map[string]map[string]model.LabelSet),
+		subscribers: make(map[*streamSubscriber]bool),
+	}
+}
+
+// serveStream starts a gRPC server listening on address serving stream.
+func serveStream(address string, stream *targetStream) error {
+	var (
+		listener net.Listener
+		server   *grpc.Server = grpc.NewServer()
+		err      error
+	)
+
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	server.RegisterService(&targetsServiceDesc, stream)
+
+	go func() {
+		var err error = server.Serve(listener)
+		if err != nil {
+			slog.Error("grpc server failed", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// update replaces the targets of group and sends the resulting events to all subscribers.
+func (stream *targetStream) update(group string, targets []*discovery.Target) {
+	var (
+		current map[string]model.LabelSet = activeTargets(targets)
+		events  []*structpb.Struct
+	)
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	events = streamEvents(group, stream.groups[group], current)
+	stream.groups[group] = current
+
+	stream.publish(group, events)
+}
+
+// remove deletes all targets of group, sending a remove event for each of them.
+func (stream *targetStream) remove(group string) {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	stream.publish(group, streamEvents(group, stream.groups[group], nil))
+	delete(stream.groups, group)
+}
+
+// publish sends events of group to all subscribers. The caller must hold mu.
+func (stream *targetStream) publish(group string, events []*structpb.Struct) {
+	var subscriber *streamSubscriber
+
+	for subscriber = range stream.subscribers {
+		if subscriber.group != "" && subscriber.group != group {
+			continue
+		}
+
+		if !subscriber.send(events) {
+			slog.Warn("grpc subscriber falling behind, disconnecting it", "group", group)
+			delete(stream.subscribers, subscriber)
+			close(subscriber.done)
+		}
+	}
+}
+
+// send queues events without blocking. It returns false when the buffer of subscriber is full.
+func (subscriber *streamSubscriber) send(events []*structpb.Struct) bool {
+	var event *structpb.Struct
+
+	for _, event = range events {
+		select {
+		case subscriber.events <- event:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// subscribe registers a new subscriber for group (all groups when empty). The events of the subscriber start with an
+// add event for each current target.
+func (stream *targetStream) subscribe(group string) *streamSubscriber {
+	var (
+		subscriber *streamSubscriber
+		events     []*structpb.Struct
+		name       string
+		names      []string
+	)
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	for name = range stream.groups {
+		if group == "" || group == name {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name = range names {
+		events = append(events, streamEvents(name, nil, stream.groups[name])...)
+	}
+
+	subscriber = &streamSubscriber{
+		group:  group,
+		events: make(chan *structpb.Struct, max(streamBufferSize, len(events))),
+		done:   make(chan struct{}),
+	}
+
+	subscriber.send(events)
+
+	stream.subscribers[subscriber] = true
+
+	return subscriber
+}
+
+// unsubscribe removes subscriber.
+func (stream *targetStream) unsubscribe(subscriber *streamSubscriber) {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	delete(stream.subscribers, subscriber)
+}
+
+// watchHandler implements the Watch method. The request is the group to watch (all groups when empty).
+func watchHandler(srv any, server grpc.ServerStream) error {
+	var (
+		stream     *targetStream = srv.(*targetStream)
+		request    wrapperspb.StringValue
+		subscriber *streamSubscriber
+		event      *structpb.Struct
+		err        error
+	)
+
+	err = server.RecvMsg(&request)
+	if err != nil {
+		return err
+	}
+
+	subscriber = stream.subscribe(request.Value)
+	defer stream.unsubscribe(subscriber)
+
+	for {
+		select {
+		case <-server.Context().Done():
+			return nil
+		case <-subscriber.done:
+			return status.Error(codes.ResourceExhausted, "subscriber falling behind")
+		case event = <-subscriber.events:
+			err = server.SendMsg(event)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// streamEvents returns the events changing the targets of group from previous to current (both as returned by
+// activeTargets) ordered by address.
+func streamEvents(group string, previous, current map[string]model.LabelSet) []*structpb.Struct {
+	var (
+		events    []*structpb.Struct
+		addresses []string
+		address   string
+		ok        bool
+	)
+
+	for address = range current {
+		addresses = append(addresses, address)
+	}
+
+	for address = range previous {
+		if _, ok = current[address]; !ok {
+			addresses = append(addresses, address)
+		}
+	}
+
+	sort.Strings(addresses)
+
+	for _, address = range addresses {
+		if _, ok = previous[address]; !ok {
+			events = append(events, streamEvent(streamEventAdd, group, address, current[address]))
+			continue
+		}
+
+		if _, ok = current[address]; !ok {
+			events = append(events, streamEvent(streamEventRemove, group, address, previous[address]))
+			continue
+		}
+
+		if !maps.Equal(previous[address], current[address]) {
+			events = append(events, streamEvent(streamEventUpdate, group, address, current[address]))
+		}
+	}
+
+	return events
+}
+
+// streamEvent returns a single event of the Watch method.
+func streamEvent(kind, group, address string, labels model.LabelSet) *structpb.Struct {
+	var (
+		values map[string]*structpb.Value = make(map[string]*structpb.Value, len(labels))
+		name   model.LabelName
+	)
+
+	for name = range labels {
+		values[string(name)] = structpb.NewStringValue(string(labels[name]))
+	}
+
+	return &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"type":    structpb.NewStringValue(kind),
+			"group":   structpb.NewStringValue(group),
+			"address": structpb.NewStringValue(address),
+			"labels":  structpb.NewStructValue(&structpb.Struct{Fields: values}),
+		},
+	}
+}
@@ -0,0 +1,115 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestStreamEvents(t *testing.T) {
+	var (
+		previous map[string]model.LabelSet = map[string]model.LabelSet{
+			"192.0.2.1:9100": {"netbox_name": "device-A"},
+			"192.0.2.2:9100": {"netbox_name": "device-B"},
+		}
+		current map[string]model.LabelSet = map[string]model.LabelSet{
+			"192.0.2.2:9100": {"netbox_name": "device-B", "foo": "bar"},
+			"192.0.2.3:9100": {"netbox_name": "device-C"},
+		}
+		events []*structpb.Struct
+	)
+
+	events = streamEvents("node.yml", previous, current)
+	require.Len(t, events, 3)
+	assert.Equal(t, map[string]any{
+		"type":    "remove",
+		"group":   "node.yml",
+		"address": "192.0.2.1:9100",
+		"labels":  map[string]any{"netbox_name": "device-A"},
+	}, events[0].AsMap())
+	assert.Equal(t, "update", events[1].AsMap()["type"])
+	assert.Equal(t, "add", events[2].AsMap()["type"])
+
+	assert.Empty(t, streamEvents("node.yml", current, current))
+}
+
+func TestWatch(t *testing.T) {
+	var (
+		stream   *targetStream     = newTargetStream()
+		listener *bufconn.Listener = bufconn.Listen(1024 * 1024)
+		server   *grpc.Server      = grpc.NewServer()
+		conn     *grpc.ClientConn
+		client   grpc.ClientStream
+		event    structpb.Struct
+		target   *discovery.Target = &discovery.Target{
+			Addresses:  []*netbox.IP{{Address: "192.0.2.1/24"}},
+			Ports:      []int{9100},
+			Labels:     model.LabelSet{"netbox_name": "device-A"},
+			SkipReason: discovery.StateActive,
+		}
+		err error
+	)
+
+	server.RegisterService(&targetsServiceDesc, stream)
+
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err = grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	defer conn.Close()
+
+	// existing targets are sent first
+	stream.update("node.yml", []*discovery.Target{target})
+	stream.update("other.yml", []*discovery.Target{target})
+
+	client, err = conn.NewStream(context.Background(), &targetsServiceDesc.Streams[0], "/netbox_sd.v1.Targets/Watch")
+	require.Nil(t, err)
+	require.Nil(t, client.SendMsg(wrapperspb.String("node.yml")))
+	require.Nil(t, client.CloseSend())
+
+	require.Nil(t, client.RecvMsg(&event))
+	assert.Equal(t, "add", event.AsMap()["type"])
+	assert.Equal(t, "192.0.2.1:9100", event.AsMap()["address"])
+
+	// events of other groups are not sent
+	stream.update("other.yml", nil)
+	stream.update("node.yml", nil)
+
+	require.Nil(t, client.RecvMsg(&event))
+	assert.Equal(t, "remove", event.AsMap()["type"])
+	assert.Equal(t, "node.yml", event.AsMap()["group"])
+}
@@ -0,0 +1,102 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+)
+
+// getByTagExpr returns all objects matching expr. Objects are queried by each of the expression's query tags using
+// query and filtered by their tags afterwards. Objects carrying more than one query tag are only returned once.
+func getByTagExpr[T any](ctx context.Context, expr *config.TagExpr, query func(context.Context, string) ([]T, error),
+	id func(T) uint64, tags func(T) []netbox.Tag) ([]T, error) {
+	var (
+		err       error
+		queryTags []string
+		tag       string
+		list      []T
+		lists     [][]T
+	)
+
+	// validated when reading the config
+	queryTags, _ = expr.QueryTags()
+
+	for _, tag = range queryTags {
+		list, err = query(ctx, tag)
+		if err != nil {
+			return nil, err
+		}
+
+		lists = append(lists, list)
+	}
+
+	return matchTagExpr(expr, lists, id, tags), nil
+}
+
+// matchTagExpr returns all objects of lists matching expr. Objects contained in more than one list are only returned
+// once.
+func matchTagExpr[T any](expr *config.TagExpr, lists [][]T, id func(T) uint64, tags func(T) []netbox.Tag) []T {
+	var (
+		list   []T
+		obj    T
+		result []T
+		seen   map[uint64]bool = make(map[uint64]bool)
+	)
+
+	for _, list = range lists {
+		for _, obj = range list {
+			if seen[id(obj)] || !expr.Match(tagSlugs(tags(obj))) {
+				continue
+			}
+
+			seen[id(obj)] = true
+			result = append(result, obj)
+		}
+	}
+
+	return result
+}
+
+// tagSlugs returns the slugs of tags.
+func tagSlugs(tags []netbox.Tag) []string {
+	var (
+		slugs []string = make([]string, 0, len(tags))
+		tag   netbox.Tag
+	)
+
+	for _, tag = range tags {
+		slugs = append(slugs, tag.Slug)
+	}
+
+	return slugs
+}
+
+func deviceID(dev *netbox.Device) uint64 { return dev.ID }
+
+func deviceTags(dev *netbox.Device) []netbox.Tag { return dev.Tags }
+
+func interfaceID(iface *netbox.Interface) uint64 { return iface.ID }
+
+func interfaceTags(iface *netbox.Interface) []netbox.Tag { return iface.Tags }
+
+func vdcID(vdc *netbox.VDC) uint64 { return vdc.ID }
+
+func vdcTags(vdc *netbox.VDC) []netbox.Tag { return vdc.Tags }
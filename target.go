@@ -0,0 +1,232 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"gopkg.in/yaml.v3"
+)
+
+// renderGroup returns the content of all files written for group, indexed by file name. Targets in the graveyard are
+// written to the graveyard file when defined. When the group has a file template, active targets are written to the
+// file rendered for them.
+func renderGroup(group *config.Group, targets []*discovery.Target) (map[string][]byte, error) {
+	var (
+		files  map[string][]byte = make(map[string][]byte)
+		alive  []*discovery.Target
+		buried []*discovery.Target
+		shards map[string][]*discovery.Target = make(map[string][]*discovery.Target)
+		target *discovery.Target
+		file   string
+		ok     bool
+		err    error
+	)
+
+	for _, target = range targets {
+		if target.Graveyard {
+			buried = append(buried, target)
+			continue
+		}
+
+		file = group.File
+		if !target.Skipped() {
+			file, err = group.ShardFile(target.Labels)
+			if err != nil {
+				slog.Warn("failed to render file_template, using group file", "group", group.File,
+					"device", target.Labels["netbox_name"], "error", err)
+				file = group.File
+			}
+
+			// targets of shards written by other instances are dropped
+			if file, ok = group.HashmodFile(file, firstAddress(target)); !ok {
+				continue
+			}
+		}
+
+		if file == group.File {
+			alive = append(alive, target)
+		} else {
+			shards[file] = append(shards[file], target)
+		}
+	}
+
+	for file = range shards {
+		files[file], err = renderTargets(shards[file], group.Format, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files[group.File], err = renderTargets(alive, group.Format, *group.Flags.ReportSkipped)
+	if err != nil {
+		return nil, err
+	}
+
+	if group.Graveyard != nil {
+		files[group.Graveyard.File], err = renderTargets(buried, group.Format, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// firstAddress returns the first address of target without prefix length or an empty string if it has no addresses.
+func firstAddress(target *discovery.Target) string {
+	if len(target.Addresses) == 0 {
+		return ""
+	}
+
+	return strings.Split(target.Addresses[0].Address, "/")[0]
+}
+
+// addVanishedFiles adds an empty target list to files for each file of previous that isn't part of files anymore (e.g.
+// a shard of a file template whose last target disappeared), so Prometheus stops scraping its targets. It returns the
+// files to be passed as previous for the next scan.
+func addVanishedFiles(group *config.Group, files map[string][]byte, previous map[string]bool) (map[string]bool, error) {
+	var (
+		current map[string]bool = make(map[string]bool, len(files))
+		file    string
+		err     error
+	)
+
+	for file = range files {
+		current[file] = true
+	}
+
+	for file = range previous {
+		if current[file] {
+			continue
+		}
+
+		files[file], err = renderTargets(nil, group.Format, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return current, nil
+}
+
+// countActive returns the number of targets that have not been skipped, excluding those in the graveyard.
+func countActive(targets []*discovery.Target) int {
+	var (
+		count  int
+		target *discovery.Target
+	)
+
+	for _, target = range targets {
+		if !target.Skipped() && !target.Graveyard {
+			count++
+		}
+	}
+
+	return count
+}
+
+// exceedsMaxTargets returns true when group limits the number of targets and targets contains more active ones.
+func exceedsMaxTargets(group *config.Group, targets []*discovery.Target) bool {
+	return group.MaxTargets > 0 && countActive(targets) > group.MaxTargets
+}
+
+// renderTargets returns the content of a group's file for targets in the given format (yaml or json). When
+// reportSkipped is true, skipped targets and the reason why they have been skipped are listed as comments at the top of
+// the file (yaml only).
+func renderTargets(targets []*discovery.Target, format string, reportSkipped bool) ([]byte, error) {
+	var (
+		buf    bytes.Buffer
+		target *discovery.Target
+		groups []*targetgroup.Group
+		data   []byte
+		err    error
+	)
+
+	if format == config.FormatJSON {
+		// an empty file must still be a valid list
+		groups = discovery.TargetGroups(targets)
+		if groups == nil {
+			groups = []*targetgroup.Group{}
+		}
+
+		data, err = json.MarshalIndent(groups, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		return append(data, '\n'), nil
+	}
+
+	if reportSkipped {
+		for _, target = range targets {
+			if !target.Skipped() || target.Device == nil {
+				continue
+			}
+
+			if buf.Len() == 0 {
+				buf.WriteString("# skipped targets:\n")
+			}
+
+			fmt.Fprintf(&buf, "#   %s: %s\n", target.Device.Name, target.SkipReason)
+		}
+	}
+
+	data, err = yaml.Marshal(discovery.TargetGroups(targets))
+	if err != nil {
+		return nil, err
+	}
+
+	buf.Write(data)
+
+	return buf.Bytes(), nil
+}
+
+// setTargetStateMetrics updates the target state metric for all targets of a group as well as the number of addresses
+// removed by address filters. Exposed defines the Netbox labels set on the target state metric.
+func setTargetStateMetrics(group string, targets []*discovery.Target, exposed []string) {
+	var (
+		target   *discovery.Target
+		filtered int
+	)
+
+	for _, target = range targets {
+		if target.Graveyard && !target.Skipped() {
+			SetTargetStatusMetric(group, target.Device, target.ServiceID, discovery.StateGraveyard, exposed)
+		} else {
+			SetTargetStatusMetric(group, target.Device, target.ServiceID, target.SkipReason, exposed)
+		}
+
+		filtered += target.AddressesFiltered
+	}
+
+	promAddressesFiltered.
+		With(prometheus.Labels{
+			"group": group,
+		}).
+		Set(float64(filtered))
+}
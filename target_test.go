@@ -0,0 +1,132 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTargets(t *testing.T) {
+	var (
+		input = []*discovery.Target{
+			{
+				Device:     &netbox.Device{Name: "device-A"},
+				Addresses:  []*netbox.IP{{Address: "10.0.0.1/24"}},
+				Labels:     model.LabelSet{"netbox_name": "device-A"},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				Device:     &netbox.Device{Name: "device-B"},
+				SkipReason: discovery.StateSkippedBadStatus,
+			},
+			{
+				Device:     &netbox.Device{Name: "device-C"},
+				SkipReason: discovery.StateSkippedNotMatchingFilters,
+			},
+		}
+		expected = `- targets:
+    - 10.0.0.1
+  labels:
+    netbox_name: device-A
+`
+		data []byte
+		err  error
+	)
+
+	data, err = renderTargets(input, config.FormatYAML, false)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(data))
+
+	data, err = renderTargets(input, config.FormatYAML, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "# skipped targets:\n#   device-B: bad status\n#   device-C: not matching filters\n"+expected,
+		string(data))
+
+	// no comments without skipped targets
+	data, err = renderTargets(input[:1], config.FormatYAML, true)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(data))
+
+	data, err = renderTargets(input, config.FormatJSON, false)
+	assert.Nil(t, err)
+	assert.Equal(t, `[
+  {
+    "targets": [
+      "10.0.0.1"
+    ],
+    "labels": {
+      "netbox_name": "device-A"
+    }
+  }
+]
+`, string(data))
+
+	// empty files are an empty list
+	data, err = renderTargets(nil, config.FormatJSON, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "[]\n", string(data))
+}
+
+func TestAddVanishedFiles(t *testing.T) {
+	var (
+		group    *config.Group     = &config.Group{Format: config.FormatYAML}
+		files    map[string][]byte = map[string][]byte{"a.yml": []byte("a"), "b.yml": []byte("b")}
+		previous map[string]bool
+		err      error
+	)
+
+	previous, err = addVanishedFiles(group, files, nil)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]bool{"a.yml": true, "b.yml": true}, previous)
+
+	// b.yml vanished and is written once with an empty target list
+	files = map[string][]byte{"a.yml": []byte("a")}
+	previous, err = addVanishedFiles(group, files, previous)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]bool{"a.yml": true}, previous)
+	assert.Equal(t, map[string][]byte{"a.yml": []byte("a"), "b.yml": []byte("[]\n")}, files)
+}
+
+func TestExceedsMaxTargets(t *testing.T) {
+	var (
+		group   *config.Group       = &config.Group{}
+		targets []*discovery.Target = []*discovery.Target{
+			{SkipReason: discovery.StateActive},
+			{SkipReason: discovery.StateActive},
+			{SkipReason: discovery.StateSkippedBadStatus},
+			{SkipReason: discovery.StateActive, Graveyard: true},
+		}
+	)
+
+	// no limit
+	assert.False(t, exceedsMaxTargets(group, targets))
+
+	group.MaxTargets = 2
+	assert.False(t, exceedsMaxTargets(group, targets))
+
+	group.MaxTargets = 1
+	assert.True(t, exceedsMaxTargets(group, targets))
+}
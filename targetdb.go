@@ -0,0 +1,317 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the target database, a SQLite database keeping the latest targets of each group together with the
+// time and changes of all scans.
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// changeAdded and changeRemoved are the values of the change column of the changes table.
+	changeAdded   string = "added"
+	changeRemoved string = "removed"
+)
+
+var (
+	ErrTargetDBVersion = errors.New("target database has been created by a newer version of netbox_sd")
+
+	// targetDBMigrations contains the statements migrating the schema of the target database from one version to the
+	// next. The version of a database is kept as its user_version, thus migrations must only ever be appended.
+	targetDBMigrations []string = []string{
+		`CREATE TABLE scans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			group_file TEXT NOT NULL,
+			time TEXT NOT NULL,
+			targets INTEGER NOT NULL,
+			added INTEGER NOT NULL,
+			removed INTEGER NOT NULL
+		);
+		CREATE INDEX scans_group_file_time ON scans (group_file, time);
+
+		CREATE TABLE changes (
+			scan_id INTEGER NOT NULL REFERENCES scans (id) ON DELETE CASCADE,
+			change TEXT NOT NULL,
+			address TEXT NOT NULL,
+			labels TEXT NOT NULL
+		);
+		CREATE INDEX changes_scan_id ON changes (scan_id);
+		CREATE INDEX changes_address ON changes (address);
+
+		CREATE TABLE targets (
+			group_file TEXT NOT NULL,
+			address TEXT NOT NULL,
+			labels TEXT NOT NULL,
+			PRIMARY KEY (group_file, address)
+		);`,
+	}
+)
+
+// targetDB stores the targets of all groups in a SQLite database. Workers of all groups share the database.
+type targetDB struct {
+	db *sql.DB
+}
+
+// openTargetDB opens the target database in file, creating it if necessary, and migrates its schema to the latest
+// version.
+func openTargetDB(file string) (*targetDB, error) {
+	var (
+		tdb *targetDB = new(targetDB)
+		err error
+	)
+
+	tdb.db, err = sql.Open("sqlite3", "file:"+file+"?_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite only supports a single writer, thus workers wait for each other instead of failing with SQLITE_BUSY
+	tdb.db.SetMaxOpenConns(1)
+
+	err = tdb.migrate()
+	if err != nil {
+		tdb.db.Close()
+		return nil, fmt.Errorf("failed to migrate target database %s: %w", file, err)
+	}
+
+	return tdb, nil
+}
+
+// migrate applies all migrations not applied to the database yet, each within a transaction of its own.
+func (tdb *targetDB) migrate() error {
+	var (
+		version int
+		tx      *sql.Tx
+		err     error
+	)
+
+	err = tdb.db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	if version > len(targetDBMigrations) {
+		return fmt.Errorf("%w: schema version %d, supported %d", ErrTargetDBVersion, version, len(targetDBMigrations))
+	}
+
+	for ; version < len(targetDBMigrations); version++ {
+		tx, err = tdb.db.Begin()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(targetDBMigrations[version])
+		if err == nil {
+			// PRAGMA doesn't support parameters
+			_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1))
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration to version %d failed: %w", version+1, err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// store records a scan of group at now and replaces the stored targets of the group with the active ones of targets.
+// Targets added and removed since the previously stored targets (which might have been stored before a restart) are
+// recorded as changes of the scan. The returned changes are nil when nothing changed.
+func (tdb *targetDB) store(group string, targets []*discovery.Target, now time.Time) (*targetChanges, error) {
+	var (
+		tx       *sql.Tx
+		previous map[string]model.LabelSet
+		current  map[string]model.LabelSet = activeTargets(targets)
+		changes  *targetChanges
+		result   sql.Result
+		scanID   int64
+		address  string
+		added    int
+		removed  int
+		err      error
+	)
+
+	tx, err = tdb.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	previous, err = queryTargets(tx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	changes = diffTargets(group, previous, current, now)
+	if changes != nil {
+		added = len(changes.Added)
+		removed = len(changes.Removed)
+	}
+
+	result, err = tx.Exec("INSERT INTO scans (group_file, time, targets, added, removed) VALUES (?, ?, ?, ?, ?)",
+		group, now.UTC().Format(time.RFC3339Nano), len(current), added, removed)
+	if err != nil {
+		return nil, err
+	}
+
+	scanID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if changes != nil {
+		err = insertChanges(tx, scanID, changeAdded, changes.Added)
+		if err == nil {
+			err = insertChanges(tx, scanID, changeRemoved, changes.Removed)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// labels might have changed without targets being added or removed, thus targets are always replaced
+	_, err = tx.Exec("DELETE FROM targets WHERE group_file = ?", group)
+	if err != nil {
+		return nil, err
+	}
+
+	for address = range current {
+		err = insertTarget(tx, group, address, current[address])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// remove deletes the stored targets of group. Its scans and their changes are kept.
+func (tdb *targetDB) remove(group string) error {
+	var err error
+
+	_, err = tdb.db.Exec("DELETE FROM targets WHERE group_file = ?", group)
+
+	return err
+}
+
+// close closes the database.
+func (tdb *targetDB) close() error {
+	return tdb.db.Close()
+}
+
+// queryTargets returns the labels of the stored targets of group by address.
+func queryTargets(tx *sql.Tx, group string) (map[string]model.LabelSet, error) {
+	var (
+		rows    *sql.Rows
+		result  map[string]model.LabelSet = make(map[string]model.LabelSet)
+		address string
+		data    []byte
+		labels  model.LabelSet
+		err     error
+	)
+
+	rows, err = tx.Query("SELECT address, labels FROM targets WHERE group_file = ?", group)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		labels = nil
+
+		err = rows.Scan(&address, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(data, &labels)
+		if err != nil {
+			return nil, fmt.Errorf("bad labels of target %s: %w", address, err)
+		}
+
+		result[address] = labels
+	}
+
+	return result, rows.Err()
+}
+
+// insertTarget stores a target of group.
+func insertTarget(tx *sql.Tx, group string, address string, labels model.LabelSet) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	data, err = json.Marshal(labels)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO targets (group_file, address, labels) VALUES (?, ?, ?)", group, address, string(data))
+
+	return err
+}
+
+// insertChanges records targets as change of the scan with scanID.
+func insertChanges(tx *sql.Tx, scanID int64, change string, targets []*changedTarget) error {
+	var (
+		target *changedTarget
+		data   []byte
+		err    error
+	)
+
+	for _, target = range targets {
+		data, err = json.Marshal(target.Labels)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("INSERT INTO changes (scan_id, change, address, labels) VALUES (?, ?, ?, ?)", scanID, change,
+			target.Address, string(data))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetDBMigrate(t *testing.T) {
+	var (
+		file    string = filepath.Join(t.TempDir(), "targets.db")
+		tdb     *targetDB
+		version int
+		err     error
+	)
+
+	tdb, err = openTargetDB(file)
+	require.Nil(t, err)
+
+	require.Nil(t, tdb.db.QueryRow("PRAGMA user_version").Scan(&version))
+	assert.Equal(t, len(targetDBMigrations), version)
+	require.Nil(t, tdb.close())
+
+	// migrations are only applied once
+	tdb, err = openTargetDB(file)
+	require.Nil(t, err)
+
+	require.Nil(t, tdb.db.QueryRow("PRAGMA user_version").Scan(&version))
+	assert.Equal(t, len(targetDBMigrations), version)
+
+	// databases of newer versions are rejected
+	_, err = tdb.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(targetDBMigrations)+1))
+	require.Nil(t, err)
+	require.Nil(t, tdb.close())
+
+	_, err = openTargetDB(file)
+	assert.ErrorIs(t, err, ErrTargetDBVersion)
+}
+
+func TestTargetDBStore(t *testing.T) {
+	var (
+		file    string    = filepath.Join(t.TempDir(), "targets.db")
+		now     time.Time = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		tdb     *targetDB
+		changes *targetChanges
+		db      *sql.DB
+		count   int
+		labels  string
+		targets []*discovery.Target = []*discovery.Target{
+			{
+				Addresses:  []*netbox.IP{{Address: "192.0.2.1/24"}},
+				Ports:      []int{9100},
+				Labels:     model.LabelSet{"netbox_name": "device-A"},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				Addresses:  []*netbox.IP{{Address: "192.0.2.2/24"}},
+				Ports:      []int{9100},
+				Labels:     model.LabelSet{"netbox_name": "device-B"},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				Addresses:  []*netbox.IP{{Address: "192.0.2.3/24"}},
+				Labels:     model.LabelSet{"netbox_name": "device-C"},
+				SkipReason: discovery.StateSkippedBadStatus,
+			},
+		}
+		err error
+	)
+
+	tdb, err = openTargetDB(file)
+	require.Nil(t, err)
+
+	// all targets of the first scan are added
+	changes, err = tdb.store("node.yml", targets[:1], now)
+	require.Nil(t, err)
+	require.NotNil(t, changes)
+	assert.Len(t, changes.Added, 1)
+	assert.Empty(t, changes.Removed)
+
+	// unchanged targets are recorded as scan without changes
+	changes, err = tdb.store("node.yml", targets[:1], now.Add(time.Minute))
+	require.Nil(t, err)
+	assert.Nil(t, changes)
+
+	require.Nil(t, tdb.close())
+
+	// changes of scans after a restart are relative to the targets stored before
+	tdb, err = openTargetDB(file)
+	require.Nil(t, err)
+
+	changes, err = tdb.store("node.yml", targets[1:], now.Add(2*time.Minute))
+	require.Nil(t, err)
+	require.NotNil(t, changes)
+	assert.Equal(t, []*changedTarget{{Address: "192.0.2.2:9100", Labels: model.LabelSet{"netbox_name": "device-B"}}},
+		changes.Added)
+	assert.Equal(t, []*changedTarget{{Address: "192.0.2.1:9100", Labels: model.LabelSet{"netbox_name": "device-A"}}},
+		changes.Removed)
+
+	_, err = tdb.store("snmp.yml", targets[:1], now.Add(2*time.Minute))
+	require.Nil(t, err)
+
+	db = tdb.db
+
+	require.Nil(t, db.QueryRow("SELECT COUNT(*) FROM scans WHERE group_file = 'node.yml'").Scan(&count))
+	assert.Equal(t, 3, count)
+
+	require.Nil(t, db.QueryRow("SELECT COUNT(*) FROM scans WHERE group_file = 'node.yml' AND added = 0 AND removed = 0").
+		Scan(&count))
+	assert.Equal(t, 1, count)
+
+	require.Nil(t, db.QueryRow(`SELECT COUNT(*) FROM changes JOIN scans ON scans.id = changes.scan_id
+		WHERE scans.group_file = 'node.yml' AND scans.time = ?`, "2024-05-01T12:02:00Z").Scan(&count))
+	assert.Equal(t, 2, count)
+
+	require.Nil(t, db.QueryRow("SELECT labels FROM targets WHERE group_file = 'node.yml' AND address = ?",
+		"192.0.2.2:9100").Scan(&labels))
+	assert.JSONEq(t, `{"netbox_name":"device-B"}`, labels)
+
+	require.Nil(t, db.QueryRow("SELECT COUNT(*) FROM targets").Scan(&count))
+	assert.Equal(t, 2, count)
+
+	// removing a group keeps its scans
+	require.Nil(t, tdb.remove("node.yml"))
+
+	require.Nil(t, db.QueryRow("SELECT COUNT(*) FROM targets WHERE group_file = 'node.yml'").Scan(&count))
+	assert.Equal(t, 0, count)
+
+	require.Nil(t, db.QueryRow("SELECT COUNT(*) FROM scans WHERE group_file = 'node.yml'").Scan(&count))
+	assert.Equal(t, 3, count)
+
+	require.Nil(t, tdb.close())
+}
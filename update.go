@@ -0,0 +1,139 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// githubRelease contains the parts of a GitHub release we're interested in.
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+}
+
+var (
+	// updateCheckURL points to the latest release of netbox_sd on GitHub.
+	updateCheckURL string = "https://api.github.com/repos/4xoc/netbox_sd/releases/latest"
+)
+
+// checkForUpdates periodically checks GitHub for a newer release of netbox_sd and exposes the result as metric. It
+// never updates anything by itself.
+func checkForUpdates(interval time.Duration) {
+	var (
+		client    *http.Client = &http.Client{Timeout: 30 * time.Second}
+		latest    string
+		available bool
+		err       error
+	)
+
+	for {
+		latest, err = getLatestRelease(client, updateCheckURL)
+		if err != nil {
+			slog.Warn("failed to check for updates", "error", err)
+		} else {
+			available, err = updateAvailable(version, latest)
+			if err != nil {
+				slog.Warn("failed to compare versions", "latest", latest, "error", err)
+			} else {
+				if available {
+					slog.Info("new version of netbox_sd is available", "latest", latest, "version", version)
+					promUpdateAvailable.Set(1)
+				} else {
+					promUpdateAvailable.Set(0)
+				}
+
+				promLatestVersion.Reset()
+				promLatestVersion.
+					With(prometheus.Labels{
+						"version": latest,
+					}).
+					Set(1)
+			}
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// getLatestRelease returns the tag name of the latest release found at url.
+func getLatestRelease(client *http.Client, url string) (string, error) {
+	var (
+		req     *http.Request
+		resp    *http.Response
+		release githubRelease
+		err     error
+	)
+
+	req, err = http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err = client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&release)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode release: %w", err)
+	}
+
+	if release.TagName == "" {
+		return "", fmt.Errorf("release has no tag name")
+	}
+
+	return release.TagName, nil
+}
+
+// updateAvailable returns true when latest is a newer version than current.
+func updateAvailable(current, latest string) (bool, error) {
+	var (
+		currentVersion *semver.Version
+		latestVersion  *semver.Version
+		err            error
+	)
+
+	currentVersion, err = semver.NewVersion(strings.TrimPrefix(current, "v"))
+	if err != nil {
+		return false, fmt.Errorf("could not parse current version '%s': %w", current, err)
+	}
+
+	latestVersion, err = semver.NewVersion(strings.TrimPrefix(latest, "v"))
+	if err != nil {
+		return false, fmt.Errorf("could not parse latest version '%s': %w", latest, err)
+	}
+
+	return latestVersion.GreaterThan(currentVersion), nil
+}
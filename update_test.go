@@ -0,0 +1,85 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateAvailable(t *testing.T) {
+	var (
+		data = []struct {
+			current  string
+			latest   string
+			expected bool
+			err      bool
+		}{
+			{"v1.1.0", "v1.2.0", true, false},
+			{"v1.1.0", "v1.1.0", false, false},
+			{"v1.2.0", "v1.1.0", false, false},
+			{"1.1.0", "v1.1.1", true, false},
+			{"", "v1.1.0", false, true},
+			{"v1.1.0", "latest", false, true},
+		}
+		result bool
+		err    error
+		i      int
+	)
+
+	for i = range data {
+		result, err = updateAvailable(data[i].current, data[i].latest)
+		if data[i].err {
+			assert.Error(t, err)
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, data[i].expected, result)
+	}
+}
+
+func TestGetLatestRelease(t *testing.T) {
+	var (
+		server *httptest.Server
+		latest string
+		err    error
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/latest" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		io.WriteString(w, `{"tag_name": "v1.2.3", "name": "release v1.2.3"}`)
+	}))
+	defer server.Close()
+
+	latest, err = getLatestRelease(server.Client(), server.URL+"/latest")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.2.3", latest)
+
+	_, err = getLatestRelease(server.Client(), server.URL+"/missing")
+	assert.Error(t, err)
+}
@@ -19,9 +19,13 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strconv"
 
 	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
 	"github.com/4xoc/netbox_sd/pkg/netbox"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -48,9 +52,7 @@ func selectAddr(addrs []*netbox.IP, group *config.Group) []*netbox.IP {
 		}
 
 		// Not all IPs are created equal.
-		if addr.Status != netbox.StatusIPActive &&
-			addr.Status != netbox.StatusIPDHCP &&
-			addr.Status != netbox.StatusIPSLAAC {
+		if !group.IPStatusAllowed(addr.Status) {
 			continue
 		}
 
@@ -95,7 +97,7 @@ func selectAddr(addrs []*netbox.IP, group *config.Group) []*netbox.IP {
 			}
 
 		default:
-			log.Printf("got unsupported address family %d from netbox", addr.Family())
+			slog.Warn("got unsupported address family from netbox", "family", addr.Family())
 			return make([]*netbox.IP, 0)
 		}
 	}
@@ -113,6 +115,31 @@ func selectAddr(addrs []*netbox.IP, group *config.Group) []*netbox.IP {
 	return result
 }
 
+// filterAddr applies the group's address filters to addrs and returns all addresses that passed. Addresses that can't
+// be parsed never pass.
+func filterAddr(addrs []*netbox.IP, group *config.Group) []*netbox.IP {
+	var (
+		result []*netbox.IP = make([]*netbox.IP, 0, len(addrs))
+		addr   *netbox.IP
+		parsed netip.Addr
+		err    error
+	)
+
+	for _, addr = range addrs {
+		parsed, err = netip.ParseAddr(addr.ToAddr())
+		if err != nil {
+			slog.Warn("failed to parse address", "address", addr.Address, "error", err)
+			continue
+		}
+
+		if group.AddressFiltersMatch(parsed) {
+			result = append(result, addr)
+		}
+	}
+
+	return result
+}
+
 // AddrExists checks if a given netbox.IP is already existing in a []*netbox.IP
 func addrExists(needle *netbox.IP, haystack []*netbox.IP) bool {
 	var i int
@@ -127,8 +154,8 @@ func addrExists(needle *netbox.IP, haystack []*netbox.IP) bool {
 
 // GenerateCustomFieldLabels generates based on a list of Netbox's custom fields an additional LabelSet. Should any of
 // the custom fields fail to convert, an error is returned and the resulting labelSet should be ignored. All labels are
-// prefixed with `netbox_`.
-func generateCustomFieldLabels(cfm netbox.CustomFieldMap) (model.LabelSet, error) {
+// prefixed with `netbox_`. When allowed is not nil, only custom fields it returns true for are converted.
+func generateCustomFieldLabels(cfm netbox.CustomFieldMap, allowed func(string) bool) (model.LabelSet, error) {
 	var (
 		allLabels model.LabelSet
 		gotError  error
@@ -143,12 +170,16 @@ func generateCustomFieldLabels(cfm netbox.CustomFieldMap) (model.LabelSet, error
 			err     error
 		)
 
+		if allowed != nil && !allowed(key) {
+			return
+		}
+
 		switch val.Datatype {
 		case netbox.CustomFieldText:
 			tmpStr, err = val.AsString()
 			if err != nil {
 				gotError = err
-				log.Printf("failed to get custom field value as string: %v", err)
+				slog.Warn("failed to get custom field value as string", "custom_field", key, "error", err)
 			}
 
 			label = model.LabelSet{
@@ -159,7 +190,7 @@ func generateCustomFieldLabels(cfm netbox.CustomFieldMap) (model.LabelSet, error
 			tmpNum, err = val.AsFloat()
 			if err != nil {
 				gotError = err
-				log.Printf("failed to get custom field value as float64: %v", err)
+				slog.Warn("failed to get custom field value as float64", "custom_field", key, "error", err)
 			}
 
 			label = model.LabelSet{
@@ -170,7 +201,7 @@ func generateCustomFieldLabels(cfm netbox.CustomFieldMap) (model.LabelSet, error
 			tmpBool, err = val.AsBool()
 			if err != nil {
 				gotError = err
-				log.Printf("failed to get custom field value as bool: %v", err)
+				slog.Warn("failed to get custom field value as bool", "custom_field", key, "error", err)
 			}
 
 			label = model.LabelSet{
@@ -186,11 +217,30 @@ func generateCustomFieldLabels(cfm netbox.CustomFieldMap) (model.LabelSet, error
 	return allLabels, gotError
 }
 
-// SetTargetStatusMetric sets the PromTargetStatus metric for a given Device in group to state.
-func SetTargetStatusMetric(group string, dev *netbox.Device, state TargetState) {
-	promTargetState.
-		With(prometheus.Labels{
+// portList returns the port configured for a group as list of ports. When no port is configured, nil is returned.
+func portList(port *int) []int {
+	if port == nil {
+		return nil
+	}
+
+	return []int{*port}
+}
+
+// graveyardLabels returns the labels added to targets written to the graveyard file of group.
+func graveyardLabels(group *config.Group, dev *netbox.Device) model.LabelSet {
+	return model.LabelSet{
+		model.LabelName("netbox_status"): model.LabelValue(dev.Status),
+	}.Merge(group.Graveyard.Labels)
+}
+
+// SetTargetStatusMetric sets the PromTargetStatus metric for a given Device in group to state. Only the Netbox labels
+// listed in exposed are set, all others are left empty (which Prometheus treats like a missing label). serviceID is
+// exposed as netbox_service_id for targets generated from a service (0 otherwise).
+func SetTargetStatusMetric(group string, dev *netbox.Device, serviceID uint64, state discovery.State, exposed []string) {
+	var (
+		labels prometheus.Labels = prometheus.Labels{
 			"group":                group,
+			"netbox_service_id":    "",
 			"netbox_name":          dev.Name,
 			"netbox_rack":          dev.Rack.Name,
 			"netbox_site":          dev.Site.Name,
@@ -198,36 +248,20 @@ func SetTargetStatusMetric(group string, dev *netbox.Device, state TargetState)
 			"netbox_role":          dev.Role.Name,
 			"netbox_serial_number": dev.SerialNumber,
 			"netbox_asset_tag":     dev.AssetTag,
-		}).Set(float64(state))
-}
-
-// ConvertToTargets takes a list of IPs and optional port and normalizes it into a slice of LabelSets.
-func convertToTargets(ips []*netbox.IP, port *int) []model.LabelSet {
-	var (
-		// Init targets with appropriate capacity.
-		targets = make([]model.LabelSet, 0, len(ips))
-		i       int
+		}
+		name string
 	)
 
-	for i = range ips {
-		// Port is optional, thus only appending it when defined.
-		if port != nil {
-			if ips[i].Family() == 4 {
-				targets = append(targets, model.LabelSet{
-					model.AddressLabel: model.LabelValue(fmt.Sprintf("%s:%d", ips[i].ToAddr(), *port)),
-				})
-			} else {
-				// IPv6 requires wrapping in brackets.
-				targets = append(targets, model.LabelSet{
-					model.AddressLabel: model.LabelValue(fmt.Sprintf("[%s]:%d", ips[i].ToAddr(), *port)),
-				})
-			}
-		} else {
-			targets = append(targets, model.LabelSet{
-				model.AddressLabel: model.LabelValue(ips[i].ToAddr()),
-			})
+	for name = range labels {
+		if name != "group" && name != "netbox_name" && !slices.Contains(exposed, name) {
+			labels[name] = ""
 		}
 	}
 
-	return targets
+	// services are identified by id as more than one service with the same name can exist on a device
+	if serviceID != 0 {
+		labels["netbox_service_id"] = strconv.FormatUint(serviceID, 10)
+	}
+
+	promTargetState.With(labels).Set(float64(state))
 }
@@ -22,8 +22,11 @@ import (
 
 	"github.com/4xoc/netbox_sd/internal/config"
 	"github.com/4xoc/netbox_sd/internal/util"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
 	"github.com/4xoc/netbox_sd/pkg/netbox"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -347,6 +350,41 @@ func TestSelectAddr(t *testing.T) {
 					},
 				},
 			},
+			{
+				// configured statuses replace the default ones
+				input: []*netbox.IP{
+					&netbox.IP{
+						Address: "2001:db8::1234",
+						Status:  netbox.StatusIPReserved,
+					},
+					&netbox.IP{
+						Address: "2001:db8::abcd",
+						Status:  netbox.StatusIPSLAAC,
+					},
+					&netbox.IP{
+						Address: "10.0.0.0",
+						Status:  netbox.StatusIPActive,
+					},
+				},
+				group: &config.Group{
+					Flags: config.Flags{
+						IncludeVMs:   util.NewPtr[bool](true),
+						InetFamily:   util.NewPtr[string]("any"),
+						AllAddresses: util.NewPtr[bool](true),
+					},
+					IPStatuses: []string{netbox.StatusIPActive, netbox.StatusIPReserved},
+				},
+				expected: []*netbox.IP{
+					&netbox.IP{
+						Address: "2001:db8::1234",
+						Status:  netbox.StatusIPReserved,
+					},
+					&netbox.IP{
+						Address: "10.0.0.0",
+						Status:  netbox.StatusIPActive,
+					},
+				},
+			},
 		}
 		result []*netbox.IP
 		i      int
@@ -385,7 +423,78 @@ func TestGenerateCustomFieldLabels(t *testing.T) {
 		err    error
 	)
 
-	result, err = generateCustomFieldLabels(input)
+	result, err = generateCustomFieldLabels(input, nil)
 	require.NoError(t, err)
 	assert.Equal(t, expected, result)
+
+	result, err = generateCustomFieldLabels(input, func(name string) bool { return name != "foo2" })
+	require.NoError(t, err)
+	assert.Equal(t, model.LabelSet{"netbox_foo": "bar", "netbox_foo3": "true"}, result)
+}
+
+// collectTargetState returns the labels and value of the only target_state metric.
+func collectTargetState(t *testing.T) (map[string]string, float64) {
+	var (
+		ch     chan prometheus.Metric = make(chan prometheus.Metric, 2)
+		metric dto.Metric
+		labels map[string]string = make(map[string]string)
+		pair   *dto.LabelPair
+	)
+
+	promTargetState.Collect(ch)
+	close(ch)
+	require.Len(t, ch, 1)
+	require.Nil(t, (<-ch).Write(&metric))
+
+	for _, pair = range metric.GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+
+	return labels, metric.GetGauge().GetValue()
+}
+
+func TestSetTargetStatusMetric(t *testing.T) {
+	var (
+		dev = &netbox.Device{
+			Name:         "device-A",
+			Site:         netbox.Name{Name: "site-A"},
+			SerialNumber: "secret-serial",
+			AssetTag:     "secret-tag",
+		}
+		labels map[string]string
+		value  float64
+	)
+
+	// serial number and asset tag are not exposed by default
+	promTargetState.Reset()
+	SetTargetStatusMetric("test", dev, 0, discovery.StateActive, config.DefaultTargetStateLabels)
+
+	labels, value = collectTargetState(t)
+	assert.Equal(t, float64(discovery.StateActive), value)
+	assert.Equal(t, "test", labels["group"])
+	assert.Equal(t, "device-A", labels["netbox_name"])
+	assert.Equal(t, "site-A", labels["netbox_site"])
+	assert.Equal(t, "", labels["netbox_serial_number"])
+	assert.Equal(t, "", labels["netbox_asset_tag"])
+
+	// netbox_name is always exposed
+	promTargetState.Reset()
+	SetTargetStatusMetric("test", dev, 0, discovery.StateSkippedBadStatus, []string{"netbox_serial_number"})
+
+	labels, value = collectTargetState(t)
+	assert.Equal(t, float64(discovery.StateSkippedBadStatus), value)
+	assert.Equal(t, "device-A", labels["netbox_name"])
+	assert.Equal(t, "secret-serial", labels["netbox_serial_number"])
+	assert.Equal(t, "", labels["netbox_site"])
+	assert.Equal(t, "", labels["netbox_asset_tag"])
+	assert.Equal(t, "", labels["netbox_service_id"])
+
+	// service targets are identified by service id
+	promTargetState.Reset()
+	SetTargetStatusMetric("test", dev, 42, discovery.StateActive, nil)
+
+	labels, _ = collectTargetState(t)
+	assert.Equal(t, "42", labels["netbox_service_id"])
+
+	promTargetState.Reset()
 }
@@ -0,0 +1,68 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/prometheus/common/model"
+)
+
+// getTargetsByVDCTag returns a list of targets for all virtual device contexts that match the group's tag expression. The VDC's primary
+// addresses are used while most labels are inherited from its parent device.
+func (sd *netboxSD) getTargetsByVDCTag(ctx context.Context, group *config.Group) ([]*discovery.Target, error) {
+	var (
+		err     error
+		vdcList []*netbox.VDC
+		vdc     *netbox.VDC
+		dev     *netbox.Device
+		devList []*netbox.Device
+		labels  map[*netbox.Device]model.LabelSet = make(map[*netbox.Device]model.LabelSet)
+	)
+
+	vdcList, err = getByTagExpr(ctx, group.TagExpr, sd.apiFor(group).GetVDCsByTag, vdcID, vdcTags)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get vdcs by tag", "group", group.File, "error", err)
+		return nil, err
+	}
+
+	for _, vdc = range vdcList {
+		dev = vdc.AsDevice()
+		devList = append(devList, dev)
+
+		labels[dev] = model.LabelSet{}
+
+		if vdc.Parent != nil {
+			labels[dev]["netbox_vdc_device"] = model.LabelValue(vdc.Parent.Name)
+		}
+
+		if vdc.Identifier != nil {
+			labels[dev]["netbox_vdc_identifier"] = model.LabelValue(strconv.Itoa(*vdc.Identifier))
+		}
+	}
+
+	return sd.getTargetsByDevices(ctx, group, devList, func(dev *netbox.Device) model.LabelSet {
+		return labels[dev]
+	}), nil
+}
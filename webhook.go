@@ -0,0 +1,109 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the webhook notifying about targets added to or removed from a group.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+)
+
+// webhookClient POSTs the target changes of a group to the configured webhook.
+type webhookClient struct {
+	cfg  *config.Webhook
+	http *http.Client
+}
+
+// newWebhookClient returns a client for the webhook described by cfg.
+func newWebhookClient(cfg *config.Webhook) *webhookClient {
+	return &webhookClient{
+		cfg:  cfg,
+		http: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// send POSTs changes to the webhook. Failed requests are retried up to the configured number of retries.
+func (client *webhookClient) send(changes *targetChanges) error {
+	var (
+		data    []byte
+		attempt int
+		err     error
+	)
+
+	data, err = json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	for attempt = 0; attempt <= *client.cfg.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(client.cfg.RetryInterval)
+		}
+
+		err = client.post(data)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+}
+
+// post sends a single request with data as body. Any status code other than 2xx is an error.
+func (client *webhookClient) post(data []byte) error {
+	var (
+		req   *http.Request
+		resp  *http.Response
+		body  []byte
+		name  string
+		value string
+		err   error
+	)
+
+	req, err = http.NewRequest(http.MethodPost, client.cfg.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "netbox_sd/"+version)
+
+	for name, value = range client.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err = client.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %d from webhook: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
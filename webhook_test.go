@@ -0,0 +1,83 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSend(t *testing.T) {
+	var (
+		retries  int = 2
+		requests int
+		received targetChanges
+		server   *httptest.Server
+		client   *webhookClient
+		changes  *targetChanges = &targetChanges{
+			Group:   "node.yml",
+			Targets: 0,
+			Added:   []*changedTarget{},
+			Removed: []*changedTarget{{Address: "192.0.2.1:9100"}},
+		}
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// the first attempt of each changes fails
+		if requests%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	client = newWebhookClient(&config.Webhook{
+		URL:           server.URL,
+		Headers:       map[string]string{"Authorization": "Bearer secret"},
+		Retries:       &retries,
+		RetryInterval: time.Millisecond,
+		Timeout:       time.Second,
+	})
+
+	require.Nil(t, client.send(changes))
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, "192.0.2.1:9100", received.Removed[0].Address)
+
+	// all attempts fail
+	requests = 0
+	client.cfg.Headers = nil
+	assert.Error(t, client.send(changes))
+	assert.Equal(t, 3, requests)
+}
@@ -0,0 +1,184 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+// This file contains the DNS zone file containing records of the devices of all groups.
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+)
+
+// zoneRecords keeps the DNS records of the last successful scan of each group for the DNS zone file.
+type zoneRecords struct {
+	mu      sync.Mutex
+	records map[string][]zoneRecord
+}
+
+// zoneRecord is a single A or AAAA record.
+type zoneRecord struct {
+	name    string
+	kind    string
+	address string
+}
+
+// update stores the records of the devices of targets as the current records of group and returns the content of the
+// zone file. Skipped and graveyard targets are not part of the zone.
+func (zone *zoneRecords) update(cfg *config.DNSZone, group string, targets []*discovery.Target) []byte {
+	var (
+		records []zoneRecord
+		target  *discovery.Target
+		name    string
+	)
+
+	for _, target = range targets {
+		if target.Skipped() || target.Graveyard || target.Device == nil {
+			continue
+		}
+
+		name = zoneName(target.Device.Name)
+		if name == "" {
+			continue
+		}
+
+		if target.Device.PrimaryIP4 != nil {
+			records = append(records, zoneRecord{name: name, kind: "A", address: target.Device.PrimaryIP4.ToAddr()})
+		}
+
+		if target.Device.PrimaryIP6 != nil {
+			records = append(records, zoneRecord{name: name, kind: "AAAA", address: target.Device.PrimaryIP6.ToAddr()})
+		}
+	}
+
+	zone.mu.Lock()
+	defer zone.mu.Unlock()
+
+	if zone.records == nil {
+		zone.records = make(map[string][]zoneRecord)
+	}
+
+	zone.records[group] = records
+
+	return zone.render(cfg)
+}
+
+// remove deletes the records of group.
+func (zone *zoneRecords) remove(group string) {
+	zone.mu.Lock()
+	defer zone.mu.Unlock()
+
+	delete(zone.records, group)
+}
+
+// render returns the zone file containing the records of all groups ordered by name. Records of devices that are part
+// of several groups are only written once. The caller must hold mu.
+func (zone *zoneRecords) render(cfg *config.DNSZone) []byte {
+	var (
+		records []zoneRecord
+		record  zoneRecord
+		seen    map[zoneRecord]bool = make(map[zoneRecord]bool)
+		group   string
+		name    string
+		buf     bytes.Buffer
+	)
+
+	for group = range zone.records {
+		for _, record = range zone.records[group] {
+			if !seen[record] {
+				seen[record] = true
+				records = append(records, record)
+			}
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].name != records[j].name {
+			return records[i].name < records[j].name
+		}
+
+		if records[i].kind != records[j].kind {
+			return records[i].kind < records[j].kind
+		}
+
+		return records[i].address < records[j].address
+	})
+
+	buf.WriteString("; generated by netbox_sd, do not edit\n")
+
+	for _, record = range records {
+		name = record.name
+		if cfg.Domain != "" {
+			name += "." + strings.TrimSuffix(cfg.Domain, ".") + "."
+		}
+
+		fmt.Fprintf(&buf, "%s\t%d\tIN\t%s\t%s\n", name, int64(cfg.TTL.Seconds()), record.kind, record.address)
+	}
+
+	return buf.Bytes()
+}
+
+// writeZone updates the DNS zone file of cfg with the devices of group. Nothing is done when cfg doesn't define a DNS
+// zone.
+func (sd *netboxSD) writeZone(cfg *config.Config, group *config.Group, targets []*discovery.Target) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if cfg.DNSZone == nil {
+		return nil
+	}
+
+	data = sd.zone.update(cfg.DNSZone, group.File, targets)
+
+	if *dryRun {
+		slog.Debug("dry-run: not writing dns zone file", "file", cfg.DNSZone.File)
+
+		return nil
+	}
+
+	_, err = writeFile(cfg.DNSZone.File, data, config.DefaultFileMode, -1, -1)
+
+	return err
+}
+
+// zoneName converts the name of a device into a DNS name. The name is lowercased and all characters other than letters,
+// digits, dots and hyphens are replaced by hyphens. Leading and trailing dots and hyphens are removed.
+func zoneName(device string) string {
+	var (
+		buf  strings.Builder
+		char rune
+	)
+
+	for _, char = range strings.ToLower(device) {
+		if (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') || char == '-' || char == '.' {
+			buf.WriteRune(char)
+		} else {
+			buf.WriteRune('-')
+		}
+	}
+
+	return strings.Trim(buf.String(), "-.")
+}
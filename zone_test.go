@@ -0,0 +1,76 @@
+// MIT License
+//
+// Copyright (c) 2024 WIIT AG
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit
+// persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the
+// Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR
+// OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/4xoc/netbox_sd/internal/config"
+	"github.com/4xoc/netbox_sd/pkg/discovery"
+	"github.com/4xoc/netbox_sd/pkg/netbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZoneRecords(t *testing.T) {
+	var (
+		zone    zoneRecords
+		cfg     *config.DNSZone     = &config.DNSZone{TTL: 5 * time.Minute}
+		targets []*discovery.Target = []*discovery.Target{
+			{
+				Device: &netbox.Device{
+					Name:       "Device_A",
+					PrimaryIP4: &netbox.IP{Address: "192.0.2.1/24"},
+					PrimaryIP6: &netbox.IP{Address: "2001:db8::1/64"},
+				},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				// devices without primary IPs are ignored
+				Device:     &netbox.Device{Name: "device-b"},
+				SkipReason: discovery.StateActive,
+			},
+			{
+				Device:     &netbox.Device{Name: "device-c", PrimaryIP4: &netbox.IP{Address: "192.0.2.3/24"}},
+				SkipReason: discovery.StateSkippedBadStatus,
+			},
+		}
+	)
+
+	assert.Equal(t, "; generated by netbox_sd, do not edit\n"+
+		"device-a\t300\tIN\tA\t192.0.2.1\n"+
+		"device-a\t300\tIN\tAAAA\t2001:db8::1\n", string(zone.update(cfg, "node.yml", targets)))
+
+	// devices of several groups are only written once
+	cfg.Domain = "devices.example.com"
+	assert.Equal(t, "; generated by netbox_sd, do not edit\n"+
+		"device-a.devices.example.com.\t300\tIN\tA\t192.0.2.1\n"+
+		"device-a.devices.example.com.\t300\tIN\tAAAA\t2001:db8::1\n", string(zone.update(cfg, "other.yml", targets)))
+
+	zone.remove("node.yml")
+	zone.remove("other.yml")
+	assert.Equal(t, "; generated by netbox_sd, do not edit\n", string(zone.update(cfg, "empty.yml", nil)))
+}
+
+func TestZoneName(t *testing.T) {
+	assert.Equal(t, "router1.fra1", zoneName("Router1.FRA1"))
+	assert.Equal(t, "switch-a-01", zoneName("switch a_01"))
+	assert.Equal(t, "core", zoneName("-core."))
+	assert.Equal(t, "", zoneName("--"))
+}